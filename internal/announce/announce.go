@@ -8,18 +8,31 @@ import (
 	"fmt"
 	"net/http"
 	"os"
+	"strconv"
 	"strings"
-	"text/template"
 
 	"github.com/charmbracelet/log"
+	"github.com/oarkflow/releaser/internal/artifact"
 	"github.com/oarkflow/releaser/internal/config"
+	"github.com/oarkflow/releaser/internal/httpclient"
 	"github.com/oarkflow/releaser/internal/tmpl"
 )
 
 // Announcer sends release announcements.
 type Announcer struct {
-	config  config.Announce
-	tmplCtx *tmpl.Context
+	config    config.Announce
+	tmplCtx   *tmpl.Context
+	artifacts *artifact.Manager
+	distDir   string
+	results   []Result
+}
+
+// Result is the outcome of one announcer: succeeded, failed or skipped,
+// with the error of a failed or skip_on_error announcer
+type Result struct {
+	Name   string
+	Status string
+	Err    error
 }
 
 // NewAnnouncer creates a new announcer.
@@ -30,64 +43,93 @@ func NewAnnouncer(cfg config.Announce, tmplCtx *tmpl.Context) *Announcer {
 	}
 }
 
+// WithArtifacts makes release artifacts, such as the checksums file,
+// available to announcers.
+func (a *Announcer) WithArtifacts(manager *artifact.Manager, distDir string) *Announcer {
+	a.artifacts = manager
+	a.distDir = distDir
+	return a
+}
+
+// announcement is a single configured announcer.
+type announcement struct {
+	name        string
+	enabled     string
+	skipOnError bool
+	send        func(context.Context) error
+}
+
 // Run sends all configured announcements.
 func (a *Announcer) Run(ctx context.Context) error {
-	if a.config.Skip == "true" {
+	skip, err := a.isEnabled("announce.skip", a.config.Skip)
+	if err != nil {
+		return fmt.Errorf("announce skip: %w", err)
+	}
+	if skip {
 		log.Info("Skipping announcements")
 		return nil
 	}
-
-	log.Info("Sending release announcements")
-
-	var errs []error
-
-	// Slack
-	if a.config.Slack.Enabled {
-		if err := a.announceSlack(ctx); err != nil {
-			errs = append(errs, fmt.Errorf("slack: %w", err))
-		}
+	if a.config.SkipPrerelease && a.tmplCtx.IsPrerelease() {
+		log.Info("Skipping announcements for prerelease", "tag", a.tmplCtx.Get("Tag"))
+		return nil
 	}
 
-	// Discord
-	if a.config.Discord.Enabled {
-		if err := a.announceDiscord(ctx); err != nil {
-			errs = append(errs, fmt.Errorf("discord: %w", err))
+	// Make optional keys safe to reference from message templates
+	for _, key := range []string{"ReleaseURL", "Changelog"} {
+		if a.tmplCtx.GetValue(key) == nil {
+			a.tmplCtx.Set(key, "")
 		}
 	}
+	a.tmplCtx.Set("Artifacts", a.artifactList())
 
-	// Teams
-	if a.config.Teams.Enabled {
-		if err := a.announceTeams(ctx); err != nil {
-			errs = append(errs, fmt.Errorf("teams: %w", err))
-		}
-	}
+	log.Info("Sending release announcements")
 
-	// Mastodon
-	if a.config.Mastodon.Enabled {
-		if err := a.announceMastodon(ctx); err != nil {
-			errs = append(errs, fmt.Errorf("mastodon: %w", err))
+	announcements := []announcement{
+		{"slack", a.config.Slack.Enabled, a.config.Slack.SkipOnError, a.announceSlack},
+		{"discord", a.config.Discord.Enabled, a.config.Discord.SkipOnError, a.announceDiscord},
+		{"teams", a.config.Teams.Enabled, a.config.Teams.SkipOnError, a.announceTeams},
+		{"mastodon", a.config.Mastodon.Enabled, a.config.Mastodon.SkipOnError, a.announceMastodon},
+		{"bluesky", a.config.Bluesky.Enabled, a.config.Bluesky.SkipOnError, a.announceBluesky},
+		{"twitter", a.config.Twitter.Enabled, a.config.Twitter.SkipOnError, a.announceTwitter},
+		{"telegram", a.config.Telegram.Enabled, a.config.Telegram.SkipOnError, a.announceTelegram},
+		{"webhook", a.config.Webhook.Enabled, a.config.Webhook.SkipOnError, a.webhookSender(a.config.Webhook)},
+		{"smtp", a.config.SMTP.Enabled, a.config.SMTP.SkipOnError, a.announceSMTP},
+		{"jira", a.config.Jira.Enabled, a.config.Jira.SkipOnError, a.announceJira},
+	}
+	for i, hook := range a.config.Webhooks {
+		name := hook.Name
+		if name == "" {
+			name = fmt.Sprintf("%d", i)
 		}
+		announcements = append(announcements, announcement{"webhooks[" + name + "]", hook.Enabled, hook.SkipOnError, a.webhookSender(hook)})
 	}
 
-	// Telegram
-	if a.config.Telegram.Enabled {
-		if err := a.announceTelegram(ctx); err != nil {
-			errs = append(errs, fmt.Errorf("telegram: %w", err))
+	var errs []error
+	for _, ann := range announcements {
+		enabled, err := a.isEnabled("announce."+ann.name+".enabled", ann.enabled)
+		if err != nil {
+			a.record(ann.name, "failed", err)
+			errs = append(errs, fmt.Errorf("%s: %w", ann.name, err))
+			continue
 		}
-	}
-
-	// Webhook
-	if a.config.Webhook.Enabled {
-		if err := a.announceWebhook(ctx); err != nil {
-			errs = append(errs, fmt.Errorf("webhook: %w", err))
+		if !enabled {
+			if ann.enabled != "" {
+				a.record(ann.name, "skipped", nil)
+			}
+			continue
 		}
-	}
 
-	// SMTP
-	if a.config.SMTP.Enabled {
-		if err := a.announceSMTP(ctx); err != nil {
-			errs = append(errs, fmt.Errorf("smtp: %w", err))
+		if err := ann.send(ctx); err != nil {
+			if ann.skipOnError {
+				log.Warn("Announcement failed, skipping", "announcer", ann.name, "error", err)
+				a.record(ann.name, "skipped", err)
+				continue
+			}
+			a.record(ann.name, "failed", err)
+			errs = append(errs, fmt.Errorf("%s: %w", ann.name, err))
+			continue
 		}
+		a.record(ann.name, "succeeded", nil)
 	}
 
 	if len(errs) > 0 {
@@ -102,14 +144,58 @@ func (a *Announcer) Run(ctx context.Context) error {
 	return nil
 }
 
-// announceSlack sends a Slack notification.
+// record adds the outcome of an announcer to the results
+func (a *Announcer) record(name, status string, err error) {
+	a.results = append(a.results, Result{Name: name, Status: status, Err: err})
+}
+
+// Results returns the outcome of each announcer of the last Run
+func (a *Announcer) Results() []Result {
+	return a.results
+}
+
+// isEnabled evaluates an announcer's enabled template. Anything other than
+// "true" disables the announcer, so prereleases can be excluded with e.g.
+// '{{ if .IsPrerelease }}false{{ else }}true{{ end }}'.
+func (a *Announcer) isEnabled(source, enabled string) (bool, error) {
+	if enabled == "" {
+		return false, nil
+	}
+	value, err := a.tmplCtx.Apply(source, enabled)
+	if err != nil {
+		return false, fmt.Errorf("failed to apply enabled template: %w", err)
+	}
+	return strings.TrimSpace(value) == "true", nil
+}
+
+// webhookURL returns the configured webhook URL, falling back to the
+// environment variable.
+func (a *Announcer) webhookURL(source, configured, env string) (string, error) {
+	if configured != "" {
+		return a.tmplCtx.Apply(source, configured)
+	}
+	return os.Getenv(env), nil
+}
+
+// announceSlack sends a Slack notification through an incoming webhook, or
+// through chat.postMessage when a bot token is available.
 func (a *Announcer) announceSlack(ctx context.Context) error {
-	webhook := os.Getenv("SLACK_WEBHOOK_URL")
-	if webhook == "" {
-		return fmt.Errorf("SLACK_WEBHOOK_URL environment variable not set")
+	cfg := a.config.Slack
+
+	webhook, err := a.webhookURL("announce.slack.webhook_url", cfg.WebhookURL, "SLACK_WEBHOOK_URL")
+	if err != nil {
+		return fmt.Errorf("failed to apply template to webhook_url: %w", err)
+	}
+	tokenEnv := cfg.TokenEnv
+	if tokenEnv == "" {
+		tokenEnv = "SLACK_BOT_TOKEN"
+	}
+	token := os.Getenv(tokenEnv)
+	if webhook == "" && token == "" {
+		return fmt.Errorf("slack.webhook_url, SLACK_WEBHOOK_URL or %s is required", tokenEnv)
 	}
 
-	message, err := a.formatMessage(a.config.Slack.MessageTemplate, "slack")
+	message, err := a.formatMessage("announce.slack.message_template", cfg.MessageTemplate)
 	if err != nil {
 		return err
 	}
@@ -118,42 +204,151 @@ func (a *Announcer) announceSlack(ctx context.Context) error {
 		"text": message,
 	}
 
-	if a.config.Slack.Channel != "" {
-		payload["channel"] = a.config.Slack.Channel
+	channel, err := a.tmplCtx.Apply("announce.slack.channel", cfg.Channel)
+	if err != nil {
+		return fmt.Errorf("failed to apply template to channel: %w", err)
+	}
+	if channel != "" {
+		payload["channel"] = channel
+	}
+	if cfg.Username != "" {
+		payload["username"] = cfg.Username
+	}
+	if cfg.IconEmoji != "" {
+		payload["icon_emoji"] = cfg.IconEmoji
+	}
+	if cfg.IconURL != "" {
+		payload["icon_url"] = cfg.IconURL
 	}
-	if a.config.Slack.Username != "" {
-		payload["username"] = a.config.Slack.Username
+	if len(cfg.Blocks) > 0 {
+		blocks, err := a.applyTemplates("announce.slack.blocks", cfg.Blocks)
+		if err != nil {
+			return fmt.Errorf("failed to apply template to blocks: %w", err)
+		}
+		payload["blocks"] = blocks
 	}
-	if a.config.Slack.IconEmoji != "" {
-		payload["icon_emoji"] = a.config.Slack.IconEmoji
+	if len(cfg.Attachments) > 0 {
+		attachments, err := a.applyTemplates("announce.slack.attachments", cfg.Attachments)
+		if err != nil {
+			return fmt.Errorf("failed to apply template to attachments: %w", err)
+		}
+		payload["attachments"] = attachments
 	}
 
-	if err := a.postJSON(ctx, webhook, payload); err != nil {
-		return err
+	if webhook != "" {
+		if err := a.postJSON(ctx, webhook, payload); err != nil {
+			return err
+		}
+	} else {
+		if channel == "" {
+			return fmt.Errorf("slack.channel is required when posting with a bot token")
+		}
+		if err := a.postSlackAPI(ctx, token, payload); err != nil {
+			return err
+		}
 	}
 
 	log.Info("Slack announcement sent")
 	return nil
 }
 
-// announceDiscord sends a Discord notification.
+// postSlackAPI posts a message with chat.postMessage. The Web API reports
+// failures in the response body rather than the status code.
+func (a *Announcer) postSlackAPI(ctx context.Context, token string, payload map[string]interface{}) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", "https://slack.com/api/chat.postMessage", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("Content-Type", "application/json; charset=utf-8")
+
+	resp, err := httpclient.Client().Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 400 {
+		return fmt.Errorf("slack returned status %d", resp.StatusCode)
+	}
+
+	var result struct {
+		OK    bool   `json:"ok"`
+		Error string `json:"error"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return fmt.Errorf("failed to decode slack response: %w", err)
+	}
+	if !result.OK {
+		return fmt.Errorf("slack API error: %s", result.Error)
+	}
+	return nil
+}
+
+// announceDiscord sends a Discord notification as an embed.
 func (a *Announcer) announceDiscord(ctx context.Context) error {
-	webhook := os.Getenv("DISCORD_WEBHOOK_URL")
+	cfg := a.config.Discord
+
+	webhook, err := a.webhookURL("announce.discord.webhook_url", cfg.WebhookURL, "DISCORD_WEBHOOK_URL")
+	if err != nil {
+		return fmt.Errorf("failed to apply template to webhook_url: %w", err)
+	}
 	if webhook == "" {
-		return fmt.Errorf("DISCORD_WEBHOOK_URL environment variable not set")
+		return fmt.Errorf("discord.webhook_url or DISCORD_WEBHOOK_URL is required")
 	}
 
-	message, err := a.formatMessage(a.config.Discord.MessageTemplate, "discord")
+	message, err := a.formatMessage("announce.discord.message_template", cfg.MessageTemplate)
+	if err != nil {
+		return err
+	}
+	title, err := a.formatTitle("announce.discord.title_template", cfg.TitleTemplate)
 	if err != nil {
 		return err
 	}
 
-	payload := map[string]interface{}{
-		"content": message,
+	color := defaultDiscordColor
+	if cfg.Color != "" {
+		color, err = parseColor(cfg.Color)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Discord rejects embed descriptions over 4096 characters
+	if runes := []rune(message); len(runes) > 4096 {
+		message = string(runes[:4093]) + "..."
+	}
+
+	embed := map[string]interface{}{
+		"title":       title,
+		"description": message,
+		"color":       color,
+	}
+	if url := a.tmplCtx.Get("ReleaseURL"); url != "" {
+		embed["url"] = url
+	}
+	if cfg.Author != "" {
+		author := map[string]interface{}{"name": cfg.Author}
+		if cfg.IconURL != "" {
+			author["icon_url"] = cfg.IconURL
+		}
+		embed["author"] = author
+	}
+	if cfg.ThumbnailURL != "" {
+		thumbnail, err := a.tmplCtx.Apply("announce.discord.thumbnail_url", cfg.ThumbnailURL)
+		if err != nil {
+			return fmt.Errorf("failed to apply template to thumbnail_url: %w", err)
+		}
+		embed["thumbnail"] = map[string]interface{}{"url": thumbnail}
 	}
 
-	if a.config.Discord.Author != "" {
-		payload["username"] = a.config.Discord.Author
+	payload := map[string]interface{}{
+		"embeds": []interface{}{embed},
 	}
 
 	if err := a.postJSON(ctx, webhook, payload); err != nil {
@@ -164,33 +359,56 @@ func (a *Announcer) announceDiscord(ctx context.Context) error {
 	return nil
 }
 
-// announceTeams sends a Microsoft Teams notification.
+// announceTeams sends a Microsoft Teams notification as a MessageCard.
 func (a *Announcer) announceTeams(ctx context.Context) error {
-	webhook := os.Getenv("TEAMS_WEBHOOK_URL")
+	cfg := a.config.Teams
+
+	webhook, err := a.webhookURL("announce.teams.webhook_url", cfg.WebhookURL, "TEAMS_WEBHOOK_URL")
+	if err != nil {
+		return fmt.Errorf("failed to apply template to webhook_url: %w", err)
+	}
 	if webhook == "" {
-		return fmt.Errorf("TEAMS_WEBHOOK_URL environment variable not set")
+		return fmt.Errorf("teams.webhook_url or TEAMS_WEBHOOK_URL is required")
 	}
 
-	message, err := a.formatMessage(a.config.Teams.MessageTemplate, "teams")
+	message, err := a.formatMessage("announce.teams.message_template", cfg.MessageTemplate)
+	if err != nil {
+		return err
+	}
+	title, err := a.formatTitle("announce.teams.title_template", cfg.TitleTemplate)
 	if err != nil {
 		return err
 	}
 
-	title := a.config.Teams.TitleTemplate
-	if title == "" {
-		title = fmt.Sprintf("%s %s Released", a.tmplCtx.Get("ProjectName"), a.tmplCtx.Get("Version"))
+	section := map[string]interface{}{
+		"activityTitle": title,
+		"text":          message,
+		"markdown":      true,
+	}
+	if cfg.IconURL != "" {
+		section["activityImage"] = cfg.IconURL
 	}
 
 	payload := map[string]interface{}{
 		"@type":    "MessageCard",
 		"@context": "http://schema.org/extensions",
 		"summary":  title,
-		"title":    title,
-		"text":     message,
+		"sections": []interface{}{section},
 	}
 
-	if a.config.Teams.Color != "" {
-		payload["themeColor"] = a.config.Teams.Color
+	if cfg.Color != "" {
+		payload["themeColor"] = strings.TrimPrefix(cfg.Color, "#")
+	}
+	if url := a.tmplCtx.Get("ReleaseURL"); url != "" {
+		payload["potentialAction"] = []interface{}{
+			map[string]interface{}{
+				"@type": "OpenUri",
+				"name":  "View release",
+				"targets": []interface{}{
+					map[string]interface{}{"os": "default", "uri": url},
+				},
+			},
+		}
 	}
 
 	if err := a.postJSON(ctx, webhook, payload); err != nil {
@@ -201,41 +419,40 @@ func (a *Announcer) announceTeams(ctx context.Context) error {
 	return nil
 }
 
-// announceMastodon posts to Mastodon.
+// announceMastodon posts a status through the instance REST API.
 func (a *Announcer) announceMastodon(ctx context.Context) error {
-	server := a.config.Mastodon.Server
+	cfg := a.config.Mastodon
+
+	server, err := a.tmplCtx.Apply("announce.mastodon.server", cfg.Server)
+	if err != nil {
+		return fmt.Errorf("failed to apply template to server: %w", err)
+	}
 	token := os.Getenv("MASTODON_ACCESS_TOKEN")
 	if server == "" || token == "" {
 		return fmt.Errorf("mastodon server config and MASTODON_ACCESS_TOKEN required")
 	}
 
-	message, err := a.formatMessage(a.config.Mastodon.MessageTemplate, "mastodon")
+	message, err := a.formatMessage("announce.mastodon.message_template", cfg.MessageTemplate)
 	if err != nil {
 		return err
 	}
 
-	url := fmt.Sprintf("%s/api/v1/statuses", strings.TrimSuffix(server, "/"))
+	maxLength := cfg.MaxLength
+	if maxLength == 0 {
+		maxLength = mastodonMaxLength
+	}
 
 	payload := map[string]interface{}{
-		"status": message,
+		"status": truncateMessage(message, maxLength),
 	}
-
-	body, _ := json.Marshal(payload)
-	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(body))
-	if err != nil {
-		return err
+	if cfg.Visibility != "" {
+		payload["visibility"] = cfg.Visibility
 	}
-	req.Header.Set("Authorization", "Bearer "+token)
-	req.Header.Set("Content-Type", "application/json")
 
-	resp, err := http.DefaultClient.Do(req)
-	if err != nil {
-		return err
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode >= 400 {
-		return fmt.Errorf("mastodon returned status %d", resp.StatusCode)
+	url := fmt.Sprintf("%s/api/v1/statuses", strings.TrimSuffix(server, "/"))
+	headers := map[string]string{"Authorization": "Bearer " + token}
+	if err := sendJSON(ctx, url, headers, payload, nil); err != nil {
+		return fmt.Errorf("failed to post status: %w", err)
 	}
 
 	log.Info("Mastodon announcement sent")
@@ -250,7 +467,7 @@ func (a *Announcer) announceTelegram(ctx context.Context) error {
 		return fmt.Errorf("TELEGRAM_BOT_TOKEN and telegram.chat_id required")
 	}
 
-	message, err := a.formatMessage(a.config.Telegram.MessageTemplate, "telegram")
+	message, err := a.formatMessage("announce.telegram.message_template", a.config.Telegram.MessageTemplate)
 	if err != nil {
 		return err
 	}
@@ -276,89 +493,80 @@ func (a *Announcer) announceTelegram(ctx context.Context) error {
 	return nil
 }
 
-// announceWebhook sends a generic webhook.
-func (a *Announcer) announceWebhook(ctx context.Context) error {
-	webhookURL := a.config.Webhook.EndpointURL
-	if webhookURL == "" {
-		webhookURL = os.Getenv("ANNOUNCE_WEBHOOK_URL")
-	}
-	if webhookURL == "" {
-		return fmt.Errorf("webhook URL not configured")
-	}
-
-	message, err := a.formatMessage(a.config.Webhook.MessageTemplate, "webhook")
-	if err != nil {
-		return err
-	}
-
-	payload := map[string]interface{}{
-		"version":     a.tmplCtx.Get("Version"),
-		"tag":         a.tmplCtx.Get("Tag"),
-		"project":     a.tmplCtx.Get("ProjectName"),
-		"message":     message,
-		"is_snapshot": a.tmplCtx.Get("IsSnapshot") == "true",
+// formatMessage applies the template context to a message template.
+func (a *Announcer) formatMessage(source, messageTemplate string) (string, error) {
+	if messageTemplate == "" {
+		messageTemplate = defaultMessageTemplate()
 	}
 
-	// Add custom headers
-	body, _ := json.Marshal(payload)
-	req, err := http.NewRequestWithContext(ctx, "POST", webhookURL, bytes.NewReader(body))
+	message, err := a.tmplCtx.Apply(source, messageTemplate)
 	if err != nil {
-		return err
+		return "", fmt.Errorf("failed to apply message template: %w", err)
 	}
+	return message, nil
+}
 
-	req.Header.Set("Content-Type", "application/json")
-
-	for key, value := range a.config.Webhook.Headers {
-		expandedValue, _ := a.tmplCtx.Apply(value)
-		req.Header.Set(key, expandedValue)
+// formatTitle applies the template context to a title template.
+func (a *Announcer) formatTitle(source, titleTemplate string) (string, error) {
+	if titleTemplate == "" {
+		titleTemplate = "{{ .ProjectName }} {{ .Tag }} is out!"
 	}
 
-	resp, err := http.DefaultClient.Do(req)
+	title, err := a.tmplCtx.Apply(source, titleTemplate)
 	if err != nil {
-		return err
+		return "", fmt.Errorf("failed to apply title template: %w", err)
 	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode >= 400 {
-		return fmt.Errorf("webhook returned status %d", resp.StatusCode)
-	}
-
-	log.Info("Webhook announcement sent")
-	return nil
+	return title, nil
 }
 
-// announceSMTP sends an email notification.
-func (a *Announcer) announceSMTP(ctx context.Context) error {
-	// SMTP requires more complex setup - placeholder
-	log.Warn("SMTP announcements require additional implementation")
-	return nil
+// applyTemplates applies the template context to every string in a
+// structure decoded from YAML, such as Slack blocks.
+func (a *Announcer) applyTemplates(source string, v interface{}) (interface{}, error) {
+	switch value := v.(type) {
+	case string:
+		return a.tmplCtx.Apply(source, value)
+	case []interface{}:
+		out := make([]interface{}, len(value))
+		for i, item := range value {
+			applied, err := a.applyTemplates(fmt.Sprintf("%s[%d]", source, i), item)
+			if err != nil {
+				return nil, err
+			}
+			out[i] = applied
+		}
+		return out, nil
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(value))
+		for key, item := range value {
+			applied, err := a.applyTemplates(source+"."+key, item)
+			if err != nil {
+				return nil, err
+			}
+			out[key] = applied
+		}
+		return out, nil
+	default:
+		return v, nil
+	}
 }
 
-// formatMessage applies template to message.
-func (a *Announcer) formatMessage(messageTemplate, service string) (string, error) {
-	if messageTemplate == "" {
-		messageTemplate = defaultMessageTemplate()
+// parseColor parses a color given as a decimal number or as hex with a
+// leading "#" or "0x".
+func parseColor(color string) (int64, error) {
+	var value int64
+	var err error
+	switch {
+	case strings.HasPrefix(color, "#"):
+		value, err = strconv.ParseInt(color[1:], 16, 32)
+	case strings.HasPrefix(color, "0x"), strings.HasPrefix(color, "0X"):
+		value, err = strconv.ParseInt(color[2:], 16, 32)
+	default:
+		value, err = strconv.ParseInt(color, 10, 32)
 	}
-
-	tmpl, err := template.New("message").Parse(messageTemplate)
 	if err != nil {
-		return "", fmt.Errorf("failed to parse message template: %w", err)
-	}
-
-	data := map[string]interface{}{
-		"ProjectName": a.tmplCtx.Get("ProjectName"),
-		"Tag":         a.tmplCtx.Get("Tag"),
-		"Version":     a.tmplCtx.Get("Version"),
-		"Changelog":   a.tmplCtx.Get("Changelog"),
-		"ReleaseURL":  a.tmplCtx.Get("ReleaseURL"),
+		return 0, fmt.Errorf("invalid color %q: %w", color, err)
 	}
-
-	var buf strings.Builder
-	if err := tmpl.Execute(&buf, data); err != nil {
-		return "", fmt.Errorf("failed to execute message template: %w", err)
-	}
-
-	return buf.String(), nil
+	return value, nil
 }
 
 // postJSON sends a JSON POST request.
@@ -374,7 +582,7 @@ func (a *Announcer) postJSON(ctx context.Context, url string, payload interface{
 	}
 	req.Header.Set("Content-Type", "application/json")
 
-	resp, err := http.DefaultClient.Do(req)
+	resp, err := httpclient.Client().Do(req)
 	if err != nil {
 		return err
 	}
@@ -387,6 +595,9 @@ func (a *Announcer) postJSON(ctx context.Context, url string, payload interface{
 	return nil
 }
 
+// defaultDiscordColor is the embed color used when none is configured.
+const defaultDiscordColor int64 = 3888754
+
 // defaultMessageTemplate returns the default announcement template.
 func defaultMessageTemplate() string {
 	return `🚀 {{ .ProjectName }} {{ .Version }} has been released!
@@ -0,0 +1,262 @@
+package announce
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/oarkflow/releaser/internal/config"
+	"github.com/oarkflow/releaser/internal/git"
+	"github.com/oarkflow/releaser/internal/tmpl"
+)
+
+const testMessageTemplate = "{{ .ProjectName }} {{ .Tag }}: {{ .ReleaseURL }}\n{{ .Changelog }}"
+
+// releaseContext returns the template context of a release of demo
+func releaseContext(tag string) *tmpl.Context {
+	ctx := tmpl.New(&config.Config{ProjectName: "demo"}, &git.Info{CurrentTag: tag}, false, false)
+	ctx.Set("ReleaseURL", "https://example.com/releases/"+tag)
+	ctx.Set("Changelog", "- fixed a bug")
+	return ctx
+}
+
+// webhookServer records the JSON payloads posted to it and answers with
+// status
+func webhookServer(t *testing.T, status int) (*httptest.Server, *[]map[string]any) {
+	t.Helper()
+	var payloads []map[string]any
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.Header.Get("Content-Type") != "application/json" {
+			t.Errorf("got %s with content type %q", r.Method, r.Header.Get("Content-Type"))
+		}
+		var payload map[string]any
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+			t.Errorf("payload is not JSON: %v", err)
+		}
+		payloads = append(payloads, payload)
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &payloads
+}
+
+// roundTrip normalizes a payload literal the way decoding JSON does
+func roundTrip(t *testing.T, v any) map[string]any {
+	t.Helper()
+	data, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out map[string]any
+	if err := json.Unmarshal(data, &out); err != nil {
+		t.Fatal(err)
+	}
+	return out
+}
+
+func TestWebhookPayloads(t *testing.T) {
+	const message = "demo v1.2.3: https://example.com/releases/v1.2.3\n- fixed a bug"
+
+	tests := []struct {
+		name      string
+		configure func(cfg *config.Announce, url string)
+		want      map[string]any
+	}{
+		{
+			name: "slack",
+			configure: func(cfg *config.Announce, url string) {
+				cfg.Slack = config.AnnounceSlack{
+					Enabled:         "true",
+					WebhookURL:      url,
+					Channel:         "#releases",
+					Username:        "releaser",
+					IconEmoji:       ":rocket:",
+					MessageTemplate: testMessageTemplate,
+					Blocks: []interface{}{
+						map[string]interface{}{
+							"type": "section",
+							"text": map[string]interface{}{"type": "mrkdwn", "text": "*{{ .ProjectName }}* {{ .Tag }}"},
+						},
+					},
+				}
+			},
+			want: map[string]any{
+				"text":       message,
+				"channel":    "#releases",
+				"username":   "releaser",
+				"icon_emoji": ":rocket:",
+				"blocks": []any{
+					map[string]any{
+						"type": "section",
+						"text": map[string]any{"type": "mrkdwn", "text": "*demo* v1.2.3"},
+					},
+				},
+			},
+		},
+		{
+			name: "discord",
+			configure: func(cfg *config.Announce, url string) {
+				cfg.Discord = config.AnnounceDiscord{
+					Enabled:         "true",
+					WebhookURL:      url,
+					TitleTemplate:   "{{ .ProjectName }} {{ .Tag }}",
+					MessageTemplate: testMessageTemplate,
+					Author:          "Release Bot",
+					IconURL:         "https://example.com/icon.png",
+					Color:           "#ff0000",
+					ThumbnailURL:    "https://example.com/{{ .ProjectName }}.png",
+				}
+			},
+			want: map[string]any{
+				"embeds": []any{
+					map[string]any{
+						"title":       "demo v1.2.3",
+						"description": message,
+						"color":       16711680,
+						"url":         "https://example.com/releases/v1.2.3",
+						"author":      map[string]any{"name": "Release Bot", "icon_url": "https://example.com/icon.png"},
+						"thumbnail":   map[string]any{"url": "https://example.com/demo.png"},
+					},
+				},
+			},
+		},
+		{
+			name: "teams",
+			configure: func(cfg *config.Announce, url string) {
+				cfg.Teams = config.AnnounceTeams{
+					Enabled:         "true",
+					WebhookURL:      url,
+					MessageTemplate: testMessageTemplate,
+					Color:           "#00ff00",
+					IconURL:         "https://example.com/icon.png",
+				}
+			},
+			want: map[string]any{
+				"@type":      "MessageCard",
+				"@context":   "http://schema.org/extensions",
+				"summary":    "demo v1.2.3 is out!",
+				"themeColor": "00ff00",
+				"sections": []any{
+					map[string]any{
+						"activityTitle": "demo v1.2.3 is out!",
+						"activityImage": "https://example.com/icon.png",
+						"text":          message,
+						"markdown":      true,
+					},
+				},
+				"potentialAction": []any{
+					map[string]any{
+						"@type":   "OpenUri",
+						"name":    "View release",
+						"targets": []any{map[string]any{"os": "default", "uri": "https://example.com/releases/v1.2.3"}},
+					},
+				},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv, payloads := webhookServer(t, http.StatusOK)
+			var cfg config.Announce
+			tt.configure(&cfg, srv.URL)
+
+			if err := NewAnnouncer(cfg, releaseContext("v1.2.3")).Run(context.Background()); err != nil {
+				t.Fatal(err)
+			}
+			if len(*payloads) != 1 {
+				t.Fatalf("posted %d payloads, want 1", len(*payloads))
+			}
+			if want := roundTrip(t, tt.want); !reflect.DeepEqual((*payloads)[0], want) {
+				got, _ := json.MarshalIndent((*payloads)[0], "", "  ")
+				exp, _ := json.MarshalIndent(want, "", "  ")
+				t.Errorf("payload:\n%s\nwant:\n%s", got, exp)
+			}
+		})
+	}
+}
+
+func TestEnabledAndSkipOnError(t *testing.T) {
+	const notPrerelease = "{{ if .IsPrerelease }}false{{ else }}true{{ end }}"
+
+	tests := []struct {
+		name        string
+		tag         string
+		enabled     string
+		skipOnError bool
+		status      int
+		posts       int
+		wantErr     bool
+		result      string
+	}{
+		{name: "enabled", tag: "v1.2.3", enabled: notPrerelease, status: http.StatusOK, posts: 1, result: "succeeded"},
+		{name: "disabled for prerelease", tag: "v1.2.3-rc.1", enabled: notPrerelease, status: http.StatusOK, posts: 0, result: "skipped"},
+		{name: "failure", tag: "v1.2.3", enabled: "true", status: http.StatusInternalServerError, posts: 1, wantErr: true, result: "failed"},
+		{name: "skip on error", tag: "v1.2.3", enabled: "true", skipOnError: true, status: http.StatusInternalServerError, posts: 1, result: "skipped"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv, payloads := webhookServer(t, tt.status)
+			cfg := config.Announce{Discord: config.AnnounceDiscord{
+				Enabled:     tt.enabled,
+				SkipOnError: tt.skipOnError,
+				WebhookURL:  srv.URL,
+			}}
+
+			a := NewAnnouncer(cfg, releaseContext(tt.tag))
+			err := a.Run(context.Background())
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Run() error = %v, want error %v", err, tt.wantErr)
+			}
+			if len(*payloads) != tt.posts {
+				t.Errorf("posted %d payloads, want %d", len(*payloads), tt.posts)
+			}
+			results := a.Results()
+			if len(results) != 1 || results[0].Name != "discord" || results[0].Status != tt.result {
+				t.Errorf("results = %+v, want discord %s", results, tt.result)
+			}
+		})
+	}
+}
+
+func TestTruncateMessage(t *testing.T) {
+	tests := []struct {
+		message string
+		limit   int
+		want    string
+	}{
+		{message: "Released v1.2.3", limit: 20, want: "Released v1.2.3"},
+		{message: "Released demo v1.2.3 today", limit: 16, want: "Released demo…"},
+		{message: "Released", limit: 1, want: "…"},
+		{message: "Released", limit: 0, want: "Released"},
+		{message: "Released", limit: -1, want: "Released"},
+	}
+
+	for _, tt := range tests {
+		if got := truncateMessage(tt.message, tt.limit); got != tt.want {
+			t.Errorf("truncateMessage(%q, %d) = %q, want %q", tt.message, tt.limit, got, tt.want)
+		}
+	}
+}
+
+func TestWebhookRetries(t *testing.T) {
+	for _, retries := range []int{0, 1} {
+		srv, payloads := webhookServer(t, http.StatusBadGateway)
+		cfg := config.Announce{Webhook: config.AnnounceWebhook{
+			Enabled:         "true",
+			EndpointURL:     srv.URL,
+			MessageTemplate: "released",
+			Retries:         &retries,
+		}}
+		if err := NewAnnouncer(cfg, releaseContext("v1.2.3")).Run(context.Background()); err == nil {
+			t.Fatal("Run() succeeded against a failing webhook")
+		}
+		if len(*payloads) != retries+1 {
+			t.Errorf("retries: %d: posted %d times, want %d", retries, len(*payloads), retries+1)
+		}
+	}
+}
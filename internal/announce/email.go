@@ -0,0 +1,173 @@
+package announce
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/log"
+	"github.com/oarkflow/releaser/internal/artifact"
+	"github.com/oarkflow/releaser/internal/mailer"
+)
+
+// SMTP connection security modes
+const (
+	smtpStartTLS = "starttls"
+	smtpImplicit = "implicit"
+	smtpNone     = "none"
+)
+
+// defaultEmailSubject is the subject used when none is configured
+const defaultEmailSubject = "{{ .ProjectName }} {{ .Tag }} released"
+
+// defaultEmailBody is the plain-text body used when none is configured
+const defaultEmailBody = `{{ .ProjectName }} {{ .Tag }} has been released.
+{{ if .ReleaseURL }}
+{{ .ReleaseURL }}
+{{ end }}{{ if .Changelog }}
+{{ .Changelog }}
+{{ end }}`
+
+// announceSMTP sends an email notification.
+func (a *Announcer) announceSMTP(ctx context.Context) error {
+	cfg := a.config.SMTP
+
+	if cfg.From == "" || len(cfg.To) == 0 {
+		return fmt.Errorf("smtp.from and smtp.to are required")
+	}
+
+	email, err := a.buildEmail()
+	if err != nil {
+		return err
+	}
+	m, err := mailer.New(email)
+	if err != nil {
+		return fmt.Errorf("invalid smtp settings: %w", err)
+	}
+
+	if cfg.DryRun {
+		message, err := m.Message(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to build email: %w", err)
+		}
+		path := filepath.Join(a.distDir, "announce-email.eml")
+		if err := os.WriteFile(path, message, 0644); err != nil {
+			return fmt.Errorf("failed to write email: %w", err)
+		}
+		log.Info("Email announcement written (dry run)", "path", path)
+		return nil
+	}
+
+	if err := m.Send(ctx); err != nil {
+		return err
+	}
+
+	log.Info("Email announcement sent", "recipients", len(cfg.To)+len(cfg.Cc))
+	return nil
+}
+
+// buildEmail renders the subject and bodies and resolves the SMTP server
+// settings into the email for the mailer.
+func (a *Announcer) buildEmail() (*mailer.EmailConfig, error) {
+	cfg := a.config.SMTP
+
+	subjectTemplate := cfg.SubjectTemplate
+	if subjectTemplate == "" {
+		subjectTemplate = defaultEmailSubject
+	}
+	subject, err := a.tmplCtx.Apply("announce.smtp.subject_template", subjectTemplate)
+	if err != nil {
+		return nil, fmt.Errorf("failed to apply subject template: %w", err)
+	}
+
+	bodyTemplate := cfg.BodyTemplate
+	if bodyTemplate == "" {
+		bodyTemplate = defaultEmailBody
+	}
+	textBody, err := a.tmplCtx.Apply("announce.smtp.body_template", bodyTemplate)
+	if err != nil {
+		return nil, fmt.Errorf("failed to apply body template: %w", err)
+	}
+
+	var htmlBody string
+	if cfg.HTMLTemplate != "" {
+		htmlBody, err = a.tmplCtx.Apply("announce.smtp.html_template", cfg.HTMLTemplate)
+		if err != nil {
+			return nil, fmt.Errorf("failed to apply html template: %w", err)
+		}
+	}
+
+	var attachments []mailer.Attachment
+	if cfg.AttachChecksums {
+		if a.artifacts == nil {
+			return nil, fmt.Errorf("no artifacts available to attach checksums")
+		}
+		for _, c := range a.artifacts.Filter(artifact.ByType(artifact.TypeChecksum)) {
+			attachments = append(attachments, mailer.Attachment{
+				Source:   c.Path,
+				MIMEType: "text/plain; charset=utf-8",
+			})
+		}
+		if len(attachments) == 0 {
+			log.Warn("No checksums file found to attach")
+		}
+	}
+
+	host := cfg.Host
+	if host == "" {
+		host = os.Getenv("SMTP_HOST")
+	}
+	if host == "" {
+		if !cfg.DryRun {
+			return nil, fmt.Errorf("smtp.host or SMTP_HOST is required")
+		}
+		// Nothing is sent on dry runs, the message only needs a sender
+		host = "localhost"
+	}
+
+	email := &mailer.EmailConfig{
+		From:        cfg.From,
+		To:          cfg.To,
+		CC:          cfg.Cc,
+		Subject:     strings.TrimSpace(subject),
+		TextBody:    textBody,
+		HTMLBody:    htmlBody,
+		Attachments: attachments,
+		Transport:   "smtp",
+		Host:        host,
+		Port:        cfg.Port,
+		Username:    cfg.Username,
+		Password:    cfg.Password,
+		// Bound the whole session so an unresponsive server cannot stall the release
+		Timeout:       2 * time.Minute,
+		SkipTLSVerify: cfg.InsecureSkipVerify,
+	}
+
+	switch strings.ToLower(cfg.TLS) {
+	case "", smtpStartTLS:
+		email.UseTLS = true
+	case "tls", "ssl", smtpImplicit:
+		email.UseSSL = true
+	case smtpNone:
+	default:
+		return nil, fmt.Errorf("unknown smtp.tls mode: %s", cfg.TLS)
+	}
+
+	if email.Port == 0 {
+		if env := os.Getenv("SMTP_PORT"); env != "" {
+			email.Port, _ = strconv.Atoi(env)
+		}
+	}
+	if email.Username == "" {
+		email.Username = os.Getenv("SMTP_USERNAME")
+	}
+	if email.Password == "" {
+		email.Password = os.Getenv("SMTP_PASSWORD")
+	}
+
+	return email, nil
+}
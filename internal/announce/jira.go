@@ -0,0 +1,198 @@
+package announce
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/log"
+
+	"github.com/oarkflow/releaser/internal/changelog"
+	"github.com/oarkflow/releaser/internal/httpclient"
+)
+
+// jiraClient calls the Jira REST API
+type jiraClient struct {
+	baseURL string
+	auth    string
+}
+
+// announceJira comments on the tickets the changelog references and adds
+// the release to their fix versions.
+func (a *Announcer) announceJira(ctx context.Context) error {
+	cfg := a.config.Jira
+
+	baseURL, err := a.webhookURL("announce.jira.url", cfg.URL, "JIRA_URL")
+	if err != nil {
+		return fmt.Errorf("failed to apply template to url: %w", err)
+	}
+	username, err := a.tmplCtx.Apply("announce.jira.username", cfg.Username)
+	if err != nil {
+		return fmt.Errorf("failed to apply template to username: %w", err)
+	}
+	if username == "" {
+		username = os.Getenv("JIRA_USERNAME")
+	}
+	token := os.Getenv("JIRA_API_TOKEN")
+	if baseURL == "" || token == "" {
+		return fmt.Errorf("jira.url or JIRA_URL, and JIRA_API_TOKEN required")
+	}
+	// The default pattern also matches names such as SHA-256 and UTF-8
+	if cfg.Pattern == "" && len(cfg.Projects) == 0 {
+		return fmt.Errorf("jira.projects required unless jira.pattern or changelog.ticket_links.pattern is set")
+	}
+	client := &jiraClient{baseURL: strings.TrimSuffix(baseURL, "/"), auth: "Bearer " + token}
+	if username != "" {
+		client.auth = "Basic " + base64.StdEncoding.EncodeToString([]byte(username+":"+token))
+	}
+
+	tickets, err := changelog.Tickets(a.tmplCtx.Get("Changelog"), cfg.Pattern)
+	if err != nil {
+		return err
+	}
+	if len(cfg.Projects) > 0 {
+		tickets = slices.DeleteFunc(tickets, func(ticket string) bool {
+			return !slices.Contains(cfg.Projects, jiraProject(ticket))
+		})
+	}
+	if len(tickets) == 0 {
+		log.Info("No Jira tickets referenced by the changelog")
+		return nil
+	}
+
+	var comment string
+	if cfg.CommentTemplate != "" || cfg.FixVersion == "" {
+		comment, err = a.formatMessage("announce.jira.comment_template", cfg.CommentTemplate)
+		if err != nil {
+			return err
+		}
+	}
+	fixVersion, err := a.tmplCtx.Apply("announce.jira.fix_version", cfg.FixVersion)
+	if err != nil {
+		return fmt.Errorf("failed to apply template to fix_version: %w", err)
+	}
+
+	// A failing ticket, e.g. one that doesn't exist, doesn't stop the others
+	var errs []error
+	versions := make(map[string]error)
+	for _, ticket := range tickets {
+		if comment != "" {
+			payload := map[string]interface{}{"body": comment}
+			if err := client.do(ctx, http.MethodPost, "/rest/api/2/issue/"+url.PathEscape(ticket)+"/comment", payload, nil); err != nil {
+				errs = append(errs, fmt.Errorf("%s: failed to comment: %w", ticket, err))
+				continue
+			}
+		}
+		if fixVersion != "" {
+			project := jiraProject(ticket)
+			verr, ok := versions[project]
+			if !ok {
+				verr = client.ensureVersion(ctx, project, fixVersion)
+				versions[project] = verr
+			}
+			if verr != nil {
+				errs = append(errs, fmt.Errorf("%s: failed to create version %s: %w", ticket, fixVersion, verr))
+				continue
+			}
+			payload := map[string]interface{}{
+				"update": map[string]interface{}{
+					"fixVersions": []interface{}{
+						map[string]interface{}{"add": map[string]string{"name": fixVersion}},
+					},
+				},
+			}
+			if err := client.do(ctx, http.MethodPut, "/rest/api/2/issue/"+url.PathEscape(ticket), payload, nil); err != nil {
+				errs = append(errs, fmt.Errorf("%s: failed to set fix version: %w", ticket, err))
+				continue
+			}
+		}
+		log.Debug("Updated Jira ticket", "ticket", ticket)
+	}
+	if err := errors.Join(errs...); err != nil {
+		return err
+	}
+
+	log.Info("Jira tickets updated", "tickets", strings.Join(tickets, ", "))
+	return nil
+}
+
+// jiraProject returns the project key of a ticket ID
+func jiraProject(ticket string) string {
+	if i := strings.LastIndex(ticket, "-"); i > 0 {
+		return ticket[:i]
+	}
+	return ticket
+}
+
+// ensureVersion creates the version in the project unless it exists,
+// released today
+func (c *jiraClient) ensureVersion(ctx context.Context, project, name string) error {
+	var versions []struct {
+		Name string `json:"name"`
+	}
+	if err := c.do(ctx, http.MethodGet, "/rest/api/2/project/"+url.PathEscape(project)+"/versions", nil, &versions); err != nil {
+		return err
+	}
+	for _, v := range versions {
+		if v.Name == name {
+			return nil
+		}
+	}
+	payload := map[string]interface{}{
+		"name":        name,
+		"project":     project,
+		"released":    true,
+		"releaseDate": time.Now().Format("2006-01-02"),
+	}
+	return c.do(ctx, http.MethodPost, "/rest/api/2/version", payload, nil)
+}
+
+// do sends a JSON request to the API, decoding the response into result
+// when given
+func (c *jiraClient) do(ctx context.Context, method, path string, payload, result interface{}) error {
+	var body io.Reader
+	if payload != nil {
+		data, err := json.Marshal(payload)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	if payload != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	req.Header.Set("Authorization", c.auth)
+
+	resp, err := httpclient.Client().Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 400 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("request failed with status %d: %s", resp.StatusCode, strings.TrimSpace(string(msg)))
+	}
+	if result != nil {
+		if err := json.NewDecoder(resp.Body).Decode(result); err != nil {
+			return fmt.Errorf("failed to decode response: %w", err)
+		}
+	}
+	return nil
+}
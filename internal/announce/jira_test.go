@@ -0,0 +1,170 @@
+package announce
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/oarkflow/releaser/internal/config"
+)
+
+// fakeJira records the requests it gets and answers ticket updates, failing
+// for ABC-404, which does not exist
+type fakeJira struct {
+	mu       sync.Mutex
+	requests []string
+	bodies   map[string]map[string]any
+	auth     []string
+}
+
+func (f *fakeJira) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	request := r.Method + " " + r.URL.Path
+	f.requests = append(f.requests, request)
+	f.auth = append(f.auth, r.Header.Get("Authorization"))
+	data, _ := io.ReadAll(r.Body)
+	if len(data) > 0 {
+		var body map[string]any
+		if err := json.Unmarshal(data, &body); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		f.bodies[request] = body
+	}
+
+	switch {
+	case strings.Contains(r.URL.Path, "ABC-404"):
+		http.Error(w, `{"errorMessages":["Issue does not exist"]}`, http.StatusNotFound)
+	case r.URL.Path == "/rest/api/2/project/ABC/versions":
+		io.WriteString(w, `[{"name": "v1.2.2"}]`)
+	case r.URL.Path == "/rest/api/2/project/OPS/versions":
+		io.WriteString(w, `[{"name": "v1.2.3"}]`)
+	default:
+		w.WriteHeader(http.StatusCreated)
+	}
+}
+
+func TestJiraUpdatesReferencedTickets(t *testing.T) {
+	fake := &fakeJira{bodies: make(map[string]map[string]any)}
+	srv := httptest.NewServer(fake)
+	t.Cleanup(srv.Close)
+	t.Setenv("JIRA_API_TOKEN", "jira-token")
+
+	ctx := releaseContext("v1.2.3")
+	ctx.Set("Changelog", "* ABC-1, ABC-2: share the cache\n* fix [OPS-3](https://jira.example.com/browse/OPS-3)\n* WEB-4 restyle\n* ABC-404 gone")
+	cfg := config.Announce{Jira: config.AnnounceJira{
+		Enabled:         "true",
+		URL:             srv.URL + "/",
+		Username:        "bot@example.com",
+		Projects:        []string{"ABC", "OPS"},
+		CommentTemplate: "Released in {{ .ProjectName }} {{ .Tag }}",
+		FixVersion:      "{{ .Tag }}",
+	}}
+
+	err := NewAnnouncer(cfg, ctx).Run(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "ABC-404: failed to comment") {
+		t.Fatalf("Run() = %v, want the ABC-404 failure", err)
+	}
+
+	// WEB-4 is not in the projects; a missing ticket doesn't stop the
+	// others; versions are looked up once per project and created when
+	// missing
+	want := []string{
+		"POST /rest/api/2/issue/ABC-1/comment",
+		"GET /rest/api/2/project/ABC/versions",
+		"POST /rest/api/2/version",
+		"PUT /rest/api/2/issue/ABC-1",
+		"POST /rest/api/2/issue/ABC-2/comment",
+		"PUT /rest/api/2/issue/ABC-2",
+		"POST /rest/api/2/issue/OPS-3/comment",
+		"GET /rest/api/2/project/OPS/versions",
+		"PUT /rest/api/2/issue/OPS-3",
+		"POST /rest/api/2/issue/ABC-404/comment",
+	}
+	if !reflect.DeepEqual(fake.requests, want) {
+		t.Errorf("requests:\n  %s\nwant:\n  %s", strings.Join(fake.requests, "\n  "), strings.Join(want, "\n  "))
+	}
+
+	basic := "Basic " + base64.StdEncoding.EncodeToString([]byte("bot@example.com:jira-token"))
+	for i, auth := range fake.auth {
+		if auth != basic {
+			t.Errorf("request %d authorization = %q", i, auth)
+		}
+	}
+
+	if got := fake.bodies["POST /rest/api/2/issue/ABC-1/comment"]; got["body"] != "Released in demo v1.2.3" {
+		t.Errorf("comment = %v", got)
+	}
+	version := fake.bodies["POST /rest/api/2/version"]
+	if version["name"] != "v1.2.3" || version["project"] != "ABC" || version["released"] != true {
+		t.Errorf("created version = %v", version)
+	}
+	fixVersions := roundTrip(t, map[string]any{
+		"update": map[string]any{"fixVersions": []any{map[string]any{"add": map[string]any{"name": "v1.2.3"}}}},
+	})
+	if got := fake.bodies["PUT /rest/api/2/issue/OPS-3"]; !reflect.DeepEqual(got, fixVersions) {
+		t.Errorf("fix version update = %v", got)
+	}
+}
+
+func TestJiraPersonalAccessToken(t *testing.T) {
+	fake := &fakeJira{bodies: make(map[string]map[string]any)}
+	srv := httptest.NewServer(fake)
+	t.Cleanup(srv.Close)
+	t.Setenv("JIRA_URL", srv.URL)
+	t.Setenv("JIRA_API_TOKEN", "jira-token")
+	t.Setenv("JIRA_USERNAME", "")
+
+	cfg := config.Announce{Jira: config.AnnounceJira{Enabled: "true", Projects: []string{"ABC"}, CommentTemplate: "Released"}}
+	if err := NewAnnouncer(cfg, releaseContext("v1.2.3")).Run(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	// releaseContext's changelog references no tickets
+	if len(fake.requests) != 0 {
+		t.Fatalf("requests = %v", fake.requests)
+	}
+
+	ctx := releaseContext("v1.2.3")
+	ctx.Set("Changelog", "* ABC-1 fix")
+	if err := NewAnnouncer(cfg, ctx).Run(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if len(fake.auth) != 1 || fake.auth[0] != "Bearer jira-token" {
+		t.Errorf("authorization = %v", fake.auth)
+	}
+}
+
+func TestJiraDefaultPatternNeedsProjects(t *testing.T) {
+	fake := &fakeJira{bodies: make(map[string]map[string]any)}
+	srv := httptest.NewServer(fake)
+	t.Cleanup(srv.Close)
+	t.Setenv("JIRA_URL", srv.URL)
+	t.Setenv("JIRA_API_TOKEN", "jira-token")
+
+	ctx := releaseContext("v1.2.3")
+	ctx.Set("Changelog", "* ABC-1 verify SHA-256 sums of UTF-8 names")
+	cfg := config.AnnounceJira{Enabled: "true", CommentTemplate: "Released"}
+	if err := NewAnnouncer(config.Announce{Jira: cfg}, ctx).Run(context.Background()); err == nil || !strings.Contains(err.Error(), "jira.projects") {
+		t.Fatalf("Run() error = %v, want jira.projects required", err)
+	}
+	if len(fake.requests) != 0 {
+		t.Fatalf("requests = %v", fake.requests)
+	}
+
+	cfg.Projects = []string{"ABC"}
+	if err := NewAnnouncer(config.Announce{Jira: cfg}, ctx).Run(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"POST /rest/api/2/issue/ABC-1/comment"}; !reflect.DeepEqual(fake.requests, want) {
+		t.Errorf("requests = %v, want %v", fake.requests, want)
+	}
+}
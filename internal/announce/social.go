@@ -0,0 +1,269 @@
+package announce
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/charmbracelet/log"
+
+	"github.com/oarkflow/releaser/internal/httpclient"
+)
+
+// Post length limits per platform
+const (
+	mastodonMaxLength = 500
+	blueskyMaxLength  = 300
+	twitterMaxLength  = 280
+)
+
+// linkPattern matches URLs that Bluesky should render as links
+var linkPattern = regexp.MustCompile(`https?://[^\s]+`)
+
+// announceBluesky posts to Bluesky through the ATProto createRecord endpoint.
+func (a *Announcer) announceBluesky(ctx context.Context) error {
+	cfg := a.config.Bluesky
+
+	handle, err := a.tmplCtx.Apply("announce.bluesky.username", cfg.Username)
+	if err != nil {
+		return fmt.Errorf("failed to apply template to username: %w", err)
+	}
+	password := os.Getenv("BLUESKY_APP_PASSWORD")
+	if handle == "" || password == "" {
+		return fmt.Errorf("bluesky.username and BLUESKY_APP_PASSWORD required")
+	}
+
+	pds := strings.TrimSuffix(cfg.PDSURL, "/")
+	if pds == "" {
+		pds = "https://bsky.social"
+	}
+
+	message, err := a.formatMessage("announce.bluesky.message_template", cfg.MessageTemplate)
+	if err != nil {
+		return err
+	}
+	message = truncateMessage(message, blueskyMaxLength)
+
+	var session struct {
+		AccessJwt string `json:"accessJwt"`
+		DID       string `json:"did"`
+	}
+	credentials := map[string]interface{}{
+		"identifier": handle,
+		"password":   password,
+	}
+	if err := sendJSON(ctx, pds+"/xrpc/com.atproto.server.createSession", nil, credentials, &session); err != nil {
+		return fmt.Errorf("failed to create session: %w", err)
+	}
+
+	post := map[string]interface{}{
+		"$type":     "app.bsky.feed.post",
+		"text":      message,
+		"createdAt": time.Now().UTC().Format(time.RFC3339),
+	}
+	if facets := linkFacets(message); len(facets) > 0 {
+		post["facets"] = facets
+	}
+
+	record := map[string]interface{}{
+		"repo":       session.DID,
+		"collection": "app.bsky.feed.post",
+		"record":     post,
+	}
+	headers := map[string]string{"Authorization": "Bearer " + session.AccessJwt}
+	if err := sendJSON(ctx, pds+"/xrpc/com.atproto.repo.createRecord", headers, record, nil); err != nil {
+		return fmt.Errorf("failed to create post: %w", err)
+	}
+
+	log.Info("Bluesky announcement sent")
+	return nil
+}
+
+// linkFacets returns link facets for the URLs in text. Bluesky does not
+// detect links itself; facets address the text by UTF-8 byte offsets.
+func linkFacets(text string) []interface{} {
+	var facets []interface{}
+	for _, loc := range linkPattern.FindAllStringIndex(text, -1) {
+		link := strings.TrimRight(text[loc[0]:loc[1]], ".,;:!?)]}'\"")
+		if strings.HasSuffix(link, "…") {
+			// The link was cut by truncation
+			continue
+		}
+		facets = append(facets, map[string]interface{}{
+			"index": map[string]interface{}{
+				"byteStart": loc[0],
+				"byteEnd":   loc[0] + len(link),
+			},
+			"features": []interface{}{
+				map[string]interface{}{
+					"$type": "app.bsky.richtext.facet#link",
+					"uri":   link,
+				},
+			},
+		})
+	}
+	return facets
+}
+
+// announceTwitter posts to X/Twitter through API v2 using OAuth 1.0a user
+// context credentials.
+func (a *Announcer) announceTwitter(ctx context.Context) error {
+	creds := oauth1Credentials{
+		consumerKey:    os.Getenv("TWITTER_CONSUMER_KEY"),
+		consumerSecret: os.Getenv("TWITTER_CONSUMER_SECRET"),
+		token:          os.Getenv("TWITTER_ACCESS_TOKEN"),
+		tokenSecret:    os.Getenv("TWITTER_ACCESS_TOKEN_SECRET"),
+	}
+	if creds.consumerKey == "" || creds.consumerSecret == "" || creds.token == "" || creds.tokenSecret == "" {
+		return fmt.Errorf("TWITTER_CONSUMER_KEY, TWITTER_CONSUMER_SECRET, TWITTER_ACCESS_TOKEN and TWITTER_ACCESS_TOKEN_SECRET required")
+	}
+
+	message, err := a.formatMessage("announce.twitter.message_template", a.config.Twitter.MessageTemplate)
+	if err != nil {
+		return err
+	}
+
+	const endpoint = "https://api.twitter.com/2/tweets"
+	authorization, err := creds.authorization("POST", endpoint)
+	if err != nil {
+		return err
+	}
+
+	payload := map[string]interface{}{
+		"text": truncateMessage(message, twitterMaxLength),
+	}
+	headers := map[string]string{"Authorization": authorization}
+	if err := sendJSON(ctx, endpoint, headers, payload, nil); err != nil {
+		return fmt.Errorf("failed to post tweet: %w", err)
+	}
+
+	log.Info("Twitter announcement sent")
+	return nil
+}
+
+// oauth1Credentials are the consumer and access token pairs for OAuth 1.0a
+type oauth1Credentials struct {
+	consumerKey    string
+	consumerSecret string
+	token          string
+	tokenSecret    string
+}
+
+// authorization builds an HMAC-SHA1 signed OAuth 1.0a Authorization header.
+// JSON request bodies are not part of the signature base string.
+func (c oauth1Credentials) authorization(method, endpoint string) (string, error) {
+	nonce := make([]byte, 16)
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+
+	params := map[string]string{
+		"oauth_consumer_key":     c.consumerKey,
+		"oauth_nonce":            hex.EncodeToString(nonce),
+		"oauth_signature_method": "HMAC-SHA1",
+		"oauth_timestamp":        strconv.FormatInt(time.Now().Unix(), 10),
+		"oauth_token":            c.token,
+		"oauth_version":          "1.0",
+	}
+
+	keys := make([]string, 0, len(params))
+	for key := range params {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	pairs := make([]string, 0, len(keys))
+	for _, key := range keys {
+		pairs = append(pairs, oauthEscape(key)+"="+oauthEscape(params[key]))
+	}
+	base := method + "&" + oauthEscape(endpoint) + "&" + oauthEscape(strings.Join(pairs, "&"))
+
+	mac := hmac.New(sha1.New, []byte(oauthEscape(c.consumerSecret)+"&"+oauthEscape(c.tokenSecret)))
+	mac.Write([]byte(base))
+	params["oauth_signature"] = base64.StdEncoding.EncodeToString(mac.Sum(nil))
+	keys = append(keys, "oauth_signature")
+	sort.Strings(keys)
+
+	header := make([]string, 0, len(keys))
+	for _, key := range keys {
+		header = append(header, fmt.Sprintf(`%s="%s"`, oauthEscape(key), oauthEscape(params[key])))
+	}
+	return "OAuth " + strings.Join(header, ", "), nil
+}
+
+// oauthEscape percent-encodes a value as required by RFC 5849
+func oauthEscape(s string) string {
+	return strings.ReplaceAll(url.QueryEscape(s), "+", "%20")
+}
+
+// truncateMessage shortens message to at most limit characters, cutting at a
+// word boundary and appending an ellipsis. A limit below 1 does not truncate.
+func truncateMessage(message string, limit int) string {
+	runes := []rune(strings.TrimSpace(message))
+	if limit <= 0 || len(runes) <= limit {
+		return string(runes)
+	}
+
+	cut := runes[:limit-1]
+	for i := len(cut) - 1; i > 0; i-- {
+		if unicode.IsSpace(cut[i]) {
+			cut = cut[:i]
+			break
+		}
+	}
+	return strings.TrimRightFunc(string(cut), func(r rune) bool {
+		return unicode.IsSpace(r) || unicode.IsPunct(r)
+	}) + "…"
+}
+
+// sendJSON posts a JSON payload with extra headers and decodes the response
+// into result when it is not nil.
+func sendJSON(ctx context.Context, url string, headers map[string]string, payload, result interface{}) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+
+	resp, err := httpclient.Client().Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 400 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("request failed with status %d: %s", resp.StatusCode, strings.TrimSpace(string(msg)))
+	}
+
+	if result != nil {
+		if err := json.NewDecoder(resp.Body).Decode(result); err != nil {
+			return fmt.Errorf("failed to decode response: %w", err)
+		}
+	}
+	return nil
+}
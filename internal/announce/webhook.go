@@ -0,0 +1,208 @@
+package announce
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/log"
+	"github.com/oarkflow/releaser/internal/config"
+	"github.com/oarkflow/releaser/internal/httpclient"
+	"github.com/oarkflow/releaser/internal/retry"
+)
+
+// defaultSignatureHeader carries the HMAC-SHA256 signature of the body
+const defaultSignatureHeader = "X-Releaser-Signature"
+
+// webhookSender returns an announcement sender for a webhook entry.
+func (a *Announcer) webhookSender(cfg config.AnnounceWebhook) func(context.Context) error {
+	return func(ctx context.Context) error {
+		return a.announceWebhook(ctx, cfg)
+	}
+}
+
+// announceWebhook sends a generic webhook, retrying server errors with
+// exponential backoff.
+func (a *Announcer) announceWebhook(ctx context.Context, cfg config.AnnounceWebhook) error {
+	webhookURL, err := a.webhookURL("announce.webhook.endpoint_url", cfg.EndpointURL, "ANNOUNCE_WEBHOOK_URL")
+	if err != nil {
+		return fmt.Errorf("failed to apply template to endpoint_url: %w", err)
+	}
+	if webhookURL == "" {
+		return fmt.Errorf("webhook URL not configured")
+	}
+
+	method := strings.ToUpper(cfg.Method)
+	if method == "" {
+		method = http.MethodPost
+	}
+
+	body, contentType, err := a.webhookBody(cfg)
+	if err != nil {
+		return err
+	}
+
+	headers := make(map[string]string, len(cfg.Headers))
+	for key, value := range cfg.Headers {
+		expanded, err := a.tmplCtx.Apply("announce.webhook.headers."+key, value)
+		if err != nil {
+			return fmt.Errorf("failed to apply template to header %s: %w", key, err)
+		}
+		headers[key] = expanded
+	}
+
+	if cfg.SigningSecretEnv != "" {
+		secret := os.Getenv(cfg.SigningSecretEnv)
+		if secret == "" {
+			return fmt.Errorf("environment variable %s is empty", cfg.SigningSecretEnv)
+		}
+		header := cfg.SignatureHeader
+		if header == "" {
+			header = defaultSignatureHeader
+		}
+		mac := hmac.New(sha256.New, []byte(secret))
+		mac.Write(body)
+		headers[header] = "sha256=" + hex.EncodeToString(mac.Sum(nil))
+	}
+
+	client := httpclient.Client()
+	if cfg.SkipTLSVerify {
+		client = httpclient.Insecure()
+	}
+
+	name := cfg.Name
+	if name == "" {
+		name = webhookURL
+	}
+
+	// Retries defaults to two; 4xx responses are never retried
+	opts := retry.DefaultOptions("webhook " + name)
+	opts.Attempts = 3
+	if cfg.Retries != nil {
+		opts.Attempts = max(*cfg.Retries, 0) + 1
+	}
+	opts.MaxDelay = 10 * time.Second
+
+	err = retry.Do(ctx, opts, func(int) error {
+		req, err := http.NewRequestWithContext(ctx, method, webhookURL, bytes.NewReader(body))
+		if err != nil {
+			return retry.Permanent(err)
+		}
+		req.Header.Set("Content-Type", contentType)
+		for key, value := range headers {
+			req.Header.Set(key, value)
+		}
+
+		resp, err := client.Do(req)
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+
+		if resp.StatusCode >= 300 {
+			msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+			err := fmt.Errorf("webhook returned status %d: %s", resp.StatusCode, strings.TrimSpace(string(msg)))
+			if resp.StatusCode < 500 {
+				return retry.Permanent(err)
+			}
+			return err
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	log.Info("Webhook announcement sent", "name", name)
+	return nil
+}
+
+// webhookBody renders the request body and its content type. A payload
+// template is sent as-is, form fields are URL encoded, and otherwise a JSON
+// document describing the release is built.
+func (a *Announcer) webhookBody(cfg config.AnnounceWebhook) ([]byte, string, error) {
+	if cfg.PayloadTemplate != "" {
+		payload, err := a.tmplCtx.Apply("announce.webhook.payload_template", cfg.PayloadTemplate)
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to apply payload template: %w", err)
+		}
+		contentType := cfg.ContentType
+		if contentType == "" {
+			contentType = "application/json"
+		}
+		if strings.Contains(contentType, "json") && !json.Valid([]byte(payload)) {
+			return nil, "", fmt.Errorf("payload template did not render valid JSON")
+		}
+		return []byte(payload), contentType, nil
+	}
+
+	if len(cfg.Form) > 0 {
+		values := url.Values{}
+		for key, value := range cfg.Form {
+			expanded, err := a.tmplCtx.Apply("announce.webhook.form."+key, value)
+			if err != nil {
+				return nil, "", fmt.Errorf("failed to apply template to form field %s: %w", key, err)
+			}
+			values.Set(key, expanded)
+		}
+		return []byte(values.Encode()), "application/x-www-form-urlencoded", nil
+	}
+
+	message, err := a.formatMessage("announce.webhook.message_template", cfg.MessageTemplate)
+	if err != nil {
+		return nil, "", err
+	}
+
+	payload := map[string]interface{}{
+		"version":     a.tmplCtx.Get("Version"),
+		"tag":         a.tmplCtx.Get("Tag"),
+		"project":     a.tmplCtx.Get("ProjectName"),
+		"message":     message,
+		"release_url": a.tmplCtx.Get("ReleaseURL"),
+		"is_snapshot": a.tmplCtx.GetValue("IsSnapshot") == true,
+		"artifacts":   a.tmplCtx.GetValue("Artifacts"),
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return nil, "", err
+	}
+
+	contentType := cfg.ContentType
+	if contentType == "" {
+		contentType = "application/json"
+	}
+	return body, contentType, nil
+}
+
+// artifactList describes the release artifacts for templates and payloads.
+func (a *Announcer) artifactList() []interface{} {
+	list := []interface{}{}
+	if a.artifacts == nil {
+		return list
+	}
+	for _, art := range a.artifacts.List() {
+		entry := map[string]interface{}{
+			"name": art.Name,
+			"path": art.Path,
+			"type": string(art.Type),
+		}
+		if art.Goos != "" {
+			entry["goos"] = art.Goos
+		}
+		if art.Goarch != "" {
+			entry["goarch"] = art.Goarch
+		}
+		list = append(list, entry)
+	}
+	return list
+}
@@ -7,12 +7,16 @@ import (
 	"archive/tar"
 	"archive/zip"
 	"compress/gzip"
+	"context"
 	"fmt"
 	"io"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"slices"
+	"sort"
 	"strings"
+	"time"
 
 	"github.com/charmbracelet/log"
 
@@ -23,6 +27,11 @@ import (
 
 // Creator creates archives
 type Creator struct {
+	// IncludeID adds the id of the archive, or of its only build, to the
+	// default name, so projects with several builds sharing a binary name
+	// get distinct archives
+	IncludeID bool
+
 	distDir string
 	tmplCtx *tmpl.Context
 }
@@ -35,65 +44,52 @@ func NewCreator(distDir string, tmplCtx *tmpl.Context) *Creator {
 	}
 }
 
-// Create creates an archive from artifacts
-func (c *Creator) Create(cfg config.Archive, artifacts []artifact.Artifact) (*artifact.Artifact, error) {
-	if len(artifacts) == 0 {
-		return nil, fmt.Errorf("no artifacts to archive")
-	}
-
-	// Get target info from first artifact
-	first := artifacts[0]
-	goos := first.Goos
-	goarch := first.Goarch
-
-	// Determine format
-	format := cfg.Format
-	if format == "" {
-		format = "tar.gz"
-	}
+// entry is a file written to an archive, or a symlink to link when set
+type entry struct {
+	src  string
+	dst  string
+	link string
+	info *config.ArchiveFileInfo
+}
 
-	// Check for format overrides
-	for _, override := range cfg.FormatOverrides {
-		if override.Goos == goos {
-			format = override.Format
-			break
+// Targets groups the binaries an archive includes by target, keeping only
+// the builds listed in its builds if any, in a stable order
+func Targets(cfg config.Archive, binaries []artifact.Artifact) [][]artifact.Artifact {
+	groups := make(map[string][]artifact.Artifact)
+	var keys []string
+	for _, bin := range binaries {
+		if len(cfg.Builds) > 0 && !slices.Contains(cfg.Builds, bin.BuildID) {
+			continue
 		}
+		key := bin.Platform()
+		if _, ok := groups[key]; !ok {
+			keys = append(keys, key)
+		}
+		groups[key] = append(groups[key], bin)
 	}
+	sort.Strings(keys)
 
-	// Apply name template
-	nameTemplate := cfg.NameTemplate
-	if nameTemplate == "" {
-		nameTemplate = "{{ .ProjectName }}_{{ .Version }}_{{ .Os }}_{{ .Arch }}"
+	targets := make([][]artifact.Artifact, 0, len(keys))
+	for _, key := range keys {
+		targets = append(targets, groups[key])
 	}
+	return targets
+}
 
-	// Create template context with artifact info
-	ctx := c.tmplCtx.WithArtifact(first.Name, goos, goarch, first.Goarm, first.Goamd64)
-	name, err := ctx.Apply(nameTemplate)
-	if err != nil {
-		return nil, fmt.Errorf("failed to apply name template: %w", err)
+// Create creates an archive from artifacts. source names the config entry,
+// e.g. "archives[0]", in template errors. A cancelled or failed archive is
+// removed rather than left half-written.
+func (c *Creator) Create(ctx context.Context, source string, cfg config.Archive, artifacts []artifact.Artifact) (*artifact.Artifact, error) {
+	if len(artifacts) == 0 {
+		return nil, fmt.Errorf("no artifacts to archive")
 	}
 
-	// Add extension
-	var ext string
-	switch format {
-	case "tar.gz", "tgz":
-		ext = ".tar.gz"
-	case "tar.xz", "txz":
-		ext = ".tar.xz"
-	case "tar":
-		ext = ".tar"
-	case "zip":
-		ext = ".zip"
-	case "gz", "gzip":
-		ext = ".gz"
-	case "binary":
-		ext = ""
-	default:
-		ext = "." + format
+	first := artifacts[0]
+	archivePath, format, err := c.Path(source, cfg, first)
+	if err != nil {
+		return nil, err
 	}
 
-	archivePath := filepath.Join(c.distDir, name+ext)
-
 	log.Info("Creating archive", "path", archivePath, "format", format)
 
 	// Run before hooks
@@ -102,31 +98,52 @@ func (c *Creator) Create(cfg config.Archive, artifacts []artifact.Artifact) (*ar
 		// TODO: Implement hook execution
 	}
 
-	// Create archive based on format
-	switch format {
-	case "tar.gz", "tgz":
-		if err := c.createTarGz(archivePath, cfg, artifacts); err != nil {
+	var entries []entry
+	if format != "binary" {
+		name := strings.TrimSuffix(filepath.Base(archivePath), extension(format))
+		wrapDir, err := c.wrapDir(source, cfg, first, name)
+		if err != nil {
 			return nil, err
 		}
-	case "tar.xz", "txz":
-		if err := c.createTarXz(archivePath, cfg, artifacts); err != nil {
-			return nil, err
+		// Generated directories are included like directory files
+		files := slices.Clip(cfg.Files)
+		for j, g := range cfg.Generated {
+			dir, err := c.GeneratedDir(fmt.Sprintf("%s.generated[%d]", source, j), g)
+			if err != nil {
+				return nil, err
+			}
+			dst := g.Dst
+			if dst == "" {
+				dst = filepath.Base(dir)
+			}
+			files = append(files, config.ArchiveFile{Src: dir, Dst: dst})
 		}
-	case "tar":
-		if err := c.createTar(archivePath, cfg, artifacts); err != nil {
+		cfg.Files = files
+		entries, err = c.entries(cfg, artifacts, wrapDir)
+		if err != nil {
 			return nil, err
 		}
+	}
+
+	// Create archive based on format
+	switch format {
+	case "tar.gz", "tgz":
+		err = c.createTarGz(ctx, archivePath, entries)
+	case "tar.xz", "txz":
+		err = c.createTarXz(ctx, archivePath, entries)
+	case "tar":
+		err = c.createTar(ctx, archivePath, entries)
 	case "zip":
-		if err := c.createZip(archivePath, cfg, artifacts); err != nil {
-			return nil, err
-		}
+		err = c.createZip(ctx, archivePath, entries)
 	case "binary":
-		if err := c.copyBinary(archivePath, artifacts); err != nil {
-			return nil, err
-		}
+		err = c.copyBinary(archivePath, artifacts)
 	default:
 		return nil, fmt.Errorf("unsupported archive format: %s", format)
 	}
+	if err != nil {
+		os.Remove(archivePath)
+		return nil, err
+	}
 
 	// Run after hooks
 	for _, hook := range cfg.Hooks.After {
@@ -134,72 +151,254 @@ func (c *Creator) Create(cfg config.Archive, artifacts []artifact.Artifact) (*ar
 		// TODO: Implement hook execution
 	}
 
+	// The archive belongs to a build when all its binaries do
+	buildID := first.BuildID
+	for _, a := range artifacts {
+		if a.BuildID != buildID {
+			buildID = ""
+			break
+		}
+	}
+
 	return &artifact.Artifact{
 		Name:    filepath.Base(archivePath),
 		Path:    archivePath,
 		Type:    artifact.TypeArchive,
-		Goos:    goos,
-		Goarch:  goarch,
+		Goos:    first.Goos,
+		Goarch:  first.Goarch,
 		Goarm:   first.Goarm,
 		Goamd64: first.Goamd64,
+		Gomips:  first.Gomips,
+		BuildID: buildID,
 		Extra: map[string]interface{}{
 			"format": format,
+			"id":     cfg.ID,
 		},
 	}, nil
 }
 
-// createTarGz creates a tar.gz archive
-func (c *Creator) createTarGz(path string, cfg config.Archive, artifacts []artifact.Artifact) error {
-	file, err := os.Create(path)
+// GeneratedDir resolves the directory the commands of a generated entry
+// write to. source names the entry, e.g. "archives[0].generated[1]".
+func (c *Creator) GeneratedDir(source string, g config.ArchiveGenerated) (string, error) {
+	if g.Dir == "" {
+		return "", fmt.Errorf("%s: dir is required", source)
+	}
+	dir, err := c.tmplCtx.Apply(source+".dir", g.Dir)
 	if err != nil {
-		return err
+		return "", fmt.Errorf("failed to apply generated dir: %w", err)
 	}
-	defer file.Close()
+	return filepath.Clean(dir), nil
+}
 
-	gw := gzip.NewWriter(file)
-	defer gw.Close()
+// Path resolves the archive path and format for a target without creating
+// anything. first is any artifact of the target being archived.
+func (c *Creator) Path(source string, cfg config.Archive, first artifact.Artifact) (string, string, error) {
+	goos := first.Goos
 
-	tw := tar.NewWriter(gw)
-	defer tw.Close()
+	// Determine format
+	format := cfg.Format
+	if format == "" {
+		format = "tar.gz"
+	}
+
+	// Check for format overrides
+	for _, override := range cfg.FormatOverrides {
+		if override.Goos == goos {
+			format = override.Format
+			break
+		}
+	}
+
+	// Apply name template
+	nameTemplate := cfg.NameTemplate
+	if nameTemplate == "" {
+		nameTemplate = "{{ .ProjectName }}_{{ .Version }}_{{ .Os }}_{{ .Arch }}{{ with .Arm }}v{{ . }}{{ end }}{{ with .Amd64 }}_{{ . }}{{ end }}{{ with .Mips }}_{{ . }}{{ end }}"
+		if c.IncludeID {
+			id := cfg.ID
+			if len(cfg.Builds) == 1 {
+				id = cfg.Builds[0]
+			}
+			nameTemplate = strings.Replace(nameTemplate, "{{ .ProjectName }}", "{{ .ProjectName }}_"+id, 1)
+		}
+		// Library archives are named like the libraries they hold
+		switch first.Type {
+		case artifact.TypeLibrary, artifact.TypeHeader, artifact.TypePkgConfig:
+			nameTemplate = "lib" + nameTemplate
+		}
+	}
+
+	// Create template context with artifact info
+	name, err := c.tmplCtx.ForArtifact(first).Apply(source+".name_template", nameTemplate)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to apply name template: %w", err)
+	}
+
+	archivePath := filepath.Join(c.distDir, name+extension(format))
+	return archivePath, format, nil
+}
+
+// extension returns the file extension of an archive format
+func extension(format string) string {
+	switch format {
+	case "tar.gz", "tgz":
+		return ".tar.gz"
+	case "tar.xz", "txz":
+		return ".tar.xz"
+	case "tar":
+		return ".tar"
+	case "zip":
+		return ".zip"
+	case "gz", "gzip":
+		return ".gz"
+	case "binary":
+		return ""
+	default:
+		return "." + format
+	}
+}
+
+// wrapDir returns the directory everything in the archive is put in:
+// none for "false", the archive name without extension for "true", or the
+// rendered template otherwise
+func (c *Creator) wrapDir(source string, cfg config.Archive, first artifact.Artifact, name string) (string, error) {
+	switch strings.TrimSpace(cfg.WrapInDirectory) {
+	case "", "false":
+		return "", nil
+	case "true":
+		return name, nil
+	}
+	dir, err := c.tmplCtx.ForArtifact(first).Apply(source+".wrap_in_directory", cfg.WrapInDirectory)
+	if err != nil {
+		return "", fmt.Errorf("failed to apply wrap_in_directory: %w", err)
+	}
+	return dir, nil
+}
 
-	// Determine wrapper directory
-	wrapDir := ""
-	if cfg.WrapInDirectory != "" {
-		wrapDir, _ = c.tmplCtx.Apply(cfg.WrapInDirectory)
+// entries lists the binaries and the extra files of an archive with their
+// paths inside it. Files are added by their base name and directories
+// recursively under theirs; dst renames a single file or directory and is
+// the directory of a glob's matches. Stripping the parent directories adds
+// every file by its base name. Paths must stay inside the wrap directory.
+func (c *Creator) entries(cfg config.Archive, artifacts []artifact.Artifact, wrapDir string) ([]entry, error) {
+	var entries []entry
+	add := func(src, dst string, info *config.ArchiveFileInfo) error {
+		if rel, err := filepath.Rel(wrapDir, filepath.Join(wrapDir, dst)); err != nil || rel == "." || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return fmt.Errorf("archive path %s of %s is outside the archive", dst, src)
+		}
+		dst = filepath.ToSlash(filepath.Join(wrapDir, dst))
+		entries = append(entries, entry{src: src, dst: strings.TrimPrefix(dst, "/"), info: info})
+		return nil
 	}
 
-	// Add binaries
 	for _, a := range artifacts {
-		if err := c.addToTar(tw, a.Path, filepath.Join(wrapDir, a.Name), nil); err != nil {
-			return err
+		var err error
+		switch a.Type {
+		case artifact.TypeLibrary:
+			// Libraries use the prefix layout they are installed with
+			err = add(a.Path, filepath.Join("lib", a.Name), nil)
+		case artifact.TypeHeader:
+			err = add(a.Path, filepath.Join("include", a.Name), nil)
+		case artifact.TypePkgConfig:
+			err = add(a.Path, filepath.Join("lib", "pkgconfig", a.Name), nil)
+		case artifact.TypeDirectory:
+			// Directory builds keep their layout, e.g. an executable next
+			// to the libraries it loads
+			err = filepath.Walk(a.Path, func(src string, info os.FileInfo, err error) error {
+				if err != nil || info.IsDir() {
+					return err
+				}
+				rel, err := filepath.Rel(a.Path, src)
+				if err != nil {
+					return err
+				}
+				return add(src, rel, nil)
+			})
+		default:
+			err = add(a.Path, a.Name, nil)
+		}
+		if err != nil {
+			return nil, err
 		}
 	}
 
-	// Add extra files
 	for _, f := range cfg.Files {
+		if f.SymlinkTo != "" {
+			if err := add("", f.Dst, &f.Info); err != nil {
+				return nil, err
+			}
+			entries[len(entries)-1].link = f.SymlinkTo
+			continue
+		}
 		matches, err := filepath.Glob(f.Src)
 		if err != nil {
+			log.Warn("Invalid archive file pattern", "pattern", f.Src, "error", err)
 			continue
 		}
+		strip := f.StripParent || cfg.StripParentDir
+		literal := !strings.ContainsAny(f.Src, "*?[")
 		for _, match := range matches {
-			dst := f.Dst
-			if dst == "" {
-				dst = filepath.Base(match)
-			}
-			if f.StripParent {
-				dst = filepath.Base(match)
-			}
-			if err := c.addToTar(tw, match, filepath.Join(wrapDir, dst), &f.Info); err != nil {
-				log.Warn("Failed to add file to archive", "file", match, "error", err)
+			err := filepath.Walk(match, func(src string, info os.FileInfo, err error) error {
+				if err != nil || info.IsDir() {
+					return err
+				}
+				// rel is the path inside a matched directory, "." for a file
+				rel, err := filepath.Rel(match, src)
+				if err != nil {
+					return err
+				}
+				dst := filepath.Join(filepath.Base(match), rel)
+				switch {
+				case strip:
+					dst = filepath.Join(f.Dst, filepath.Base(src))
+				case f.Dst == "":
+				case literal:
+					dst = filepath.Join(f.Dst, rel)
+				default:
+					dst = filepath.Join(f.Dst, dst)
+				}
+				return add(src, dst, &f.Info)
+			})
+			if err != nil {
+				return nil, fmt.Errorf("failed to add %s to archive: %w", match, err)
 			}
 		}
 	}
 
+	return entries, nil
+}
+
+// writeTar writes the entries to a tar archive
+func (c *Creator) writeTar(ctx context.Context, tw *tar.Writer, entries []entry) error {
+	for _, e := range entries {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := c.addToTar(tw, e); err != nil {
+			return fmt.Errorf("failed to add %s to archive: %w", e.name(), err)
+		}
+	}
 	return nil
 }
 
+// createTarGz creates a tar.gz archive
+func (c *Creator) createTarGz(ctx context.Context, path string, entries []entry) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	gw := gzip.NewWriter(file)
+	defer gw.Close()
+
+	tw := tar.NewWriter(gw)
+	defer tw.Close()
+
+	return c.writeTar(ctx, tw, entries)
+}
+
 // createTarXz creates a tar.xz archive
-func (c *Creator) createTarXz(path string, cfg config.Archive, artifacts []artifact.Artifact) error {
+func (c *Creator) createTarXz(ctx context.Context, path string, entries []entry) error {
 	file, err := os.Create(path)
 	if err != nil {
 		return err
@@ -216,46 +415,12 @@ func (c *Creator) createTarXz(path string, cfg config.Archive, artifacts []artif
 		defer pw.Close()
 		tw := tar.NewWriter(pw)
 		defer tw.Close()
-
-		// Determine wrapper directory
-		wrapDir := ""
-		if cfg.WrapInDirectory != "" {
-			wrapDir, _ = c.tmplCtx.Apply(cfg.WrapInDirectory)
-		}
-
-		// Add binaries
-		for _, a := range artifacts {
-			if err := c.addToTar(tw, a.Path, filepath.Join(wrapDir, a.Name), nil); err != nil {
-				errCh <- err
-				return
-			}
-		}
-
-		// Add extra files
-		for _, f := range cfg.Files {
-			matches, err := filepath.Glob(f.Src)
-			if err != nil {
-				continue
-			}
-			for _, match := range matches {
-				dst := f.Dst
-				if dst == "" {
-					dst = filepath.Base(match)
-				}
-				if f.StripParent {
-					dst = filepath.Base(match)
-				}
-				if err := c.addToTar(tw, match, filepath.Join(wrapDir, dst), &f.Info); err != nil {
-					log.Warn("Failed to add file to archive", "file", match, "error", err)
-				}
-			}
-		}
-		errCh <- nil
+		errCh <- c.writeTar(ctx, tw, entries)
 	}()
 
 	// Compress with xz using exec (Go stdlib doesn't have xz support)
 	// Note: xz may not be available on Windows by default
-	cmd := exec.Command("xz", "-c")
+	cmd := exec.CommandContext(ctx, "xz", "-c")
 	cmd.Stdin = pr
 	cmd.Stdout = file
 
@@ -276,7 +441,7 @@ func (c *Creator) createTarXz(path string, cfg config.Archive, artifacts []artif
 }
 
 // createTar creates a tar archive
-func (c *Creator) createTar(path string, cfg config.Archive, artifacts []artifact.Artifact) error {
+func (c *Creator) createTar(ctx context.Context, path string, entries []entry) error {
 	file, err := os.Create(path)
 	if err != nil {
 		return err
@@ -286,38 +451,11 @@ func (c *Creator) createTar(path string, cfg config.Archive, artifacts []artifac
 	tw := tar.NewWriter(file)
 	defer tw.Close()
 
-	wrapDir := ""
-	if cfg.WrapInDirectory != "" {
-		wrapDir, _ = c.tmplCtx.Apply(cfg.WrapInDirectory)
-	}
-
-	for _, a := range artifacts {
-		if err := c.addToTar(tw, a.Path, filepath.Join(wrapDir, a.Name), nil); err != nil {
-			return err
-		}
-	}
-
-	for _, f := range cfg.Files {
-		matches, err := filepath.Glob(f.Src)
-		if err != nil {
-			continue
-		}
-		for _, match := range matches {
-			dst := f.Dst
-			if dst == "" {
-				dst = filepath.Base(match)
-			}
-			if err := c.addToTar(tw, match, filepath.Join(wrapDir, dst), &f.Info); err != nil {
-				log.Warn("Failed to add file to archive", "file", match, "error", err)
-			}
-		}
-	}
-
-	return nil
+	return c.writeTar(ctx, tw, entries)
 }
 
 // createZip creates a zip archive
-func (c *Creator) createZip(path string, cfg config.Archive, artifacts []artifact.Artifact) error {
+func (c *Creator) createZip(ctx context.Context, path string, entries []entry) error {
 	file, err := os.Create(path)
 	if err != nil {
 		return err
@@ -327,30 +465,12 @@ func (c *Creator) createZip(path string, cfg config.Archive, artifacts []artifac
 	zw := zip.NewWriter(file)
 	defer zw.Close()
 
-	wrapDir := ""
-	if cfg.WrapInDirectory != "" {
-		wrapDir, _ = c.tmplCtx.Apply(cfg.WrapInDirectory)
-	}
-
-	for _, a := range artifacts {
-		if err := c.addToZip(zw, a.Path, filepath.Join(wrapDir, a.Name)); err != nil {
+	for _, e := range entries {
+		if err := ctx.Err(); err != nil {
 			return err
 		}
-	}
-
-	for _, f := range cfg.Files {
-		matches, err := filepath.Glob(f.Src)
-		if err != nil {
-			continue
-		}
-		for _, match := range matches {
-			dst := f.Dst
-			if dst == "" {
-				dst = filepath.Base(match)
-			}
-			if err := c.addToZip(zw, match, filepath.Join(wrapDir, dst)); err != nil {
-				log.Warn("Failed to add file to archive", "file", match, "error", err)
-			}
+		if err := c.addToZip(zw, e); err != nil {
+			return fmt.Errorf("failed to add %s to archive: %w", e.name(), err)
 		}
 	}
 
@@ -362,6 +482,9 @@ func (c *Creator) copyBinary(path string, artifacts []artifact.Artifact) error {
 	if len(artifacts) != 1 {
 		return fmt.Errorf("binary format requires exactly one artifact")
 	}
+	if artifacts[0].Type == artifact.TypeDirectory {
+		return fmt.Errorf("binary format cannot hold the directory build %s, use an archive format", artifacts[0].Name)
+	}
 
 	src, err := os.Open(artifacts[0].Path)
 	if err != nil {
@@ -387,36 +510,75 @@ func (c *Creator) copyBinary(path string, artifacts []artifact.Artifact) error {
 	return os.Chmod(path, info.Mode())
 }
 
-// addToTar adds a file to a tar archive
-func (c *Creator) addToTar(tw *tar.Writer, src, dst string, info *config.ArchiveFileInfo) error {
-	file, err := os.Open(src)
+// name returns the file an entry is made from, for errors
+func (e entry) name() string {
+	if e.link != "" {
+		return e.dst
+	}
+	return e.src
+}
+
+// stat returns the file info of an entry: the source file, or a symlink
+// made now for symlink entries
+func (e entry) stat() (os.FileInfo, error) {
+	if e.link != "" {
+		return symlinkInfo{name: filepath.Base(e.dst), modTime: time.Now()}, nil
+	}
+	return os.Stat(e.src)
+}
+
+// modTime returns the modification time of an entry in the archive
+func (e entry) modTime(stat os.FileInfo) (time.Time, error) {
+	if e.info == nil || e.info.MTime == "" {
+		return stat.ModTime(), nil
+	}
+	mtime, err := time.Parse(time.RFC3339, e.info.MTime)
 	if err != nil {
-		return err
+		return time.Time{}, fmt.Errorf("invalid info.mtime %q: %w", e.info.MTime, err)
 	}
-	defer file.Close()
+	return mtime, nil
+}
+
+// symlinkInfo describes a symlink that only exists in an archive
+type symlinkInfo struct {
+	name    string
+	modTime time.Time
+}
+
+func (i symlinkInfo) Name() string       { return i.name }
+func (i symlinkInfo) Size() int64        { return 0 }
+func (i symlinkInfo) Mode() os.FileMode  { return os.ModeSymlink | 0777 }
+func (i symlinkInfo) ModTime() time.Time { return i.modTime }
+func (i symlinkInfo) IsDir() bool        { return false }
+func (i symlinkInfo) Sys() interface{}   { return nil }
 
-	stat, err := file.Stat()
+// addToTar adds a file or symlink to a tar archive
+func (c *Creator) addToTar(tw *tar.Writer, e entry) error {
+	stat, err := e.stat()
 	if err != nil {
 		return err
 	}
 
-	header, err := tar.FileInfoHeader(stat, "")
+	header, err := tar.FileInfoHeader(stat, e.link)
 	if err != nil {
 		return err
 	}
 
-	header.Name = strings.TrimPrefix(dst, "/")
+	header.Name = e.dst
+	if header.ModTime, err = e.modTime(stat); err != nil {
+		return err
+	}
 
 	// Apply custom file info
-	if info != nil {
-		if info.Mode != 0 {
-			header.Mode = int64(info.Mode)
+	if e.info != nil {
+		if e.info.Mode != 0 {
+			header.Mode = int64(e.info.Mode)
 		}
-		if info.Owner != "" {
-			header.Uname = info.Owner
+		if e.info.Owner != "" {
+			header.Uname = e.info.Owner
 		}
-		if info.Group != "" {
-			header.Gname = info.Group
+		if e.info.Group != "" {
+			header.Gname = e.info.Group
 		}
 	}
 
@@ -424,24 +586,22 @@ func (c *Creator) addToTar(tw *tar.Writer, src, dst string, info *config.Archive
 		return err
 	}
 
-	if !stat.IsDir() {
-		if _, err := io.Copy(tw, file); err != nil {
-			return err
-		}
+	if header.Typeflag != tar.TypeReg {
+		return nil
 	}
-
-	return nil
-}
-
-// addToZip adds a file to a zip archive
-func (c *Creator) addToZip(zw *zip.Writer, src, dst string) error {
-	file, err := os.Open(src)
+	file, err := os.Open(e.src)
 	if err != nil {
 		return err
 	}
 	defer file.Close()
+	_, err = io.Copy(tw, file)
+	return err
+}
 
-	stat, err := file.Stat()
+// addToZip adds a file or symlink to a zip archive. Symlinks are stored
+// the way Info-ZIP does, with the target as their content.
+func (c *Creator) addToZip(zw *zip.Writer, e entry) error {
+	stat, err := e.stat()
 	if err != nil {
 		return err
 	}
@@ -451,19 +611,35 @@ func (c *Creator) addToZip(zw *zip.Writer, src, dst string) error {
 		return err
 	}
 
-	header.Name = strings.TrimPrefix(dst, "/")
+	header.Name = e.dst
+	if e.info != nil && e.info.Mode != 0 {
+		header.SetMode(stat.Mode()&^os.ModePerm | e.info.Mode.Perm())
+	}
+	if header.Modified, err = e.modTime(stat); err != nil {
+		return err
+	}
 	header.Method = zip.Deflate
+	if e.link != "" {
+		header.Method = zip.Store
+	}
 
 	writer, err := zw.CreateHeader(header)
 	if err != nil {
 		return err
 	}
 
-	if !stat.IsDir() {
-		if _, err := io.Copy(writer, file); err != nil {
-			return err
-		}
+	switch {
+	case e.link != "":
+		_, err = io.WriteString(writer, e.link)
+		return err
+	case stat.IsDir():
+		return nil
 	}
-
-	return nil
+	file, err := os.Open(e.src)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	_, err = io.Copy(writer, file)
+	return err
 }
@@ -0,0 +1,89 @@
+package archive
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/oarkflow/releaser/internal/artifact"
+	"github.com/oarkflow/releaser/internal/config"
+)
+
+func TestEntries(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"LICENSE", "sub/NOTICE", "docs/a.md", "docs/api/b.md", "configs/app.yaml"} {
+		path := filepath.Join(dir, "src", name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(name), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	t.Chdir(filepath.Join(dir, "src"))
+	binaries := []artifact.Artifact{{Name: "demo", Path: "demo", Type: artifact.TypeBinary}}
+
+	tests := []struct {
+		name    string
+		cfg     config.Archive
+		wrapDir string
+		want    []string
+		err     string
+	}{
+		{
+			name: "files by base name",
+			cfg:  config.Archive{Files: []config.ArchiveFile{{Src: "LICENSE"}, {Src: "sub/NOTICE"}, {Src: "../src/docs"}}},
+			want: []string{"demo", "LICENSE", "NOTICE", "docs/a.md", "docs/api/b.md"},
+		},
+		{
+			name:    "dst",
+			wrapDir: "demo_1.2.3",
+			cfg: config.Archive{Files: []config.ArchiveFile{
+				{Src: "sub/NOTICE", Dst: "legal/NOTICE.txt"},
+				{Src: "docs", Dst: "share/doc"},
+				{Src: "configs/*", Dst: "etc"},
+			}},
+			want: []string{"demo_1.2.3/demo", "demo_1.2.3/legal/NOTICE.txt", "demo_1.2.3/share/doc/a.md", "demo_1.2.3/share/doc/api/b.md", "demo_1.2.3/etc/app.yaml"},
+		},
+		{
+			name: "strip parent directories",
+			cfg:  config.Archive{StripParentDir: true, Files: []config.ArchiveFile{{Src: "docs"}}},
+			want: []string{"demo", "a.md", "b.md"},
+		},
+		{
+			name:    "dst outside the wrap directory",
+			wrapDir: "demo_1.2.3",
+			cfg:     config.Archive{Files: []config.ArchiveFile{{Src: "LICENSE", Dst: "../LICENSE"}}},
+			err:     "outside the archive",
+		},
+		{
+			name: "parent directory source",
+			cfg:  config.Archive{Files: []config.ArchiveFile{{Src: ".."}}},
+			err:  "outside the archive",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entries, err := (&Creator{}).entries(tt.cfg, binaries, tt.wrapDir)
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Fatalf("entries() error = %v, want %q", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, e := range entries {
+				got = append(got, e.dst)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("entries = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
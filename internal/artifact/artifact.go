@@ -5,53 +5,86 @@ package artifact
 
 import (
 	"encoding/json"
+	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 	"sync"
+
+	"github.com/oarkflow/releaser/internal/target"
 )
 
 // Type represents the type of artifact
 type Type string
 
 const (
-	TypeBinary          Type = "Binary"
-	TypeArchive         Type = "Archive"
-	TypePackage         Type = "Package"
-	TypeChecksum        Type = "Checksum"
-	TypeSignature       Type = "Signature"
-	TypeLinuxPackage    Type = "Linux Package"
-	TypeDockerImage     Type = "Docker Image"
-	TypeDockerManifest  Type = "Docker Manifest"
-	TypeSourceArchive   Type = "Source Archive"
-	TypeSBOM            Type = "SBOM"
-	TypeUploadable      Type = "Uploadable"
-	TypePublishable     Type = "Publishable"
-	TypeAnnounce        Type = "Announce"
-	TypeMetadata        Type = "Metadata"
-	TypeHeader          Type = "Header"
-	TypeBrewTap         Type = "Homebrew Tap"
-	TypeScoopManifest   Type = "Scoop Manifest"
-	TypeNPMPackage      Type = "NPM Package"
-	TypeDMG             Type = "DMG"
-	TypePKG             Type = "PKG"
-	TypeMSI             Type = "MSI"
-	TypeNSIS            Type = "NSIS"
-	TypeAppBundle       Type = "App Bundle"
-	TypeUniversalBinary Type = "Universal Binary"
-	TypeFlatpak         Type = "Flatpak"
-	TypeAppImage        Type = "AppImage"
-	TypeSnap            Type = "Snap"
-	TypeChocolatey      Type = "Chocolatey"
-	TypeWinget          Type = "Winget"
-	TypeAUR             Type = "AUR"
-	TypeCrate           Type = "Crate"
-	TypePyPI            Type = "PyPI"
-	TypeMaven           Type = "Maven"
-	TypeNuGet           Type = "NuGet"
-	TypeGem             Type = "Gem"
-	TypeHelm            Type = "Helm"
+	TypeBinary             Type = "Binary"
+	TypeArchive            Type = "Archive"
+	TypePackage            Type = "Package"
+	TypeChecksum           Type = "Checksum"
+	TypeSignature          Type = "Signature"
+	TypeCertificate        Type = "Certificate"
+	TypeAttestation        Type = "Attestation"
+	TypeProvenance         Type = "Provenance"
+	TypeLinuxPackage       Type = "Linux Package"
+	TypeDockerImage        Type = "Docker Image"
+	TypeDockerManifest     Type = "Docker Manifest"
+	TypeDockerImageArchive Type = "Docker Image Archive"
+	TypeSourceArchive      Type = "Source Archive"
+	TypeSBOM               Type = "SBOM"
+	TypeUploadable         Type = "Uploadable"
+	TypePublishable        Type = "Publishable"
+	TypeAnnounce           Type = "Announce"
+	TypeMetadata           Type = "Metadata"
+	TypeHeader             Type = "Header"
+	TypeBrewTap            Type = "Homebrew Tap"
+	TypeScoopManifest      Type = "Scoop Manifest"
+	TypeNPMPackage         Type = "NPM Package"
+	TypeDMG                Type = "DMG"
+	TypePKG                Type = "PKG"
+	TypeMSI                Type = "MSI"
+	TypeNSIS               Type = "NSIS"
+	TypeAppBundle          Type = "App Bundle"
+	TypeUniversalBinary    Type = "Universal Binary"
+	TypeFlatpak            Type = "Flatpak"
+	TypeAppImage           Type = "AppImage"
+	TypeSnap               Type = "Snap"
+	TypeChocolatey         Type = "Chocolatey"
+	TypeWinget             Type = "Winget"
+	TypeAUR                Type = "AUR"
+	TypeCrate              Type = "Crate"
+	TypePyPI               Type = "PyPI"
+	TypeMaven              Type = "Maven"
+	TypeNuGet              Type = "NuGet"
+	TypeGem                Type = "Gem"
+	TypeHelm               Type = "Helm"
+	TypeKubernetes         Type = "Kubernetes Manifest"
+	TypeDockerCompose      Type = "Docker Compose"
+	TypeDirectory          Type = "Directory"
+	TypeAndroidLibrary     Type = "Android Library"
+	TypeXCFramework        Type = "XCFramework"
+	TypeLibrary            Type = "Library"
+	TypePkgConfig          Type = "PkgConfig"
+	TypeObfuscationMap     Type = "Obfuscation Map"
 )
 
+// ExtraFormat is the Extra key holding the package format of Linux packages
+// (deb, rpm, apk or archlinux)
+const ExtraFormat = "format"
+
+// ExtraChecksum is the Extra key under which the checksum stage records an
+// artifact's digest, formatted as "algorithm:hex". The stage also records
+// each hex digest under its algorithm name, e.g. Extra["sha256"].
+const ExtraChecksum = "Checksum"
+
+// ExtraSize is the Extra key under which the size report records the size
+// of binaries, archives and packages in bytes
+const ExtraSize = "size"
+
+// ExtraPushed is the Extra key set on Docker images once they are pushed,
+// so a publish run again after a failure skips them
+const ExtraPushed = "pushed"
+
 // Artifact represents a build artifact
 type Artifact struct {
 	// Name of the artifact
@@ -75,6 +108,9 @@ type Artifact struct {
 	// Goamd64 is the AMD64 version
 	Goamd64 string `json:"goamd64,omitempty"`
 
+	// Gomips is the MIPS floating point mode
+	Gomips string `json:"gomips,omitempty"`
+
 	// BuildID is the ID of the build that created this artifact
 	BuildID string `json:"build_id,omitempty"`
 
@@ -95,11 +131,28 @@ func NewManager() *Manager {
 	}
 }
 
-// Add adds an artifact
-func (m *Manager) Add(a Artifact) {
+// Add adds an artifact. Two artifacts can't share a path: the second
+// would have overwritten the file of the first, so it is rejected.
+func (m *Manager) Add(a Artifact) error {
 	m.mu.Lock()
 	defer m.mu.Unlock()
+	if a.Path != "" {
+		for _, existing := range m.artifacts {
+			if existing.Path == a.Path {
+				return fmt.Errorf("%s %s and %s %s both write %s", existing.Type, describe(existing), a.Type, describe(a), a.Path)
+			}
+		}
+	}
 	m.artifacts = append(m.artifacts, a)
+	return nil
+}
+
+// describe names an artifact by its name and, when known, its build
+func describe(a Artifact) string {
+	if a.BuildID != "" {
+		return fmt.Sprintf("%s (build %s)", a.Name, a.BuildID)
+	}
+	return a.Name
 }
 
 // All returns all artifacts
@@ -144,6 +197,24 @@ func (m *Manager) Filter(filters ...FilterFunc) []Artifact {
 	return result
 }
 
+// FindByName returns the first artifact with the given name
+func (m *Manager) FindByName(name string) (Artifact, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	for _, a := range m.artifacts {
+		if a.Name == name {
+			return a, true
+		}
+	}
+	return Artifact{}, false
+}
+
+// ByID returns the artifacts created by the build with the given ID
+func (m *Manager) ByID(id string) []Artifact {
+	return m.Filter(ByBuildID(id))
+}
+
 // FilterFunc is a function that filters artifacts
 type FilterFunc func(Artifact) bool
 
@@ -182,6 +253,67 @@ func ByBuildID(id string) FilterFunc {
 	}
 }
 
+// ByID returns a filter for artifacts built by any of the given build IDs
+func ByID(ids ...string) FilterFunc {
+	return func(a Artifact) bool {
+		for _, id := range ids {
+			if a.BuildID == id {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// ByName returns a filter for artifact name
+func ByName(name string) FilterFunc {
+	return func(a Artifact) bool {
+		return a.Name == name
+	}
+}
+
+// ByExtra returns a filter for an Extra value. Values are compared by their
+// string form, so numbers match after a JSON round trip.
+func ByExtra(key string, value interface{}) FilterFunc {
+	want := fmt.Sprint(value)
+	return func(a Artifact) bool {
+		v, ok := a.Extra[key]
+		return ok && fmt.Sprint(v) == want
+	}
+}
+
+// And returns a filter matching artifacts that match all filters
+func And(filters ...FilterFunc) FilterFunc {
+	return func(a Artifact) bool {
+		for _, f := range filters {
+			if !f(a) {
+				return false
+			}
+		}
+		return true
+	}
+}
+
+// Or returns a filter matching artifacts that match any filter
+func Or(filters ...FilterFunc) FilterFunc {
+	return func(a Artifact) bool {
+		for _, f := range filters {
+			if f(a) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// ByFormat returns a filter for the package format recorded in Extra
+func ByFormat(format string) FilterFunc {
+	return func(a Artifact) bool {
+		f, _ := a.Extra[ExtraFormat].(string)
+		return f == format
+	}
+}
+
 // ByIf evaluates an if statement for artifact filtering
 func ByIf(expr string, ctx map[string]interface{}) FilterFunc {
 	return func(a Artifact) bool {
@@ -221,6 +353,35 @@ func (m *Manager) Load(path string) error {
 	return json.Unmarshal(data, &m.artifacts)
 }
 
+// Update applies fn to every artifact matching the filter
+func (m *Manager) Update(filter FilterFunc, fn func(*Artifact)) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for i := range m.artifacts {
+		if filter(m.artifacts[i]) {
+			fn(&m.artifacts[i])
+		}
+	}
+}
+
+// Checksum returns the digest recorded by the checksum stage for the given
+// algorithm, or an empty string if none was recorded
+func (a Artifact) Checksum(algorithm string) string {
+	if sum, ok := a.Extra[algorithm].(string); ok {
+		return sum
+	}
+	value, ok := a.Extra[ExtraChecksum].(string)
+	if !ok {
+		return ""
+	}
+	algo, sum, found := strings.Cut(value, ":")
+	if !found || algo != algorithm {
+		return ""
+	}
+	return sum
+}
+
 // Clear removes all artifacts
 func (m *Manager) Clear() {
 	m.mu.Lock()
@@ -242,18 +403,53 @@ func (m *Manager) Remove(filter FilterFunc) {
 	m.artifacts = result
 }
 
-// GroupByPlatform groups artifacts by platform (goos/goarch)
+// Replace removes artifacts matching the filter and puts the replacements
+// where the first of them was, or at the end when none matched, so stages
+// like universal binaries keep the artifact order. It returns the number of
+// artifacts removed.
+func (m *Manager) Replace(filter FilterFunc, replacements ...Artifact) int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	result := make([]Artifact, 0, len(m.artifacts)+len(replacements))
+	removed := 0
+	for _, a := range m.artifacts {
+		if !filter(a) {
+			result = append(result, a)
+			continue
+		}
+		if removed == 0 {
+			result = append(result, replacements...)
+		}
+		removed++
+	}
+	if removed == 0 {
+		result = append(result, replacements...)
+	}
+	m.artifacts = result
+	return removed
+}
+
+// Platform returns the target of the artifact in the form of build
+// directory names, e.g. linux_arm_v7 or linux_amd64_v3
+func (a Artifact) Platform() string {
+	return a.Target().String()
+}
+
+// Target returns the OS, architecture and variant the artifact was built for
+func (a Artifact) Target() target.Target {
+	return target.Target{OS: a.Goos, Arch: a.Goarch, Arm: a.Goarm, Amd64: a.Goamd64, Mips: a.Gomips}
+}
+
+// GroupByPlatform groups artifacts by platform, including the goarm,
+// goamd64 and gomips variant
 func (m *Manager) GroupByPlatform() map[string][]Artifact {
 	m.mu.RLock()
 	defer m.mu.RUnlock()
 
 	result := make(map[string][]Artifact)
 	for _, a := range m.artifacts {
-		key := a.Goos + "_" + a.Goarch
-		if a.Goarm != "" {
-			key += "_" + a.Goarm
-		}
-		result[key] = append(result[key], a)
+		result[a.Platform()] = append(result[a.Platform()], a)
 	}
 	return result
 }
@@ -4,16 +4,21 @@ Package builder provides build functionality for different languages.
 package builder
 
 import (
+	"archive/zip"
 	"bytes"
 	"context"
 	"crypto/rand"
 	"crypto/sha256"
 	"encoding/hex"
+	"encoding/json"
 	"fmt"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"runtime"
+	"slices"
+	"sort"
+	"strconv"
 	"strings"
 	"time"
 
@@ -21,6 +26,9 @@ import (
 
 	"github.com/oarkflow/releaser/internal/config"
 	"github.com/oarkflow/releaser/internal/deps"
+	"github.com/oarkflow/releaser/internal/hook"
+	"github.com/oarkflow/releaser/internal/redact"
+	"github.com/oarkflow/releaser/internal/target"
 	"github.com/oarkflow/releaser/internal/tmpl"
 )
 
@@ -30,25 +38,34 @@ type Builder interface {
 	Supports(builder string) bool
 }
 
-// Target represents a build target
-type Target struct {
-	OS    string
-	Arch  string
-	Arm   string
-	Amd64 string
-	Mips  string
+// Target is the build target of a builder
+type Target = target.Target
+
+// IsLibrary reports whether a Go build mode produces a C library
+func IsLibrary(buildmode string) bool {
+	return buildmode == "c-shared" || buildmode == "c-archive"
 }
 
-// String returns the target as a string
-func (t Target) String() string {
-	s := t.OS + "_" + t.Arch
-	if t.Arm != "" {
-		s += "_v" + t.Arm
+// LibraryName returns the file name of a library following each OS's
+// conventions: libname.so, libname.dylib and name.dll for c-shared, and
+// libname.a for c-archive. Go writes the header next to it.
+func LibraryName(buildmode, goos, name string) string {
+	if buildmode == "c-archive" {
+		return "lib" + name + ".a"
 	}
-	if t.Amd64 != "" {
-		s += "_" + t.Amd64
+	switch goos {
+	case "windows":
+		return name + ".dll"
+	case "darwin", "ios":
+		return "lib" + name + ".dylib"
+	default:
+		return "lib" + name + ".so"
 	}
-	return s
+}
+
+// templateSource names a field of a build in template errors
+func templateSource(build config.Build, field string) string {
+	return "builds." + build.ID + "." + field
 }
 
 // BuildLdFlags builds a "-ldflags" string from a map of key/value pairs.
@@ -87,6 +104,10 @@ func (b *GoBuilder) Supports(builder string) bool {
 func (b *GoBuilder) Build(ctx context.Context, build config.Build, target Target, output string, tmplCtx *tmpl.Context) error {
 	log.Info("Starting Go build", "target", target.String(), "output", output)
 
+	// Env, flags and ldflags templates see the target, e.g. {{ .Arm }}
+	tmplCtx = tmplCtx.WithArtifactInfo(filepath.Base(output), target.OS, target.Arch, target.Arm, target.Amd64)
+	tmplCtx.Set("Mips", target.Mips)
+
 	// Log build configuration details
 	log.Debug("Go build configuration",
 		"main_package", build.Main,
@@ -126,8 +147,19 @@ func (b *GoBuilder) Build(ctx context.Context, build config.Build, target Target
 		env = append(env, fmt.Sprintf("GOAMD64=%s", target.Amd64))
 	}
 	if target.Mips != "" {
-		log.Debug("Setting GOMIPS environment variable", "value", target.Mips)
-		env = append(env, fmt.Sprintf("GOMIPS=%s", target.Mips))
+		// mips64 and mips64le read GOMIPS64
+		name := "GOMIPS"
+		if strings.HasPrefix(target.Arch, "mips64") {
+			name = "GOMIPS64"
+		}
+		log.Debug("Setting "+name+" environment variable", "value", target.Mips)
+		env = append(env, fmt.Sprintf("%s=%s", name, target.Mips))
+	}
+
+	// Libraries are linked by cgo
+	if IsLibrary(build.Buildmode) && !build.Cgo.Enabled {
+		log.Debug("Enabling CGO for library build", "buildmode", build.Buildmode)
+		build.Cgo.Enabled = true
 	}
 
 	// Handle CGO configuration
@@ -187,7 +219,7 @@ func (b *GoBuilder) Build(ctx context.Context, build config.Build, target Target
 
 	// Add build-specific environment
 	for _, e := range build.Env {
-		expanded, err := tmplCtx.Apply(e)
+		expanded, err := tmplCtx.Apply(templateSource(build, "env"), e)
 		if err != nil {
 			return fmt.Errorf("failed to expand env %s: %w", e, err)
 		}
@@ -197,7 +229,7 @@ func (b *GoBuilder) Build(ctx context.Context, build config.Build, target Target
 	// Add obfuscation-specific environment
 	if build.Obfuscation.Enabled {
 		for _, e := range build.Obfuscation.Env {
-			expanded, err := tmplCtx.Apply(e)
+			expanded, err := tmplCtx.Apply(templateSource(build, "obfuscation.env"), e)
 			if err != nil {
 				return fmt.Errorf("failed to expand obfuscation env %s: %w", e, err)
 			}
@@ -210,11 +242,11 @@ func (b *GoBuilder) Build(ctx context.Context, build config.Build, target Target
 
 	// Map form -> individual -X entries
 	for key, value := range build.LdflagsMap {
-		expandedKey, err := tmplCtx.Apply(key)
+		expandedKey, err := tmplCtx.Apply(templateSource(build, "ldflags_map"), key)
 		if err != nil {
 			return fmt.Errorf("failed to expand ldflag key %s: %w", key, err)
 		}
-		expandedValue, err := tmplCtx.Apply(value)
+		expandedValue, err := tmplCtx.Apply(templateSource(build, "ldflags_map."+key), value)
 		if err != nil {
 			return fmt.Errorf("failed to expand ldflag value %s: %w", value, err)
 		}
@@ -223,7 +255,7 @@ func (b *GoBuilder) Build(ctx context.Context, build config.Build, target Target
 
 	// List form (e.g. -s -w)
 	for _, ldflag := range build.Ldflags {
-		expanded, err := tmplCtx.Apply(ldflag)
+		expanded, err := tmplCtx.Apply(templateSource(build, "ldflags"), ldflag)
 		if err != nil {
 			return fmt.Errorf("failed to expand ldflag %s: %w", ldflag, err)
 		}
@@ -233,7 +265,7 @@ func (b *GoBuilder) Build(ctx context.Context, build config.Build, target Target
 	// Prepare tags
 	var tags []string
 	for _, tag := range build.Tags {
-		expanded, err := tmplCtx.Apply(tag)
+		expanded, err := tmplCtx.Apply(templateSource(build, "tags"), tag)
 		if err != nil {
 			return fmt.Errorf("failed to expand tag %s: %w", tag, err)
 		}
@@ -255,7 +287,7 @@ func (b *GoBuilder) Build(ctx context.Context, build config.Build, target Target
 
 	// Add flags
 	for _, flag := range build.Flags {
-		expanded, err := tmplCtx.Apply(flag)
+		expanded, err := tmplCtx.Apply(templateSource(build, "flags"), flag)
 		if err != nil {
 			return fmt.Errorf("failed to expand flag %s: %w", flag, err)
 		}
@@ -288,7 +320,7 @@ func (b *GoBuilder) Build(ctx context.Context, build config.Build, target Target
 	if len(build.Asmflags) > 0 {
 		var asmflags []string
 		for _, af := range build.Asmflags {
-			expanded, err := tmplCtx.Apply(af)
+			expanded, err := tmplCtx.Apply(templateSource(build, "asmflags"), af)
 			if err != nil {
 				return fmt.Errorf("failed to expand asmflag %s: %w", af, err)
 			}
@@ -301,7 +333,7 @@ func (b *GoBuilder) Build(ctx context.Context, build config.Build, target Target
 	if len(build.Gcflags) > 0 {
 		var gcflags []string
 		for _, gf := range build.Gcflags {
-			expanded, err := tmplCtx.Apply(gf)
+			expanded, err := tmplCtx.Apply(templateSource(build, "gcflags"), gf)
 			if err != nil {
 				return fmt.Errorf("failed to expand gcflag %s: %w", gf, err)
 			}
@@ -333,7 +365,7 @@ func (b *GoBuilder) Build(ctx context.Context, build config.Build, target Target
 
 	// Run pre-build hooks
 	if build.Hooks.Pre != "" {
-		if err := runHookCmd(ctx, build.Hooks.Pre, dir, env, tmplCtx); err != nil {
+		if err := runHookCmd(ctx, templateSource(build, "hooks.pre"), build.Hooks.Pre, dir, env, tmplCtx); err != nil {
 			return fmt.Errorf("pre-build hook failed: %w", err)
 		}
 	}
@@ -351,7 +383,7 @@ func (b *GoBuilder) Build(ctx context.Context, build config.Build, target Target
 
 	log.Debug("Full command details",
 		"command", goBinary,
-		"args", args,
+		"args", redact.Args(args),
 		"working_directory", dir)
 
 	cmd := exec.CommandContext(ctx, goBinary, args...)
@@ -377,7 +409,7 @@ func (b *GoBuilder) Build(ctx context.Context, build config.Build, target Target
 	// Run post-build hooks
 	if build.Hooks.Post != "" {
 		log.Debug("Running post-build hook")
-		if err := runHookCmd(ctx, build.Hooks.Post, dir, env, tmplCtx); err != nil {
+		if err := runHookCmd(ctx, templateSource(build, "hooks.post"), build.Hooks.Post, dir, env, tmplCtx); err != nil {
 			log.Error("Post-build hook failed", "error", err)
 			return fmt.Errorf("post-build hook failed: %w", err)
 		}
@@ -456,6 +488,9 @@ func (b *GoBuilder) tryGarbleObfuscation(ctx context.Context, build config.Build
 
 	// Ensure garble is available
 	if _, err := exec.LookPath("garble"); err != nil {
+		if !deps.Installing() {
+			return &deps.MissingError{Tools: []deps.Missing{{Tool: "garble", NeededBy: "obfuscation", Install: deps.InstallCommand("garble")}}}
+		}
 		log.Info("Garble not found, installing...")
 		installCmd := exec.CommandContext(ctx, goBinary, "install", "mvdan.cc/garble@latest")
 		installCmd.Dir = dir
@@ -464,15 +499,15 @@ func (b *GoBuilder) tryGarbleObfuscation(ctx context.Context, build config.Build
 			return fmt.Errorf("failed to install garble: %w", err)
 		}
 		log.Info("Garble installed successfully")
+	} else if deps.AutoInstall && !deps.Offline {
+		// Update garble to latest version
+		log.Info("Updating garble to latest version...")
+		updateCmd := exec.CommandContext(ctx, goBinary, "install", "mvdan.cc/garble@latest")
+		updateCmd.Dir = dir
+		updateCmd.Env = env
+		updateCmd.Run() // Ignore errors, continue with available version
 	}
 
-	// Update garble to latest version
-	log.Info("Updating garble to latest version...")
-	updateCmd := exec.CommandContext(ctx, goBinary, "install", "mvdan.cc/garble@latest")
-	updateCmd.Dir = dir
-	updateCmd.Env = env
-	updateCmd.Run() // Ignore errors, continue with available version
-
 	// Clear garble cache
 	os.RemoveAll(filepath.Join(os.Getenv("GOCACHE"), "garble"))
 
@@ -482,17 +517,9 @@ func (b *GoBuilder) tryGarbleObfuscation(ctx context.Context, build config.Build
 	garbleEnv = append(garbleEnv, fmt.Sprintf("GOARCH=%s", target.Arch))
 
 	// Prepare garble flags
-	garbleFlags := []string{"-literals", "-tiny", "-seed=random"}
-
-	// Add custom flags
-	for _, flag := range build.Obfuscation.Flags {
-		expanded, err := tmplCtx.Apply(flag)
-		if err != nil {
-			return fmt.Errorf("failed to expand obfuscation flag %s: %w", flag, err)
-		}
-		if strings.TrimSpace(expanded) != "" {
-			garbleFlags = append(garbleFlags, expanded)
-		}
+	garbleFlags, err := GarbleFlags(build.Obfuscation, tmplCtx)
+	if err != nil {
+		return err
 	}
 
 	// Prepare build args with obfuscation
@@ -507,7 +534,7 @@ func (b *GoBuilder) tryGarbleObfuscation(ctx context.Context, build config.Build
 	cmd.Stdout = &stdout
 	cmd.Stderr = &stderr
 
-	log.Debug("Running garble build", "args", buildArgs)
+	log.Debug("Running garble build", "args", redact.Args(buildArgs))
 	if err := cmd.Run(); err != nil {
 		return fmt.Errorf("garble build failed: %w\n%s", err, stderr.String())
 	}
@@ -517,9 +544,67 @@ func (b *GoBuilder) tryGarbleObfuscation(ctx context.Context, build config.Build
 		return fmt.Errorf("garble build did not produce output binary")
 	}
 
+	if build.Obfuscation.ReverseMap {
+		if err := WriteReverseMap(build.Obfuscation, target, output, garbleFlags); err != nil {
+			return fmt.Errorf("failed to write reverse map: %w", err)
+		}
+	}
+
 	return nil
 }
 
+// GarbleFlags returns the garble flags of an obfuscation config: -literals
+// and -tiny unless turned off, -seed (random when unset) and the custom
+// flags
+func GarbleFlags(cfg config.GoObfuscationConfig, tmplCtx *tmpl.Context) ([]string, error) {
+	var flags []string
+	if cfg.Literals == nil || *cfg.Literals {
+		flags = append(flags, "-literals")
+	}
+	if cfg.Tiny == nil || *cfg.Tiny {
+		flags = append(flags, "-tiny")
+	}
+	seed := cfg.Seed
+	if seed == "" {
+		seed = "random"
+	}
+	flags = append(flags, "-seed="+seed)
+
+	for _, flag := range cfg.Flags {
+		expanded, err := tmplCtx.Apply("builds.obfuscation.flags", flag)
+		if err != nil {
+			return nil, fmt.Errorf("failed to expand obfuscation flag %s: %w", flag, err)
+		}
+		if strings.TrimSpace(expanded) != "" {
+			flags = append(flags, expanded)
+		}
+	}
+	return flags, nil
+}
+
+// ReverseMapPath returns where the reverse map of a binary is written
+func ReverseMapPath(output string) string {
+	return output + ".garble.json"
+}
+
+// WriteReverseMap records what garble reverse needs to translate the
+// binary's obfuscated stack traces and panics back: the seed and flags it
+// was built with
+func WriteReverseMap(cfg config.GoObfuscationConfig, target Target, output string, flags []string) error {
+	data, err := json.MarshalIndent(map[string]interface{}{
+		"tool":    "garble",
+		"binary":  filepath.Base(output),
+		"target":  target.String(),
+		"seed":    cfg.Seed,
+		"flags":   flags,
+		"reverse": fmt.Sprintf("garble %s reverse <main package> <stack trace file>", strings.Join(flags, " ")),
+	}, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(ReverseMapPath(output), append(data, '\n'), 0600)
+}
+
 // tryGobfuscateObfuscation attempts obfuscation using gobfuscate
 func (b *GoBuilder) tryGobfuscateObfuscation(ctx context.Context, build config.Build, target Target, output string, tmplCtx *tmpl.Context, env []string, dir, goBinary string, args []string, buildID string) error {
 	log.Info("Attempting gobfuscate obfuscation")
@@ -594,7 +679,7 @@ func (b *GoBuilder) comprehensiveSecurityBuild(ctx context.Context, build config
 
 	// Add existing ldflags
 	for _, ldflag := range build.Ldflags {
-		expanded, err := tmplCtx.Apply(ldflag)
+		expanded, err := tmplCtx.Apply(templateSource(build, "ldflags"), ldflag)
 		if err != nil {
 			return fmt.Errorf("failed to expand ldflag %s: %w", ldflag, err)
 		}
@@ -610,7 +695,7 @@ func (b *GoBuilder) comprehensiveSecurityBuild(ctx context.Context, build config
 
 	// Add existing gcflags
 	for _, gcflag := range build.Gcflags {
-		expanded, err := tmplCtx.Apply(gcflag)
+		expanded, err := tmplCtx.Apply(templateSource(build, "gcflags"), gcflag)
 		if err != nil {
 			return fmt.Errorf("failed to expand gcflag %s: %w", gcflag, err)
 		}
@@ -626,7 +711,7 @@ func (b *GoBuilder) comprehensiveSecurityBuild(ctx context.Context, build config
 
 	// Add existing flags
 	for _, flag := range build.Flags {
-		expanded, err := tmplCtx.Apply(flag)
+		expanded, err := tmplCtx.Apply(templateSource(build, "flags"), flag)
 		if err != nil {
 			return fmt.Errorf("failed to expand flag %s: %w", flag, err)
 		}
@@ -661,7 +746,7 @@ func (b *GoBuilder) comprehensiveSecurityBuild(ctx context.Context, build config
 	cmd.Stdout = &stdout
 	cmd.Stderr = &stderr
 
-	log.Debug("Running comprehensive security build", "args", finalArgs)
+	log.Debug("Running comprehensive security build", "args", redact.Args(finalArgs))
 	if err := cmd.Run(); err != nil {
 		return fmt.Errorf("comprehensive security build failed: %w\n%s", err, stderr.String())
 	}
@@ -805,27 +890,38 @@ func (b *GoBuilder) getCrossCompiler(cgo config.CgoConfig, targetKey, goos, goar
 	return cc, cxx, nil
 }
 
-// findCrossCompiler looks for an available cross-compiler
-func findCrossCompiler(goos, goarch, compiler string) (string, error) {
-	// Common cross-compiler prefixes
-	crossPrefixes := map[string]map[string]string{
-		"linux": {
-			"amd64": "x86_64-linux-gnu-",
-			"arm64": "aarch64-linux-gnu-",
-			"arm":   "arm-linux-gnueabihf-",
-			"386":   "i686-linux-gnu-",
-		},
-		"windows": {
-			"amd64": "x86_64-w64-mingw32-",
-			"386":   "i686-w64-mingw32-",
-		},
-		"darwin": {
-			// macOS cross-compilation typically uses osxcross
-			"amd64": "o64-clang",
-			"arm64": "oa64-clang",
-		},
+// crossPrefixes are the common cross-compiler prefixes per target
+var crossPrefixes = map[string]map[string]string{
+	"linux": {
+		"amd64": "x86_64-linux-gnu-",
+		"arm64": "aarch64-linux-gnu-",
+		"arm":   "arm-linux-gnueabihf-",
+		"386":   "i686-linux-gnu-",
+	},
+	"windows": {
+		"amd64": "x86_64-w64-mingw32-",
+		"386":   "i686-w64-mingw32-",
+	},
+	"darwin": {
+		// macOS cross-compilation typically uses osxcross
+		"amd64": "o64-clang",
+		"arm64": "oa64-clang",
+	},
+}
+
+// HasCrossCompiler reports whether a C cross-compiler for the target is
+// installed: a gcc toolchain, osxcross or zig
+func HasCrossCompiler(goos, goarch string) bool {
+	if prefix := crossPrefixes[goos][goarch]; prefix != "" {
+		if deps.IsAvailable(prefix+"gcc") || (goos == "darwin" && deps.IsAvailable(prefix)) {
+			return true
+		}
 	}
+	return getZigTarget(goos, goarch) != "" && deps.IsAvailable("zig")
+}
 
+// findCrossCompiler looks for an available cross-compiler
+func findCrossCompiler(goos, goarch, compiler string) (string, error) {
 	// Look up cross-compiler prefix
 	if osPrefixes, ok := crossPrefixes[goos]; ok {
 		if prefix, ok := osPrefixes[goarch]; ok {
@@ -905,6 +1001,9 @@ func createZigWrapper(target string, isCxx bool) (string, error) {
 	if isCxx {
 		wrapperName = fmt.Sprintf("zig-cxx-%s", strings.ReplaceAll(target, "/", "-"))
 	}
+	if runtime.GOOS == "windows" {
+		wrapperName += ".bat"
+	}
 	wrapperPath := filepath.Join(tmpDir, wrapperName)
 
 	// Check if wrapper already exists
@@ -918,6 +1017,10 @@ func createZigWrapper(target string, isCxx bool) (string, error) {
 	}
 
 	script := fmt.Sprintf("#!/bin/sh\nexec zig %s -target %s \"$@\"\n", cmd, target)
+	if runtime.GOOS == "windows" {
+		// Windows hosts can't run shell scripts; use a batch file instead
+		script = fmt.Sprintf("@echo off\r\nzig %s -target %s %%*\r\n", cmd, target)
+	}
 	if err := os.WriteFile(wrapperPath, []byte(script), 0755); err != nil {
 		return "", err
 	}
@@ -965,7 +1068,8 @@ func (b *RustBuilder) Supports(builder string) bool {
 	return builder == "rust" || builder == "cargo"
 }
 
-// Build builds a Rust binary
+// Build builds a Rust binary. Cross-compiling goes through cross when it is
+// installed, or cargo with zig as the target's linker.
 func (b *RustBuilder) Build(ctx context.Context, build config.Build, target Target, output string, tmplCtx *tmpl.Context) error {
 	log.Debug("Building Rust binary", "target", target.String(), "output", output)
 
@@ -974,23 +1078,63 @@ func (b *RustBuilder) Build(ctx context.Context, build config.Build, target Targ
 	if triple == "" {
 		return fmt.Errorf("unsupported Rust target: %s", target.String())
 	}
+	native := target.OS == runtime.GOOS && target.Arch == runtime.GOARCH
+	if target.OS == "windows" && runtime.GOOS != "windows" {
+		// The msvc toolchain only links on Windows
+		triple = strings.Replace(triple, "-msvc", "-gnu", 1)
+		if target.Arch == "arm64" {
+			triple += "llvm"
+		}
+	}
+
+	// Target overrides replace flags and ldflags and add to env
+	envs, flags, ldflags := build.Env, build.Flags, build.Ldflags
+	if override := matchOverride(build.Overrides, target); override != nil {
+		log.Debug("Applying build override", "target", target.String())
+		envs = append(append([]string{}, envs...), override.Env...)
+		if len(override.Flags) > 0 {
+			flags = override.Flags
+		}
+		if len(override.Ldflags) > 0 {
+			ldflags = override.Ldflags
+		}
+	}
 
 	// Prepare environment
 	env := os.Environ()
-	for _, e := range build.Env {
-		expanded, err := tmplCtx.Apply(e)
+	for _, e := range envs {
+		expanded, err := tmplCtx.Apply(templateSource(build, "env"), e)
 		if err != nil {
 			return fmt.Errorf("failed to expand env %s: %w", e, err)
 		}
 		env = append(env, expanded)
 	}
 
+	// Ldflags are passed to rustc
+	if len(ldflags) > 0 {
+		rustflags := envValue(env, "RUSTFLAGS")
+		for _, flag := range ldflags {
+			expanded, err := tmplCtx.Apply(templateSource(build, "ldflags"), flag)
+			if err != nil {
+				return fmt.Errorf("failed to expand ldflag %s: %w", flag, err)
+			}
+			rustflags = strings.TrimSpace(rustflags + " " + expanded)
+		}
+		env = append(env, "RUSTFLAGS="+rustflags)
+	}
+
 	// Build arguments
 	args := []string{"build", "--release", "--target", triple}
+	if build.Package != "" {
+		args = append(args, "-p", build.Package)
+	}
+	if build.Bin != "" {
+		args = append(args, "--bin", build.Bin)
+	}
 
 	// Add flags
-	for _, flag := range build.Flags {
-		expanded, err := tmplCtx.Apply(flag)
+	for _, flag := range flags {
+		expanded, err := tmplCtx.Apply(templateSource(build, "flags"), flag)
 		if err != nil {
 			return fmt.Errorf("failed to expand flag %s: %w", flag, err)
 		}
@@ -1003,9 +1147,32 @@ func (b *RustBuilder) Build(ctx context.Context, build config.Build, target Targ
 		dir, _ = os.Getwd()
 	}
 
+	// cross has no images for Apple targets, zig links those
+	cargo := "cargo"
+	if !native {
+		if _, err := exec.LookPath("cross"); err == nil && target.OS != "darwin" {
+			cargo = "cross"
+		} else if zigTarget := getZigTarget(target.OS, target.Arch); zigTarget != "" {
+			if _, err := exec.LookPath("zig"); err == nil {
+				linker, err := createZigWrapper(zigTarget, false)
+				if err != nil {
+					return fmt.Errorf("failed to create zig linker: %w", err)
+				}
+				key := strings.ToUpper(strings.ReplaceAll(triple, "-", "_"))
+				if envValue(env, "CARGO_TARGET_"+key+"_LINKER") == "" {
+					env = append(env, "CARGO_TARGET_"+key+"_LINKER="+linker)
+				}
+				// Build scripts compiling C code use the same compiler
+				if cc := "CC_" + strings.ReplaceAll(triple, "-", "_"); envValue(env, cc) == "" {
+					env = append(env, cc+"="+linker)
+				}
+			}
+		}
+	}
+
 	// Run build
-	log.Debug("Running cargo build", "args", args)
-	cmd := exec.CommandContext(ctx, "cargo", args...)
+	log.Debug("Running cargo build", "cargo", cargo, "args", redact.Args(args))
+	cmd := exec.CommandContext(ctx, cargo, args...)
 	cmd.Dir = dir
 	cmd.Env = env
 
@@ -1014,19 +1181,27 @@ func (b *RustBuilder) Build(ctx context.Context, build config.Build, target Targ
 	cmd.Stderr = &stderr
 
 	if err := cmd.Run(); err != nil {
-		return fmt.Errorf("cargo build failed: %w\n%s", err, stderr.String())
+		return fmt.Errorf("%s build failed: %w\n%s", cargo, err, stderr.String())
 	}
 
 	// Copy binary to output location
-	binaryName := build.Binary
+	binaryName := build.Bin
 	if binaryName == "" {
-		binaryName = filepath.Base(dir)
+		name, err := cargoBinary(dir, build.Package)
+		switch {
+		case err == nil:
+			binaryName = name
+		case build.Binary != "":
+			binaryName = build.Binary
+		default:
+			return err
+		}
 	}
 	if target.OS == "windows" {
 		binaryName += ".exe"
 	}
 
-	srcPath := filepath.Join(dir, "target", triple, "release", binaryName)
+	srcPath := filepath.Join(cargoTargetDir(dir, env), triple, "release", binaryName)
 	if err := copyFile(srcPath, output); err != nil {
 		return fmt.Errorf("failed to copy binary: %w", err)
 	}
@@ -1035,6 +1210,165 @@ func (b *RustBuilder) Build(ctx context.Context, build config.Build, target Targ
 	return nil
 }
 
+// matchOverride returns the last override matching the target
+func matchOverride(overrides []config.BuildOverride, target Target) *config.BuildOverride {
+	var match *config.BuildOverride
+	for i, o := range overrides {
+		if (o.Goos != "" && o.Goos != target.OS) || (o.Goarch != "" && o.Goarch != target.Arch) {
+			continue
+		}
+		if (o.Goarm != "" && o.Goarm != target.Arm) || (o.Goamd64 != "" && o.Goamd64 != target.Amd64) {
+			continue
+		}
+		match = &overrides[i]
+	}
+	return match
+}
+
+// envValue returns the last value of key in env
+func envValue(env []string, key string) string {
+	for i := len(env) - 1; i >= 0; i-- {
+		if value, ok := strings.CutPrefix(env[i], key+"="); ok {
+			return value
+		}
+	}
+	return ""
+}
+
+// cargoTargetDir returns the directory cargo builds into: CARGO_TARGET_DIR,
+// or target in the workspace root
+func cargoTargetDir(dir string, env []string) string {
+	if targetDir := envValue(env, "CARGO_TARGET_DIR"); targetDir != "" {
+		if filepath.IsAbs(targetDir) {
+			return targetDir
+		}
+		return filepath.Join(dir, targetDir)
+	}
+	root := dir
+	for d := dir; ; {
+		if manifest, err := readCargoManifest(filepath.Join(d, "Cargo.toml")); err == nil && manifest.workspace {
+			root = d
+			break
+		}
+		parent := filepath.Dir(d)
+		if parent == d {
+			break
+		}
+		d = parent
+	}
+	return filepath.Join(root, "target")
+}
+
+// cargoBinary returns the binary a package builds: its first [[bin]] or its
+// package name. In a workspace the package is looked up among the members.
+func cargoBinary(dir, pkg string) (string, error) {
+	manifest, err := readCargoManifest(filepath.Join(dir, "Cargo.toml"))
+	if err != nil {
+		return "", fmt.Errorf("failed to read Cargo.toml, set binary or bin: %w", err)
+	}
+	if pkg != "" && manifest.name != pkg {
+		found := false
+		for _, member := range manifest.members {
+			paths, _ := filepath.Glob(filepath.Join(dir, member, "Cargo.toml"))
+			for _, path := range paths {
+				if m, err := readCargoManifest(path); err == nil && m.name == pkg {
+					manifest, found = m, true
+					break
+				}
+			}
+			if found {
+				break
+			}
+		}
+		if !found {
+			return "", fmt.Errorf("package %s not found in the cargo workspace", pkg)
+		}
+	}
+	for _, bin := range manifest.bins {
+		if bin != "" {
+			return bin, nil
+		}
+	}
+	if manifest.name == "" {
+		return "", fmt.Errorf("no binary in %s, set package or bin", filepath.Join(dir, "Cargo.toml"))
+	}
+	return manifest.name, nil
+}
+
+// cargoManifest holds the Cargo.toml fields needed to find binaries
+type cargoManifest struct {
+	name      string
+	bins      []string
+	workspace bool
+	members   []string
+}
+
+// readCargoManifest reads package, bin and workspace fields of a Cargo.toml.
+// It understands the plain key = value and array forms cargo init writes,
+// not all of TOML.
+func readCargoManifest(path string) (cargoManifest, error) {
+	var manifest cargoManifest
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return manifest, err
+	}
+
+	section := ""
+	var array *[]string
+	for _, line := range strings.Split(string(data), "\n") {
+		if i := strings.Index(line, "#"); i >= 0 && !strings.Contains(line[:i], `"`) {
+			line = line[:i]
+		}
+		line = strings.TrimSpace(line)
+		if array != nil {
+			// Continuation of a multi-line array
+			*array = append(*array, tomlStrings(line)...)
+			if strings.Contains(line, "]") {
+				array = nil
+			}
+			continue
+		}
+		if strings.HasPrefix(line, "[") {
+			section = strings.Trim(line, "[] ")
+			if section == "bin" {
+				manifest.bins = append(manifest.bins, "")
+			}
+			if section == "workspace" {
+				manifest.workspace = true
+			}
+			continue
+		}
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			continue
+		}
+		key, value = strings.TrimSpace(key), strings.TrimSpace(value)
+		switch {
+		case section == "package" && key == "name":
+			manifest.name = strings.Trim(value, `"'`)
+		case section == "bin" && key == "name":
+			manifest.bins[len(manifest.bins)-1] = strings.Trim(value, `"'`)
+		case section == "workspace" && key == "members":
+			manifest.members = append(manifest.members, tomlStrings(value)...)
+			if !strings.Contains(value, "]") {
+				array = &manifest.members
+			}
+		}
+	}
+	return manifest, nil
+}
+
+// tomlStrings returns the quoted strings of an array line
+func tomlStrings(line string) []string {
+	var values []string
+	for _, field := range strings.Split(strings.Trim(line, "[] "), ",") {
+		if field = strings.Trim(strings.TrimSpace(field), `"'`); field != "" {
+			values = append(values, field)
+		}
+	}
+	return values
+}
+
 // NodeBuilder builds Node.js packages
 type NodeBuilder struct{}
 
@@ -1048,9 +1382,10 @@ func (b *NodeBuilder) Supports(builder string) bool {
 	return builder == "node" || builder == "npm" || builder == "yarn" || builder == "pnpm"
 }
 
-// Build builds a Node.js package
+// Build builds a self-contained Node.js executable with pkg, nexe or a
+// single executable application
 func (b *NodeBuilder) Build(ctx context.Context, build config.Build, target Target, output string, tmplCtx *tmpl.Context) error {
-	log.Debug("Building Node.js package", "target", target.String(), "output", output)
+	log.Debug("Building Node.js executable", "target", target.String(), "output", output)
 
 	// Determine package manager
 	pm := "npm"
@@ -1061,13 +1396,21 @@ func (b *NodeBuilder) Build(ctx context.Context, build config.Build, target Targ
 		pm = "pnpm"
 	}
 
+	bundler := build.Node.Bundler
+	if bundler == "" {
+		bundler = "pkg"
+	}
+	if bundler != "pkg" && bundler != "nexe" && bundler != "sea" {
+		return fmt.Errorf("unknown node bundler %q, use pkg, nexe or sea", bundler)
+	}
+
 	// Prepare environment
 	env := os.Environ()
 	env = append(env, fmt.Sprintf("npm_config_target_platform=%s", target.OS))
 	env = append(env, fmt.Sprintf("npm_config_target_arch=%s", nodeArch(target.Arch)))
 
 	for _, e := range build.Env {
-		expanded, err := tmplCtx.Apply(e)
+		expanded, err := tmplCtx.Apply(templateSource(build, "env"), e)
 		if err != nil {
 			return fmt.Errorf("failed to expand env %s: %w", e, err)
 		}
@@ -1093,106 +1436,909 @@ func (b *NodeBuilder) Build(ctx context.Context, build config.Build, target Targ
 		return fmt.Errorf("failed to install dependencies: %w", err)
 	}
 
-	// Run build
-	buildArgs := []string{"run", "build"}
+	// Run the build script, if any, before bundling its output
+	pkg, _ := readPackageJSON(dir)
+	if _, ok := pkg.Scripts["build"]; ok {
+		cmd := exec.CommandContext(ctx, pm, "run", "build")
+		cmd.Dir = dir
+		cmd.Env = env
+
+		var stderr bytes.Buffer
+		cmd.Stderr = &stderr
+		if err := cmd.Run(); err != nil {
+			return fmt.Errorf("%s run build failed: %w\n%s", pm, err, stderr.String())
+		}
+	}
+
+	var flags []string
 	for _, flag := range build.Flags {
-		expanded, err := tmplCtx.Apply(flag)
+		expanded, err := tmplCtx.Apply(templateSource(build, "flags"), flag)
 		if err != nil {
 			return fmt.Errorf("failed to expand flag %s: %w", flag, err)
 		}
-		buildArgs = append(buildArgs, expanded)
+		flags = append(flags, expanded)
 	}
 
-	cmd := exec.CommandContext(ctx, pm, buildArgs...)
-	cmd.Dir = dir
-	cmd.Env = env
+	if err := os.MkdirAll(filepath.Dir(output), 0755); err != nil {
+		return err
+	}
 
-	var stdout, stderr bytes.Buffer
-	cmd.Stdout = &stdout
-	cmd.Stderr = &stderr
+	var err error
+	switch bundler {
+	case "pkg":
+		err = b.pkg(ctx, build, target, output, dir, env, flags)
+	case "nexe":
+		err = b.nexe(ctx, build, target, output, dir, env, flags)
+	case "sea":
+		err = b.sea(ctx, build, target, output, dir, env, flags, tmplCtx)
+	}
+	if err != nil {
+		return err
+	}
 
-	if err := cmd.Run(); err != nil {
-		return fmt.Errorf("npm build failed: %w\n%s", err, stderr.String())
+	if _, err := os.Stat(output); err != nil {
+		return fmt.Errorf("%s produced no executable at %s", bundler, output)
 	}
 
-	log.Info("Built package", "output", output)
+	log.Info("Built binary", "output", output, "bundler", bundler)
 	return nil
 }
 
-// PythonBuilder builds Python packages
-type PythonBuilder struct{}
+// pkg bundles the project with pkg. Without main, pkg reads the bin and pkg
+// fields of package.json.
+func (b *NodeBuilder) pkg(ctx context.Context, build config.Build, target Target, output, dir string, env, flags []string) error {
+	bin, err := nodeTool(dir, "pkg", "npm install --save-dev @yao-pkg/pkg")
+	if err != nil {
+		return err
+	}
 
-// NewPythonBuilder creates a new Python builder
-func NewPythonBuilder() *PythonBuilder {
-	return &PythonBuilder{}
+	platform := map[string]string{"linux": "linux", "darwin": "macos", "windows": "win", "freebsd": "freebsd"}[target.OS]
+	if platform == "" {
+		return fmt.Errorf("pkg does not support %s", target.OS)
+	}
+	pkgTarget := platform + "-" + nodeArch(target.Arch)
+	if version := build.Node.Version; version != "" {
+		pkgTarget = "node" + strings.TrimPrefix(version, "node") + "-" + pkgTarget
+	}
+
+	entry := build.Main
+	if entry == "" {
+		entry = "."
+	}
+	args := append([]string{entry, "--targets", pkgTarget, "--output", output}, flags...)
+	return runNodeTool(ctx, bin, args, dir, env)
 }
 
-// Supports returns true if this builder supports the given builder type
-func (b *PythonBuilder) Supports(builder string) bool {
-	return builder == "python" || builder == "pip" || builder == "poetry" || builder == "pyinstaller"
+// nexe bundles the entry point with nexe
+func (b *NodeBuilder) nexe(ctx context.Context, build config.Build, target Target, output, dir string, env, flags []string) error {
+	bin, err := nodeTool(dir, "nexe", "npm install --save-dev nexe")
+	if err != nil {
+		return err
+	}
+
+	platform := map[string]string{"linux": "linux", "darwin": "mac", "windows": "windows"}[target.OS]
+	if platform == "" {
+		return fmt.Errorf("nexe does not support %s", target.OS)
+	}
+	arch := nodeArch(target.Arch)
+	if arch == "ia32" {
+		arch = "x86"
+	}
+	nexeTarget := platform + "-" + arch
+	if version := build.Node.Version; version != "" {
+		nexeTarget += "-" + strings.TrimPrefix(version, "v")
+	}
+
+	entry, err := nodeEntry(dir, build.Main)
+	if err != nil {
+		return err
+	}
+	args := append([]string{entry, "--target", nexeTarget, "--output", output}, flags...)
+	return runNodeTool(ctx, bin, args, dir, env)
 }
 
-// Build builds a Python package
-func (b *PythonBuilder) Build(ctx context.Context, build config.Build, target Target, output string, tmplCtx *tmpl.Context) error {
-	log.Debug("Building Python package", "target", target.String(), "output", output)
+// seaFuse is the sentinel Node looks for to find an injected blob
+const seaFuse = "NODE_SEA_FUSE_fce680ab2cc467b6e072b8b5df1996b2"
 
-	// Prepare environment
-	env := os.Environ()
-	for _, e := range build.Env {
-		expanded, err := tmplCtx.Apply(e)
+// sea builds a single executable application (Node 20+): the entry point is
+// turned into a blob and injected into a copy of the target's node binary
+func (b *NodeBuilder) sea(ctx context.Context, build config.Build, target Target, output, dir string, env, flags []string, tmplCtx *tmpl.Context) error {
+	postject, err := nodeTool(dir, "postject", "npm install --save-dev postject")
+	if err != nil {
+		return err
+	}
+
+	// The executable must be the node binary of the target platform
+	node := build.Node.Binary
+	if node != "" {
+		node, err = tmplCtx.WithArtifactInfo(filepath.Base(output), target.OS, target.Arch, target.Arm, target.Amd64).Apply(templateSource(build, "node.binary"), node)
 		if err != nil {
-			return fmt.Errorf("failed to expand env %s: %w", e, err)
+			return fmt.Errorf("failed to expand node binary: %w", err)
 		}
-		env = append(env, expanded)
+	} else if target.OS == runtime.GOOS && target.Arch == runtime.GOARCH {
+		node, err = exec.LookPath("node")
+		if err != nil {
+			return fmt.Errorf("node not found: install Node.js 20 or later")
+		}
+	} else {
+		return fmt.Errorf("sea builds for %s need the target's node executable, set node.binary", target.String())
 	}
 
-	// Determine working directory
-	dir := build.Dir
-	if dir == "" {
-		dir, _ = os.Getwd()
+	entry, err := nodeEntry(dir, build.Main)
+	if err != nil {
+		return err
 	}
 
-	// Determine build tool
-	switch build.Builder {
-	case "poetry":
-		// Poetry build
-		args := []string{"build"}
-		for _, flag := range build.Flags {
-			expanded, err := tmplCtx.Apply(flag)
-			if err != nil {
-				return fmt.Errorf("failed to expand flag %s: %w", flag, err)
-			}
-			args = append(args, expanded)
-		}
+	tmpDir, err := os.MkdirTemp("", "releaser-sea-*")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tmpDir)
 
-		cmd := exec.CommandContext(ctx, "poetry", args...)
-		cmd.Dir = dir
-		cmd.Env = env
-		if err := cmd.Run(); err != nil {
-			return fmt.Errorf("poetry build failed: %w", err)
-		}
-	case "pyinstaller":
-		// PyInstaller build
-		args := []string{"--onefile", "--distpath", filepath.Dir(output)}
-		if target.OS == "windows" {
-			args = append(args, "--name", strings.TrimSuffix(filepath.Base(output), ".exe"))
-		} else {
-			args = append(args, "--name", filepath.Base(output))
-		}
+	blob := filepath.Join(tmpDir, "sea-prep.blob")
+	seaConfig := filepath.Join(tmpDir, "sea-config.json")
+	data, err := json.Marshal(map[string]interface{}{
+		"main":                          filepath.Join(dir, entry),
+		"output":                        blob,
+		"disableExperimentalSEAWarning": true,
+	})
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(seaConfig, data, 0644); err != nil {
+		return err
+	}
 
-		for _, flag := range build.Flags {
-			expanded, err := tmplCtx.Apply(flag)
-			if err != nil {
-				return fmt.Errorf("failed to expand flag %s: %w", flag, err)
-			}
-			args = append(args, expanded)
-		}
+	// The blob is generated by the host node, which must match the target's
+	// major version
+	if err := runNodeTool(ctx, "node", []string{"--experimental-sea-config", seaConfig}, dir, env); err != nil {
+		return err
+	}
+	if err := copyFile(node, output); err != nil {
+		return fmt.Errorf("failed to copy node binary: %w", err)
+	}
+	if err := os.Chmod(output, 0755); err != nil {
+		return err
+	}
 
-		main := build.Main
-		if main == "" {
-			main = "main.py"
+	codesign, _ := exec.LookPath("codesign")
+	if target.OS == "darwin" && codesign != "" {
+		if err := runNodeTool(ctx, codesign, []string{"--remove-signature", output}, dir, env); err != nil {
+			return err
 		}
-		args = append(args, main)
+	}
+
+	args := []string{output, "NODE_SEA_BLOB", blob, "--sentinel-fuse", seaFuse}
+	if target.OS == "darwin" {
+		args = append(args, "--macho-segment-name", "NODE_SEA")
+	}
+	if err := runNodeTool(ctx, postject, append(args, flags...), dir, env); err != nil {
+		return err
+	}
+
+	// Apple Silicon refuses to run unsigned binaries
+	if target.OS == "darwin" && codesign != "" {
+		return runNodeTool(ctx, codesign, []string{"--sign", "-", output}, dir, env)
+	}
+	return nil
+}
+
+// packageJSON holds the package.json fields the node builder reads
+type packageJSON struct {
+	Main    string            `json:"main"`
+	Bin     json.RawMessage   `json:"bin"`
+	Scripts map[string]string `json:"scripts"`
+}
+
+// readPackageJSON reads dir/package.json
+func readPackageJSON(dir string) (packageJSON, error) {
+	var pkg packageJSON
+	data, err := os.ReadFile(filepath.Join(dir, "package.json"))
+	if err != nil {
+		return pkg, err
+	}
+	err = json.Unmarshal(data, &pkg)
+	return pkg, err
+}
+
+// nodeEntry returns the entry script: main, else the package.json bin or
+// main, else index.js
+func nodeEntry(dir, main string) (string, error) {
+	if main != "" {
+		return main, nil
+	}
+	pkg, err := readPackageJSON(dir)
+	if err != nil && !os.IsNotExist(err) {
+		return "", fmt.Errorf("failed to read package.json: %w", err)
+	}
+
+	var bin string
+	if json.Unmarshal(pkg.Bin, &bin) != nil {
+		var bins map[string]string
+		if json.Unmarshal(pkg.Bin, &bins) == nil {
+			names := make([]string, 0, len(bins))
+			for name := range bins {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+			if len(names) > 0 {
+				bin = bins[names[0]]
+			}
+		}
+	}
+	switch {
+	case bin != "":
+		return bin, nil
+	case pkg.Main != "":
+		return pkg.Main, nil
+	default:
+		return "index.js", nil
+	}
+}
+
+// nodeTool finds a bundler installed in the project or on PATH
+func nodeTool(dir, name, install string) (string, error) {
+	local := filepath.Join(dir, "node_modules", ".bin", name)
+	if runtime.GOOS == "windows" {
+		local += ".cmd"
+	}
+	if _, err := os.Stat(local); err == nil {
+		return local, nil
+	}
+	if path, err := exec.LookPath(name); err == nil {
+		return path, nil
+	}
+	return "", fmt.Errorf("%s not found: install it with `%s`", name, install)
+}
+
+// runNodeTool runs a bundler command, returning its output on failure
+func runNodeTool(ctx context.Context, bin string, args []string, dir string, env []string) error {
+	log.Debug("Running", "cmd", bin, "args", redact.Args(args))
+	cmd := exec.CommandContext(ctx, bin, args...)
+	cmd.Dir = dir
+	cmd.Env = env
+
+	var out bytes.Buffer
+	cmd.Stdout = &out
+	cmd.Stderr = &out
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("%s failed: %w\n%s", filepath.Base(bin), err, out.String())
+	}
+	return nil
+}
+
+// DenoBuilder compiles Deno programs into executables
+type DenoBuilder struct{}
+
+// NewDenoBuilder creates a new Deno builder
+func NewDenoBuilder() *DenoBuilder {
+	return &DenoBuilder{}
+}
+
+// Supports returns true if this builder supports the given builder type
+func (b *DenoBuilder) Supports(builder string) bool {
+	return builder == "deno"
+}
+
+// Build compiles the entry point with deno compile
+func (b *DenoBuilder) Build(ctx context.Context, build config.Build, target Target, output string, tmplCtx *tmpl.Context) error {
+	log.Debug("Building Deno executable", "target", target.String(), "output", output)
+
+	triple := denoTarget(target)
+	if triple == "" {
+		return fmt.Errorf("deno compile does not support %s", target.String())
+	}
+
+	main := build.Main
+	if main == "" {
+		main = "main.ts"
+	}
+	// Flags such as permissions must come before the script, anything after
+	// it is passed to the program
+	args := []string{"compile", "--target", triple, "--output", output}
+	if err := runCompiler(ctx, "deno", build, args, main, tmplCtx); err != nil {
+		return err
+	}
+
+	log.Info("Built binary", "output", output)
+	return nil
+}
+
+// BunBuilder compiles Bun programs into executables
+type BunBuilder struct{}
+
+// NewBunBuilder creates a new Bun builder
+func NewBunBuilder() *BunBuilder {
+	return &BunBuilder{}
+}
+
+// Supports returns true if this builder supports the given builder type
+func (b *BunBuilder) Supports(builder string) bool {
+	return builder == "bun"
+}
+
+// Build compiles the entry point with bun build --compile
+func (b *BunBuilder) Build(ctx context.Context, build config.Build, target Target, output string, tmplCtx *tmpl.Context) error {
+	log.Debug("Building Bun executable", "target", target.String(), "output", output)
+
+	bunTarget := bunTarget(target)
+	if bunTarget == "" {
+		return fmt.Errorf("bun build --compile does not support %s", target.String())
+	}
+
+	main := build.Main
+	if main == "" {
+		main = "index.ts"
+	}
+	args := []string{"build", "--compile", "--target", bunTarget, "--outfile", output}
+	if err := runCompiler(ctx, "bun", build, args, main, tmplCtx); err != nil {
+		return err
+	}
+
+	log.Info("Built binary", "output", output)
+	return nil
+}
+
+// runCompiler runs a compiler in the build directory with the templated
+// env, then args, flags and the entry point
+func runCompiler(ctx context.Context, compiler string, build config.Build, args []string, main string, tmplCtx *tmpl.Context) error {
+	if _, err := exec.LookPath(compiler); err != nil {
+		return fmt.Errorf("%s not found in PATH", compiler)
+	}
+
+	env := os.Environ()
+	for _, e := range build.Env {
+		expanded, err := tmplCtx.Apply(templateSource(build, "env"), e)
+		if err != nil {
+			return fmt.Errorf("failed to expand env %s: %w", e, err)
+		}
+		env = append(env, expanded)
+	}
+
+	for _, flag := range build.Flags {
+		expanded, err := tmplCtx.Apply(templateSource(build, "flags"), flag)
+		if err != nil {
+			return fmt.Errorf("failed to expand flag %s: %w", flag, err)
+		}
+		args = append(args, expanded)
+	}
+	args = append(args, main)
+
+	dir := build.Dir
+	if dir == "" {
+		dir, _ = os.Getwd()
+	}
+
+	log.Debug("Running "+compiler, "args", redact.Args(args))
+	cmd := exec.CommandContext(ctx, compiler, args...)
+	cmd.Dir = dir
+	cmd.Env = env
+
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("%s failed: %w\n%s", strings.Join(cmd.Args[:2], " "), err, stderr.String())
+	}
+
+	return nil
+}
+
+// CheckTarget reports targets a builder cannot produce, so a release fails
+// before building anything rather than midway
+func CheckTarget(builder string, target Target) error {
+	switch {
+	case builder == "deno" && denoTarget(target) == "":
+		return fmt.Errorf("deno compile does not support %s", target.String())
+	case builder == "bun" && bunTarget(target) == "":
+		return fmt.Errorf("bun build --compile does not support %s", target.String())
+	case builder == "dotnet" && dotnetRID(target) == "":
+		return fmt.Errorf("dotnet publish does not support %s", target.String())
+	}
+	return nil
+}
+
+// denoTarget returns the deno compile target for a target
+func denoTarget(target Target) string {
+	return map[string]string{
+		"linux_amd64":   "x86_64-unknown-linux-gnu",
+		"linux_arm64":   "aarch64-unknown-linux-gnu",
+		"darwin_amd64":  "x86_64-apple-darwin",
+		"darwin_arm64":  "aarch64-apple-darwin",
+		"windows_amd64": "x86_64-pc-windows-msvc",
+	}[target.OS+"_"+target.Arch]
+}
+
+// bunTarget returns the bun build --compile target for a target
+func bunTarget(target Target) string {
+	return map[string]string{
+		"linux_amd64":   "bun-linux-x64",
+		"linux_arm64":   "bun-linux-arm64",
+		"darwin_amd64":  "bun-darwin-x64",
+		"darwin_arm64":  "bun-darwin-arm64",
+		"windows_amd64": "bun-windows-x64",
+	}[target.OS+"_"+target.Arch]
+}
+
+// DotnetBuilder publishes .NET applications
+type DotnetBuilder struct{}
+
+// NewDotnetBuilder creates a new .NET builder
+func NewDotnetBuilder() *DotnetBuilder {
+	return &DotnetBuilder{}
+}
+
+// Supports returns true if this builder supports the given builder type
+func (b *DotnetBuilder) Supports(builder string) bool {
+	return builder == "dotnet"
+}
+
+// PublishesDirectory reports whether a build produces a directory rather
+// than a single binary: .NET builds that are not single-file publish the
+// executable next to its assemblies
+func PublishesDirectory(build config.Build) bool {
+	return build.Builder == "dotnet" && !build.Dotnet.SingleFile
+}
+
+// Build runs dotnet publish for the target's runtime identifier. A
+// single-file build is copied to output; otherwise the published directory
+// is copied to output's directory with the executable renamed to output.
+func (b *DotnetBuilder) Build(ctx context.Context, build config.Build, target Target, output string, tmplCtx *tmpl.Context) error {
+	log.Debug("Building .NET application", "target", target.String(), "output", output)
+
+	rid := dotnetRID(target)
+	if rid == "" {
+		return fmt.Errorf("dotnet publish does not support %s", target.String())
+	}
+	opts := build.Dotnet
+	if opts.Trimmed && !opts.SelfContained {
+		return fmt.Errorf("trimmed .NET builds must be self_contained")
+	}
+
+	env := os.Environ()
+	for _, e := range build.Env {
+		expanded, err := tmplCtx.Apply(templateSource(build, "env"), e)
+		if err != nil {
+			return fmt.Errorf("failed to expand env %s: %w", e, err)
+		}
+		env = append(env, expanded)
+	}
+
+	dir := build.Dir
+	if dir == "" {
+		dir, _ = os.Getwd()
+	}
+
+	publishDir, err := os.MkdirTemp("", "releaser-dotnet-*")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(publishDir)
+
+	args := []string{"publish"}
+	if build.Main != "" {
+		args = append(args, build.Main)
+	}
+	args = append(args,
+		"-c", "Release",
+		"-r", rid,
+		"-o", publishDir,
+		"--self-contained", fmt.Sprintf("%t", opts.SelfContained),
+		"-p:Version="+strings.TrimPrefix(tmplCtx.Get("Version"), "v"),
+	)
+	if opts.Framework != "" {
+		args = append(args, "-f", opts.Framework)
+	}
+	if opts.SingleFile {
+		args = append(args, "-p:PublishSingleFile=true")
+	}
+	if opts.Trimmed {
+		args = append(args, "-p:PublishTrimmed=true")
+	}
+	for _, flag := range build.Flags {
+		expanded, err := tmplCtx.Apply(templateSource(build, "flags"), flag)
+		if err != nil {
+			return fmt.Errorf("failed to expand flag %s: %w", flag, err)
+		}
+		args = append(args, expanded)
+	}
+
+	log.Debug("Running dotnet publish", "args", redact.Args(args))
+	cmd := exec.CommandContext(ctx, "dotnet", args...)
+	cmd.Dir = dir
+	cmd.Env = env
+
+	var out bytes.Buffer
+	cmd.Stdout = &out
+	cmd.Stderr = &out
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("dotnet publish failed: %w\n%s", err, out.String())
+	}
+
+	project := build.Main
+	if project == "" {
+		project = dir
+	}
+	exe, err := dotnetExecutable(publishDir, project, target.OS == "windows")
+	if err != nil {
+		return err
+	}
+
+	if opts.SingleFile {
+		if err := copyFile(filepath.Join(publishDir, exe), output); err != nil {
+			return fmt.Errorf("failed to copy binary: %w", err)
+		}
+		log.Info("Built binary", "output", output)
+		return nil
+	}
+
+	outputDir := filepath.Dir(output)
+	err = filepath.Walk(publishDir, func(path string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return err
+		}
+		rel, err := filepath.Rel(publishDir, path)
+		if err != nil {
+			return err
+		}
+		dst := filepath.Join(outputDir, rel)
+		if rel == exe {
+			dst = output
+		}
+		if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+			return err
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		return os.WriteFile(dst, data, info.Mode().Perm())
+	})
+	if err != nil {
+		return fmt.Errorf("failed to copy published files: %w", err)
+	}
+	if err := os.Chmod(output, 0755); err != nil {
+		return err
+	}
+
+	log.Info("Built application directory", "output", outputDir)
+	return nil
+}
+
+// dotnetRID returns the .NET runtime identifier for a target
+func dotnetRID(target Target) string {
+	return map[string]string{
+		"linux_amd64":   "linux-x64",
+		"linux_arm64":   "linux-arm64",
+		"linux_arm":     "linux-arm",
+		"darwin_amd64":  "osx-x64",
+		"darwin_arm64":  "osx-arm64",
+		"windows_amd64": "win-x64",
+		"windows_arm64": "win-arm64",
+		"windows_386":   "win-x86",
+	}[target.OS+"_"+target.Arch]
+}
+
+// dotnetExecutable finds the apphost dotnet publish wrote: the only
+// executable in dir, or the one named after the project file or directory
+func dotnetExecutable(dir, project string, windows bool) (string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return "", err
+	}
+
+	var candidates []string
+	for _, e := range entries {
+		info, err := e.Info()
+		if err != nil || e.IsDir() {
+			continue
+		}
+		name := e.Name()
+		if windows {
+			if strings.HasSuffix(name, ".exe") && name != "createdump.exe" {
+				candidates = append(candidates, name)
+			}
+		} else if info.Mode()&0111 != 0 && filepath.Ext(name) == "" && name != "createdump" {
+			candidates = append(candidates, name)
+		}
+	}
+	if len(candidates) == 1 {
+		return candidates[0], nil
+	}
+
+	// Project is a project file or a directory named after it
+	name := filepath.Base(project)
+	switch filepath.Ext(name) {
+	case ".csproj", ".fsproj", ".vbproj":
+		name = strings.TrimSuffix(name, filepath.Ext(name))
+	}
+	if windows {
+		name += ".exe"
+	}
+	if slices.Contains(candidates, name) {
+		return name, nil
+	}
+	return "", fmt.Errorf("cannot tell the executable apart in the published files (%s), set main to the project", strings.Join(candidates, ", "))
+}
+
+// GomobileBuilder binds Go packages into Android and iOS libraries
+type GomobileBuilder struct{}
+
+// NewGomobileBuilder creates a new gomobile builder
+func NewGomobileBuilder() *GomobileBuilder {
+	return &GomobileBuilder{}
+}
+
+// Supports returns true if this builder supports the given builder type
+func (b *GomobileBuilder) Supports(builder string) bool {
+	return builder == "gomobile"
+}
+
+// Build runs gomobile bind for a platform, target.OS being android or ios.
+// Android builds write an .aar to output, iOS builds an .xcframework that is
+// zipped to output.
+func (b *GomobileBuilder) Build(ctx context.Context, build config.Build, target Target, output string, tmplCtx *tmpl.Context) error {
+	log.Debug("Building mobile library", "platform", target.OS, "output", output)
+
+	if _, err := exec.LookPath("gomobile"); err != nil {
+		return fmt.Errorf("gomobile not found: install it with `go install golang.org/x/mobile/cmd/gomobile@latest && gomobile init`")
+	}
+
+	env := os.Environ()
+	for _, e := range build.Env {
+		expanded, err := tmplCtx.Apply(templateSource(build, "env"), e)
+		if err != nil {
+			return fmt.Errorf("failed to expand env %s: %w", e, err)
+		}
+		env = append(env, expanded)
+	}
+
+	bindTarget := target.OS
+	bindOutput := output
+	switch target.OS {
+	case "android":
+		ndk, err := androidNDK(env)
+		if err != nil {
+			return err
+		}
+		env = append(env, "ANDROID_NDK_HOME="+ndk)
+		// goarch narrows the Android ABIs, all four are built otherwise
+		var archs []string
+		for _, arch := range build.Goarch {
+			archs = append(archs, "android/"+arch)
+		}
+		if len(archs) > 0 {
+			bindTarget = strings.Join(archs, ",")
+		}
+	case "ios":
+		if err := exec.CommandContext(ctx, "xcrun", "--find", "xcodebuild").Run(); err != nil {
+			return fmt.Errorf("iOS builds need Xcode: install it and run xcode-select --install")
+		}
+		bindOutput = strings.TrimSuffix(output, ".zip")
+		if !strings.HasSuffix(bindOutput, ".xcframework") {
+			bindOutput += ".xcframework"
+		}
+		defer os.RemoveAll(bindOutput)
+	default:
+		return fmt.Errorf("gomobile does not support %s, use android or ios", target.OS)
+	}
+
+	args := []string{"bind", "-target", bindTarget, "-o", bindOutput}
+	if len(build.Tags) > 0 {
+		args = append(args, "-tags", strings.Join(build.Tags, ","))
+	}
+	if len(build.Ldflags) > 0 {
+		var ldflags []string
+		for _, flag := range build.Ldflags {
+			expanded, err := tmplCtx.Apply(templateSource(build, "ldflags"), flag)
+			if err != nil {
+				return fmt.Errorf("failed to expand ldflag %s: %w", flag, err)
+			}
+			ldflags = append(ldflags, expanded)
+		}
+		args = append(args, "-ldflags", strings.Join(ldflags, " "))
+	}
+	for _, flag := range build.Flags {
+		expanded, err := tmplCtx.Apply(templateSource(build, "flags"), flag)
+		if err != nil {
+			return fmt.Errorf("failed to expand flag %s: %w", flag, err)
+		}
+		args = append(args, expanded)
+	}
+	main := build.Main
+	if main == "" {
+		main = "."
+	}
+	args = append(args, main)
+
+	dir := build.Dir
+	if dir == "" {
+		dir, _ = os.Getwd()
+	}
+
+	if err := os.MkdirAll(filepath.Dir(output), 0755); err != nil {
+		return err
+	}
+
+	log.Debug("Running gomobile bind", "args", redact.Args(args))
+	cmd := exec.CommandContext(ctx, "gomobile", args...)
+	cmd.Dir = dir
+	cmd.Env = env
+
+	var out bytes.Buffer
+	cmd.Stdout = &out
+	cmd.Stderr = &out
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("gomobile bind failed: %w\n%s", err, out.String())
+	}
+
+	if target.OS == "ios" {
+		if err := zipDir(bindOutput, output); err != nil {
+			return fmt.Errorf("failed to zip xcframework: %w", err)
+		}
+	}
+
+	log.Info("Built mobile library", "output", output)
+	return nil
+}
+
+// androidNDK returns the NDK directory: ANDROID_NDK_HOME, or the newest NDK
+// installed in the Android SDK
+func androidNDK(env []string) (string, error) {
+	if ndk := envValue(env, "ANDROID_NDK_HOME"); ndk != "" {
+		return ndk, nil
+	}
+	sdk := envValue(env, "ANDROID_HOME")
+	if sdk == "" {
+		sdk = envValue(env, "ANDROID_SDK_ROOT")
+	}
+	if sdk != "" {
+		versions, _ := filepath.Glob(filepath.Join(sdk, "ndk", "*"))
+		if len(versions) > 0 {
+			// Compare the major versions numerically, 9.x is older than 25.x
+			major := func(path string) int {
+				n, _ := strconv.Atoi(strings.SplitN(filepath.Base(path), ".", 2)[0])
+				return n
+			}
+			sort.SliceStable(versions, func(i, j int) bool {
+				return major(versions[i]) < major(versions[j])
+			})
+			return versions[len(versions)-1], nil
+		}
+		if _, err := os.Stat(filepath.Join(sdk, "ndk-bundle")); err == nil {
+			return filepath.Join(sdk, "ndk-bundle"), nil
+		}
+	}
+	return "", fmt.Errorf("android builds need the NDK: set ANDROID_NDK_HOME, or ANDROID_HOME to an SDK with the ndk installed")
+}
+
+// zipDir zips dir into path, with dir as the top-level entry
+func zipDir(dir, path string) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	zw := zip.NewWriter(file)
+	parent := filepath.Dir(dir)
+	err = filepath.Walk(dir, func(src string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(parent, src)
+		if err != nil {
+			return err
+		}
+		header, err := zip.FileInfoHeader(info)
+		if err != nil {
+			return err
+		}
+		header.Name = filepath.ToSlash(rel)
+		if info.IsDir() {
+			header.Name += "/"
+		} else {
+			header.Method = zip.Deflate
+		}
+		w, err := zw.CreateHeader(header)
+		if err != nil || info.IsDir() {
+			return err
+		}
+		// xcframeworks hold symlinks on macOS
+		if info.Mode()&os.ModeSymlink != 0 {
+			link, err := os.Readlink(src)
+			if err != nil {
+				return err
+			}
+			_, err = w.Write([]byte(link))
+			return err
+		}
+		data, err := os.ReadFile(src)
+		if err != nil {
+			return err
+		}
+		_, err = w.Write(data)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	return zw.Close()
+}
+
+// PythonBuilder builds Python packages
+type PythonBuilder struct{}
+
+// NewPythonBuilder creates a new Python builder
+func NewPythonBuilder() *PythonBuilder {
+	return &PythonBuilder{}
+}
+
+// Supports returns true if this builder supports the given builder type
+func (b *PythonBuilder) Supports(builder string) bool {
+	return builder == "python" || builder == "pip" || builder == "poetry" || builder == "pyinstaller"
+}
+
+// Build builds a Python package
+func (b *PythonBuilder) Build(ctx context.Context, build config.Build, target Target, output string, tmplCtx *tmpl.Context) error {
+	log.Debug("Building Python package", "target", target.String(), "output", output)
+
+	// Prepare environment
+	env := os.Environ()
+	for _, e := range build.Env {
+		expanded, err := tmplCtx.Apply(templateSource(build, "env"), e)
+		if err != nil {
+			return fmt.Errorf("failed to expand env %s: %w", e, err)
+		}
+		env = append(env, expanded)
+	}
+
+	// Determine working directory
+	dir := build.Dir
+	if dir == "" {
+		dir, _ = os.Getwd()
+	}
+
+	// Determine build tool
+	switch build.Builder {
+	case "poetry":
+		// Poetry build
+		args := []string{"build"}
+		for _, flag := range build.Flags {
+			expanded, err := tmplCtx.Apply(templateSource(build, "flags"), flag)
+			if err != nil {
+				return fmt.Errorf("failed to expand flag %s: %w", flag, err)
+			}
+			args = append(args, expanded)
+		}
+
+		cmd := exec.CommandContext(ctx, "poetry", args...)
+		cmd.Dir = dir
+		cmd.Env = env
+		if err := cmd.Run(); err != nil {
+			return fmt.Errorf("poetry build failed: %w", err)
+		}
+	case "pyinstaller":
+		// PyInstaller build
+		args := []string{"--onefile", "--distpath", filepath.Dir(output)}
+		if target.OS == "windows" {
+			args = append(args, "--name", strings.TrimSuffix(filepath.Base(output), ".exe"))
+		} else {
+			args = append(args, "--name", filepath.Base(output))
+		}
+
+		for _, flag := range build.Flags {
+			expanded, err := tmplCtx.Apply(templateSource(build, "flags"), flag)
+			if err != nil {
+				return fmt.Errorf("failed to expand flag %s: %w", flag, err)
+			}
+			args = append(args, expanded)
+		}
+
+		main := build.Main
+		if main == "" {
+			main = "main.py"
+		}
+		args = append(args, main)
 
 		cmd := exec.CommandContext(ctx, "pyinstaller", args...)
 		cmd.Dir = dir
@@ -1204,7 +2350,7 @@ func (b *PythonBuilder) Build(ctx context.Context, build config.Build, target Ta
 		// Standard Python build
 		args := []string{"setup.py", "build"}
 		for _, flag := range build.Flags {
-			expanded, err := tmplCtx.Apply(flag)
+			expanded, err := tmplCtx.Apply(templateSource(build, "flags"), flag)
 			if err != nil {
 				return fmt.Errorf("failed to expand flag %s: %w", flag, err)
 			}
@@ -1247,7 +2393,7 @@ func (b *PrebuiltBuilder) Build(ctx context.Context, build config.Build, target
 	}
 
 	// Apply template to source path
-	srcPath, err := tmplCtx.Apply(srcPath)
+	srcPath, err := tmplCtx.Apply(templateSource(build, "main"), srcPath)
 	if err != nil {
 		return fmt.Errorf("failed to expand source path: %w", err)
 	}
@@ -1262,28 +2408,22 @@ func (b *PrebuiltBuilder) Build(ctx context.Context, build config.Build, target
 }
 
 // runHookCmd runs a simple hook command string
-func runHookCmd(ctx context.Context, cmdStr, dir string, env []string, tmplCtx *tmpl.Context) error {
+func runHookCmd(ctx context.Context, source, cmdStr, dir string, env []string, tmplCtx *tmpl.Context) error {
 	if cmdStr == "" {
 		return nil
 	}
 
 	// Expand templates in command
-	expanded, err := tmplCtx.Apply(cmdStr)
+	expanded, err := tmplCtx.Apply(source, cmdStr)
 	if err != nil {
 		return fmt.Errorf("failed to expand hook command: %w", err)
 	}
 
-	log.Debug("Running hook", "cmd", expanded)
+	log.Debug("Running hook", "cmd", redact.String(expanded))
 
-	// Determine appropriate shell for platform
-	shell := "sh"
-	shellArg := "-c"
-	if runtime.GOOS == "windows" {
-		shell = "powershell.exe"
-		shellArg = "-Command"
-	}
-
-	cmd := exec.CommandContext(ctx, shell, shellArg, expanded)
+	// Run through the platform shell: sh on unix, cmd on Windows
+	args := hook.ShellCommand("", expanded)
+	cmd := exec.CommandContext(ctx, args[0], args[1:]...)
 	cmd.Dir = dir
 	cmd.Env = env
 	cmd.Stdout = os.Stdout
@@ -1293,32 +2433,37 @@ func runHookCmd(ctx context.Context, cmdStr, dir string, env []string, tmplCtx *
 }
 
 // runHook runs a build hook
-func runHook(ctx context.Context, hook config.Hook, dir string, env []string, tmplCtx *tmpl.Context) error {
-	cmdStr, err := tmplCtx.Apply(hook.Cmd)
+func runHook(ctx context.Context, h config.Hook, dir string, env []string, tmplCtx *tmpl.Context) error {
+	cmdStr, err := tmplCtx.Apply("builds.hooks.cmd", h.Cmd)
 	if err != nil {
 		return err
 	}
 
-	hookDir := hook.Dir
+	hookDir := h.Dir
 	if hookDir == "" {
 		hookDir = dir
 	}
 
-	// Parse command
-	parts := strings.Fields(cmdStr)
-	if len(parts) == 0 {
+	var args []string
+	if h.Shell == "false" {
+		args = strings.Fields(cmdStr)
+	} else {
+		args = hook.ShellCommand(h.Shell, cmdStr)
+	}
+	if len(args) == 0 {
 		return nil
 	}
 
-	cmd := exec.CommandContext(ctx, parts[0], parts[1:]...)
+	cmd := exec.CommandContext(ctx, args[0], args[1:]...)
 	cmd.Dir = hookDir
 
 	// Add environment variables
-	for k, v := range hook.Env {
-		cmd.Env = append(env, fmt.Sprintf("%s=%s", k, v))
+	cmd.Env = env
+	for k, v := range h.Env {
+		cmd.Env = append(cmd.Env, fmt.Sprintf("%s=%s", k, v))
 	}
 
-	if hook.Output != "" {
+	if h.Output != "" {
 		cmd.Stdout = os.Stdout
 		cmd.Stderr = os.Stderr
 	}
@@ -1346,6 +2491,9 @@ func rustTarget(target Target) string {
 		},
 	}
 
+	if target.OS == "linux" && target.Arch == "arm" && target.Arm == "6" {
+		return "arm-unknown-linux-gnueabihf"
+	}
 	if osTargets, ok := targets[target.OS]; ok {
 		if triple, ok := osTargets[target.Arch]; ok {
 			return triple
@@ -1400,7 +2548,7 @@ func (b *JavaBuilder) Build(ctx context.Context, build config.Build, target Targ
 	// Prepare environment
 	env := os.Environ()
 	for _, e := range build.Env {
-		expanded, err := tmplCtx.Apply(e)
+		expanded, err := tmplCtx.Apply(templateSource(build, "env"), e)
 		if err != nil {
 			return fmt.Errorf("failed to expand env %s: %w", e, err)
 		}
@@ -1431,14 +2579,14 @@ func (b *JavaBuilder) Build(ctx context.Context, build config.Build, target Targ
 	case "maven", "mvn":
 		args := []string{"package", "-DskipTests"}
 		for _, flag := range build.Flags {
-			expanded, _ := tmplCtx.Apply(flag)
+			expanded, _ := tmplCtx.Apply(templateSource(build, "flags"), flag)
 			args = append(args, expanded)
 		}
 		cmd = exec.CommandContext(ctx, "mvn", args...)
 	case "gradle":
 		args := []string{"build", "-x", "test"}
 		for _, flag := range build.Flags {
-			expanded, _ := tmplCtx.Apply(flag)
+			expanded, _ := tmplCtx.Apply(templateSource(build, "flags"), flag)
 			args = append(args, expanded)
 		}
 		// Use wrapper if available
@@ -1515,7 +2663,7 @@ func (b *PHPBuilder) Build(ctx context.Context, build config.Build, target Targe
 	// Prepare environment
 	env := os.Environ()
 	for _, e := range build.Env {
-		expanded, err := tmplCtx.Apply(e)
+		expanded, err := tmplCtx.Apply(templateSource(build, "env"), e)
 		if err != nil {
 			return fmt.Errorf("failed to expand env %s: %w", e, err)
 		}
@@ -1618,6 +2766,10 @@ func GetBuilder(builderType string) Builder {
 		NewGoBuilder(),
 		NewRustBuilder(),
 		NewNodeBuilder(),
+		NewDenoBuilder(),
+		NewBunBuilder(),
+		NewDotnetBuilder(),
+		NewGomobileBuilder(),
 		NewPythonBuilder(),
 		NewJavaBuilder(),
 		NewPHPBuilder(),
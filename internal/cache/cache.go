@@ -407,7 +407,7 @@ func (c *Cache) Stats() map[string]interface{} {
 	}
 }
 
-// copyFile copies a file from src to dst
+// copyFile copies a file from src to dst, keeping its mode
 func copyFile(src, dst string) error {
 	source, err := os.Open(src)
 	if err != nil {
@@ -415,14 +415,21 @@ func copyFile(src, dst string) error {
 	}
 	defer source.Close()
 
+	info, err := source.Stat()
+	if err != nil {
+		return err
+	}
+
 	dest, err := os.Create(dst)
 	if err != nil {
 		return err
 	}
 	defer dest.Close()
 
-	_, err = io.Copy(dest, source)
-	return err
+	if _, err := io.Copy(dest, source); err != nil {
+		return err
+	}
+	return dest.Chmod(info.Mode().Perm())
 }
 
 // formatBytes formats bytes as human-readable string
@@ -453,9 +460,10 @@ func NewBuildCache(opts CacheOptions) (*BuildCache, error) {
 	return &BuildCache{cache: c}, nil
 }
 
-// BuildKey generates a cache key for a build
-func (bc *BuildCache) BuildKey(goos, goarch, binary string, sources []string) string {
-	parts := []string{goos, goarch, binary}
+// BuildKey generates a cache key for a build. settings are other inputs
+// that change the binary, e.g. obfuscation options.
+func (bc *BuildCache) BuildKey(goos, goarch, binary string, sources []string, settings ...string) string {
+	parts := append([]string{goos, goarch, binary}, settings...)
 
 	// Hash source files
 	for _, src := range sources {
@@ -18,6 +18,7 @@ import (
 
 	"github.com/oarkflow/releaser/internal/config"
 	"github.com/oarkflow/releaser/internal/git"
+	"github.com/oarkflow/releaser/internal/httpclient"
 )
 
 // Options for changelog generation
@@ -38,6 +39,9 @@ type Generator struct {
 // New creates a new changelog generator
 func New(opts Options) (*Generator, error) {
 	cfgPath := opts.ConfigFile
+	if cfgPath == "" {
+		cfgPath = config.Find()
+	}
 	if cfgPath == "" {
 		cfgPath = ".releaser.yaml"
 	}
@@ -53,6 +57,14 @@ func New(opts Options) (*Generator, error) {
 	}, nil
 }
 
+// NewFromConfig creates a changelog generator for an already loaded config
+func NewFromConfig(cfg *config.Config, opts Options) *Generator {
+	return &Generator{
+		options: opts,
+		config:  cfg,
+	}
+}
+
 // Generate generates a changelog
 func (g *Generator) Generate(ctx context.Context) (string, error) {
 	// Get git info
@@ -127,6 +139,16 @@ func (g *Generator) Generate(ctx context.Context) (string, error) {
 func (g *Generator) formatMarkdown(groups []git.GroupedCommits, gitInfo *git.Info) (string, error) {
 	var buf bytes.Buffer
 
+	// Ticket links
+	var linker *TicketLinker
+	if links := g.config.Changelog.TicketLinks; links.URLTemplate != "" {
+		var err error
+		linker, err = NewTicketLinker(links.Pattern, links.URLTemplate)
+		if err != nil {
+			return "", err
+		}
+	}
+
 	// Header
 	if g.config.Release.Header != "" {
 		header, err := applyTemplate(g.config.Release.Header, gitInfo)
@@ -167,6 +189,13 @@ func (g *Generator) formatMarkdown(groups []git.GroupedCommits, gitInfo *git.Inf
 		for _, commit := range commits {
 			// Clean up subject
 			subject := cleanSubject(commit.Subject)
+			if linker != nil {
+				linked, err := linker.Link(subject)
+				if err != nil {
+					return "", err
+				}
+				subject = linked
+			}
 			shortHash := commit.Hash[:8]
 			buf.WriteString(fmt.Sprintf("* %s (%s)\n", subject, shortHash))
 		}
@@ -355,7 +384,7 @@ func (g *Generator) callOpenAI(ctx context.Context, apiKey, model, prompt string
 	req.Header.Set("Content-Type", "application/json")
 	req.Header.Set("Authorization", "Bearer "+apiKey)
 
-	resp, err := http.DefaultClient.Do(req)
+	resp, err := httpclient.Client().Do(req)
 	if err != nil {
 		return "", fmt.Errorf("API request failed: %w", err)
 	}
@@ -433,7 +462,7 @@ func (g *Generator) callAnthropic(ctx context.Context, apiKey, model, prompt str
 	req.Header.Set("x-api-key", apiKey)
 	req.Header.Set("anthropic-version", "2023-06-01")
 
-	resp, err := http.DefaultClient.Do(req)
+	resp, err := httpclient.Client().Do(req)
 	if err != nil {
 		return "", fmt.Errorf("API request failed: %w", err)
 	}
@@ -0,0 +1,125 @@
+package changelog
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strings"
+	"text/template"
+	"unicode"
+	"unicode/utf8"
+)
+
+// DefaultTicketPattern matches Jira and Linear style ticket IDs
+const DefaultTicketPattern = `[A-Z][A-Z0-9]+-\d+`
+
+// linkedSpanRe matches markdown that already is or contains a link, or is
+// code: inline links, autolinks, bare URLs and code spans
+var linkedSpanRe = regexp.MustCompile("\\[[^\\]]*\\]\\([^)]*\\)|<[a-z][a-z0-9+.-]*:[^>\\s]*>|https?://[^\\s)]+|`[^`]*`")
+
+// TicketLinker links ticket IDs in markdown to their issue tracker
+type TicketLinker struct {
+	re  *regexp.Regexp
+	url *template.Template
+}
+
+// NewTicketLinker compiles a ticket pattern, DefaultTicketPattern when
+// empty, and the URL template of a ticket, which gets the ID as .Ticket
+func NewTicketLinker(pattern, urlTemplate string) (*TicketLinker, error) {
+	re, err := ticketRegexp(pattern)
+	if err != nil {
+		return nil, err
+	}
+	url, err := template.New("url_template").Option("missingkey=error").Parse(urlTemplate)
+	if err != nil {
+		return nil, fmt.Errorf("changelog.ticket_links.url_template: %w", err)
+	}
+	return &TicketLinker{re: re, url: url}, nil
+}
+
+// Link replaces the ticket IDs of text with markdown links. IDs in links,
+// URLs and code spans are left alone, so linking twice changes nothing.
+func (l *TicketLinker) Link(text string) (string, error) {
+	var b strings.Builder
+	last := 0
+	for _, span := range linkedSpanRe.FindAllStringIndex(text, -1) {
+		if err := l.linkPlain(&b, text[last:span[0]]); err != nil {
+			return "", err
+		}
+		b.WriteString(text[span[0]:span[1]])
+		last = span[1]
+	}
+	if err := l.linkPlain(&b, text[last:]); err != nil {
+		return "", err
+	}
+	return b.String(), nil
+}
+
+// linkPlain links the IDs of text without links or code
+func (l *TicketLinker) linkPlain(b *strings.Builder, text string) error {
+	last := 0
+	for _, m := range findTickets(l.re, text) {
+		var url bytes.Buffer
+		if err := l.url.Execute(&url, map[string]string{"Ticket": text[m[0]:m[1]]}); err != nil {
+			return fmt.Errorf("changelog.ticket_links.url_template: %w", err)
+		}
+		fmt.Fprintf(b, "%s[%s](%s)", text[last:m[0]], text[m[0]:m[1]], strings.TrimSpace(url.String()))
+		last = m[1]
+	}
+	b.WriteString(text[last:])
+	return nil
+}
+
+// Tickets returns the ticket IDs referenced by text in order of first
+// appearance, each once. IDs inside links count, so a linked changelog
+// yields the same tickets as the plain one.
+func Tickets(text, pattern string) ([]string, error) {
+	re, err := ticketRegexp(pattern)
+	if err != nil {
+		return nil, err
+	}
+	seen := make(map[string]bool)
+	var tickets []string
+	for _, m := range findTickets(re, text) {
+		if id := text[m[0]:m[1]]; !seen[id] {
+			seen[id] = true
+			tickets = append(tickets, id)
+		}
+	}
+	return tickets, nil
+}
+
+// ticketRegexp compiles a ticket pattern
+func ticketRegexp(pattern string) (*regexp.Regexp, error) {
+	if pattern == "" {
+		pattern = DefaultTicketPattern
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid ticket pattern %q: %w", pattern, err)
+	}
+	return re, nil
+}
+
+// findTickets returns the matches of re that are whole words, so
+// ABC-12 is not found in XYZABC-123
+func findTickets(re *regexp.Regexp, text string) [][]int {
+	var matches [][]int
+	for _, m := range re.FindAllStringIndex(text, -1) {
+		if m[0] == m[1] {
+			continue
+		}
+		before, _ := utf8.DecodeLastRuneInString(text[:m[0]])
+		after, _ := utf8.DecodeRuneInString(text[m[1]:])
+		if isWordRune(before) || isWordRune(after) {
+			continue
+		}
+		matches = append(matches, m)
+	}
+	return matches
+}
+
+// isWordRune reports whether r continues an identifier
+func isWordRune(r rune) bool {
+	return r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r)
+}
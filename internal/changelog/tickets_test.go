@@ -0,0 +1,135 @@
+package changelog
+
+import (
+	"reflect"
+	"testing"
+)
+
+const jiraURL = "https://jira.example.com/browse/{{ .Ticket }}"
+
+func TestTicketLinkerLink(t *testing.T) {
+	tests := []struct {
+		name    string
+		pattern string
+		text    string
+		want    string
+	}{
+		{
+			name: "single ID",
+			text: "* abc1234 fix crash on start (ABC-1234)",
+			want: "* abc1234 fix crash on start ([ABC-1234](https://jira.example.com/browse/ABC-1234))",
+		},
+		{
+			name: "several IDs in one commit",
+			text: "* abc1234 ABC-1, ABC-2 and OPS-30: share the cache",
+			want: "* abc1234 [ABC-1](https://jira.example.com/browse/ABC-1), [ABC-2](https://jira.example.com/browse/ABC-2) and [OPS-30](https://jira.example.com/browse/OPS-30): share the cache",
+		},
+		{
+			name: "ID as markdown link text",
+			text: "* fix [ABC-12](https://jira.example.com/browse/ABC-12) and ABC-13",
+			want: "* fix [ABC-12](https://jira.example.com/browse/ABC-12) and [ABC-13](https://jira.example.com/browse/ABC-13)",
+		},
+		{
+			name: "ID inside other link text",
+			text: "* see [the ABC-12 writeup](https://example.com/notes)",
+			want: "* see [the ABC-12 writeup](https://example.com/notes)",
+		},
+		{
+			name: "ID in link target, URL and autolink",
+			text: "* [notes](https://example.com/ABC-1) https://example.com/ABC-2 <https://example.com/ABC-3>",
+			want: "* [notes](https://example.com/ABC-1) https://example.com/ABC-2 <https://example.com/ABC-3>",
+		},
+		{
+			name: "ID in code span",
+			text: "* rename `ABC-1` to ABC-2",
+			want: "* rename `ABC-1` to [ABC-2](https://jira.example.com/browse/ABC-2)",
+		},
+		{
+			name:    "IDs within words",
+			pattern: `ABC-\d+`,
+			text:    "* XYZABC-123 ABC-123b _ABC-1 ABC-7.",
+			want:    "* XYZABC-123 ABC-123b _ABC-1 [ABC-7](https://jira.example.com/browse/ABC-7).",
+		},
+		{
+			name:    "custom pattern",
+			pattern: `#\d+`,
+			text:    "* fix #42, not ABC-1",
+			want:    "* fix [#42](https://jira.example.com/browse/#42), not ABC-1",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			linker, err := NewTicketLinker(tt.pattern, jiraURL)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := linker.Link(tt.text)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("Link():\n got: %s\nwant: %s", got, tt.want)
+			}
+
+			// Linking again changes nothing
+			again, err := linker.Link(got)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if again != got {
+				t.Errorf("linking twice:\n got: %s\nwant: %s", again, got)
+			}
+		})
+	}
+}
+
+func TestNewTicketLinkerErrors(t *testing.T) {
+	if _, err := NewTicketLinker("[A-Z", jiraURL); err == nil {
+		t.Error("an invalid pattern was accepted")
+	}
+	if _, err := NewTicketLinker("", "{{ .Ticket "); err == nil {
+		t.Error("an invalid url_template was accepted")
+	}
+
+	linker, err := NewTicketLinker("", "https://jira.example.com/{{ .Issue }}")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := linker.Link("ABC-1"); err == nil {
+		t.Error("url_template with an unknown field rendered")
+	}
+}
+
+func TestTickets(t *testing.T) {
+	const notes = `## Changelog
+* abc1234 ABC-1, ABC-2: share the cache
+* def5678 fix [ABC-2](https://jira.example.com/browse/ABC-2) again
+* 0123456 OPS-30 see https://example.com/ABC-99 and ` + "`ABC-98`" + `
+* 89abcde bump go-yaml to v3
+`
+
+	got, err := Tickets(notes, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// IDs inside links count, each once, in order of appearance; the
+	// pattern alone decides what an ID is
+	want := []string{"ABC-1", "ABC-2", "OPS-30", "ABC-99", "ABC-98"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Tickets() = %v, want %v", got, want)
+	}
+
+	// The linked changelog references the same tickets
+	linker, err := NewTicketLinker("", jiraURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	linked, err := linker.Link(notes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fromLinked, _ := Tickets(linked, ""); !reflect.DeepEqual(fromLinked, want) {
+		t.Errorf("Tickets() of the linked changelog = %v, want %v", fromLinked, want)
+	}
+}
@@ -2,6 +2,7 @@
 package checksum
 
 import (
+	"context"
 	"crypto/md5"
 	"crypto/sha1"
 	"crypto/sha256"
@@ -47,8 +48,8 @@ func NewGenerator(cfg config.Checksum, distDir string, manager *artifact.Manager
 	}
 }
 
-// Run generates checksums for all artifacts.
-func (g *Generator) Run() error {
+// Run generates checksums for all artifacts, stopping when ctx is done.
+func (g *Generator) Run(ctx context.Context) error {
 	if g.config.Disable {
 		log.Info("Skipping checksum generation")
 		return nil
@@ -84,12 +85,36 @@ func (g *Generator) Run() error {
 	// Generate checksums
 	checksums := make(map[string]string)
 	for _, a := range checksumArtifacts {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
 		sum, err := g.calculateChecksum(a.Path, algorithm)
 		if err != nil {
 			return fmt.Errorf("failed to calculate checksum for %s: %w", a.Name, err)
 		}
 		checksums[a.Name] = sum
 		log.Debug("Generated checksum", "artifact", a.Name, "algorithm", algorithm, "checksum", sum[:16]+"...")
+
+		// Record the digests on the artifact so publishers can embed them;
+		// package managers want sha256 whatever the configured algorithm
+		digests := map[string]string{string(algorithm): sum}
+		if algorithm != AlgorithmSHA256 {
+			sha, err := g.calculateChecksum(a.Path, AlgorithmSHA256)
+			if err != nil {
+				return fmt.Errorf("failed to calculate sha256 for %s: %w", a.Name, err)
+			}
+			digests[string(AlgorithmSHA256)] = sha
+		}
+		path := a.Path
+		g.manager.Update(func(o artifact.Artifact) bool { return o.Path == path }, func(o *artifact.Artifact) {
+			if o.Extra == nil {
+				o.Extra = make(map[string]interface{})
+			}
+			o.Extra[artifact.ExtraChecksum] = fmt.Sprintf("%s:%s", algorithm, sum)
+			for algo, digest := range digests {
+				o.Extra[algo] = digest
+			}
+		})
 	}
 
 	// Write checksum file
@@ -100,7 +125,7 @@ func (g *Generator) Run() error {
 
 	// Apply template to filename
 	if g.templateCtx != nil {
-		expandedFile, err := g.templateCtx.Apply(checksumFile)
+		expandedFile, err := g.templateCtx.Apply("checksum.name_template", checksumFile)
 		if err != nil {
 			log.Warn("Failed to apply template to checksum filename, using as-is", "template", checksumFile, "error", err)
 		} else {
@@ -114,14 +139,16 @@ func (g *Generator) Run() error {
 	}
 
 	// Add checksum file as artifact
-	g.manager.Add(artifact.Artifact{
+	if err := g.manager.Add(artifact.Artifact{
 		Name: checksumFile,
 		Path: checksumPath,
 		Type: artifact.TypeChecksum,
 		Extra: map[string]interface{}{
 			"algorithm": string(algorithm),
 		},
-	})
+	}); err != nil {
+		return err
+	}
 
 	log.Info("Checksums generated", "file", checksumFile, "count", len(checksums))
 	return nil
@@ -184,11 +211,28 @@ func (g *Generator) writeChecksumFile(path string, checksums map[string]string,
 
 // shouldChecksum returns true if the artifact should have a checksum.
 func shouldChecksum(a artifact.Artifact) bool {
+	// Pushed images have no local file
+	if a.Path == "" {
+		return false
+	}
+
 	switch a.Type {
 	case artifact.TypeArchive,
 		artifact.TypeBinary,
+		artifact.TypeUniversalBinary,
+		artifact.TypeLibrary,
+		artifact.TypeHeader,
+		artifact.TypePkgConfig,
 		artifact.TypeLinuxPackage,
+		artifact.TypeHelm,
+		artifact.TypeKubernetes,
+		artifact.TypeDockerCompose,
+		artifact.TypeAndroidLibrary,
+		artifact.TypeXCFramework,
 		artifact.TypeDockerImage,
+		artifact.TypeDockerImageArchive,
+		artifact.TypeUploadable,
+		artifact.TypeMetadata,
 		artifact.TypeChecksum:
 		return true
 	default:
@@ -210,3 +254,63 @@ func VerifyChecksum(path string, expected string, algorithm Algorithm) (bool, er
 	}
 	return strings.EqualFold(actual, expected), nil
 }
+
+// Entry is a line of a checksums file.
+type Entry struct {
+	Name      string
+	Sum       string
+	Algorithm Algorithm
+}
+
+// ParseFile reads a checksums file in the "checksum  filename" format the
+// generator writes, also accepting the "*filename" binary marker of
+// sha256sum. The algorithm of each line follows from the checksum length.
+func ParseFile(path string) ([]Entry, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []Entry
+	for i, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		sum, name, ok := strings.Cut(line, " ")
+		name = strings.TrimPrefix(strings.TrimLeft(name, " "), "*")
+		if !ok || name == "" {
+			return nil, fmt.Errorf("%s:%d: expected \"checksum  filename\"", path, i+1)
+		}
+		algorithm, err := algorithmForSum(sum)
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", path, i+1, err)
+		}
+		entries = append(entries, Entry{Name: name, Sum: strings.ToLower(sum), Algorithm: algorithm})
+	}
+	if len(entries) == 0 {
+		return nil, fmt.Errorf("%s lists no checksums", path)
+	}
+	return entries, nil
+}
+
+// algorithmForSum returns the algorithm of a hex checksum by its length.
+func algorithmForSum(sum string) (Algorithm, error) {
+	for _, c := range sum {
+		if !strings.ContainsRune("0123456789abcdefABCDEF", c) {
+			return "", fmt.Errorf("checksum %q is not hexadecimal", sum)
+		}
+	}
+	switch len(sum) {
+	case 32:
+		return AlgorithmMD5, nil
+	case 40:
+		return AlgorithmSHA1, nil
+	case 64:
+		return AlgorithmSHA256, nil
+	case 128:
+		return AlgorithmSHA512, nil
+	default:
+		return "", fmt.Errorf("checksum %q has no known algorithm", sum)
+	}
+}
@@ -0,0 +1,147 @@
+package cicd
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+)
+
+// GitHubActions reports whether releaser runs in a GitHub Actions job
+func GitHubActions() bool {
+	return os.Getenv("GITHUB_ACTIONS") == "true"
+}
+
+// Group starts a collapsible section of the job log, ended by the returned
+// func
+func Group(w io.Writer, name string) func() {
+	fmt.Fprintf(w, "::group::%s\n", name)
+	return func() {
+		fmt.Fprintln(w, "::endgroup::")
+	}
+}
+
+// SetOutputs appends step outputs to $GITHUB_OUTPUT, multiline values
+// between random delimiters
+func SetOutputs(outputs map[string]string) error {
+	path := os.Getenv("GITHUB_OUTPUT")
+	if path == "" {
+		return nil
+	}
+	names := make([]string, 0, len(outputs))
+	for name := range outputs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var b strings.Builder
+	for _, name := range names {
+		value := outputs[name]
+		if !strings.Contains(value, "\n") {
+			fmt.Fprintf(&b, "%s=%s\n", name, value)
+			continue
+		}
+		delimiter, err := randomDelimiter()
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(&b, "%s<<%s\n%s\n%s\n", name, delimiter, value, delimiter)
+	}
+	return appendFile(path, b.String())
+}
+
+// randomDelimiter returns a heredoc delimiter no value can contain by
+// chance
+func randomDelimiter() (string, error) {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return "ghadelimiter_" + hex.EncodeToString(b), nil
+}
+
+// Release is what the step summary describes
+type Release struct {
+	Title string
+	URL   string
+	Notes string
+	Files []File
+}
+
+// File is a row of the artifacts table of the step summary
+type File struct {
+	Name     string
+	Type     string
+	Size     int64
+	Checksum string
+}
+
+// WriteSummary appends the release summary to $GITHUB_STEP_SUMMARY
+func WriteSummary(r Release) error {
+	path := os.Getenv("GITHUB_STEP_SUMMARY")
+	if path == "" {
+		return nil
+	}
+	var b strings.Builder
+	RenderSummary(&b, r)
+	return appendFile(path, b.String())
+}
+
+// RenderSummary writes the release as markdown: a link to the release, a
+// table of the artifacts with their sizes and checksums, and the notes
+func RenderSummary(w io.Writer, r Release) {
+	fmt.Fprintf(w, "## %s\n\n", r.Title)
+	if r.URL != "" {
+		fmt.Fprintf(w, "Release: %s\n\n", r.URL)
+	}
+	if len(r.Files) > 0 {
+		fmt.Fprintln(w, "| Artifact | Type | Size | Checksum |")
+		fmt.Fprintln(w, "| --- | --- | ---: | --- |")
+		for _, f := range r.Files {
+			checksum := ""
+			if f.Checksum != "" {
+				checksum = "`" + f.Checksum + "`"
+			}
+			fmt.Fprintf(w, "| %s | %s | %s | %s |\n", escapeCell(f.Name), f.Type, formatSize(f.Size), checksum)
+		}
+		fmt.Fprintln(w)
+	}
+	if notes := strings.TrimSpace(r.Notes); notes != "" {
+		fmt.Fprintf(w, "### Release notes\n\n%s\n\n", notes)
+	}
+}
+
+// escapeCell keeps a value from breaking a markdown table row
+func escapeCell(s string) string {
+	return strings.ReplaceAll(s, "|", `\|`)
+}
+
+// formatSize formats bytes as human readable size
+func formatSize(bytes int64) string {
+	const unit = 1024
+	if bytes < unit {
+		return fmt.Sprintf("%d B", bytes)
+	}
+	div, exp := int64(unit), 0
+	for n := bytes / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %cB", float64(bytes)/float64(div), "KMGTPE"[exp])
+}
+
+// appendFile appends content to a file the runner provides
+func appendFile(path, content string) error {
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := f.WriteString(content); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
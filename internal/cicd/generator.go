@@ -1,4 +1,5 @@
-// Package cicd provides CI/CD pipeline template generation.
+// Package cicd provides CI/CD pipeline template generation and the GitHub
+// Actions integration of releases.
 package cicd
 
 import (
@@ -20,6 +21,7 @@ const (
 	PlatformAzurePipelines Platform = "azure"
 	PlatformBitbucket      Platform = "bitbucket"
 	PlatformDrone          Platform = "drone"
+	PlatformWoodpecker     Platform = "woodpecker"
 )
 
 // Options for CI/CD template generation
@@ -36,19 +38,36 @@ type Options struct {
 	TestCommand   string
 	BuildCommand  string
 	PublishBranch string
+
+	// GoVersionFile sets up the go version of go.mod instead of GoVersion
+	GoVersionFile string
+	// ReleaserVersion is the releaser version the workflow installs
+	ReleaserVersion string
+
+	// What the release config needs from the runner, set by Inspect
+	MacOS         bool
+	CrossCompile  bool
+	NFPM          bool
+	SBOM          bool
+	UPX           bool
+	GPG           bool
+	Cosign        bool
+	CosignKeyless bool
+	Secrets       []string
 }
 
 // DefaultOptions returns default options
 func DefaultOptions() Options {
 	return Options{
-		Platform:      PlatformGitHubActions,
-		GoVersion:     "1.22",
-		NodeVersion:   "20",
-		PythonVersion: "3.11",
-		RustVersion:   "stable",
-		PublishBranch: "main",
-		TestCommand:   "go test ./...",
-		BuildCommand:  "releaser build --snapshot",
+		Platform:        PlatformGitHubActions,
+		GoVersion:       "1.22",
+		NodeVersion:     "20",
+		PythonVersion:   "3.11",
+		RustVersion:     "stable",
+		PublishBranch:   "main",
+		ReleaserVersion: "latest",
+		TestCommand:     "go test ./...",
+		BuildCommand:    "releaser build --snapshot",
 	}
 }
 
@@ -81,6 +100,8 @@ func (g *Generator) Generate(outputDir string) error {
 		return g.generateBitbucket(outputDir)
 	case PlatformDrone:
 		return g.generateDrone(outputDir)
+	case PlatformWoodpecker:
+		return g.generateWoodpecker(outputDir)
 	default:
 		return fmt.Errorf("unsupported platform: %s", g.opts.Platform)
 	}
@@ -93,7 +114,45 @@ func (g *Generator) generateGitHubActions(outputDir string) error {
 		return err
 	}
 
-	tmpl := `name: Release
+	tmpl := `{{ define "setup" }}      - uses: actions/checkout@v4
+        with:
+          fetch-depth: 0
+
+      - name: Set up Go
+        uses: actions/setup-go@v5
+        with:
+{{- if .GoVersionFile }}
+          go-version-file: {{ .GoVersionFile }}
+{{- else }}
+          go-version: '{{ .GoVersion }}'
+{{- end }}
+          cache: true
+
+      - name: Install releaser
+        run: go install github.com/oarkflow/releaser/cmd/releaser@{{ .ReleaserVersion }}
+{{- if .CrossCompile }}
+
+      - name: Set up Zig for cgo cross-compiling
+        uses: mlugg/setup-zig@v2
+{{- end }}
+{{- if .NFPM }}
+
+      - name: Install nfpm
+        run: go install github.com/goreleaser/nfpm/v2/cmd/nfpm@latest
+{{- end }}
+{{- if .UPX }}
+
+      - name: Install UPX
+        uses: crazy-max/ghaction-upx@v3
+        with:
+          install-only: true
+{{- end }}
+{{- if .SBOM }}
+
+      - name: Install Syft
+        uses: anchore/sbom-action/download-syft@v0
+{{- end }}
+{{ end }}name: Release
 
 on:
   push:
@@ -105,21 +164,15 @@ on:
 permissions:
   contents: write
   packages: write
+{{- if .CosignKeyless }}
+  id-token: write
+{{- end }}
 
 jobs:
   build:
-    runs-on: ubuntu-latest
+    runs-on: {{ if .MacOS }}macos-latest{{ else }}ubuntu-latest{{ end }}
     steps:
-      - uses: actions/checkout@v4
-        with:
-          fetch-depth: 0
-
-      - name: Set up Go
-        uses: actions/setup-go@v5
-        with:
-          go-version: '{{ .GoVersion }}'
-          cache: true
-
+{{ template "setup" . }}
       - name: Run tests
         run: {{ .TestCommand }}
 
@@ -129,27 +182,37 @@ jobs:
 
   release:
     needs: build
-    runs-on: ubuntu-latest
+    runs-on: {{ if .MacOS }}macos-latest{{ else }}ubuntu-latest{{ end }}
     if: startsWith(github.ref, 'refs/tags/')
     steps:
-      - uses: actions/checkout@v4
+{{ template "setup" . }}
+{{- if .Cosign }}
+      - name: Install cosign
+        uses: sigstore/cosign-installer@v3
+{{ end }}
+{{- if .GPG }}
+      - name: Import GPG key
+        uses: crazy-max/ghaction-import-gpg@v6
         with:
-          fetch-depth: 0
+          gpg_private_key: ${{"{{"}} secrets.GPG_PRIVATE_KEY {{"}}"}}
+          passphrase: ${{"{{"}} secrets.GPG_PASSPHRASE {{"}}"}}
+{{ end }}
+{{- if .DockerEnabled }}
+{{- if .MacOS }}
+      - name: Set up Docker
+        uses: docker/setup-docker-action@v4
+{{ end }}
+      - name: Set up QEMU
+        uses: docker/setup-qemu-action@v3
 
-      - name: Set up Go
-        uses: actions/setup-go@v5
-        with:
-          go-version: '{{ .GoVersion }}'
-          cache: true
-{{ if .DockerEnabled }}
       - name: Set up Docker Buildx
         uses: docker/setup-buildx-action@v3
 
       - name: Login to Docker Hub
         uses: docker/login-action@v3
         with:
-          username: ${{"{{"}} secrets.DOCKERHUB_USERNAME {{"}}"}}
-          password: ${{"{{"}} secrets.DOCKERHUB_TOKEN {{"}}"}}
+          username: ${{"{{"}} secrets.DOCKER_USERNAME {{"}}"}}
+          password: ${{"{{"}} secrets.DOCKER_PASSWORD {{"}}"}}
 
       - name: Login to GitHub Container Registry
         uses: docker/login-action@v3
@@ -159,13 +222,13 @@ jobs:
           password: ${{"{{"}} secrets.GITHUB_TOKEN {{"}}"}}
 {{ end }}
       - name: Run Releaser
-        run: releaser release
+        run: releaser release --clean
         env:
           GITHUB_TOKEN: ${{"{{"}} secrets.GITHUB_TOKEN {{"}}"}}
-{{ if .DockerEnabled }}
-          DOCKER_USERNAME: ${{"{{"}} secrets.DOCKERHUB_USERNAME {{"}}"}}
-          DOCKER_PASSWORD: ${{"{{"}} secrets.DOCKERHUB_TOKEN {{"}}"}}
-{{ end }}
+{{- range .Secrets }}
+          {{ . }}: ${{"{{"}} secrets.{{ . }} {{"}}"}}
+{{- end }}
+
       - name: Upload artifacts
         uses: actions/upload-artifact@v4
         with:
@@ -186,6 +249,8 @@ func (g *Generator) generateGitLabCI(outputDir string) error {
 
 variables:
   GO_VERSION: "{{ .GoVersion }}"
+  # Tags and the previous release are needed for the changelog
+  GIT_DEPTH: 0
 
 .go-cache:
   variables:
@@ -194,6 +259,21 @@ variables:
     paths:
       - .go/pkg/mod/
 
+.releaser:
+  extends: .go-cache
+{{- if .MacOS }}
+  # DMGs and PKGs are made on macOS
+  image: macos-14-xcode-15
+  tags:
+    - saas-macos-medium-m1
+{{- else }}
+  image: golang:${GO_VERSION}
+{{- end }}
+  before_script:
+{{- range .InstallCommands }}
+    - {{ . }}
+{{- end }}
+
 test:
   stage: test
   image: golang:${GO_VERSION}
@@ -206,8 +286,7 @@ test:
 
 build:snapshot:
   stage: build
-  image: golang:${GO_VERSION}
-  extends: .go-cache
+  extends: .releaser
   script:
     - {{ .BuildCommand }}
   artifacts:
@@ -219,34 +298,104 @@ build:snapshot:
 
 release:
   stage: release
-  image: golang:${GO_VERSION}
-  extends: .go-cache
+  extends: .releaser
+{{- if and .DockerEnabled (not .MacOS) }}
+  services:
+    - docker:dind
+  variables:
+    DOCKER_HOST: tcp://docker:2375
+    DOCKER_TLS_CERTDIR: ""
+{{- end }}
+{{- if .CosignKeyless }}
+  id_tokens:
+    SIGSTORE_ID_TOKEN:
+      aud: sigstore
+{{- end }}
+{{- if .Secrets }}
+  # Set these as masked CI/CD variables:{{ range .Secrets }} {{ . }}{{ end }}
+{{- end }}
   script:
-    - releaser release
+{{- if .GPG }}
+    - echo "$GPG_PRIVATE_KEY" | gpg --batch --import
+{{- end }}
+{{- if .DockerEnabled }}
+    - echo "$DOCKER_PASSWORD" | docker login -u "$DOCKER_USERNAME" --password-stdin
+    - echo "$CI_REGISTRY_PASSWORD" | docker login -u "$CI_REGISTRY_USER" --password-stdin "$CI_REGISTRY"
+{{- end }}
+    - releaser release --clean
   artifacts:
     paths:
       - dist/
     expire_in: 30 days
   rules:
     - if: $CI_COMMIT_TAG
-{{ if .DockerEnabled }}
-release:docker:
-  stage: release
-  image: docker:latest
-  services:
-    - docker:dind
-  script:
-    - docker login -u $CI_REGISTRY_USER -p $CI_REGISTRY_PASSWORD $CI_REGISTRY
-    - docker build -t $CI_REGISTRY_IMAGE:$CI_COMMIT_TAG .
-    - docker push $CI_REGISTRY_IMAGE:$CI_COMMIT_TAG
-  rules:
-    - if: $CI_COMMIT_TAG
-{{ end }}
 `
 
 	return g.writeTemplate(filepath.Join(outputDir, ".gitlab-ci.yml"), tmpl)
 }
 
+// generateWoodpecker creates a Woodpecker CI workflow
+func (g *Generator) generateWoodpecker(outputDir string) error {
+	workflowDir := filepath.Join(outputDir, ".woodpecker")
+	if err := os.MkdirAll(workflowDir, 0755); err != nil {
+		return err
+	}
+
+	tmpl := `when:
+  - event: tag
+    ref: refs/tags/v*
+
+clone:
+  - name: clone
+    image: woodpeckerci/plugin-git
+    settings:
+      # Tags and the previous release are needed for the changelog
+      partial: false
+      tags: true
+{{ if .MacOS }}
+# DMGs and PKGs are made on macOS, by an agent with the local backend
+labels:
+  platform: darwin/arm64
+{{ end }}
+steps:
+  - name: test
+    image: golang:{{ .GoVersion }}
+    commands:
+      - {{ .TestCommand }}
+
+  - name: release
+    image: golang:{{ .GoVersion }}
+{{- if .DockerEnabled }}
+    # Building images needs the host's docker, so the repository must be trusted
+    volumes:
+      - /var/run/docker.sock:/var/run/docker.sock
+{{- end }}
+    environment:
+      GITHUB_TOKEN:
+        from_secret: GITHUB_TOKEN
+{{- range .Secrets }}
+      {{ . }}:
+        from_secret: {{ . }}
+{{- end }}
+    commands:
+{{- range .InstallCommands }}
+      - {{ . }}
+{{- end }}
+{{- if .GPG }}
+      - echo "$GPG_PRIVATE_KEY" | gpg --batch --import
+{{- end }}
+{{- if .DockerEnabled }}
+      - echo "$DOCKER_PASSWORD" | docker login -u "$DOCKER_USERNAME" --password-stdin
+{{- end }}
+{{- if .CosignKeyless }}
+      # Woodpecker has no OIDC token for keyless cosign, set SIGSTORE_ID_TOKEN or a key_ref
+{{- end }}
+      - releaser release --clean
+`
+
+	return g.writeTemplate(filepath.Join(workflowDir, "release.yaml"), tmpl)
+}
+
 // generateCircleCI creates CircleCI configuration
 func (g *Generator) generateCircleCI(outputDir string) error {
 	circleDir := filepath.Join(outputDir, ".circleci")
@@ -592,5 +741,6 @@ func GetSupportedPlatforms() []Platform {
 		PlatformAzurePipelines,
 		PlatformBitbucket,
 		PlatformDrone,
+		PlatformWoodpecker,
 	}
 }
@@ -0,0 +1,209 @@
+package cicd
+
+import (
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/oarkflow/releaser/internal/builder"
+	"github.com/oarkflow/releaser/internal/config"
+	"github.com/oarkflow/releaser/internal/publish"
+	"github.com/oarkflow/releaser/internal/redact"
+)
+
+// envRefRe finds environment variables referenced by config templates
+var envRefRe = regexp.MustCompile(`(?:\.Env\.|env\s+")([A-Za-z_][A-Za-z0-9_]*)`)
+
+// runnerSecrets are provided by the CI itself and never listed as secrets
+var runnerSecrets = []string{"GITHUB_TOKEN", "CI_JOB_TOKEN"}
+
+// Inspect fills in what the release config needs from the runner: docker,
+// cosign, a macOS runner for DMGs and PKGs, cross-compilers for cgo
+// builds, the tools of the enabled stages and the secrets the publishers,
+// announcers and templates use. raw is the config file, whose env
+// references name secrets.
+func (o *Options) Inspect(cfg *config.Config, raw []byte) {
+	if o.ProjectName == "" {
+		o.ProjectName = cfg.ProjectName
+	}
+	o.DockerEnabled = o.DockerEnabled || len(cfg.Dockers) > 0 || len(cfg.DockerManifests) > 0
+	o.MacOS = len(cfg.DMGs) > 0 || len(cfg.PKGs) > 0
+	o.NFPM = len(cfg.NFPMs) > 0
+	o.SBOM = len(cfg.SBOMs) > 0
+	o.UPX = len(cfg.UPXs) > 0
+	for _, s := range cfg.Signs {
+		if s.Method != "" && s.Method != "cmd" {
+			// Built-in signers read their key from key_env
+			continue
+		}
+		if s.Cmd == "" || s.Cmd == "gpg" {
+			o.GPG = true
+		}
+	}
+	for _, c := range cfg.Cosigns {
+		o.Cosign = true
+		// Without a key cosign signs keyless with the job's OIDC token
+		if c.Keyless || c.KeyRef == "" {
+			o.CosignKeyless = true
+		}
+	}
+	// Runners are linux/amd64, so cgo builds for anything else need zig
+	for _, b := range cfg.Builds {
+		if b.Skip || (!b.Cgo.Enabled && !builder.IsLibrary(b.Buildmode)) {
+			continue
+		}
+		if len(b.Goos) == 0 || len(b.Goarch) == 0 {
+			o.CrossCompile = true
+		}
+		for _, goos := range b.Goos {
+			for _, goarch := range b.Goarch {
+				if goos != "linux" || goarch != "amd64" {
+					o.CrossCompile = true
+				}
+			}
+		}
+	}
+
+	secrets := make(map[string]bool)
+	for _, name := range cfg.Requirements.Env {
+		secrets[name] = true
+	}
+	for _, req := range publish.Requirements(cfg) {
+		if len(req.Env) > 0 {
+			secrets[req.Env[0]] = true
+		}
+	}
+	for _, m := range envRefRe.FindAllStringSubmatch(string(raw), -1) {
+		if redact.IsSecretName(m[1]) && !slices.Contains(cfg.MaskEnv, m[1]) {
+			secrets[m[1]] = true
+		}
+	}
+	for _, name := range cfg.MaskEnv {
+		secrets[name] = true
+	}
+	for _, name := range announceSecrets(cfg.Announce) {
+		secrets[name] = true
+	}
+	for _, s := range cfg.Signs {
+		for _, name := range []string{s.KeyEnv, s.PasswordEnv} {
+			if name != "" {
+				secrets[name] = true
+			}
+		}
+	}
+	if o.DockerEnabled {
+		secrets["DOCKER_USERNAME"], secrets["DOCKER_PASSWORD"] = true, true
+	}
+	if o.GPG {
+		secrets["GPG_PRIVATE_KEY"], secrets["GPG_PASSPHRASE"] = true, true
+	}
+	if cfg.Updates.Appcast.Enabled && cfg.Updates.Appcast.EdKey == "" {
+		secrets["SPARKLE_PRIVATE_KEY"] = true
+	}
+	o.Secrets = nil
+	for name := range secrets {
+		if !slices.Contains(runnerSecrets, name) {
+			o.Secrets = append(o.Secrets, name)
+		}
+	}
+	sort.Strings(o.Secrets)
+}
+
+// announceSecrets returns the environment variables the enabled announcers
+// read their credentials from
+func announceSecrets(a config.Announce) []string {
+	var names []string
+	if a.Slack.Enabled != "" {
+		switch {
+		case a.Slack.TokenEnv != "":
+			names = append(names, a.Slack.TokenEnv)
+		case a.Slack.WebhookURL == "":
+			names = append(names, "SLACK_WEBHOOK_URL")
+		}
+	}
+	if a.Discord.Enabled != "" && a.Discord.WebhookURL == "" {
+		names = append(names, "DISCORD_WEBHOOK_URL")
+	}
+	if a.Teams.Enabled != "" && a.Teams.WebhookURL == "" {
+		names = append(names, "TEAMS_WEBHOOK_URL")
+	}
+	if a.Mastodon.Enabled != "" {
+		names = append(names, "MASTODON_ACCESS_TOKEN")
+	}
+	if a.Telegram.Enabled != "" {
+		names = append(names, "TELEGRAM_BOT_TOKEN")
+	}
+	if a.Bluesky.Enabled != "" {
+		names = append(names, "BLUESKY_APP_PASSWORD")
+	}
+	if a.Twitter.Enabled != "" {
+		names = append(names, "TWITTER_CONSUMER_KEY", "TWITTER_CONSUMER_SECRET", "TWITTER_ACCESS_TOKEN", "TWITTER_ACCESS_TOKEN_SECRET")
+	}
+	if a.SMTP.Enabled != "" && a.SMTP.Password == "" {
+		names = append(names, "SMTP_PASSWORD")
+	}
+	if a.Jira.Enabled != "" {
+		names = append(names, "JIRA_API_TOKEN")
+	}
+	return names
+}
+
+// InstallCommands returns the shell commands installing releaser and the
+// tools the release needs, in a golang image or, for MacOS, on a macOS
+// runner with Homebrew
+func (o Options) InstallCommands() []string {
+	var cmds []string
+	if o.MacOS {
+		cmds = append(cmds, "brew install go")
+	}
+	cmds = append(cmds, "go install github.com/oarkflow/releaser/cmd/releaser@"+o.ReleaserVersion)
+	if o.NFPM {
+		cmds = append(cmds, "go install github.com/goreleaser/nfpm/v2/cmd/nfpm@latest")
+	}
+	if o.Cosign {
+		cmds = append(cmds, "go install github.com/sigstore/cosign/v2/cmd/cosign@latest")
+	}
+
+	var packages []string
+	if o.CrossCompile {
+		packages = append(packages, "zig")
+	}
+	if o.UPX {
+		packages = append(packages, "upx")
+	}
+	if o.SBOM {
+		packages = append(packages, "syft")
+	}
+	if o.MacOS {
+		if o.DockerEnabled {
+			packages = append(packages, "docker", "colima")
+		}
+		if len(packages) > 0 {
+			cmds = append(cmds, "brew install "+strings.Join(packages, " "))
+		}
+		if o.DockerEnabled {
+			cmds = append(cmds, "colima start")
+		}
+		return cmds
+	}
+
+	// Debian has no zig or syft packages
+	var apt []string
+	if o.UPX {
+		apt = append(apt, "upx-ucl")
+	}
+	if o.DockerEnabled {
+		apt = append(apt, "docker.io")
+	}
+	if len(apt) > 0 {
+		cmds = append(cmds, "apt-get update && apt-get install -y "+strings.Join(apt, " "))
+	}
+	if o.CrossCompile {
+		cmds = append(cmds, "curl -sL https://ziglang.org/download/0.13.0/zig-linux-x86_64-0.13.0.tar.xz | tar -xJ -C /usr/local && ln -sf /usr/local/zig-linux-x86_64-0.13.0/zig /usr/local/bin/zig")
+	}
+	if o.SBOM {
+		cmds = append(cmds, "curl -sSfL https://raw.githubusercontent.com/anchore/syft/main/install.sh | sh -s -- -b /usr/local/bin")
+	}
+	return cmds
+}
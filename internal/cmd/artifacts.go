@@ -0,0 +1,148 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+
+	"github.com/oarkflow/releaser/internal/artifact"
+	"github.com/oarkflow/releaser/internal/pipeline"
+)
+
+var (
+	artifactsTypes  []string
+	artifactsGoos   string
+	artifactsGoarch string
+	artifactsIDs    []string
+	artifactsOutput string
+)
+
+var artifactsCmd = &cobra.Command{
+	Use:   "artifacts",
+	Short: "Inspect the artifacts of the last build",
+}
+
+var artifactsListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List the artifacts of the last build",
+	Long: `List the artifacts registered by the last build or release.
+
+Artifacts are read from the state saved in dist; without one, the binaries
+found in dist are listed. Filters combine: --type and --id match any of their
+values, and every given filter must match.
+
+The JSON output is the artifact list written by the artifact manager, the
+same schema as the artifacts of the saved state.
+
+Example:
+  releaser artifacts list
+  releaser artifacts list --type archive --goos linux
+  releaser artifacts list --id server -o json`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if artifactsOutput != "table" && artifactsOutput != "json" {
+			return fmt.Errorf("unknown output %q, expected table or json", artifactsOutput)
+		}
+
+		p, err := pipeline.New(cmd.Context(), pipeline.ReleaseOptions{
+			ConfigFile: cfgFile,
+			Profile:    profile,
+			Snapshot:   true,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to create pipeline: %w", err)
+		}
+		all, err := p.Artifacts()
+		if err != nil {
+			return err
+		}
+
+		manager := artifact.NewManager()
+		for _, a := range all {
+			if err := manager.Add(a); err != nil {
+				return err
+			}
+		}
+		artifacts := manager.Filter(artifactsFilters()...)
+
+		if artifactsOutput == "json" {
+			data, err := json.MarshalIndent(artifacts, "", "  ")
+			if err != nil {
+				return err
+			}
+			fmt.Println(string(data))
+			return nil
+		}
+
+		if len(artifacts) == 0 {
+			fmt.Println("No matching artifacts")
+			return nil
+		}
+		tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(tw, "NAME\tTYPE\tPLATFORM\tID\tPATH")
+		for _, a := range artifacts {
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", a.Name, a.Type, platform(a), a.BuildID, a.Path)
+		}
+		return tw.Flush()
+	},
+}
+
+// artifactsFilters returns the filters selected by the flags
+func artifactsFilters() []artifact.FilterFunc {
+	var filters []artifact.FilterFunc
+	if len(artifactsTypes) > 0 {
+		var types []artifact.FilterFunc
+		for _, t := range artifactsTypes {
+			types = append(types, byTypeName(t))
+		}
+		filters = append(filters, artifact.Or(types...))
+	}
+	if artifactsGoos != "" {
+		filters = append(filters, artifact.ByGoos(artifactsGoos))
+	}
+	if artifactsGoarch != "" {
+		filters = append(filters, artifact.ByGoarch(artifactsGoarch))
+	}
+	if len(artifactsIDs) > 0 {
+		filters = append(filters, artifact.ByID(artifactsIDs...))
+	}
+	return filters
+}
+
+// byTypeName matches a type by name, ignoring case and separators, so
+// "linux-package" matches "Linux Package"
+func byTypeName(name string) artifact.FilterFunc {
+	normalize := strings.NewReplacer(" ", "", "-", "", "_", "")
+	want := strings.ToLower(normalize.Replace(name))
+	return func(a artifact.Artifact) bool {
+		return strings.ToLower(normalize.Replace(string(a.Type))) == want
+	}
+}
+
+// platform formats the target of an artifact, e.g. linux/arm/7
+func platform(a artifact.Artifact) string {
+	if a.Goos == "" {
+		return "-"
+	}
+	s := a.Goos + "/" + a.Goarch
+	if a.Goarm != "" {
+		s += "/" + a.Goarm
+	}
+	if a.Goamd64 != "" {
+		s += "/" + a.Goamd64
+	}
+	return s
+}
+
+func init() {
+	artifactsListCmd.Flags().StringSliceVar(&artifactsTypes, "type", nil, "only list artifacts of these types, e.g. binary, archive, linux-package")
+	artifactsListCmd.Flags().StringVar(&artifactsGoos, "goos", "", "only list artifacts for this OS")
+	artifactsListCmd.Flags().StringVar(&artifactsGoarch, "goarch", "", "only list artifacts for this architecture")
+	artifactsListCmd.Flags().StringSliceVar(&artifactsIDs, "id", nil, "only list artifacts of these build ids")
+	artifactsListCmd.Flags().StringVarP(&artifactsOutput, "output", "o", "table", "output format: table or json")
+	artifactsCmd.AddCommand(artifactsListCmd)
+	rootCmd.AddCommand(artifactsCmd)
+}
@@ -2,12 +2,18 @@ package cmd
 
 import (
 	"fmt"
+	"strings"
 
 	"github.com/spf13/cobra"
 
 	"github.com/oarkflow/releaser/internal/pipeline"
 )
 
+var (
+	buildIDs  []string
+	buildOnly string
+)
+
 var buildCmd = &cobra.Command{
 	Use:   "build",
 	Short: "Build artifacts only",
@@ -17,12 +23,18 @@ This is useful for testing your build configuration locally
 or in CI before creating an actual release.
 
 This command builds binaries, creates archives, generates packages
-(deb/rpm/apk), and creates checksums - everything except publish and announce.`,
+(deb/rpm/apk), and creates checksums - everything except publish and announce.
+
+Use --id to limit builds, archives and nfpms to the given config ids, and
+--only to rerun a single stage against the outputs of a previous build:
+
+  releaser build --id agent --only nfpm`,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		ctx := cmd.Context()
 
 		opts := pipeline.ReleaseOptions{
 			ConfigFile:   cfgFile,
+			Profile:      profile,
 			Snapshot:     snapshot,
 			SingleTarget: singleTarget,
 			SkipPublish:  true,
@@ -34,6 +46,9 @@ This command builds binaries, creates archives, generates packages
 			Parallelism:  parallelism,
 			Timeout:      timeout,
 			Silent:       silent,
+			Skip:         skip,
+			IDs:          buildIDs,
+			GroupLogs:    githubActions(),
 		}
 
 		p, err := pipeline.New(ctx, opts)
@@ -41,9 +56,18 @@ This command builds binaries, creates archives, generates packages
 			return fmt.Errorf("failed to create pipeline: %w", err)
 		}
 
+		if buildOnly != "" {
+			if err := p.RunStage(ctx, buildOnly); err != nil {
+				return err
+			}
+			reportToCI(p)
+			return nil
+		}
+
 		if err := p.BuildAll(ctx); err != nil {
 			return fmt.Errorf("build failed: %w", err)
 		}
+		reportToCI(p)
 
 		return nil
 	},
@@ -51,9 +75,12 @@ This command builds binaries, creates archives, generates packages
 
 func init() {
 	buildCmd.Flags().BoolVar(&snapshot, "snapshot", false, "create a snapshot build")
-	buildCmd.Flags().StringVar(&singleTarget, "single-target", "", "build for a single target")
+	buildCmd.Flags().StringVar(&singleTarget, "single-target", "", "build for a single target (e.g., linux_amd64, or current for the host)")
 	buildCmd.Flags().BoolVar(&clean, "clean", false, "remove dist folder before building")
 	buildCmd.Flags().BoolVar(&skipDocker, "skip-docker", false, "skip building Docker images")
 	buildCmd.Flags().BoolVar(&skipSign, "skip-sign", false, "skip signing artifacts")
+	buildCmd.Flags().StringSliceVar(&skip, "skip", nil, "skip stages, comma separated or repeated: "+strings.Join(pipeline.SkipStages, ", "))
+	buildCmd.Flags().StringSliceVar(&buildIDs, "id", nil, "only build, archive and package these config ids")
+	buildCmd.Flags().StringVar(&buildOnly, "only", "", "run a single stage against the previous build: build, upx, generate, archive, nfpm, packages, docker, sbom, checksum, provenance, sign, updates, notes, docs")
 	buildCmd.Flags().BoolVar(&silent, "silent", false, "show minimal output and continue on build errors")
 }
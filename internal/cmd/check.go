@@ -6,9 +6,12 @@ import (
 
 	"github.com/oarkflow/releaser"
 	"github.com/oarkflow/releaser/internal/config"
+	"github.com/oarkflow/releaser/internal/pipeline"
 	"github.com/spf13/cobra"
 )
 
+var checkSigning bool
+
 var checkCmd = &cobra.Command{
 	Use:   "check",
 	Short: "Check configuration file",
@@ -19,9 +22,17 @@ This validates:
   - Required fields
   - Template syntax
   - File references
-  - Include statements`,
+  - Include statements
+
+Pass --profile to check the config with a profile applied.
+
+Use --signing to also verify that cosign is installed and has a key or
+OIDC identity and registry credentials, without building anything.`,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		configPath := cfgFile
+		if configPath == "" {
+			configPath = config.Find()
+		}
 		if configPath == "" {
 			configPath = ".releaser.yaml"
 		}
@@ -35,11 +46,28 @@ This validates:
 			return fmt.Errorf("failed to load config: %w", err)
 		}
 
+		if profile != "" {
+			if err := cfg.ApplyProfile(profile); err != nil {
+				return err
+			}
+		}
+
 		if err := cfg.Validate(); err != nil {
 			return fmt.Errorf("config validation failed: %w", err)
 		}
 
 		fmt.Printf("✓ Configuration file %s is valid\n", configPath)
+
+		if checkSigning {
+			p, err := pipeline.New(cmd.Context(), pipeline.ReleaseOptions{ConfigFile: configPath, Profile: profile, Snapshot: true})
+			if err != nil {
+				return fmt.Errorf("failed to create pipeline: %w", err)
+			}
+			if err := p.Preflight(); err != nil {
+				return err
+			}
+			fmt.Println("✓ Signing is ready")
+		}
 		return nil
 	},
 }
@@ -86,3 +114,7 @@ var versionCmd = &cobra.Command{
 		}
 	},
 }
+
+func init() {
+	checkCmd.Flags().BoolVar(&checkSigning, "signing", false, "verify cosign signing prerequisites without building")
+}
@@ -0,0 +1,86 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/charmbracelet/log"
+
+	"github.com/oarkflow/releaser/internal/artifact"
+	"github.com/oarkflow/releaser/internal/cicd"
+	"github.com/oarkflow/releaser/internal/pipeline"
+)
+
+// ciIntegration selects the CI the run reports to: auto, github or none
+var ciIntegration string
+
+// githubActions reports whether to write GitHub Actions outputs, the step
+// summary and log groups
+func githubActions() bool {
+	switch ciIntegration {
+	case "none":
+		return false
+	case "github":
+		return true
+	default:
+		return cicd.GitHubActions()
+	}
+}
+
+// checkCIIntegration rejects unknown --ci-integration values
+func checkCIIntegration() error {
+	switch ciIntegration {
+	case "auto", "github", "none":
+		return nil
+	}
+	return fmt.Errorf("unknown --ci-integration %q, expected auto, github or none", ciIntegration)
+}
+
+// reportToCI sets the version, tag, release_url and artifacts step outputs
+// and appends the release summary to the job summary. Failing to write
+// them only warns, the release itself succeeded.
+func reportToCI(p *pipeline.Pipeline) {
+	if !githubActions() {
+		return
+	}
+	r := p.Result()
+
+	artifacts, err := json.Marshal(r.Artifacts)
+	if err != nil {
+		log.Warn("Failed to encode the artifacts output", "error", err)
+	}
+	if err := cicd.SetOutputs(map[string]string{
+		"version":     r.Version,
+		"tag":         r.Tag,
+		"release_url": r.ReleaseURL,
+		"artifacts":   string(artifacts),
+	}); err != nil {
+		log.Warn("Failed to write GitHub Actions outputs", "error", err)
+	}
+
+	release := cicd.Release{
+		Title: fmt.Sprintf("%s %s", r.ProjectName, r.Tag),
+		URL:   r.ReleaseURL,
+		Notes: r.Notes,
+	}
+	for _, a := range r.Artifacts {
+		if a.Type == artifact.TypeChecksum {
+			continue
+		}
+		info, err := os.Stat(a.Path)
+		if err != nil || info.IsDir() {
+			// Images and directories have no single file to describe
+			continue
+		}
+		release.Files = append(release.Files, cicd.File{
+			Name:     a.Name,
+			Type:     string(a.Type),
+			Size:     info.Size(),
+			Checksum: r.Checksums[a.Name],
+		})
+	}
+	if err := cicd.WriteSummary(release); err != nil {
+		log.Warn("Failed to write the GitHub Actions job summary", "error", err)
+	}
+}
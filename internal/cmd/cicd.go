@@ -11,7 +11,9 @@ import (
 	"github.com/charmbracelet/log"
 	"github.com/spf13/cobra"
 
+	"github.com/oarkflow/releaser"
 	"github.com/oarkflow/releaser/internal/cicd"
+	"github.com/oarkflow/releaser/internal/config"
 )
 
 var cicdCmd = &cobra.Command{
@@ -20,9 +22,16 @@ var cicdCmd = &cobra.Command{
 	Short:   "Generate CI/CD pipeline configurations",
 	Long: `Generate CI/CD pipeline configurations for various platforms.
 
+The release config (.releaser.yaml or --config) decides what the workflow
+sets up: a macOS runner for DMGs and PKGs, zig for cgo cross-compiling,
+docker, cosign with the OIDC id-token permission when it signs keyless,
+nfpm, syft, UPX, a GPG key, and the secrets its publishers and templates
+use.
+
 Supported platforms:
-  - github    GitHub Actions
+  - github    GitHub Actions (also github-actions)
   - gitlab    GitLab CI
+  - woodpecker Woodpecker CI
   - circleci  CircleCI
   - travis    Travis CI
   - jenkins   Jenkinsfile
@@ -31,9 +40,9 @@ Supported platforms:
   - drone     Drone CI
 
 Examples:
-  releaser cicd github
-  releaser cicd gitlab --docker
-  releaser cicd github --go-version 1.22
+  releaser ci github-actions
+  releaser ci gitlab --docker
+  releaser ci woodpecker --go-version 1.22
 `,
 }
 
@@ -47,7 +56,7 @@ Examples:
   releaser cicd generate gitlab
   releaser cicd generate circleci --docker
 `,
-	ValidArgs: []string{"github", "gitlab", "circleci", "travis", "jenkins", "azure", "bitbucket", "drone"},
+	ValidArgs: []string{"github", "gitlab", "circleci", "travis", "jenkins", "azure", "bitbucket", "drone", "woodpecker"},
 	Args:      cobra.ExactArgs(1),
 	RunE:      runCICDGenerate,
 }
@@ -65,26 +74,31 @@ var (
 )
 
 func init() {
-	cicdGenerateCmd.Flags().StringVar(&cicdGoVersion, "go-version", "1.22", "Go version")
-	cicdGenerateCmd.Flags().StringVar(&cicdNodeVersion, "node-version", "20", "Node.js version")
-	cicdGenerateCmd.Flags().StringVar(&cicdPythonVersion, "python-version", "3.11", "Python version")
-	cicdGenerateCmd.Flags().BoolVar(&cicdDockerEnabled, "docker", false, "Enable Docker build and push")
-	cicdGenerateCmd.Flags().StringVar(&cicdDockerImage, "docker-image", "", "Docker image name")
-	cicdGenerateCmd.Flags().StringVarP(&cicdOutputDir, "output", "o", ".", "Output directory")
-	cicdGenerateCmd.Flags().StringVar(&cicdTestCommand, "test-command", "go test ./...", "Test command")
-	cicdGenerateCmd.Flags().StringVar(&cicdBuildCommand, "build-command", "releaser build --snapshot", "Build command")
-	cicdGenerateCmd.Flags().StringVar(&cicdBranch, "branch", "main", "Main branch name")
+	cicdCmd.PersistentFlags().StringVar(&cicdGoVersion, "go-version", "1.22", "Go version")
+	cicdCmd.PersistentFlags().StringVar(&cicdNodeVersion, "node-version", "20", "Node.js version")
+	cicdCmd.PersistentFlags().StringVar(&cicdPythonVersion, "python-version", "3.11", "Python version")
+	cicdCmd.PersistentFlags().BoolVar(&cicdDockerEnabled, "docker", false, "Enable Docker build and push")
+	cicdCmd.PersistentFlags().StringVar(&cicdDockerImage, "docker-image", "", "Docker image name")
+	cicdCmd.PersistentFlags().StringVarP(&cicdOutputDir, "output", "o", ".", "Output directory")
+	cicdCmd.PersistentFlags().StringVar(&cicdTestCommand, "test-command", "go test ./...", "Test command")
+	cicdCmd.PersistentFlags().StringVar(&cicdBuildCommand, "build-command", "releaser build --snapshot", "Build command")
+	cicdCmd.PersistentFlags().StringVar(&cicdBranch, "branch", "main", "Main branch name")
 
 	cicdCmd.AddCommand(cicdGenerateCmd)
 
 	// Add shortcuts for common platforms
-	for _, platform := range []string{"github", "gitlab", "circleci", "travis", "jenkins", "azure", "bitbucket", "drone"} {
+	for _, platform := range []string{"github", "gitlab", "circleci", "travis", "jenkins", "azure", "bitbucket", "drone", "woodpecker"} {
 		p := platform // capture
+		var aliases []string
+		if platform == "github" {
+			aliases = []string{"github-actions"}
+		}
 		cicdCmd.AddCommand(&cobra.Command{
-			Use:   platform,
-			Short: fmt.Sprintf("Generate %s configuration", platformName(platform)),
+			Use:     platform,
+			Aliases: aliases,
+			Short:   fmt.Sprintf("Generate %s configuration", platformName(platform)),
 			RunE: func(cmd *cobra.Command, args []string) error {
-				return generateCICD(cicd.Platform(p))
+				return generateCICD(cmd, cicd.Platform(p))
 			},
 		})
 	}
@@ -94,10 +108,13 @@ func init() {
 
 func runCICDGenerate(cmd *cobra.Command, args []string) error {
 	platform := cicd.Platform(strings.ToLower(args[0]))
-	return generateCICD(platform)
+	if platform == "github-actions" {
+		platform = cicd.PlatformGitHubActions
+	}
+	return generateCICD(cmd, platform)
 }
 
-func generateCICD(platform cicd.Platform) error {
+func generateCICD(cmd *cobra.Command, platform cicd.Platform) error {
 	opts := cicd.Options{
 		Platform:      platform,
 		GoVersion:     cicdGoVersion,
@@ -108,6 +125,39 @@ func generateCICD(platform cicd.Platform) error {
 		TestCommand:   cicdTestCommand,
 		BuildCommand:  cicdBuildCommand,
 		PublishBranch: cicdBranch,
+		// Install the releaser generating the workflow, so its config works
+		ReleaserVersion: "v" + releaser.Version,
+	}
+
+	// Set up the go version of go.mod unless one was asked for
+	if !cmd.Flags().Changed("go-version") {
+		if version := goModVersion(); version != "" {
+			opts.GoVersion = version
+			opts.GoVersionFile = "go.mod"
+		}
+	}
+
+	// Set up what the release config needs
+	configPath := cfgFile
+	if configPath == "" {
+		configPath = config.Find()
+	}
+	if configPath != "" {
+		raw, err := os.ReadFile(configPath)
+		if err != nil {
+			return err
+		}
+		cfg, err := config.Load(configPath)
+		if err != nil {
+			return fmt.Errorf("failed to load config: %w", err)
+		}
+		if profile != "" {
+			if err := cfg.ApplyProfile(profile); err != nil {
+				return err
+			}
+		}
+		opts.Inspect(cfg, raw)
+		log.Info("Inspected release config", "path", configPath)
 	}
 
 	// Try to get project name from go.mod or package.json
@@ -128,21 +178,41 @@ func generateCICD(platform cicd.Platform) error {
 
 	log.Info("CI/CD configuration generated", "platform", platform, "dir", outputDir)
 	fmt.Printf("\nGenerated %s configuration in %s\n", platformName(string(platform)), outputDir)
-	printPlatformInstructions(platform)
+	printPlatformInstructions(platform, opts.Secrets)
 
 	return nil
 }
 
+// goModVersion returns the major.minor go version of go.mod, as golang
+// image tags name it
+func goModVersion() string {
+	data, err := os.ReadFile("go.mod")
+	if err != nil {
+		return ""
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		if version, ok := strings.CutPrefix(strings.TrimSpace(line), "go "); ok {
+			parts := strings.Split(strings.TrimSpace(version), ".")
+			if len(parts) >= 2 {
+				return parts[0] + "." + parts[1]
+			}
+			return parts[0]
+		}
+	}
+	return ""
+}
+
 func platformName(p string) string {
 	names := map[string]string{
-		"github":    "GitHub Actions",
-		"gitlab":    "GitLab CI",
-		"circleci":  "CircleCI",
-		"travis":    "Travis CI",
-		"jenkins":   "Jenkins",
-		"azure":     "Azure Pipelines",
-		"bitbucket": "Bitbucket Pipelines",
-		"drone":     "Drone CI",
+		"github":     "GitHub Actions",
+		"gitlab":     "GitLab CI",
+		"circleci":   "CircleCI",
+		"travis":     "Travis CI",
+		"jenkins":    "Jenkins",
+		"azure":      "Azure Pipelines",
+		"bitbucket":  "Bitbucket Pipelines",
+		"drone":      "Drone CI",
+		"woodpecker": "Woodpecker CI",
 	}
 	if name, ok := names[p]; ok {
 		return name
@@ -186,16 +256,28 @@ func detectProjectName() string {
 	return "project"
 }
 
-func printPlatformInstructions(platform cicd.Platform) {
+func printPlatformInstructions(platform cicd.Platform, secrets []string) {
 	fmt.Println("\nNext steps:")
 	switch platform {
 	case cicd.PlatformGitHubActions:
 		fmt.Println("  1. Commit .github/workflows/release.yml")
-		fmt.Println("  2. Add GITHUB_TOKEN to repository secrets (auto-provided)")
+		if len(secrets) > 0 {
+			fmt.Printf("  2. Add repository secrets: %s (GITHUB_TOKEN is provided)\n", strings.Join(secrets, ", "))
+		} else {
+			fmt.Println("  2. Nothing to add, GITHUB_TOKEN is provided")
+		}
 		fmt.Println("  3. Push a tag (git tag v1.0.0 && git push --tags)")
 	case cicd.PlatformGitLabCI:
 		fmt.Println("  1. Commit .gitlab-ci.yml")
-		fmt.Println("  2. Configure CI/CD variables in GitLab settings")
+		if len(secrets) > 0 {
+			fmt.Printf("  2. Add masked CI/CD variables: %s\n", strings.Join(secrets, ", "))
+		} else {
+			fmt.Println("  2. Configure CI/CD variables in GitLab settings")
+		}
+		fmt.Println("  3. Push a tag to trigger release")
+	case cicd.PlatformWoodpecker:
+		fmt.Println("  1. Commit .woodpecker/release.yaml")
+		fmt.Printf("  2. Add repository secrets: %s\n", strings.Join(append([]string{"GITHUB_TOKEN"}, secrets...), ", "))
 		fmt.Println("  3. Push a tag to trigger release")
 	case cicd.PlatformCircleCI:
 		fmt.Println("  1. Commit .circleci/config.yml")
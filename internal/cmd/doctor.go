@@ -0,0 +1,45 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/oarkflow/releaser/internal/pipeline"
+)
+
+var doctorIDs []string
+
+var doctorCmd = &cobra.Command{
+	Use:   "doctor",
+	Short: "Check the tools the config needs",
+	Long: `Check that every tool the current config needs is installed.
+
+Builders, packagers, signers and other stages are checked for the tools they
+run, such as go, cargo, nfpm, docker, gpg, cosign, syft and upx. A table
+lists each tool, whether it was found, what needs it and the command
+installing it. Nothing is installed. The command fails when a required tool
+is missing.
+
+Example:
+  releaser doctor
+  releaser doctor --id server --profile nightly`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		p, err := pipeline.New(cmd.Context(), pipeline.ReleaseOptions{
+			ConfigFile: cfgFile,
+			Profile:    profile,
+			Snapshot:   true,
+			IDs:        doctorIDs,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to create pipeline: %w", err)
+		}
+		return p.Doctor(os.Stdout)
+	},
+}
+
+func init() {
+	doctorCmd.Flags().StringSliceVar(&doctorIDs, "id", nil, "only check the builds of these config ids")
+	rootCmd.AddCommand(doctorCmd)
+}
@@ -2,26 +2,42 @@ package cmd
 
 import (
 	"fmt"
+	"os"
 
+	"github.com/mattn/go-isatty"
 	"github.com/spf13/cobra"
 
 	"github.com/oarkflow/releaser/internal/pipeline"
+	"github.com/oarkflow/releaser/internal/publish"
 )
 
+var publishConfirm bool
+
 var publishCmd = &cobra.Command{
 	Use:   "publish",
 	Short: "Publish prepared artifacts",
 	Long: `Publish artifacts that were prepared with 'releaser release --prepare'.
 
 This command reads the prepared artifacts from the dist folder
-and publishes them to the configured registries and repositories.`,
+and publishes them to the configured registries and repositories.
+
+With --confirm, each change, such as creating the GitHub release or
+pushing a formula to a tap, is shown with its URL, files and rendered
+manifest and asked about first: y runs it, n skips it, a runs it and
+every later change, q stops publishing.`,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		ctx := cmd.Context()
+		if err := confirmPublish(); err != nil {
+			return err
+		}
 
 		opts := pipeline.ReleaseOptions{
 			ConfigFile:  cfgFile,
+			Profile:     profile,
 			Parallelism: parallelism,
 			Timeout:     timeout,
+			FromState:   true,
+			GroupLogs:   githubActions(),
 		}
 
 		p, err := pipeline.New(ctx, opts)
@@ -32,6 +48,7 @@ and publishes them to the configured registries and repositories.`,
 		if err := p.Publish(ctx); err != nil {
 			return fmt.Errorf("publish failed: %w", err)
 		}
+		reportToCI(p)
 
 		return nil
 	},
@@ -54,8 +71,11 @@ This sends notifications to configured channels like:
 
 		opts := pipeline.ReleaseOptions{
 			ConfigFile:  cfgFile,
+			Profile:     profile,
 			Parallelism: parallelism,
 			Timeout:     timeout,
+			FromState:   true,
+			GroupLogs:   githubActions(),
 		}
 
 		p, err := pipeline.New(ctx, opts)
@@ -79,11 +99,17 @@ var continueCmd = &cobra.Command{
 This will both publish and announce the release in sequence.`,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		ctx := cmd.Context()
+		if err := confirmPublish(); err != nil {
+			return err
+		}
 
 		opts := pipeline.ReleaseOptions{
 			ConfigFile:  cfgFile,
+			Profile:     profile,
 			Parallelism: parallelism,
 			Timeout:     timeout,
+			FromState:   true,
+			GroupLogs:   githubActions(),
 		}
 
 		p, err := pipeline.New(ctx, opts)
@@ -94,7 +120,26 @@ This will both publish and announce the release in sequence.`,
 		if err := p.Continue(ctx); err != nil {
 			return fmt.Errorf("continue failed: %w", err)
 		}
+		reportToCI(p)
 
 		return nil
 	},
 }
+
+func init() {
+	publishCmd.Flags().BoolVar(&publishConfirm, "confirm", false, "ask before each change publishers make, showing what they are about to do")
+	continueCmd.Flags().BoolVar(&publishConfirm, "confirm", false, "ask before each change publishers make, showing what they are about to do")
+}
+
+// confirmPublish makes publishers ask before each change with --confirm.
+// Without a terminal to answer on it fails rather than waiting forever.
+func confirmPublish() error {
+	if !publishConfirm {
+		return nil
+	}
+	if !isatty.IsTerminal(os.Stdin.Fd()) {
+		return fmt.Errorf("--confirm needs an interactive terminal to ask on, run without it in CI")
+	}
+	publish.Confirmation = publish.NewPrompt(os.Stdin, os.Stderr)
+	return nil
+}
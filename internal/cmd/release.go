@@ -2,6 +2,7 @@ package cmd
 
 import (
 	"fmt"
+	"strings"
 
 	"github.com/spf13/cobra"
 
@@ -10,6 +11,8 @@ import (
 
 var (
 	prepare bool
+	dryRun  bool
+	autoTag bool
 )
 
 var releaseCmd = &cobra.Command{
@@ -28,12 +31,17 @@ This includes:
   - Running after hooks
 
 Use --prepare to prepare the release without publishing or announcing.
-Use --single-target to build for a single architecture locally.`,
+Use --single-target to build for a single architecture locally.
+Use --dry-run to resolve every template and check files, tools and
+credentials without building or publishing anything.
+Use --auto-tag to tag an untagged HEAD with the next --bump version first,
+as releaser tag does; the tag is pushed unless publishing is skipped.`,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		ctx := cmd.Context()
 
 		opts := pipeline.ReleaseOptions{
 			ConfigFile:   cfgFile,
+			Profile:      profile,
 			Prepare:      prepare,
 			Snapshot:     snapshot,
 			Nightly:      nightly,
@@ -45,6 +53,13 @@ Use --single-target to build for a single architecture locally.`,
 			Clean:        clean,
 			Parallelism:  parallelism,
 			Timeout:      timeout,
+			DryRun:       dryRun,
+			Skip:         skip,
+			AutoTag:      autoTag,
+			Bump:         tagBump,
+			SignTag:      tagSign,
+			AllowDirty:   allowDirty,
+			GroupLogs:    githubActions(),
 		}
 
 		p, err := pipeline.New(ctx, opts)
@@ -55,6 +70,9 @@ Use --single-target to build for a single architecture locally.`,
 		if err := p.Run(ctx); err != nil {
 			return fmt.Errorf("release failed: %w", err)
 		}
+		if !dryRun {
+			reportToCI(p)
+		}
 
 		return nil
 	},
@@ -64,10 +82,16 @@ func init() {
 	releaseCmd.Flags().BoolVar(&prepare, "prepare", false, "prepare release without publishing or announcing")
 	releaseCmd.Flags().BoolVar(&snapshot, "snapshot", false, "create a snapshot release (no tag required)")
 	releaseCmd.Flags().BoolVar(&nightly, "nightly", false, "create a nightly release")
-	releaseCmd.Flags().StringVar(&singleTarget, "single-target", "", "build for a single target (e.g., linux_amd64)")
+	releaseCmd.Flags().StringVar(&singleTarget, "single-target", "", "build for a single target (e.g., linux_amd64, or current for the host)")
 	releaseCmd.Flags().BoolVar(&skipPublish, "skip-publish", false, "skip publishing artifacts")
 	releaseCmd.Flags().BoolVar(&skipSign, "skip-sign", false, "skip signing artifacts")
 	releaseCmd.Flags().BoolVar(&skipDocker, "skip-docker", false, "skip Docker builds and publishing")
 	releaseCmd.Flags().BoolVar(&skipAnnounce, "skip-announce", false, "skip announcing the release")
+	releaseCmd.Flags().BoolVar(&dryRun, "dry-run", false, "show what would be released and report all problems without building or publishing")
+	releaseCmd.Flags().StringSliceVar(&skip, "skip", nil, "skip stages, comma separated or repeated: "+strings.Join(pipeline.SkipStages, ", "))
 	releaseCmd.Flags().BoolVar(&clean, "clean", false, "remove dist folder before building")
+	releaseCmd.Flags().BoolVar(&autoTag, "auto-tag", false, "tag HEAD with the next version when it is not tagged")
+	releaseCmd.Flags().StringVar(&tagBump, "bump", "patch", "version part --auto-tag increments: major, minor or patch")
+	releaseCmd.Flags().BoolVar(&tagSign, "sign-tag", false, "sign the tag created by --auto-tag")
+	releaseCmd.Flags().BoolVar(&allowDirty, "allow-dirty", false, "release and --auto-tag with uncommitted changes")
 }
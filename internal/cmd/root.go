@@ -4,6 +4,7 @@ Package cmd provides the CLI commands for Releaser.
 package cmd
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"runtime"
@@ -12,6 +13,9 @@ import (
 	"github.com/spf13/cobra"
 
 	"github.com/oarkflow/releaser/internal/deps"
+	"github.com/oarkflow/releaser/internal/env"
+	"github.com/oarkflow/releaser/internal/httpclient"
+	"github.com/oarkflow/releaser/internal/redact"
 )
 
 var (
@@ -30,7 +34,10 @@ var (
 	singleTarget string
 	autoInstall  bool
 	skipInstall  bool
+	offline      bool
 	silent       bool
+	skip         []string
+	profile      string
 )
 
 // rootCmd represents the base command when called without any subcommands
@@ -49,24 +56,34 @@ Example:
   releaser build --snapshot     # Build snapshot version
   releaser changelog            # Preview changelog`,
 	SilenceUsage: true,
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		return checkCIIntegration()
+	},
 }
 
 // Execute adds all child commands to the root command and sets flags appropriately.
+// Commands run with a context cancelled on Ctrl-C.
 func Execute() error {
-	return rootCmd.Execute()
+	ctx, stop := withSignals(context.Background())
+	defer stop()
+	defer httpclient.Cleanup()
+	return rootCmd.ExecuteContext(ctx)
 }
 
 func init() {
 	cobra.OnInitialize(initConfig)
 
 	// Global flags
-	rootCmd.PersistentFlags().StringVarP(&cfgFile, "config", "c", "", "config file (default is .releaser.yaml)")
+	rootCmd.PersistentFlags().StringVarP(&cfgFile, "config", "c", "", "config file, YAML, JSON or TOML (default is .releaser.yaml)")
+	rootCmd.PersistentFlags().StringVar(&profile, "profile", "", "config profile to apply (default: snapshot, nightly or stable when defined)")
 	rootCmd.PersistentFlags().BoolVarP(&verbose, "verbose", "v", false, "enable verbose output")
 	rootCmd.PersistentFlags().BoolVar(&debug, "debug", false, "enable debug output")
 	rootCmd.PersistentFlags().IntVarP(&parallelism, "parallelism", "p", runtime.NumCPU(), "number of parallel tasks")
-	rootCmd.PersistentFlags().StringVar(&timeout, "timeout", "60m", "timeout for the entire release")
+	rootCmd.PersistentFlags().StringVar(&timeout, "timeout", "", "overall build timeout, overrides timeouts.build (default 2h)")
 	rootCmd.PersistentFlags().BoolVar(&autoInstall, "auto-install", false, "automatically install missing dependencies without prompting")
-	rootCmd.PersistentFlags().BoolVar(&skipInstall, "skip-install", false, "skip dependency installation prompts")
+	rootCmd.PersistentFlags().BoolVar(&skipInstall, "skip-install", false, "never install missing dependencies")
+	rootCmd.PersistentFlags().BoolVar(&offline, "offline", false, "never attempt to install anything over the network")
+	rootCmd.PersistentFlags().StringVar(&ciIntegration, "ci-integration", "auto", "report outputs, a job summary and log groups to the CI: auto (GitHub Actions when GITHUB_ACTIONS=true), github or none")
 
 	// Add subcommands
 	rootCmd.AddCommand(releaseCmd)
@@ -89,14 +106,19 @@ func initConfig() {
 		log.SetLevel(log.WarnLevel)
 	}
 
-	// Configure dependency installation behavior
+	// Configure dependency installation behavior; the flags take
+	// precedence over deps.auto_install in the config
 	if autoInstall {
-		deps.AutoInstall = true
-		deps.PromptForInstall = false
+		deps.SetAutoInstall(true)
 	} else if skipInstall {
-		deps.AutoInstall = false
-		deps.PromptForInstall = false
+		deps.SetAutoInstall(false)
 	}
+	deps.Offline = offline
+
+	// Keep credentials out of logs and error output
+	redact.Env(nil)
+	log.SetOutput(env.NewMaskingWriter(os.Stderr, nil))
+	rootCmd.SetErr(env.NewMaskingWriter(os.Stderr, nil))
 
 	if cfgFile != "" {
 		// Use config file from the flag
@@ -11,16 +11,26 @@ import (
 	"github.com/charmbracelet/log"
 	"github.com/spf13/cobra"
 
+	"github.com/oarkflow/releaser/internal/config"
 	"github.com/oarkflow/releaser/internal/schema"
 )
 
 var schemaCmd = &cobra.Command{
 	Use:   "schema",
-	Short: "JSON Schema utilities",
-	Long: `Generate and validate JSON Schema for releaser configuration.
+	Short: "Print the JSON Schema of the configuration",
+	Long: `Print the JSON Schema of releaser configuration files, YAML, JSON or
+TOML, for editors to validate and complete them.
 
-This command provides utilities for working with the JSON Schema
-that defines the structure of releaser.yaml configuration files.`,
+The schema is generated from the config structs of this releaser version.
+
+Examples:
+  releaser schema > releaser.schema.json
+  releaser schema generate releaser.schema.json
+  releaser schema validate`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return writeSchema("")
+	},
 }
 
 var schemaGenerateCmd = &cobra.Command{
@@ -36,22 +46,11 @@ Examples:
 `,
 	Args: cobra.MaximumNArgs(1),
 	RunE: func(cmd *cobra.Command, args []string) error {
-		s := schema.GenerateSchema()
-		data, err := json.MarshalIndent(s, "", "  ")
-		if err != nil {
-			return fmt.Errorf("failed to marshal schema: %w", err)
-		}
-
+		var output string
 		if len(args) > 0 {
-			if err := os.WriteFile(args[0], data, 0644); err != nil {
-				return fmt.Errorf("failed to write schema: %w", err)
-			}
-			log.Info("Schema written", "path", args[0])
-		} else {
-			fmt.Println(string(data))
+			output = args[0]
 		}
-
-		return nil
+		return writeSchema(output)
 	},
 }
 
@@ -63,14 +62,17 @@ var schemaValidateCmd = &cobra.Command{
 Examples:
   releaser schema validate
   releaser schema validate .releaser.yaml
-  releaser schema validate path/to/config.yaml
+  releaser schema validate path/to/config.toml
 `,
 	Args: cobra.MaximumNArgs(1),
 	RunE: func(cmd *cobra.Command, args []string) error {
-		configPath := ".releaser.yaml"
+		configPath := config.Find()
 		if len(args) > 0 {
 			configPath = args[0]
 		}
+		if configPath == "" {
+			configPath = ".releaser.yaml"
+		}
 
 		// Check if file exists
 		if _, err := os.Stat(configPath); os.IsNotExist(err) {
@@ -91,6 +93,24 @@ Examples:
 	},
 }
 
+// writeSchema writes the schema to output, or stdout when empty
+func writeSchema(output string) error {
+	data, err := json.MarshalIndent(schema.GenerateSchema(), "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal schema: %w", err)
+	}
+
+	if output == "" {
+		fmt.Println(string(data))
+		return nil
+	}
+	if err := os.WriteFile(output, append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("failed to write schema: %w", err)
+	}
+	log.Info("Schema written", "path", output)
+	return nil
+}
+
 func init() {
 	schemaCmd.AddCommand(schemaGenerateCmd)
 	schemaCmd.AddCommand(schemaValidateCmd)
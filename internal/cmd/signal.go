@@ -0,0 +1,43 @@
+package cmd
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/charmbracelet/log"
+)
+
+// withSignals returns a context cancelled on the first interrupt or
+// termination signal, so the running stage stops and the pipeline cleans
+// up. A second signal exits immediately.
+func withSignals(parent context.Context) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(parent)
+	signals := make(chan os.Signal, 2)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+
+	done := make(chan struct{})
+	go func() {
+		select {
+		case <-signals:
+		case <-done:
+			return
+		}
+		log.Warn("Interrupted, stopping; press Ctrl-C again to force exit")
+		cancel()
+
+		select {
+		case <-signals:
+			log.Error("Forced exit")
+			os.Exit(130)
+		case <-done:
+		}
+	}()
+
+	return ctx, func() {
+		signal.Stop(signals)
+		close(done)
+		cancel()
+	}
+}
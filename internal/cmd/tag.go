@@ -0,0 +1,60 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/oarkflow/releaser/internal/pipeline"
+)
+
+var (
+	tagBump    string
+	tagSign    bool
+	tagPush    bool
+	tagRemote  string
+	allowDirty bool
+)
+
+var tagCmd = &cobra.Command{
+	Use:   "tag",
+	Short: "Create the next release tag",
+	Long: `Create the next release tag by bumping the latest tag.
+
+The tag is annotated with git.tag_message, a template that sees the new
+.Tag and .Version and the .PreviousTag (default "{{ .ProjectName }} {{ .Tag }}").
+Tagging is refused when the worktree is dirty unless --allow-dirty is passed.
+
+Example:
+  releaser tag --bump minor --push
+  releaser tag -s`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg, err := pipeline.LoadConfig(pipeline.ReleaseOptions{ConfigFile: cfgFile, Profile: profile})
+		if err != nil {
+			return err
+		}
+
+		tag, err := pipeline.Tag(cmd.Context(), cfg, pipeline.TagOptions{
+			Bump:       tagBump,
+			Sign:       tagSign,
+			Push:       tagPush,
+			Remote:     tagRemote,
+			AllowDirty: allowDirty,
+		})
+		if err != nil {
+			return err
+		}
+
+		fmt.Println(tag)
+		return nil
+	},
+}
+
+func init() {
+	tagCmd.Flags().StringVar(&tagBump, "bump", "patch", "version part to increment: major, minor or patch")
+	tagCmd.Flags().BoolVarP(&tagSign, "sign", "s", false, "create a signed tag")
+	tagCmd.Flags().BoolVar(&tagPush, "push", false, "push the tag after creating it")
+	tagCmd.Flags().StringVar(&tagRemote, "remote", "origin", "remote to push the tag to")
+	tagCmd.Flags().BoolVar(&allowDirty, "allow-dirty", false, "tag even when the worktree has uncommitted changes")
+	rootCmd.AddCommand(tagCmd)
+}
@@ -0,0 +1,165 @@
+package cmd
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/oarkflow/releaser/internal/checksum"
+	"github.com/oarkflow/releaser/internal/sign"
+)
+
+var (
+	verifyChecksums   string
+	verifyGPGKey      string
+	verifyCosignKey   string
+	verifyMinisignKey string
+	verifySSHKey      string
+)
+
+var verifyCmd = &cobra.Command{
+	Use:   "verify --checksums FILE [DIR]",
+	Short: "Verify artifacts against their checksums and signatures",
+	Long: `Verify that the files listed in a checksums file are unchanged.
+
+Each file listed in the checksums file is hashed, relative to DIR (default
+the directory of the checksums file), with the algorithm matching the
+length of its checksum. Every file is checked and reported, and the command
+exits non-zero listing all mismatches and missing files.
+
+With a public key, the .minisig, .asc and .sig signatures found next to the
+checksums file and the listed files are verified too: minisign and SSH
+signatures natively, gpg and cosign signatures with their tools. Each key
+must verify at least one signature.
+
+Example:
+  releaser verify --checksums dist/checksums.txt dist/
+  releaser verify --checksums dist/checksums.txt --minisign-key minisign.pub
+  releaser verify --checksums dist/checksums.txt --cosign-key cosign.pub
+  releaser verify --checksums dist/checksums.txt --ssh-key id_ed25519.pub`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if verifyChecksums == "" {
+			return fmt.Errorf("--checksums is required")
+		}
+		entries, err := checksum.ParseFile(verifyChecksums)
+		if err != nil {
+			return err
+		}
+		dir := filepath.Dir(verifyChecksums)
+		if len(args) == 1 {
+			dir = args[0]
+		}
+
+		var failures []string
+		report := func(name string, problem string) {
+			if problem == "" {
+				fmt.Printf("✓ %s\n", name)
+				return
+			}
+			fmt.Printf("✗ %s: %s\n", name, problem)
+			failures = append(failures, fmt.Sprintf("%s: %s", name, problem))
+		}
+
+		files := []string{verifyChecksums}
+		for _, e := range entries {
+			if err := cmd.Context().Err(); err != nil {
+				return err
+			}
+			path, problem := verifyEntry(dir, e)
+			if path != "" {
+				files = append(files, path)
+			}
+			report(e.Name, problem)
+		}
+
+		keys := map[string]string{
+			sign.MethodGPG:      verifyGPGKey,
+			sign.MethodCosign:   verifyCosignKey,
+			sign.MethodMinisign: verifyMinisignKey,
+			sign.MethodSSH:      verifySSHKey,
+		}
+		verified := make(map[string]int)
+		for _, path := range files {
+			for _, ext := range sign.SignatureExtensions {
+				sigPath := path + ext
+				if _, err := os.Stat(sigPath); err != nil {
+					continue
+				}
+				method, err := sign.SignatureMethod(sigPath)
+				if err != nil {
+					report(filepath.Base(sigPath), err.Error())
+					continue
+				}
+				if keys[method] == "" {
+					continue
+				}
+				verified[method]++
+				problem := ""
+				if err := sign.VerifySignature(cmd.Context(), method, keys[method], path, sigPath); err != nil {
+					problem = err.Error()
+				}
+				report(fmt.Sprintf("%s (%s)", filepath.Base(sigPath), method), problem)
+			}
+		}
+		for _, method := range []string{sign.MethodGPG, sign.MethodCosign, sign.MethodMinisign, sign.MethodSSH} {
+			if keys[method] != "" && verified[method] == 0 {
+				report(method+" signatures", "none found")
+			}
+		}
+
+		if len(failures) > 0 {
+			return fmt.Errorf("%d of the checks failed:\n  %s", len(failures), strings.Join(failures, "\n  "))
+		}
+		fmt.Printf("\nAll %d files match %s\n", len(entries), verifyChecksums)
+		return nil
+	},
+}
+
+// verifyEntry finds the file of a checksums entry in dir and describes how
+// it differs from its checksum, or returns an empty problem when it
+// matches. Binaries are listed by name but kept in per-target directories,
+// so a name missing from dir itself is looked up in its subdirectories and
+// any file of that name matching the checksum passes.
+func verifyEntry(dir string, e checksum.Entry) (string, string) {
+	candidates := []string{filepath.Join(dir, filepath.FromSlash(e.Name))}
+	if _, err := os.Stat(candidates[0]); err != nil {
+		candidates = nil
+		filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+			if err == nil && !d.IsDir() && d.Name() == filepath.Base(e.Name) {
+				candidates = append(candidates, path)
+			}
+			return nil
+		})
+	}
+	if len(candidates) == 0 {
+		return "", "missing"
+	}
+
+	problem := ""
+	for _, path := range candidates {
+		ok, err := checksum.VerifyChecksum(path, e.Sum, e.Algorithm)
+		switch {
+		case err != nil:
+			problem = fmt.Sprintf("unreadable: %v", err)
+		case ok:
+			return path, ""
+		default:
+			problem = fmt.Sprintf("%s does not match", e.Algorithm)
+		}
+	}
+	return candidates[0], problem
+}
+
+func init() {
+	verifyCmd.Flags().StringVar(&verifyChecksums, "checksums", "", "checksums file to verify, e.g. dist/checksums.txt")
+	verifyCmd.Flags().StringVar(&verifyGPGKey, "gpg-key", "", "GPG public key to verify .asc and OpenPGP .sig signatures with")
+	verifyCmd.Flags().StringVar(&verifyCosignKey, "cosign-key", "", "cosign public key to verify .sig signatures with")
+	verifyCmd.Flags().StringVar(&verifyMinisignKey, "minisign-key", "", "minisign public key to verify .minisig signatures with")
+	verifyCmd.Flags().StringVar(&verifySSHKey, "ssh-key", "", "SSH public key to verify SSHSIG .sig signatures with")
+	rootCmd.AddCommand(verifyCmd)
+}
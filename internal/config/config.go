@@ -5,15 +5,19 @@ package config
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
 	"regexp"
 	"strings"
 	"text/template"
+	"text/template/parse"
+	"time"
 
-	"dario.cat/mergo"
 	"gopkg.in/yaml.v3"
+
+	"github.com/oarkflow/releaser/internal/target"
 )
 
 // Config represents the complete Releaser configuration
@@ -22,7 +26,7 @@ type Config struct {
 	Version int `yaml:"version"`
 
 	// ProjectName is the name of the project
-	ProjectName string `yaml:"project_name"`
+	ProjectName string `yaml:"project_name" jsonschema:"required"`
 
 	// Dist is the output directory for artifacts
 	Dist string `yaml:"dist,omitempty"`
@@ -30,18 +34,51 @@ type Config struct {
 	// CleanupDistDirs removes build folders inside dist after packaging completes
 	CleanupDistDirs bool `yaml:"cleanup_dist_dirs,omitempty"`
 
+	// DistLayout places final artifacts in dist: flat (default), by-target
+	// or by-type
+	DistLayout string `yaml:"dist_layout,omitempty" enum:"flat,by-target,by-type"`
+
 	// Global defaults
 	Defaults Defaults `yaml:"defaults,omitempty"`
 
-	// Environment variables (list of KEY=VALUE strings)
+	// Environment variables (list of KEY=VALUE strings, templated) exported
+	// to every process the pipeline runs
 	Env []string `yaml:"env,omitempty"`
 
-	// Custom template variables
+	// Dotenv files loaded before env is expanded
+	EnvFiles []string `yaml:"env_files,omitempty"`
+
+	// Names of environment variables whose values are masked in logs
+	MaskEnv []string `yaml:"mask_env,omitempty"`
+
+	// StrictEnv makes templates fail when they reference an unset .Env variable
+	StrictEnv bool `yaml:"strict_env,omitempty"`
+
+	// Stage time limits
+	Timeouts Timeouts `yaml:"timeouts,omitempty"`
+
+	// Deps controls how missing tools are handled
+	Deps DepsConfig `yaml:"deps,omitempty"`
+
+	// Requirements are checked before anything is built
+	Requirements Requirements `yaml:"requirements,omitempty"`
+
+	// HTTP configures outbound HTTP requests
+	HTTP HTTPConfig `yaml:"http,omitempty"`
+
+	// GitHubURLs points GitHub publishing at a GitHub Enterprise Server
+	GitHubURLs GitHubURLs `yaml:"github_urls,omitempty"`
+
+	// Custom template variables, available as {{ .Var.name }}
 	Variables map[string]interface{} `yaml:"variables,omitempty"`
 
-	// Include other configuration files
+	// Include other configuration files, by path, glob or http(s) URL
 	Includes []string `yaml:"includes,omitempty"`
 
+	// Profiles are named overlays merged over the config, selected with
+	// --profile or automatically for snapshot, nightly and stable releases
+	Profiles map[string]Config `yaml:"profiles,omitempty"`
+
 	// Before hooks run at the start of the release
 	Before Hooks `yaml:"before,omitempty"`
 
@@ -75,6 +112,9 @@ type Config struct {
 	// Homebrew taps configuration
 	Brews []Brew `yaml:"brews,omitempty"`
 
+	// Homebrew casks configuration
+	Casks []Cask `yaml:"casks,omitempty"`
+
 	// Scoop buckets configuration
 	Scoops []Scoop `yaml:"scoops,omitempty"`
 
@@ -99,6 +139,10 @@ type Config struct {
 	// Windows NSIS installers configuration
 	NSISs []NSIS `yaml:"nsiss,omitempty"`
 
+	// Localizations translate the names and descriptions shown by
+	// installers and desktop entries, by language code such as de or pt_BR
+	Localizations map[string]Localization `yaml:"localizations,omitempty"`
+
 	// Signs configuration
 	Signs []Sign `yaml:"signs,omitempty"`
 
@@ -108,6 +152,13 @@ type Config struct {
 	// Checksum configuration
 	Checksum Checksum `yaml:"checksum,omitempty"`
 
+	// ExtraFiles are files made outside releaser that are released with
+	// the artifacts, checksummed, signed and uploaded
+	ExtraFiles []ExtraFile `yaml:"extra_files,omitempty"`
+
+	// Provenance (SLSA) configuration
+	Provenance Provenance `yaml:"provenance,omitempty"`
+
 	// Changelog configuration
 	Changelog Changelog `yaml:"changelog,omitempty"`
 
@@ -117,6 +168,9 @@ type Config struct {
 	// Announce configuration
 	Announce Announce `yaml:"announce,omitempty"`
 
+	// GoMod warms up and verifies the Go module proxy before announcing
+	GoMod GoMod `yaml:"gomod,omitempty"`
+
 	// Blobs (S3, GCS, Azure) configuration
 	Blobs []Blob `yaml:"blobs,omitempty"`
 
@@ -126,6 +180,12 @@ type Config struct {
 	// Publishers configuration
 	Publishers []Publisher `yaml:"publishers,omitempty"`
 
+	// Publish configures how the publish stage handles failures
+	Publish PublishConfig `yaml:"publish,omitempty"`
+
+	// Plugins are executables notified of pipeline events
+	Plugins []Plugin `yaml:"plugins,omitempty"`
+
 	// Source archive configuration
 	Source Source `yaml:"source,omitempty"`
 
@@ -165,9 +225,21 @@ type Config struct {
 	// Template files configuration
 	TemplateFiles []TemplateFile `yaml:"template_files,omitempty"`
 
+	// Docs configuration
+	Docs Docs `yaml:"docs,omitempty"`
+
+	// Update feeds configuration
+	Updates Updates `yaml:"updates,omitempty"`
+
 	// Metadata configuration
 	Metadata Metadata `yaml:"metadata,omitempty"`
 
+	// Size report configuration
+	SizeReport SizeReport `yaml:"size_report,omitempty"`
+
+	// SizeLimits fail the release when artifacts grow too large
+	SizeLimits []SizeLimit `yaml:"size_limits,omitempty"`
+
 	// Monorepo configuration
 	Monorepo Monorepo `yaml:"monorepo,omitempty"`
 
@@ -235,6 +307,122 @@ type Defaults struct {
 	Vendor string `yaml:"vendor,omitempty"`
 }
 
+// Timeouts configures stage time limits as Go durations (e.g. 45m, 1h).
+// Empty values use the defaults: 2h for all builds, 30m per target, and no
+// limit for publish and docker.
+type Timeouts struct {
+	// Build bounds the whole build stage; --timeout overrides it
+	Build string `yaml:"build,omitempty"`
+
+	// PerTarget bounds each build target
+	PerTarget string `yaml:"per_target,omitempty"`
+
+	// Publish bounds the publish stage
+	Publish string `yaml:"publish,omitempty"`
+
+	// Docker bounds docker image builds and pushes
+	Docker string `yaml:"docker,omitempty"`
+}
+
+// DepsConfig controls the installation of missing tools
+type DepsConfig struct {
+	// AutoInstall installs missing tools without asking when true, and never
+	// installs them when false; --auto-install and --skip-install override
+	// it. Unset, interactive sessions are asked.
+	AutoInstall *bool `yaml:"auto_install,omitempty"`
+
+	// FailFast fails the build before compiling anything when a tool is
+	// missing, listing all of them, instead of warning and degrading
+	FailFast bool `yaml:"fail_fast,omitempty"`
+}
+
+// Requirements declare what a release needs, so a missing credential or
+// tool fails the run at once, all listed together, instead of midway
+type Requirements struct {
+	// Env lists variables that must be set; they are checked on runs that
+	// publish, along with the credentials of the configured publishers
+	Env []string `yaml:"env,omitempty"`
+
+	// Tools lists binaries that must be in PATH
+	Tools []string `yaml:"tools,omitempty"`
+
+	// MinGo is the lowest go version allowed, e.g. "1.22"
+	MinGo string `yaml:"min_go,omitempty"`
+}
+
+// PublishConfig configures the publish stage. Each publisher runs even when
+// another one failed, and the stage fails at the end.
+type PublishConfig struct {
+	// FailFast stops publishing at the first failed publisher
+	FailFast bool `yaml:"fail_fast,omitempty"`
+}
+
+// Plugin is an executable run for the pipeline events it subscribes to,
+// with the event as JSON on its stdin
+type Plugin struct {
+	// Name identifies the plugin in logs (default: the command)
+	Name string `yaml:"name,omitempty"`
+
+	// Cmd is the executable to run, with its Args
+	Cmd  string   `yaml:"cmd" jsonschema:"required"`
+	Args []string `yaml:"args,omitempty"`
+
+	// Events lists the events to receive: build.success, archive.created,
+	// publish.done and release.failed (default: all)
+	Events []string `yaml:"events,omitempty" enum:"build.success,archive.created,publish.done,release.failed"`
+
+	// Timeout bounds each run, e.g. 30s (default: 5m)
+	Timeout string `yaml:"timeout,omitempty"`
+
+	// OnError is warn (default) to log a failed run, or fail to fail the
+	// release
+	OnError string `yaml:"on_error,omitempty" enum:"warn,fail"`
+}
+
+// HTTPConfig configures the client of every outbound HTTP request. Proxies
+// come from HTTP_PROXY, HTTPS_PROXY and NO_PROXY.
+type HTTPConfig struct {
+	// CABundle is a PEM file of extra certificate authorities to trust, on
+	// top of the system roots and SSL_CERT_FILE
+	CABundle string `yaml:"ca_bundle,omitempty"`
+
+	// Timeout bounds each request, uploads included, e.g. 10m (default: no
+	// limit)
+	Timeout string `yaml:"timeout,omitempty"`
+
+	// Retry is the policy of retried requests
+	Retry HTTPRetry `yaml:"retry,omitempty"`
+}
+
+// HTTPRetry is the retry policy of outbound requests
+type HTTPRetry struct {
+	// Attempts is the maximum number of attempts, the first included
+	// (default: 5)
+	Attempts int `yaml:"attempts,omitempty"`
+
+	// InitialDelay is the delay before the first retry, doubled after each
+	// one (default: 1s)
+	InitialDelay string `yaml:"initial_delay,omitempty"`
+
+	// MaxDelay caps the delay between attempts (default: 30s)
+	MaxDelay string `yaml:"max_delay,omitempty"`
+}
+
+// GitHubURLs are the endpoints of a GitHub Enterprise Server. Upload and
+// download default to the Enterprise layout under the API host.
+type GitHubURLs struct {
+	// API is the REST API root, e.g. https://github.example.com/api/v3
+	API string `yaml:"api,omitempty"`
+
+	// Upload is the release asset upload root (default:
+	// https://<host>/api/uploads)
+	Upload string `yaml:"upload,omitempty"`
+
+	// Download is the root release downloads are served from (default:
+	// https://<host>)
+	Download string `yaml:"download,omitempty"`
+}
+
 // Hooks represents before/after hooks
 type Hooks struct {
 	// Commands to run
@@ -252,8 +440,11 @@ type Hooks struct {
 
 // Hook represents a single hook command
 type Hook struct {
+	// ID names the hook; captured output is available as {{ .Outputs.<id> }}
+	ID string `yaml:"id,omitempty"`
+
 	// Command to run
-	Cmd string `yaml:"cmd"`
+	Cmd string `yaml:"cmd" jsonschema:"required"`
 
 	// Directory to run the command in
 	Dir string `yaml:"dir,omitempty"`
@@ -261,17 +452,31 @@ type Hook struct {
 	// Environment variables
 	Env map[string]string `yaml:"env,omitempty"`
 
-	// Output handling
+	// Output handling: log (default), discard or capture
 	Output string `yaml:"output,omitempty"`
 
-	// If condition
+	// If condition, a template that must render "true" for the hook to run
 	If string `yaml:"if,omitempty"`
 
-	// FailFast stops on error
-	FailFast bool `yaml:"fail_fast,omitempty"`
+	// FailFast stops on error (default); "false" records the failure and
+	// runs the remaining hooks
+	FailFast string `yaml:"fail_fast,omitempty"`
 
-	// Shell runs command in shell
-	Shell bool `yaml:"shell,omitempty"`
+	// Shell runs command in the platform shell (default: sh on unix, cmd on
+	// Windows); "false" executes argv directly, or name a shell such as
+	// bash or pwsh
+	Shell string `yaml:"shell,omitempty"`
+}
+
+// UnmarshalYAML allows Hook to be specified as either a command string or object
+func (h *Hook) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind == yaml.ScalarNode {
+		h.Cmd = value.Value
+		return nil
+	}
+
+	type rawHook Hook
+	return value.Decode((*rawHook)(h))
 }
 
 // InstallStep represents an install instruction executed before a build.
@@ -312,11 +517,20 @@ type GitConfig struct {
 
 	// IgnoreTags for filtering tags
 	IgnoreTags []string `yaml:"ignore_tags,omitempty"`
+
+	// TagPattern is the regexp release tags must match (default: semver
+	// with an optional "v" prefix)
+	TagPattern string `yaml:"tag_pattern,omitempty"`
+
+	// TagMessage is the templated annotation of tags created by releaser tag
+	// and release --auto-tag (default "{{ .ProjectName }} {{ .Tag }}")
+	TagMessage string `yaml:"tag_message,omitempty"`
 }
 
 // VersioningConfig allows customizing rendered version strings
 type VersioningConfig struct {
-	// Template overrides the default version string (applied after snapshot/nightly logic)
+	// Template overrides the version derived from the tag; snapshot and
+	// nightly suffixes are added to the result
 	Template string `yaml:"template,omitempty"`
 
 	// RawTemplate overrides RawVersion (defaults to the same as Template when empty)
@@ -328,8 +542,9 @@ type Build struct {
 	// ID of the build
 	ID string `yaml:"id,omitempty"`
 
-	// Builder to use (go, rust, node, python, prebuilt)
-	Builder string `yaml:"builder,omitempty"`
+	// Builder to use (go, rust, node, deno, bun, dotnet, gomobile, python,
+	// java, php, prebuilt) or one of their aliases
+	Builder string `yaml:"builder,omitempty" enum:"go,rust,cargo,node,npm,yarn,pnpm,deno,bun,dotnet,gomobile,python,pip,poetry,pyinstaller,java,maven,mvn,gradle,php,composer,phar,prebuilt"`
 
 	// Type of application: "cli" (default), "gui", "service", "library"
 	Type string `yaml:"type,omitempty"`
@@ -343,10 +558,16 @@ type Build struct {
 	// Binary name
 	Binary string `yaml:"binary,omitempty"`
 
+	// Package is the cargo workspace member to build (Rust builds, -p)
+	Package string `yaml:"package,omitempty"`
+
+	// Bin is the cargo binary to build (Rust builds, --bin)
+	Bin string `yaml:"bin,omitempty"`
+
 	// Flags for the builder
 	Flags []string `yaml:"flags,omitempty"`
 
-	// Ldflags for Go builds
+	// Ldflags for Go builds, passed as RUSTFLAGS to Rust builds
 	Ldflags []string `yaml:"ldflags,omitempty"`
 
 	// LdflagsMap for Go builds (key-value pairs for -X flags)
@@ -379,6 +600,9 @@ type Build struct {
 	// Targets to build (alternative to goos/goarch)
 	Targets []string `yaml:"targets,omitempty"`
 
+	// Platforms gomobile binds for: android, ios (default both)
+	Platforms []string `yaml:"platforms,omitempty"`
+
 	// Mod for Go modules mode
 	Mod string `yaml:"mod,omitempty"`
 
@@ -388,9 +612,12 @@ type Build struct {
 	// Gcflags for Go builds
 	Gcflags []string `yaml:"gcflags,omitempty"`
 
-	// Buildmode for Go builds
+	// Buildmode for Go builds; c-shared and c-archive build C libraries
 	Buildmode string `yaml:"buildmode,omitempty"`
 
+	// Library options for c-shared and c-archive builds
+	Library LibraryConfig `yaml:"library,omitempty"`
+
 	// ModTimestamp for reproducible builds
 	ModTimestamp string `yaml:"mod_timestamp,omitempty"`
 
@@ -415,6 +642,12 @@ type Build struct {
 	// Obfuscation configuration for Go builds
 	Obfuscation GoObfuscationConfig `yaml:"obfuscation,omitempty"`
 
+	// Node executable bundling for Node.js builds
+	Node NodeBuildConfig `yaml:"node,omitempty"`
+
+	// Dotnet publish options for .NET builds
+	Dotnet DotnetBuildConfig `yaml:"dotnet,omitempty"`
+
 	// GUI application metadata
 	GUI *GUIConfig `yaml:"gui,omitempty"`
 
@@ -428,6 +661,46 @@ type Build struct {
 	Overrides []BuildOverride `yaml:"overrides,omitempty"`
 }
 
+// LibraryConfig configures the files shipped with C library builds
+type LibraryConfig struct {
+	// PkgConfig writes a pkg-config .pc file next to the library
+	PkgConfig bool `yaml:"pkg_config,omitempty"`
+
+	// PkgConfigTemplate replaces the default .pc file, a template with
+	// LibName set to the name -l takes
+	PkgConfigTemplate string `yaml:"pkg_config_template,omitempty"`
+}
+
+// NodeBuildConfig configures how Node.js builds become executables
+type NodeBuildConfig struct {
+	// Bundler is pkg (default), nexe or sea (Node 20+ single executable
+	// applications)
+	Bundler string `yaml:"bundler,omitempty"`
+
+	// Version is the Node.js version to embed with pkg and nexe, e.g. 20 or
+	// 20.11.1
+	Version string `yaml:"version,omitempty"`
+
+	// Binary is the target's node executable for sea builds, a template
+	// (defaults to the host node for native builds)
+	Binary string `yaml:"binary,omitempty"`
+}
+
+// DotnetBuildConfig configures dotnet publish
+type DotnetBuildConfig struct {
+	// SelfContained bundles the .NET runtime
+	SelfContained bool `yaml:"self_contained,omitempty"`
+
+	// SingleFile publishes a single executable instead of a directory
+	SingleFile bool `yaml:"single_file,omitempty"`
+
+	// Trimmed removes unused assemblies, self-contained builds only
+	Trimmed bool `yaml:"trimmed,omitempty"`
+
+	// Framework to publish when the project targets several
+	Framework string `yaml:"framework,omitempty"`
+}
+
 // CgoConfig represents CGO cross-compilation configuration
 type CgoConfig struct {
 	// Enabled enables CGO (default: false)
@@ -467,6 +740,21 @@ type GoObfuscationConfig struct {
 	// Flags passed directly to the obfuscation tool before the subcommand
 	Flags []string `yaml:"flags,omitempty"`
 
+	// Seed passed to garble as -seed: "random" (default) draws one seed per
+	// release for all targets, anything else is a base64 seed template,
+	// e.g. {{ .Env.GARBLE_SEED }}, for reproducible builds
+	Seed string `yaml:"seed,omitempty"`
+
+	// Literals obfuscates string literals with -literals (default: true)
+	Literals *bool `yaml:"literals,omitempty"`
+
+	// Tiny strips extra information with -tiny (default: true)
+	Tiny *bool `yaml:"tiny,omitempty"`
+
+	// ReverseMap writes the seed and flags garble reverse needs next to
+	// each binary, registered as a private artifact
+	ReverseMap bool `yaml:"reverse_map,omitempty"`
+
 	// Env appends environment variables when running the obfuscation tool
 	Env []string `yaml:"env,omitempty"`
 
@@ -524,6 +812,12 @@ type BuildIgnore struct {
 type BuildHooks struct {
 	Pre  string `yaml:"pre,omitempty"`
 	Post string `yaml:"post,omitempty"`
+
+	// PrePerArtifact and PostPerArtifact run before and after each binary
+	// is produced, from source or the build cache. Templates see
+	// .ArtifactPath, .ArtifactName, .Os and .Arch.
+	PrePerArtifact  []Hook `yaml:"pre_per_artifact,omitempty"`
+	PostPerArtifact []Hook `yaml:"post_per_artifact,omitempty"`
 }
 
 // GUIConfig represents GUI application configuration
@@ -565,9 +859,25 @@ type GUIConfig struct {
 	Windows *GUIWindows `yaml:"windows,omitempty"`
 }
 
+// Localization holds the strings of one language. Strings left empty
+// keep the untranslated ones.
+type Localization struct {
+	// Name is the product or application name
+	Name string `yaml:"name,omitempty"`
+
+	// Comment is the short description of desktop entries and shortcuts
+	Comment string `yaml:"comment,omitempty"`
+
+	// Description describes the product in installers
+	Description string `yaml:"description,omitempty"`
+}
+
+// localeKey matches language codes like de, pt_BR or sr@latin
+var localeKey = regexp.MustCompile(`^[a-z]{2,3}(_[A-Z]{2})?(@[a-z]+)?$`)
+
 // GUIAction represents a desktop action
 type GUIAction struct {
-	Name string `yaml:"name"`
+	Name string `yaml:"name" jsonschema:"required"`
 	Exec string `yaml:"exec,omitempty"`
 	Icon string `yaml:"icon,omitempty"`
 }
@@ -610,7 +920,7 @@ type GUIWindows struct {
 
 // WindowsFileAssoc represents a Windows file association
 type WindowsFileAssoc struct {
-	Extension   string `yaml:"extension"`
+	Extension   string `yaml:"extension" jsonschema:"required"`
 	Description string `yaml:"description,omitempty"`
 	Icon        string `yaml:"icon,omitempty"`
 }
@@ -631,30 +941,35 @@ type BuildOverride struct {
 type Archive struct {
 	ID                        string                  `yaml:"id,omitempty"`
 	Builds                    []string                `yaml:"builds,omitempty"`
-	Format                    string                  `yaml:"format,omitempty"`
+	Format                    string                  `yaml:"format,omitempty" enum:"tar.gz,tgz,tar.xz,txz,tar,zip,binary"`
 	FormatOverrides           []ArchiveFormatOverride `yaml:"format_overrides,omitempty"`
 	NameTemplate              string                  `yaml:"name_template,omitempty"`
 	WrapInDirectory           string                  `yaml:"wrap_in_directory,omitempty"`
 	StripParentDir            bool                    `yaml:"strip_parent_dir,omitempty"`
 	Files                     []ArchiveFile           `yaml:"files,omitempty"`
+	Generated                 []ArchiveGenerated      `yaml:"generated,omitempty"`
 	Meta                      bool                    `yaml:"meta,omitempty"`
 	AllowDifferentBinaryCount bool                    `yaml:"allow_different_binary_count,omitempty"`
 	Hooks                     ArchiveHooks            `yaml:"hooks,omitempty"`
 	If                        string                  `yaml:"if,omitempty"`
+	Disable                   string                  `yaml:"disable,omitempty"`
 }
 
 // ArchiveFormatOverride for OS-specific formats
 type ArchiveFormatOverride struct {
-	Goos   string `yaml:"goos"`
-	Format string `yaml:"format"`
+	Goos   string `yaml:"goos" jsonschema:"required"`
+	Format string `yaml:"format" jsonschema:"required" enum:"tar.gz,tgz,tar.xz,txz,tar,zip,binary"`
 }
 
 // ArchiveFile represents a file to include in archive
 type ArchiveFile struct {
-	Src         string          `yaml:"src"`
+	Src         string          `yaml:"src,omitempty"`
 	Dst         string          `yaml:"dst,omitempty"`
 	StripParent bool            `yaml:"strip_parent,omitempty"`
 	Info        ArchiveFileInfo `yaml:"info,omitempty"`
+	// SymlinkTo makes dst a symlink to this target instead of copying src,
+	// e.g. ../bin/app
+	SymlinkTo string `yaml:"symlink_to,omitempty"`
 }
 
 // UnmarshalYAML allows ArchiveFile to be specified as either a string or object
@@ -670,12 +985,28 @@ func (f *ArchiveFile) UnmarshalYAML(value *yaml.Node) error {
 	return value.Decode((*rawArchiveFile)(f))
 }
 
-// ArchiveFileInfo for file metadata
+// ArchiveGenerated runs commands writing files into a directory, e.g. shell
+// completions or man pages, which is then included in the archive
+type ArchiveGenerated struct {
+	// Cmds write the files; dir exists before they run
+	Cmds []Hook `yaml:"cmds" jsonschema:"required"`
+
+	// Dir is the directory the commands write to (templated)
+	Dir string `yaml:"dir" jsonschema:"required"`
+
+	// Dst is the directory inside the archive (default: the base name of dir)
+	Dst string `yaml:"dst,omitempty"`
+}
+
+// ArchiveFileInfo overrides the metadata files have on disk. Zip archives
+// have no owner or group.
 type ArchiveFileInfo struct {
-	Owner string      `yaml:"owner,omitempty"`
-	Group string      `yaml:"group,omitempty"`
-	Mode  os.FileMode `yaml:"mode,omitempty"`
-	MTime string      `yaml:"mtime,omitempty"`
+	Owner string `yaml:"owner,omitempty"`
+	Group string `yaml:"group,omitempty"`
+	// Mode replaces the permissions of the file, e.g. 0755
+	Mode os.FileMode `yaml:"mode,omitempty"`
+	// MTime replaces the modification time, in RFC 3339 format
+	MTime string `yaml:"mtime,omitempty"`
 }
 
 // ArchiveHooks for archive before/after hooks
@@ -689,54 +1020,37 @@ type DockerExportConfig struct {
 	ID     string `yaml:"id"`
 	Image  string `yaml:"image"`
 	Format string `yaml:"format"`
-	Output string `yaml:"output"`
+	Output string `yaml:"output" jsonschema:"required"`
+	// CLI is the container CLI: docker, podman or nerdctl
+	CLI string `yaml:"cli,omitempty"`
+	// IDs exports the images built by these docker configs
+	IDs []string `yaml:"ids,omitempty"`
+	// Input is a local OCI layout directory or image tarball to export
+	// instead of, or in addition to, registry images
+	Input string `yaml:"input,omitempty"`
+	// Platform selects the image platform for docker-archive exports
+	Platform string `yaml:"platform,omitempty"`
 }
 
 // Load loads configuration from a file
 func Load(path string) (*Config, error) {
-	data, err := os.ReadFile(path)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read config file: %w", err)
+	if !isURL(path) {
+		if abs, err := filepath.Abs(path); err == nil {
+			path = abs
+		}
 	}
 
-	// Expand environment variables
-	data = []byte(os.ExpandEnv(string(data)))
-
-	var cfg Config
-	if err := yaml.Unmarshal(data, &cfg); err != nil {
-		return nil, fmt.Errorf("failed to parse config file: %w", err)
+	cfg, err := loadSource(path, nil)
+	if err != nil {
+		return nil, err
 	}
 
-	// Set defaults
+	// Set defaults after includes so they can set them
 	if cfg.Dist == "" {
 		cfg.Dist = "dist"
 	}
 
-	// Process includes
 	baseDir := filepath.Dir(path)
-	for _, include := range cfg.Includes {
-		includePath := include
-		if !filepath.IsAbs(includePath) {
-			includePath = filepath.Join(baseDir, include)
-		}
-
-		// Support glob patterns
-		matches, err := filepath.Glob(includePath)
-		if err != nil {
-			return nil, fmt.Errorf("invalid include pattern %s: %w", include, err)
-		}
-
-		for _, match := range matches {
-			includeCfg, err := Load(match)
-			if err != nil {
-				return nil, fmt.Errorf("failed to load include %s: %w", match, err)
-			}
-
-			if err := mergo.Merge(&cfg, includeCfg, mergo.WithAppendSlice); err != nil {
-				return nil, fmt.Errorf("failed to merge include %s: %w", match, err)
-			}
-		}
-	}
 
 	// Detect module path from go.mod
 	if data, err := os.ReadFile(filepath.Join(baseDir, "go.mod")); err == nil {
@@ -754,7 +1068,7 @@ func Load(path string) (*Config, error) {
 		}
 	}
 
-	return &cfg, nil
+	return cfg, nil
 }
 
 // Validate validates the configuration
@@ -773,6 +1087,11 @@ func (c *Config) Validate() error {
 			return fmt.Errorf("duplicate build ID: %s", c.Builds[i].ID)
 		}
 		buildIDs[c.Builds[i].ID] = true
+		for _, name := range build.Targets {
+			if _, err := target.Parse(name); err != nil {
+				return fmt.Errorf("build %s: %w", c.Builds[i].ID, err)
+			}
+		}
 	}
 
 	// Validate archives
@@ -780,6 +1099,60 @@ func (c *Config) Validate() error {
 		if archive.ID == "" {
 			c.Archives[i].ID = fmt.Sprintf("archive%d", i)
 		}
+		for j, f := range archive.Files {
+			prefix := fmt.Sprintf("archives[%d].files[%d]", i, j)
+			switch {
+			case f.SymlinkTo != "" && f.Dst == "":
+				return fmt.Errorf("%s: symlink_to needs dst, the path of the symlink", prefix)
+			case f.SymlinkTo != "" && f.Src != "":
+				return fmt.Errorf("%s: set either src or symlink_to", prefix)
+			case f.SymlinkTo == "" && f.Src == "":
+				return fmt.Errorf("%s: src is required", prefix)
+			}
+			if f.Info.MTime != "" {
+				if _, err := time.Parse(time.RFC3339, f.Info.MTime); err != nil {
+					return fmt.Errorf("%s.info.mtime: %w", prefix, err)
+				}
+			}
+		}
+	}
+
+	// Validate localizations
+	for lang := range c.Localizations {
+		if !localeKey.MatchString(lang) {
+			return fmt.Errorf("localizations: invalid language code %q, use codes like de, pt_BR or sr@latin", lang)
+		}
+	}
+
+	// Validate nfpm contents
+	for i, nfpm := range c.NFPMs {
+		for j, content := range nfpm.Contents {
+			if content.SymlinkTo == "" {
+				continue
+			}
+			prefix := fmt.Sprintf("nfpms[%d].contents[%d]", i, j)
+			if content.Src != "" {
+				return fmt.Errorf("%s: set either src or symlink_to", prefix)
+			}
+			if content.Type != "" && content.Type != "symlink" {
+				return fmt.Errorf("%s: symlink_to can't be of type %s", prefix, content.Type)
+			}
+		}
+	}
+
+	// Validate timeouts
+	for name, value := range map[string]string{
+		"build":      c.Timeouts.Build,
+		"per_target": c.Timeouts.PerTarget,
+		"publish":    c.Timeouts.Publish,
+		"docker":     c.Timeouts.Docker,
+	} {
+		if value == "" {
+			continue
+		}
+		if _, err := time.ParseDuration(value); err != nil {
+			return fmt.Errorf("invalid timeouts.%s: %w", name, err)
+		}
 	}
 
 	// Validate templates in configuration
@@ -787,41 +1160,203 @@ func (c *Config) Validate() error {
 		return err
 	}
 
+	// Validate package scripts
+	if err := c.validateNFPMScripts(); err != nil {
+		return err
+	}
+
+	// Validate MSI settings
+	if err := c.validateMSIs(); err != nil {
+		return err
+	}
+
+	// Validate size limits
+	if err := c.validateSizeLimits(); err != nil {
+		return err
+	}
+
 	return nil
 }
 
-// validateTemplates validates all template strings in the configuration
-func (c *Config) validateTemplates() error {
-	templateRe := regexp.MustCompile(`\{\{.*?\}\}`)
-
-	// Helper to validate a template string
-	validateTemplate := func(name, tmpl string) error {
-		if !templateRe.MatchString(tmpl) {
+// validateNFPMScripts checks that every nfpm script and changelog file exists,
+// so packaging does not fail late in a release
+func (c *Config) validateNFPMScripts() error {
+	checkFile := func(name, path string) error {
+		if path == "" || strings.Contains(path, "{{") {
 			return nil
 		}
-		_, err := template.New(name).Parse(tmpl)
-		if err != nil {
-			return fmt.Errorf("invalid template in %s: %w", name, err)
+		if _, err := os.Stat(path); err != nil {
+			return fmt.Errorf("%s: file %q not found", name, path)
 		}
 		return nil
 	}
 
-	// Validate common template fields
-	for i, build := range c.Builds {
-		if err := validateTemplate(fmt.Sprintf("builds[%d].binary", i), build.Binary); err != nil {
-			return err
+	checkScripts := func(prefix string, scripts NFPMScripts) error {
+		for _, s := range []struct {
+			name string
+			path string
+		}{
+			{"preinstall", scripts.PreInstall},
+			{"postinstall", scripts.PostInstall},
+			{"preremove", scripts.PreRemove},
+			{"postremove", scripts.PostRemove},
+		} {
+			if err := checkFile(fmt.Sprintf("%s.scripts.%s", prefix, s.name), s.path); err != nil {
+				return err
+			}
 		}
+		return nil
 	}
 
-	for i, archive := range c.Archives {
-		if err := validateTemplate(fmt.Sprintf("archives[%d].name_template", i), archive.NameTemplate); err != nil {
+	for i, nfpm := range c.NFPMs {
+		prefix := fmt.Sprintf("nfpms[%d]", i)
+		if err := checkScripts(prefix, nfpm.Scripts); err != nil {
 			return err
 		}
+		for format, override := range nfpm.Overrides {
+			if err := checkScripts(fmt.Sprintf("%s.overrides.%s", prefix, format), override.Scripts); err != nil {
+				return err
+			}
+		}
+
+		for name, path := range map[string]string{
+			"changelog":                     nfpm.Changelog,
+			"deb.scripts.rules":             nfpm.Deb.Scripts.Rules,
+			"deb.scripts.templates":         nfpm.Deb.Scripts.Templates,
+			"deb.scripts.config":            nfpm.Deb.Scripts.Config,
+			"deb.scripts.postinst":          nfpm.Deb.Scripts.Postinst,
+			"rpm.scripts.pretrans":          nfpm.RPM.Scripts.Pretrans,
+			"rpm.scripts.posttrans":         nfpm.RPM.Scripts.Posttrans,
+			"rpm.scriptlets.pretrans":       nfpm.RPM.Scriptlets.Pretrans,
+			"rpm.scriptlets.posttrans":      nfpm.RPM.Scriptlets.Posttrans,
+			"apk.scripts.preupgrade":        nfpm.APK.Scripts.PreUpgrade,
+			"apk.scripts.postupgrade":       nfpm.APK.Scripts.PostUpgrade,
+			"archlinux.scripts.preupgrade":  nfpm.Archlinux.Scripts.PreUpgrade,
+			"archlinux.scripts.postupgrade": nfpm.Archlinux.Scripts.PostUpgrade,
+		} {
+			if err := checkFile(prefix+"."+name, path); err != nil {
+				return err
+			}
+		}
 	}
 
 	return nil
 }
 
+// msiGUIDPattern matches a GUID as MSI upgrade codes are written
+var msiGUIDPattern = regexp.MustCompile(`^\{?[0-9A-Fa-f]{8}-[0-9A-Fa-f]{4}-[0-9A-Fa-f]{4}-[0-9A-Fa-f]{4}-[0-9A-Fa-f]{12}\}?$`)
+
+// validateMSIs checks upgrade codes and services. An all-zero upgrade code
+// is valid XML but makes every upgrade install side by side.
+func (c *Config) validateMSIs() error {
+	for i, msi := range c.MSIs {
+		prefix := fmt.Sprintf("msis[%d]", i)
+		if msi.UpgradeCode != "" {
+			if !msiGUIDPattern.MatchString(msi.UpgradeCode) {
+				return fmt.Errorf("%s.upgrade_code: %q is not a GUID", prefix, msi.UpgradeCode)
+			}
+			if strings.Trim(msi.UpgradeCode, "{}0-") == "" {
+				return fmt.Errorf("%s.upgrade_code: the all-zero GUID breaks upgrades, remove it to derive one from identifier", prefix)
+			}
+		}
+		if msi.Service != nil {
+			if msi.Service.Name == "" {
+				return fmt.Errorf("%s.service.name is required", prefix)
+			}
+			switch msi.Service.Start {
+			case "", "auto", "demand", "disabled":
+			default:
+				return fmt.Errorf("%s.service.start: invalid value %q, valid values are: auto, demand, disabled", prefix, msi.Service.Start)
+			}
+		}
+	}
+	return nil
+}
+
+// Template is a template string of the configuration and the field it
+// comes from, e.g. "archives[0].name_template"
+type Template struct {
+	Source string
+	Text   string
+}
+
+// Templates returns the templates that name what a release produces:
+// binaries, archives, packages, images and the release itself
+func (c *Config) Templates() []Template {
+	var templates []Template
+	add := func(text, format string, args ...interface{}) {
+		if strings.Contains(text, "{{") {
+			templates = append(templates, Template{Source: fmt.Sprintf(format, args...), Text: text})
+		}
+	}
+
+	for i, build := range c.Builds {
+		add(build.Binary, "builds[%d].binary", i)
+	}
+	for i, archive := range c.Archives {
+		add(archive.NameTemplate, "archives[%d].name_template", i)
+		add(archive.WrapInDirectory, "archives[%d].wrap_in_directory", i)
+		for j, g := range archive.Generated {
+			add(g.Dir, "archives[%d].generated[%d].dir", i, j)
+		}
+	}
+	for i, nfpm := range c.NFPMs {
+		add(nfpm.FileNameTemplate, "nfpms[%d].file_name_template", i)
+	}
+	for i, ub := range c.UniversalBinaries {
+		add(ub.NameTemplate, "universal_binaries[%d].name_template", i)
+	}
+	for i, dmg := range c.DMGs {
+		add(dmg.NameTemplate, "dmgs[%d].name_template", i)
+	}
+	for i, pkg := range c.PKGs {
+		add(pkg.NameTemplate, "pkgs[%d].name_template", i)
+	}
+	for i, msi := range c.MSIs {
+		add(msi.NameTemplate, "msis[%d].name_template", i)
+	}
+	for i, nsis := range c.NSISs {
+		add(nsis.NameTemplate, "nsiss[%d].name_template", i)
+	}
+	for i, docker := range c.Dockers {
+		for j, image := range docker.ImageTemplates {
+			add(image, "dockers[%d].image_templates[%d]", i, j)
+		}
+	}
+	for i, manifest := range c.DockerManifests {
+		add(manifest.NameTemplate, "docker_manifests[%d].name_template", i)
+		for j, image := range manifest.ImageTemplates {
+			add(image, "docker_manifests[%d].image_templates[%d]", i, j)
+		}
+	}
+	for i, extra := range c.ExtraFiles {
+		add(extra.NameTemplate, "extra_files[%d].name_template", i)
+	}
+	add(c.Checksum.NameTemplate, "checksum.name_template")
+	add(c.Provenance.NameTemplate, "provenance.name_template")
+	add(c.Release.NameTemplate, "release.name_template")
+	add(c.Release.Notes.FromFileTemplate, "release.notes.from_file_template")
+	add(c.Release.Notes.HeaderTemplate, "release.notes.header_template")
+	add(c.Release.Notes.FooterTemplate, "release.notes.footer_template")
+
+	return templates
+}
+
+// validateTemplates checks the syntax of the templates, reporting every
+// broken one. Functions and fields are checked by the template context once
+// the release is resolved.
+func (c *Config) validateTemplates() error {
+	var errs []error
+	for _, t := range c.Templates() {
+		tree := parse.New(t.Source)
+		tree.Mode = parse.SkipFuncCheck
+		if _, err := tree.Parse(t.Text, "", "", map[string]*parse.Tree{}); err != nil {
+			errs = append(errs, fmt.Errorf("%w in %q", err, t.Text))
+		}
+	}
+	return errors.Join(errs...)
+}
+
 // ApplyTemplate applies template variables to a string
 func (c *Config) ApplyTemplate(tmpl string, data map[string]interface{}) (string, error) {
 	t, err := template.New("").Funcs(templateFuncs()).Parse(tmpl)
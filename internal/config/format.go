@@ -0,0 +1,72 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// FileNames are the config files looked for in the working directory, in
+// order
+var FileNames = []string{
+	".releaser.yaml",
+	".releaser.yml",
+	".releaser.json",
+	".releaser.toml",
+	"releaser.yaml",
+	"releaser.yml",
+	"releaser.json",
+	"releaser.toml",
+	".goreleaser.yaml",
+	".goreleaser.yml",
+}
+
+// Find returns the first of FileNames in the working directory, or ""
+// without one
+func Find() string {
+	for _, name := range FileNames {
+		if _, err := os.Stat(name); err == nil {
+			return name
+		}
+	}
+	return ""
+}
+
+// ToYAML converts a JSON or TOML config, told apart by the extension of
+// source, to YAML. Every format then decodes through the yaml tags and
+// unmarshalers of the config types. Other files are returned as they are.
+func ToYAML(source string, data []byte) ([]byte, error) {
+	var doc interface{}
+	switch format(source) {
+	case ".json":
+		if err := json.Unmarshal(data, &doc); err != nil {
+			return nil, fmt.Errorf("invalid JSON: %w", err)
+		}
+	case ".toml":
+		var table map[string]interface{}
+		if err := toml.Unmarshal(data, &table); err != nil {
+			return nil, fmt.Errorf("invalid TOML: %w", err)
+		}
+		doc = table
+	default:
+		return data, nil
+	}
+	return yaml.Marshal(doc)
+}
+
+// format returns the lowercased extension of a config file or URL
+func format(source string) string {
+	if isURL(source) {
+		if u, err := url.Parse(source); err == nil {
+			return strings.ToLower(path.Ext(u.Path))
+		}
+	}
+	return strings.ToLower(filepath.Ext(source))
+}
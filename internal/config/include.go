@@ -0,0 +1,158 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"dario.cat/mergo"
+	"gopkg.in/yaml.v3"
+
+	"github.com/oarkflow/releaser/internal/httpclient"
+)
+
+// includeTimeout bounds fetching a remote include
+const includeTimeout = 30 * time.Second
+
+// loadSource reads, parses and merges a config file or URL with its
+// includes. stack holds the sources being loaded, to detect cycles. Values
+// in the including file take precedence over its includes.
+func loadSource(source string, stack []string) (*Config, error) {
+	for _, seen := range stack {
+		if seen == source {
+			return nil, fmt.Errorf("include cycle: %s -> %s", strings.Join(stack, " -> "), source)
+		}
+	}
+	stack = append(stack, source)
+
+	data, err := readSource(source)
+	if err != nil {
+		return nil, err
+	}
+
+	// Expand environment variables
+	data = []byte(os.ExpandEnv(string(data)))
+
+	data, err = ToYAML(source, data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse config file %s: %w", source, err)
+	}
+	var cfg Config
+	if err := yaml.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("failed to parse config file %s: %w", source, err)
+	}
+
+	for _, include := range cfg.Includes {
+		sources, err := resolveInclude(source, include)
+		if err != nil {
+			return nil, err
+		}
+		for _, src := range sources {
+			includeCfg, err := loadSource(src, stack)
+			if err != nil {
+				return nil, fmt.Errorf("failed to load include %s: %w", include, err)
+			}
+			if err := mergo.Merge(&cfg, includeCfg, mergo.WithAppendSlice); err != nil {
+				return nil, fmt.Errorf("failed to merge include %s: %w", src, err)
+			}
+		}
+	}
+
+	return &cfg, nil
+}
+
+// readSource reads a local config file or fetches an http(s) URL
+func readSource(source string) ([]byte, error) {
+	if !isURL(source) {
+		data, err := os.ReadFile(source)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read config file: %w", err)
+		}
+		return data, nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), includeTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, source, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := httpclient.Client().Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch %s: %w", source, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to fetch %s: status %d", source, resp.StatusCode)
+	}
+	return io.ReadAll(resp.Body)
+}
+
+// resolveInclude resolves an include relative to the file or URL that
+// references it. Local includes may be glob patterns.
+func resolveInclude(parent, include string) ([]string, error) {
+	if isURL(include) {
+		return []string{include}, nil
+	}
+
+	if isURL(parent) {
+		base, err := url.Parse(parent)
+		if err != nil {
+			return nil, err
+		}
+		ref, err := url.Parse(include)
+		if err != nil {
+			return nil, fmt.Errorf("invalid include %s: %w", include, err)
+		}
+		return []string{base.ResolveReference(ref).String()}, nil
+	}
+
+	includePath := include
+	if !filepath.IsAbs(includePath) {
+		includePath = filepath.Join(filepath.Dir(parent), include)
+	}
+
+	matches, err := filepath.Glob(includePath)
+	if err != nil {
+		return nil, fmt.Errorf("invalid include pattern %s: %w", include, err)
+	}
+	if len(matches) == 0 && !strings.ContainsAny(include, "*?[") {
+		return nil, fmt.Errorf("include %s not found", include)
+	}
+	for i, match := range matches {
+		if abs, err := filepath.Abs(match); err == nil {
+			matches[i] = abs
+		}
+	}
+	return matches, nil
+}
+
+// isURL reports whether a source is an http(s) URL
+func isURL(source string) bool {
+	return strings.HasPrefix(source, "https://") || strings.HasPrefix(source, "http://")
+}
+
+// ApplyProfile deep-merges the named profile over the config. Values set in
+// the profile win; slices in the profile replace those of the base. Zero
+// values such as false cannot unset a base value.
+func (c *Config) ApplyProfile(name string) error {
+	profile, ok := c.Profiles[name]
+	if !ok {
+		return fmt.Errorf("profile %q not found", name)
+	}
+	profile.Profiles = nil
+	profile.Includes = nil
+
+	if err := mergo.Merge(c, profile, mergo.WithOverride); err != nil {
+		return fmt.Errorf("failed to apply profile %s: %w", name, err)
+	}
+	return nil
+}
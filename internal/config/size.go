@@ -0,0 +1,79 @@
+package config
+
+import (
+	"fmt"
+	"path"
+	"strconv"
+	"strings"
+)
+
+// sizeUnits are the suffixes ParseSize accepts, longest first so MB is
+// not read as B
+var sizeUnits = []struct {
+	suffix string
+	bytes  int64
+}{
+	{"KB", 1 << 10},
+	{"MB", 1 << 20},
+	{"GB", 1 << 30},
+	{"K", 1 << 10},
+	{"M", 1 << 20},
+	{"G", 1 << 30},
+	{"B", 1},
+}
+
+// ParseSize parses a size such as 25MB, 512K or 1048576 into bytes. Units
+// are powers of 1024.
+func ParseSize(s string) (int64, error) {
+	value := strings.ToUpper(strings.TrimSpace(s))
+	multiplier := int64(1)
+	for _, u := range sizeUnits {
+		if strings.HasSuffix(value, u.suffix) {
+			value, multiplier = strings.TrimSpace(strings.TrimSuffix(value, u.suffix)), u.bytes
+			break
+		}
+	}
+	n, err := strconv.ParseFloat(value, 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid size %q, use a number of bytes or KB, MB or GB", s)
+	}
+	return int64(n * float64(multiplier)), nil
+}
+
+// Growth parses max_growth into a percentage or a size in bytes; percent
+// tells which
+func (l SizeLimit) Growth() (value float64, percent bool, err error) {
+	if strings.HasSuffix(strings.TrimSpace(l.MaxGrowth), "%") {
+		value, err = strconv.ParseFloat(strings.TrimSuffix(strings.TrimSpace(l.MaxGrowth), "%"), 64)
+		if err != nil || value < 0 {
+			return 0, false, fmt.Errorf("invalid percentage %q", l.MaxGrowth)
+		}
+		return value, true, nil
+	}
+	size, err := ParseSize(l.MaxGrowth)
+	return float64(size), false, err
+}
+
+// validateSizeLimits checks the sizes and globs of size_limits
+func (c *Config) validateSizeLimits() error {
+	for i, limit := range c.SizeLimits {
+		prefix := fmt.Sprintf("size_limits[%d]", i)
+		if limit.Max == "" && limit.MaxGrowth == "" {
+			return fmt.Errorf("%s: set max, max_growth or both", prefix)
+		}
+		if limit.Max != "" {
+			if _, err := ParseSize(limit.Max); err != nil {
+				return fmt.Errorf("%s.max: %w", prefix, err)
+			}
+		}
+		if limit.MaxGrowth != "" {
+			if _, _, err := limit.Growth(); err != nil {
+				return fmt.Errorf("%s.max_growth: %w", prefix, err)
+			}
+		}
+		if _, err := path.Match(limit.Glob, ""); err != nil {
+			return fmt.Errorf("%s.glob: invalid pattern %q: %w", prefix, limit.Glob, err)
+		}
+	}
+	return nil
+}
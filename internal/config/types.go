@@ -6,7 +6,7 @@ import "os"
 type NFPM struct {
 	ID               string                  `yaml:"id,omitempty"`
 	Builds           []string                `yaml:"builds,omitempty"`
-	Formats          []string                `yaml:"formats,omitempty"`
+	Formats          []string                `yaml:"formats,omitempty" enum:"deb,rpm,apk,archlinux"`
 	Vendor           string                  `yaml:"vendor,omitempty"`
 	Homepage         string                  `yaml:"homepage,omitempty"`
 	Maintainer       string                  `yaml:"maintainer,omitempty"`
@@ -37,16 +37,21 @@ type NFPM struct {
 	Skip             string                  `yaml:"skip,omitempty"`
 	PackageName      string                  `yaml:"package_name,omitempty"`
 	Dependencies     []string                `yaml:"dependencies,omitempty"`
+	Changelog        string                  `yaml:"changelog,omitempty"`
+	Disable          string                  `yaml:"disable,omitempty"`
 }
 
 // NFPMContent represents file contents for packages
 type NFPMContent struct {
-	Src      string          `yaml:"src,omitempty"`
-	Dst      string          `yaml:"dst"`
-	Type     string          `yaml:"type,omitempty"`
-	Packager string          `yaml:"packager,omitempty"`
-	FileInfo NFPMContentInfo `yaml:"file_info,omitempty"`
-	Expand   bool            `yaml:"expand,omitempty"`
+	Src  string `yaml:"src,omitempty"`
+	Dst  string `yaml:"dst" jsonschema:"required"`
+	Type string `yaml:"type,omitempty" enum:"config,config|noreplace,dir,tree,ghost,symlink,doc,license,readme"`
+	// SymlinkTo makes dst a symlink to this target, like type symlink with
+	// the target as src, e.g. /usr/libexec/app/app
+	SymlinkTo string          `yaml:"symlink_to,omitempty"`
+	Packager  string          `yaml:"packager,omitempty"`
+	FileInfo  NFPMContentInfo `yaml:"file_info,omitempty"`
+	Expand    bool            `yaml:"expand,omitempty"`
 }
 
 // NFPMContentInfo represents file metadata
@@ -91,14 +96,16 @@ type NFPMDeb struct {
 type NFPMDebSignature struct {
 	KeyFile       string `yaml:"key_file,omitempty"`
 	KeyID         string `yaml:"key_id,omitempty"`
-	KeyPassphrase string `yaml:"key_passphrase,omitempty"`
+	KeyPassphrase string `yaml:"key_passphrase,omitempty" secret:"true"`
 	Type          string `yaml:"type,omitempty"`
 }
 
 // NFPMDebScripts for Debian-specific scripts
 type NFPMDebScripts struct {
-	Rules    string `yaml:"rules,omitempty"`
-	Postinst string `yaml:"postinst,omitempty"`
+	Rules     string `yaml:"rules,omitempty"`
+	Templates string `yaml:"templates,omitempty"`
+	Config    string `yaml:"config,omitempty"`
+	Postinst  string `yaml:"postinst,omitempty"`
 }
 
 // NFPMDebTriggers for Debian triggers
@@ -118,6 +125,7 @@ type NFPMRPM struct {
 	Compression string           `yaml:"compression,omitempty"`
 	Signature   NFPMRPMSignature `yaml:"signature,omitempty"`
 	Scripts     NFPMRPMScripts   `yaml:"scripts,omitempty"`
+	Scriptlets  NFPMRPMScripts   `yaml:"scriptlets,omitempty"` // alias of scripts
 	Prefixes    []string         `yaml:"prefixes,omitempty"`
 	Packager    string           `yaml:"packager,omitempty"`
 }
@@ -126,7 +134,7 @@ type NFPMRPM struct {
 type NFPMRPMSignature struct {
 	KeyFile       string `yaml:"key_file,omitempty"`
 	KeyID         string `yaml:"key_id,omitempty"`
-	KeyPassphrase string `yaml:"key_passphrase,omitempty"`
+	KeyPassphrase string `yaml:"key_passphrase,omitempty" secret:"true"`
 }
 
 // NFPMRPMScripts for RPM-specific scripts
@@ -146,7 +154,7 @@ type NFPMAPKSignature struct {
 	KeyFile       string `yaml:"key_file,omitempty"`
 	KeyID         string `yaml:"key_id,omitempty"`
 	KeyName       string `yaml:"key_name,omitempty"`
-	KeyPassphrase string `yaml:"key_passphrase,omitempty"`
+	KeyPassphrase string `yaml:"key_passphrase,omitempty" secret:"true"`
 }
 
 // NFPMAPKScripts for Alpine scripts
@@ -186,13 +194,17 @@ type Snapcraft struct {
 	Plugs            []string                   `yaml:"plugs,omitempty"`
 	ExtraFiles       []SnapcraftExtraFile       `yaml:"extra_files,omitempty"`
 	Layout           map[string]SnapcraftLayout `yaml:"layout,omitempty"`
-	Skip             string                     `yaml:"skip,omitempty"`
+	// Mode is how snapcraft builds: destructive, lxd, or empty to use
+	// destructive mode in CI and LXD when it is installed
+	Mode string `yaml:"mode,omitempty"`
+	Skip string `yaml:"skip,omitempty"`
 }
 
 // SnapcraftApp represents a Snap application
 type SnapcraftApp struct {
-	Command   string   `yaml:"command"`
+	Command   string   `yaml:"command" jsonschema:"required"`
 	Plugs     []string `yaml:"plugs,omitempty"`
+	Slots     []string `yaml:"slots,omitempty"`
 	Daemon    string   `yaml:"daemon,omitempty"`
 	Completer string   `yaml:"completer,omitempty"`
 	Args      string   `yaml:"args,omitempty"`
@@ -200,8 +212,8 @@ type SnapcraftApp struct {
 
 // SnapcraftExtraFile for additional files
 type SnapcraftExtraFile struct {
-	Source      string `yaml:"source"`
-	Destination string `yaml:"destination"`
+	Source      string `yaml:"source" jsonschema:"required"`
+	Destination string `yaml:"destination" jsonschema:"required"`
 	Mode        uint32 `yaml:"mode,omitempty"`
 }
 
@@ -232,6 +244,38 @@ type Docker struct {
 	Buildx             bool     `yaml:"buildx,omitempty"`
 	BuildxPlatforms    []string `yaml:"buildx_platforms,omitempty"`
 	Push               bool     `yaml:"push,omitempty"`
+	// Platforms builds a single multi-arch image with buildx
+	Platforms []string `yaml:"platforms,omitempty"`
+	// Labels are added to the image on top of the default OCI labels
+	Labels map[string]string `yaml:"labels,omitempty"`
+	// Secrets are passed as buildx --secret values, e.g. "id=npmrc,src=.npmrc"
+	Secrets []string `yaml:"secrets,omitempty"`
+	// SSH forwards SSH agent sockets or keys, e.g. "default"
+	SSH []string `yaml:"ssh,omitempty"`
+	// CLI is the container CLI: docker, podman or nerdctl; detected from
+	// PATH when unset
+	CLI string `yaml:"cli,omitempty"`
+	// Disable skips this image when it renders to "true"
+	Disable string `yaml:"disable,omitempty"`
+	// Login signs in to the registry before pushing, and again before
+	// retrying a push the registry refused for authentication
+	Login DockerLogin `yaml:"login,omitempty"`
+	// Retry is the policy of retried pushes (default: the http retry policy)
+	Retry HTTPRetry `yaml:"retry,omitempty"`
+}
+
+// DockerLogin signs in to a registry with credentials from the environment
+// or with a command
+type DockerLogin struct {
+	// Registry is the registry host, e.g. ghcr.io (default: Docker Hub)
+	Registry string `yaml:"registry,omitempty"`
+	// UsernameEnv names an environment variable holding the username
+	UsernameEnv string `yaml:"username_env,omitempty"`
+	// PasswordEnv names an environment variable holding the password or token
+	PasswordEnv string `yaml:"password_env,omitempty" secret:"env"`
+	// Cmd is a shell command that logs in instead, e.g. for cloud
+	// credential helpers
+	Cmd string `yaml:"cmd,omitempty"`
 }
 
 // DockerManifest represents Docker manifest configuration
@@ -243,6 +287,9 @@ type DockerManifest struct {
 	Use            string   `yaml:"use,omitempty"`
 	CreateFlags    []string `yaml:"create_flags,omitempty"`
 	PushFlags      []string `yaml:"push_flags,omitempty"`
+	// CLI is the container CLI: docker, podman or nerdctl; falls back to
+	// use, then detection from PATH
+	CLI string `yaml:"cli,omitempty"`
 }
 
 // Brew represents Homebrew configuration
@@ -270,11 +317,13 @@ type Brew struct {
 	CommitAuthor      CommitAuthor     `yaml:"commit_author,omitempty"`
 	CommitMsgTemplate string           `yaml:"commit_msg_template,omitempty"`
 	Directory         string           `yaml:"directory,omitempty"`
+	Disable           string           `yaml:"disable,omitempty"`
+	SkipPrerelease    bool             `yaml:"skip_prerelease,omitempty"`
 }
 
 // BrewDependency for Homebrew dependencies
 type BrewDependency struct {
-	Name string `yaml:"name"`
+	Name string `yaml:"name" jsonschema:"required"`
 	Type string `yaml:"type,omitempty"`
 	OS   string `yaml:"os,omitempty"`
 }
@@ -288,6 +337,39 @@ type BrewService struct {
 	WorkingDir           string            `yaml:"working_dir,omitempty"`
 }
 
+// Cask represents Homebrew cask configuration
+type Cask struct {
+	Name              string        `yaml:"name,omitempty"`
+	DisplayName       string        `yaml:"display_name,omitempty"`
+	Description       string        `yaml:"description,omitempty"`
+	Homepage          string        `yaml:"homepage,omitempty"`
+	SkipUpload        string        `yaml:"skip_upload,omitempty"`
+	Caveats           string        `yaml:"caveats,omitempty"`
+	App               string        `yaml:"app,omitempty"`
+	Binaries          []string      `yaml:"binaries,omitempty"`
+	Depends           []string      `yaml:"depends,omitempty"`
+	Conflicts         []string      `yaml:"conflicts,omitempty"`
+	Uninstall         CaskUninstall `yaml:"uninstall,omitempty"`
+	Zap               CaskUninstall `yaml:"zap,omitempty"`
+	Repository        RepoRef       `yaml:"repository,omitempty"`
+	Tap               RepoRef       `yaml:"tap,omitempty"`
+	URLTemplate       string        `yaml:"url_template,omitempty"`
+	IDs               []string      `yaml:"ids,omitempty"`
+	CommitAuthor      CommitAuthor  `yaml:"commit_author,omitempty"`
+	CommitMsgTemplate string        `yaml:"commit_msg_template,omitempty"`
+	Directory         string        `yaml:"directory,omitempty"`
+	Disable           string        `yaml:"disable,omitempty"`
+}
+
+// CaskUninstall for cask uninstall and zap stanzas
+type CaskUninstall struct {
+	LaunchCtl []string `yaml:"launchctl,omitempty"`
+	Quit      []string `yaml:"quit,omitempty"`
+	LoginItem []string `yaml:"login_item,omitempty"`
+	Delete    []string `yaml:"delete,omitempty"`
+	Trash     []string `yaml:"trash,omitempty"`
+}
+
 // Scoop represents Scoop bucket configuration
 type Scoop struct {
 	Name              string       `yaml:"name,omitempty"`
@@ -309,36 +391,55 @@ type Scoop struct {
 	CommitAuthor      CommitAuthor `yaml:"commit_author,omitempty"`
 	CommitMsgTemplate string       `yaml:"commit_msg_template,omitempty"`
 	Directory         string       `yaml:"directory,omitempty"`
+	Disable           string       `yaml:"disable,omitempty"`
+	SkipPrerelease    bool         `yaml:"skip_prerelease,omitempty"`
 }
 
 // RepoRef represents a repository reference
 type RepoRef struct {
 	Owner       string            `yaml:"owner,omitempty"`
 	Name        string            `yaml:"name,omitempty"`
-	Token       string            `yaml:"token,omitempty"`
+	Token       string            `yaml:"token,omitempty" secret:"true"`
 	Branch      string            `yaml:"branch,omitempty"`
 	Git         GitRepoRef        `yaml:"git,omitempty"`
 	PullRequest PullRequestConfig `yaml:"pull_request,omitempty"`
 }
 
-// GitRepoRef for Git repository references
+// GitRepoRef pushes to a repository over git instead of the GitHub API.
+// https URLs authenticate with the repository token, ssh URLs with the
+// private key, a path or the key itself, or a custom ssh_command.
 type GitRepoRef struct {
 	URL        string `yaml:"url,omitempty"`
 	SSHCommand string `yaml:"ssh_command,omitempty"`
-	PrivateKey string `yaml:"private_key,omitempty"`
+	PrivateKey string `yaml:"private_key,omitempty" secret:"true"`
 }
 
 // PullRequestConfig for PR creation
 type PullRequestConfig struct {
-	Enabled bool   `yaml:"enabled,omitempty"`
-	Base    string `yaml:"base,omitempty"`
-	Draft   bool   `yaml:"draft,omitempty"`
+	Enabled        bool   `yaml:"enabled,omitempty"`
+	Base           string `yaml:"base,omitempty"`
+	Draft          bool   `yaml:"draft,omitempty"`
+	BranchTemplate string `yaml:"branch_template,omitempty"`
+	TitleTemplate  string `yaml:"title_template,omitempty"`
+	BodyTemplate   string `yaml:"body_template,omitempty"`
 }
 
 // CommitAuthor for commit authorship
 type CommitAuthor struct {
-	Name  string `yaml:"name,omitempty"`
-	Email string `yaml:"email,omitempty"`
+	Name    string        `yaml:"name,omitempty"`
+	Email   string        `yaml:"email,omitempty"`
+	Signing CommitSigning `yaml:"signing,omitempty"`
+}
+
+// CommitSigning signs commits pushed over git with repository.git
+type CommitSigning struct {
+	Enabled bool `yaml:"enabled,omitempty"`
+	// Key is the signing key, git's user.signingkey
+	Key string `yaml:"key,omitempty"`
+	// Program is the signing program, git's gpg.program
+	Program string `yaml:"program,omitempty"`
+	// Format is openpgp (default), x509 or ssh
+	Format string `yaml:"format,omitempty"`
 }
 
 // NPM represents NPM package configuration
@@ -351,7 +452,7 @@ type NPM struct {
 	Registry         string                 `yaml:"registry,omitempty"`
 	Scope            string                 `yaml:"scope,omitempty"`
 	Access           string                 `yaml:"access,omitempty"`
-	Token            string                 `yaml:"token,omitempty"`
+	Token            string                 `yaml:"token,omitempty" secret:"true"`
 	Tag              string                 `yaml:"tag,omitempty"`
 	IDs              []string               `yaml:"ids,omitempty"`
 	Dependencies     map[string]string      `yaml:"dependencies,omitempty"`
@@ -362,6 +463,14 @@ type NPM struct {
 	Bin              map[string]string      `yaml:"bin,omitempty"`
 	Files            []string               `yaml:"files,omitempty"`
 	ExtraFields      map[string]interface{} `yaml:"extra_fields,omitempty"`
+	Disable          string                 `yaml:"disable,omitempty"`
+	// PlatformPackages publishes a package per platform holding its
+	// binaries, and a meta package depending on them through
+	// optionalDependencies
+	PlatformPackages bool `yaml:"platform_packages,omitempty"`
+	// Provenance is "false" to publish without --provenance in GitHub
+	// Actions
+	Provenance string `yaml:"provenance,omitempty"`
 }
 
 // Chocolatey represents Chocolatey package configuration
@@ -383,15 +492,17 @@ type Chocolatey struct {
 	SkipPublish              string                 `yaml:"skip_publish,omitempty"`
 	URLTemplate              string                 `yaml:"url_template,omitempty"`
 	SourceRepo               string                 `yaml:"source_repo,omitempty"`
-	APIKey                   string                 `yaml:"api_key,omitempty"`
+	APIKey                   string                 `yaml:"api_key,omitempty" secret:"true"`
 	IDs                      []string               `yaml:"ids,omitempty"`
 	Goarm                    string                 `yaml:"goarm,omitempty"`
 	Goamd64                  string                 `yaml:"goamd64,omitempty"`
 	Dependencies             []ChocolateyDependency `yaml:"dependencies,omitempty"`
+	Disable                  string                 `yaml:"disable,omitempty"`
+	SkipPrerelease           bool                   `yaml:"skip_prerelease,omitempty"`
 }
 
 // ChocolateyDependency for Chocolatey dependencies
 type ChocolateyDependency struct {
-	ID      string `yaml:"id"`
+	ID      string `yaml:"id" jsonschema:"required"`
 	Version string `yaml:"version,omitempty"`
 }
@@ -23,7 +23,7 @@ type AppBundle struct {
 type AppBundleSign struct {
 	Identity         string   `yaml:"identity,omitempty"`
 	Keychain         string   `yaml:"keychain,omitempty"`
-	KeychainPassword string   `yaml:"keychain_password,omitempty"`
+	KeychainPassword string   `yaml:"keychain_password,omitempty" secret:"true"`
 	Entitlements     string   `yaml:"entitlements,omitempty"`
 	Hardened         bool     `yaml:"hardened,omitempty"`
 	Timestamp        bool     `yaml:"timestamp,omitempty"`
@@ -32,7 +32,7 @@ type AppBundleSign struct {
 
 // AppBundleFile for extra files in app bundle
 type AppBundleFile struct {
-	Src string `yaml:"src"`
+	Src string `yaml:"src" jsonschema:"required"`
 	Dst string `yaml:"dst,omitempty"`
 }
 
@@ -72,6 +72,8 @@ type PKG struct {
 	Sign            PKGSign     `yaml:"sign,omitempty"`
 	Notarize        DMGNotarize `yaml:"notarize,omitempty"`
 	Component       bool        `yaml:"component,omitempty"`
+	ComponentPlist  string      `yaml:"component_plist,omitempty"`
+	Relocatable     bool        `yaml:"relocatable,omitempty"`
 	Distribution    string      `yaml:"distribution,omitempty"`
 	Resources       string      `yaml:"resources,omitempty"`
 	ExtraFiles      []PKGFile   `yaml:"extra_files,omitempty"`
@@ -91,7 +93,7 @@ type PKGSign struct {
 
 // PKGFile for extra files in PKG
 type PKGFile struct {
-	Src string `yaml:"src"`
+	Src string `yaml:"src" jsonschema:"required"`
 	Dst string `yaml:"dst,omitempty"`
 }
 
@@ -115,7 +117,7 @@ type DMGContent struct {
 type DMGNotarize struct {
 	Enabled  bool   `yaml:"enabled,omitempty"`
 	AppleID  string `yaml:"apple_id,omitempty"`
-	Password string `yaml:"password,omitempty"`
+	Password string `yaml:"password,omitempty" secret:"true"`
 	TeamID   string `yaml:"team_id,omitempty"`
 	BundleID string `yaml:"bundle_id,omitempty"`
 	Timeout  string `yaml:"timeout,omitempty"`
@@ -138,20 +140,37 @@ type MSI struct {
 	ProductName    string        `yaml:"product_name,omitempty"`
 	ProductVersion string        `yaml:"product_version,omitempty"`
 	Manufacturer   string        `yaml:"manufacturer,omitempty"`
+	Identifier     string        `yaml:"identifier,omitempty"`
 	UpgradeCode    string        `yaml:"upgrade_code,omitempty"`
 	Icon           string        `yaml:"icon,omitempty"`
 	License        string        `yaml:"license,omitempty"`
 	Shortcuts      []MSIShortcut `yaml:"shortcuts,omitempty"`
 	InstallDir     string        `yaml:"install_dir,omitempty"`
 	ExtraFiles     []MSIFile     `yaml:"extra_files,omitempty"`
+	Service        *MSIService   `yaml:"service,omitempty"`
+	AddToPath      bool          `yaml:"add_to_path,omitempty"`
 	Sign           MSISign       `yaml:"sign,omitempty"`
+	// Language is the localizations entry the installer is made in, en
+	// when unset
+	Language string `yaml:"language,omitempty"`
+}
+
+// MSIService installs one of the MSI's binaries as a Windows service
+type MSIService struct {
+	Name        string `yaml:"name" jsonschema:"required"`
+	Binary      string `yaml:"binary,omitempty"`
+	DisplayName string `yaml:"display_name,omitempty"`
+	Description string `yaml:"description,omitempty"`
+	Arguments   string `yaml:"arguments,omitempty"`
+	Start       string `yaml:"start,omitempty"`
+	Account     string `yaml:"account,omitempty"`
 }
 
 // MSIShortcut for desktop/start menu shortcuts
 type MSIShortcut struct {
-	Name        string `yaml:"name"`
+	Name        string `yaml:"name" jsonschema:"required"`
 	Description string `yaml:"description,omitempty"`
-	Target      string `yaml:"target"`
+	Target      string `yaml:"target" jsonschema:"required"`
 	Arguments   string `yaml:"arguments,omitempty"`
 	Desktop     bool   `yaml:"desktop,omitempty"`
 	StartMenu   bool   `yaml:"start_menu,omitempty"`
@@ -159,14 +178,14 @@ type MSIShortcut struct {
 
 // MSIFile for additional MSI files
 type MSIFile struct {
-	Src string `yaml:"src"`
+	Src string `yaml:"src" jsonschema:"required"`
 	Dst string `yaml:"dst,omitempty"`
 }
 
 // MSISign for MSI signing
 type MSISign struct {
 	Certificate         string `yaml:"certificate,omitempty"`
-	CertificatePassword string `yaml:"certificate_password,omitempty"`
+	CertificatePassword string `yaml:"certificate_password,omitempty" secret:"true"`
 	TimestampServer     string `yaml:"timestamp_server,omitempty"`
 }
 
@@ -181,19 +200,23 @@ type NSIS struct {
 	OutFile      string            `yaml:"out_file,omitempty"`
 	Defines      map[string]string `yaml:"defines,omitempty"`
 	ExtraFiles   []NSISFile        `yaml:"extra_files,omitempty"`
+	LicenseFile  string            `yaml:"license_file,omitempty"`
+	Publisher    string            `yaml:"publisher,omitempty"`
+	Icon         string            `yaml:"icon,omitempty"`
+	InstallScope string            `yaml:"install_scope,omitempty"`
 	Sign         NSISSign          `yaml:"sign,omitempty"`
 }
 
 // NSISFile for NSIS files
 type NSISFile struct {
-	Src string `yaml:"src"`
+	Src string `yaml:"src" jsonschema:"required"`
 	Dst string `yaml:"dst,omitempty"`
 }
 
 // NSISSign for NSIS signing
 type NSISSign struct {
 	Certificate         string `yaml:"certificate,omitempty"`
-	CertificatePassword string `yaml:"certificate_password,omitempty"`
+	CertificatePassword string `yaml:"certificate_password,omitempty" secret:"true"`
 	TimestampServer     string `yaml:"timestamp_server,omitempty"`
 }
 
@@ -205,11 +228,25 @@ type Sign struct {
 	Signature   string   `yaml:"signature,omitempty"`
 	Artifacts   string   `yaml:"artifacts,omitempty"`
 	IDs         []string `yaml:"ids,omitempty"`
-	Stdin       string   `yaml:"stdin,omitempty"`
+	Stdin       string   `yaml:"stdin,omitempty" secret:"true"`
 	StdinFile   string   `yaml:"stdin_file,omitempty"`
 	Env         []string `yaml:"env,omitempty"`
 	Certificate string   `yaml:"certificate,omitempty"`
 	Output      bool     `yaml:"output,omitempty"`
+
+	// Method selects a built-in signer instead of running cmd:
+	// minisign, ssh or gpg-native
+	Method string `yaml:"method,omitempty" enum:"cmd,minisign,ssh,gpg-native"`
+	// Key is the path to the private key used by the built-in signers
+	Key string `yaml:"key,omitempty"`
+	// KeyEnv names an environment variable holding the private key contents
+	KeyEnv string `yaml:"key_env,omitempty" secret:"env"`
+	// PasswordEnv names an environment variable holding the key passphrase
+	PasswordEnv string `yaml:"password_env,omitempty" secret:"env"`
+	// Namespace is the SSH signature namespace (default: file)
+	Namespace string `yaml:"namespace,omitempty"`
+	// Disable skips this signature when it renders to "true"
+	Disable string `yaml:"disable,omitempty"`
 }
 
 // DockerSign represents Docker image signing
@@ -250,27 +287,61 @@ type CosignOptions struct {
 // Checksum represents checksum configuration
 type Checksum struct {
 	NameTemplate string      `yaml:"name_template,omitempty"`
-	Algorithm    string      `yaml:"algorithm,omitempty"`
+	Algorithm    string      `yaml:"algorithm,omitempty" enum:"sha256,sha512,sha1,md5"`
 	IDs          []string    `yaml:"ids,omitempty"`
 	Disable      bool        `yaml:"disable,omitempty"`
 	ExtraFiles   []ExtraFile `yaml:"extra_files,omitempty"`
 	Split        bool        `yaml:"split,omitempty"`
 }
 
+// Provenance configures SLSA provenance generation
+type Provenance struct {
+	Enabled bool `yaml:"enabled,omitempty"`
+	// Artifacts selects the subjects: all (default), archive, binary, package or source
+	Artifacts string   `yaml:"artifacts,omitempty"`
+	IDs       []string `yaml:"ids,omitempty"`
+	// Split writes one statement per artifact instead of a single
+	// multi-subject statement
+	Split bool `yaml:"split,omitempty"`
+	// NameTemplate is the statement file name; defaults to
+	// "{{ .ProjectName }}_{{ .Version }}.intoto.json", or
+	// "{{ .ArtifactName }}.intoto.json" when split
+	NameTemplate string `yaml:"name_template,omitempty"`
+	// BuilderID overrides the detected builder identity
+	BuilderID string `yaml:"builder_id,omitempty"`
+}
+
 // ExtraFile for additional files
 type ExtraFile struct {
-	Glob string `yaml:"glob"`
+	Glob string `yaml:"glob" jsonschema:"required"`
+	// NameTemplate renames the file, .ArtifactName is its own name
+	NameTemplate string `yaml:"name_template,omitempty"`
+	// Type is the artifact type of top-level extra files: uploadable
+	// (default), metadata or sbom
+	Type string `yaml:"type,omitempty"`
 }
 
 // Changelog represents changelog configuration
 type Changelog struct {
 	Use     string           `yaml:"use,omitempty"`
-	Sort    string           `yaml:"sort,omitempty"`
+	Sort    string           `yaml:"sort,omitempty" enum:"asc,desc"`
 	Abbrev  int              `yaml:"abbrev,omitempty"`
 	Filters ChangelogFilters `yaml:"filters,omitempty"`
 	Groups  []ChangelogGroup `yaml:"groups,omitempty"`
 	Divider string           `yaml:"divider,omitempty"`
 	AI      ChangelogAI      `yaml:"ai,omitempty"`
+	// TicketLinks links the issue tracker IDs of commit subjects
+	TicketLinks ChangelogTicketLinks `yaml:"ticket_links,omitempty"`
+}
+
+// ChangelogTicketLinks turns ticket IDs such as ABC-1234 in the markdown
+// changelog into links to the issue tracker
+type ChangelogTicketLinks struct {
+	// Pattern matches ticket IDs, [A-Z][A-Z0-9]+-\d+ by default
+	Pattern string `yaml:"pattern,omitempty"`
+	// URLTemplate is the link of a ticket, with the ID as .Ticket, e.g.
+	// https://jira.example.com/browse/{{ .Ticket }}
+	URLTemplate string `yaml:"url_template,omitempty"`
 }
 
 // ChangelogFilters for filtering commits
@@ -281,7 +352,7 @@ type ChangelogFilters struct {
 
 // ChangelogGroup for grouping commits
 type ChangelogGroup struct {
-	Title  string `yaml:"title"`
+	Title  string `yaml:"title" jsonschema:"required"`
 	Regexp string `yaml:"regexp,omitempty"`
 	Order  int    `yaml:"order,omitempty"`
 }
@@ -291,35 +362,65 @@ type ChangelogAI struct {
 	Enabled  bool   `yaml:"enabled,omitempty"`
 	Provider string `yaml:"provider,omitempty"`
 	Model    string `yaml:"model,omitempty"`
-	APIKey   string `yaml:"api_key,omitempty"`
+	APIKey   string `yaml:"api_key,omitempty" secret:"true"`
 	Prompt   string `yaml:"prompt,omitempty"`
 }
 
 // Release represents release configuration
 type Release struct {
-	GitHub                   ReleaseRepo `yaml:"github,omitempty"`
-	GitLab                   ReleaseRepo `yaml:"gitlab,omitempty"`
-	Gitea                    ReleaseRepo `yaml:"gitea,omitempty"`
-	Draft                    bool        `yaml:"draft,omitempty"`
-	Prerelease               string      `yaml:"prerelease,omitempty"`
-	NameTemplate             string      `yaml:"name_template,omitempty"`
-	ReplaceExisting          bool        `yaml:"replace_existing,omitempty"`
-	ReplaceExistingDraft     bool        `yaml:"replace_existing_draft,omitempty"`
-	ReplaceExistingArtifacts bool        `yaml:"replace_existing_artifacts,omitempty"`
-	TargetCommitish          string      `yaml:"target_commitish,omitempty"`
-	Mode                     string      `yaml:"mode,omitempty"`
-	Header                   string      `yaml:"header,omitempty"`
-	Footer                   string      `yaml:"footer,omitempty"`
-	ExtraFiles               []ExtraFile `yaml:"extra_files,omitempty"`
-	IDs                      []string    `yaml:"ids,omitempty"`
-	SkipUpload               bool        `yaml:"skip_upload,omitempty"`
-	MakeLatest               string      `yaml:"make_latest,omitempty"`
+	GitHub                   ReleaseRepo  `yaml:"github,omitempty"`
+	GitLab                   ReleaseRepo  `yaml:"gitlab,omitempty"`
+	Gitea                    ReleaseRepo  `yaml:"gitea,omitempty"`
+	Draft                    bool         `yaml:"draft,omitempty"`
+	Prerelease               string       `yaml:"prerelease,omitempty"`
+	NameTemplate             string       `yaml:"name_template,omitempty"`
+	ReplaceExisting          bool         `yaml:"replace_existing,omitempty"`
+	ReplaceExistingDraft     bool         `yaml:"replace_existing_draft,omitempty"`
+	ReplaceExistingArtifacts bool         `yaml:"replace_existing_artifacts,omitempty"`
+	TargetCommitish          string       `yaml:"target_commitish,omitempty"`
+	Mode                     string       `yaml:"mode,omitempty"`
+	Header                   string       `yaml:"header,omitempty"`
+	Footer                   string       `yaml:"footer,omitempty"`
+	Notes                    ReleaseNotes `yaml:"notes,omitempty"`
+	ExtraFiles               []ExtraFile  `yaml:"extra_files,omitempty"`
+	IDs                      []string     `yaml:"ids,omitempty"`
+	SkipUpload               bool         `yaml:"skip_upload,omitempty"`
+	MakeLatest               string       `yaml:"make_latest,omitempty"`
+	Disable                  string       `yaml:"disable,omitempty"`
+}
+
+// ReleaseNotes builds the release body from hand-written notes. Templates
+// can use .Changelog, the generated changelog, and .Checksums, the content
+// of the checksums file.
+type ReleaseNotes struct {
+	// FromFileTemplate is the templated path of the notes file, e.g.
+	// docs/releases/{{ .Tag }}.md. The file is rendered as a template and
+	// the changelog appended unless it places {{ .Changelog }} itself; a
+	// missing file falls back to the changelog.
+	FromFileTemplate string `yaml:"from_file_template,omitempty"`
+	HeaderTemplate   string `yaml:"header_template,omitempty"`
+	FooterTemplate   string `yaml:"footer_template,omitempty"`
 }
 
 // ReleaseRepo for release repository configuration
 type ReleaseRepo struct {
 	Owner string `yaml:"owner,omitempty"`
 	Name  string `yaml:"name,omitempty"`
+	// Auth authenticates as a GitHub App instead of with GITHUB_TOKEN;
+	// release.github only
+	Auth GitHubAppAuth `yaml:"auth,omitempty"`
+}
+
+// GitHubAppAuth authenticates as a GitHub App installation. The
+// installation token replaces GITHUB_TOKEN for the whole release.
+type GitHubAppAuth struct {
+	AppID string `yaml:"app_id,omitempty"`
+	// InstallationID defaults to the installation on the release repository
+	InstallationID string `yaml:"installation_id,omitempty"`
+	// PrivateKey is the PEM private key of the app, or a path to it
+	PrivateKey string `yaml:"private_key,omitempty" secret:"true"`
+	// PrivateKeyEnv names an environment variable holding the PEM key
+	PrivateKeyEnv string `yaml:"private_key_env,omitempty" secret:"env"`
 }
 
 // Announce represents announcement configuration
@@ -333,15 +434,23 @@ type Announce struct {
 	Teams      AnnounceTeams      `yaml:"teams,omitempty"`
 	Telegram   AnnounceTelegram   `yaml:"telegram,omitempty"`
 	Webhook    AnnounceWebhook    `yaml:"webhook,omitempty"`
+	Webhooks   []AnnounceWebhook  `yaml:"webhooks,omitempty"`
 	SMTP       AnnounceSMTP       `yaml:"smtp,omitempty"`
 	Mattermost AnnounceMattermost `yaml:"mattermost,omitempty"`
 	LinkedIn   AnnounceLinkedIn   `yaml:"linkedin,omitempty"`
 	Bluesky    AnnounceBluesky    `yaml:"bluesky,omitempty"`
+	Jira       AnnounceJira       `yaml:"jira,omitempty"`
+
+	// SkipPrerelease skips every announcer for prereleases
+	SkipPrerelease bool `yaml:"skip_prerelease,omitempty"`
 }
 
 // AnnounceSlack for Slack announcements
 type AnnounceSlack struct {
-	Enabled         bool          `yaml:"enabled,omitempty"`
+	Enabled         string        `yaml:"enabled,omitempty"`
+	SkipOnError     bool          `yaml:"skip_on_error,omitempty"`
+	WebhookURL      string        `yaml:"webhook_url,omitempty" secret:"true"`
+	TokenEnv        string        `yaml:"token_env,omitempty" secret:"env"`
 	Channel         string        `yaml:"channel,omitempty"`
 	Username        string        `yaml:"username,omitempty"`
 	IconEmoji       string        `yaml:"icon_emoji,omitempty"`
@@ -353,29 +462,38 @@ type AnnounceSlack struct {
 
 // AnnounceDiscord for Discord announcements
 type AnnounceDiscord struct {
-	Enabled         bool   `yaml:"enabled,omitempty"`
+	Enabled         string `yaml:"enabled,omitempty"`
+	SkipOnError     bool   `yaml:"skip_on_error,omitempty"`
+	WebhookURL      string `yaml:"webhook_url,omitempty" secret:"true"`
+	TitleTemplate   string `yaml:"title_template,omitempty"`
 	MessageTemplate string `yaml:"message_template,omitempty"`
 	Author          string `yaml:"author,omitempty"`
 	Color           string `yaml:"color,omitempty"`
 	IconURL         string `yaml:"icon_url,omitempty"`
+	ThumbnailURL    string `yaml:"thumbnail_url,omitempty"`
 }
 
 // AnnounceTwitter for Twitter announcements
 type AnnounceTwitter struct {
-	Enabled         bool   `yaml:"enabled,omitempty"`
+	Enabled         string `yaml:"enabled,omitempty"`
+	SkipOnError     bool   `yaml:"skip_on_error,omitempty"`
 	MessageTemplate string `yaml:"message_template,omitempty"`
 }
 
 // AnnounceMastodon for Mastodon announcements
 type AnnounceMastodon struct {
-	Enabled         bool   `yaml:"enabled,omitempty"`
+	Enabled         string `yaml:"enabled,omitempty"`
+	SkipOnError     bool   `yaml:"skip_on_error,omitempty"`
 	Server          string `yaml:"server,omitempty"`
 	MessageTemplate string `yaml:"message_template,omitempty"`
+	Visibility      string `yaml:"visibility,omitempty"`
+	MaxLength       int    `yaml:"max_length,omitempty"`
 }
 
 // AnnounceReddit for Reddit announcements
 type AnnounceReddit struct {
-	Enabled       bool   `yaml:"enabled,omitempty"`
+	Enabled       string `yaml:"enabled,omitempty"`
+	SkipOnError   bool   `yaml:"skip_on_error,omitempty"`
 	ApplicationID string `yaml:"application_id,omitempty"`
 	Username      string `yaml:"username,omitempty"`
 	TitleTemplate string `yaml:"title_template,omitempty"`
@@ -385,7 +503,9 @@ type AnnounceReddit struct {
 
 // AnnounceTeams for Microsoft Teams announcements
 type AnnounceTeams struct {
-	Enabled         bool   `yaml:"enabled,omitempty"`
+	Enabled         string `yaml:"enabled,omitempty"`
+	SkipOnError     bool   `yaml:"skip_on_error,omitempty"`
+	WebhookURL      string `yaml:"webhook_url,omitempty" secret:"true"`
 	TitleTemplate   string `yaml:"title_template,omitempty"`
 	MessageTemplate string `yaml:"message_template,omitempty"`
 	Color           string `yaml:"color,omitempty"`
@@ -394,7 +514,8 @@ type AnnounceTeams struct {
 
 // AnnounceTelegram for Telegram announcements
 type AnnounceTelegram struct {
-	Enabled         bool   `yaml:"enabled,omitempty"`
+	Enabled         string `yaml:"enabled,omitempty"`
+	SkipOnError     bool   `yaml:"skip_on_error,omitempty"`
 	ChatID          string `yaml:"chat_id,omitempty"`
 	MessageTemplate string `yaml:"message_template,omitempty"`
 	ParseMode       string `yaml:"parse_mode,omitempty"`
@@ -402,31 +523,47 @@ type AnnounceTelegram struct {
 
 // AnnounceWebhook for generic webhook announcements
 type AnnounceWebhook struct {
-	Enabled         bool              `yaml:"enabled,omitempty"`
-	EndpointURL     string            `yaml:"endpoint_url,omitempty"`
-	MessageTemplate string            `yaml:"message_template,omitempty"`
-	Headers         map[string]string `yaml:"headers,omitempty"`
-	ContentType     string            `yaml:"content_type,omitempty"`
-	SkipTLSVerify   bool              `yaml:"skip_tls_verify,omitempty"`
+	Name             string            `yaml:"name,omitempty"`
+	Enabled          string            `yaml:"enabled,omitempty"`
+	SkipOnError      bool              `yaml:"skip_on_error,omitempty"`
+	EndpointURL      string            `yaml:"endpoint_url,omitempty"`
+	Method           string            `yaml:"method,omitempty"`
+	MessageTemplate  string            `yaml:"message_template,omitempty"`
+	PayloadTemplate  string            `yaml:"payload_template,omitempty"`
+	Form             map[string]string `yaml:"form,omitempty"`
+	Headers          map[string]string `yaml:"headers,omitempty"`
+	ContentType      string            `yaml:"content_type,omitempty"`
+	SigningSecretEnv string            `yaml:"signing_secret_env,omitempty" secret:"env"`
+	SignatureHeader  string            `yaml:"signature_header,omitempty"`
+	// Retries of failed deliveries, two when unset; 0 disables retrying
+	Retries       *int `yaml:"retries,omitempty"`
+	SkipTLSVerify bool `yaml:"skip_tls_verify,omitempty"`
 }
 
 // AnnounceSMTP for email announcements
 type AnnounceSMTP struct {
-	Enabled            bool     `yaml:"enabled,omitempty"`
+	Enabled            string   `yaml:"enabled,omitempty"`
+	SkipOnError        bool     `yaml:"skip_on_error,omitempty"`
 	Host               string   `yaml:"host,omitempty"`
 	Port               int      `yaml:"port,omitempty"`
+	TLS                string   `yaml:"tls,omitempty"` // starttls (default), implicit, none
 	Username           string   `yaml:"username,omitempty"`
-	Password           string   `yaml:"password,omitempty"`
+	Password           string   `yaml:"password,omitempty" secret:"true"`
 	From               string   `yaml:"from,omitempty"`
 	To                 []string `yaml:"to,omitempty"`
+	Cc                 []string `yaml:"cc,omitempty"`
 	SubjectTemplate    string   `yaml:"subject_template,omitempty"`
 	BodyTemplate       string   `yaml:"body_template,omitempty"`
+	HTMLTemplate       string   `yaml:"html_template,omitempty"`
+	AttachChecksums    bool     `yaml:"attach_checksums,omitempty"`
+	DryRun             bool     `yaml:"dry_run,omitempty"`
 	InsecureSkipVerify bool     `yaml:"insecure_skip_verify,omitempty"`
 }
 
 // AnnounceMattermost for Mattermost announcements
 type AnnounceMattermost struct {
-	Enabled         bool   `yaml:"enabled,omitempty"`
+	Enabled         string `yaml:"enabled,omitempty"`
+	SkipOnError     bool   `yaml:"skip_on_error,omitempty"`
 	MessageTemplate string `yaml:"message_template,omitempty"`
 	TitleTemplate   string `yaml:"title_template,omitempty"`
 	Color           string `yaml:"color,omitempty"`
@@ -438,21 +575,48 @@ type AnnounceMattermost struct {
 
 // AnnounceLinkedIn for LinkedIn announcements
 type AnnounceLinkedIn struct {
-	Enabled         bool   `yaml:"enabled,omitempty"`
+	Enabled         string `yaml:"enabled,omitempty"`
+	SkipOnError     bool   `yaml:"skip_on_error,omitempty"`
 	MessageTemplate string `yaml:"message_template,omitempty"`
 }
 
+// AnnounceJira comments on the Jira tickets referenced by the changelog and
+// adds the release to their fix versions. JIRA_API_TOKEN authenticates,
+// with the username as basic auth or alone as a personal access token.
+type AnnounceJira struct {
+	Enabled     string `yaml:"enabled,omitempty"`
+	SkipOnError bool   `yaml:"skip_on_error,omitempty"`
+	// URL is the Jira site, $JIRA_URL by default
+	URL string `yaml:"url,omitempty"`
+	// Username is the account of the API token, $JIRA_USERNAME by default
+	Username string `yaml:"username,omitempty"`
+	// Pattern matches ticket IDs, changelog.ticket_links.pattern by default
+	Pattern string `yaml:"pattern,omitempty"`
+	// Projects limits the tickets to these project keys; required with the
+	// default pattern
+	Projects []string `yaml:"projects,omitempty"`
+	// CommentTemplate is the release comment, the default announcement
+	// unless only fix_version is set
+	CommentTemplate string `yaml:"comment_template,omitempty"`
+	// FixVersion is the templated version added to the tickets' fix
+	// versions, created in the project when missing
+	FixVersion string `yaml:"fix_version,omitempty"`
+}
+
 // AnnounceBluesky for Bluesky announcements
 type AnnounceBluesky struct {
-	Enabled         bool   `yaml:"enabled,omitempty"`
+	Enabled         string `yaml:"enabled,omitempty"`
+	SkipOnError     bool   `yaml:"skip_on_error,omitempty"`
+	Username        string `yaml:"username,omitempty"`
+	PDSURL          string `yaml:"pds_url,omitempty"`
 	MessageTemplate string `yaml:"message_template,omitempty"`
 }
 
 // CustomBuilder represents a custom build configuration
 type CustomBuilder struct {
-	ID      string            `yaml:"id"`
+	ID      string            `yaml:"id" jsonschema:"required"`
 	Name    string            `yaml:"name,omitempty"`
-	Command string            `yaml:"command"`
+	Command string            `yaml:"command" jsonschema:"required"`
 	Dir     string            `yaml:"dir,omitempty"`
 	Env     map[string]string `yaml:"env,omitempty"`
 	Outputs []string          `yaml:"outputs,omitempty"`
@@ -473,10 +637,16 @@ type Flatpak struct {
 	SDK            string   `yaml:"sdk,omitempty"`
 	Command        string   `yaml:"command,omitempty"`
 	FinishArgs     []string `yaml:"finish_args,omitempty"`
-	Modules        []string `yaml:"modules,omitempty"`
-	Categories     []string `yaml:"categories,omitempty"`
-	Keywords       []string `yaml:"keywords,omitempty"`
-	Skip           string   `yaml:"skip,omitempty"`
+	// Permissions are sandbox permissions added to finish_args, e.g.
+	// network, wayland, dri, home or filesystem=xdg-download
+	Permissions []string `yaml:"permissions,omitempty"`
+	Modules     []string `yaml:"modules,omitempty"`
+	Categories  []string `yaml:"categories,omitempty"`
+	Keywords    []string `yaml:"keywords,omitempty"`
+	// Manifest is a hand-written manifest used instead of generating one
+	Manifest string `yaml:"manifest,omitempty"`
+	Branch   string `yaml:"branch,omitempty"`
+	Skip     string `yaml:"skip,omitempty"`
 }
 
 // AppImage represents AppImage package configuration
@@ -490,14 +660,25 @@ type AppImage struct {
 	Categories  string      `yaml:"categories,omitempty"`
 	Terminal    bool        `yaml:"terminal,omitempty"`
 	ExtraFiles  []ExtraFile `yaml:"extra_files,omitempty"`
-	Skip        string      `yaml:"skip,omitempty"`
+	// UpdateInformation is embedded for AppImageUpdate and enables the
+	// .zsync file, e.g. "gh-releases-zsync|owner|repo|latest|*x86_64.AppImage.zsync"
+	UpdateInformation string `yaml:"update_information,omitempty"`
+	// Runtime is a type 2 runtime file to use instead of downloading one
+	// for the target architecture
+	Runtime string `yaml:"runtime,omitempty"`
+	// RuntimeVersion is the AppImage/type2-runtime release runtimes are
+	// downloaded from, and RuntimeSHA256 their sha256 by AppImage
+	// architecture; a runtime is only downloaded when both are set
+	RuntimeVersion string            `yaml:"runtime_version,omitempty"`
+	RuntimeSHA256  map[string]string `yaml:"runtime_sha256,omitempty"`
+	Skip           string            `yaml:"skip,omitempty"`
 }
 
 // Crate represents Rust crates.io publishing configuration
 type Crate struct {
 	ID           string   `yaml:"id,omitempty"`
 	Registry     string   `yaml:"registry,omitempty"`
-	Token        string   `yaml:"token,omitempty"`
+	Token        string   `yaml:"token,omitempty" secret:"true"`
 	AllowDirty   bool     `yaml:"allow_dirty,omitempty"`
 	DryRun       bool     `yaml:"dry_run,omitempty"`
 	NoVerify     bool     `yaml:"no_verify,omitempty"`
@@ -506,6 +687,7 @@ type Crate struct {
 	Jobs         int      `yaml:"jobs,omitempty"`
 	SkipUpload   string   `yaml:"skip_upload,omitempty"`
 	ManifestPath string   `yaml:"manifest_path,omitempty"`
+	Disable      string   `yaml:"disable,omitempty"`
 }
 
 // PyPI represents Python PyPI publishing configuration
@@ -513,10 +695,20 @@ type PyPI struct {
 	ID            string   `yaml:"id,omitempty"`
 	Repository    string   `yaml:"repository,omitempty"`
 	Username      string   `yaml:"username,omitempty"`
-	Password      string   `yaml:"password,omitempty"`
+	Password      string   `yaml:"password,omitempty" secret:"true"`
 	Distributions []string `yaml:"distributions,omitempty"`
 	SkipExisting  bool     `yaml:"skip_existing,omitempty"`
 	SkipUpload    string   `yaml:"skip_upload,omitempty"`
+	Disable       string   `yaml:"disable,omitempty"`
+
+	// Binary packages the built binaries as platform wheels instead of
+	// uploading distributions built by Python tooling
+	Binary   bool     `yaml:"binary,omitempty"`
+	Name     string   `yaml:"name,omitempty"`
+	IDs      []string `yaml:"ids,omitempty"`
+	Summary  string   `yaml:"summary,omitempty"`
+	Homepage string   `yaml:"homepage,omitempty"`
+	License  string   `yaml:"license,omitempty"`
 }
 
 // Maven represents Maven Central publishing configuration
@@ -527,48 +719,79 @@ type Maven struct {
 	Repository    string `yaml:"repository,omitempty"`
 	SnapshotRepo  string `yaml:"snapshot_repo,omitempty"`
 	Username      string `yaml:"username,omitempty"`
-	Password      string `yaml:"password,omitempty"`
-	GPGPassphrase string `yaml:"gpg_passphrase,omitempty"`
+	Password      string `yaml:"password,omitempty" secret:"true"`
+	GPGPassphrase string `yaml:"gpg_passphrase,omitempty" secret:"true"`
 	GPGKeyID      string `yaml:"gpg_key_id,omitempty"`
 	SkipUpload    string `yaml:"skip_upload,omitempty"`
+	Disable       string `yaml:"disable,omitempty"`
 }
 
 // NuGet represents NuGet package publishing configuration
 type NuGet struct {
 	ID         string `yaml:"id,omitempty"`
 	Source     string `yaml:"source,omitempty"`
-	APIKey     string `yaml:"api_key,omitempty"`
-	SymbolsKey string `yaml:"symbols_key,omitempty"`
+	APIKey     string `yaml:"api_key,omitempty" secret:"true"`
+	SymbolsKey string `yaml:"symbols_key,omitempty" secret:"true"`
 	SkipUpload string `yaml:"skip_upload,omitempty"`
+	Disable    string `yaml:"disable,omitempty"`
 }
 
 // Gem represents Ruby Gem publishing configuration
 type Gem struct {
 	ID         string `yaml:"id,omitempty"`
 	Host       string `yaml:"host,omitempty"`
-	APIKey     string `yaml:"api_key,omitempty"`
+	APIKey     string `yaml:"api_key,omitempty" secret:"true"`
 	Gemspec    string `yaml:"gemspec,omitempty"`
 	SkipUpload string `yaml:"skip_upload,omitempty"`
+	Disable    string `yaml:"disable,omitempty"`
+
+	// Binary packages the built binaries as platform gems instead of
+	// building the gemspec with Ruby tooling
+	Binary   bool     `yaml:"binary,omitempty"`
+	Name     string   `yaml:"name,omitempty"`
+	IDs      []string `yaml:"ids,omitempty"`
+	Summary  string   `yaml:"summary,omitempty"`
+	Homepage string   `yaml:"homepage,omitempty"`
+	License  string   `yaml:"license,omitempty"`
+	Authors  []string `yaml:"authors,omitempty"`
 }
 
-// Helm represents Helm chart publishing configuration
+// Helm represents Helm chart packaging and publishing configuration
 type Helm struct {
 	ID         string `yaml:"id,omitempty"`
 	Repository string `yaml:"repository,omitempty"`
 	Username   string `yaml:"username,omitempty"`
-	Password   string `yaml:"password,omitempty"`
+	Password   string `yaml:"password,omitempty" secret:"true"`
 	ChartPath  string `yaml:"chart_path,omitempty"`
+	Version    string `yaml:"version,omitempty"`
 	AppVersion string `yaml:"app_version,omitempty"`
 	SkipUpload string `yaml:"skip_upload,omitempty"`
+	Disable    string `yaml:"disable,omitempty"`
+
+	// ImageTagPath is the dotted path of the image tag in values.yaml,
+	// e.g. image.tag, set to ImageTag
+	ImageTagPath string   `yaml:"image_tag_path,omitempty"`
+	ImageTag     string   `yaml:"image_tag,omitempty"`
+	Sign         HelmSign `yaml:"sign,omitempty"`
+}
+
+// HelmSign configures the provenance file of a chart, made with helm
+// package --sign
+type HelmSign struct {
+	Key            string `yaml:"key,omitempty"`
+	Keyring        string `yaml:"keyring,omitempty"`
+	PassphraseFile string `yaml:"passphrase_file,omitempty"`
 }
 
 // Cosign represents Cosign signing configuration
 type Cosign struct {
+	ID              string   `yaml:"id,omitempty"`
 	Cmd             string   `yaml:"cmd,omitempty"`
 	Artifacts       string   `yaml:"artifacts,omitempty"`
+	IDs             []string `yaml:"ids,omitempty"`
 	Images          []string `yaml:"images,omitempty"`
 	KeyRef          string   `yaml:"key_ref,omitempty"`
-	Password        string   `yaml:"password,omitempty"`
+	Password        string   `yaml:"password,omitempty" secret:"true"`
 	Keyless         bool     `yaml:"keyless,omitempty"`
 	FulcioURL       string   `yaml:"fulcio_url,omitempty"`
 	RekorURL        string   `yaml:"rekor_url,omitempty"`
@@ -576,6 +799,13 @@ type Cosign struct {
 	OIDCIssuer      string   `yaml:"oidc_issuer,omitempty"`
 	OIDCClientID    string   `yaml:"oidc_client_id,omitempty"`
 	RegistryOptions []string `yaml:"registry_options,omitempty"`
+	// IdentityToken is an OIDC token for keyless signing, e.g.
+	// "{{ .Env.SIGSTORE_ID_TOKEN }}"
+	IdentityToken string `yaml:"identity_token,omitempty" secret:"true"`
+	// Attest creates a SLSA provenance attestation for each signed blob and image
+	Attest bool `yaml:"attest,omitempty"`
+	// Env sets additional environment variables for cosign
+	Env []string `yaml:"env,omitempty"`
 }
 
 // Kubernetes represents Kubernetes deployment configuration
@@ -595,6 +825,23 @@ type Kubernetes struct {
 	HelmChart    bool              `yaml:"helm_chart,omitempty"`
 	ChartVersion string            `yaml:"chart_version,omitempty"`
 	OutputDir    string            `yaml:"output_dir,omitempty"`
+	Disable      string            `yaml:"disable,omitempty"`
+
+	// Manifests is the directory of manifest templates, rendered with the
+	// release context
+	Manifests string `yaml:"manifests,omitempty"`
+	// Kustomize is an overlay directory, relative to Manifests, built with
+	// kustomize build
+	Kustomize string `yaml:"kustomize,omitempty"`
+	// Variants emits one file per overlay; without them a single file named
+	// Name (default install.yaml) is built from Kustomize
+	Variants []K8sVariant `yaml:"variants,omitempty"`
+}
+
+// K8sVariant is an install file built from its own overlay
+type K8sVariant struct {
+	Name      string `yaml:"name" jsonschema:"required"`
+	Kustomize string `yaml:"kustomize,omitempty"`
 }
 
 // K8sResources for Kubernetes resource limits
@@ -618,6 +865,25 @@ type DockerCompose struct {
 	Networks    map[string]interface{}    `yaml:"networks,omitempty"`
 	Volumes     map[string]interface{}    `yaml:"volumes,omitempty"`
 	OutputDir   string                    `yaml:"output_dir,omitempty"`
+	Disable     string                    `yaml:"disable,omitempty"`
+
+	// Template is a compose file rendered with the release context; without
+	// it a file is generated from Services plus the released image's service
+	Template string `yaml:"template,omitempty"`
+	// Service runs the released image, default the project name. Without it
+	// template services using the image's repository are pinned.
+	Service string `yaml:"service,omitempty"`
+	// Docker is the id of the docker config whose first image template is
+	// the released image, default the first docker config
+	Docker string `yaml:"docker,omitempty"`
+	// Image overrides the released image
+	Image string `yaml:"image,omitempty"`
+	// Env holds environment defaults of the service, written as
+	// ${KEY:-default} so they can be overridden
+	Env    map[string]string `yaml:"env,omitempty"`
+	Labels map[string]string `yaml:"labels,omitempty"`
+	// EnvExample also writes .env.example with the Env defaults
+	EnvExample bool `yaml:"env_example,omitempty"`
 }
 
 // ComposeService represents a Docker Compose service
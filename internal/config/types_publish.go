@@ -3,7 +3,7 @@ package config
 // Blob represents cloud storage configuration
 type Blob struct {
 	ID                 string      `yaml:"id,omitempty"`
-	Provider           string      `yaml:"provider,omitempty"`
+	Provider           string      `yaml:"provider,omitempty" enum:"s3,gcs,azblob,azure,minio"`
 	Bucket             string      `yaml:"bucket,omitempty"`
 	Region             string      `yaml:"region,omitempty"`
 	Directory          string      `yaml:"directory,omitempty"`
@@ -14,6 +14,7 @@ type Blob struct {
 	ACL                string      `yaml:"acl,omitempty"`
 	CacheControl       []string    `yaml:"cache_control,omitempty"`
 	ContentDisposition string      `yaml:"content_disposition,omitempty"`
+	IncludePrivate     bool        `yaml:"include_private,omitempty"`
 }
 
 // Upload represents custom HTTP upload configuration
@@ -27,7 +28,7 @@ type Upload struct {
 	ChecksumHeader     string            `yaml:"checksum_header,omitempty"`
 	TrustedCerts       string            `yaml:"trusted_certs,omitempty"`
 	ClientCert         string            `yaml:"client_cert,omitempty"`
-	ClientKey          string            `yaml:"client_key,omitempty"`
+	ClientKey          string            `yaml:"client_key,omitempty" secret:"true"`
 	IDs                []string          `yaml:"ids,omitempty"`
 	ExtraFiles         []ExtraFile       `yaml:"extra_files,omitempty"`
 	CustomArtifactName bool              `yaml:"custom_artifact_name,omitempty"`
@@ -58,7 +59,7 @@ type Source struct {
 
 // SourceFile for source archive files
 type SourceFile struct {
-	Src         string `yaml:"src"`
+	Src         string `yaml:"src" jsonschema:"required"`
 	Dst         string `yaml:"dst,omitempty"`
 	StripParent bool   `yaml:"strip_parent,omitempty"`
 }
@@ -92,6 +93,7 @@ type UniversalBinary struct {
 	IDs          []string   `yaml:"ids,omitempty"`
 	NameTemplate string     `yaml:"name_template,omitempty"`
 	Replace      bool       `yaml:"replace,omitempty"`
+	Goamd64      string     `yaml:"goamd64,omitempty"`
 	ModTimestamp string     `yaml:"mod_timestamp,omitempty"`
 	Hooks        BuildHooks `yaml:"hooks,omitempty"`
 }
@@ -142,6 +144,11 @@ type Winget struct {
 	CommitAuthor        CommitAuthor `yaml:"commit_author,omitempty"`
 	CommitMsgTemplate   string       `yaml:"commit_msg_template,omitempty"`
 	Path                string       `yaml:"path,omitempty"`
+	Fork                string       `yaml:"fork,omitempty"`
+	Moniker             string       `yaml:"moniker,omitempty"`
+	Binary              string       `yaml:"binary,omitempty"`
+	Disable             string       `yaml:"disable,omitempty"`
+	SkipPrerelease      bool         `yaml:"skip_prerelease,omitempty"`
 }
 
 // AUR represents Arch User Repository configuration
@@ -152,7 +159,7 @@ type AUR struct {
 	License           string       `yaml:"license,omitempty"`
 	Maintainers       []string     `yaml:"maintainers,omitempty"`
 	Contributors      []string     `yaml:"contributors,omitempty"`
-	PrivateKey        string       `yaml:"private_key,omitempty"`
+	PrivateKey        string       `yaml:"private_key,omitempty" secret:"true"`
 	GitURL            string       `yaml:"git_url,omitempty"`
 	GitSSHCommand     string       `yaml:"git_ssh_command,omitempty"`
 	SkipUpload        string       `yaml:"skip_upload,omitempty"`
@@ -169,6 +176,17 @@ type AUR struct {
 	CommitAuthor      CommitAuthor `yaml:"commit_author,omitempty"`
 	CommitMsgTemplate string       `yaml:"commit_msg_template,omitempty"`
 	Directory         string       `yaml:"directory,omitempty"`
+
+	// Mode selects a prebuilt "binary" package (default) or a "source"
+	// package that builds from the uploaded source archive
+	Mode              string   `yaml:"mode,omitempty"`
+	MakeDepends       []string `yaml:"makedepends,omitempty"`
+	SourceURLTemplate string   `yaml:"source_url_template,omitempty"`
+	Prepare           string   `yaml:"prepare,omitempty"`
+	Build             string   `yaml:"build,omitempty"`
+	Check             string   `yaml:"check,omitempty"`
+	Disable           string   `yaml:"disable,omitempty"`
+	SkipPrerelease    bool     `yaml:"skip_prerelease,omitempty"`
 }
 
 // Krew represents kubectl krew plugin configuration
@@ -235,6 +253,9 @@ type Fury struct {
 	Account    string   `yaml:"account,omitempty"`
 	SkipUpload string   `yaml:"skip_upload,omitempty"`
 	IDs        []string `yaml:"ids,omitempty"`
+	Disable    string   `yaml:"disable,omitempty"`
+	// Formats limits the uploaded packages: deb, rpm, python or gem
+	Formats []string `yaml:"formats,omitempty"`
 }
 
 // CloudSmith represents CloudSmith configuration
@@ -244,6 +265,16 @@ type CloudSmith struct {
 	SkipUpload   string   `yaml:"skip_upload,omitempty"`
 	IDs          []string `yaml:"ids,omitempty"`
 	Distribution string   `yaml:"distribution,omitempty"`
+	Disable      string   `yaml:"disable,omitempty"`
+	// Formats limits the uploaded packages: deb, rpm, apk, python or gem
+	Formats []string `yaml:"formats,omitempty"`
+	// Distributions overrides Distribution per format, e.g.
+	// deb: ubuntu/jammy, rpm: el/9, apk: alpine/v3.19
+	Distributions map[string]string `yaml:"distributions,omitempty"`
+	// Component is the Debian component, default main
+	Component string `yaml:"component,omitempty"`
+	// Republish replaces an existing package of the same version
+	Republish bool `yaml:"republish,omitempty"`
 }
 
 // TemplateFile represents template file configuration
@@ -253,11 +284,130 @@ type TemplateFile struct {
 	Content string `yaml:"content,omitempty"`
 }
 
+// Docs renders version docs after stable releases: install instructions
+// with the pinned version and checksums, a JSON manifest of the latest
+// version and templates, written to dist/docs and pushed to a docs
+// repository or branch such as gh-pages
+type Docs struct {
+	// Install is the path of the install instructions, install.md by
+	// default
+	Install string `yaml:"install,omitempty"`
+	// Manifest is the path of the JSON manifest, versions.json by default
+	Manifest string `yaml:"manifest,omitempty"`
+	// Templates are more files rendered with the release, which can use
+	// .Install, the install command of each method
+	Templates []TemplateFile `yaml:"templates,omitempty"`
+	// Directory is prepended to the paths in the repository
+	Directory string `yaml:"directory,omitempty"`
+	// Repository is where the docs are pushed; nothing is pushed without
+	// one
+	Repository        RepoRef      `yaml:"repository,omitempty"`
+	CommitAuthor      CommitAuthor `yaml:"commit_author,omitempty"`
+	CommitMsgTemplate string       `yaml:"commit_msg_template,omitempty"`
+	Disable           string       `yaml:"disable,omitempty"`
+}
+
+// Updates generates the feeds desktop apps poll for new versions: a
+// Sparkle appcast and a generic latest.json. They are registered as
+// artifacts with the ID "updates", so the release and blobs with
+// ids: [updates] upload them.
+type Updates struct {
+	Appcast  Appcast        `yaml:"appcast,omitempty"`
+	Manifest UpdateManifest `yaml:"manifest,omitempty"`
+	// URLTemplate is the download URL of an artifact,
+	// {{ .ReleaseDownloadURL }}/{{ .ArtifactName }} by default
+	URLTemplate string `yaml:"url_template,omitempty"`
+	// NotesURL links the release notes, .ReleaseURL by default
+	NotesURL       string `yaml:"notes_url,omitempty"`
+	SkipPrerelease bool   `yaml:"skip_prerelease,omitempty"`
+	Disable        string `yaml:"disable,omitempty"`
+}
+
+// Appcast is a Sparkle appcast with an item for the DMG of each release
+type Appcast struct {
+	Enabled bool `yaml:"enabled,omitempty"`
+	// Name is the file name, appcast.xml by default
+	Name string `yaml:"name,omitempty"`
+	// URL serves the current appcast. Its items are kept and the release
+	// is added, so uploading the result appends to the feed.
+	URL string `yaml:"url,omitempty"`
+	// ForbiddenIsMissing starts a new appcast when the URL answers 403, as
+	// buckets do for missing objects unless listing is allowed
+	ForbiddenIsMissing bool `yaml:"forbidden_is_missing,omitempty"`
+	// Title of the channel, the project name by default
+	Title string `yaml:"title,omitempty"`
+	// IDs select the DMG when several are built
+	IDs []string `yaml:"ids,omitempty"`
+	// Version is sparkle:version, compared with CFBundleVersion,
+	// {{ .Version }} by default
+	Version              string `yaml:"version,omitempty"`
+	ShortVersion         string `yaml:"short_version,omitempty"`
+	MinimumSystemVersion string `yaml:"minimum_system_version,omitempty"`
+	// EdKey is the base64 EdDSA private key of Sparkle's generate_keys, or
+	// the path of a file holding it; $SPARKLE_PRIVATE_KEY by default
+	EdKey string `yaml:"ed_key,omitempty" secret:"true"`
+}
+
+// UpdateManifest is a JSON file with the version and the download of each
+// platform
+type UpdateManifest struct {
+	Enabled bool `yaml:"enabled,omitempty"`
+	// Name is the file name, latest.json by default
+	Name string   `yaml:"name,omitempty"`
+	IDs  []string `yaml:"ids,omitempty"`
+}
+
 // Metadata represents project metadata
 type Metadata struct {
 	ModTimestamp string `yaml:"mod_timestamp,omitempty"`
 }
 
+// GoMod gets the released tag into the Go module proxy and checks that it
+// installs, before the release is announced
+type GoMod struct {
+	// Module is the module path, read from go.mod by default
+	Module string `yaml:"module,omitempty"`
+	// ProxyWarmup requests the tag from the proxy so it is indexed, and
+	// waits until the proxy serves it
+	ProxyWarmup bool `yaml:"proxy_warmup,omitempty"`
+	// Verify runs go install for the tag in a clean GOPATH
+	Verify bool `yaml:"verify,omitempty"`
+	// Main is the package go install verifies, relative to the module,
+	// e.g. ./cmd/app (default: the main of the first go build)
+	Main string `yaml:"main,omitempty"`
+	// Proxy is the module proxy (default: https://proxy.golang.org)
+	Proxy string `yaml:"proxy,omitempty"`
+	// Timeout bounds waiting for the proxy to serve the tag (default: 10m)
+	Timeout string `yaml:"timeout,omitempty"`
+	// Disable skips the step when it renders to "true"
+	Disable string `yaml:"disable,omitempty"`
+}
+
+// SizeReport records the sizes of binaries, archives and packages in
+// sizes.json and compares them with the previous release
+type SizeReport struct {
+	// Previous is where the sizes of the previous release come from:
+	// github (default) reads its release, a path or URL reads the
+	// sizes.json of that release, and none skips the comparison
+	Previous string `yaml:"previous,omitempty"`
+	// Disable skips the report when it renders to "true"
+	Disable string `yaml:"disable,omitempty"`
+}
+
+// SizeLimit fails the release when the artifacts it selects are too large
+// or grew too much since the previous release
+type SizeLimit struct {
+	// IDs select artifacts by build ID
+	IDs []string `yaml:"ids,omitempty"`
+	// Glob selects artifacts by file name, e.g. "*.tar.gz"
+	Glob string `yaml:"glob,omitempty"`
+	// Max is the largest size allowed, e.g. 25MB
+	Max string `yaml:"max,omitempty"`
+	// MaxGrowth is the most an artifact may grow over the previous release,
+	// a size like 2MB or a percentage like 10%
+	MaxGrowth string `yaml:"max_growth,omitempty"`
+}
+
 // Monorepo represents monorepo configuration
 type Monorepo struct {
 	Enabled      bool              `yaml:"enabled,omitempty"`
@@ -270,8 +420,8 @@ type Monorepo struct {
 
 // MonorepoProject represents a project within a monorepo
 type MonorepoProject struct {
-	Name      string   `yaml:"name"`
-	Path      string   `yaml:"path"`
+	Name      string   `yaml:"name" jsonschema:"required"`
+	Path      string   `yaml:"path" jsonschema:"required"`
 	DependsOn []string `yaml:"depends_on,omitempty"`
 }
 
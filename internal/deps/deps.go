@@ -13,6 +13,9 @@ import (
 	"strings"
 
 	"github.com/charmbracelet/log"
+
+	"github.com/oarkflow/releaser/internal/hook"
+	"github.com/oarkflow/releaser/internal/redact"
 )
 
 // Tool represents a required tool/dependency
@@ -117,6 +120,17 @@ var CommonTools = map[string]Tool{
 		},
 		Optional: true,
 	},
+	"garble": {
+		Name:        "Garble",
+		Binary:      "garble",
+		Description: "Go binary obfuscator",
+		InstallCmds: []string{
+			"linux:go install mvdan.cc/garble@latest",
+			"darwin:go install mvdan.cc/garble@latest",
+			"windows:go install mvdan.cc/garble@latest",
+		},
+		Optional: true,
+	},
 	"fyne-cross": {
 		Name:        "Fyne Cross",
 		Binary:      "fyne-cross",
@@ -144,8 +158,83 @@ var CommonTools = map[string]Tool{
 // AutoInstall controls whether to auto-install missing dependencies
 var AutoInstall = false
 
-// PromptForInstall controls whether to prompt user for installation
-var PromptForInstall = true
+// PromptForInstall controls whether to prompt user for installation. Only
+// interactive sessions are asked, so CI never installs unless told to.
+var PromptForInstall = isTerminal(os.Stdin)
+
+// Offline disables every installation attempt, for machines without network
+// access
+var Offline = false
+
+// flagged is set when the command line chose whether to install, which the
+// config then does not override
+var flagged bool
+
+// SetAutoInstall installs missing tools without asking when on, and never
+// installs them when off. It takes precedence over Configure.
+func SetAutoInstall(on bool) {
+	AutoInstall = on
+	PromptForInstall = false
+	flagged = true
+}
+
+// Configure applies the config's deps.auto_install, unless the command line
+// already decided
+func Configure(autoInstall *bool) {
+	if autoInstall == nil || flagged {
+		return
+	}
+	AutoInstall = *autoInstall
+	PromptForInstall = false
+}
+
+// Installing reports whether missing tools may be installed
+func Installing() bool {
+	return !Offline && (AutoInstall || PromptForInstall)
+}
+
+// Missing is a tool that is not installed
+type Missing struct {
+	// Tool is the binary that was not found
+	Tool string
+	// NeededBy is what needs the tool, e.g. nfpms
+	NeededBy string
+	// Install is the command installing it on this OS, if known
+	Install string
+}
+
+// MissingError lists every missing tool with its install command, so all
+// of them can be installed at once
+type MissingError struct {
+	Tools []Missing
+}
+
+func (e *MissingError) Error() string {
+	var sb strings.Builder
+	sb.WriteString("missing tools:")
+	for _, m := range e.Tools {
+		sb.WriteString("\n  - " + m.Tool)
+		if m.NeededBy != "" {
+			sb.WriteString(" (" + m.NeededBy + ")")
+		}
+		if m.Install != "" {
+			sb.WriteString(": " + m.Install)
+		} else {
+			sb.WriteString(": install it and add it to PATH")
+		}
+	}
+	return sb.String()
+}
+
+// InstallCommand returns the command installing a known tool on this OS,
+// or "" when there is none
+func InstallCommand(toolName string) string {
+	tool, ok := CommonTools[toolName]
+	if !ok {
+		return ""
+	}
+	return findInstallCommand(tool.InstallCmds)
+}
 
 // CheckAndInstall checks if a tool is available and offers to install it if missing
 func CheckAndInstall(toolName string) error {
@@ -163,15 +252,13 @@ func CheckAndInstallTool(tool Tool) error {
 		return nil
 	}
 
-	log.Warn("Tool not found", "tool", tool.Name, "binary", tool.Binary)
-
-	if tool.Optional && !AutoInstall && !PromptForInstall {
-		log.Info("Skipping optional tool", "tool", tool.Name)
-		return nil
-	}
-
 	// Find installation command for current OS
 	installCmd := findInstallCommand(tool.InstallCmds)
+	if !Installing() {
+		return &MissingError{Tools: []Missing{{Tool: tool.Binary, Install: installCmd}}}
+	}
+
+	log.Warn("Tool not found", "tool", tool.Name, "binary", tool.Binary)
 	if installCmd == "" {
 		if tool.Optional {
 			log.Warn("No installation method available", "tool", tool.Name, "os", runtime.GOOS)
@@ -226,12 +313,23 @@ func CheckAndInstallTool(tool Tool) error {
 	return nil
 }
 
+// isTerminal reports whether f is an interactive terminal
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
 // IsAvailable checks if a binary is available in PATH
 func IsAvailable(binary string) bool {
 	_, err := exec.LookPath(binary)
 	return err == nil
 }
 
+// ContainerCLIAvailable reports whether docker, podman or nerdctl is in PATH
+func ContainerCLIAvailable() bool {
+	return IsAvailable("docker") || IsAvailable("podman") || IsAvailable("nerdctl")
+}
+
 // findInstallCommand finds the best installation command for the current OS
 func findInstallCommand(cmds []string) string {
 	os := runtime.GOOS
@@ -267,8 +365,9 @@ func findInstallCommand(cmds []string) string {
 
 		// Check for package manager specific command
 		remaining := parts[1]
+		// The colon of a URL does not start a package manager
 		subParts := strings.SplitN(remaining, ":", 2)
-		if len(subParts) == 2 {
+		if len(subParts) == 2 && !strings.Contains(subParts[0], " ") {
 			// Has package manager specification
 			if subParts[0] == pkgManager {
 				return subParts[1]
@@ -287,9 +386,10 @@ func findInstallCommand(cmds []string) string {
 
 // runInstallCommand runs an installation command
 func runInstallCommand(cmdStr string) error {
-	log.Debug("Running installation command", "cmd", cmdStr)
+	log.Debug("Running installation command", "cmd", redact.String(cmdStr))
 
-	cmd := exec.Command("sh", "-c", cmdStr)
+	args := hook.ShellCommand("", cmdStr)
+	cmd := exec.Command(args[0], args[1:]...)
 	cmd.Stdout = os.Stdout
 	cmd.Stderr = os.Stderr
 	cmd.Stdin = os.Stdin
@@ -383,7 +483,7 @@ func EnsureBuildTools(needsPackaging, needsSigning, needsDocker, needsSBOM bool)
 		}
 	}
 
-	if needsDocker {
+	if needsDocker && !ContainerCLIAvailable() {
 		if err := CheckAndInstall("docker"); err != nil {
 			return err
 		}
@@ -426,6 +526,13 @@ func DetectAndInstallForFyne() error {
 		}
 
 		if needsInstall {
+			if !Installing() {
+				return &MissingError{Tools: []Missing{{
+					Tool:     "Fyne system libraries",
+					NeededBy: "gui builds",
+					Install:  "sudo apt-get install -y " + strings.Join(deps, " "),
+				}}}
+			}
 			if PromptForInstall {
 				fmt.Printf("\n🔧 Fyne GUI requires system dependencies.\n")
 				fmt.Printf("   Packages: %s\n", strings.Join(deps, " "))
@@ -474,7 +581,7 @@ func GetInstallInstructions(toolName string) string {
 			remaining := parts[1]
 
 			subParts := strings.SplitN(remaining, ":", 2)
-			if len(subParts) == 2 {
+			if len(subParts) == 2 && !strings.Contains(subParts[0], " ") {
 				sb.WriteString(fmt.Sprintf("  %s (%s):\n    %s\n", os, subParts[0], subParts[1]))
 			} else {
 				sb.WriteString(fmt.Sprintf("  %s:\n    %s\n", os, remaining))
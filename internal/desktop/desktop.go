@@ -0,0 +1,192 @@
+// Package desktop generates freedesktop desktop entries and icon theme
+// files for the Linux packages of GUI builds.
+package desktop
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/oarkflow/releaser/internal/config"
+)
+
+// mainCategories are the main categories of the desktop menu specification
+var mainCategories = []string{
+	"AudioVideo", "Audio", "Video", "Development", "Education", "Game",
+	"Graphics", "Network", "Office", "Science", "Settings", "System", "Utility",
+}
+
+// additionalCategories are the additional and reserved categories of the
+// desktop menu specification
+var additionalCategories = []string{
+	"Building", "Debugger", "IDE", "GUIDesigner", "Profiling", "RevisionControl",
+	"Translation", "Calendar", "ContactManagement", "Database", "Dictionary",
+	"Chart", "Email", "Finance", "FlowChart", "PDA", "ProjectManagement",
+	"Presentation", "Spreadsheet", "WordProcessor", "2DGraphics",
+	"VectorGraphics", "RasterGraphics", "3DGraphics", "Scanning", "OCR",
+	"Photography", "Publishing", "Viewer", "TextTools", "DesktopSettings",
+	"HardwareSettings", "Printing", "PackageManager", "Dialup",
+	"InstantMessaging", "Chat", "IRCClient", "Feed", "FileTransfer", "HamRadio",
+	"News", "P2P", "RemoteAccess", "Telephony", "TelephonyTools",
+	"VideoConference", "WebBrowser", "WebDevelopment", "Midi", "Mixer",
+	"Sequencer", "Tuner", "TV", "AudioVideoEditing", "Player", "Recorder",
+	"DiscBurning", "ActionGame", "AdventureGame", "ArcadeGame", "BoardGame",
+	"BlocksGame", "CardGame", "KidsGame", "LogicGame", "RolePlaying", "Shooter",
+	"Simulation", "SportsGame", "StrategyGame", "Art", "Construction", "Music",
+	"Languages", "ArtificialIntelligence", "Astronomy", "Biology", "Chemistry",
+	"ComputerScience", "DataVisualization", "Economy", "Electricity",
+	"Geography", "Geology", "Geoscience", "History", "Humanities",
+	"ImageProcessing", "Literature", "Maps", "Math", "NumericalAnalysis",
+	"MedicalSoftware", "Physics", "Robotics", "Spirituality", "Sports",
+	"ParallelComputing", "Amusement", "Archiving", "Compression", "Electronics",
+	"Emulator", "Engineering", "FileTools", "FileManager", "TerminalEmulator",
+	"Filesystem", "Monitor", "Security", "Accessibility", "Calculator", "Clock",
+	"TextEditor", "Documentation", "Adult", "Core", "KDE", "GNOME", "XFCE",
+	"DDE", "GTK", "Qt", "Motif", "Java", "ConsoleOnly",
+	"Screensaver", "TrayIcon", "Applet", "Shell",
+}
+
+// Entry describes a .desktop file. Its own settings take precedence over
+// the build's gui settings.
+type Entry struct {
+	Name       string
+	Comment    string
+	Exec       string
+	Icon       string
+	Categories []string
+	Keywords   []string
+	Terminal   bool
+	GUI        *config.GUIConfig
+	// Localizations add translated Name and Comment keys, by language
+	Localizations map[string]config.Localization
+}
+
+// gui returns the build's gui settings, empty when there are none
+func (e Entry) gui() *config.GUIConfig {
+	if e.GUI == nil {
+		return &config.GUIConfig{}
+	}
+	return e.GUI
+}
+
+// categories returns the entry's categories, the gui ones, or Utility
+func (e Entry) categories() []string {
+	if len(e.Categories) > 0 {
+		return e.Categories
+	}
+	if len(e.gui().Categories) > 0 {
+		return e.gui().Categories
+	}
+	return []string{"Utility"}
+}
+
+// Validate checks the categories against the desktop menu specification.
+// Vendor categories prefixed with "X-" are accepted.
+func (e Entry) Validate() error {
+	var unknown []string
+	for _, category := range e.categories() {
+		if strings.HasPrefix(category, "X-") || slices.Contains(mainCategories, category) || slices.Contains(additionalCategories, category) {
+			continue
+		}
+		unknown = append(unknown, category)
+	}
+	if len(unknown) > 0 {
+		return fmt.Errorf("unknown desktop categories %s, see https://specifications.freedesktop.org/menu-spec/latest/category-registry.html",
+			strings.Join(unknown, ", "))
+	}
+	return nil
+}
+
+// String renders the desktop file
+func (e Entry) String() string {
+	gui := e.gui()
+	comment := e.Comment
+	if comment == "" {
+		comment = gui.Comment
+	}
+	keywords := e.Keywords
+	if len(keywords) == 0 {
+		keywords = gui.Keywords
+	}
+
+	var sb strings.Builder
+	sb.WriteString("[Desktop Entry]\nType=Application\n")
+	langs := make([]string, 0, len(e.Localizations))
+	for lang := range e.Localizations {
+		langs = append(langs, lang)
+	}
+	sort.Strings(langs)
+
+	// Languages without a translation use the untranslated key
+	fmt.Fprintf(&sb, "Name=%s\n", e.Name)
+	for _, lang := range langs {
+		if name := e.Localizations[lang].Name; name != "" {
+			fmt.Fprintf(&sb, "Name[%s]=%s\n", lang, name)
+		}
+	}
+	if gui.GenericName != "" {
+		fmt.Fprintf(&sb, "GenericName=%s\n", gui.GenericName)
+	}
+	if comment != "" {
+		fmt.Fprintf(&sb, "Comment=%s\n", comment)
+		for _, lang := range langs {
+			if translated := e.Localizations[lang].Comment; translated != "" {
+				fmt.Fprintf(&sb, "Comment[%s]=%s\n", lang, translated)
+			}
+		}
+	}
+	exec := e.Exec
+	if len(gui.MimeTypes) > 0 {
+		// Let the desktop pass the files the app is opened with
+		exec += " %F"
+	}
+	fmt.Fprintf(&sb, "Exec=%s\n", exec)
+	fmt.Fprintf(&sb, "Icon=%s\n", e.Icon)
+	fmt.Fprintf(&sb, "Terminal=%t\n", e.Terminal || gui.Terminal)
+	fmt.Fprintf(&sb, "Categories=%s;\n", strings.Join(e.categories(), ";"))
+	if len(keywords) > 0 {
+		fmt.Fprintf(&sb, "Keywords=%s;\n", strings.Join(keywords, ";"))
+	}
+	if len(gui.MimeTypes) > 0 {
+		fmt.Fprintf(&sb, "MimeType=%s;\n", strings.Join(gui.MimeTypes, ";"))
+	}
+	if gui.StartupNotify {
+		sb.WriteString("StartupNotify=true\n")
+	}
+
+	ids := make([]string, len(gui.Actions))
+	for i, action := range gui.Actions {
+		ids[i] = ActionID(action.Name)
+	}
+	if len(ids) > 0 {
+		fmt.Fprintf(&sb, "Actions=%s;\n", strings.Join(ids, ";"))
+	}
+	for i, action := range gui.Actions {
+		command := action.Exec
+		if command == "" {
+			command = e.Exec
+		}
+		fmt.Fprintf(&sb, "\n[Desktop Action %s]\nName=%s\nExec=%s\n", ids[i], action.Name, command)
+		if action.Icon != "" {
+			fmt.Fprintf(&sb, "Icon=%s\n", action.Icon)
+		}
+	}
+
+	return sb.String()
+}
+
+// ActionID turns an action name into a desktop action identifier
+func ActionID(name string) string {
+	id := strings.Map(func(r rune) rune {
+		if r < unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r) || r == '-') {
+			return r
+		}
+		return -1
+	}, name)
+	if id == "" {
+		return "action"
+	}
+	return id
+}
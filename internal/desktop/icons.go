@@ -0,0 +1,90 @@
+package desktop
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/png"
+	"os"
+	"path/filepath"
+	"strings"
+
+	imagedraw "golang.org/x/image/draw"
+)
+
+// IconSizes are the hicolor theme sizes PNG icons are rendered at
+var IconSizes = []int{16, 32, 48, 64, 128, 256, 512}
+
+// InstallIcons installs an icon into a hicolor theme directory as name and
+// returns the installed paths relative to it. PNG icons are scaled to each
+// theme size up to their own size; SVG icons are installed as scalable.
+func InstallIcons(themeDir, src, name string) ([]string, error) {
+	ext := strings.ToLower(filepath.Ext(src))
+	switch ext {
+	case ".svg":
+		rel := filepath.Join("scalable", "apps", name+ext)
+		if err := copyFile(src, filepath.Join(themeDir, rel)); err != nil {
+			return nil, err
+		}
+		return []string{rel}, nil
+	case ".png":
+	default:
+		return nil, fmt.Errorf("unsupported icon %s: use a png or svg icon", src)
+	}
+
+	f, err := os.Open(src)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	img, err := png.Decode(f)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode icon %s: %w", src, err)
+	}
+
+	width := img.Bounds().Dx()
+	var installed []string
+	for _, size := range IconSizes {
+		if size > width {
+			break
+		}
+		scaled := image.NewRGBA(image.Rect(0, 0, size, size))
+		imagedraw.CatmullRom.Scale(scaled, scaled.Bounds(), img, img.Bounds(), imagedraw.Over, nil)
+
+		var buf bytes.Buffer
+		if err := png.Encode(&buf, scaled); err != nil {
+			return nil, err
+		}
+		rel := filepath.Join(fmt.Sprintf("%dx%d", size, size), "apps", name+ext)
+		dst := filepath.Join(themeDir, rel)
+		if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+			return nil, err
+		}
+		if err := os.WriteFile(dst, buf.Bytes(), 0644); err != nil {
+			return nil, err
+		}
+		installed = append(installed, rel)
+	}
+
+	// Icons smaller than every theme size are installed as they are
+	if len(installed) == 0 {
+		rel := filepath.Join(fmt.Sprintf("%dx%d", width, width), "apps", name+ext)
+		if err := copyFile(src, filepath.Join(themeDir, rel)); err != nil {
+			return nil, err
+		}
+		installed = append(installed, rel)
+	}
+	return installed, nil
+}
+
+// copyFile copies src to dst, creating dst's directory
+func copyFile(src, dst string) error {
+	data, err := os.ReadFile(src)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(dst, data, 0644)
+}
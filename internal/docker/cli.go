@@ -0,0 +1,34 @@
+package docker
+
+import (
+	"os/exec"
+
+	"github.com/charmbracelet/log"
+)
+
+// Supported container CLIs
+const (
+	CLIDocker  = "docker"
+	CLIPodman  = "podman"
+	CLINerdctl = "nerdctl"
+)
+
+// CLIs lists the supported container CLIs in detection order
+var CLIs = []string{CLIDocker, CLIPodman, CLINerdctl}
+
+// ResolveCLI returns the configured container CLI, or the first supported
+// CLI found in PATH when none is configured
+func ResolveCLI(configured string) string {
+	if configured != "" {
+		return configured
+	}
+	for _, cli := range CLIs {
+		if _, err := exec.LookPath(cli); err == nil {
+			if cli != CLIDocker {
+				log.Debug("Using container CLI", "cli", cli)
+			}
+			return cli
+		}
+	}
+	return CLIDocker
+}
@@ -0,0 +1,372 @@
+package docker
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/charmbracelet/log"
+	"gopkg.in/yaml.v3"
+
+	"github.com/oarkflow/releaser/internal/artifact"
+	"github.com/oarkflow/releaser/internal/config"
+	"github.com/oarkflow/releaser/internal/tmpl"
+)
+
+// ComposeBuilder writes a Docker Compose file pinned to the released image.
+type ComposeBuilder struct {
+	config  config.DockerCompose
+	dockers []config.Docker
+	tmplCtx *tmpl.Context
+	manager *artifact.Manager
+	distDir string
+}
+
+// NewComposeBuilder creates a new Docker Compose file builder. The released
+// image is taken from dockers unless the config sets one.
+func NewComposeBuilder(cfg config.DockerCompose, dockers []config.Docker, tmplCtx *tmpl.Context, manager *artifact.Manager, distDir string) *ComposeBuilder {
+	return &ComposeBuilder{
+		config:  cfg,
+		dockers: dockers,
+		tmplCtx: tmplCtx,
+		manager: manager,
+		distDir: distDir,
+	}
+}
+
+// Build renders the compose template or generates a compose file, pins the
+// released image's service with its env defaults and labels, validates the
+// result and writes it, with .env.example when configured, to dist.
+func (b *ComposeBuilder) Build() error {
+	image, err := b.image()
+	if err != nil {
+		return err
+	}
+
+	var source []byte
+	if b.config.Template != "" {
+		data, err := os.ReadFile(b.config.Template)
+		if err != nil {
+			return fmt.Errorf("failed to read compose template: %w", err)
+		}
+		rendered, err := b.tmplCtx.Apply(b.config.Template, string(data))
+		if err != nil {
+			return fmt.Errorf("failed to render %s: %w", b.config.Template, err)
+		}
+		source = []byte(rendered)
+	} else {
+		source, err = b.generate(image)
+		if err != nil {
+			return err
+		}
+	}
+
+	var doc yaml.Node
+	if err := yaml.Unmarshal(source, &doc); err != nil {
+		return fmt.Errorf("invalid compose file: %w", err)
+	}
+	if doc.Kind != yaml.DocumentNode || doc.Content[0].Kind != yaml.MappingNode {
+		return fmt.Errorf("invalid compose file: not a mapping")
+	}
+	pinned, err := b.pin(doc.Content[0], image)
+	if err != nil {
+		return err
+	}
+
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(&doc); err != nil {
+		return err
+	}
+	if err := enc.Close(); err != nil {
+		return err
+	}
+	if err := validateCompose(buf.Bytes()); err != nil {
+		return err
+	}
+
+	outDir := b.distDir
+	if b.config.OutputDir != "" {
+		outDir = filepath.Join(b.distDir, b.config.OutputDir)
+	}
+	if err := os.MkdirAll(outDir, 0755); err != nil {
+		return err
+	}
+	name := b.config.File
+	if name == "" {
+		name = "docker-compose.yml"
+	}
+	out := filepath.Join(outDir, name)
+	if err := os.WriteFile(out, buf.Bytes(), 0644); err != nil {
+		return err
+	}
+	if err := b.manager.Add(artifact.Artifact{
+		Name: name,
+		Path: out,
+		Type: artifact.TypeDockerCompose,
+		Extra: map[string]interface{}{
+			"id":       b.config.ID,
+			"image":    image,
+			"services": pinned,
+		},
+	}); err != nil {
+		return err
+	}
+	log.Info("Docker Compose file created", "path", out, "image", image)
+
+	if b.config.EnvExample && len(b.config.Env) > 0 {
+		env, err := b.env()
+		if err != nil {
+			return err
+		}
+		var example strings.Builder
+		for _, key := range sortedKeys(env) {
+			fmt.Fprintf(&example, "%s=%s\n", key, env[key])
+		}
+		envOut := filepath.Join(outDir, ".env.example")
+		if err := os.WriteFile(envOut, []byte(example.String()), 0644); err != nil {
+			return err
+		}
+		if err := b.manager.Add(artifact.Artifact{
+			Name:  ".env.example",
+			Path:  envOut,
+			Type:  artifact.TypeDockerCompose,
+			Extra: map[string]interface{}{"id": b.config.ID},
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// image returns the released image: the configured one, or the first image
+// template of the selected docker config
+func (b *ComposeBuilder) image() (string, error) {
+	if b.config.Image != "" {
+		return b.tmplCtx.Apply("docker_composes.image", b.config.Image)
+	}
+	for _, d := range b.dockers {
+		if b.config.Docker != "" && d.ID != b.config.Docker {
+			continue
+		}
+		if len(d.ImageTemplates) == 0 {
+			continue
+		}
+		return b.tmplCtx.Apply("dockers.image_templates", d.ImageTemplates[0])
+	}
+	if b.config.Docker != "" {
+		return "", fmt.Errorf("no docker config %s with image templates", b.config.Docker)
+	}
+	return "", fmt.Errorf("no image for the compose file, set image or configure dockers")
+}
+
+// service returns the name of the released image's service
+func (b *ComposeBuilder) service() string {
+	if b.config.Service != "" {
+		return b.config.Service
+	}
+	if name := b.tmplCtx.Get("ProjectName"); name != "" {
+		return name
+	}
+	return "app"
+}
+
+// generate builds a compose file from the configured services, adding the
+// released image's service when it is not one of them
+func (b *ComposeBuilder) generate(image string) ([]byte, error) {
+	services := make(map[string]config.ComposeService, len(b.config.Services)+1)
+	for name, svc := range b.config.Services {
+		if svc.Image != "" {
+			rendered, err := b.tmplCtx.Apply("docker_composes.services."+name+".image", svc.Image)
+			if err != nil {
+				return nil, err
+			}
+			svc.Image = rendered
+		}
+		services[name] = svc
+	}
+	if _, ok := services[b.service()]; !ok {
+		services[b.service()] = config.ComposeService{Image: image, Restart: "unless-stopped"}
+	}
+
+	compose := struct {
+		Name     string                           `yaml:"name,omitempty"`
+		Services map[string]config.ComposeService `yaml:"services"`
+		Networks map[string]interface{}           `yaml:"networks,omitempty"`
+		Volumes  map[string]interface{}           `yaml:"volumes,omitempty"`
+	}{
+		Name:     b.config.ProjectName,
+		Services: services,
+		Networks: b.config.Networks,
+		Volumes:  b.config.Volumes,
+	}
+	return yaml.Marshal(compose)
+}
+
+// pin sets the released image, env defaults and labels on the configured
+// service or, without one, on every service using the image's repository.
+// It returns the names of the pinned services.
+func (b *ComposeBuilder) pin(root *yaml.Node, image string) ([]string, error) {
+	services := mappingValue(root, "services")
+	if services == nil || services.Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("compose file has no services")
+	}
+
+	env, err := b.env()
+	if err != nil {
+		return nil, err
+	}
+	labels := make(map[string]string, len(b.config.Labels))
+	for key, value := range b.config.Labels {
+		rendered, err := b.tmplCtx.Apply("docker_composes.labels."+key, value)
+		if err != nil {
+			return nil, fmt.Errorf("failed to apply template to label %s: %w", key, err)
+		}
+		labels[key] = rendered
+	}
+
+	var pinned []string
+	for i := 0; i+1 < len(services.Content); i += 2 {
+		name, svc := services.Content[i].Value, services.Content[i+1]
+		if svc.Kind != yaml.MappingNode {
+			continue
+		}
+		if b.config.Service != "" || b.config.Template == "" {
+			if name != b.service() {
+				continue
+			}
+		} else if current := mappingValue(svc, "image"); current == nil || imageRepository(current.Value) != imageRepository(image) {
+			continue
+		}
+
+		setMapping(svc, "image", image)
+		for _, key := range sortedKeys(env) {
+			addEntry(svc, "environment", key, fmt.Sprintf("${%s:-%s}", key, env[key]))
+		}
+		for _, key := range sortedKeys(labels) {
+			addEntry(svc, "labels", key, labels[key])
+		}
+		pinned = append(pinned, name)
+	}
+	if len(pinned) == 0 {
+		if b.config.Service != "" {
+			return nil, fmt.Errorf("compose file has no service %s", b.config.Service)
+		}
+		return nil, fmt.Errorf("no compose service uses %s", imageRepository(image))
+	}
+	return pinned, nil
+}
+
+// env returns the rendered environment defaults
+func (b *ComposeBuilder) env() (map[string]string, error) {
+	env := make(map[string]string, len(b.config.Env))
+	for key, value := range b.config.Env {
+		rendered, err := b.tmplCtx.Apply("docker_composes.env."+key, value)
+		if err != nil {
+			return nil, fmt.Errorf("failed to apply template to env %s: %w", key, err)
+		}
+		env[key] = rendered
+	}
+	return env, nil
+}
+
+// imageRepository strips the tag and digest from an image reference
+func imageRepository(image string) string {
+	image, _, _ = strings.Cut(image, "@")
+	if i := strings.LastIndex(image, ":"); i > strings.LastIndex(image, "/") {
+		image = image[:i]
+	}
+	return image
+}
+
+// mappingValue returns the value of key in a mapping node, or nil
+func mappingValue(node *yaml.Node, key string) *yaml.Node {
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i+1]
+		}
+	}
+	return nil
+}
+
+// setMapping sets key to a string in a mapping node
+func setMapping(node *yaml.Node, key, value string) {
+	scalar := &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: value}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			scalar.LineComment = node.Content[i+1].LineComment
+			node.Content[i+1] = scalar
+			return
+		}
+	}
+	node.Content = append(node.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: key}, scalar)
+}
+
+// addEntry adds key to a service's environment or labels unless it is
+// already set. Both the mapping and the KEY=value list forms are kept.
+func addEntry(svc *yaml.Node, field, key, value string) {
+	list := mappingValue(svc, field)
+	if list == nil {
+		list = &yaml.Node{Kind: yaml.MappingNode}
+		svc.Content = append(svc.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: field}, list)
+	}
+	switch list.Kind {
+	case yaml.MappingNode:
+		if mappingValue(list, key) == nil {
+			setMapping(list, key, value)
+		}
+	case yaml.SequenceNode:
+		for _, item := range list.Content {
+			if name, _, _ := strings.Cut(item.Value, "="); name == key {
+				return
+			}
+		}
+		list.Content = append(list.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: key + "=" + value})
+	}
+}
+
+// validateCompose checks that a compose file has services that each run an
+// image or a build
+func validateCompose(data []byte) error {
+	var compose struct {
+		Services map[string]map[string]interface{} `yaml:"services"`
+	}
+	if err := yaml.Unmarshal(data, &compose); err != nil {
+		return fmt.Errorf("invalid compose file: %w", err)
+	}
+	if len(compose.Services) == 0 {
+		return fmt.Errorf("compose file has no services")
+	}
+	for name, svc := range compose.Services {
+		if svc["image"] == nil && svc["build"] == nil {
+			return fmt.Errorf("compose service %s has neither image nor build", name)
+		}
+	}
+	return nil
+}
+
+// sortedKeys returns the keys of m in order
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// BuildAllComposeFiles writes the compose files of all configurations.
+func BuildAllComposeFiles(configs []config.DockerCompose, dockers []config.Docker, tmplCtx *tmpl.Context, manager *artifact.Manager, distDir string) error {
+	for i, cfg := range configs {
+		log.Info("Building Docker Compose file", "index", i+1, "total", len(configs))
+		builder := NewComposeBuilder(cfg, dockers, tmplCtx, manager, distDir)
+		if err := builder.Build(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
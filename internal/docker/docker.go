@@ -3,15 +3,22 @@ package docker
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"sort"
 	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/charmbracelet/log"
 	"github.com/oarkflow/releaser/internal/artifact"
 	"github.com/oarkflow/releaser/internal/config"
+	"github.com/oarkflow/releaser/internal/fsutil"
+	"github.com/oarkflow/releaser/internal/redact"
 	"github.com/oarkflow/releaser/internal/tmpl"
 )
 
@@ -21,6 +28,7 @@ type Builder struct {
 	tmplCtx *tmpl.Context
 	manager *artifact.Manager
 	distDir string
+	cli     string
 }
 
 // NewBuilder creates a new Docker builder.
@@ -30,6 +38,7 @@ func NewBuilder(cfg config.Docker, tmplCtx *tmpl.Context, manager *artifact.Mana
 		tmplCtx: tmplCtx,
 		manager: manager,
 		distDir: distDir,
+		cli:     ResolveCLI(cfg.CLI),
 	}
 }
 
@@ -68,56 +77,34 @@ func (b *Builder) Build(ctx context.Context) error {
 		return fmt.Errorf("no image tags configured")
 	}
 
-	// Build command
-	var args []string
-
-	if b.config.Buildx {
-		args = append(args, "buildx", "build")
-		if len(b.config.BuildxPlatforms) > 0 {
-			args = append(args, "--platform", strings.Join(b.config.BuildxPlatforms, ","))
-		}
-		if b.config.Push {
-			args = append(args, "--push")
-		}
-	} else {
-		args = append(args, "build")
-	}
-
-	// Add tags
-	for _, tag := range imageTags {
-		args = append(args, "-t", tag)
-	}
-
-	// Add Dockerfile
-	args = append(args, "-f", dockerfile)
-
-	// Add build args
-	for _, arg := range b.config.BuildArgs {
-		expandedArg, _ := b.tmplCtx.Apply(arg)
-		args = append(args, "--build-arg", expandedArg)
-	}
-
 	// Add extra files if needed
-	for _, file := range b.config.ExtraFiles {
-		expandedFile, _ := b.tmplCtx.Apply(file)
+	for i, file := range b.config.ExtraFiles {
+		expandedFile, err := b.tmplCtx.Apply(fmt.Sprintf("dockers.extra_files[%d]", i), file)
+		if err != nil {
+			return fmt.Errorf("failed to apply template to extra file: %w", err)
+		}
 		// Copy file to build context if needed
 		if _, err := os.Stat(expandedFile); err == nil {
 			destPath := filepath.Join(filepath.Dir(dockerfile), filepath.Base(expandedFile))
-			if err := copyFile(expandedFile, destPath); err != nil {
+			if err := fsutil.CopyFile(expandedFile, destPath); err != nil {
 				log.Warn("Failed to copy extra file", "file", expandedFile, "error", err)
 			}
 		}
 	}
 
-	// Build context
-	buildContext := "."
-	args = append(args, buildContext)
+	args, err := b.buildArgs(dockerfile, imageTags, ".")
+	if err != nil {
+		return err
+	}
 
-	log.Debug("Running docker command", "args", args)
+	log.Debug("Running docker command", "cli", b.cli, "args", redact.Args(args))
 
-	cmd := exec.CommandContext(ctx, "docker", args...)
+	cmd := cliCommand(ctx, b.cli, args...)
 	cmd.Stdout = os.Stdout
 	cmd.Stderr = os.Stderr
+	if b.cli == CLIDocker && (len(b.config.Secrets) > 0 || len(b.config.SSH) > 0) {
+		cmd.Env = append(os.Environ(), "DOCKER_BUILDKIT=1")
+	}
 
 	if err := cmd.Run(); err != nil {
 		return fmt.Errorf("docker build failed: %w", err)
@@ -125,14 +112,26 @@ func (b *Builder) Build(ctx context.Context) error {
 
 	// Add artifact for each tag
 	for _, tag := range imageTags {
-		b.manager.Add(artifact.Artifact{
-			Name: tag,
-			Path: "",
-			Type: artifact.TypeDockerImage,
-			Extra: map[string]interface{}{
-				"image": tag,
-			},
-		})
+		extra := map[string]interface{}{
+			"image": tag,
+		}
+		if b.config.ID != "" {
+			extra["id"] = b.config.ID
+		}
+		if platforms := b.platforms(); len(platforms) > 0 {
+			extra["platforms"] = platforms
+		}
+		if b.loaded() {
+			extra["local_cli"] = b.cli
+		}
+		if err := b.manager.Add(artifact.Artifact{
+			Name:  tag,
+			Path:  "",
+			Type:  artifact.TypeDockerImage,
+			Extra: extra,
+		}); err != nil {
+			return err
+		}
 	}
 
 	log.Info("Docker image built successfully", "tags", imageTags)
@@ -141,49 +140,250 @@ func (b *Builder) Build(ctx context.Context) error {
 
 // Push pushes Docker images.
 func (b *Builder) Push(ctx context.Context) error {
+	imageTags, err := b.pushTags()
+	if err != nil {
+		return err
+	}
+	if len(imageTags) == 0 {
+		return nil
+	}
+	if err := b.loginFor(ctx, imageTags); err != nil {
+		return err
+	}
+
+	for _, tag := range imageTags {
+		if err := b.pushTag(ctx, imageTags[0], tag); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// pushTags returns the image tags to push, none when pushing is skipped
+func (b *Builder) pushTags() ([]string, error) {
 	if b.config.Skip == "true" {
 		log.Info("Skipping Docker push")
-		return nil
+		return nil, nil
 	}
 
-	if !b.config.Push {
+	if !b.shouldPush() {
 		log.Debug("Docker push not enabled")
-		return nil
+		return nil, nil
 	}
 
 	// If using buildx with --push, images are already pushed
-	if b.config.Buildx {
+	if b.cli == CLIDocker && b.useBuildx() {
 		log.Debug("Images already pushed via buildx")
-		return nil
+		return nil, nil
 	}
 
 	log.Info("Pushing Docker images")
 
 	imageTags, err := b.prepareTags()
 	if err != nil {
-		return fmt.Errorf("failed to prepare tags: %w", err)
+		return nil, fmt.Errorf("failed to prepare tags: %w", err)
 	}
+	return imageTags, nil
+}
 
-	for _, tag := range imageTags {
-		cmd := exec.CommandContext(ctx, "docker", "push", tag)
-		cmd.Stdout = os.Stdout
-		cmd.Stderr = os.Stderr
+// pushArgs returns the arguments to push an image tag. Multi-platform podman
+// builds produce a manifest list named after the first tag which is pushed
+// under every tag.
+func (b *Builder) pushArgs(first, tag string) []string {
+	switch {
+	case b.cli == CLIPodman && len(b.platforms()) > 1:
+		return []string{"manifest", "push", "--all", first, "docker://" + tag}
+	case b.cli == CLINerdctl && len(b.platforms()) > 1:
+		return []string{"push", "--all-platforms", tag}
+	default:
+		return []string{"push", tag}
+	}
+}
 
-		if err := cmd.Run(); err != nil {
-			return fmt.Errorf("failed to push %s: %w", tag, err)
+// cliStopTimeout is how long an interrupted container CLI gets to stop
+// before it is killed
+const cliStopTimeout = 10 * time.Second
+
+// cliCommand returns a container CLI command that is interrupted rather
+// than killed when ctx is cancelled. The CLI then cancels the build on the
+// daemon, which removes its containers instead of leaving them orphaned.
+func cliCommand(ctx context.Context, cli string, args ...string) *exec.Cmd {
+	cmd := exec.CommandContext(ctx, cli, args...)
+	cmd.Cancel = func() error {
+		if err := cmd.Process.Signal(os.Interrupt); err != nil {
+			return cmd.Process.Kill()
 		}
-		log.Info("Pushed Docker image", "tag", tag)
+		return nil
 	}
+	cmd.WaitDelay = cliStopTimeout
+	return cmd
+}
 
-	return nil
+// runCLI runs a container CLI command with output attached to the terminal
+func runCLI(ctx context.Context, cli string, args ...string) error {
+	log.Debug("Running container command", "cli", cli, "args", redact.Args(args))
+	cmd := cliCommand(ctx, cli, args...)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	return cmd.Run()
+}
+
+// useBuildx reports whether the image is built with docker buildx
+func (b *Builder) useBuildx() bool {
+	return b.config.Buildx || b.config.Use == "buildx" || len(b.config.Platforms) > 0
+}
+
+// platforms returns the target platforms of a buildx build
+func (b *Builder) platforms() []string {
+	if len(b.config.Platforms) > 0 {
+		return b.config.Platforms
+	}
+	return b.config.BuildxPlatforms
+}
+
+// shouldPush reports whether built images are pushed; snapshots are never pushed
+func (b *Builder) shouldPush() bool {
+	if b.tmplCtx.GetValue("IsSnapshot") == true {
+		return false
+	}
+	return b.config.Push && b.config.SkipPush != "true"
+}
+
+// loaded reports whether the built image is kept in the local image store;
+// only buildx with --push leaves it in the build cache
+func (b *Builder) loaded() bool {
+	return b.cli != CLIDocker || !b.useBuildx() || !b.shouldPush()
+}
+
+// buildArgs returns the docker command line arguments for building the image
+func (b *Builder) buildArgs(dockerfile string, imageTags []string, buildContext string) ([]string, error) {
+	var args []string
+
+	switch {
+	case b.cli != CLIDocker:
+		// podman and nerdctl build multi-platform images natively and
+		// store the result locally, so there is no buildx or --load
+		args = append(args, "build")
+		if platforms := b.platforms(); len(platforms) > 0 {
+			args = append(args, "--platform", strings.Join(platforms, ","))
+		}
+	case b.useBuildx():
+		args = append(args, "buildx", "build")
+		if platforms := b.platforms(); len(platforms) > 0 {
+			args = append(args, "--platform", strings.Join(platforms, ","))
+		}
+		if b.shouldPush() {
+			args = append(args, "--push")
+		} else {
+			if len(b.platforms()) > 1 {
+				log.Warn("Loading a multi-platform image requires the containerd image store", "platforms", b.platforms())
+			}
+			args = append(args, "--load")
+		}
+	default:
+		// The classic builder keeps the containers of failed and
+		// interrupted steps unless told otherwise
+		args = append(args, "build", "--force-rm")
+	}
+
+	// Add tags; podman collects multi-platform images in a manifest list
+	if b.cli == CLIPodman && len(b.platforms()) > 1 {
+		args = append(args, "--manifest", imageTags[0])
+	} else {
+		for _, tag := range imageTags {
+			args = append(args, "-t", tag)
+		}
+	}
+
+	// Add Dockerfile
+	args = append(args, "-f", dockerfile)
+
+	// Add build args
+	for i, arg := range b.config.BuildArgs {
+		expandedArg, err := b.tmplCtx.Apply(fmt.Sprintf("dockers.build_args[%d]", i), arg)
+		if err != nil {
+			return nil, fmt.Errorf("failed to apply template to build arg %s: %w", arg, err)
+		}
+		args = append(args, "--build-arg", expandedArg)
+	}
+
+	// Add labels, user labels override the OCI defaults
+	labels, err := b.labels()
+	if err != nil {
+		return nil, err
+	}
+	keys := make([]string, 0, len(labels))
+	for key := range labels {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		args = append(args, "--label", key+"="+labels[key])
+	}
+
+	// Secrets and SSH forwarding require BuildKit
+	for i, secret := range b.config.Secrets {
+		expanded, err := b.tmplCtx.Apply(fmt.Sprintf("dockers.secrets[%d]", i), secret)
+		if err != nil {
+			return nil, fmt.Errorf("failed to apply template to secret: %w", err)
+		}
+		args = append(args, "--secret", expanded)
+	}
+	for i, ssh := range b.config.SSH {
+		expanded, err := b.tmplCtx.Apply(fmt.Sprintf("dockers.ssh[%d]", i), ssh)
+		if err != nil {
+			return nil, fmt.Errorf("failed to apply template to ssh: %w", err)
+		}
+		args = append(args, "--ssh", expanded)
+	}
+
+	// Add extra build flags
+	for i, flag := range b.config.BuildFlagTemplates {
+		expanded, err := b.tmplCtx.Apply(fmt.Sprintf("dockers.build_flag_templates[%d]", i), flag)
+		if err != nil {
+			return nil, fmt.Errorf("failed to apply template to build flag %s: %w", flag, err)
+		}
+		args = append(args, expanded)
+	}
+
+	return append(args, buildContext), nil
+}
+
+// labels returns the image labels: OCI annotations derived from the release
+// plus the configured labels
+func (b *Builder) labels() (map[string]string, error) {
+	labels := map[string]string{
+		"org.opencontainers.image.version": b.tmplCtx.Get("Version"),
+		"org.opencontainers.image.created": b.tmplCtx.Get("Date"),
+	}
+	if revision := b.tmplCtx.Get("FullCommit"); revision != "" {
+		labels["org.opencontainers.image.revision"] = revision
+	}
+	if title := b.tmplCtx.Get("ProjectName"); title != "" {
+		labels["org.opencontainers.image.title"] = title
+	}
+	if source := b.tmplCtx.Get("GitURL"); source != "" {
+		labels["org.opencontainers.image.source"] = source
+	}
+
+	for key, value := range b.config.Labels {
+		expanded, err := b.tmplCtx.Apply("dockers.labels."+key, value)
+		if err != nil {
+			return nil, fmt.Errorf("failed to apply template to label %s: %w", key, err)
+		}
+		labels[key] = expanded
+	}
+
+	return labels, nil
 }
 
 // prepareTags prepares image tags with template expansion.
 func (b *Builder) prepareTags() ([]string, error) {
 	var tags []string
 
-	for _, tagTemplate := range b.config.ImageTemplates {
-		tag, err := b.tmplCtx.Apply(tagTemplate)
+	for i, tagTemplate := range b.config.ImageTemplates {
+		tag, err := b.tmplCtx.Apply(fmt.Sprintf("dockers.image_templates[%d]", i), tagTemplate)
 		if err != nil {
 			return nil, fmt.Errorf("failed to apply template to tag: %w", err)
 		}
@@ -193,33 +393,35 @@ func (b *Builder) prepareTags() ([]string, error) {
 	return tags, nil
 }
 
-// copyFile copies a file from src to dst.
-func copyFile(src, dst string) error {
-	data, err := os.ReadFile(src)
-	if err != nil {
-		return err
-	}
-	return os.WriteFile(dst, data, 0644)
-}
-
 // MultiBuilder builds multiple Docker configurations.
 type MultiBuilder struct {
 	configs []config.Docker
 	tmplCtx *tmpl.Context
 	manager *artifact.Manager
 	distDir string
+
+	parallelism int
 }
 
 // NewMultiBuilder creates a multi-config Docker builder.
 func NewMultiBuilder(configs []config.Docker, tmplCtx *tmpl.Context, manager *artifact.Manager, distDir string) *MultiBuilder {
 	return &MultiBuilder{
-		configs: configs,
-		tmplCtx: tmplCtx,
-		manager: manager,
-		distDir: distDir,
+		configs:     configs,
+		tmplCtx:     tmplCtx,
+		manager:     manager,
+		distDir:     distDir,
+		parallelism: 1,
 	}
 }
 
+// WithParallelism bounds the number of images pushed at once
+func (m *MultiBuilder) WithParallelism(n int) *MultiBuilder {
+	if n > 0 {
+		m.parallelism = n
+	}
+	return m
+}
+
 // BuildAll builds all Docker configurations.
 func (m *MultiBuilder) BuildAll(ctx context.Context) error {
 	for i, cfg := range m.configs {
@@ -232,13 +434,69 @@ func (m *MultiBuilder) BuildAll(ctx context.Context) error {
 	return nil
 }
 
-// PushAll pushes all Docker images.
+// PushAll pushes the images of all Docker configurations, at most
+// parallelism at a time. Every image is pushed even when another failed;
+// the errors are joined and the images pushed are reported.
 func (m *MultiBuilder) PushAll(ctx context.Context) error {
+	type image struct {
+		builder *Builder
+		first   string
+		tag     string
+	}
+	var images []image
 	for _, cfg := range m.configs {
 		builder := NewBuilder(cfg, m.tmplCtx, m.manager, m.distDir)
-		if err := builder.Push(ctx); err != nil {
+		imageTags, err := builder.pushTags()
+		if err != nil {
 			return err
 		}
+		if len(imageTags) == 0 {
+			continue
+		}
+		if err := builder.loginFor(ctx, imageTags); err != nil {
+			return err
+		}
+		for _, tag := range imageTags {
+			images = append(images, image{builder: builder, first: imageTags[0], tag: tag})
+		}
+	}
+
+	sem := make(chan struct{}, m.parallelism)
+	errCh := make(chan error, len(images))
+	var pushed atomic.Int32
+	var wg sync.WaitGroup
+
+	for _, img := range images {
+		wg.Add(1)
+		go func(img image) {
+			defer wg.Done()
+
+			select {
+			case sem <- struct{}{}:
+			case <-ctx.Done():
+				errCh <- fmt.Errorf("failed to push %s: %w", img.tag, ctx.Err())
+				return
+			}
+			defer func() { <-sem }()
+
+			if err := img.builder.pushTag(ctx, img.first, img.tag); err != nil {
+				errCh <- err
+				return
+			}
+			pushed.Add(1)
+		}(img)
+	}
+
+	wg.Wait()
+	close(errCh)
+
+	var errs []error
+	for err := range errCh {
+		errs = append(errs, err)
+	}
+	if len(errs) > 0 {
+		log.Error("Some Docker images were not pushed", "pushed", pushed.Load(), "failed", len(errs), "total", len(images))
+		return fmt.Errorf("%d of %d Docker images failed to push: %w", len(errs), len(images), errors.Join(errs...))
 	}
 	return nil
 }
@@ -284,8 +542,8 @@ func (s *DockerSigner) signWithConfig(ctx context.Context, cfg config.DockerSign
 
 	if len(cfg.Images) > 0 {
 		// Use explicitly specified images
-		for _, img := range cfg.Images {
-			expanded, err := s.tmplCtx.Apply(img)
+		for i, img := range cfg.Images {
+			expanded, err := s.tmplCtx.Apply(fmt.Sprintf("docker_signs.images[%d]", i), img)
 			if err != nil {
 				return fmt.Errorf("failed to expand image template: %w", err)
 			}
@@ -379,8 +637,8 @@ func (s *DockerSigner) signWithCosign(ctx context.Context, cfg config.DockerSign
 
 		// Prepare environment
 		env := os.Environ()
-		for _, e := range cfg.Env {
-			expanded, _ := s.tmplCtx.Apply(e)
+		for i, e := range cfg.Env {
+			expanded, _ := s.tmplCtx.Apply(fmt.Sprintf("docker_signs.env[%d]", i), e)
 			env = append(env, expanded)
 		}
 
@@ -408,14 +666,14 @@ func (s *DockerSigner) signWithCustomCmd(ctx context.Context, cfg config.DockerS
 		args := make([]string, len(cfg.Args))
 		for i, arg := range cfg.Args {
 			expanded := strings.ReplaceAll(arg, "${image}", image)
-			expanded, _ = s.tmplCtx.Apply(expanded)
+			expanded, _ = s.tmplCtx.Apply(fmt.Sprintf("docker_signs.args[%d]", i), expanded)
 			args[i] = expanded
 		}
 
 		// Prepare environment
 		env := os.Environ()
-		for _, e := range cfg.Env {
-			expanded, _ := s.tmplCtx.Apply(e)
+		for i, e := range cfg.Env {
+			expanded, _ := s.tmplCtx.Apply(fmt.Sprintf("docker_signs.env[%d]", i), e)
 			env = append(env, expanded)
 		}
 
@@ -0,0 +1,166 @@
+package docker
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/oarkflow/releaser/internal/config"
+	"github.com/oarkflow/releaser/internal/git"
+	"github.com/oarkflow/releaser/internal/tmpl"
+)
+
+// releaseContext returns the template context of a v1.2.3 release, or of a
+// snapshot, with a fixed version and date so labels are stable
+func releaseContext(snapshot bool) *tmpl.Context {
+	ctx := tmpl.New(&config.Config{ProjectName: "demo"}, &git.Info{
+		CurrentTag:  "v1.2.3",
+		Commit:      "0123456789abcdef0123456789abcdef01234567",
+		ShortCommit: "0123456",
+		URL:         "https://github.com/oarkflow/demo",
+	}, snapshot, false)
+	ctx.Set("Version", "1.2.3")
+	ctx.Set("Date", "2026-01-02T03:04:05Z")
+	return ctx
+}
+
+// ociLabels are the labels every build of releaseContext gets
+var ociLabels = []string{
+	"--label", "org.opencontainers.image.created=2026-01-02T03:04:05Z",
+	"--label", "org.opencontainers.image.revision=0123456789abcdef0123456789abcdef01234567",
+	"--label", "org.opencontainers.image.source=https://github.com/oarkflow/demo",
+	"--label", "org.opencontainers.image.title=demo",
+	"--label", "org.opencontainers.image.version=1.2.3",
+}
+
+func TestBuildArgs(t *testing.T) {
+	tags := []string{"ghcr.io/oarkflow/demo:1.2.3", "ghcr.io/oarkflow/demo:latest"}
+
+	tests := []struct {
+		name     string
+		cfg      config.Docker
+		snapshot bool
+		want     []string
+	}{
+		{
+			name: "plain",
+			cfg:  config.Docker{CLI: CLIDocker},
+			want: join(
+				[]string{"build", "--force-rm", "-t", tags[0], "-t", tags[1], "-f", "Dockerfile"},
+				ociLabels,
+				[]string{"."},
+			),
+		},
+		{
+			name: "buildx multi-platform push",
+			cfg: config.Docker{
+				CLI:       CLIDocker,
+				Use:       "buildx",
+				Platforms: []string{"linux/amd64", "linux/arm64"},
+				Push:      true,
+			},
+			want: join(
+				[]string{"buildx", "build", "--platform", "linux/amd64,linux/arm64", "--push", "-t", tags[0], "-t", tags[1], "-f", "Dockerfile"},
+				ociLabels,
+				[]string{"."},
+			),
+		},
+		{
+			name: "buildx snapshot loads",
+			cfg: config.Docker{
+				CLI:       CLIDocker,
+				Platforms: []string{"linux/amd64"},
+				Push:      true,
+			},
+			snapshot: true,
+			want: join(
+				[]string{"buildx", "build", "--platform", "linux/amd64", "--load", "-t", tags[0], "-t", tags[1], "-f", "Dockerfile"},
+				ociLabels,
+				[]string{"."},
+			),
+		},
+		{
+			name: "build args, labels, secrets and ssh",
+			cfg: config.Docker{
+				CLI:                CLIDocker,
+				Buildx:             true,
+				BuildArgs:          []string{"VERSION={{ .Version }}", "COMMIT={{ .ShortCommit }}"},
+				Labels:             map[string]string{"org.opencontainers.image.title": "{{ .ProjectName }}-cli", "maintainer": "team"},
+				Secrets:            []string{"id=npmrc,src=.npmrc"},
+				SSH:                []string{"default"},
+				BuildFlagTemplates: []string{"--pull"},
+			},
+			want: []string{
+				"buildx", "build", "--load", "-t", tags[0], "-t", tags[1], "-f", "Dockerfile",
+				"--build-arg", "VERSION=1.2.3", "--build-arg", "COMMIT=0123456",
+				"--label", "maintainer=team",
+				"--label", "org.opencontainers.image.created=2026-01-02T03:04:05Z",
+				"--label", "org.opencontainers.image.revision=0123456789abcdef0123456789abcdef01234567",
+				"--label", "org.opencontainers.image.source=https://github.com/oarkflow/demo",
+				"--label", "org.opencontainers.image.title=demo-cli",
+				"--label", "org.opencontainers.image.version=1.2.3",
+				"--secret", "id=npmrc,src=.npmrc", "--ssh", "default", "--pull",
+				".",
+			},
+		},
+		{
+			name: "podman manifest",
+			cfg: config.Docker{
+				CLI:       CLIPodman,
+				Platforms: []string{"linux/amd64", "linux/arm64"},
+				Push:      true,
+			},
+			want: join(
+				[]string{"build", "--platform", "linux/amd64,linux/arm64", "--manifest", tags[0], "-f", "Dockerfile"},
+				ociLabels,
+				[]string{"."},
+			),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := NewBuilder(tt.cfg, releaseContext(tt.snapshot), nil, t.TempDir())
+			got, err := b.buildArgs("Dockerfile", tags, ".")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if strings.Join(got, " ") != strings.Join(tt.want, " ") {
+				t.Errorf("build command line:\n got: %s\nwant: %s", strings.Join(got, " "), strings.Join(tt.want, " "))
+			}
+		})
+	}
+}
+
+func TestCLICommandInterruptsOnCancel(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("processes cannot be interrupted on Windows")
+	}
+
+	// The CLI cleans up when interrupted, as docker cancels its build
+	marker := filepath.Join(t.TempDir(), "interrupted")
+	ctx, cancel := context.WithCancel(context.Background())
+	cmd := cliCommand(ctx, "sh", "-c", `trap 'touch "$0"; exit 0' INT; while :; do sleep 0.05; done`, marker)
+	if err := cmd.Start(); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(200 * time.Millisecond)
+	cancel()
+	_ = cmd.Wait()
+
+	if _, err := os.Stat(marker); err != nil {
+		t.Errorf("the CLI was not interrupted: %v", err)
+	}
+}
+
+func join(parts ...[]string) []string {
+	var all []string
+	for _, p := range parts {
+		all = append(all, p...)
+	}
+	return all
+}
@@ -1,28 +1,55 @@
 package docker
 
 import (
+	"archive/tar"
 	"compress/gzip"
+	"context"
 	"fmt"
+	"io"
 	"os"
-	"os/exec"
 	"path/filepath"
+	"runtime"
 	"strings"
+	"time"
 
+	"github.com/charmbracelet/log"
+	"github.com/google/go-containerregistry/pkg/authn"
+	"github.com/google/go-containerregistry/pkg/name"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/empty"
+	"github.com/google/go-containerregistry/pkg/v1/layout"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/google/go-containerregistry/pkg/v1/tarball"
+
+	"github.com/oarkflow/releaser/internal/artifact"
 	"github.com/oarkflow/releaser/internal/config"
+	"github.com/oarkflow/releaser/internal/httpclient"
+)
+
+// Export formats. tar and tar.gz use `save` of the container CLI and need a
+// daemon; oci-layout and docker-archive are written directly from a registry,
+// the local image store of the build or a local build output.
+const (
+	ExportFormatTar           = "tar"
+	ExportFormatTarGz         = "tar.gz"
+	ExportFormatOCILayout     = "oci-layout"
+	ExportFormatDockerArchive = "docker-archive"
 )
 
-func ExportAll(exports []config.DockerExportConfig) error {
+// ExportAll exports the configured images and registers the exports as artifacts.
+func ExportAll(ctx context.Context, exports []config.DockerExportConfig, manager *artifact.Manager) error {
 	for _, e := range exports {
-		if err := exportOne(e); err != nil {
+		if err := exportOne(ctx, e, manager); err != nil {
 			return fmt.Errorf("docker export %s: %w", e.ID, err)
 		}
 	}
 	return nil
 }
 
-func exportOne(e config.DockerExportConfig) error {
-	if e.Image == "" {
-		return fmt.Errorf("image is required")
+func exportOne(ctx context.Context, e config.DockerExportConfig, manager *artifact.Manager) error {
+	images := exportImages(e, manager)
+	if len(images) == 0 && e.Input == "" {
+		return fmt.Errorf("image, ids or input is required")
 	}
 	if e.Output == "" {
 		return fmt.Errorf("output is required")
@@ -31,37 +58,468 @@ func exportOne(e config.DockerExportConfig) error {
 	if err := os.MkdirAll(filepath.Dir(e.Output), 0o755); err != nil {
 		return err
 	}
-	fmt.Printf("→ Exporting docker image %s to %s (%s)\n", e.Image, e.Output, format)
+	log.Info("Exporting docker image", "images", refs(images), "output", e.Output, "format", format)
+
+	var err error
+	switch format {
+	case ExportFormatTar, ExportFormatTarGz:
+		err = saveImages(ctx, e, refs(images), format == ExportFormatTarGz)
+	case ExportFormatOCILayout, "oci":
+		err = writeOCILayout(ctx, e, images)
+	case ExportFormatDockerArchive:
+		err = writeDockerArchive(ctx, e, images)
+	default:
+		return fmt.Errorf("unsupported docker export format: %s", format)
+	}
+	if err != nil {
+		return err
+	}
+
+	if err := manager.Add(artifact.Artifact{
+		Name: filepath.Base(e.Output),
+		Path: e.Output,
+		Type: artifact.TypeDockerImageArchive,
+		Extra: map[string]interface{}{
+			"id":     e.ID,
+			"format": format,
+			"images": refs(images),
+		},
+	}); err != nil {
+		return err
+	}
+	return nil
+}
+
+// exportImage is an image to export and where it is read from
+type exportImage struct {
+	Ref string
+	// CLI is the container CLI whose image store holds the image, empty
+	// when the image is only in a registry
+	CLI string
+}
+
+// exportImages returns the images to export: the configured image, fetched
+// from its registry, plus the images built by the docker configs listed in
+// ids, read from the local image store unless buildx pushed them without
+// loading them. With an input, image only names the local image and is not
+// fetched.
+func exportImages(e config.DockerExportConfig, manager *artifact.Manager) []exportImage {
+	var images []exportImage
+	if e.Image != "" && e.Input == "" {
+		images = append(images, exportImage{Ref: e.Image})
+	}
+	if len(e.IDs) == 0 {
+		return images
+	}
+
+	ids := make(map[string]bool, len(e.IDs))
+	for _, id := range e.IDs {
+		ids[id] = true
+	}
+	for _, a := range manager.Filter(artifact.ByType(artifact.TypeDockerImage)) {
+		id, _ := a.Extra["id"].(string)
+		image, _ := a.Extra["image"].(string)
+		if ids[id] && image != "" {
+			cli, _ := a.Extra["local_cli"].(string)
+			images = append(images, exportImage{Ref: image, CLI: cli})
+		}
+	}
+	return images
+}
+
+// refs returns the references of images
+func refs(images []exportImage) []string {
+	names := make([]string, len(images))
+	for i, image := range images {
+		names[i] = image.Ref
+	}
+	return names
+}
+
+// localImage reads an image from the image store of a container CLI with
+// `<cli> save`, so images export before they are pushed, or when they
+// never are
+func localImage(ctx context.Context, cli, image, dir string) (v1.Image, error) {
+	ref, err := name.ParseReference(image)
+	if err != nil {
+		return nil, fmt.Errorf("invalid image reference %s: %w", image, err)
+	}
+	path := filepath.Join(dir, fmt.Sprintf("image-%d.tar", time.Now().UnixNano()))
+	cmd := cliCommand(ctx, cli, "save", "-o", path, image)
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("failed to save %s from %s: %w", image, cli, err)
+	}
+
+	var tag *name.Tag
+	if t, ok := ref.(name.Tag); ok {
+		tag = &t
+	}
+	img, err := tarball.ImageFromPath(path, tag)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s saved by %s: %w", image, cli, err)
+	}
+	return img, nil
+}
+
+// saveImages exports images with `<cli> save`
+func saveImages(ctx context.Context, e config.DockerExportConfig, images []string, compress bool) error {
+	if len(images) == 0 {
+		return fmt.Errorf("image is required for %s exports", formatOrDefault(e.Format))
+	}
+
+	out, err := os.Create(e.Output)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
 
-	cmd := exec.Command("docker", "save", e.Image)
+	cmd := cliCommand(ctx, ResolveCLI(e.CLI), append([]string{"save"}, images...)...)
 	cmd.Stderr = os.Stderr
 
-	switch format {
-	case "tar":
-		out, err := os.Create(e.Output)
+	if !compress {
+		cmd.Stdout = out
+		return cmd.Run()
+	}
+
+	gz := gzip.NewWriter(out)
+	cmd.Stdout = gz
+	if err := cmd.Run(); err != nil {
+		return err
+	}
+	return gz.Close()
+}
+
+// writeOCILayout writes an OCI image layout holding every platform of each
+// image, as a tarball of the layout directory
+func writeOCILayout(ctx context.Context, e config.DockerExportConfig, images []exportImage) error {
+	dir, err := os.MkdirTemp("", "releaser-oci-*")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+
+	path, err := layout.Write(dir, empty.Index)
+	if err != nil {
+		return fmt.Errorf("failed to create OCI layout: %w", err)
+	}
+
+	// Images saved from the local image store stay outside the layout
+	saved, err := os.MkdirTemp("", "releaser-oci-save-*")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(saved)
+
+	if e.Input != "" {
+		if err := appendInput(path, e.Input); err != nil {
+			return err
+		}
+	}
+
+	for _, image := range images {
+		ref, err := name.ParseReference(image.Ref)
+		if err != nil {
+			return fmt.Errorf("invalid image reference %s: %w", image.Ref, err)
+		}
+		annotations := layout.WithAnnotations(map[string]string{
+			"org.opencontainers.image.ref.name": ref.Name(),
+		})
+
+		if image.CLI != "" {
+			img, err := localImage(ctx, image.CLI, image.Ref, saved)
+			if err != nil {
+				return err
+			}
+			if err := path.AppendImage(img, annotations); err != nil {
+				return fmt.Errorf("failed to write %s to OCI layout: %w", image.Ref, err)
+			}
+			continue
+		}
+
+		desc, err := remote.Get(ref, remoteOptions(ctx)...)
+		if err != nil {
+			return fmt.Errorf("failed to fetch %s: %w", image.Ref, err)
+		}
+		if err := appendRemote(path, desc, annotations); err != nil {
+			return fmt.Errorf("failed to write %s to OCI layout: %w", image.Ref, err)
+		}
+	}
+
+	return tarDirectory(dir, e.Output)
+}
+
+// appendRemote adds a fetched image or index to the layout
+func appendRemote(path layout.Path, desc *remote.Descriptor, options ...layout.Option) error {
+	if desc.MediaType.IsIndex() {
+		idx, err := desc.ImageIndex()
 		if err != nil {
 			return err
 		}
-		defer out.Close()
-		cmd.Stdout = out
-	case "tar.gz":
-		out, err := os.Create(e.Output)
+		return path.AppendIndex(idx, options...)
+	}
+	img, err := desc.Image()
+	if err != nil {
+		return err
+	}
+	return path.AppendImage(img, options...)
+}
+
+// appendInput adds a local OCI layout directory or image tarball to the layout
+func appendInput(path layout.Path, input string) error {
+	info, err := os.Stat(input)
+	if err != nil {
+		return fmt.Errorf("failed to read export input: %w", err)
+	}
+
+	if info.IsDir() {
+		idx, err := layout.ImageIndexFromPath(input)
+		if err != nil {
+			return fmt.Errorf("failed to read OCI layout %s: %w", input, err)
+		}
+		manifest, err := idx.IndexManifest()
 		if err != nil {
 			return err
 		}
-		defer out.Close()
-		gz := gzip.NewWriter(out)
-		defer gz.Close()
-		cmd.Stdout = gz
-	default:
-		return fmt.Errorf("unsupported docker export format: %s", format)
+		for _, desc := range manifest.Manifests {
+			if err := path.AppendDescriptor(desc); err != nil {
+				return err
+			}
+			if err := copyLayoutBlobs(path, idx, desc); err != nil {
+				return err
+			}
+		}
+		return nil
 	}
-	return cmd.Run()
+
+	img, err := tarball.ImageFromPath(input, nil)
+	if err != nil {
+		return fmt.Errorf("failed to read image tarball %s: %w", input, err)
+	}
+	return path.AppendImage(img)
+}
+
+// copyLayoutBlobs writes the image or index behind a descriptor into the layout
+func copyLayoutBlobs(dst layout.Path, idx v1.ImageIndex, desc v1.Descriptor) error {
+	if desc.MediaType.IsIndex() {
+		child, err := idx.ImageIndex(desc.Digest)
+		if err != nil {
+			return err
+		}
+		return dst.WriteIndex(child)
+	}
+	img, err := idx.Image(desc.Digest)
+	if err != nil {
+		return err
+	}
+	return dst.WriteImage(img)
+}
+
+// writeDockerArchive writes a `docker load` compatible tarball with a single
+// platform of each image
+func writeDockerArchive(ctx context.Context, e config.DockerExportConfig, images []exportImage) error {
+	platform, err := exportPlatform(e.Platform)
+	if err != nil {
+		return err
+	}
+
+	dir, err := os.MkdirTemp("", "releaser-docker-archive-*")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+
+	refs := make(map[name.Reference]v1.Image)
+	for _, image := range images {
+		ref, err := name.ParseReference(image.Ref)
+		if err != nil {
+			return fmt.Errorf("invalid image reference %s: %w", image.Ref, err)
+		}
+		// The local image store holds the platform the image was loaded for
+		var img v1.Image
+		if image.CLI != "" {
+			img, err = localImage(ctx, image.CLI, image.Ref, dir)
+		} else if img, err = remote.Image(ref, append(remoteOptions(ctx), remote.WithPlatform(*platform))...); err != nil {
+			err = fmt.Errorf("failed to fetch %s: %w", image.Ref, err)
+		}
+		if err != nil {
+			return err
+		}
+		refs[ref] = img
+	}
+
+	if e.Input != "" {
+		img, err := inputImage(e.Input, platform)
+		if err != nil {
+			return err
+		}
+		tag := e.Image
+		if tag == "" {
+			tag = "releaser/" + e.ID + ":latest"
+		}
+		ref, err := name.ParseReference(tag)
+		if err != nil {
+			return fmt.Errorf("invalid image reference %s: %w", tag, err)
+		}
+		refs[ref] = img
+	}
+
+	out, err := os.Create(e.Output)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+
+	var w io.Writer = out
+	var gz *gzip.Writer
+	if isGzip(e.Output) {
+		gz = gzip.NewWriter(out)
+		w = gz
+	}
+
+	if err := tarball.MultiRefWrite(refs, w); err != nil {
+		return fmt.Errorf("failed to write docker archive: %w", err)
+	}
+	if gz != nil {
+		return gz.Close()
+	}
+	return nil
+}
+
+// inputImage reads the image for a platform from a local OCI layout or tarball
+func inputImage(input string, platform *v1.Platform) (v1.Image, error) {
+	info, err := os.Stat(input)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read export input: %w", err)
+	}
+	if !info.IsDir() {
+		return tarball.ImageFromPath(input, nil)
+	}
+
+	idx, err := layout.ImageIndexFromPath(input)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read OCI layout %s: %w", input, err)
+	}
+	return platformImage(idx, platform)
+}
+
+// platformImage finds the image for a platform in an index, descending into
+// nested indexes
+func platformImage(idx v1.ImageIndex, platform *v1.Platform) (v1.Image, error) {
+	manifest, err := idx.IndexManifest()
+	if err != nil {
+		return nil, err
+	}
+	for _, desc := range manifest.Manifests {
+		if desc.MediaType.IsIndex() {
+			child, err := idx.ImageIndex(desc.Digest)
+			if err != nil {
+				return nil, err
+			}
+			if img, err := platformImage(child, platform); err == nil {
+				return img, nil
+			}
+			continue
+		}
+		if desc.Platform == nil || desc.Platform.Satisfies(*platform) {
+			return idx.Image(desc.Digest)
+		}
+	}
+	return nil, fmt.Errorf("no image for platform %s", platform)
+}
+
+// exportPlatform parses the export platform, defaulting to linux on the host architecture
+func exportPlatform(platform string) (*v1.Platform, error) {
+	if platform == "" {
+		platform = "linux/" + runtime.GOARCH
+	}
+	p, err := v1.ParsePlatform(platform)
+	if err != nil {
+		return nil, fmt.Errorf("invalid platform %s: %w", platform, err)
+	}
+	return p, nil
+}
+
+// remoteOptions authenticates with the docker credential configuration
+// and connects through the shared transport, so registry requests honor
+// the proxy and http.ca_bundle settings
+func remoteOptions(ctx context.Context) []remote.Option {
+	return []remote.Option{
+		remote.WithContext(ctx),
+		remote.WithAuthFromKeychain(authn.DefaultKeychain),
+		remote.WithTransport(httpclient.Client().Transport),
+	}
+}
+
+// tarDirectory writes the contents of dir as a tarball, gzipped when the
+// output ends in .gz or .tgz
+func tarDirectory(dir, output string) error {
+	out, err := os.Create(output)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+
+	var w io.Writer = out
+	var gz *gzip.Writer
+	if isGzip(output) {
+		gz = gzip.NewWriter(out)
+		w = gz
+	}
+
+	tw := tar.NewWriter(w)
+	err = filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil || rel == "." {
+			return err
+		}
+
+		header, err := tar.FileInfoHeader(info, "")
+		if err != nil {
+			return err
+		}
+		header.Name = filepath.ToSlash(rel)
+		if info.IsDir() {
+			header.Name += "/"
+		}
+		if err := tw.WriteHeader(header); err != nil {
+			return err
+		}
+		if info.IsDir() {
+			return nil
+		}
+
+		f, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		_, err = io.Copy(tw, f)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	if gz != nil {
+		return gz.Close()
+	}
+	return nil
+}
+
+func isGzip(path string) bool {
+	return strings.HasSuffix(path, ".gz") || strings.HasSuffix(path, ".tgz")
 }
 
 func formatOrDefault(f string) string {
 	if f == "" {
-		return "tar"
+		return ExportFormatTar
 	}
 	return strings.ToLower(f)
 }
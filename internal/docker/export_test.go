@@ -0,0 +1,137 @@
+package docker
+
+import (
+	"context"
+	"encoding/pem"
+	"io"
+	"log"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/registry"
+	"github.com/google/go-containerregistry/pkg/v1/layout"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/google/go-containerregistry/pkg/v1/tarball"
+
+	"github.com/oarkflow/releaser/internal/artifact"
+	"github.com/oarkflow/releaser/internal/config"
+	"github.com/oarkflow/releaser/internal/httpclient"
+)
+
+func TestRemoteOptionsTrustCABundle(t *testing.T) {
+	srv := httptest.NewTLSServer(registry.New(registry.Logger(log.New(io.Discard, "", 0))))
+	t.Cleanup(srv.Close)
+
+	ref, err := name.ParseReference(strings.TrimPrefix(srv.URL, "https://") + "/demo:1.2.3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	img, err := random.Image(64, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := remote.Write(ref, img, remote.WithTransport(srv.Client().Transport)); err != nil {
+		t.Fatal(err)
+	}
+
+	// The registry's certificate is only trusted through http.ca_bundle
+	bundle := filepath.Join(t.TempDir(), "ca.pem")
+	cert := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: srv.Certificate().Raw})
+	if err := os.WriteFile(bundle, cert, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := httpclient.Configure(httpclient.Options{CABundle: bundle}); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = httpclient.Configure(httpclient.Options{}) })
+
+	if _, err := remote.Get(ref, remoteOptions(context.Background())...); err != nil {
+		t.Fatalf("fetching from a registry signed by the CA bundle: %v", err)
+	}
+}
+
+func TestExportBuiltImageWithoutRegistry(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the fake CLI is a shell script")
+	}
+
+	// The image is only in the local store of the CLI that built it; its
+	// registry does not exist
+	const image = "registry.invalid/demo:1.2.3"
+	dir := t.TempDir()
+	tag, err := name.NewTag(image)
+	if err != nil {
+		t.Fatal(err)
+	}
+	img, err := random.Image(64, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	saved := filepath.Join(dir, "saved.tar")
+	if err := tarball.WriteToFile(saved, tag, img); err != nil {
+		t.Fatal(err)
+	}
+	cli := filepath.Join(dir, "fake-docker")
+	script := "#!/bin/sh\n[ \"$1 $2 $4\" = \"save -o " + image + "\" ] || exit 1\ncp " + saved + " \"$3\"\n"
+	if err := os.WriteFile(cli, []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	manager := artifact.NewManager()
+	if err := manager.Add(artifact.Artifact{
+		Name:  image,
+		Type:  artifact.TypeDockerImage,
+		Extra: map[string]interface{}{"image": image, "id": "api", "local_cli": cli},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	want, err := img.Digest()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("docker-archive", func(t *testing.T) {
+		output := filepath.Join(t.TempDir(), "demo.tar")
+		e := config.DockerExportConfig{ID: "archive", IDs: []string{"api"}, Output: output, Format: ExportFormatDockerArchive}
+		if err := exportOne(context.Background(), e, manager); err != nil {
+			t.Fatal(err)
+		}
+		got, err := tarball.ImageFromPath(output, &tag)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if digest, err := got.Digest(); err != nil || digest != want {
+			t.Errorf("exported digest = %v (%v), want %v", digest, err, want)
+		}
+	})
+
+	t.Run("oci-layout", func(t *testing.T) {
+		output := filepath.Join(t.TempDir(), "demo.tar")
+		e := config.DockerExportConfig{ID: "layout", IDs: []string{"api"}, Output: output, Format: ExportFormatOCILayout}
+		if err := exportOne(context.Background(), e, manager); err != nil {
+			t.Fatal(err)
+		}
+		extracted := t.TempDir()
+		if out, err := exec.Command("tar", "-xf", output, "-C", extracted).CombinedOutput(); err != nil {
+			t.Fatalf("tar: %v: %s", err, out)
+		}
+		index, err := layout.ImageIndexFromPath(extracted)
+		if err != nil {
+			t.Fatal(err)
+		}
+		m, err := index.IndexManifest()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(m.Manifests) != 1 || m.Manifests[0].Digest != want {
+			t.Errorf("layout manifests = %+v, want %v", m.Manifests, want)
+		}
+	})
+}
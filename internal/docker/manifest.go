@@ -0,0 +1,102 @@
+package docker
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/charmbracelet/log"
+	"github.com/oarkflow/releaser/internal/artifact"
+	"github.com/oarkflow/releaser/internal/config"
+	"github.com/oarkflow/releaser/internal/tmpl"
+)
+
+// ManifestBuilder creates and pushes multi-arch manifest lists.
+type ManifestBuilder struct {
+	config  config.DockerManifest
+	tmplCtx *tmpl.Context
+	manager *artifact.Manager
+	cli     string
+}
+
+// NewManifestBuilder creates a new manifest builder.
+func NewManifestBuilder(cfg config.DockerManifest, tmplCtx *tmpl.Context, manager *artifact.Manager) *ManifestBuilder {
+	cli := cfg.CLI
+	if cli == "" {
+		cli = cfg.Use
+	}
+	return &ManifestBuilder{
+		config:  cfg,
+		tmplCtx: tmplCtx,
+		manager: manager,
+		cli:     ResolveCLI(cli),
+	}
+}
+
+// Run creates the manifest list from the image templates and pushes it.
+func (m *ManifestBuilder) Run(ctx context.Context) error {
+	if m.config.SkipPush == "true" || m.tmplCtx.GetValue("IsSnapshot") == true {
+		log.Debug("Skipping docker manifest", "id", m.config.ID)
+		return nil
+	}
+
+	name, err := m.tmplCtx.Apply("docker_manifests.name_template", m.config.NameTemplate)
+	if err != nil {
+		return fmt.Errorf("failed to apply template to manifest name: %w", err)
+	}
+	if name == "" {
+		return fmt.Errorf("manifest name_template is required")
+	}
+
+	var images []string
+	for i, image := range m.config.ImageTemplates {
+		expanded, err := m.tmplCtx.Apply(fmt.Sprintf("docker_manifests.image_templates[%d]", i), image)
+		if err != nil {
+			return fmt.Errorf("failed to apply template to manifest image: %w", err)
+		}
+		images = append(images, expanded)
+	}
+	if len(images) == 0 {
+		return fmt.Errorf("manifest %s has no image_templates", name)
+	}
+
+	log.Info("Creating docker manifest", "name", name, "images", len(images), "cli", m.cli)
+
+	if m.cli == CLIPodman {
+		// podman creates an empty list and adds each image
+		if err := runCLI(ctx, m.cli, append([]string{"manifest", "create", name}, m.config.CreateFlags...)...); err != nil {
+			return fmt.Errorf("failed to create manifest %s: %w", name, err)
+		}
+		for _, image := range images {
+			if err := runCLI(ctx, m.cli, "manifest", "add", name, "docker://"+image); err != nil {
+				return fmt.Errorf("failed to add %s to manifest %s: %w", image, name, err)
+			}
+		}
+		args := append([]string{"manifest", "push", "--all"}, m.config.PushFlags...)
+		if err := runCLI(ctx, m.cli, append(args, name, "docker://"+name)...); err != nil {
+			return fmt.Errorf("failed to push manifest %s: %w", name, err)
+		}
+	} else {
+		args := append([]string{"manifest", "create", name}, m.config.CreateFlags...)
+		if err := runCLI(ctx, m.cli, append(args, images...)...); err != nil {
+			return fmt.Errorf("failed to create manifest %s: %w", name, err)
+		}
+		args = append([]string{"manifest", "push"}, m.config.PushFlags...)
+		if err := runCLI(ctx, m.cli, append(args, name)...); err != nil {
+			return fmt.Errorf("failed to push manifest %s: %w", name, err)
+		}
+	}
+
+	if err := m.manager.Add(artifact.Artifact{
+		Name: name,
+		Type: artifact.TypeDockerManifest,
+		Extra: map[string]interface{}{
+			"image":  name,
+			"images": images,
+		},
+	}); err != nil {
+		return err
+	}
+
+	log.Info("Docker manifest pushed", "name", name)
+	return nil
+}
@@ -0,0 +1,215 @@
+package docker
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/charmbracelet/log"
+	"github.com/oarkflow/releaser/internal/artifact"
+	"github.com/oarkflow/releaser/internal/hook"
+	"github.com/oarkflow/releaser/internal/redact"
+	"github.com/oarkflow/releaser/internal/retry"
+)
+
+// loginMu serializes registry logins, which all write the credentials file
+// of the container CLI
+var loginMu sync.Mutex
+
+// authErrors are messages of registries refusing a push for its credentials
+var authErrors = []string{
+	"unauthorized",
+	"authentication required",
+	"denied",
+	"forbidden",
+	"no basic auth credentials",
+	"token has expired",
+}
+
+// pushTag pushes one image tag, retrying with backoff. A push refused for
+// its credentials logs in again before the next attempt. Tags already
+// pushed, by this run or an earlier one, are skipped.
+func (b *Builder) pushTag(ctx context.Context, first, tag string) error {
+	if b.pushed(tag) {
+		log.Info("Docker image already pushed", "tag", tag)
+		return nil
+	}
+
+	opts, err := b.retryOptions("push " + tag)
+	if err != nil {
+		return err
+	}
+
+	relogin := false
+	err = retry.Do(ctx, opts, func(int) error {
+		if relogin {
+			if err := b.login(ctx); err != nil {
+				return err
+			}
+			relogin = false
+		}
+
+		var stderr bytes.Buffer
+		err := runCLIOutput(ctx, b.cli, &stderr, b.pushArgs(first, tag)...)
+		if err == nil || !isAuthError(stderr.String()) {
+			return err
+		}
+		if !b.canLogin() {
+			// Without a login the same credentials would be refused again
+			return retry.Permanent(fmt.Errorf("%w: registry refused the credentials, log in or configure dockers.login", err))
+		}
+		relogin = true
+		return fmt.Errorf("%w: registry refused the credentials", err)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to push %s: %w", tag, err)
+	}
+
+	b.markPushed(tag)
+	log.Info("Pushed Docker image", "tag", tag)
+	return nil
+}
+
+// retryOptions returns the retry policy of pushes: dockers.retry on top of
+// the http retry policy
+func (b *Builder) retryOptions(name string) (retry.Options, error) {
+	opts := retry.DefaultOptions(name)
+	if b.config.Retry.Attempts > 0 {
+		opts.Attempts = b.config.Retry.Attempts
+	}
+	for _, d := range []struct {
+		name  string
+		value string
+		out   *time.Duration
+	}{
+		{"dockers.retry.initial_delay", b.config.Retry.InitialDelay, &opts.InitialDelay},
+		{"dockers.retry.max_delay", b.config.Retry.MaxDelay, &opts.MaxDelay},
+	} {
+		if d.value == "" {
+			continue
+		}
+		parsed, err := time.ParseDuration(d.value)
+		if err != nil {
+			return opts, fmt.Errorf("invalid %s %q: %w", d.name, d.value, err)
+		}
+		*d.out = parsed
+	}
+	return opts, nil
+}
+
+// canLogin reports whether a registry login is configured
+func (b *Builder) canLogin() bool {
+	l := b.config.Login
+	return l.Cmd != "" || l.UsernameEnv != "" || l.PasswordEnv != ""
+}
+
+// login signs in to the registry with the configured command or
+// credentials; without a login it does nothing
+func (b *Builder) login(ctx context.Context) error {
+	if !b.canLogin() {
+		return nil
+	}
+	l := b.config.Login
+
+	loginMu.Lock()
+	defer loginMu.Unlock()
+
+	if l.Cmd != "" {
+		cmdline, err := b.tmplCtx.Apply("dockers.login.cmd", l.Cmd)
+		if err != nil {
+			return fmt.Errorf("failed to apply template to login command: %w", err)
+		}
+		args := hook.ShellCommand("", cmdline)
+		log.Debug("Logging in to registry", "cmd", redact.String(cmdline))
+		cmd := exec.CommandContext(ctx, args[0], args[1:]...)
+		cmd.Stdout = os.Stdout
+		cmd.Stderr = os.Stderr
+		if err := cmd.Run(); err != nil {
+			return fmt.Errorf("registry login command failed: %w", err)
+		}
+		return nil
+	}
+
+	registry, err := b.tmplCtx.Apply("dockers.login.registry", l.Registry)
+	if err != nil {
+		return fmt.Errorf("failed to apply template to login registry: %w", err)
+	}
+	username, password := os.Getenv(l.UsernameEnv), os.Getenv(l.PasswordEnv)
+	if username == "" || password == "" {
+		return fmt.Errorf("registry login needs %s and %s set", l.UsernameEnv, l.PasswordEnv)
+	}
+
+	args := []string{"login", "--username", username, "--password-stdin"}
+	if registry != "" {
+		args = append(args, registry)
+	}
+	log.Info("Logging in to registry", "registry", registry)
+	cmd := exec.CommandContext(ctx, b.cli, args...)
+	cmd.Stdin = strings.NewReader(password)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("%s login failed: %w", b.cli, err)
+	}
+	return nil
+}
+
+// loginFor logs in before pushing tags, unless all were pushed already
+func (b *Builder) loginFor(ctx context.Context, tags []string) error {
+	for _, tag := range tags {
+		if !b.pushed(tag) {
+			return b.login(ctx)
+		}
+	}
+	return nil
+}
+
+// pushed reports whether a tag was pushed already
+func (b *Builder) pushed(tag string) bool {
+	for _, a := range b.manager.Filter(artifact.ByType(artifact.TypeDockerImage)) {
+		if a.Name == tag && a.Extra[artifact.ExtraPushed] == true {
+			return true
+		}
+	}
+	return false
+}
+
+// markPushed records on the image artifact of a tag that it was pushed
+func (b *Builder) markPushed(tag string) {
+	b.manager.Update(func(a artifact.Artifact) bool {
+		return a.Type == artifact.TypeDockerImage && a.Name == tag
+	}, func(a *artifact.Artifact) {
+		if a.Extra == nil {
+			a.Extra = make(map[string]interface{})
+		}
+		a.Extra[artifact.ExtraPushed] = true
+	})
+}
+
+// isAuthError reports whether the output of a failed push says the
+// registry refused its credentials
+func isAuthError(output string) bool {
+	output = strings.ToLower(output)
+	for _, msg := range authErrors {
+		if strings.Contains(output, msg) {
+			return true
+		}
+	}
+	return false
+}
+
+// runCLIOutput runs a container CLI command like runCLI, also copying its
+// error output to stderr
+func runCLIOutput(ctx context.Context, cli string, stderr io.Writer, args ...string) error {
+	log.Debug("Running container command", "cli", cli, "args", redact.Args(args))
+	cmd := cliCommand(ctx, cli, args...)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = io.MultiWriter(os.Stderr, stderr)
+	return cmd.Run()
+}
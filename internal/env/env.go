@@ -0,0 +1,141 @@
+/*
+Package env resolves the global environment shared by every process the
+pipeline spawns.
+
+Values are exported into the releaser process itself, so builders, hooks,
+docker and publishers inherit them without further plumbing. The resulting
+precedence is process env < env_files < config env < build-specific env,
+since build-specific variables are appended to os.Environ() when a command
+is started.
+*/
+package env
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/oarkflow/releaser/internal/redact"
+	"github.com/oarkflow/releaser/internal/tmpl"
+)
+
+// LoadFiles reads dotenv files in order and exports their variables, so
+// later files override earlier ones and the process environment.
+func LoadFiles(paths []string) error {
+	for _, path := range paths {
+		vars, err := ParseFile(path)
+		if err != nil {
+			return err
+		}
+		for _, kv := range vars {
+			if err := os.Setenv(kv[0], kv[1]); err != nil {
+				return fmt.Errorf("failed to set %s from %s: %w", kv[0], path, err)
+			}
+		}
+	}
+	return nil
+}
+
+// ParseFile parses a dotenv file into ordered key/value pairs. Lines may be
+// prefixed with "export", values may be single or double quoted, and
+// unquoted values may carry a trailing " #" comment.
+func ParseFile(path string) ([][2]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open env file: %w", err)
+	}
+	defer f.Close()
+
+	var vars [][2]string
+	scanner := bufio.NewScanner(f)
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		line = strings.TrimPrefix(line, "export ")
+
+		key, value, ok := strings.Cut(line, "=")
+		key = strings.TrimSpace(key)
+		if !ok || key == "" {
+			return nil, fmt.Errorf("%s:%d: expected KEY=VALUE", path, lineNo)
+		}
+
+		value = strings.TrimSpace(value)
+		switch {
+		case len(value) >= 2 && value[0] == '"' && value[len(value)-1] == '"':
+			unquoted, err := strconv.Unquote(value)
+			if err != nil {
+				return nil, fmt.Errorf("%s:%d: invalid quoted value: %w", path, lineNo, err)
+			}
+			value = unquoted
+		case len(value) >= 2 && value[0] == '\'' && value[len(value)-1] == '\'':
+			value = value[1 : len(value)-1]
+		default:
+			if i := strings.Index(value, " #"); i >= 0 {
+				value = strings.TrimSpace(value[:i])
+			}
+		}
+
+		vars = append(vars, [2]string{key, value})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read env file %s: %w", path, err)
+	}
+	return vars, nil
+}
+
+// Expand applies templates to KEY=VALUE entries and exports them. Entries
+// are expanded in order, so an entry can reference earlier ones through
+// {{ .Env.NAME }}.
+func Expand(entries []string, tmplCtx *tmpl.Context) error {
+	for i, entry := range entries {
+		expanded, err := tmplCtx.Apply(fmt.Sprintf("env[%d]", i), entry)
+		if err != nil {
+			return fmt.Errorf("failed to expand env: %w", err)
+		}
+		key, value, ok := strings.Cut(expanded, "=")
+		if !ok || key == "" {
+			return fmt.Errorf("invalid env entry %q: expected KEY=VALUE", entry)
+		}
+		if err := os.Setenv(key, value); err != nil {
+			return fmt.Errorf("failed to set %s: %w", key, err)
+		}
+		tmplCtx.SetEnv(key, value)
+	}
+	return nil
+}
+
+// MaskingWriter replaces registered secrets with [redacted] before writing
+// to the underlying writer.
+type MaskingWriter struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+// NewMaskingWriter registers the current values of the named variables as
+// secrets and masks every registered secret, including ones added later,
+// in everything written to w.
+func NewMaskingWriter(w io.Writer, names []string) *MaskingWriter {
+	for _, name := range names {
+		redact.Add(os.Getenv(name))
+	}
+	return &MaskingWriter{w: w}
+}
+
+// Write implements io.Writer.
+func (m *MaskingWriter) Write(p []byte) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, err := io.WriteString(m.w, redact.String(string(p))); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
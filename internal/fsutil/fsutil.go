@@ -0,0 +1,30 @@
+/*
+Package fsutil provides file helpers shared by the build and packaging stages.
+*/
+package fsutil
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// CopyFile copies a file from src to dst, creating dst's directory and
+// keeping the mode of src
+func CopyFile(src, dst string) error {
+	info, err := os.Stat(src)
+	if err != nil {
+		return err
+	}
+	data, err := os.ReadFile(src)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return err
+	}
+	if err := os.WriteFile(dst, data, info.Mode().Perm()); err != nil {
+		return err
+	}
+	// WriteFile leaves the mode of an existing file and applies the umask
+	return os.Chmod(dst, info.Mode().Perm())
+}
@@ -0,0 +1,62 @@
+package fsutil
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCopyFileKeepsMode(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join(dir, "demo")
+	if err := os.WriteFile(src, []byte("binary"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	// Group and world write bits are stripped by the usual umask
+	if err := os.Chmod(src, 0777); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		existing bool
+	}{
+		{name: "new file"},
+		{name: "existing file", existing: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dst := filepath.Join(t.TempDir(), "bin", "demo")
+			if tt.existing {
+				if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.WriteFile(dst, []byte("stale"), 0600); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			if err := CopyFile(src, dst); err != nil {
+				t.Fatal(err)
+			}
+			info, err := os.Stat(dst)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if info.Mode().Perm() != 0777 {
+				t.Errorf("mode = %v, want 0777", info.Mode().Perm())
+			}
+			data, err := os.ReadFile(dst)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(data) != "binary" {
+				t.Errorf("content = %q", data)
+			}
+		})
+	}
+
+	if err := CopyFile(filepath.Join(dir, "missing"), filepath.Join(dir, "copy")); !os.IsNotExist(err) {
+		t.Errorf("CopyFile() of a missing file = %v", err)
+	}
+}
@@ -9,6 +9,7 @@ import (
 	"fmt"
 	"os/exec"
 	"regexp"
+	"strconv"
 	"strings"
 	"time"
 )
@@ -36,6 +37,13 @@ type Info struct {
 	// Summary is the git describe summary
 	Summary string
 
+	// LatestTag is the nearest tag reachable from HEAD
+	LatestTag string
+
+	// CommitCount is the number of commits since LatestTag, or since the
+	// first commit when there is no tag
+	CommitCount int
+
 	// TagSubject is the tag annotation subject
 	TagSubject string
 
@@ -76,28 +84,33 @@ type Info struct {
 	Metadata string
 }
 
-// GetInfo extracts git information from the current repository
+// GetInfo extracts git information from the current repository. It runs a
+// handful of git commands: rev-parse, log, status, describe and remote, plus
+// the tag annotation and previous tag when HEAD is tagged.
 func GetInfo(ctx context.Context) (*Info, error) {
 	info := &Info{}
 
-	// Check if this is a git repository
-	if _, err := run("git", "rev-parse", "--git-dir"); err != nil {
+	// Check if this is a git repository and get the branch
+	refs, err := run("git", "rev-parse", "--git-dir", "--abbrev-ref", "HEAD")
+	if err != nil {
 		return nil, fmt.Errorf("not a git repository")
 	}
 	info.IsGitRepo = true
+	if lines := strings.Split(strings.TrimSpace(refs), "\n"); len(lines) == 2 {
+		info.Branch = strings.TrimSpace(lines[1])
+	}
 
-	// Get current commit
-	commit, err := run("git", "rev-parse", "HEAD")
+	// Get current commit and its date
+	commit, err := run("git", "log", "-1", "--format=%H%x00%ci")
 	if err != nil {
 		return nil, fmt.Errorf("failed to get commit: %w", err)
 	}
-	info.Commit = strings.TrimSpace(commit)
+	hash, dateStr, _ := strings.Cut(strings.TrimSpace(commit), "\x00")
+	info.Commit = hash
 	info.ShortCommit = info.Commit[:8]
-
-	// Get current branch
-	branch, err := run("git", "rev-parse", "--abbrev-ref", "HEAD")
-	if err == nil {
-		info.Branch = strings.TrimSpace(branch)
+	if date, err := time.Parse("2006-01-02 15:04:05 -0700", dateStr); err == nil {
+		info.CommitDate = date
+		info.CommitTimestamp = fmt.Sprintf("%d", date.Unix())
 	}
 
 	// Get tree state
@@ -108,43 +121,48 @@ func GetInfo(ctx context.Context) (*Info, error) {
 		info.TreeState = "dirty"
 	}
 
-	// Get commit date
-	dateStr, err := run("git", "show", "-s", "--format=%ci", "HEAD")
-	if err == nil {
-		date, err := time.Parse("2006-01-02 15:04:05 -0700", strings.TrimSpace(dateStr))
-		if err == nil {
-			info.CommitDate = date
-			info.CommitTimestamp = fmt.Sprintf("%d", date.Unix())
+	// Get the nearest tag and the commit distance to it; a distance of zero
+	// means HEAD is tagged
+	describe, err := run("git", "describe", "--tags", "--long", "HEAD")
+	latest, count, ok := parseDescribe(strings.TrimSpace(describe))
+	if err == nil && ok {
+		info.LatestTag = latest
+		info.CommitCount = count
+		parseVersion(info, latest)
+		if count == 0 {
+			info.CurrentTag = latest
+			info.Summary = latest
+		} else {
+			info.Summary = strings.TrimSpace(describe)
+		}
+	} else {
+		info.Summary = info.ShortCommit
+		if total, err := run("git", "rev-list", "--count", "HEAD"); err == nil {
+			fmt.Sscanf(strings.TrimSpace(total), "%d", &info.CommitCount)
 		}
 	}
-
-	// Get current tag
-	tag, err := run("git", "describe", "--tags", "--exact-match", "HEAD")
-	if err == nil {
-		info.CurrentTag = strings.TrimSpace(tag)
-		parseVersion(info, info.CurrentTag)
-
-		// Get tag annotation
-		subject, _ := run("git", "tag", "-l", "--format=%(subject)", info.CurrentTag)
-		info.TagSubject = strings.TrimSpace(subject)
-
-		body, _ := run("git", "tag", "-l", "--format=%(body)", info.CurrentTag)
-		info.TagBody = strings.TrimSpace(body)
-
-		contents, _ := run("git", "tag", "-l", "--format=%(contents)", info.CurrentTag)
-		info.TagContents = strings.TrimSpace(contents)
+	if info.TreeState == "dirty" {
+		info.Summary += "-dirty"
 	}
 
-	// Get previous tag
-	prevTag, err := run("git", "describe", "--tags", "--abbrev=0", "HEAD^")
-	if err == nil {
-		info.PreviousTag = strings.TrimSpace(prevTag)
-	}
+	if info.CurrentTag != "" {
+		// Get tag annotation
+		contents, _ := run("git", "for-each-ref", "--format=%(subject)%00%(body)%00%(contents)", "refs/tags/"+info.CurrentTag)
+		parts := strings.SplitN(contents, "\x00", 3)
+		if len(parts) == 3 {
+			info.TagSubject = strings.TrimSpace(parts[0])
+			info.TagBody = strings.TrimSpace(parts[1])
+			info.TagContents = strings.TrimSpace(parts[2])
+		}
 
-	// Get git describe summary
-	summary, err := run("git", "describe", "--tags", "--always", "--dirty")
-	if err == nil {
-		info.Summary = strings.TrimSpace(summary)
+		// Get previous tag
+		prevTag, err := run("git", "describe", "--tags", "--abbrev=0", info.CurrentTag+"^")
+		if err == nil {
+			info.PreviousTag = strings.TrimSpace(prevTag)
+		}
+	} else {
+		// The nearest tag is the previous release
+		info.PreviousTag = info.LatestTag
 	}
 
 	// Get remote URL
@@ -312,6 +330,20 @@ type GroupedCommits struct {
 	Order   int
 }
 
+// describeRe matches "git describe --long" output: tag-count-gsha
+var describeRe = regexp.MustCompile(`^(.+)-(\d+)-g[0-9a-f]+$`)
+
+// parseDescribe extracts the tag and commit count from "git describe --long"
+func parseDescribe(describe string) (string, int, bool) {
+	matches := describeRe.FindStringSubmatch(describe)
+	if matches == nil {
+		return "", 0, false
+	}
+	var count int
+	fmt.Sscanf(matches[2], "%d", &count)
+	return matches[1], count, true
+}
+
 // parseVersion parses a semver tag
 func parseVersion(info *Info, tag string) {
 	// Strip leading 'v' if present
@@ -337,9 +369,117 @@ func parseVersion(info *Info, tag string) {
 	}
 }
 
+// BumpVersion increments the major, minor or patch part of a semantic
+// version, keeping any "v" prefix and dropping prerelease and build metadata
+func BumpVersion(version, part string) (string, error) {
+	index := map[string]int{"major": 0, "minor": 1, "patch": 2}
+	i, ok := index[part]
+	if !ok {
+		return "", fmt.Errorf("invalid version bump %q, expected major, minor or patch", part)
+	}
+
+	prefix := ""
+	core := version
+	if strings.HasPrefix(core, "v") {
+		prefix = "v"
+		core = core[1:]
+	}
+	if j := strings.IndexAny(core, "-+"); j >= 0 {
+		core = core[:j]
+	}
+
+	fields := strings.Split(core, ".")
+	if len(fields) != 3 {
+		return "", fmt.Errorf("invalid semantic version %q", version)
+	}
+	nums := make([]int, 3)
+	for j, field := range fields {
+		n, err := strconv.Atoi(field)
+		if err != nil {
+			return "", fmt.Errorf("invalid semantic version %q", version)
+		}
+		nums[j] = n
+	}
+
+	nums[i]++
+	for j := i + 1; j < 3; j++ {
+		nums[j] = 0
+	}
+	return fmt.Sprintf("%s%d.%d.%d", prefix, nums[0], nums[1], nums[2]), nil
+}
+
+// CreateTag creates an annotated tag on HEAD, signed with the user's key
+// when sign is set
+func CreateTag(ctx context.Context, name, message string, sign bool) error {
+	flag := "-a"
+	if sign {
+		flag = "-s"
+	}
+	if _, err := runContext(ctx, "git", "tag", flag, name, "-m", message); err != nil {
+		return fmt.Errorf("failed to create tag %s: %w", name, err)
+	}
+	return nil
+}
+
+// PushTag pushes a tag to a remote
+func PushTag(ctx context.Context, remote, name string) error {
+	if _, err := runContext(ctx, "git", "push", remote, "refs/tags/"+name); err != nil {
+		return fmt.Errorf("failed to push tag %s to %s: %w", name, remote, err)
+	}
+	return nil
+}
+
+// ChangedFiles returns the uncommitted changes in the worktree as
+// "git status --porcelain" lines
+func ChangedFiles(ctx context.Context) ([]string, error) {
+	out, err := run("git", "status", "--porcelain")
+	if err != nil {
+		return nil, fmt.Errorf("failed to get git status: %w", err)
+	}
+	var files []string
+	for _, line := range strings.Split(out, "\n") {
+		if strings.TrimSpace(line) != "" {
+			files = append(files, line)
+		}
+	}
+	return files, nil
+}
+
+// TagCommit returns the commit a tag points to
+func TagCommit(ctx context.Context, tag string) (string, error) {
+	out, err := run("git", "rev-list", "-n", "1", tag)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve tag %s: %w", tag, err)
+	}
+	return strings.TrimSpace(out), nil
+}
+
+// remoteRe matches the host and path of https, ssh and scp-like remotes,
+// e.g. https://github.com/o/r.git, ssh://git@host:22/o/r and git@host:o/r.git
+var remoteRe = regexp.MustCompile(`^(?:[a-z+]+://)?(?:[^@/]+@)?([^:/]+)(?::\d+)?[:/](.+?)(?:\.git)?/?$`)
+
+// ParseRemote splits a remote URL into its host, owner and repository name.
+// The owner may contain slashes, as with GitLab subgroups.
+func ParseRemote(remote string) (host, owner, name string, ok bool) {
+	matches := remoteRe.FindStringSubmatch(strings.TrimSpace(remote))
+	if matches == nil {
+		return "", "", "", false
+	}
+	i := strings.LastIndex(matches[2], "/")
+	if i <= 0 {
+		return "", "", "", false
+	}
+	return matches[1], matches[2][:i], matches[2][i+1:], true
+}
+
 // run executes a git command and returns the output
 func run(name string, args ...string) (string, error) {
-	cmd := exec.Command(name, args...)
+	return runContext(context.Background(), name, args...)
+}
+
+// runContext runs a command that is killed when ctx is done
+func runContext(ctx context.Context, name string, args ...string) (string, error) {
+	cmd := exec.CommandContext(ctx, name, args...)
 	var stdout, stderr bytes.Buffer
 	cmd.Stdout = &stdout
 	cmd.Stderr = &stderr
@@ -0,0 +1,279 @@
+package github
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/charmbracelet/log"
+
+	"github.com/oarkflow/releaser/internal/httpclient"
+	"github.com/oarkflow/releaser/internal/redact"
+)
+
+// refreshBefore is how long before expiry an installation token is renewed
+const refreshBefore = 5 * time.Minute
+
+// App authenticates as a GitHub App installation
+type App struct {
+	ID int64
+	// InstallationID is looked up from the repository when zero
+	InstallationID int64
+	// PrivateKey is the PEM encoded private key of the app
+	PrivateKey []byte
+}
+
+// appToken is the installation token the app authenticates with
+type appToken struct {
+	mu      sync.Mutex
+	app     App
+	key     *rsa.PrivateKey
+	token   string
+	expires time.Time
+	// issued holds every token handed out, so clients created with an older
+	// one still get the current one
+	issued map[string]bool
+}
+
+var (
+	appMu sync.RWMutex
+	app   *appToken
+)
+
+// UseApp authenticates every client as the app installation from now on.
+// The installation token is fetched at once, exported as GITHUB_TOKEN for
+// tools and publishers that read it, and renewed before it expires.
+func UseApp(ctx context.Context, a App, owner, repo string) error {
+	key, err := parsePrivateKey(a.PrivateKey)
+	if err != nil {
+		return err
+	}
+	t := &appToken{app: a, key: key, issued: make(map[string]bool)}
+
+	if t.app.InstallationID == 0 {
+		if owner == "" || repo == "" {
+			return errors.New("github app: installation_id is required without a release repository")
+		}
+		id, err := t.installationID(ctx, owner, repo)
+		if err != nil {
+			return err
+		}
+		t.app.InstallationID = id
+	}
+	if _, err := t.get(ctx); err != nil {
+		return err
+	}
+
+	appMu.Lock()
+	app = t
+	appMu.Unlock()
+	log.Info("Authenticated as GitHub App", "app_id", a.ID, "installation_id", t.app.InstallationID)
+	return nil
+}
+
+// ValidatePrivateKey checks that a GitHub App private key can be used
+func ValidatePrivateKey(pemData []byte) error {
+	_, err := parsePrivateKey(pemData)
+	return err
+}
+
+// Token returns the current installation token when authenticating as an
+// app, renewing it when needed, or GITHUB_TOKEN otherwise
+func Token(ctx context.Context) (string, error) {
+	appMu.RLock()
+	t := app
+	appMu.RUnlock()
+	if t == nil {
+		return os.Getenv("GITHUB_TOKEN"), nil
+	}
+	return t.get(ctx)
+}
+
+// authToken returns the token a client sends: the current installation
+// token in place of any token the app issued
+func authToken(ctx context.Context, token string) (string, error) {
+	appMu.RLock()
+	t := app
+	appMu.RUnlock()
+	if t == nil || (token != "" && !t.wasIssued(token)) {
+		return token, nil
+	}
+	return t.get(ctx)
+}
+
+func (t *appToken) wasIssued(token string) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.issued[token]
+}
+
+// get returns the installation token, exchanging a new app JWT for one
+// when it is missing or about to expire
+func (t *appToken) get(ctx context.Context) (string, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.token != "" && time.Until(t.expires) > refreshBefore {
+		return t.token, nil
+	}
+
+	var result struct {
+		Token     string    `json:"token"`
+		ExpiresAt time.Time `json:"expires_at"`
+	}
+	path := fmt.Sprintf("/app/installations/%d/access_tokens", t.app.InstallationID)
+	if err := t.appRequest(ctx, http.MethodPost, path, &result); err != nil {
+		return "", fmt.Errorf("github app: failed to create installation token: %w", err)
+	}
+
+	redact.Add(result.Token)
+	if t.token != "" {
+		log.Debug("Renewed GitHub App installation token", "expires", result.ExpiresAt)
+	}
+	t.token, t.expires = result.Token, result.ExpiresAt
+	t.issued[result.Token] = true
+	if err := os.Setenv("GITHUB_TOKEN", result.Token); err != nil {
+		return "", err
+	}
+	return t.token, nil
+}
+
+// installationID finds the installation of the app on a repository
+func (t *appToken) installationID(ctx context.Context, owner, repo string) (int64, error) {
+	var result struct {
+		ID int64 `json:"id"`
+	}
+	if err := t.appRequest(ctx, http.MethodGet, fmt.Sprintf("/repos/%s/%s/installation", owner, repo), &result); err != nil {
+		return 0, fmt.Errorf("github app: no installation found on %s/%s: %w", owner, repo, err)
+	}
+	return result.ID, nil
+}
+
+// appRequest sends a request authenticated with an app JWT
+func (t *appToken) appRequest(ctx context.Context, method, path string, out interface{}) error {
+	jwt, err := t.jwt()
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, method, CurrentURLs().API+path, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+jwt)
+	req.Header.Set("Accept", "application/vnd.github+json")
+	req.Header.Set("X-GitHub-Api-Version", "2022-11-28")
+
+	resp, err := httpclient.Client().Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 400 {
+		var body struct {
+			Message string `json:"message"`
+		}
+		_ = json.NewDecoder(resp.Body).Decode(&body)
+		return &Error{StatusCode: resp.StatusCode, Body: body.Message}
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// jwt returns an RS256 app JWT valid for nine minutes, backdated a minute
+// against clock drift as GitHub recommends
+func (t *appToken) jwt() (string, error) {
+	now := time.Now()
+	header := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"RS256","typ":"JWT"}`))
+	claims, err := json.Marshal(map[string]interface{}{
+		"iat": now.Add(-time.Minute).Unix(),
+		"exp": now.Add(9 * time.Minute).Unix(),
+		"iss": strconv.FormatInt(t.app.ID, 10),
+	})
+	if err != nil {
+		return "", err
+	}
+	signingInput := header + "." + base64.RawURLEncoding.EncodeToString(claims)
+
+	digest := sha256.Sum256([]byte(signingInput))
+	sig, err := rsa.SignPKCS1v15(rand.Reader, t.key, crypto.SHA256, digest[:])
+	if err != nil {
+		return "", fmt.Errorf("github app: failed to sign JWT: %w", err)
+	}
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(sig), nil
+}
+
+// parsePrivateKey parses a PKCS#1 or PKCS#8 RSA key, as GitHub issues
+// PKCS#1 keys and converted ones are often PKCS#8
+func parsePrivateKey(pemData []byte) (*rsa.PrivateKey, error) {
+	block, _ := pem.Decode(pemData)
+	if block == nil {
+		return nil, errors.New("github app: private key is not PEM encoded")
+	}
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, errors.New("github app: private key is not an RSA key")
+	}
+	key, ok := parsed.(*rsa.PrivateKey)
+	if !ok {
+		return nil, errors.New("github app: private key is not an RSA key")
+	}
+	return key, nil
+}
+
+// ActionsIDToken requests the ambient OIDC token of a GitHub Actions job
+// run with id-token: write. ok is false outside such a job.
+func ActionsIDToken(ctx context.Context, audience string) (token string, ok bool, err error) {
+	requestURL := os.Getenv("ACTIONS_ID_TOKEN_REQUEST_URL")
+	requestToken := os.Getenv("ACTIONS_ID_TOKEN_REQUEST_TOKEN")
+	if requestURL == "" || requestToken == "" {
+		return "", false, nil
+	}
+
+	u, err := url.Parse(requestURL)
+	if err != nil {
+		return "", true, fmt.Errorf("invalid ACTIONS_ID_TOKEN_REQUEST_URL: %w", err)
+	}
+	if audience != "" {
+		q := u.Query()
+		q.Set("audience", audience)
+		u.RawQuery = q.Encode()
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return "", true, err
+	}
+	req.Header.Set("Authorization", "Bearer "+requestToken)
+
+	resp, err := httpclient.Client().Do(req)
+	if err != nil {
+		return "", true, fmt.Errorf("failed to request OIDC token: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", true, fmt.Errorf("failed to request OIDC token: %s", resp.Status)
+	}
+
+	var result struct {
+		Value string `json:"value"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return "", true, fmt.Errorf("failed to decode OIDC token: %w", err)
+	}
+	redact.Add(result.Value)
+	return result.Value, true, nil
+}
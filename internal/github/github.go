@@ -0,0 +1,387 @@
+/*
+Package github provides the GitHub API client shared by the release
+publisher, the tap, bucket and winget publishers and the milestone closer.
+It authenticates requests, waits out primary and secondary rate limits,
+paginates list endpoints and talks to GitHub Enterprise Server when its URLs
+are configured.
+*/
+package github
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/charmbracelet/log"
+
+	"github.com/oarkflow/releaser/internal/httpclient"
+)
+
+const (
+	defaultAPI    = "https://api.github.com"
+	defaultUpload = "https://uploads.github.com"
+
+	// maxRateLimitRetries bounds how often one request waits for a limit
+	maxRateLimitRetries = 5
+
+	// maxRateLimitWait is the longest single wait; a later reset fails the
+	// request instead of stalling the release
+	maxRateLimitWait = 15 * time.Minute
+
+	// secondaryWait is the first wait for a secondary limit without
+	// Retry-After, as GitHub documents, doubled on each retry
+	secondaryWait = time.Minute
+)
+
+// URLs are the endpoints of a GitHub instance
+type URLs struct {
+	// API is the REST API root, e.g. https://github.example.com/api/v3
+	API string
+	// Upload is the release asset upload root, e.g.
+	// https://github.example.com/api/uploads
+	Upload string
+	// Download is the web root release downloads are served from, e.g.
+	// https://github.example.com
+	Download string
+}
+
+var (
+	urlsMu sync.RWMutex
+	urls   = defaultURLs()
+
+	// pause is shared by all clients, so concurrent requests stop together
+	// while a rate limit lasts instead of each tripping it again
+	pauseMu    sync.Mutex
+	pauseUntil time.Time
+)
+
+// linkNextRe finds the next page in a Link header
+var linkNextRe = regexp.MustCompile(`<([^>]+)>;\s*rel="next"`)
+
+// defaultURLs returns github.com, or the instance GitHub Actions runs on
+func defaultURLs() URLs {
+	u := URLs{API: defaultAPI, Upload: defaultUpload, Download: "https://github.com"}
+	if api := os.Getenv("GITHUB_API_URL"); api != "" && api != defaultAPI {
+		u = enterpriseURLs(URLs{API: api, Download: os.Getenv("GITHUB_SERVER_URL")})
+	}
+	return u
+}
+
+// Configure points every client at a GitHub Enterprise Server. Unset URLs
+// are derived from the API URL following the Enterprise layout.
+func Configure(u URLs) {
+	urlsMu.Lock()
+	defer urlsMu.Unlock()
+	if u.API == "" {
+		urls = defaultURLs()
+		return
+	}
+	urls = enterpriseURLs(u)
+}
+
+// enterpriseURLs fills in the upload and download URLs of an instance
+func enterpriseURLs(u URLs) URLs {
+	u.API = strings.TrimSuffix(u.API, "/")
+	root := strings.TrimSuffix(u.API, "/api/v3")
+	if u.Upload == "" {
+		u.Upload = root + "/api/uploads"
+	}
+	if u.Download == "" {
+		u.Download = root
+	}
+	u.Upload = strings.TrimSuffix(u.Upload, "/")
+	u.Download = strings.TrimSuffix(u.Download, "/")
+	return u
+}
+
+// CurrentURLs returns the configured endpoints
+func CurrentURLs() URLs {
+	urlsMu.RLock()
+	defer urlsMu.RUnlock()
+	return urls
+}
+
+// Error is a GitHub API error response
+type Error struct {
+	StatusCode int
+	Body       string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("GitHub API error (%d): %s", e.StatusCode, e.Body)
+}
+
+// IsNotFound reports whether err is a 404 response
+func IsNotFound(err error) bool {
+	var apiErr *Error
+	return errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusNotFound
+}
+
+// HasStatus reports whether err is a response with the given status
+func HasStatus(err error, status int) bool {
+	var apiErr *Error
+	return errors.As(err, &apiErr) && apiErr.StatusCode == status
+}
+
+// Client is an authenticated GitHub API client
+type Client struct {
+	token  string
+	api    string
+	upload string
+
+	// sleep waits out rate limits; tests record the waits instead
+	sleep func(ctx context.Context, d time.Duration) error
+}
+
+// New returns a client authenticating with token against the configured
+// instance
+func New(token string) *Client {
+	u := CurrentURLs()
+	return &Client{token: token, api: u.API, upload: u.Upload, sleep: sleep}
+}
+
+// URL returns the API URL of a path like /repos/o/r; absolute URLs, such as
+// pagination links, are returned unchanged
+func (c *Client) URL(path string) string {
+	if strings.HasPrefix(path, "https://") || strings.HasPrefix(path, "http://") {
+		return path
+	}
+	return c.api + path
+}
+
+// Do sends a JSON request and decodes the response into out, when not nil
+func (c *Client) Do(ctx context.Context, method, path string, in, out interface{}) error {
+	var data []byte
+	if in != nil {
+		var err error
+		if data, err = json.Marshal(in); err != nil {
+			return err
+		}
+	}
+
+	var body func() (io.Reader, error)
+	if in != nil {
+		body = func() (io.Reader, error) { return bytes.NewReader(data), nil }
+	}
+	resp, err := c.send(ctx, method, c.URL(path), body, int64(len(data)), "application/json", "")
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	return decode(resp, out)
+}
+
+// Upload sends a file to the upload API, e.g. a release asset to
+// /repos/o/r/releases/1/assets?name=x, and decodes the response into out
+func (c *Client) Upload(ctx context.Context, path string, file io.ReadSeeker, size int64, out interface{}) error {
+	body := func() (io.Reader, error) {
+		_, err := file.Seek(0, io.SeekStart)
+		// The transport closes bodies it sent, which would end retries
+		return io.NopCloser(file), err
+	}
+	resp, err := c.send(ctx, http.MethodPost, c.upload+path, body, size, "application/octet-stream", "")
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	return decode(resp, out)
+}
+
+// Download returns the contents of a release asset, given the API path of
+// the asset like /repos/o/r/releases/assets/1, so private repositories work
+func (c *Client) Download(ctx context.Context, path string) ([]byte, error) {
+	resp, err := c.send(ctx, http.MethodGet, c.URL(path), nil, 0, "", "application/octet-stream")
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	return io.ReadAll(resp.Body)
+}
+
+// List fetches every page of a list endpoint, 100 items at a time
+func List[T any](ctx context.Context, c *Client, path string) ([]T, error) {
+	next := c.URL(path)
+	if !strings.Contains(next, "per_page=") {
+		sep := "?"
+		if strings.Contains(next, "?") {
+			sep = "&"
+		}
+		next += sep + "per_page=100"
+	}
+
+	var all []T
+	for next != "" {
+		resp, err := c.send(ctx, http.MethodGet, next, nil, 0, "", "")
+		if err != nil {
+			return nil, err
+		}
+		var page []T
+		err = decode(resp, &page)
+		resp.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		all = append(all, page...)
+
+		next = ""
+		if m := linkNextRe.FindStringSubmatch(resp.Header.Get("Link")); m != nil {
+			next = m[1]
+		}
+	}
+	return all, nil
+}
+
+// send performs a request, waiting out rate limits. body is called for
+// each attempt so it can be sent again. accept overrides the JSON media
+// type of responses. Responses of 400 and above are returned as *Error.
+func (c *Client) send(ctx context.Context, method, target string, body func() (io.Reader, error), size int64, contentType, accept string) (*http.Response, error) {
+	if accept == "" {
+		accept = "application/vnd.github+json"
+	}
+	for attempt := 1; ; attempt++ {
+		if err := c.waitForPause(ctx); err != nil {
+			return nil, err
+		}
+
+		var reader io.Reader
+		if body != nil {
+			var err error
+			if reader, err = body(); err != nil {
+				return nil, err
+			}
+		}
+		req, err := http.NewRequestWithContext(ctx, method, target, reader)
+		if err != nil {
+			return nil, err
+		}
+		if body != nil {
+			req.ContentLength = size
+			req.Header.Set("Content-Type", contentType)
+		}
+		token, err := authToken(ctx, c.token)
+		if err != nil {
+			return nil, err
+		}
+		if token != "" {
+			req.Header.Set("Authorization", "token "+token)
+		}
+		req.Header.Set("Accept", accept)
+		req.Header.Set("X-GitHub-Api-Version", "2022-11-28")
+
+		resp, err := httpclient.Client().Do(req)
+		if err != nil {
+			return nil, err
+		}
+		if resp.StatusCode < 400 {
+			return resp, nil
+		}
+
+		data, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		apiErr := &Error{StatusCode: resp.StatusCode, Body: strings.TrimSpace(string(data))}
+
+		wait, limited := rateLimitWait(resp, data, attempt)
+		if !limited {
+			return nil, apiErr
+		}
+		if attempt > maxRateLimitRetries || wait > maxRateLimitWait {
+			return nil, fmt.Errorf("GitHub rate limit exceeded, retry in %s: %w", wait.Round(time.Second), apiErr)
+		}
+
+		log.Warn("GitHub rate limit reached, waiting", "wait", wait.Round(time.Second), "request", method+" "+redactQuery(target))
+		c.pause(wait)
+	}
+}
+
+// pause holds back every request for d
+func (c *Client) pause(d time.Duration) {
+	pauseMu.Lock()
+	defer pauseMu.Unlock()
+	if until := time.Now().Add(d); until.After(pauseUntil) {
+		pauseUntil = until
+	}
+}
+
+// waitForPause sleeps until a rate limit hit by any client is over
+func (c *Client) waitForPause(ctx context.Context) error {
+	pauseMu.Lock()
+	d := time.Until(pauseUntil)
+	pauseMu.Unlock()
+	if d <= 0 {
+		return nil
+	}
+	return c.sleep(ctx, d)
+}
+
+// rateLimitWait reports whether a response is a rate limit and how long to
+// wait before retrying: Retry-After when sent, else until X-RateLimit-Reset
+// when the quota is spent, else a minute doubled per attempt for secondary
+// limits
+func rateLimitWait(resp *http.Response, body []byte, attempt int) (time.Duration, bool) {
+	if resp.StatusCode != http.StatusForbidden && resp.StatusCode != http.StatusTooManyRequests {
+		return 0, false
+	}
+
+	if s := resp.Header.Get("Retry-After"); s != "" {
+		if secs, err := strconv.Atoi(s); err == nil {
+			return time.Duration(secs) * time.Second, true
+		}
+	}
+	if resp.Header.Get("X-RateLimit-Remaining") == "0" {
+		if reset, err := strconv.ParseInt(resp.Header.Get("X-RateLimit-Reset"), 10, 64); err == nil {
+			wait := time.Until(time.Unix(reset, 0)) + time.Second
+			if wait < time.Second {
+				wait = time.Second
+			}
+			return wait, true
+		}
+		return secondaryWait, true
+	}
+
+	lower := strings.ToLower(string(body))
+	if resp.StatusCode == http.StatusTooManyRequests || strings.Contains(lower, "secondary rate limit") || strings.Contains(lower, "abuse") {
+		return secondaryWait << (attempt - 1), true
+	}
+	return 0, false
+}
+
+// decode reads a JSON response into out, when not nil
+func decode(resp *http.Response, out interface{}) error {
+	if out == nil || resp.StatusCode == http.StatusNoContent {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// redactQuery drops the query of a URL for logging
+func redactQuery(target string) string {
+	u, err := url.Parse(target)
+	if err != nil {
+		return target
+	}
+	u.RawQuery = ""
+	return u.String()
+}
+
+// sleep waits for d or until ctx is done
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
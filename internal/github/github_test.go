@@ -0,0 +1,294 @@
+package github
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// response is one canned reply of the fake GitHub server
+type response struct {
+	status  int
+	headers map[string]string
+	body    string
+}
+
+// fakeGitHub answers requests with the given responses in order, repeating
+// the last one, and records the requests it received
+type fakeGitHub struct {
+	mu        sync.Mutex
+	responses []response
+	requests  []*http.Request
+	bodies    []string
+}
+
+func (f *fakeGitHub) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+
+	f.mu.Lock()
+	f.requests = append(f.requests, r)
+	f.bodies = append(f.bodies, string(body))
+	resp := f.responses[min(len(f.requests), len(f.responses))-1]
+	f.mu.Unlock()
+
+	for k, v := range resp.headers {
+		w.Header().Set(k, v)
+	}
+	w.WriteHeader(resp.status)
+	io.WriteString(w, resp.body)
+}
+
+// testClient returns a client of a fake server whose waits are recorded
+// instead of slept
+func testClient(t *testing.T, responses ...response) (*Client, *fakeGitHub, *[]time.Duration) {
+	t.Helper()
+	fake := &fakeGitHub{responses: responses}
+	srv := httptest.NewServer(fake)
+	t.Cleanup(srv.Close)
+
+	// Rate limits pause every client; start and end without one
+	resetPause := func() {
+		pauseMu.Lock()
+		pauseUntil = time.Time{}
+		pauseMu.Unlock()
+	}
+	resetPause()
+	t.Cleanup(resetPause)
+
+	var waits []time.Duration
+	c := &Client{
+		token:  "test-token",
+		api:    srv.URL,
+		upload: srv.URL,
+		sleep: func(ctx context.Context, d time.Duration) error {
+			waits = append(waits, d)
+			return ctx.Err()
+		},
+	}
+	return c, fake, &waits
+}
+
+var ok = response{status: http.StatusOK, body: `{"id": 1}`}
+
+func TestRateLimits(t *testing.T) {
+	reset := strconv.FormatInt(time.Now().Add(30*time.Second).Unix(), 10)
+
+	tests := []struct {
+		name      string
+		responses []response
+		// waits are the expected waits, each within a second
+		waits    []time.Duration
+		requests int
+		status   int
+	}{
+		{
+			name: "primary limit waits for reset",
+			responses: []response{
+				{status: http.StatusForbidden, headers: map[string]string{"X-RateLimit-Remaining": "0", "X-RateLimit-Reset": reset}, body: `{"message": "API rate limit exceeded"}`},
+				ok,
+			},
+			waits:    []time.Duration{31 * time.Second},
+			requests: 2,
+		},
+		{
+			name: "secondary limit honors Retry-After",
+			responses: []response{
+				{status: http.StatusForbidden, headers: map[string]string{"Retry-After": "7"}, body: `{"message": "You have exceeded a secondary rate limit"}`},
+				ok,
+			},
+			waits:    []time.Duration{7 * time.Second},
+			requests: 2,
+		},
+		{
+			name: "secondary limit backs off",
+			responses: []response{
+				{status: http.StatusForbidden, body: `{"message": "You have exceeded a secondary rate limit. Please wait a few minutes"}`},
+				{status: http.StatusForbidden, body: `{"message": "You have exceeded a secondary rate limit. Please wait a few minutes"}`},
+				ok,
+			},
+			waits:    []time.Duration{time.Minute, 2 * time.Minute},
+			requests: 3,
+		},
+		{
+			name: "too many requests",
+			responses: []response{
+				{status: http.StatusTooManyRequests, headers: map[string]string{"Retry-After": "2"}},
+				ok,
+			},
+			waits:    []time.Duration{2 * time.Second},
+			requests: 2,
+		},
+		{
+			name: "forbidden is not retried",
+			responses: []response{
+				{status: http.StatusForbidden, body: `{"message": "Resource not accessible by integration"}`},
+			},
+			requests: 1,
+			status:   http.StatusForbidden,
+		},
+		{
+			name: "gives up after the retries",
+			responses: []response{
+				{status: http.StatusForbidden, headers: map[string]string{"Retry-After": "1"}, body: `{"message": "secondary rate limit"}`},
+			},
+			waits:    []time.Duration{time.Second, time.Second, time.Second, time.Second, time.Second},
+			requests: maxRateLimitRetries + 1,
+			status:   http.StatusForbidden,
+		},
+		{
+			name: "does not wait past the longest wait",
+			responses: []response{
+				{status: http.StatusForbidden, headers: map[string]string{"Retry-After": "3600"}, body: `{"message": "secondary rate limit"}`},
+			},
+			requests: 1,
+			status:   http.StatusForbidden,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, fake, waits := testClient(t, tt.responses...)
+
+			var out struct{ ID int }
+			err := c.Do(context.Background(), http.MethodPost, "/repos/o/r/releases", map[string]string{"tag_name": "v1.2.3"}, &out)
+			if tt.status == 0 {
+				if err != nil {
+					t.Fatal(err)
+				}
+				if out.ID != 1 {
+					t.Errorf("decoded %+v", out)
+				}
+			} else if !HasStatus(err, tt.status) {
+				t.Fatalf("error = %v, want status %d", err, tt.status)
+			}
+
+			if len(fake.requests) != tt.requests {
+				t.Errorf("sent %d requests, want %d", len(fake.requests), tt.requests)
+			}
+			// Retries send the body again
+			for i, body := range fake.bodies {
+				if body != `{"tag_name":"v1.2.3"}` {
+					t.Errorf("request %d body = %q", i, body)
+				}
+			}
+			if len(*waits) != len(tt.waits) {
+				t.Fatalf("waited %v, want %v", *waits, tt.waits)
+			}
+			for i, want := range tt.waits {
+				if got := (*waits)[i]; got < want-time.Second || got > want+time.Second {
+					t.Errorf("wait %d = %s, want %s", i, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestRateLimitWaitEndsWithContext(t *testing.T) {
+	c, fake, _ := testClient(t, response{status: http.StatusForbidden, headers: map[string]string{"Retry-After": "5"}, body: "secondary rate limit"})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	// The release is canceled while waiting out the limit
+	c.sleep = func(ctx context.Context, d time.Duration) error {
+		cancel()
+		return ctx.Err()
+	}
+
+	err := c.Do(ctx, http.MethodGet, "/repos/o/r", nil, nil)
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("error = %v, want context.Canceled", err)
+	}
+	if len(fake.requests) != 1 {
+		t.Errorf("sent %d requests, want 1", len(fake.requests))
+	}
+}
+
+func TestListPaginates(t *testing.T) {
+	c, fake, _ := testClient(t)
+	srvURL := c.api
+
+	page := func(next string, ids ...int) response {
+		var items []string
+		for _, id := range ids {
+			items = append(items, fmt.Sprintf(`{"id": %d}`, id))
+		}
+		resp := response{status: http.StatusOK, body: "[" + strings.Join(items, ",") + "]"}
+		if next != "" {
+			resp.headers = map[string]string{"Link": fmt.Sprintf(`<%s%s>; rel="next", <%s/last>; rel="last"`, srvURL, next, srvURL)}
+		}
+		return resp
+	}
+	fake.responses = []response{
+		page("/repos/o/r/releases?per_page=100&page=2", 1, 2),
+		page("/repos/o/r/releases?per_page=100&page=3", 3),
+		page("", 4),
+	}
+
+	items, err := List[struct{ ID int }](context.Background(), c, "/repos/o/r/releases")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 4 || items[0].ID != 1 || items[3].ID != 4 {
+		t.Errorf("items = %+v", items)
+	}
+
+	wantQueries := []string{"per_page=100", "per_page=100&page=2", "per_page=100&page=3"}
+	if len(fake.requests) != len(wantQueries) {
+		t.Fatalf("sent %d requests, want %d", len(fake.requests), len(wantQueries))
+	}
+	for i, r := range fake.requests {
+		if r.URL.RawQuery != wantQueries[i] {
+			t.Errorf("request %d query = %q, want %q", i, r.URL.RawQuery, wantQueries[i])
+		}
+		if got := r.Header.Get("Authorization"); got != "token test-token" {
+			t.Errorf("request %d Authorization = %q", i, got)
+		}
+	}
+}
+
+func TestUploadRetriesWithBody(t *testing.T) {
+	c, fake, waits := testClient(t,
+		response{status: http.StatusForbidden, headers: map[string]string{"Retry-After": "1"}, body: "secondary rate limit"},
+		ok,
+	)
+
+	file := strings.NewReader("asset contents")
+	if err := c.Upload(context.Background(), "/repos/o/r/releases/1/assets?name=demo.tar.gz", file, file.Size(), nil); err != nil {
+		t.Fatal(err)
+	}
+	if len(*waits) != 1 || len(fake.requests) != 2 {
+		t.Fatalf("waited %v over %d requests", *waits, len(fake.requests))
+	}
+	for i, body := range fake.bodies {
+		if body != "asset contents" {
+			t.Errorf("upload %d body = %q", i, body)
+		}
+		if ct := fake.requests[i].Header.Get("Content-Type"); ct != "application/octet-stream" {
+			t.Errorf("upload %d content type = %q", i, ct)
+		}
+	}
+}
+
+func TestEnterpriseURLs(t *testing.T) {
+	t.Cleanup(func() { Configure(URLs{}) })
+
+	Configure(URLs{API: "https://github.example.com/api/v3/"})
+	want := URLs{
+		API:      "https://github.example.com/api/v3",
+		Upload:   "https://github.example.com/api/uploads",
+		Download: "https://github.example.com",
+	}
+	if got := CurrentURLs(); got != want {
+		t.Errorf("CurrentURLs() = %+v, want %+v", got, want)
+	}
+	if got := New("").URL("/repos/o/r"); got != "https://github.example.com/api/v3/repos/o/r" {
+		t.Errorf("URL() = %s", got)
+	}
+}
@@ -2,22 +2,34 @@
 package hook
 
 import (
+	"bytes"
 	"context"
 	"fmt"
 	"os"
 	"os/exec"
+	"path/filepath"
 	"runtime"
 	"strings"
+	"unicode"
 
 	"github.com/charmbracelet/log"
 	"github.com/oarkflow/releaser/internal/config"
+	"github.com/oarkflow/releaser/internal/redact"
 	"github.com/oarkflow/releaser/internal/tmpl"
 )
 
+// Output modes for hooks
+const (
+	OutputLog     = "log"
+	OutputDiscard = "discard"
+	OutputCapture = "capture"
+)
+
 // Runner executes lifecycle hooks.
 type Runner struct {
-	tmplCtx *tmpl.Context
-	workDir string
+	tmplCtx  *tmpl.Context
+	workDir  string
+	failures []error
 }
 
 // NewRunner creates a new hook runner.
@@ -28,14 +40,20 @@ func NewRunner(tmplCtx *tmpl.Context, workDir string) *Runner {
 	}
 }
 
+// Failures returns the errors of hooks that failed with fail_fast: false.
+func (r *Runner) Failures() []error {
+	return r.failures
+}
+
 // Run executes a hook.
 func (r *Runner) Run(ctx context.Context, hook config.Hook) error {
 	// Check condition
 	if hook.If != "" {
-		condition, err := r.tmplCtx.Apply(hook.If)
+		condition, err := r.tmplCtx.Apply("hooks.if", hook.If)
 		if err != nil {
 			return fmt.Errorf("failed to evaluate condition: %w", err)
 		}
+		condition = strings.TrimSpace(condition)
 		if condition != "true" && condition != "1" {
 			log.Debug("Skipping hook due to condition", "condition", hook.If)
 			return nil
@@ -49,64 +67,177 @@ func (r *Runner) Run(ctx context.Context, hook config.Hook) error {
 	}
 
 	// Apply template
-	cmd, err := r.tmplCtx.Apply(cmd)
+	cmd, err := r.tmplCtx.Apply("hooks.cmd", cmd)
 	if err != nil {
 		return fmt.Errorf("failed to apply template to command: %w", err)
 	}
 
-	log.Info("Running hook", "cmd", cmd)
+	output := outputMode(hook.Output)
+	if output == OutputCapture && hook.ID == "" {
+		return fmt.Errorf("hook %q captures output but has no id", cmd)
+	}
+
+	log.Info("Running hook", "cmd", redact.String(cmd))
 
 	// Create command
 	var c *exec.Cmd
-	shellPath := os.Getenv("SHELL")
-	if shellPath == "" {
-		if runtime.GOOS == "windows" {
-			shellPath = "powershell.exe"
-		} else {
-			shellPath = "/bin/sh"
+	if hook.Shell == "false" {
+		args, err := splitArgs(cmd)
+		if err != nil {
+			return fmt.Errorf("failed to parse command: %w", err)
+		}
+		if len(args) == 0 {
+			return nil
 		}
+		c = exec.CommandContext(ctx, args[0], args[1:]...)
+	} else {
+		args := ShellCommand(hook.Shell, cmd)
+		c = exec.CommandContext(ctx, args[0], args[1:]...)
 	}
 
-	if hook.Shell {
-		if runtime.GOOS == "windows" {
-			c = exec.CommandContext(ctx, shellPath, "-Command", cmd)
-		} else {
-			c = exec.CommandContext(ctx, shellPath, "-c", cmd)
+	c.Dir = r.workDir
+	if hook.Dir != "" {
+		dir, err := r.tmplCtx.Apply("hooks.dir", hook.Dir)
+		if err != nil {
+			return fmt.Errorf("failed to apply template to dir: %w", err)
 		}
-	} else {
-		// Parse command into args
-		parts := strings.Fields(cmd)
-		if len(parts) == 0 {
-			return nil
+		if !filepath.IsAbs(dir) && r.workDir != "" {
+			dir = filepath.Join(r.workDir, dir)
 		}
-		c = exec.CommandContext(ctx, parts[0], parts[1:]...)
+		c.Dir = dir
 	}
-	c.Dir = r.workDir
 
-	// Set environment
+	// Set environment; hook env overrides the inherited environment
 	c.Env = os.Environ()
 	for key, value := range hook.Env {
-		expandedValue, _ := r.tmplCtx.Apply(value)
+		expandedValue, err := r.tmplCtx.Apply("hooks.env."+key, value)
+		if err != nil {
+			return fmt.Errorf("failed to apply template to env %s: %w", key, err)
+		}
 		c.Env = append(c.Env, fmt.Sprintf("%s=%s", key, expandedValue))
 	}
 
 	// Handle output
-	if hook.Output == "true" || hook.Output == "1" {
+	var captured bytes.Buffer
+	switch output {
+	case OutputLog:
 		c.Stdout = os.Stdout
 		c.Stderr = os.Stderr
+	case OutputCapture:
+		c.Stdout = &captured
+		c.Stderr = os.Stderr
 	}
 
 	// Run command
 	if err := c.Run(); err != nil {
-		if hook.FailFast {
-			return fmt.Errorf("hook failed: %w", err)
+		err = fmt.Errorf("hook %s failed: %w", cmd, err)
+		if hook.FailFast == "false" {
+			log.Warn("Hook failed but continuing", "cmd", redact.String(cmd), "error", err)
+			r.failures = append(r.failures, err)
+			return nil
 		}
-		log.Warn("Hook failed but continuing", "cmd", cmd, "error", err)
+		return err
+	}
+
+	if output == OutputCapture {
+		r.setOutput(hook.ID, strings.TrimSpace(captured.String()))
 	}
 
 	return nil
 }
 
+// setOutput exposes captured hook output to later templates
+func (r *Runner) setOutput(id, value string) {
+	outputs, ok := r.tmplCtx.GetValue("Outputs").(map[string]string)
+	if !ok {
+		outputs = make(map[string]string)
+		r.tmplCtx.Set("Outputs", outputs)
+	}
+	outputs[id] = value
+}
+
+// outputMode normalizes the output setting, accepting legacy boolean values
+func outputMode(output string) string {
+	switch strings.ToLower(output) {
+	case "", OutputLog, "true", "1":
+		return OutputLog
+	case OutputCapture:
+		return OutputCapture
+	default:
+		return OutputDiscard
+	}
+}
+
+// ShellCommand returns the program and arguments that run cmd through
+// shell. An empty shell (or "true") selects the platform default: sh on
+// unix and cmd on Windows. "pwsh" and "powershell" run PowerShell, "cmd"
+// runs cmd.exe, and any other value is treated as a POSIX-style shell that
+// accepts -c.
+func ShellCommand(shell, cmd string) []string {
+	switch strings.ToLower(shell) {
+	case "", "true":
+		if runtime.GOOS == "windows" {
+			return []string{"cmd", "/C", cmd}
+		}
+		return []string{"sh", "-c", cmd}
+	case "cmd", "cmd.exe":
+		return []string{"cmd", "/C", cmd}
+	case "pwsh", "pwsh.exe":
+		return []string{"pwsh", "-NoProfile", "-NonInteractive", "-Command", cmd}
+	case "powershell", "powershell.exe":
+		return []string{"powershell", "-NoProfile", "-NonInteractive", "-Command", cmd}
+	default:
+		return []string{shell, "-c", cmd}
+	}
+}
+
+// splitArgs splits a command line into arguments, honoring single and
+// double quotes and backslash escapes.
+func splitArgs(cmd string) ([]string, error) {
+	var args []string
+	var current strings.Builder
+	var quote rune
+	inArg := false
+	escaped := false
+
+	for _, r := range cmd {
+		switch {
+		case escaped:
+			current.WriteRune(r)
+			escaped = false
+		case r == '\\' && quote != '\'':
+			escaped = true
+			inArg = true
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			} else {
+				current.WriteRune(r)
+			}
+		case r == '"' || r == '\'':
+			quote = r
+			inArg = true
+		case unicode.IsSpace(r):
+			if inArg {
+				args = append(args, current.String())
+				current.Reset()
+				inArg = false
+			}
+		default:
+			current.WriteRune(r)
+			inArg = true
+		}
+	}
+
+	if quote != 0 {
+		return nil, fmt.Errorf("unterminated quote in %q", cmd)
+	}
+	if inArg {
+		args = append(args, current.String())
+	}
+	return args, nil
+}
+
 // RunCommand executes a simple command string.
 func (r *Runner) RunCommand(ctx context.Context, cmd string) error {
 	if cmd == "" {
@@ -114,28 +245,15 @@ func (r *Runner) RunCommand(ctx context.Context, cmd string) error {
 	}
 
 	// Apply template
-	cmd, err := r.tmplCtx.Apply(cmd)
+	cmd, err := r.tmplCtx.Apply("hooks.cmd", cmd)
 	if err != nil {
 		return fmt.Errorf("failed to apply template to command: %w", err)
 	}
 
-	log.Info("Running command", "cmd", cmd)
-
-	shell := os.Getenv("SHELL")
-	if shell == "" {
-		if runtime.GOOS == "windows" {
-			shell = "powershell.exe"
-		} else {
-			shell = "/bin/sh"
-		}
-	}
+	log.Info("Running command", "cmd", redact.String(cmd))
 
-	var c *exec.Cmd
-	if runtime.GOOS == "windows" {
-		c = exec.CommandContext(ctx, shell, "-Command", cmd)
-	} else {
-		c = exec.CommandContext(ctx, shell, "-c", cmd)
-	}
+	args := ShellCommand("", cmd)
+	c := exec.CommandContext(ctx, args[0], args[1:]...)
 	c.Dir = r.workDir
 	c.Env = os.Environ()
 	c.Stdout = os.Stdout
@@ -148,7 +266,7 @@ func (r *Runner) RunCommand(ctx context.Context, cmd string) error {
 	return nil
 }
 
-// RunHooks executes multiple hooks.
+// RunHooks executes multiple hooks, stopping at the first fail-fast error.
 func (r *Runner) RunHooks(ctx context.Context, hooks []config.Hook) error {
 	for _, hook := range hooks {
 		if err := r.Run(ctx, hook); err != nil {
@@ -161,9 +279,8 @@ func (r *Runner) RunHooks(ctx context.Context, hooks []config.Hook) error {
 // ParseCommand parses a command string into a hook.
 func ParseCommand(cmd string) config.Hook {
 	return config.Hook{
-		Cmd:      cmd,
-		FailFast: true,
-		Output:   "true",
+		Cmd:    cmd,
+		Output: OutputLog,
 	}
 }
 
@@ -219,9 +336,8 @@ func FromStrings(commands []string) []config.Hook {
 	hooks := make([]config.Hook, 0, len(commands))
 	for _, cmd := range commands {
 		hooks = append(hooks, config.Hook{
-			Cmd:      cmd,
-			FailFast: true,
-			Output:   "true",
+			Cmd:    cmd,
+			Output: OutputLog,
 		})
 	}
 	return hooks
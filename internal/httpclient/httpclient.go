@@ -0,0 +1,196 @@
+/*
+Package httpclient provides the HTTP client every outbound request of
+Releaser goes through. It honors HTTP_PROXY, HTTPS_PROXY and NO_PROXY,
+trusts the system roots plus SSL_CERT_FILE and a configured CA bundle, and
+bounds requests with the configured timeout.
+*/
+package httpclient
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/charmbracelet/log"
+)
+
+// Options configures the shared client
+type Options struct {
+	// CABundle is a PEM file of extra certificate authorities to trust
+	CABundle string
+
+	// Timeout bounds each request, reading the response included; zero
+	// means no limit
+	Timeout time.Duration
+}
+
+// systemBundles are the usual locations of the system CA bundle, which
+// tools run by releaser are pointed at with the extra bundle appended
+var systemBundles = []string{
+	"/etc/ssl/certs/ca-certificates.crt",
+	"/etc/pki/tls/certs/ca-bundle.crt",
+	"/etc/ssl/ca-bundle.pem",
+	"/etc/pki/tls/cacert.pem",
+	"/etc/pki/ca-trust/extracted/pem/tls-ca-bundle.pem",
+	"/etc/ssl/cert.pem",
+}
+
+var (
+	mu       sync.RWMutex
+	client   *http.Client
+	insecure *http.Client
+	roots    *x509.CertPool
+
+	// certFile is SSL_CERT_FILE as releaser was started with, before
+	// Configure points it at the combined bundle
+	certFile = os.Getenv("SSL_CERT_FILE")
+
+	// exported is the combined bundle SSL_CERT_FILE points at, if any
+	exported string
+)
+
+func init() {
+	if err := Configure(Options{}); err != nil {
+		log.Warn("Ignoring SSL_CERT_FILE", "error", err)
+		certFile = ""
+		_ = Configure(Options{})
+	}
+}
+
+// Configure rebuilds the shared client from opts. With a CA bundle, the
+// tools releaser runs, such as cosign and helm, get SSL_CERT_FILE pointing
+// at the system roots plus the bundle.
+func Configure(opts Options) error {
+	pool, err := rootPool(opts.CABundle)
+	if err != nil {
+		return err
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = http.ProxyFromEnvironment
+	transport.TLSClientConfig = &tls.Config{RootCAs: pool, MinVersion: tls.VersionTLS12}
+
+	skipVerify := transport.Clone()
+	skipVerify.TLSClientConfig = &tls.Config{InsecureSkipVerify: true, MinVersion: tls.VersionTLS12}
+
+	mu.Lock()
+	client = &http.Client{Transport: transport, Timeout: opts.Timeout}
+	insecure = &http.Client{Transport: skipVerify, Timeout: opts.Timeout}
+	roots = pool
+	mu.Unlock()
+
+	Cleanup()
+	if opts.CABundle != "" {
+		return exportBundle(opts.CABundle)
+	}
+	return nil
+}
+
+// Cleanup removes the combined bundle written by Configure and restores
+// SSL_CERT_FILE
+func Cleanup() {
+	mu.Lock()
+	defer mu.Unlock()
+	if exported == "" {
+		return
+	}
+	os.Remove(exported)
+	exported = ""
+	if certFile != "" {
+		os.Setenv("SSL_CERT_FILE", certFile)
+	} else {
+		os.Unsetenv("SSL_CERT_FILE")
+	}
+}
+
+// Client returns the shared client
+func Client() *http.Client {
+	mu.RLock()
+	defer mu.RUnlock()
+	return client
+}
+
+// Insecure returns the shared client without certificate verification, for
+// targets configured to skip it
+func Insecure() *http.Client {
+	mu.RLock()
+	defer mu.RUnlock()
+	return insecure
+}
+
+// RootCAs returns the trusted certificate authorities, for TLS connections
+// that are not HTTP
+func RootCAs() *x509.CertPool {
+	mu.RLock()
+	defer mu.RUnlock()
+	return roots
+}
+
+// rootPool returns the system roots plus SSL_CERT_FILE and the bundle
+func rootPool(bundle string) (*x509.CertPool, error) {
+	pool, err := x509.SystemCertPool()
+	if err != nil || pool == nil {
+		pool = x509.NewCertPool()
+	}
+	for _, file := range []string{certFile, bundle} {
+		if file == "" {
+			continue
+		}
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CA bundle: %w", err)
+		}
+		if !pool.AppendCertsFromPEM(data) {
+			return nil, fmt.Errorf("no certificates found in CA bundle %s", file)
+		}
+	}
+	return pool, nil
+}
+
+// exportBundle writes the system bundle, or SSL_CERT_FILE, plus the extra
+// bundle to a temporary file and sets SSL_CERT_FILE to it. Systems without a
+// bundle file, like macOS and Windows, are left alone.
+func exportBundle(bundle string) error {
+	base := certFile
+	if base == "" {
+		for _, file := range systemBundles {
+			if _, err := os.Stat(file); err == nil {
+				base = file
+				break
+			}
+		}
+	}
+	if base == "" {
+		return nil
+	}
+
+	system, err := os.ReadFile(base)
+	if err != nil {
+		return fmt.Errorf("failed to read CA bundle: %w", err)
+	}
+	extra, err := os.ReadFile(bundle)
+	if err != nil {
+		return fmt.Errorf("failed to read CA bundle: %w", err)
+	}
+	f, err := os.CreateTemp("", "releaser-ca-*.pem")
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(append(append(system, '\n'), extra...))
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(f.Name())
+		return err
+	}
+
+	mu.Lock()
+	exported = f.Name()
+	mu.Unlock()
+	return os.Setenv("SSL_CERT_FILE", f.Name())
+}
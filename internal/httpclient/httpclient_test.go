@@ -0,0 +1,58 @@
+package httpclient
+
+import (
+	"crypto/tls"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestConfigureCABundle(t *testing.T) {
+	srv := httptest.NewTLSServer(http.NotFoundHandler())
+	t.Cleanup(srv.Close)
+	bundle := filepath.Join(t.TempDir(), "ca.pem")
+	cert := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: srv.Certificate().Raw})
+	if err := os.WriteFile(bundle, cert, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// The bundle is appended to SSL_CERT_FILE as releaser was started with
+	base := filepath.Join(t.TempDir(), "system.pem")
+	if err := os.WriteFile(base, cert, 0644); err != nil {
+		t.Fatal(err)
+	}
+	saved := certFile
+	certFile = base
+	t.Setenv("SSL_CERT_FILE", base)
+	t.Cleanup(func() {
+		certFile = saved
+		_ = Configure(Options{})
+	})
+
+	if err := Configure(Options{CABundle: bundle}); err != nil {
+		t.Fatal(err)
+	}
+	combined := os.Getenv("SSL_CERT_FILE")
+	if combined == base {
+		t.Fatal("SSL_CERT_FILE does not point at the combined bundle")
+	}
+	if _, err := Client().Get(srv.URL); err != nil {
+		t.Errorf("request to a server signed by the bundle: %v", err)
+	}
+	for _, c := range []*http.Client{Client(), Insecure()} {
+		if v := c.Transport.(*http.Transport).TLSClientConfig.MinVersion; v != tls.VersionTLS12 {
+			t.Errorf("minimum TLS version = %x, want TLS 1.2", v)
+		}
+	}
+
+	Cleanup()
+	if _, err := os.Stat(combined); !os.IsNotExist(err) {
+		t.Errorf("combined bundle %s was not removed: %v", combined, err)
+	}
+	if got := os.Getenv("SSL_CERT_FILE"); got != base {
+		t.Errorf("SSL_CERT_FILE = %s after cleanup, want %s", got, base)
+	}
+}
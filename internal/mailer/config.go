@@ -0,0 +1,1006 @@
+package mailer
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/mail"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/log"
+)
+
+// fieldAliases are the keys ParseConfig accepts for each field
+var fieldAliases = map[string][]string{
+	"from":                    {"from", "sender", "from_email", "fromaddress", "sender_email", "mailfrom"},
+	"from_name":               {"from_name", "sender_name", "fromname", "display_name", "name"},
+	"return_path":             {"return_path", "bounce", "envelope_from", "returnpath"},
+	"envelope_from":           {"envelope_from", "mail_from", "mfrom"},
+	"reply_to":                {"reply_to", "replyto", "respond_to", "response_to"},
+	"to":                      {"to", "recipient", "recipients", "send_to", "sending_to", "mail_to", "to_email", "sendto"},
+	"cc":                      {"cc", "carbon_copy", "copy_to"},
+	"bcc":                     {"bcc", "blind_carbon_copy", "blind_copy"},
+	"list_unsubscribe":        {"list_unsubscribe", "unsubscribe", "listunsubscribe"},
+	"list_unsubscribe_post":   {"list_unsubscribe_post", "unsubscribe_post", "one_click"},
+	"subject":                 {"subject", "title", "email_subject"},
+	"body":                    {"body", "message", "msg", "content", "email_content", "text"},
+	"body_html":               {"body_html", "html_body", "html", "message_html"},
+	"body_text":               {"body_text", "text_body", "plain_text", "message_text"},
+	"attachments":             {"attachments", "attachment", "files", "file", "attach"},
+	"configuration_set":       {"configuration_set", "config_set", "ses_configuration_set"},
+	"tags":                    {"tags", "ses_tags", "metadata", "ses_metadata"},
+	"provider":                {"provider", "use", "service", "email_service"},
+	"type":                    {"type", "transport", "channel", "method"},
+	"host":                    {"host", "server", "smtp_host", "address", "addr", "smtp_server"},
+	"port":                    {"port", "smtp_port"},
+	"username":                {"username", "user", "email", "login", "auth_user"},
+	"password":                {"password", "pass", "pwd", "auth_password"},
+	"api_key":                 {"api_key", "apikey", "key"},
+	"api_token":               {"api_token", "apitoken", "token", "access_token", "bearer", "bearer_token"},
+	"endpoint":                {"endpoint", "url", "api_url", "api_endpoint"},
+	"http_method":             {"http_method", "httpverb", "method"},
+	"headers":                 {"headers", "custom_headers", "http_headers"},
+	"query_params":            {"query_params", "query", "params", "querystrings", "querystring"},
+	"http_payload":            {"http_payload", "payload", "http_body", "custom_payload"},
+	"payload_format":          {"payload_format", "http_profile", "http_format"},
+	"http_content_type":       {"http_content_type", "payload_content_type", "http_payload_type"},
+	"http_auth":               {"http_auth", "auth", "auth_type"},
+	"http_auth_header":        {"http_auth_header", "auth_header", "api_key_header"},
+	"http_auth_query":         {"http_auth_query", "auth_query", "api_key_query", "auth_param"},
+	"http_auth_prefix":        {"http_auth_prefix", "auth_prefix", "bearer_prefix"},
+	"max_conns_per_host":      {"max_conns_per_host", "max_connections", "max_conns"},
+	"max_idle_conns":          {"max_idle_conns", "idle_conns", "max_idle"},
+	"max_idle_conns_per_host": {"max_idle_conns_per_host", "max_idle_host", "idle_conns_host"},
+	"disable_keepalives":      {"disable_keepalives", "no_keepalive", "disable_keep_alive"},
+	"smtp_auth":               {"smtp_auth", "smtp_auth_type", "smtp_auth_mechanism"},
+	"html_template":           {"html_template", "template_html", "html_file", "html_path"},
+	"text_template":           {"text_template", "template_text", "text_file", "text_path"},
+	"body_template":           {"body_template", "message_template", "msg_template", "message_file", "template_message"},
+	"timeout":                 {"timeout", "timeout_seconds", "request_timeout", "http_timeout"},
+	"retries":                 {"retries", "retry", "retry_count", "attempts"},
+	"retry_delay":             {"retry_delay", "retry_wait", "retry_backoff", "retry_pause"},
+	"use_tls":                 {"use_tls", "tls", "starttls", "enable_tls"},
+	"use_ssl":                 {"use_ssl", "ssl", "enable_ssl"},
+	"skip_tls_verify":         {"skip_tls_verify", "insecure", "disable_tls_verify"},
+	"aws_region":              {"aws_region", "region"},
+	"aws_access_key":          {"aws_access_key", "access_key", "aws_access_key_id"},
+	"aws_secret_key":          {"aws_secret_key", "secret_key", "aws_secret_access_key"},
+	"aws_session_token":       {"aws_session_token", "session_token", "aws_token"},
+}
+
+func init() {
+	for canonical, aliases := range fieldAliases {
+		seen := make(map[string]struct{})
+		normalized := make([]string, 0, len(aliases)+1)
+		for _, alias := range aliases {
+			alias = strings.TrimSpace(alias)
+			if alias == "" {
+				continue
+			}
+			lower := strings.ToLower(alias)
+			if _, ok := seen[lower]; ok {
+				continue
+			}
+			seen[lower] = struct{}{}
+			normalized = append(normalized, alias)
+		}
+		if _, ok := seen[strings.ToLower(canonical)]; !ok {
+			normalized = append(normalized, canonical)
+		}
+		fieldAliases[canonical] = normalized
+	}
+}
+
+// ParseConfig builds a configuration from a JSON style map, such as a
+// decoded config file. Keys may be any of the aliases of a field, and
+// leftover keys become AdditionalData. {{placeholders}} in values are
+// expanded before and after defaults are applied.
+func ParseConfig(raw map[string]any) (*EmailConfig, error) {
+	norm := newNormalizedConfig(raw)
+	cfg := &EmailConfig{
+		Headers:     map[string]string{},
+		QueryParams: map[string]string{},
+	}
+
+	cfg.From = getStringField(norm, "from")
+	cfg.FromName = getStringField(norm, "from_name")
+	cfg.ReturnPath = getStringField(norm, "return_path")
+	if env := getStringField(norm, "envelope_from"); env != "" {
+		cfg.EnvelopeFrom = env
+	}
+	cfg.ReplyTo = getStringArrayField(norm, "reply_to")
+	cfg.To = getStringArrayField(norm, "to")
+	cfg.CC = getStringArrayField(norm, "cc")
+	cfg.BCC = getStringArrayField(norm, "bcc")
+	cfg.ListUnsubscribe = getStringArrayField(norm, "list_unsubscribe")
+	cfg.ListUnsubscribePost = getBoolField(norm, "list_unsubscribe_post")
+	cfg.Subject = getStringField(norm, "subject")
+	cfg.Body = getStringField(norm, "body")
+	cfg.TextBody = getStringField(norm, "body_text")
+	cfg.HTMLBody = getStringField(norm, "body_html")
+	cfg.HTMLTemplatePath = getStringField(norm, "html_template")
+	cfg.TextTemplatePath = getStringField(norm, "text_template")
+	cfg.BodyTemplatePath = getStringField(norm, "body_template")
+	cfg.ConfigurationSet = getStringField(norm, "configuration_set")
+	cfg.Tags = getStringMapField(norm, "tags")
+
+	attachments, err := getAttachments(norm, "attachments")
+	if err != nil {
+		return nil, err
+	}
+	cfg.Attachments = attachments
+
+	cfg.Provider = strings.ToLower(getStringField(norm, "provider"))
+	cfg.Transport = strings.ToLower(getStringField(norm, "type"))
+	cfg.Host = getStringField(norm, "host")
+	cfg.Port = getIntField(norm, "port")
+	cfg.Username = getStringField(norm, "username")
+	cfg.Password = getStringField(norm, "password")
+	cfg.APIKey = getStringField(norm, "api_key")
+	cfg.APIToken = getStringField(norm, "api_token")
+	cfg.Endpoint = getStringField(norm, "endpoint")
+	cfg.HTTPMethod = strings.ToUpper(getStringField(norm, "http_method"))
+	if cfg.HTTPMethod == "" {
+		cfg.HTTPMethod = http.MethodPost
+	}
+	cfg.Headers = ensureStringMap(getStringMapField(norm, "headers"))
+	cfg.QueryParams = ensureStringMap(getStringMapField(norm, "query_params"))
+	cfg.HTTPPayload = getObjectField(norm, "http_payload")
+	cfg.PayloadFormat = strings.ToLower(getStringField(norm, "payload_format"))
+	cfg.HTTPContentType = getStringField(norm, "http_content_type")
+	cfg.HTTPAuth = strings.ToLower(getStringField(norm, "http_auth"))
+	cfg.HTTPAuthHeader = getStringField(norm, "http_auth_header")
+	cfg.HTTPAuthQuery = getStringField(norm, "http_auth_query")
+	cfg.HTTPAuthPrefix = getStringField(norm, "http_auth_prefix")
+	cfg.MaxConnsPerHost = getIntField(norm, "max_conns_per_host")
+	cfg.MaxIdleConns = getIntField(norm, "max_idle_conns")
+	cfg.MaxIdleConnsHost = getIntField(norm, "max_idle_conns_per_host")
+	cfg.DisableKeepAlives = getBoolField(norm, "disable_keepalives")
+	cfg.SMTPAuth = strings.ToLower(getStringField(norm, "smtp_auth"))
+	cfg.AWSRegion = getStringField(norm, "aws_region")
+	cfg.AWSAccessKey = getStringField(norm, "aws_access_key")
+	cfg.AWSSecretKey = getStringField(norm, "aws_secret_key")
+	cfg.AWSSessionToken = getStringField(norm, "aws_session_token")
+	cfg.Timeout = getDurationField(norm, "timeout")
+	cfg.RetryCount = getIntField(norm, "retries")
+	cfg.RetryDelay = getDurationField(norm, "retry_delay")
+	cfg.UseTLS = getBoolField(norm, "use_tls")
+	cfg.UseSSL = getBoolField(norm, "use_ssl")
+	cfg.SkipTLSVerify = getBoolField(norm, "skip_tls_verify")
+	cfg.AdditionalData = norm.leftovers()
+	if cfg.AdditionalData == nil {
+		cfg.AdditionalData = map[string]any{}
+	}
+
+	if err := applyPlaceholders(cfg, placeholderModeInitial); err != nil {
+		return nil, err
+	}
+
+	if err := finalizeConfig(cfg); err != nil {
+		return nil, err
+	}
+
+	if err := loadTemplateBodies(cfg); err != nil {
+		return nil, err
+	}
+
+	if err := applyPlaceholders(cfg, placeholderModePostFinalize); err != nil {
+		return nil, err
+	}
+	resolveBodies(cfg)
+
+	return cfg, nil
+}
+
+// MergeConfig merges override into base, merging nested maps key by key
+func MergeConfig(base, override map[string]any) map[string]any {
+	if base == nil {
+		base = map[string]any{}
+	}
+	for key, value := range override {
+		if existing, ok := base[key]; ok {
+			existingMap, okExisting := asMap(existing)
+			valueMap, okValue := asMap(value)
+			if okExisting && okValue {
+				base[key] = MergeConfig(existingMap, valueMap)
+				continue
+			}
+		}
+		base[key] = value
+	}
+	return base
+}
+
+func asMap(value any) (map[string]any, bool) {
+	switch v := value.(type) {
+	case map[string]any:
+		return v, true
+	case map[string]string:
+		result := make(map[string]any, len(v))
+		for key, val := range v {
+			result[key] = val
+		}
+		return result, true
+	default:
+		return nil, false
+	}
+}
+
+// prepareConfig applies defaults to a configuration and reads its template
+// files, as ParseConfig does without expanding placeholders
+func prepareConfig(cfg *EmailConfig) error {
+	if err := finalizeConfig(cfg); err != nil {
+		return err
+	}
+	if err := loadTemplateBodies(cfg); err != nil {
+		return err
+	}
+	resolveBodies(cfg)
+	return nil
+}
+
+func finalizeConfig(cfg *EmailConfig) error {
+	cfg.Provider = strings.ToLower(cfg.Provider)
+	// The sender's domain only hints at a provider when no server is given
+	if cfg.Provider == "" && cfg.Host == "" && cfg.Endpoint == "" {
+		cfg.Provider = inferProvider(cfg.From, cfg.Username)
+	}
+	if cfg.Tags == nil {
+		cfg.Tags = map[string]string{}
+	}
+	if cfg.HTTPAuthPrefix == "" {
+		cfg.HTTPAuthPrefix = "Bearer"
+	}
+	applyProviderDefaults(cfg)
+	applyHTTPProfile(cfg)
+
+	if cfg.Transport == "" {
+		if cfg.Endpoint != "" && looksLikeURL(cfg.Endpoint) {
+			cfg.Transport = "http"
+		} else if looksLikeURL(cfg.Host) {
+			cfg.Transport = "http"
+		} else {
+			cfg.Transport = "smtp"
+		}
+	}
+
+	if cfg.Transport != "http" {
+		cfg.Transport = "smtp"
+	}
+
+	if cfg.Transport == "http" && cfg.Endpoint == "" {
+		cfg.Endpoint = cfg.Host
+	}
+
+	if cfg.Transport == "http" && cfg.Endpoint != "" && !looksLikeURL(cfg.Endpoint) {
+		cfg.Endpoint = "https://" + strings.TrimLeft(cfg.Endpoint, ":/")
+	}
+
+	if cfg.From == "" && cfg.Username != "" {
+		cfg.From = cfg.Username
+	}
+	name, addr := splitAddress(cfg.From)
+	if cfg.FromName == "" {
+		cfg.FromName = name
+	}
+	if addr == "" {
+		return errors.New("sender address is required")
+	}
+	cfg.From = addr
+	if cfg.EnvelopeFrom == "" {
+		cfg.EnvelopeFrom = addr
+	}
+	if cfg.ReturnPath != "" {
+		cfg.EnvelopeFrom = cfg.ReturnPath
+	}
+	if cfg.Username == "" {
+		cfg.Username = addr
+	}
+	if cfg.AWSRegion == "" {
+		cfg.AWSRegion = inferAWSRegion(cfg.Endpoint)
+	}
+
+	if cfg.Subject == "" {
+		cfg.Subject = "(no subject)"
+	}
+	resolveBodies(cfg)
+
+	if len(cfg.To) == 0 {
+		return errors.New("at least one recipient (to) is required")
+	}
+
+	if cfg.Transport == "smtp" {
+		if cfg.Host == "" {
+			return errors.New("smtp host is required")
+		}
+		if cfg.Port == 0 {
+			if cfg.UseSSL {
+				cfg.Port = 465
+			} else if cfg.UseTLS {
+				cfg.Port = 587
+			} else {
+				cfg.Port = 25
+			}
+		}
+	} else {
+		if cfg.Endpoint == "" {
+			return errors.New("http endpoint is required when type=http")
+		}
+	}
+
+	if cfg.Timeout == 0 {
+		cfg.Timeout = 30 * time.Second
+	}
+	if cfg.RetryCount <= 0 {
+		cfg.RetryCount = 1
+	}
+	if cfg.RetryDelay <= 0 {
+		cfg.RetryDelay = 2 * time.Second
+	}
+	applyHTTPScalingDefaults(cfg)
+
+	return nil
+}
+
+func applyHTTPScalingDefaults(cfg *EmailConfig) {
+	if cfg.Transport != "http" {
+		return
+	}
+	if cfg.MaxConnsPerHost == 0 {
+		cfg.MaxConnsPerHost = 32
+	}
+	if cfg.MaxIdleConns == 0 {
+		cfg.MaxIdleConns = 120
+	}
+	if cfg.MaxIdleConnsHost == 0 {
+		cfg.MaxIdleConnsHost = 32
+	}
+}
+
+func applyHTTPProfile(cfg *EmailConfig) {
+	profile, ok := httpProviderProfiles[cfg.Provider]
+	if !ok {
+		return
+	}
+	if cfg.Transport == "" {
+		cfg.Transport = "http"
+	}
+	if cfg.Transport != "http" {
+		return
+	}
+	if cfg.Endpoint == "" {
+		cfg.Endpoint = profile.Endpoint
+	}
+	if cfg.HTTPMethod == "" && profile.Method != "" {
+		cfg.HTTPMethod = profile.Method
+	}
+	if cfg.PayloadFormat == "" && profile.PayloadFormat != "" {
+		cfg.PayloadFormat = profile.PayloadFormat
+	}
+	if cfg.HTTPContentType == "" {
+		cfg.HTTPContentType = profile.ContentType
+	}
+	if cfg.MaxConnsPerHost == 0 && profile.Endpoint != "" {
+		cfg.MaxConnsPerHost = 32
+	}
+	if cfg.MaxIdleConns == 0 && profile.Endpoint != "" {
+		cfg.MaxIdleConns = 120
+	}
+	if cfg.MaxIdleConnsHost == 0 && profile.Endpoint != "" {
+		cfg.MaxIdleConnsHost = 32
+	}
+	if cfg.Provider == "ses" || cfg.Provider == "aws_ses" || cfg.Provider == "amazon_ses" {
+		if cfg.HTTPAuth == "" {
+			cfg.HTTPAuth = "aws_sigv4"
+		}
+		if cfg.AWSRegion == "" {
+			cfg.AWSRegion = inferAWSRegion(cfg.Endpoint)
+		}
+	}
+	if cfg.Provider == "postmark" && cfg.HTTPAuth == "" {
+		cfg.HTTPAuth = "api_key_header"
+		cfg.HTTPAuthHeader = "X-Postmark-Server-Token"
+	}
+	if cfg.Provider == "resend" && cfg.HTTPAuth == "" {
+		cfg.HTTPAuth = "bearer"
+	}
+	if cfg.Provider == "sparkpost" && cfg.HTTPAuth == "" {
+		cfg.HTTPAuth = "bearer"
+	}
+	// Seed sensible per-provider scaling defaults if not provided.
+	switch cfg.Provider {
+	case "ses", "aws_ses", "amazon_ses", "sendgrid", "sparkpost", "postmark", "resend", "mailgun":
+		if cfg.MaxConnsPerHost == 0 {
+			cfg.MaxConnsPerHost = 64
+		}
+		if cfg.MaxIdleConns == 0 {
+			cfg.MaxIdleConns = 200
+		}
+		if cfg.MaxIdleConnsHost == 0 {
+			cfg.MaxIdleConnsHost = 64
+		}
+	case "brevo", "sendinblue", "mailtrap":
+		if cfg.MaxConnsPerHost == 0 {
+			cfg.MaxConnsPerHost = 32
+		}
+		if cfg.MaxIdleConns == 0 {
+			cfg.MaxIdleConns = 120
+		}
+		if cfg.MaxIdleConnsHost == 0 {
+			cfg.MaxIdleConnsHost = 32
+		}
+	}
+	if cfg.Headers == nil {
+		cfg.Headers = map[string]string{}
+	}
+	for k, v := range profile.Headers {
+		if _, exists := cfg.Headers[k]; !exists {
+			cfg.Headers[k] = v
+		}
+	}
+}
+
+func applyProviderDefaults(cfg *EmailConfig) {
+	if cfg.Provider == "" {
+		return
+	}
+	if defaults, ok := providerDefaults[cfg.Provider]; ok {
+		if cfg.Host == "" {
+			cfg.Host = defaults.Host
+		}
+		if cfg.Port == 0 {
+			cfg.Port = defaults.Port
+		}
+		if !cfg.UseTLS && !cfg.UseSSL {
+			cfg.UseTLS = defaults.UseTLS
+			cfg.UseSSL = defaults.UseSSL
+		}
+		if cfg.Transport == "" && defaults.Transport != "" {
+			cfg.Transport = defaults.Transport
+		}
+		if cfg.Endpoint == "" && defaults.Endpoint != "" {
+			cfg.Endpoint = defaults.Endpoint
+		}
+	}
+}
+
+func inferProvider(addresses ...string) string {
+	for _, addr := range addresses {
+		_, email := splitAddress(addr)
+		if email == "" {
+			continue
+		}
+		parts := strings.Split(email, "@")
+		if len(parts) != 2 {
+			continue
+		}
+		domain := strings.ToLower(strings.TrimSpace(parts[1]))
+		if provider, ok := emailDomainMap[domain]; ok {
+			return provider
+		}
+	}
+	return ""
+}
+
+func resolveBodies(cfg *EmailConfig) {
+	text := strings.TrimSpace(cfg.TextBody)
+	html := strings.TrimSpace(cfg.HTMLBody)
+	base := strings.TrimSpace(cfg.Body)
+
+	if html == "" && looksLikeHTML(base) {
+		html = base
+	}
+	if text == "" {
+		if html == "" {
+			text = base
+		} else if base != "" && !looksLikeHTML(base) {
+			text = base
+		}
+	}
+	if text == "" && html == "" {
+		text = "(empty message)"
+	}
+
+	cfg.TextBody = text
+	cfg.HTMLBody = html
+}
+
+// loadTemplateBodies reads template files into the bodies, clearing their
+// paths so that they are read once
+func loadTemplateBodies(cfg *EmailConfig) error {
+	if path := strings.TrimSpace(cfg.HTMLTemplatePath); path != "" {
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("read html template %s: %w", path, err)
+		}
+		cfg.HTMLBody = string(content)
+		cfg.HTMLTemplatePath = ""
+		log.Debug("Loaded HTML template", "path", path)
+	}
+	if path := strings.TrimSpace(cfg.TextTemplatePath); path != "" {
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("read text template %s: %w", path, err)
+		}
+		cfg.TextBody = string(content)
+		cfg.TextTemplatePath = ""
+		log.Debug("Loaded text template", "path", path)
+	}
+	if path := strings.TrimSpace(cfg.BodyTemplatePath); path != "" {
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("read body template %s: %w", path, err)
+		}
+		cfg.Body = string(content)
+		cfg.BodyTemplatePath = ""
+		log.Debug("Loaded message template", "path", path)
+	}
+	return nil
+}
+
+type configEntry struct {
+	original  string
+	sanitized string
+	value     any
+	used      bool
+}
+
+type normalizedConfig struct {
+	entries map[string][]*configEntry
+}
+
+func newNormalizedConfig(raw map[string]any) *normalizedConfig {
+	entries := make(map[string][]*configEntry)
+	for key, value := range raw {
+		sanitized := sanitizeKey(key)
+		e := &configEntry{original: key, sanitized: sanitized, value: value}
+		entries[sanitized] = append(entries[sanitized], e)
+	}
+	return &normalizedConfig{entries: entries}
+}
+
+func (n *normalizedConfig) leftOverEntries() []*configEntry {
+	var result []*configEntry
+	for _, list := range n.entries {
+		for _, entry := range list {
+			if !entry.used {
+				result = append(result, entry)
+			}
+		}
+	}
+	return result
+}
+
+func (n *normalizedConfig) leftovers() map[string]any {
+	result := make(map[string]any)
+	for _, entry := range n.leftOverEntries() {
+		result[entry.original] = entry.value
+	}
+	return result
+}
+
+func (n *normalizedConfig) pullValue(canonical string) (any, bool) {
+	if canonical == "" {
+		return nil, false
+	}
+	if aliases, ok := fieldAliases[canonical]; ok {
+		if val, ok := n.consumeAliases(aliases); ok {
+			return val, true
+		}
+	}
+	if val, ok := n.consumeExact(canonical); ok {
+		return val, true
+	}
+	return n.consumeFuzzy(canonical)
+}
+
+func (n *normalizedConfig) consumeAliases(aliases []string) (any, bool) {
+	for _, alias := range aliases {
+		if val, ok := n.consumeExact(alias); ok {
+			return val, true
+		}
+	}
+	return nil, false
+}
+
+func (n *normalizedConfig) consumeExact(key string) (any, bool) {
+	sanitized := sanitizeKey(key)
+	if entries, ok := n.entries[sanitized]; ok {
+		for _, entry := range entries {
+			if entry.used {
+				continue
+			}
+			entry.used = true
+			return entry.value, true
+		}
+	}
+	return nil, false
+}
+
+func (n *normalizedConfig) consumeFuzzy(target string) (any, bool) {
+	token := sanitizeKey(target)
+	if len(token) < 4 {
+		return nil, false
+	}
+	for key, entries := range n.entries {
+		if len(key) < 4 {
+			continue
+		}
+		if !strings.Contains(key, token) && !strings.Contains(token, key) {
+			continue
+		}
+		for _, entry := range entries {
+			if entry.used {
+				continue
+			}
+			entry.used = true
+			return entry.value, true
+		}
+	}
+	return nil, false
+}
+
+func sanitizeKey(key string) string {
+	lower := strings.ToLower(key)
+	var b strings.Builder
+	for _, r := range lower {
+		if r >= 'a' && r <= 'z' || r >= '0' && r <= '9' {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+func getStringField(norm *normalizedConfig, canonical string) string {
+	val, ok := norm.pullValue(canonical)
+	if !ok || val == nil {
+		return ""
+	}
+	switch v := val.(type) {
+	case string:
+		return strings.TrimSpace(v)
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case int:
+		return strconv.Itoa(v)
+	case bool:
+		if v {
+			return "true"
+		}
+		return "false"
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+func getStringArrayField(norm *normalizedConfig, canonical string) []string {
+	val, ok := norm.pullValue(canonical)
+	if !ok || val == nil {
+		return nil
+	}
+	return normalizeStringSlice(val)
+}
+
+func normalizeStringSlice(val any) []string {
+	switch v := val.(type) {
+	case string:
+		return splitList(v)
+	case []any:
+		out := make([]string, 0, len(v))
+		for _, item := range v {
+			switch entry := item.(type) {
+			case string:
+				if trimmed := strings.TrimSpace(entry); trimmed != "" {
+					out = append(out, trimmed)
+				}
+			default:
+				out = append(out, strings.TrimSpace(fmt.Sprint(entry)))
+			}
+		}
+		return out
+	case []string:
+		out := make([]string, 0, len(v))
+		for _, item := range v {
+			if trimmed := strings.TrimSpace(item); trimmed != "" {
+				out = append(out, trimmed)
+			}
+		}
+		return out
+	default:
+		return []string{strings.TrimSpace(fmt.Sprint(v))}
+	}
+}
+
+func splitList(value string) []string {
+	parts := strings.FieldsFunc(value, func(r rune) bool {
+		return r == ',' || r == ';' || r == '\n'
+	})
+	result := make([]string, 0, len(parts))
+	for _, part := range parts {
+		if trimmed := strings.TrimSpace(part); trimmed != "" {
+			result = append(result, trimmed)
+		}
+	}
+	return result
+}
+
+func getIntField(norm *normalizedConfig, canonical string) int {
+	val, ok := norm.pullValue(canonical)
+	if !ok || val == nil {
+		return 0
+	}
+	switch v := val.(type) {
+	case float64:
+		return int(v)
+	case int:
+		return v
+	case string:
+		if i, err := strconv.Atoi(strings.TrimSpace(v)); err == nil {
+			return i
+		}
+	}
+	return 0
+}
+
+func getBoolField(norm *normalizedConfig, canonical string) bool {
+	val, ok := norm.pullValue(canonical)
+	if !ok || val == nil {
+		return false
+	}
+	return normalizeBool(val)
+}
+
+func normalizeBool(val any) bool {
+	switch v := val.(type) {
+	case bool:
+		return v
+	case string:
+		lower := strings.ToLower(strings.TrimSpace(v))
+		return lower == "true" || lower == "yes" || lower == "1"
+	case float64:
+		return v != 0
+	case int:
+		return v != 0
+	}
+	return false
+}
+
+func getDurationField(norm *normalizedConfig, canonical string) time.Duration {
+	val, ok := norm.pullValue(canonical)
+	if !ok || val == nil {
+		return 0
+	}
+	switch v := val.(type) {
+	case float64:
+		return time.Duration(v) * time.Second
+	case int:
+		return time.Duration(v) * time.Second
+	case string:
+		if d, err := time.ParseDuration(strings.TrimSpace(v)); err == nil {
+			return d
+		}
+		if i, err := strconv.Atoi(strings.TrimSpace(v)); err == nil {
+			return time.Duration(i) * time.Second
+		}
+	}
+	return 0
+}
+
+func getStringMapField(norm *normalizedConfig, canonical string) map[string]string {
+	val, ok := norm.pullValue(canonical)
+	if !ok || val == nil {
+		return nil
+	}
+	result := map[string]string{}
+	switch v := val.(type) {
+	case map[string]any:
+		for key, value := range v {
+			result[key] = strings.TrimSpace(fmt.Sprint(value))
+		}
+	case map[string]string:
+		for key, value := range v {
+			result[key] = strings.TrimSpace(value)
+		}
+	case []any:
+		for _, item := range v {
+			switch entry := item.(type) {
+			case string:
+				k, val := splitKeyValue(entry)
+				if k != "" {
+					result[k] = val
+				}
+			case map[string]any:
+				for key, value := range entry {
+					result[key] = strings.TrimSpace(fmt.Sprint(value))
+				}
+			}
+		}
+	case string:
+		pairs := strings.FieldsFunc(v, func(r rune) bool { return r == ';' || r == ',' || r == '\n' })
+		for _, pair := range pairs {
+			k, val := splitKeyValue(pair)
+			if k != "" {
+				result[k] = val
+			}
+		}
+	}
+	if len(result) == 0 {
+		return nil
+	}
+	return result
+}
+
+func getObjectField(norm *normalizedConfig, canonical string) map[string]any {
+	val, ok := norm.pullValue(canonical)
+	if !ok || val == nil {
+		return nil
+	}
+	return normalizeObject(val)
+}
+
+func mergeAdditional(base map[string]any, extras map[string]any, overwrite bool) map[string]any {
+	if len(extras) == 0 {
+		return base
+	}
+	if base == nil {
+		base = make(map[string]any, len(extras))
+	}
+	for k, v := range extras {
+		if !overwrite {
+			if _, exists := base[k]; exists {
+				continue
+			}
+		}
+		base[k] = v
+	}
+	return base
+}
+
+func normalizeObject(val any) map[string]any {
+	switch v := val.(type) {
+	case map[string]any:
+		return v
+	case map[string]string:
+		result := make(map[string]any, len(v))
+		for k, value := range v {
+			result[k] = value
+		}
+		return result
+	case string:
+		trimmed := strings.TrimSpace(v)
+		if trimmed == "" {
+			return nil
+		}
+		var decoded map[string]any
+		if err := json.Unmarshal([]byte(trimmed), &decoded); err == nil {
+			return decoded
+		}
+	}
+	return nil
+}
+
+func splitKeyValue(input string) (string, string) {
+	if strings.Contains(input, "=") {
+		parts := strings.SplitN(input, "=", 2)
+		return strings.TrimSpace(parts[0]), strings.TrimSpace(parts[1])
+	}
+	if strings.Contains(input, ":") {
+		parts := strings.SplitN(input, ":", 2)
+		return strings.TrimSpace(parts[0]), strings.TrimSpace(parts[1])
+	}
+	return "", ""
+}
+
+func ensureStringMap(input map[string]string) map[string]string {
+	if input != nil {
+		return input
+	}
+	return map[string]string{}
+}
+
+func getAttachments(norm *normalizedConfig, canonical string) ([]Attachment, error) {
+	val, ok := norm.pullValue(canonical)
+	if !ok || val == nil {
+		return nil, nil
+	}
+	switch v := val.(type) {
+	case string:
+		return []Attachment{{Source: strings.TrimSpace(v)}}, nil
+	case []any:
+		attachments := make([]Attachment, 0, len(v))
+		for _, item := range v {
+			att, err := normalizeAttachmentItem(item)
+			if err != nil {
+				return nil, err
+			}
+			if att.Source != "" {
+				attachments = append(attachments, att)
+			}
+		}
+		return attachments, nil
+	case map[string]any:
+		var attachments []Attachment
+		for _, item := range v {
+			att, err := normalizeAttachmentItem(item)
+			if err != nil {
+				return nil, err
+			}
+			if att.Source != "" {
+				attachments = append(attachments, att)
+			}
+		}
+		return attachments, nil
+	default:
+		att, err := normalizeAttachmentItem(v)
+		return []Attachment{att}, err
+	}
+}
+
+func normalizeAttachmentItem(item any) (Attachment, error) {
+	switch v := item.(type) {
+	case string:
+		return Attachment{Source: strings.TrimSpace(v)}, nil
+	case map[string]any:
+		att := Attachment{}
+		if source := firstString(v, "source", "path", "file", "filepath", "url"); source != "" {
+			att.Source = source
+		}
+		if name := firstString(v, "name", "filename", "label"); name != "" {
+			att.Name = name
+		}
+		if mime := firstString(v, "content_type", "mimetype", "mime"); mime != "" {
+			att.MIMEType = mime
+		}
+		if cid := firstString(v, "cid", "content_id"); cid != "" {
+			att.ContentID = cid
+		}
+		if inlineRaw, ok := v["inline"]; ok {
+			att.Inline = normalizeBool(inlineRaw)
+		}
+		if att.Source == "" {
+			return att, errors.New("attachment entry missing source")
+		}
+		return att, nil
+	default:
+		return Attachment{}, fmt.Errorf("unsupported attachment format %T", item)
+	}
+}
+
+func firstString(values map[string]any, keys ...string) string {
+	for _, key := range keys {
+		if raw, ok := values[key]; ok {
+			switch v := raw.(type) {
+			case string:
+				if trimmed := strings.TrimSpace(v); trimmed != "" {
+					return trimmed
+				}
+			}
+		}
+	}
+	return ""
+}
+
+func splitAddress(value string) (string, string) {
+	if strings.TrimSpace(value) == "" {
+		return "", ""
+	}
+	addr, err := mail.ParseAddress(value)
+	if err != nil {
+		return "", strings.TrimSpace(value)
+	}
+	return addr.Name, addr.Address
+}
+
+func looksLikeHTML(body string) bool {
+	body = strings.TrimSpace(body)
+	return strings.HasPrefix(body, "<") && strings.Contains(body, ">")
+}
+
+func looksLikeURL(value string) bool {
+	lower := strings.ToLower(value)
+	return strings.HasPrefix(lower, "http://") || strings.HasPrefix(lower, "https://")
+}
@@ -0,0 +1,307 @@
+package mailer
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/charmbracelet/log"
+	"github.com/oarkflow/releaser/internal/httpclient"
+)
+
+// httpClientCache holds a client per host and connection settings, so that
+// repeated sends reuse connections
+var (
+	httpClientMu    sync.Mutex
+	httpClientCache = map[string]*http.Client{}
+)
+
+func sendViaHTTP(ctx context.Context, cfg *EmailConfig) error {
+	endpoint := cfg.Endpoint
+	if endpoint == "" {
+		return errors.New("http endpoint is required")
+	}
+	if len(cfg.QueryParams) > 0 {
+		if parsed, err := url.Parse(endpoint); err == nil {
+			query := parsed.Query()
+			for k, v := range cfg.QueryParams {
+				query.Set(k, v)
+			}
+			parsed.RawQuery = query.Encode()
+			endpoint = parsed.String()
+		}
+	}
+
+	payload, hintedType, err := cfg.resolveHTTPPayload(ctx)
+	if err != nil {
+		return err
+	}
+	bodyBytes, finalType, err := encodePayload(payload, hintedType)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, cfg.HTTPMethod, endpoint, bytes.NewReader(bodyBytes))
+	if err != nil {
+		return err
+	}
+	if len(cfg.Headers) == 0 {
+		cfg.Headers = map[string]string{}
+	}
+	contentTypeSet := false
+	if finalType != "" {
+		req.Header.Set("Content-Type", finalType)
+		contentTypeSet = true
+	}
+	for k, v := range cfg.Headers {
+		if strings.EqualFold(k, "Content-Type") {
+			contentTypeSet = true
+		}
+		req.Header.Set(k, v)
+	}
+	if !contentTypeSet {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	applyAuthHeaders(req, cfg, bodyBytes)
+
+	client := getHTTPClient(cfg)
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		reqID := resp.Header.Get("x-amzn-requestid")
+		if reqID == "" {
+			reqID = resp.Header.Get("x-request-id")
+		}
+		if reqID != "" {
+			return fmt.Errorf("http send failed: %s request_id=%s body=%s", resp.Status, reqID, strings.TrimSpace(string(respBody)))
+		}
+		return fmt.Errorf("http send failed: %s body=%s", resp.Status, strings.TrimSpace(string(respBody)))
+	}
+	if id := resp.Header.Get("x-amzn-requestid"); id != "" {
+		log.Debug("Email accepted", "request_id", id)
+	}
+	return nil
+}
+
+func getHTTPClient(cfg *EmailConfig) *http.Client {
+	key := httpClientKey(cfg)
+	httpClientMu.Lock()
+	if client, ok := httpClientCache[key]; ok {
+		httpClientMu.Unlock()
+		return client
+	}
+	// Start from the shared transport for its proxy settings
+	base, ok := httpclient.Client().Transport.(*http.Transport)
+	if !ok {
+		base = http.DefaultTransport.(*http.Transport)
+	}
+	transport := base.Clone()
+	transport.TLSClientConfig = &tls.Config{RootCAs: httpclient.RootCAs(), InsecureSkipVerify: cfg.SkipTLSVerify}
+	transport.IdleConnTimeout = 90 * time.Second
+	transport.MaxIdleConns = choosePositive(cfg.MaxIdleConns, 200)
+	transport.MaxIdleConnsPerHost = choosePositive(cfg.MaxIdleConnsHost, 32)
+	transport.MaxConnsPerHost = cfg.MaxConnsPerHost
+	transport.DisableKeepAlives = cfg.DisableKeepAlives
+	client := &http.Client{Timeout: cfg.Timeout, Transport: transport}
+	httpClientCache[key] = client
+	httpClientMu.Unlock()
+	return client
+}
+
+func httpClientKey(cfg *EmailConfig) string {
+	host := cfg.Host
+	if cfg.Endpoint != "" {
+		if parsed, err := url.Parse(cfg.Endpoint); err == nil && parsed.Host != "" {
+			host = parsed.Host
+		}
+	}
+	return fmt.Sprintf("host-%s-tls-%t-maxc-%d-idle-%d-idlehost-%d-noka-%t-timeout-%d", host, cfg.SkipTLSVerify, cfg.MaxConnsPerHost, cfg.MaxIdleConns, cfg.MaxIdleConnsHost, cfg.DisableKeepAlives, cfg.Timeout)
+}
+
+func choosePositive(value, fallback int) int {
+	if value > 0 {
+		return value
+	}
+	return fallback
+}
+
+func (cfg *EmailConfig) resolveHTTPPayload(ctx context.Context) (any, string, error) {
+	if cfg.HTTPPayload != nil {
+		return cfg.HTTPPayload, pickContentType(cfg.HTTPContentType, ""), nil
+	}
+	if cfg.PayloadFormat != "" {
+		if builder, ok := httpPayloadBuilders[cfg.PayloadFormat]; ok {
+			payload, contentType, err := builder(ctx, cfg)
+			return payload, pickContentType(cfg.HTTPContentType, contentType), err
+		}
+	}
+	if builder, ok := httpPayloadBuilders[cfg.Provider]; ok {
+		payload, contentType, err := builder(ctx, cfg)
+		return payload, pickContentType(cfg.HTTPContentType, contentType), err
+	}
+	payload, err := buildHTTPPayload(ctx, cfg)
+	return payload, pickContentType(cfg.HTTPContentType, ""), err
+}
+
+func encodePayload(payload any, contentType string) ([]byte, string, error) {
+	switch v := payload.(type) {
+	case nil:
+		return []byte{}, contentType, nil
+	case []byte:
+		if contentType == "" {
+			contentType = "application/octet-stream"
+		}
+		return v, contentType, nil
+	case string:
+		if contentType == "" {
+			contentType = "text/plain"
+		}
+		return []byte(v), contentType, nil
+	case url.Values:
+		if contentType == "" {
+			contentType = "application/x-www-form-urlencoded"
+		}
+		return []byte(v.Encode()), contentType, nil
+	default:
+		data, err := json.Marshal(v)
+		if err != nil {
+			return nil, "", err
+		}
+		if contentType == "" {
+			contentType = "application/json"
+		}
+		return data, contentType, nil
+	}
+}
+
+func pickContentType(primary, fallback string) string {
+	if strings.TrimSpace(primary) != "" {
+		return primary
+	}
+	return fallback
+}
+
+func applyAuthHeaders(req *http.Request, cfg *EmailConfig, body []byte) {
+	token := strings.TrimSpace(cfg.APIToken)
+	apiKey := strings.TrimSpace(cfg.APIKey)
+	if token == "" {
+		token = apiKey
+	}
+
+	// Explicit auth override takes priority.
+	switch cfg.HTTPAuth {
+	case "none":
+		return
+	case "basic":
+		user := cfg.Username
+		pass := cfg.Password
+		if user != "" || pass != "" {
+			req.SetBasicAuth(user, pass)
+			return
+		}
+	case "bearer":
+		if token == "" {
+			break
+		}
+		if req.Header.Get("Authorization") == "" {
+			req.Header.Set("Authorization", strings.TrimSpace(cfg.HTTPAuthPrefix+" "+token))
+		}
+		return
+	case "api_key_header":
+		header := cfg.HTTPAuthHeader
+		if header == "" {
+			header = "X-API-Key"
+		}
+		if token != "" && req.Header.Get(header) == "" {
+			req.Header.Set(header, token)
+		}
+		return
+	case "api_key_query":
+		param := cfg.HTTPAuthQuery
+		if param == "" {
+			param = "api_key"
+		}
+		if token != "" {
+			q := req.URL.Query()
+			if q.Get(param) == "" {
+				q.Set(param, token)
+				req.URL.RawQuery = q.Encode()
+			}
+		}
+		return
+	case "aws_sigv4":
+		if err := signAWSv4(req, body, cfg); err != nil {
+			log.Warn("Failed to sign the request with SigV4", "error", err)
+		}
+		return
+	}
+
+	switch cfg.Provider {
+	case "brevo", "sendinblue":
+		if apiKey == "" {
+			apiKey = token
+		}
+		if apiKey == "" || req.Header.Get("api-key") != "" {
+			return
+		}
+		req.Header.Set("api-key", apiKey)
+		return
+	case "mailgun":
+		if token == "" {
+			return
+		}
+		req.SetBasicAuth("api", token)
+		return
+	case "postmark":
+		if token == "" {
+			return
+		}
+		if req.Header.Get("X-Postmark-Server-Token") == "" {
+			req.Header.Set("X-Postmark-Server-Token", token)
+		}
+		return
+	case "sparkpost":
+		if token == "" {
+			return
+		}
+		if req.Header.Get("Authorization") == "" {
+			req.Header.Set("Authorization", token)
+		}
+		return
+	case "resend":
+		if token == "" {
+			return
+		}
+		if req.Header.Get("Authorization") == "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		return
+	case "ses", "aws_ses", "amazon_ses":
+		if err := signAWSv4(req, body, cfg); err != nil {
+			log.Warn("Failed to sign the request with SigV4", "error", err)
+		}
+		return
+	}
+
+	if token == "" {
+		return
+	}
+	if req.Header.Get("Authorization") != "" {
+		return
+	}
+	req.Header.Set("Authorization", strings.TrimSpace(cfg.HTTPAuthPrefix+" "+token))
+}
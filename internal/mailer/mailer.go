@@ -0,0 +1,191 @@
+/*
+Package mailer sends email over SMTP or the HTTP APIs of providers such as
+SendGrid, Amazon SES, Mailgun, Postmark, SparkPost, Resend, Brevo and
+Mailtrap. An EmailConfig is either filled in directly or parsed from a loose
+JSON style map with ParseConfig, which accepts aliases for most keys and
+expands {{placeholders}}. Provider defaults, HTTP profiles and payload
+builders can be extended with the Register functions.
+*/
+package mailer
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"time"
+
+	"github.com/charmbracelet/log"
+)
+
+// EmailConfig represents the fully normalized configuration.
+type EmailConfig struct {
+	From                string
+	FromName            string
+	EnvelopeFrom        string
+	ReturnPath          string
+	ReplyTo             []string
+	To                  []string
+	CC                  []string
+	BCC                 []string
+	ListUnsubscribe     []string
+	ListUnsubscribePost bool
+	Subject             string
+	Body                string
+	TextBody            string
+	HTMLBody            string
+	Attachments         []Attachment
+	ConfigurationSet    string
+	Tags                map[string]string
+	Provider            string
+	Transport           string
+	Host                string
+	Port                int
+	Username            string
+	Password            string
+	APIKey              string
+	APIToken            string
+	Endpoint            string
+	HTTPMethod          string
+	Headers             map[string]string
+	QueryParams         map[string]string
+	HTTPPayload         map[string]any
+	PayloadFormat       string
+	HTTPContentType     string
+	HTTPAuth            string
+	HTTPAuthHeader      string
+	HTTPAuthQuery       string
+	HTTPAuthPrefix      string
+	MaxConnsPerHost     int
+	MaxIdleConns        int
+	MaxIdleConnsHost    int
+	DisableKeepAlives   bool
+	SMTPAuth            string
+	HTMLTemplatePath    string
+	TextTemplatePath    string
+	BodyTemplatePath    string
+	AdditionalData      map[string]any
+	AWSRegion           string
+	AWSAccessKey        string
+	AWSSecretKey        string
+	AWSSessionToken     string
+	UseTLS              bool
+	UseSSL              bool
+	SkipTLSVerify       bool
+	Timeout             time.Duration
+	RetryCount          int
+	RetryDelay          time.Duration
+}
+
+// Attachment describes a file to be included with the email.
+type Attachment struct {
+	Source    string
+	Name      string
+	MIMEType  string
+	Inline    bool
+	ContentID string
+}
+
+// ProviderSetting captures smart defaults for known providers.
+type ProviderSetting struct {
+	Host      string
+	Port      int
+	UseTLS    bool
+	UseSSL    bool
+	Transport string
+	Endpoint  string
+}
+
+// PayloadBuilder builds the HTTP request body of a provider, returning it
+// with its content type
+type PayloadBuilder func(ctx context.Context, cfg *EmailConfig) (any, string, error)
+
+// HTTPProviderProfile describes the HTTP API of a provider.
+type HTTPProviderProfile struct {
+	Endpoint      string
+	Method        string
+	ContentType   string
+	PayloadFormat string
+	Headers       map[string]string
+}
+
+// Mailer sends the email an EmailConfig describes.
+type Mailer struct {
+	config *EmailConfig
+}
+
+// New creates a mailer, applying provider defaults to cfg and validating
+// it. Template files cfg points at are read into its bodies.
+func New(cfg *EmailConfig) (*Mailer, error) {
+	if err := prepareConfig(cfg); err != nil {
+		return nil, err
+	}
+	return &Mailer{config: cfg}, nil
+}
+
+// Config returns the configuration with defaults applied
+func (m *Mailer) Config() *EmailConfig {
+	return m.config
+}
+
+// Send delivers the email, retrying failed attempts with backoff until the
+// configured retry count is reached or ctx is done.
+func (m *Mailer) Send(ctx context.Context) error {
+	cfg := m.config
+	var lastErr error
+	for attempt := 1; attempt <= cfg.RetryCount; attempt++ {
+		if cfg.Transport == "http" {
+			lastErr = sendViaHTTP(ctx, cfg)
+		} else {
+			lastErr = sendViaSMTP(ctx, cfg)
+		}
+		if lastErr == nil {
+			return nil
+		}
+		if attempt < cfg.RetryCount {
+			delay := backoffDelay(attempt, cfg.RetryDelay)
+			log.Warn("Sending email failed, retrying", "attempt", fmt.Sprintf("%d/%d", attempt, cfg.RetryCount), "delay", delay, "error", lastErr)
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(delay):
+			}
+		}
+	}
+	return lastErr
+}
+
+// Message renders the email as the MIME message sent over SMTP, for dry
+// runs.
+func (m *Mailer) Message(ctx context.Context) ([]byte, error) {
+	msg, err := buildMessage(ctx, m.config)
+	if err != nil {
+		return nil, err
+	}
+	return []byte(msg), nil
+}
+
+func backoffDelay(attempt int, base time.Duration) time.Duration {
+	if base <= 0 {
+		base = 2 * time.Second
+	}
+	factor := 1 << (attempt - 1)
+	delay := time.Duration(factor) * base
+	jitter := time.Duration(rand.Int63n(int64(delay/2) + 1))
+	return delay + jitter
+}
+
+// TransportDetails returns the endpoint or SMTP address the email is sent to
+func (cfg *EmailConfig) TransportDetails() string {
+	if cfg.Transport == "http" {
+		return cfg.Endpoint
+	}
+	return fmt.Sprintf("%s:%d", cfg.Host, cfg.Port)
+}
+
+// ProviderOrHost returns the provider, or the SMTP host without one
+func (cfg *EmailConfig) ProviderOrHost() string {
+	if cfg.Provider != "" {
+		return cfg.Provider
+	}
+	return cfg.Host
+}
@@ -0,0 +1,416 @@
+package mailer
+
+import (
+	"context"
+	cryptorand "crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/quotedprintable"
+	"net/http"
+	"net/mail"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/oarkflow/releaser/internal/httpclient"
+)
+
+// buildMessage renders the MIME message sent over SMTP, and to providers
+// taking raw messages
+func buildMessage(ctx context.Context, cfg *EmailConfig) (string, error) {
+	var msg strings.Builder
+	fromAddr := mail.Address{Name: cfg.FromName, Address: cfg.From}
+	msg.WriteString(fmt.Sprintf("From: %s\r\n", fromAddr.String()))
+	msg.WriteString(fmt.Sprintf("To: %s\r\n", strings.Join(cfg.To, ", ")))
+	if len(cfg.CC) > 0 {
+		msg.WriteString(fmt.Sprintf("Cc: %s\r\n", strings.Join(cfg.CC, ", ")))
+	}
+	if len(cfg.ReplyTo) > 0 {
+		msg.WriteString(fmt.Sprintf("Reply-To: %s\r\n", strings.Join(cfg.ReplyTo, ", ")))
+	}
+	msg.WriteString(fmt.Sprintf("Subject: %s\r\n", mime.QEncoding.Encode("utf-8", cfg.Subject)))
+	msg.WriteString(fmt.Sprintf("Date: %s\r\n", time.Now().Format(time.RFC1123Z)))
+	msg.WriteString(fmt.Sprintf("Message-ID: <%s@%s>\r\n", randomBoundary("msg"), messageDomain(cfg)))
+	msg.WriteString("MIME-Version: 1.0\r\n")
+	if cfg.ReturnPath != "" {
+		msg.WriteString(fmt.Sprintf("Return-Path: %s\r\n", cfg.EnvelopeFrom))
+	}
+	for k, v := range cfg.Headers {
+		if strings.EqualFold(k, "Content-Type") {
+			continue
+		}
+		msg.WriteString(fmt.Sprintf("%s: %s\r\n", k, v))
+	}
+	if len(cfg.ListUnsubscribe) > 0 {
+		msg.WriteString(fmt.Sprintf("List-Unsubscribe: %s\r\n", strings.Join(cfg.ListUnsubscribe, ", ")))
+		if cfg.ListUnsubscribePost {
+			msg.WriteString("List-Unsubscribe-Post: List-Unsubscribe=One-Click\r\n")
+		}
+	}
+	if cfg.ConfigurationSet != "" {
+		msg.WriteString(fmt.Sprintf("X-SES-CONFIGURATION-SET: %s\r\n", cfg.ConfigurationSet))
+	}
+	if len(cfg.Tags) > 0 {
+		var parts []string
+		for k, v := range cfg.Tags {
+			parts = append(parts, fmt.Sprintf("%s=%s", k, v))
+		}
+		sort.Strings(parts)
+		msg.WriteString(fmt.Sprintf("X-SES-MESSAGE-TAGS: %s\r\n", strings.Join(parts, ";")))
+	}
+
+	inline, regular := partitionAttachments(cfg.Attachments)
+	if len(regular) > 0 {
+		mixedBoundary := randomBoundary("mixed")
+		msg.WriteString(fmt.Sprintf("Content-Type: multipart/mixed; boundary=%s\r\n\r\n", mixedBoundary))
+		if err := writeBodySection(ctx, &msg, cfg, inline, mixedBoundary); err != nil {
+			return "", err
+		}
+		for _, att := range regular {
+			if err := writeAttachmentPart(ctx, &msg, att, mixedBoundary, false); err != nil {
+				return "", err
+			}
+		}
+		msg.WriteString(fmt.Sprintf("--%s--\r\n", mixedBoundary))
+		return msg.String(), nil
+	}
+
+	if err := writeBodySection(ctx, &msg, cfg, inline, ""); err != nil {
+		return "", err
+	}
+	return msg.String(), nil
+}
+
+func writeBodySection(ctx context.Context, msg *strings.Builder, cfg *EmailConfig, inline []Attachment, boundary string) error {
+	if boundary != "" {
+		msg.WriteString(fmt.Sprintf("--%s\r\n", boundary))
+	}
+	return writeAlternativeBody(ctx, msg, cfg, inline)
+}
+
+func writeAlternativeBody(ctx context.Context, msg *strings.Builder, cfg *EmailConfig, inline []Attachment) error {
+	hasInline := len(inline) > 0 && cfg.HTMLBody != ""
+	if hasInline && cfg.TextBody != "" {
+		altBoundary := randomBoundary("alt")
+		relatedBoundary := randomBoundary("rel")
+		msg.WriteString(fmt.Sprintf("Content-Type: multipart/alternative; boundary=%s\r\n\r\n", altBoundary))
+		msg.WriteString(fmt.Sprintf("--%s\r\n", altBoundary))
+		if err := writeTextPart(msg, "text/plain", cfg.TextBody); err != nil {
+			return err
+		}
+		msg.WriteString(fmt.Sprintf("--%s\r\n", altBoundary))
+		msg.WriteString(fmt.Sprintf("Content-Type: multipart/related; boundary=%s\r\n\r\n", relatedBoundary))
+		msg.WriteString(fmt.Sprintf("--%s\r\n", relatedBoundary))
+		if err := writeTextPart(msg, "text/html", cfg.HTMLBody); err != nil {
+			return err
+		}
+		for _, att := range inline {
+			if err := writeAttachmentPart(ctx, msg, att, relatedBoundary, true); err != nil {
+				return err
+			}
+		}
+		msg.WriteString(fmt.Sprintf("--%s--\r\n", relatedBoundary))
+		msg.WriteString(fmt.Sprintf("--%s--\r\n", altBoundary))
+		return nil
+	}
+
+	if hasInline {
+		relatedBoundary := randomBoundary("rel")
+		msg.WriteString(fmt.Sprintf("Content-Type: multipart/related; boundary=%s\r\n\r\n", relatedBoundary))
+		msg.WriteString(fmt.Sprintf("--%s\r\n", relatedBoundary))
+		if err := writeTextPart(msg, "text/html", cfg.HTMLBody); err != nil {
+			return err
+		}
+		for _, att := range inline {
+			if err := writeAttachmentPart(ctx, msg, att, relatedBoundary, true); err != nil {
+				return err
+			}
+		}
+		msg.WriteString(fmt.Sprintf("--%s--\r\n", relatedBoundary))
+		return nil
+	}
+
+	if cfg.HTMLBody != "" && cfg.TextBody != "" {
+		altBoundary := randomBoundary("alt")
+		msg.WriteString(fmt.Sprintf("Content-Type: multipart/alternative; boundary=%s\r\n\r\n", altBoundary))
+		msg.WriteString(fmt.Sprintf("--%s\r\n", altBoundary))
+		if err := writeTextPart(msg, "text/plain", cfg.TextBody); err != nil {
+			return err
+		}
+		msg.WriteString(fmt.Sprintf("--%s\r\n", altBoundary))
+		if err := writeTextPart(msg, "text/html", cfg.HTMLBody); err != nil {
+			return err
+		}
+		msg.WriteString(fmt.Sprintf("--%s--\r\n", altBoundary))
+		return nil
+	}
+
+	contentType := "text/plain"
+	body := cfg.TextBody
+	if cfg.HTMLBody != "" {
+		contentType = "text/html"
+		body = cfg.HTMLBody
+	}
+	return writeTextPart(msg, contentType, body)
+}
+
+// writeTextPart writes a text part quoted-printable encoded, which keeps
+// long lines and 8-bit text intact over SMTP
+func writeTextPart(msg *strings.Builder, contentType, body string) error {
+	msg.WriteString(fmt.Sprintf("Content-Type: %s; charset=UTF-8\r\n", contentType))
+	msg.WriteString("Content-Transfer-Encoding: quoted-printable\r\n\r\n")
+	qp := quotedprintable.NewWriter(msg)
+	if _, err := qp.Write([]byte(body)); err != nil {
+		return err
+	}
+	if err := qp.Close(); err != nil {
+		return err
+	}
+	msg.WriteString("\r\n\r\n")
+	return nil
+}
+
+// messageDomain returns the domain of Message-IDs, the sender's
+func messageDomain(cfg *EmailConfig) string {
+	if at := strings.LastIndex(cfg.From, "@"); at >= 0 && at < len(cfg.From)-1 {
+		return cfg.From[at+1:]
+	}
+	if cfg.Host != "" {
+		return cfg.Host
+	}
+	return "localhost"
+}
+
+func writeAttachmentPart(ctx context.Context, msg *strings.Builder, att Attachment, boundary string, inline bool) error {
+	data, filename, mimeType, err := loadAttachment(ctx, att)
+	if err != nil {
+		return err
+	}
+	msg.WriteString(fmt.Sprintf("--%s\r\n", boundary))
+	msg.WriteString(fmt.Sprintf("Content-Type: %s\r\n", mimeType))
+	disposition := "attachment"
+	if inline {
+		disposition = "inline"
+	}
+	msg.WriteString(fmt.Sprintf("Content-Disposition: %s; filename=\"%s\"\r\n", disposition, filename))
+	if inline {
+		cid := att.ContentID
+		if cid == "" {
+			cid = filename
+		}
+		if !strings.HasPrefix(cid, "<") {
+			cid = "<" + cid + ">"
+		}
+		msg.WriteString(fmt.Sprintf("Content-ID: %s\r\n", cid))
+	}
+	msg.WriteString("Content-Transfer-Encoding: base64\r\n\r\n")
+	encoded := base64.StdEncoding.EncodeToString(data)
+	for i := 0; i < len(encoded); i += 76 {
+		end := i + 76
+		if end > len(encoded) {
+			end = len(encoded)
+		}
+		msg.WriteString(encoded[i:end])
+		msg.WriteString("\r\n")
+	}
+	msg.WriteString("\r\n")
+	return nil
+}
+
+type encodedAttachment struct {
+	Filename  string
+	MIMEType  string
+	Content   string
+	Inline    bool
+	ContentID string
+}
+
+func loadAttachment(ctx context.Context, att Attachment) ([]byte, string, string, error) {
+	source := strings.TrimSpace(att.Source)
+	if source == "" {
+		return nil, "", "", errors.New("attachment source is empty")
+	}
+	if strings.HasPrefix(source, "data:") {
+		return decodeDataURI(source, att)
+	}
+	if looksLikeURL(source) {
+		data, name, err := downloadFile(ctx, source)
+		if err != nil {
+			return nil, "", "", err
+		}
+		mimeType := att.MIMEType
+		if mimeType == "" {
+			mimeType = detectMIMEType(name, data)
+		}
+		return data, name, mimeType, nil
+	}
+	data, err := os.ReadFile(source)
+	if err != nil {
+		return nil, "", "", err
+	}
+	filename := att.Name
+	if filename == "" {
+		filename = filepath.Base(source)
+	}
+	mimeType := att.MIMEType
+	if mimeType == "" {
+		mimeType = detectMIMEType(filename, data)
+	}
+	return data, filename, mimeType, nil
+}
+
+func decodeDataURI(uri string, att Attachment) ([]byte, string, string, error) {
+	parts := strings.SplitN(uri, ",", 2)
+	if len(parts) != 2 {
+		return nil, "", "", fmt.Errorf("invalid data URI for attachment")
+	}
+	meta := parts[0]
+	dataPart := parts[1]
+	var data []byte
+	var err error
+	if strings.HasSuffix(meta, ";base64") {
+		data, err = base64.StdEncoding.DecodeString(dataPart)
+		if err != nil {
+			return nil, "", "", err
+		}
+	} else {
+		decoded, decErr := url.QueryUnescape(dataPart)
+		if decErr != nil {
+			return nil, "", "", decErr
+		}
+		data = []byte(decoded)
+	}
+	mimeType := att.MIMEType
+	if mimeType == "" {
+		mimeType = strings.TrimPrefix(meta, "data:")
+		mimeType = strings.TrimSuffix(mimeType, ";base64")
+	}
+	name := att.Name
+	if name == "" {
+		name = "attachment.bin"
+	}
+	return data, name, mimeType, nil
+}
+
+func encodeAttachment(ctx context.Context, att Attachment) (map[string]string, error) {
+	data, filename, mimeType, err := loadAttachment(ctx, att)
+	if err != nil {
+		return nil, err
+	}
+	return map[string]string{
+		"filename":     filename,
+		"content":      base64.StdEncoding.EncodeToString(data),
+		"content_type": mimeType,
+	}, nil
+}
+
+func encodeAllAttachments(ctx context.Context, cfg *EmailConfig) ([]encodedAttachment, error) {
+	if len(cfg.Attachments) == 0 {
+		return nil, nil
+	}
+	result := make([]encodedAttachment, 0, len(cfg.Attachments))
+	for _, att := range cfg.Attachments {
+		data, filename, mimeType, err := loadAttachment(ctx, att)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, encodedAttachment{
+			Filename:  filename,
+			MIMEType:  mimeType,
+			Content:   base64.StdEncoding.EncodeToString(data),
+			Inline:    att.Inline,
+			ContentID: att.ContentID,
+		})
+	}
+	return result, nil
+}
+
+func partitionAttachments(list []Attachment) (inline []Attachment, regular []Attachment) {
+	for _, att := range list {
+		if att.Inline {
+			inline = append(inline, att)
+			continue
+		}
+		regular = append(regular, att)
+	}
+	return inline, regular
+}
+
+func detectMIMEType(filename string, data []byte) string {
+	if ext := filepath.Ext(filename); ext != "" {
+		if mt := mime.TypeByExtension(ext); mt != "" {
+			return mt
+		}
+	}
+	if len(data) == 0 {
+		return "application/octet-stream"
+	}
+	return http.DetectContentType(data)
+}
+
+func downloadFile(ctx context.Context, link string) ([]byte, string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, link, nil)
+	if err != nil {
+		return nil, "", err
+	}
+	resp, err := httpclient.Client().Do(req)
+	if err != nil {
+		return nil, "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, "", fmt.Errorf("failed to download %s: %s", link, resp.Status)
+	}
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, "", err
+	}
+	filename := filenameFromURL(link)
+	if disp := resp.Header.Get("Content-Disposition"); disp != "" {
+		if name := parseFilenameFromDisposition(disp); name != "" {
+			filename = name
+		}
+	}
+	return data, filename, nil
+}
+
+func filenameFromURL(link string) string {
+	parsed, err := url.Parse(link)
+	if err != nil {
+		return "attachment"
+	}
+	segments := strings.Split(strings.Trim(parsed.Path, "/"), "/")
+	if len(segments) == 0 {
+		return "attachment"
+	}
+	if segments[len(segments)-1] == "" {
+		return "attachment"
+	}
+	return segments[len(segments)-1]
+}
+
+func parseFilenameFromDisposition(header string) string {
+	parts := strings.Split(header, ";")
+	for _, part := range parts {
+		part = strings.TrimSpace(part)
+		if strings.HasPrefix(strings.ToLower(part), "filename=") {
+			return strings.Trim(part[len("filename="):], "\"")
+		}
+	}
+	return ""
+}
+
+func randomBoundary(prefix string) string {
+	buf := make([]byte, 12)
+	if _, err := cryptorand.Read(buf); err != nil {
+		return fmt.Sprintf("%s-%d", prefix, time.Now().UnixNano())
+	}
+	return fmt.Sprintf("%s-%s", prefix, hex.EncodeToString(buf))
+}
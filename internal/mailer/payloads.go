@@ -0,0 +1,521 @@
+package mailer
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// buildHTTPPayload builds the generic JSON body of HTTP endpoints without
+// a payload builder
+func buildHTTPPayload(ctx context.Context, cfg *EmailConfig) (map[string]any, error) {
+	payload := map[string]any{
+		"from":        cfg.From,
+		"from_name":   cfg.FromName,
+		"reply_to":    cfg.ReplyTo,
+		"to":          cfg.To,
+		"cc":          cfg.CC,
+		"bcc":         cfg.BCC,
+		"subject":     cfg.Subject,
+		"text_body":   cfg.TextBody,
+		"html_body":   cfg.HTMLBody,
+		"provider":    cfg.Provider,
+		"attachments": []map[string]string{},
+	}
+
+	if len(cfg.Attachments) > 0 {
+		files := make([]map[string]string, 0, len(cfg.Attachments))
+		for _, att := range cfg.Attachments {
+			encoded, err := encodeAttachment(ctx, att)
+			if err != nil {
+				return nil, err
+			}
+			files = append(files, encoded)
+		}
+		payload["attachments"] = files
+	}
+
+	payload = mergeAdditional(payload, cfg.AdditionalData, false)
+
+	return payload, nil
+}
+
+// buildSendGridPayload builds a SendGrid v3 mail send request
+func buildSendGridPayload(ctx context.Context, cfg *EmailConfig) (any, string, error) {
+	personalization := map[string]any{
+		"to": addressMaps(parseAddressList(cfg.To), "email", "name"),
+	}
+	if len(cfg.CC) > 0 {
+		personalization["cc"] = addressMaps(parseAddressList(cfg.CC), "email", "name")
+	}
+	if len(cfg.BCC) > 0 {
+		personalization["bcc"] = addressMaps(parseAddressList(cfg.BCC), "email", "name")
+	}
+
+	fromName, fromEmail := splitAddress(cfg.From)
+	fromEntry := singleAddressMap(simpleAddress{Name: fromName, Email: fromEmail}, "email", "name")
+	contents := make([]map[string]string, 0, 2)
+	if cfg.TextBody != "" {
+		contents = append(contents, map[string]string{"type": "text/plain", "value": cfg.TextBody})
+	}
+	if cfg.HTMLBody != "" {
+		contents = append(contents, map[string]string{"type": "text/html", "value": cfg.HTMLBody})
+	}
+	if len(contents) == 0 {
+		contents = append(contents, map[string]string{"type": "text/plain", "value": fallbackBody(cfg.TextBody)})
+	}
+
+	payload := map[string]any{
+		"personalizations": []any{personalization},
+		"from":             fromEntry,
+		"subject":          cfg.Subject,
+		"content":          contents,
+	}
+	if reply := firstAddressEntry(cfg.ReplyTo); reply.Email != "" {
+		payload["reply_to"] = singleAddressMap(reply, "email", "name")
+	}
+	encoded, err := encodeAllAttachments(ctx, cfg)
+	if err != nil {
+		return nil, "", err
+	}
+	if len(encoded) > 0 {
+		attachments := make([]map[string]string, 0, len(encoded))
+		for _, att := range encoded {
+			entry := map[string]string{
+				"content":  att.Content,
+				"type":     att.MIMEType,
+				"filename": att.Filename,
+			}
+			if att.Inline {
+				entry["disposition"] = "inline"
+				if att.ContentID != "" {
+					entry["content_id"] = att.ContentID
+				}
+			}
+			attachments = append(attachments, entry)
+		}
+		payload["attachments"] = attachments
+	}
+	payload = mergeAdditional(payload, cfg.AdditionalData, true)
+	return payload, "application/json", nil
+}
+
+// buildMailtrapPayload builds a Mailtrap send API request
+func buildMailtrapPayload(ctx context.Context, cfg *EmailConfig) (any, string, error) {
+	fromName, fromEmail := splitAddress(cfg.From)
+	sender := singleAddressMap(simpleAddress{Name: fromName, Email: fromEmail}, "email", "name")
+	payload := map[string]any{
+		"from":    sender,
+		"to":      addressMaps(parseAddressList(cfg.To), "email", "name"),
+		"subject": cfg.Subject,
+		"text":    fallbackBody(cfg.TextBody),
+		"html":    cfg.HTMLBody,
+	}
+	if len(cfg.CC) > 0 {
+		payload["cc"] = addressMaps(parseAddressList(cfg.CC), "email", "name")
+	}
+	if len(cfg.BCC) > 0 {
+		payload["bcc"] = addressMaps(parseAddressList(cfg.BCC), "email", "name")
+	}
+	if reply := firstAddressEntry(cfg.ReplyTo); reply.Email != "" {
+		payload["reply_to"] = singleAddressMap(reply, "email", "name")
+	}
+	encoded, err := encodeAllAttachments(ctx, cfg)
+	if err != nil {
+		return nil, "", err
+	}
+	if len(encoded) > 0 {
+		attachments := make([]map[string]string, 0, len(encoded))
+		for _, att := range encoded {
+			entry := map[string]string{
+				"content":  att.Content,
+				"type":     att.MIMEType,
+				"filename": att.Filename,
+			}
+			if att.Inline {
+				entry["disposition"] = "inline"
+				if att.ContentID != "" {
+					entry["content_id"] = att.ContentID
+				}
+			}
+			attachments = append(attachments, entry)
+		}
+		payload["attachments"] = attachments
+	}
+	payload = mergeAdditional(payload, cfg.AdditionalData, true)
+	return payload, "application/json", nil
+}
+
+// buildBrevoPayload builds a Brevo, formerly Sendinblue, transactional
+// email request
+func buildBrevoPayload(ctx context.Context, cfg *EmailConfig) (any, string, error) {
+	fromName, fromEmail := splitAddress(cfg.From)
+	sender := singleAddressMap(simpleAddress{Name: fromName, Email: fromEmail}, "email", "name")
+	payload := map[string]any{
+		"sender":      sender,
+		"to":          addressMaps(parseAddressList(cfg.To), "email", "name"),
+		"subject":     cfg.Subject,
+		"textContent": fallbackBody(cfg.TextBody),
+		"htmlContent": cfg.HTMLBody,
+	}
+	if len(cfg.CC) > 0 {
+		payload["cc"] = addressMaps(parseAddressList(cfg.CC), "email", "name")
+	}
+	if len(cfg.BCC) > 0 {
+		payload["bcc"] = addressMaps(parseAddressList(cfg.BCC), "email", "name")
+	}
+	if reply := firstAddressEntry(cfg.ReplyTo); reply.Email != "" {
+		payload["replyTo"] = singleAddressMap(reply, "email", "name")
+	}
+	encoded, err := encodeAllAttachments(ctx, cfg)
+	if err != nil {
+		return nil, "", err
+	}
+	if len(encoded) > 0 {
+		attachments := make([]map[string]string, 0, len(encoded))
+		for _, att := range encoded {
+			entry := map[string]string{
+				"name":    att.Filename,
+				"content": att.Content,
+			}
+			if att.Inline {
+				entry["disposition"] = "inline"
+				if att.ContentID != "" {
+					entry["contentId"] = att.ContentID
+				}
+			}
+			attachments = append(attachments, entry)
+		}
+		payload["attachment"] = attachments
+	}
+	payload = mergeAdditional(payload, cfg.AdditionalData, true)
+	return payload, "application/json", nil
+}
+
+// buildSESPayload builds an Amazon SES v2 request sending the raw MIME
+// message
+func buildSESPayload(ctx context.Context, cfg *EmailConfig) (any, string, error) {
+	raw, err := buildMessage(ctx, cfg)
+	if err != nil {
+		return nil, "", err
+	}
+	dest := map[string][]string{}
+	if len(cfg.To) > 0 {
+		dest["ToAddresses"] = cfg.To
+	}
+	if len(cfg.CC) > 0 {
+		dest["CcAddresses"] = cfg.CC
+	}
+	if len(cfg.BCC) > 0 {
+		dest["BccAddresses"] = cfg.BCC
+	}
+	payload := map[string]any{
+		"Content": map[string]any{
+			"Raw": map[string]string{
+				"Data": base64.StdEncoding.EncodeToString([]byte(raw)),
+			},
+		},
+	}
+	if len(dest) > 0 {
+		payload["Destination"] = dest
+	}
+	if cfg.From != "" {
+		payload["FromEmailAddress"] = cfg.From
+	}
+	if cfg.ConfigurationSet != "" {
+		payload["ConfigurationSetName"] = cfg.ConfigurationSet
+	}
+	if len(cfg.Tags) > 0 {
+		tags := make([]map[string]string, 0, len(cfg.Tags))
+		for k, v := range cfg.Tags {
+			tags = append(tags, map[string]string{"Name": k, "Value": v})
+		}
+		sort.Slice(tags, func(i, j int) bool { return tags[i]["Name"] < tags[j]["Name"] })
+		payload["EmailTags"] = tags
+	}
+	return payload, "application/json", nil
+}
+
+// buildPostmarkPayload builds a Postmark email request
+func buildPostmarkPayload(ctx context.Context, cfg *EmailConfig) (any, string, error) {
+	payload := map[string]any{
+		"From":    cfg.From,
+		"To":      strings.Join(cfg.To, ","),
+		"Subject": cfg.Subject,
+	}
+	if len(cfg.CC) > 0 {
+		payload["Cc"] = strings.Join(cfg.CC, ",")
+	}
+	if len(cfg.BCC) > 0 {
+		payload["Bcc"] = strings.Join(cfg.BCC, ",")
+	}
+	if cfg.TextBody != "" {
+		payload["TextBody"] = fallbackBody(cfg.TextBody)
+	}
+	if cfg.HTMLBody != "" {
+		payload["HtmlBody"] = cfg.HTMLBody
+	}
+	if reply := firstAddressEntry(cfg.ReplyTo); reply.Email != "" {
+		payload["ReplyTo"] = reply.Email
+	}
+	if len(cfg.Headers) > 0 {
+		var headers []map[string]string
+		for k, v := range cfg.Headers {
+			headers = append(headers, map[string]string{"Name": k, "Value": v})
+		}
+		payload["Headers"] = headers
+	}
+	encoded, err := encodeAllAttachments(ctx, cfg)
+	if err != nil {
+		return nil, "", err
+	}
+	if len(encoded) > 0 {
+		attachments := make([]map[string]string, 0, len(encoded))
+		for _, att := range encoded {
+			entry := map[string]string{
+				"Name":        att.Filename,
+				"Content":     att.Content,
+				"ContentType": att.MIMEType,
+			}
+			if att.ContentID != "" {
+				entry["ContentID"] = att.ContentID
+			}
+			if att.Inline {
+				entry["ContentDisposition"] = "inline"
+			}
+			attachments = append(attachments, entry)
+		}
+		payload["Attachments"] = attachments
+	}
+	return payload, "application/json", nil
+}
+
+// buildSparkPostPayload builds a SparkPost transmission
+func buildSparkPostPayload(ctx context.Context, cfg *EmailConfig) (any, string, error) {
+	encoded, err := encodeAllAttachments(ctx, cfg)
+	if err != nil {
+		return nil, "", err
+	}
+	inlineImages := []map[string]string{}
+	attachments := []map[string]string{}
+	for _, att := range encoded {
+		entry := map[string]string{
+			"type": att.MIMEType,
+			"name": att.Filename,
+			"data": att.Content,
+		}
+		if att.Inline {
+			if att.ContentID != "" {
+				entry["name"] = att.ContentID
+			}
+			inlineImages = append(inlineImages, entry)
+		} else {
+			attachments = append(attachments, entry)
+		}
+	}
+	fromName, fromEmail := splitAddress(cfg.From)
+	if fromName == "" {
+		fromName = cfg.FromName
+	}
+	content := map[string]any{
+		"from":    singleAddressMap(simpleAddress{Name: fromName, Email: fromEmail}, "email", "name"),
+		"subject": cfg.Subject,
+		"text":    fallbackBody(cfg.TextBody),
+	}
+	if cfg.HTMLBody != "" {
+		content["html"] = cfg.HTMLBody
+	}
+	if len(attachments) > 0 {
+		content["attachments"] = attachments
+	}
+	if len(inlineImages) > 0 {
+		content["inline_images"] = inlineImages
+	}
+	to := parseAddressList(cfg.To)
+	recipients := make([]map[string]any, 0, len(to))
+	for _, addr := range to {
+		recipients = append(recipients, map[string]any{
+			"address": singleAddressMap(addr, "email", "name"),
+		})
+	}
+	payload := map[string]any{
+		"recipients": recipients,
+		"content":    content,
+	}
+	if len(cfg.Tags) > 0 {
+		var tags []string
+		for k := range cfg.Tags {
+			tags = append(tags, k)
+		}
+		sort.Strings(tags)
+		payload["metadata"] = cfg.Tags
+		payload["description"] = strings.Join(tags, ",")
+	}
+	return payload, "application/json", nil
+}
+
+// buildResendPayload builds a Resend email request
+func buildResendPayload(ctx context.Context, cfg *EmailConfig) (any, string, error) {
+	payload := map[string]any{
+		"from":    cfg.From,
+		"to":      cfg.To,
+		"subject": cfg.Subject,
+		"text":    fallbackBody(cfg.TextBody),
+		"html":    cfg.HTMLBody,
+	}
+	if len(cfg.CC) > 0 {
+		payload["cc"] = cfg.CC
+	}
+	if len(cfg.BCC) > 0 {
+		payload["bcc"] = cfg.BCC
+	}
+	if reply := firstAddressEntry(cfg.ReplyTo); reply.Email != "" {
+		payload["reply_to"] = []string{reply.Email}
+	}
+	encoded, err := encodeAllAttachments(ctx, cfg)
+	if err != nil {
+		return nil, "", err
+	}
+	if len(encoded) > 0 {
+		attachments := make([]map[string]string, 0, len(encoded))
+		for _, att := range encoded {
+			entry := map[string]string{
+				"filename":     att.Filename,
+				"content":      att.Content,
+				"content_type": att.MIMEType,
+			}
+			if att.ContentID != "" {
+				entry["cid"] = att.ContentID
+			}
+			if att.Inline {
+				entry["disposition"] = "inline"
+			}
+			attachments = append(attachments, entry)
+		}
+		payload["attachments"] = attachments
+	}
+	return payload, "application/json", nil
+}
+
+// buildMailgunPayload builds a Mailgun messages form, completing the
+// endpoint with the sending domain
+func buildMailgunPayload(ctx context.Context, cfg *EmailConfig) (any, string, error) {
+	if len(cfg.Attachments) > 0 {
+		return nil, "", errors.New("mailgun http builder does not support attachments; use SMTP or raw payload")
+	}
+	domain := strings.TrimSpace(firstString(cfg.AdditionalData, "domain", "mailgun_domain"))
+	inferred := inferMailgunDomain(cfg.Endpoint)
+	if domain == "" {
+		domain = inferred
+	}
+	if domain == "" {
+		return nil, "", errors.New("mailgun domain is required (set 'domain' in payload)")
+	}
+	if cfg.Endpoint != "" && !strings.Contains(cfg.Endpoint, "/messages") {
+		// The endpoint may name the domain already, e.g. /v3/example.com
+		if inferred == "" {
+			cfg.Endpoint = strings.TrimRight(cfg.Endpoint, "/") + "/" + domain
+		}
+		cfg.Endpoint = strings.TrimRight(cfg.Endpoint, "/") + "/messages"
+	}
+	form := url.Values{}
+	fromAddr := cfg.From
+	if cfg.FromName != "" {
+		fromAddr = fmt.Sprintf("%s <%s>", cfg.FromName, cfg.From)
+	}
+	form.Set("from", fromAddr)
+	for _, to := range cfg.To {
+		form.Add("to", to)
+	}
+	for _, cc := range cfg.CC {
+		form.Add("cc", cc)
+	}
+	for _, bcc := range cfg.BCC {
+		form.Add("bcc", bcc)
+	}
+	if reply := firstAddressEntry(cfg.ReplyTo); reply.Email != "" {
+		form.Set("h:Reply-To", reply.Email)
+	}
+	form.Set("subject", cfg.Subject)
+	if cfg.TextBody != "" {
+		form.Set("text", fallbackBody(cfg.TextBody))
+	}
+	if cfg.HTMLBody != "" {
+		form.Set("html", cfg.HTMLBody)
+	}
+	return form, "application/x-www-form-urlencoded", nil
+}
+
+func inferMailgunDomain(endpoint string) string {
+	parsed, err := url.Parse(endpoint)
+	if err != nil {
+		return ""
+	}
+	segments := strings.Split(strings.Trim(parsed.Path, "/"), "/")
+	for i, segment := range segments {
+		if strings.EqualFold(segment, "v3") && i+1 < len(segments) {
+			return segments[i+1]
+		}
+	}
+	return ""
+}
+
+type simpleAddress struct {
+	Name  string
+	Email string
+}
+
+func parseAddressList(values []string) []simpleAddress {
+	var result []simpleAddress
+	for _, raw := range values {
+		name, addr := splitAddress(raw)
+		addr = strings.TrimSpace(addr)
+		if addr == "" {
+			continue
+		}
+		result = append(result, simpleAddress{Name: strings.TrimSpace(name), Email: addr})
+	}
+	return result
+}
+
+func firstAddressEntry(values []string) simpleAddress {
+	list := parseAddressList(values)
+	if len(list) == 0 {
+		return simpleAddress{}
+	}
+	return list[0]
+}
+
+func addressMaps(addresses []simpleAddress, emailKey, nameKey string) []map[string]string {
+	result := make([]map[string]string, 0, len(addresses))
+	for _, addr := range addresses {
+		entry := map[string]string{emailKey: addr.Email}
+		if addr.Name != "" {
+			entry[nameKey] = addr.Name
+		}
+		result = append(result, entry)
+	}
+	return result
+}
+
+func singleAddressMap(addr simpleAddress, emailKey, nameKey string) map[string]string {
+	if addr.Email == "" {
+		return nil
+	}
+	entry := map[string]string{emailKey: addr.Email}
+	if addr.Name != "" {
+		entry[nameKey] = addr.Name
+	}
+	return entry
+}
+
+func fallbackBody(value string) string {
+	if strings.TrimSpace(value) == "" {
+		return "(empty message)"
+	}
+	return value
+}
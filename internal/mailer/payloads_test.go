@@ -0,0 +1,364 @@
+package mailer
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// testEmail returns a message with every field the payload builders map,
+// a file attachment and an inline image
+func testEmail(t *testing.T) *EmailConfig {
+	t.Helper()
+	dir := t.TempDir()
+	for name, content := range map[string]string{"notes.txt": "hello", "logo.png": "png"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return &EmailConfig{
+		From:     "bot@example.com",
+		FromName: "Release Bot",
+		ReplyTo:  []string{"Support <support@example.com>"},
+		To:       []string{"Jane Doe <jane@example.com>", "ops@example.com"},
+		CC:       []string{"cc@example.com"},
+		BCC:      []string{"bcc@example.com"},
+		Subject:  "demo v1.2.3 released",
+		TextBody: "Notes",
+		HTMLBody: "<p>Notes</p>",
+		Headers:  map[string]string{"X-Release": "v1.2.3"},
+		Tags:     map[string]string{"release": "v1.2.3"},
+		Attachments: []Attachment{
+			{Source: filepath.Join(dir, "notes.txt"), MIMEType: "text/plain"},
+			{Source: filepath.Join(dir, "logo.png"), MIMEType: "image/png", Inline: true, ContentID: "logo"},
+		},
+	}
+}
+
+// normalizeJSON re-encodes a JSON document so that key order and spacing
+// don't matter
+func normalizeJSON(t *testing.T, v any) string {
+	t.Helper()
+	if s, ok := v.(string); ok {
+		var doc any
+		if err := json.Unmarshal([]byte(s), &doc); err != nil {
+			t.Fatalf("invalid JSON %s: %v", s, err)
+		}
+		v = doc
+	}
+	// Maps are marshaled with sorted keys
+	data, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func TestJSONPayloads(t *testing.T) {
+	tests := []struct {
+		name   string
+		build  PayloadBuilder
+		modify func(*EmailConfig)
+		want   string
+	}{
+		{
+			name:  "sendgrid",
+			build: buildSendGridPayload,
+			want: `{
+				"personalizations": [{
+					"to": [{"email": "jane@example.com", "name": "Jane Doe"}, {"email": "ops@example.com"}],
+					"cc": [{"email": "cc@example.com"}],
+					"bcc": [{"email": "bcc@example.com"}]
+				}],
+				"from": {"email": "bot@example.com"},
+				"reply_to": {"email": "support@example.com", "name": "Support"},
+				"subject": "demo v1.2.3 released",
+				"content": [{"type": "text/plain", "value": "Notes"}, {"type": "text/html", "value": "<p>Notes</p>"}],
+				"attachments": [
+					{"content": "aGVsbG8=", "type": "text/plain", "filename": "notes.txt"},
+					{"content": "cG5n", "type": "image/png", "filename": "logo.png", "disposition": "inline", "content_id": "logo"}
+				]
+			}`,
+		},
+		{
+			name:  "sendgrid without bodies",
+			build: buildSendGridPayload,
+			modify: func(cfg *EmailConfig) {
+				cfg.From = "Release Bot <bot@example.com>"
+				cfg.TextBody, cfg.HTMLBody = "", ""
+				cfg.CC, cfg.BCC, cfg.ReplyTo, cfg.Attachments = nil, nil, nil, nil
+				cfg.AdditionalData = map[string]any{"categories": []string{"release"}, "subject": "overridden"}
+			},
+			want: `{
+				"personalizations": [{"to": [{"email": "jane@example.com", "name": "Jane Doe"}, {"email": "ops@example.com"}]}],
+				"from": {"email": "bot@example.com", "name": "Release Bot"},
+				"subject": "overridden",
+				"content": [{"type": "text/plain", "value": "(empty message)"}],
+				"categories": ["release"]
+			}`,
+		},
+		{
+			name:  "mailtrap",
+			build: buildMailtrapPayload,
+			want: `{
+				"from": {"email": "bot@example.com"},
+				"to": [{"email": "jane@example.com", "name": "Jane Doe"}, {"email": "ops@example.com"}],
+				"cc": [{"email": "cc@example.com"}],
+				"bcc": [{"email": "bcc@example.com"}],
+				"reply_to": {"email": "support@example.com", "name": "Support"},
+				"subject": "demo v1.2.3 released",
+				"text": "Notes",
+				"html": "<p>Notes</p>",
+				"attachments": [
+					{"content": "aGVsbG8=", "type": "text/plain", "filename": "notes.txt"},
+					{"content": "cG5n", "type": "image/png", "filename": "logo.png", "disposition": "inline", "content_id": "logo"}
+				]
+			}`,
+		},
+		{
+			name:  "brevo",
+			build: buildBrevoPayload,
+			want: `{
+				"sender": {"email": "bot@example.com"},
+				"to": [{"email": "jane@example.com", "name": "Jane Doe"}, {"email": "ops@example.com"}],
+				"cc": [{"email": "cc@example.com"}],
+				"bcc": [{"email": "bcc@example.com"}],
+				"replyTo": {"email": "support@example.com", "name": "Support"},
+				"subject": "demo v1.2.3 released",
+				"textContent": "Notes",
+				"htmlContent": "<p>Notes</p>",
+				"attachment": [
+					{"name": "notes.txt", "content": "aGVsbG8="},
+					{"name": "logo.png", "content": "cG5n", "disposition": "inline", "contentId": "logo"}
+				]
+			}`,
+		},
+		{
+			name:  "postmark",
+			build: buildPostmarkPayload,
+			want: `{
+				"From": "bot@example.com",
+				"To": "Jane Doe <jane@example.com>,ops@example.com",
+				"Cc": "cc@example.com",
+				"Bcc": "bcc@example.com",
+				"ReplyTo": "support@example.com",
+				"Subject": "demo v1.2.3 released",
+				"TextBody": "Notes",
+				"HtmlBody": "<p>Notes</p>",
+				"Headers": [{"Name": "X-Release", "Value": "v1.2.3"}],
+				"Attachments": [
+					{"Name": "notes.txt", "Content": "aGVsbG8=", "ContentType": "text/plain"},
+					{"Name": "logo.png", "Content": "cG5n", "ContentType": "image/png", "ContentID": "logo", "ContentDisposition": "inline"}
+				]
+			}`,
+		},
+		{
+			name:  "sparkpost",
+			build: buildSparkPostPayload,
+			want: `{
+				"recipients": [
+					{"address": {"email": "jane@example.com", "name": "Jane Doe"}},
+					{"address": {"email": "ops@example.com"}}
+				],
+				"content": {
+					"from": {"email": "bot@example.com", "name": "Release Bot"},
+					"subject": "demo v1.2.3 released",
+					"text": "Notes",
+					"html": "<p>Notes</p>",
+					"attachments": [{"type": "text/plain", "name": "notes.txt", "data": "aGVsbG8="}],
+					"inline_images": [{"type": "image/png", "name": "logo", "data": "cG5n"}]
+				},
+				"metadata": {"release": "v1.2.3"},
+				"description": "release"
+			}`,
+		},
+		{
+			name:  "resend",
+			build: buildResendPayload,
+			want: `{
+				"from": "bot@example.com",
+				"to": ["Jane Doe <jane@example.com>", "ops@example.com"],
+				"cc": ["cc@example.com"],
+				"bcc": ["bcc@example.com"],
+				"reply_to": ["support@example.com"],
+				"subject": "demo v1.2.3 released",
+				"text": "Notes",
+				"html": "<p>Notes</p>",
+				"attachments": [
+					{"filename": "notes.txt", "content": "aGVsbG8=", "content_type": "text/plain"},
+					{"filename": "logo.png", "content": "cG5n", "content_type": "image/png", "cid": "logo", "disposition": "inline"}
+				]
+			}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := testEmail(t)
+			if tt.modify != nil {
+				tt.modify(cfg)
+			}
+			payload, contentType, err := tt.build(context.Background(), cfg)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if contentType != "application/json" {
+				t.Errorf("content type = %q", contentType)
+			}
+			if got, want := normalizeJSON(t, payload), normalizeJSON(t, tt.want); got != want {
+				t.Errorf("payload =\n%s\nwant\n%s", got, want)
+			}
+		})
+	}
+}
+
+func TestSESPayload(t *testing.T) {
+	cfg := testEmail(t)
+	cfg.ConfigurationSet = "releases"
+	cfg.Tags = map[string]string{"release": "v1.2.3", "project": "demo"}
+
+	payload, contentType, err := buildSESPayload(context.Background(), cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if contentType != "application/json" {
+		t.Errorf("content type = %q", contentType)
+	}
+
+	// The raw MIME message has a date and boundaries of its own, so it is
+	// checked apart from the rest of the request
+	m := payload.(map[string]any)
+	raw := m["Content"].(map[string]any)["Raw"].(map[string]string)["Data"]
+	delete(m, "Content")
+	message, err := base64.StdEncoding.DecodeString(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"Subject: demo v1.2.3 released", "notes.txt", "Content-ID: <logo>"} {
+		if !strings.Contains(string(message), want) {
+			t.Errorf("raw message lacks %q:\n%s", want, message)
+		}
+	}
+
+	want := `{
+		"Destination": {
+			"ToAddresses": ["Jane Doe <jane@example.com>", "ops@example.com"],
+			"CcAddresses": ["cc@example.com"],
+			"BccAddresses": ["bcc@example.com"]
+		},
+		"FromEmailAddress": "bot@example.com",
+		"ConfigurationSetName": "releases",
+		"EmailTags": [{"Name": "project", "Value": "demo"}, {"Name": "release", "Value": "v1.2.3"}]
+	}`
+	if got, want := normalizeJSON(t, m), normalizeJSON(t, want); got != want {
+		t.Errorf("payload =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestMailgunPayload(t *testing.T) {
+	tests := []struct {
+		name         string
+		endpoint     string
+		data         map[string]any
+		attachments  bool
+		wantEndpoint string
+		wantErr      string
+	}{
+		{
+			name:         "domain from the payload",
+			endpoint:     "https://api.mailgun.net/v3",
+			data:         map[string]any{"domain": "mg.example.com"},
+			wantEndpoint: "https://api.mailgun.net/v3/mg.example.com/messages",
+		},
+		{
+			name:         "domain from the endpoint",
+			endpoint:     "https://api.eu.mailgun.net/v3/mg.example.com/",
+			wantEndpoint: "https://api.eu.mailgun.net/v3/mg.example.com/messages",
+		},
+		{
+			name:         "messages endpoint",
+			endpoint:     "https://api.mailgun.net/v3/mg.example.com/messages",
+			wantEndpoint: "https://api.mailgun.net/v3/mg.example.com/messages",
+		},
+		{name: "no domain", endpoint: "https://api.mailgun.net/v3", wantErr: "mailgun domain is required"},
+		{name: "attachments", endpoint: "https://api.mailgun.net/v3/mg.example.com", attachments: true, wantErr: "does not support attachments"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := testEmail(t)
+			cfg.Endpoint = tt.endpoint
+			cfg.AdditionalData = tt.data
+			if !tt.attachments {
+				cfg.Attachments = nil
+			}
+
+			payload, contentType, err := buildMailgunPayload(context.Background(), cfg)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("buildMailgunPayload() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if contentType != "application/x-www-form-urlencoded" {
+				t.Errorf("content type = %q", contentType)
+			}
+			if cfg.Endpoint != tt.wantEndpoint {
+				t.Errorf("endpoint = %q, want %q", cfg.Endpoint, tt.wantEndpoint)
+			}
+
+			want := url.Values{
+				"from":       {"Release Bot <bot@example.com>"},
+				"to":         {"Jane Doe <jane@example.com>", "ops@example.com"},
+				"cc":         {"cc@example.com"},
+				"bcc":        {"bcc@example.com"},
+				"h:Reply-To": {"support@example.com"},
+				"subject":    {"demo v1.2.3 released"},
+				"text":       {"Notes"},
+				"html":       {"<p>Notes</p>"},
+			}
+			if got := payload.(url.Values).Encode(); got != want.Encode() {
+				t.Errorf("form = %s, want %s", got, want.Encode())
+			}
+		})
+	}
+}
+
+func TestHTTPPayload(t *testing.T) {
+	cfg := testEmail(t)
+	cfg.Provider = "custom"
+	cfg.Attachments = cfg.Attachments[:1]
+	cfg.AdditionalData = map[string]any{"subject": "ignored", "campaign": "releases"}
+
+	payload, err := buildHTTPPayload(context.Background(), cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Additional data doesn't replace the message fields
+	want := `{
+		"from": "bot@example.com",
+		"from_name": "Release Bot",
+		"reply_to": ["Support <support@example.com>"],
+		"to": ["Jane Doe <jane@example.com>", "ops@example.com"],
+		"cc": ["cc@example.com"],
+		"bcc": ["bcc@example.com"],
+		"subject": "demo v1.2.3 released",
+		"text_body": "Notes",
+		"html_body": "<p>Notes</p>",
+		"provider": "custom",
+		"attachments": [{"filename": "notes.txt", "content": "aGVsbG8=", "content_type": "text/plain"}],
+		"campaign": "releases"
+	}`
+	if got, want := normalizeJSON(t, payload), normalizeJSON(t, want); got != want {
+		t.Errorf("payload =\n%s\nwant\n%s", got, want)
+	}
+}
@@ -0,0 +1,178 @@
+package mailer
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// signAWSv4 signs a request for Amazon SES with AWS Signature Version 4
+func signAWSv4(req *http.Request, body []byte, cfg *EmailConfig) error {
+	region := strings.TrimSpace(cfg.AWSRegion)
+	if region == "" {
+		region = inferAWSRegion(req.URL.String())
+	}
+	if region == "" {
+		return errors.New("aws region required for sigv4")
+	}
+	access := strings.TrimSpace(cfg.AWSAccessKey)
+	secret := strings.TrimSpace(cfg.AWSSecretKey)
+	if access == "" || secret == "" {
+		return errors.New("aws credentials required for sigv4")
+	}
+	service := "ses"
+	now := time.Now().UTC()
+	amzDate := now.Format("20060102T150405Z")
+	dateStamp := now.Format("20060102")
+	payloadHash := sha256Hex(body)
+	if req.Header.Get("Host") == "" {
+		req.Header.Set("Host", req.URL.Host)
+	}
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+	if cfg.AWSSessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", cfg.AWSSessionToken)
+	}
+
+	canonicalHeaders, signedHeaders := canonicalizeHeaders(req)
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		canonicalURI(req.URL.Path),
+		canonicalQuery(req.URL.RawQuery),
+		canonicalHeaders,
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+
+	credentialScope := strings.Join([]string{dateStamp, region, service, "aws4_request"}, "/")
+	stringToSign := strings.Join([]string{
+		"AWS4-HMAC-SHA256",
+		amzDate,
+		credentialScope,
+		sha256Hex([]byte(canonicalRequest)),
+	}, "\n")
+
+	signingKey := awsSigningKey(secret, dateStamp, region, service)
+	signature := hex.EncodeToString(hmacSHA256(signingKey, []byte(stringToSign)))
+	authHeader := fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s", access, credentialScope, signedHeaders, signature)
+	req.Header.Set("Authorization", authHeader)
+	return nil
+}
+
+func canonicalURI(path string) string {
+	if path == "" {
+		return "/"
+	}
+	escaped := url.PathEscape(path)
+	return strings.ReplaceAll(escaped, "%2F", "/")
+}
+
+func canonicalQuery(raw string) string {
+	if raw == "" {
+		return ""
+	}
+	values, err := url.ParseQuery(raw)
+	if err != nil {
+		return raw
+	}
+	var keys []string
+	for k := range values {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	parts := make([]string, 0, len(keys))
+	for _, k := range keys {
+		sortedVals := values[k]
+		sort.Strings(sortedVals)
+		for _, v := range sortedVals {
+			parts = append(parts, escapeQuery(k)+"="+escapeQuery(v))
+		}
+	}
+	return strings.Join(parts, "&")
+}
+
+func escapeQuery(value string) string {
+	encoded := url.QueryEscape(value)
+	encoded = strings.ReplaceAll(encoded, "+", "%20")
+	encoded = strings.ReplaceAll(encoded, "*", "%2A")
+	encoded = strings.ReplaceAll(encoded, "%7E", "~")
+	return encoded
+}
+
+func canonicalizeHeaders(req *http.Request) (string, string) {
+	var keys []string
+	headers := map[string][]string{}
+	for k, vals := range req.Header {
+		lower := strings.ToLower(k)
+		keys = append(keys, lower)
+		headers[lower] = vals
+	}
+	if _, ok := headers["host"]; !ok {
+		keys = append(keys, "host")
+		headers["host"] = []string{req.URL.Host}
+	}
+	sort.Strings(keys)
+	var canonical strings.Builder
+	var signed []string
+	seen := map[string]bool{}
+	for _, k := range keys {
+		if seen[k] {
+			continue
+		}
+		seen[k] = true
+		signed = append(signed, k)
+		values := headers[k]
+		for i, v := range values {
+			values[i] = strings.TrimSpace(v)
+		}
+		canonical.WriteString(k)
+		canonical.WriteString(":")
+		canonical.WriteString(strings.Join(values, ","))
+		canonical.WriteString("\n")
+	}
+	return canonical.String(), strings.Join(signed, ";")
+}
+
+func awsSigningKey(secret, date, region, service string) []byte {
+	kDate := hmacSHA256([]byte("AWS4"+secret), []byte(date))
+	kRegion := hmacSHA256(kDate, []byte(region))
+	kService := hmacSHA256(kRegion, []byte(service))
+	return hmacSHA256(kService, []byte("aws4_request"))
+}
+
+func hmacSHA256(key, data []byte) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write(data)
+	return h.Sum(nil)
+}
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func inferAWSRegion(endpoint string) string {
+	endpoint = strings.ToLower(strings.TrimSpace(endpoint))
+	if endpoint == "" {
+		return ""
+	}
+	if strings.Contains(endpoint, "email-") && strings.Contains(endpoint, ".amazonaws.com") {
+		re := regexp.MustCompile(`email-([a-z0-9-]+)\.amazonaws\.com`)
+		if match := re.FindStringSubmatch(endpoint); len(match) == 2 {
+			return match[1]
+		}
+	}
+	re := regexp.MustCompile(`\.([a-z0-9-]+)\.amazonaws\.com`)
+	if match := re.FindStringSubmatch(endpoint); len(match) == 2 {
+		return match[1]
+	}
+	return ""
+}
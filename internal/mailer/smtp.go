@@ -0,0 +1,194 @@
+package mailer
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/smtp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/oarkflow/releaser/internal/httpclient"
+)
+
+// sendViaSMTP delivers the message over SMTP with implicit TLS, STARTTLS or
+// a plain connection. The whole session is bounded by the timeout and ends
+// when ctx is done.
+func sendViaSMTP(ctx context.Context, cfg *EmailConfig) error {
+	msg, err := buildMessage(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	recipients, err := gatherRecipients(cfg)
+	if err != nil {
+		return err
+	}
+	if len(recipients) == 0 {
+		return errors.New("no valid recipients found")
+	}
+
+	addr := net.JoinHostPort(cfg.Host, strconv.Itoa(cfg.Port))
+	conn, err := dialSMTP(ctx, cfg, addr)
+	if err != nil {
+		return fmt.Errorf("failed to connect to %s: %w", addr, err)
+	}
+
+	deadline := time.Now().Add(cfg.Timeout)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+	_ = conn.SetDeadline(deadline)
+	// Cancellation unblocks whatever the session is waiting on
+	stop := context.AfterFunc(ctx, func() {
+		_ = conn.SetDeadline(time.Now())
+	})
+	defer stop()
+
+	client, err := smtp.NewClient(conn, cfg.Host)
+	if err != nil {
+		conn.Close()
+		return fmt.Errorf("failed to start SMTP session: %w", withContext(ctx, err))
+	}
+	defer client.Close()
+
+	if cfg.UseTLS && !cfg.UseSSL {
+		if ok, _ := client.Extension("STARTTLS"); !ok {
+			return fmt.Errorf("%s does not support STARTTLS", cfg.Host)
+		}
+		if err := client.StartTLS(smtpTLSConfig(cfg)); err != nil {
+			return fmt.Errorf("STARTTLS failed: %w", withContext(ctx, err))
+		}
+	}
+
+	if cfg.Username != "" && cfg.Password != "" {
+		auth, err := buildSMTPAuth(cfg)
+		if err != nil {
+			return err
+		}
+		if auth != nil {
+			if err := client.Auth(auth); err != nil {
+				return fmt.Errorf("SMTP authentication failed: %w", withContext(ctx, err))
+			}
+		}
+	}
+
+	if err := client.Mail(cfg.EnvelopeFrom); err != nil {
+		return fmt.Errorf("MAIL FROM failed: %w", withContext(ctx, err))
+	}
+	for _, recipient := range recipients {
+		if err := client.Rcpt(recipient); err != nil {
+			return fmt.Errorf("RCPT TO %s failed: %w", recipient, withContext(ctx, err))
+		}
+	}
+
+	w, err := client.Data()
+	if err != nil {
+		return fmt.Errorf("DATA failed: %w", withContext(ctx, err))
+	}
+	if _, err := w.Write([]byte(msg)); err != nil {
+		return fmt.Errorf("failed to write message: %w", withContext(ctx, err))
+	}
+	if err := w.Close(); err != nil {
+		return fmt.Errorf("failed to send message: %w", withContext(ctx, err))
+	}
+
+	return client.Quit()
+}
+
+// withContext returns the context's error for errors caused by it ending,
+// which otherwise surface as I/O timeouts
+func withContext(ctx context.Context, err error) error {
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		return ctxErr
+	}
+	return err
+}
+
+func gatherRecipients(cfg *EmailConfig) ([]string, error) {
+	unique := make(map[string]struct{})
+	var recipients []string
+	for _, set := range [][]string{cfg.To, cfg.CC, cfg.BCC} {
+		for _, candidate := range set {
+			_, addr := splitAddress(candidate)
+			if addr == "" {
+				continue
+			}
+			addr = strings.ToLower(strings.TrimSpace(addr))
+			if addr == "" {
+				continue
+			}
+			if _, exists := unique[addr]; exists {
+				continue
+			}
+			unique[addr] = struct{}{}
+			recipients = append(recipients, addr)
+		}
+	}
+	return recipients, nil
+}
+
+// dialSMTP connects to the server, with implicit TLS when UseSSL is set
+func dialSMTP(ctx context.Context, cfg *EmailConfig, addr string) (net.Conn, error) {
+	dialer := &net.Dialer{Timeout: cfg.Timeout}
+	if cfg.UseSSL {
+		return (&tls.Dialer{NetDialer: dialer, Config: smtpTLSConfig(cfg)}).DialContext(ctx, "tcp", addr)
+	}
+	return dialer.DialContext(ctx, "tcp", addr)
+}
+
+// smtpTLSConfig verifies the server against the trusted CAs unless
+// SkipTLSVerify is set
+func smtpTLSConfig(cfg *EmailConfig) *tls.Config {
+	return &tls.Config{
+		ServerName:         cfg.Host,
+		RootCAs:            httpclient.RootCAs(),
+		InsecureSkipVerify: cfg.SkipTLSVerify,
+	}
+}
+
+func buildSMTPAuth(cfg *EmailConfig) (smtp.Auth, error) {
+	authType := strings.ToLower(strings.TrimSpace(cfg.SMTPAuth))
+	switch authType {
+	case "", "plain":
+		return smtp.PlainAuth("", cfg.Username, cfg.Password, cfg.Host), nil
+	case "login":
+		return &loginAuth{username: cfg.Username, password: cfg.Password, host: cfg.Host}, nil
+	case "cram-md5", "crammd5":
+		return smtp.CRAMMD5Auth(cfg.Username, cfg.Password), nil
+	case "none":
+		return nil, nil
+	default:
+		return nil, fmt.Errorf("unsupported smtp auth %s", authType)
+	}
+}
+
+// loginAuth implements the LOGIN SMTP auth mechanism.
+type loginAuth struct {
+	username string
+	password string
+	host     string
+}
+
+func (a *loginAuth) Start(server *smtp.ServerInfo) (string, []byte, error) {
+	if server.Name != a.host {
+		return "", nil, fmt.Errorf("unexpected server name %s", server.Name)
+	}
+	return "LOGIN", nil, nil
+}
+
+func (a *loginAuth) Next(fromServer []byte, more bool) ([]byte, error) {
+	if more {
+		switch strings.ToLower(string(fromServer)) {
+		case "username:", "user:":
+			return []byte(a.username), nil
+		case "password:", "pass:":
+			return []byte(a.password), nil
+		default:
+			return nil, fmt.Errorf("unexpected login challenge: %s", string(fromServer))
+		}
+	}
+	return nil, nil
+}
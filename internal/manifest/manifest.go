@@ -0,0 +1,90 @@
+/*
+Package manifest defines how releaser renders the files it generates for
+package managers and installers, such as PKGBUILDs, nuspecs, Scoop and
+winget manifests, Homebrew formulas, WiX sources, NSIS scripts and property
+lists. Each generator is a struct holding everything its file is made of,
+with templates already applied, so the same fields always render the same
+bytes. The package also holds the escaping those formats need.
+*/
+package manifest
+
+import (
+	"bytes"
+	"encoding/xml"
+	"os"
+	"strings"
+)
+
+// Generator renders a manifest from the fields of its struct
+type Generator interface {
+	Generate() ([]byte, error)
+}
+
+// WriteFile renders a manifest to path
+func WriteFile(path string, g Generator) error {
+	data, err := g.Generate()
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}
+
+// ShellQuote quotes a value for POSIX shells, such as PKGBUILD variables,
+// in single quotes that keep $, ` and \ literal
+func ShellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// ShellQuoteAll quotes each value and joins them with spaces, for shell
+// arrays
+func ShellQuoteAll(values []string) string {
+	quoted := make([]string, len(values))
+	for i, v := range values {
+		quoted[i] = ShellQuote(v)
+	}
+	return strings.Join(quoted, " ")
+}
+
+// RubyString quotes a value as a double quoted Ruby string, escaping the
+// characters Ruby would interpret, #{ interpolation included
+func RubyString(s string) string {
+	var b strings.Builder
+	b.WriteByte('"')
+	for i, r := range s {
+		switch r {
+		case '"', '\\':
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		case '#':
+			// #{, #@ and #$ interpolate
+			if i+1 < len(s) && strings.ContainsRune("{@$", rune(s[i+1])) {
+				b.WriteByte('\\')
+			}
+			b.WriteRune(r)
+		case '\n':
+			b.WriteString(`\n`)
+		case '\t':
+			b.WriteString(`\t`)
+		default:
+			b.WriteRune(r)
+		}
+	}
+	b.WriteByte('"')
+	return b.String()
+}
+
+// PowerShellString quotes a value as a single quoted PowerShell string,
+// which nothing is expanded in
+func PowerShellString(s string) string {
+	// Smart quotes end PowerShell strings as well
+	s = strings.NewReplacer("'", "''", "‘", "‘‘", "’", "’’", "‚", "‚‚", "‛", "‛‛").Replace(s)
+	return "'" + s + "'"
+}
+
+// XMLEscape escapes a value for XML text and attribute values
+func XMLEscape(s string) string {
+	var buf bytes.Buffer
+	// Writing to a bytes.Buffer can't fail
+	xml.EscapeText(&buf, []byte(s))
+	return buf.String()
+}
@@ -0,0 +1,37 @@
+// Package manifesttest compares generated manifests with golden files in
+// the testdata directory of the package under test
+package manifesttest
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/oarkflow/releaser/internal/manifest"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files in testdata")
+
+// AssertGolden compares a generated manifest with testdata/name
+func AssertGolden(t *testing.T, name string, g manifest.Generator) {
+	t.Helper()
+	got, err := g.Generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	path := filepath.Join("testdata", name)
+	if *update {
+		if err := os.WriteFile(path, got, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != string(want) {
+		t.Errorf("%s differs from the golden file (run go test -update to rewrite it):\n%s", name, got)
+	}
+}
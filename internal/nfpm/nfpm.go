@@ -8,13 +8,15 @@ import (
 	"os/exec"
 	"path/filepath"
 	"strings"
-	"text/template"
+	"time"
 
 	"github.com/charmbracelet/log"
 	"github.com/oarkflow/releaser/internal/artifact"
 	"github.com/oarkflow/releaser/internal/assets"
 	"github.com/oarkflow/releaser/internal/config"
+	"github.com/oarkflow/releaser/internal/desktop"
 	"github.com/oarkflow/releaser/internal/tmpl"
+	"gopkg.in/yaml.v3"
 )
 
 // Packager creates Linux packages.
@@ -84,9 +86,9 @@ func (p *Packager) Build(ctx context.Context) error {
 	}
 
 	// Build packages for each format
-	formats := p.config.Formats
-	if len(formats) == 0 {
-		formats = []string{"deb", "rpm"}
+	formats, err := p.formats()
+	if err != nil {
+		return err
 	}
 
 	// Build a single package per architecture containing ALL binaries
@@ -102,19 +104,56 @@ func (p *Packager) Build(ctx context.Context) error {
 	return nil
 }
 
-// buildPackageWithBinaries builds a single package containing multiple binaries.
-func (p *Packager) buildPackageWithBinaries(ctx context.Context, binaries []artifact.Artifact, arch, format string) error {
-	log.Debug("Building package", "arch", arch, "format", format, "binaries", len(binaries))
+// Plan returns the package file names Build would produce for the given
+// Linux architectures, without generating or packaging anything.
+func (p *Packager) Plan(arches []string) ([]string, error) {
+	if p.config.Skip == "true" {
+		return nil, nil
+	}
+
+	formats, err := p.formats()
+	if err != nil {
+		return nil, err
+	}
+
+	pkgName, version := p.nameAndVersion()
+	var names []string
+	for _, arch := range arches {
+		for _, format := range formats {
+			names = append(names, packageFileName(pkgName, version, p.config.Release, normalizeArch(arch, format), format))
+		}
+	}
+	return names, nil
+}
 
-	// Prepare package name
+// formats returns the configured package formats, defaulting to deb and rpm.
+func (p *Packager) formats() ([]string, error) {
+	formats := p.config.Formats
+	if len(formats) == 0 {
+		formats = []string{"deb", "rpm"}
+	}
+	for _, format := range formats {
+		if !isSupportedFormat(format) {
+			return nil, fmt.Errorf("unsupported package format %q (supported: deb, rpm, apk, archlinux)", format)
+		}
+	}
+	return formats, nil
+}
+
+// nameAndVersion returns the package name and version without a "v" prefix.
+func (p *Packager) nameAndVersion() (string, string) {
 	pkgName := p.config.PackageName
 	if pkgName == "" {
 		pkgName = p.tmplCtx.Get("ProjectName")
 	}
+	return pkgName, strings.TrimPrefix(p.tmplCtx.Get("Version"), "v")
+}
+
+// buildPackageWithBinaries builds a single package containing multiple binaries.
+func (p *Packager) buildPackageWithBinaries(ctx context.Context, binaries []artifact.Artifact, arch, format string) error {
+	log.Debug("Building package", "arch", arch, "format", format, "binaries", len(binaries))
 
-	// Prepare version
-	version := p.tmplCtx.Get("Version")
-	version = strings.TrimPrefix(version, "v")
+	pkgName, version := p.nameAndVersion()
 
 	// Normalize architecture for package format
 	normalizedArch := normalizeArch(arch, format)
@@ -127,7 +166,7 @@ func (p *Packager) buildPackageWithBinaries(ctx context.Context, binaries []arti
 	defer os.Remove(nfpmConfigPath)
 
 	// Generate output filename
-	outputName := fmt.Sprintf("%s_%s_%s.%s", pkgName, version, normalizedArch, format)
+	outputName := packageFileName(pkgName, version, p.config.Release, normalizedArch, format)
 	outputPath := filepath.Join(p.distDir, outputName)
 
 	// Try nfpm first, then fpm
@@ -139,16 +178,19 @@ func (p *Packager) buildPackageWithBinaries(ctx context.Context, binaries []arti
 	}
 
 	// Add artifact
-	p.manager.Add(artifact.Artifact{
+	if err := p.manager.Add(artifact.Artifact{
 		Name:   outputName,
 		Path:   outputPath,
 		Type:   artifact.TypeLinuxPackage,
 		Goos:   "linux",
 		Goarch: arch,
 		Extra: map[string]interface{}{
-			"format": format,
+			artifact.ExtraFormat: format,
+			"arch":               normalizedArch,
 		},
-	})
+	}); err != nil {
+		return err
+	}
 
 	log.Info("Package created", "name", outputName, "binaries", len(binaries))
 	return nil
@@ -159,313 +201,488 @@ func (p *Packager) buildPackage(ctx context.Context, binary artifact.Artifact, f
 	return p.buildPackageWithBinaries(ctx, []artifact.Artifact{binary}, binary.Goarch, format)
 }
 
-// generateNfpmConfigMulti generates an nfpm configuration file for multiple binaries.
-func (p *Packager) generateNfpmConfigMulti(path string, binaries []artifact.Artifact, name, version, arch, format string) error {
-	configTemplate := `name: "{{ .Name }}"
-arch: "{{ .Arch }}"
-platform: "linux"
-version: "{{ .Version }}"
-maintainer: "{{ .Maintainer }}"
-description: "{{ .Description }}"
-vendor: "{{ .Vendor }}"
-homepage: "{{ .Homepage }}"
-license: "{{ .License }}"
-
-contents:
-{{ range .Binaries }}
-  - src: "{{ .Path }}"
-    dst: "{{ $.Bindir }}/{{ .Name }}"
-    type: file
-    file_info:
-      mode: 0755
-{{ end }}
-{{ range .GUIEntries }}
-  - src: "{{ .DesktopFile }}"
-    dst: "/usr/share/applications/{{ .AppID }}.desktop"
-    type: file
-{{ if .IconPath }}
-  - src: "{{ .IconPath }}"
-    dst: "/usr/share/icons/hicolor/256x256/apps/{{ .AppID }}.png"
-    type: file
-{{ end }}
-{{ end }}
-{{ range .Contents }}
-  - src: "{{ .Src }}"
-    dst: "{{ .Dst }}"
-{{ if .Type }}
-    type: {{ .Type }}
-{{ end }}
-{{ end }}
-{{ if .Dependencies }}
-depends:
-{{ range .Dependencies }}
-  - "{{ . }}"
-{{ end }}
-{{ end }}
-`
+// nfpmFile mirrors the subset of the nfpm configuration file we generate.
+type nfpmFile struct {
+	Name            string         `yaml:"name"`
+	Arch            string         `yaml:"arch"`
+	Platform        string         `yaml:"platform"`
+	Version         string         `yaml:"version"`
+	Epoch           string         `yaml:"epoch,omitempty"`
+	Release         string         `yaml:"release,omitempty"`
+	Prerelease      string         `yaml:"prerelease,omitempty"`
+	VersionMetadata string         `yaml:"version_metadata,omitempty"`
+	Section         string         `yaml:"section,omitempty"`
+	Priority        string         `yaml:"priority,omitempty"`
+	Maintainer      string         `yaml:"maintainer,omitempty"`
+	Description     string         `yaml:"description,omitempty"`
+	Vendor          string         `yaml:"vendor,omitempty"`
+	Homepage        string         `yaml:"homepage,omitempty"`
+	License         string         `yaml:"license,omitempty"`
+	Changelog       string         `yaml:"changelog,omitempty"`
+	Meta            bool           `yaml:"meta,omitempty"`
+	Depends         []string       `yaml:"depends,omitempty"`
+	Recommends      []string       `yaml:"recommends,omitempty"`
+	Suggests        []string       `yaml:"suggests,omitempty"`
+	Conflicts       []string       `yaml:"conflicts,omitempty"`
+	Replaces        []string       `yaml:"replaces,omitempty"`
+	Provides        []string       `yaml:"provides,omitempty"`
+	Contents        []nfpmContent  `yaml:"contents,omitempty"`
+	Scripts         nfpmScripts    `yaml:"scripts,omitempty"`
+	Deb             *nfpmDeb       `yaml:"deb,omitempty"`
+	RPM             *nfpmRPM       `yaml:"rpm,omitempty"`
+	APK             *nfpmAPK       `yaml:"apk,omitempty"`
+	Archlinux       *nfpmArchlinux `yaml:"archlinux,omitempty"`
+}
 
-	tmpl, err := template.New("nfpm").Parse(configTemplate)
-	if err != nil {
-		return err
-	}
+type nfpmContent struct {
+	Src      string        `yaml:"src,omitempty"`
+	Dst      string        `yaml:"dst"`
+	Type     string        `yaml:"type,omitempty"`
+	FileInfo *nfpmFileInfo `yaml:"file_info,omitempty"`
+}
+
+type nfpmFileInfo struct {
+	Owner string      `yaml:"owner,omitempty"`
+	Group string      `yaml:"group,omitempty"`
+	Mode  os.FileMode `yaml:"mode,omitempty"`
+	MTime string      `yaml:"mtime,omitempty"`
+}
+
+type nfpmScripts struct {
+	PreInstall  string `yaml:"preinstall,omitempty"`
+	PostInstall string `yaml:"postinstall,omitempty"`
+	PreRemove   string `yaml:"preremove,omitempty"`
+	PostRemove  string `yaml:"postremove,omitempty"`
+}
+
+type nfpmDeb struct {
+	Compression string                 `yaml:"compression,omitempty"`
+	Predepends  []string               `yaml:"predepends,omitempty"`
+	Breaks      []string               `yaml:"breaks,omitempty"`
+	Fields      map[string]string      `yaml:"fields,omitempty"`
+	Triggers    config.NFPMDebTriggers `yaml:"triggers,omitempty"`
+	Scripts     struct {
+		Rules     string `yaml:"rules,omitempty"`
+		Templates string `yaml:"templates,omitempty"`
+		Config    string `yaml:"config,omitempty"`
+	} `yaml:"scripts,omitempty"`
+	Signature struct {
+		KeyFile string `yaml:"key_file,omitempty"`
+		KeyID   string `yaml:"key_id,omitempty"`
+		Type    string `yaml:"type,omitempty"`
+	} `yaml:"signature,omitempty"`
+}
 
+type nfpmRPM struct {
+	Summary     string                `yaml:"summary,omitempty"`
+	Group       string                `yaml:"group,omitempty"`
+	Compression string                `yaml:"compression,omitempty"`
+	Packager    string                `yaml:"packager,omitempty"`
+	Prefixes    []string              `yaml:"prefixes,omitempty"`
+	Scripts     config.NFPMRPMScripts `yaml:"scripts,omitempty"`
+	Signature   struct {
+		KeyFile string `yaml:"key_file,omitempty"`
+		KeyID   string `yaml:"key_id,omitempty"`
+	} `yaml:"signature,omitempty"`
+}
+
+type nfpmAPK struct {
+	Scripts   config.NFPMAPKScripts `yaml:"scripts,omitempty"`
+	Signature struct {
+		KeyFile string `yaml:"key_file,omitempty"`
+		KeyName string `yaml:"key_name,omitempty"`
+	} `yaml:"signature,omitempty"`
+}
+
+type nfpmArchlinux struct {
+	Pkgbase  string                      `yaml:"pkgbase,omitempty"`
+	Packager string                      `yaml:"packager,omitempty"`
+	Scripts  config.NFPMArchlinuxScripts `yaml:"scripts,omitempty"`
+}
+
+// generateNfpmConfigMulti generates an nfpm configuration file for multiple binaries.
+func (p *Packager) generateNfpmConfigMulti(path string, binaries []artifact.Artifact, name, version, arch, format string) error {
 	bindir := p.config.Bindir
 	if bindir == "" {
 		bindir = "/usr/bin"
 	}
 
-	// Collect GUI entries for all GUI binaries
-	type guiEntry struct {
-		AppID       string
-		DesktopFile string
-		IconPath    string
+	cfg := p.resolvedConfig(format)
+
+	file := nfpmFile{
+		Name:            name,
+		Arch:            arch,
+		Platform:        "linux",
+		Version:         version,
+		Epoch:           cfg.Epoch,
+		Release:         cfg.Release,
+		Prerelease:      cfg.Prerelease,
+		VersionMetadata: cfg.VersionMetadata,
+		Section:         cfg.Section,
+		Priority:        cfg.Priority,
+		Maintainer:      cfg.Maintainer,
+		Description:     cfg.Description,
+		Vendor:          cfg.Vendor,
+		Homepage:        cfg.Homepage,
+		License:         cfg.License,
+		Meta:            cfg.Meta,
+		Depends:         append(append([]string{}, cfg.Depends...), cfg.Dependencies...),
+		Recommends:      cfg.Recommends,
+		Suggests:        cfg.Suggests,
+		Conflicts:       cfg.Conflicts,
+		Replaces:        cfg.Replaces,
+		Provides:        cfg.Provides,
+		Scripts: nfpmScripts{
+			PreInstall:  cfg.Scripts.PreInstall,
+			PostInstall: cfg.Scripts.PostInstall,
+			PreRemove:   cfg.Scripts.PreRemove,
+			PostRemove:  cfg.Scripts.PostRemove,
+		},
 	}
-	var guiEntries []guiEntry
 
 	for _, binary := range binaries {
-		// Check if this is a GUI application
-		isGUI := false
-		var guiConfig *config.GUIConfig
-		appID := binary.Name
-
-		if p.allConfigs != nil {
-			for _, build := range p.allConfigs.Builds {
-				if build.ID == binary.BuildID && build.Type == "gui" {
-					isGUI = true
-					guiConfig = build.GUI
-					break
-				}
-			}
+		file.Contents = append(file.Contents, nfpmContent{
+			Src:      binary.Path,
+			Dst:      bindir + "/" + binary.Name,
+			Type:     "file",
+			FileInfo: &nfpmFileInfo{Mode: 0755},
+		})
+	}
+
+	// Desktop entries and icons of GUI binaries
+	hasGUI := false
+	for _, binary := range binaries {
+		gui, ok := p.guiConfig(binary)
+		if !ok {
+			continue
+		}
+		contents, err := p.desktopContents(binary, gui, bindir)
+		if err != nil {
+			return fmt.Errorf("failed to set up desktop integration for %s: %w", binary.Name, err)
 		}
+		file.Contents = append(file.Contents, contents...)
+		hasGUI = true
+	}
 
-		if isGUI {
-			iconPath := resolveGUIIconPath(guiConfig, p.distDir, appID)
+	// User-provided contents, with config files, symlinks and directories
+	for i, c := range cfg.Contents {
+		if c.Packager != "" && c.Packager != format {
+			continue
+		}
 
-			desktopFile := filepath.Join(p.distDir, appID+".desktop")
-			if err := p.generateDesktopFile(desktopFile, binary, guiConfig, bindir); err != nil {
-				log.Warn("Failed to generate desktop file", "binary", binary.Name, "error", err)
-			} else {
-				guiEntries = append(guiEntries, guiEntry{
-					AppID:       appID,
-					DesktopFile: desktopFile,
-					IconPath:    iconPath,
-				})
+		content := nfpmContent{
+			Src:  p.applyTemplate(fmt.Sprintf("nfpms.contents[%d].src", i), c.Src),
+			Dst:  p.applyTemplate(fmt.Sprintf("nfpms.contents[%d].dst", i), c.Dst),
+			Type: c.Type,
+		}
+		// nfpm takes the target of symlinks as their src
+		if c.SymlinkTo != "" {
+			content.Src = p.applyTemplate(fmt.Sprintf("nfpms.contents[%d].symlink_to", i), c.SymlinkTo)
+			content.Type = "symlink"
+		}
+		if c.FileInfo.Owner != "" || c.FileInfo.Group != "" || c.FileInfo.Mode != 0 || c.FileInfo.MTime != "" {
+			content.FileInfo = &nfpmFileInfo{
+				Owner: c.FileInfo.Owner,
+				Group: c.FileInfo.Group,
+				Mode:  c.FileInfo.Mode,
+				MTime: c.FileInfo.MTime,
 			}
 		}
+		file.Contents = append(file.Contents, content)
+	}
+
+	switch format {
+	case "deb":
+		deb := &nfpmDeb{
+			Compression: cfg.Deb.Compression,
+			Predepends:  cfg.Deb.Predepends,
+			Breaks:      cfg.Deb.Breaks,
+			Fields:      cfg.Deb.Fields,
+			Triggers:    cfg.Deb.Triggers,
+		}
+		deb.Scripts.Rules = cfg.Deb.Scripts.Rules
+		deb.Scripts.Templates = cfg.Deb.Scripts.Templates
+		deb.Scripts.Config = cfg.Deb.Scripts.Config
+		deb.Signature.KeyFile = cfg.Deb.Signature.KeyFile
+		deb.Signature.KeyID = cfg.Deb.Signature.KeyID
+		deb.Signature.Type = cfg.Deb.Signature.Type
+		if cfg.Deb.Scripts.Postinst != "" {
+			file.Scripts.PostInstall = cfg.Deb.Scripts.Postinst
+		}
+		file.Deb = deb
+	case "rpm":
+		rpm := &nfpmRPM{
+			Summary:     cfg.RPM.Summary,
+			Group:       cfg.RPM.Group,
+			Compression: cfg.RPM.Compression,
+			Packager:    cfg.RPM.Packager,
+			Prefixes:    cfg.RPM.Prefixes,
+			Scripts:     cfg.RPM.Scripts,
+		}
+		if cfg.RPM.Scriptlets.Pretrans != "" {
+			rpm.Scripts.Pretrans = cfg.RPM.Scriptlets.Pretrans
+		}
+		if cfg.RPM.Scriptlets.Posttrans != "" {
+			rpm.Scripts.Posttrans = cfg.RPM.Scriptlets.Posttrans
+		}
+		rpm.Signature.KeyFile = cfg.RPM.Signature.KeyFile
+		rpm.Signature.KeyID = cfg.RPM.Signature.KeyID
+		file.RPM = rpm
+	case "apk":
+		apk := &nfpmAPK{Scripts: cfg.APK.Scripts}
+		apk.Signature.KeyFile = cfg.APK.Signature.KeyFile
+		apk.Signature.KeyName = cfg.APK.Signature.KeyName
+		file.APK = apk
+	case "archlinux":
+		file.Archlinux = &nfpmArchlinux{
+			Pkgbase:  cfg.Archlinux.Pkgbase,
+			Packager: cfg.Archlinux.Packager,
+			Scripts:  cfg.Archlinux.Scripts,
+		}
 	}
 
-	data := map[string]interface{}{
-		"Name":         name,
-		"Arch":         arch,
-		"Version":      version,
-		"Maintainer":   p.config.Maintainer,
-		"Description":  p.config.Description,
-		"Vendor":       p.config.Vendor,
-		"Homepage":     p.config.Homepage,
-		"License":      p.config.License,
-		"Binaries":     binaries,
-		"Bindir":       bindir,
-		"Contents":     p.config.Contents,
-		"Dependencies": p.config.Dependencies,
-		"GUIEntries":   guiEntries,
+	// Refresh the desktop database and icon cache once the files are in
+	// place or gone, after the format's own script overrides are applied
+	if hasGUI {
+		var err error
+		if file.Scripts.PostInstall, err = p.withDesktopRefresh(file.Scripts.PostInstall, name, format, "postinstall"); err != nil {
+			return err
+		}
+		if file.Scripts.PostRemove, err = p.withDesktopRefresh(file.Scripts.PostRemove, name, format, "postremove"); err != nil {
+			return err
+		}
 	}
 
-	f, err := os.Create(path)
-	if err != nil {
-		return err
+	// Changelog: an explicit chglog file, or one generated from the release changelog
+	if cfg.Changelog != "" {
+		file.Changelog = cfg.Changelog
+	} else if format == "deb" || format == "rpm" {
+		changelogPath := filepath.Join(p.distDir, fmt.Sprintf("nfpm-changelog-%s.yaml", name))
+		if ok, err := p.writeChangelog(changelogPath, version); err != nil {
+			log.Warn("Failed to generate package changelog", "error", err)
+		} else if ok {
+			file.Changelog = changelogPath
+		}
 	}
-	defer f.Close()
-
-	return tmpl.Execute(f, data)
-}
-
-// generateNfpmConfig generates an nfpm configuration file.
-func (p *Packager) generateNfpmConfig(path string, binary artifact.Artifact, name, version, arch, format string) error {
-	configTemplate := `name: "{{ .Name }}"
-arch: "{{ .Arch }}"
-platform: "linux"
-version: "{{ .Version }}"
-maintainer: "{{ .Maintainer }}"
-description: "{{ .Description }}"
-vendor: "{{ .Vendor }}"
-homepage: "{{ .Homepage }}"
-license: "{{ .License }}"
-
-contents:
-  - src: "{{ .BinaryPath }}"
-    dst: "{{ .Bindir }}/{{ .BinaryName }}"
-    type: file
-    file_info:
-      mode: 0755
-{{ if .IsGUI }}
-  - src: "{{ .DesktopFile }}"
-    dst: "/usr/share/applications/{{ .AppID }}.desktop"
-    type: file
-{{ if .IconPath }}
-  - src: "{{ .IconPath }}"
-    dst: "/usr/share/icons/hicolor/256x256/apps/{{ .AppID }}.png"
-    type: file
-{{ end }}
-{{ end }}
-{{ range .Contents }}
-  - src: "{{ .Src }}"
-    dst: "{{ .Dst }}"
-{{ if .Type }}
-    type: {{ .Type }}
-{{ end }}
-{{ end }}
-{{ if .Dependencies }}
-depends:
-{{ range .Dependencies }}
-  - "{{ . }}"
-{{ end }}
-{{ end }}
-`
 
-	tmpl, err := template.New("nfpm").Parse(configTemplate)
+	data, err := yaml.Marshal(file)
 	if err != nil {
 		return err
 	}
 
-	bindir := p.config.Bindir
-	if bindir == "" {
-		bindir = "/usr/bin"
-	}
+	return os.WriteFile(path, data, 0644)
+}
 
-	// Check if this is a GUI application
-	isGUI := false
-	var guiConfig *config.GUIConfig
-	appID := name
-	desktopFile := ""
-	iconPath := ""
+// resolvedConfig returns the nfpm config with the overrides for format applied.
+func (p *Packager) resolvedConfig(format string) config.NFPM {
+	cfg := p.config
+	override, ok := cfg.Overrides[format]
+	if !ok {
+		return cfg
+	}
 
-	if p.allConfigs != nil {
-		for _, build := range p.allConfigs.Builds {
-			if build.ID == binary.BuildID && build.Type == "gui" {
-				isGUI = true
-				guiConfig = build.GUI
-				break
-			}
-		}
+	if len(override.Depends) > 0 {
+		cfg.Depends = override.Depends
+	}
+	if len(override.Recommends) > 0 {
+		cfg.Recommends = override.Recommends
+	}
+	if len(override.Suggests) > 0 {
+		cfg.Suggests = override.Suggests
+	}
+	if len(override.Replaces) > 0 {
+		cfg.Replaces = override.Replaces
+	}
+	if len(override.Conflicts) > 0 {
+		cfg.Conflicts = override.Conflicts
+	}
+	if len(override.Contents) > 0 {
+		cfg.Contents = append(append([]config.NFPMContent{}, cfg.Contents...), override.Contents...)
+	}
+	if override.Scripts.PreInstall != "" {
+		cfg.Scripts.PreInstall = override.Scripts.PreInstall
+	}
+	if override.Scripts.PostInstall != "" {
+		cfg.Scripts.PostInstall = override.Scripts.PostInstall
+	}
+	if override.Scripts.PreRemove != "" {
+		cfg.Scripts.PreRemove = override.Scripts.PreRemove
+	}
+	if override.Scripts.PostRemove != "" {
+		cfg.Scripts.PostRemove = override.Scripts.PostRemove
 	}
 
-	// Generate desktop file for GUI apps
-	if isGUI {
-		iconPath = resolveGUIIconPath(guiConfig, p.distDir, appID)
-		desktopFile = filepath.Join(p.distDir, appID+".desktop")
-		if err := p.generateDesktopFile(desktopFile, binary, guiConfig, bindir); err != nil {
-			log.Warn("Failed to generate desktop file", "error", err)
-			isGUI = false // Fall back to non-GUI mode
-		}
+	return cfg
+}
+
+// applyTemplate applies the template context to s, returning s unchanged on error.
+func (p *Packager) applyTemplate(source, s string) string {
+	if s == "" || !strings.Contains(s, "{{") {
+		return s
 	}
+	out, err := p.tmplCtx.Apply(source, s)
+	if err != nil {
+		log.Warn("Failed to apply template", "error", err)
+		return s
+	}
+	return out
+}
 
-	data := map[string]interface{}{
-		"Name":         name,
-		"Arch":         arch,
-		"Version":      version,
-		"Maintainer":   p.config.Maintainer,
-		"Description":  p.config.Description,
-		"Vendor":       p.config.Vendor,
-		"Homepage":     p.config.Homepage,
-		"License":      p.config.License,
-		"BinaryPath":   binary.Path,
-		"BinaryName":   binary.Name,
-		"Bindir":       bindir,
-		"Contents":     p.config.Contents,
-		"Dependencies": p.config.Dependencies,
-		"IsGUI":        isGUI,
-		"AppID":        appID,
-		"DesktopFile":  desktopFile,
-		"IconPath":     iconPath,
+// chglogEntry is a single release in the chglog format nfpm reads.
+type chglogEntry struct {
+	Semver   string         `yaml:"semver"`
+	Date     time.Time      `yaml:"date"`
+	Packager string         `yaml:"packager"`
+	Changes  []chglogChange `yaml:"changes"`
+}
+
+type chglogChange struct {
+	Note string `yaml:"note"`
+}
+
+// writeChangelog writes a chglog file for this release from the changelog in
+// the template context. It reports false when there is no changelog to write.
+func (p *Packager) writeChangelog(path, version string) (bool, error) {
+	notes := p.tmplCtx.Get("Changelog")
+	if strings.TrimSpace(notes) == "" {
+		return false, nil
 	}
 
-	f, err := os.Create(path)
-	if err != nil {
-		return err
+	entry := chglogEntry{
+		Semver:   version,
+		Date:     time.Now().UTC(),
+		Packager: p.config.Maintainer,
+	}
+	for _, line := range strings.Split(notes, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		line = strings.TrimSpace(strings.TrimLeft(line, "-*"))
+		entry.Changes = append(entry.Changes, chglogChange{Note: line})
+	}
+	if len(entry.Changes) == 0 {
+		return false, nil
 	}
-	defer f.Close()
-
-	return tmpl.Execute(f, data)
-}
-
-// generateDesktopFile creates a .desktop file for GUI applications
-func (p *Packager) generateDesktopFile(path string, binary artifact.Artifact, guiConfig *config.GUIConfig, bindir string) error {
-	desktopTemplate := `[Desktop Entry]
-Type=Application
-Name={{ .Name }}
-{{ if .GenericName }}GenericName={{ .GenericName }}
-{{ end }}{{ if .Comment }}Comment={{ .Comment }}
-{{ end }}Exec={{ .Exec }}
-{{ if .Icon }}Icon={{ .Icon }}
-{{ end }}Terminal={{ .Terminal }}
-{{ if .Categories }}Categories={{ .Categories }}
-{{ end }}{{ if .Keywords }}Keywords={{ .Keywords }}
-{{ end }}{{ if .MimeTypes }}MimeType={{ .MimeTypes }}
-{{ end }}StartupNotify={{ .StartupNotify }}
-`
 
-	tmpl, err := template.New("desktop").Parse(desktopTemplate)
+	data, err := yaml.Marshal([]chglogEntry{entry})
 	if err != nil {
-		return err
+		return false, err
 	}
 
-	name := binary.Name
-	comment := p.config.Description
-	categories := "Utility;"
-	keywords := ""
-	genericName := ""
-	icon := binary.Name
-	terminal := "false"
-	startupNotify := "true"
-	mimeTypes := ""
+	return true, os.WriteFile(path, data, 0644)
+}
 
-	if guiConfig != nil {
-		if guiConfig.Name != "" {
-			name = guiConfig.Name
-		}
-		if guiConfig.Comment != "" {
-			comment = guiConfig.Comment
-		}
-		if guiConfig.GenericName != "" {
-			genericName = guiConfig.GenericName
-		}
-		if len(guiConfig.Categories) > 0 {
-			categories = strings.Join(guiConfig.Categories, ";") + ";"
-		}
-		if len(guiConfig.Keywords) > 0 {
-			keywords = strings.Join(guiConfig.Keywords, ";") + ";"
-		}
-		if guiConfig.Terminal {
-			terminal = "true"
-		}
-		if !guiConfig.StartupNotify {
-			startupNotify = "false"
-		}
-		if len(guiConfig.MimeTypes) > 0 {
-			mimeTypes = strings.Join(guiConfig.MimeTypes, ";") + ";"
+// guiConfig returns the gui settings of the binary's build, and whether it
+// is a gui build
+func (p *Packager) guiConfig(binary artifact.Artifact) (*config.GUIConfig, bool) {
+	if p.allConfigs == nil {
+		return nil, false
+	}
+	for _, build := range p.allConfigs.Builds {
+		if build.ID == binary.BuildID && build.Type == "gui" {
+			return build.GUI, true
 		}
 	}
+	return nil, false
+}
+
+// desktopContents stages the desktop file and the hicolor icons of a GUI
+// binary in dist and returns the package contents installing them
+func (p *Packager) desktopContents(binary artifact.Artifact, gui *config.GUIConfig, bindir string) ([]nfpmContent, error) {
+	appID := binary.Name
+	name, comment := binary.Name, p.config.Description
+	if gui != nil && gui.Name != "" {
+		name = gui.Name
+	}
+	if gui != nil && gui.Comment != "" {
+		comment = gui.Comment
+	}
+
+	entry := desktop.Entry{
+		Name:    name,
+		Comment: comment,
+		Exec:    filepath.Join(bindir, binary.Name),
+		Icon:    appID,
+		GUI:     gui,
+	}
+	if p.allConfigs != nil {
+		entry.Localizations = p.allConfigs.Localizations
+	}
+	if err := entry.Validate(); err != nil {
+		return nil, err
+	}
 
-	data := map[string]interface{}{
-		"Name":          name,
-		"GenericName":   genericName,
-		"Comment":       comment,
-		"Exec":          filepath.Join(bindir, binary.Name),
-		"Icon":          icon,
-		"Terminal":      terminal,
-		"Categories":    categories,
-		"Keywords":      keywords,
-		"MimeTypes":     mimeTypes,
-		"StartupNotify": startupNotify,
+	stageDir := filepath.Join(p.distDir, "nfpm-desktop", appID)
+	if err := os.RemoveAll(stageDir); err != nil {
+		return nil, err
 	}
+	desktopFile := filepath.Join(stageDir, appID+".desktop")
+	if err := os.MkdirAll(stageDir, 0755); err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(desktopFile, []byte(entry.String()), 0644); err != nil {
+		return nil, err
+	}
+
+	contents := []nfpmContent{{
+		Src:  desktopFile,
+		Dst:  "/usr/share/applications/" + appID + ".desktop",
+		Type: "file",
+	}}
 
-	f, err := os.Create(path)
+	iconPath := resolveGUIIconPath(gui, p.distDir, appID)
+	if iconPath == "" {
+		return contents, nil
+	}
+	themeDir := filepath.Join(stageDir, "hicolor")
+	icons, err := desktop.InstallIcons(themeDir, iconPath, appID)
 	if err != nil {
-		return err
+		return nil, err
+	}
+	for _, rel := range icons {
+		contents = append(contents, nfpmContent{
+			Src:  filepath.Join(themeDir, rel),
+			Dst:  "/usr/share/icons/hicolor/" + filepath.ToSlash(rel),
+			Type: "file",
+		})
 	}
-	defer f.Close()
+	return contents, nil
+}
+
+// desktopRefresh updates the desktop database and the hicolor icon cache,
+// when the tools are installed
+const desktopRefresh = `if command -v update-desktop-database >/dev/null 2>&1; then
+	update-desktop-database -q /usr/share/applications || true
+fi
+if command -v gtk-update-icon-cache >/dev/null 2>&1; then
+	gtk-update-icon-cache -q -t -f /usr/share/icons/hicolor || true
+fi
+`
 
-	return tmpl.Execute(f, data)
+// withDesktopRefresh writes a maintainer script running desktopRefresh
+// followed by the configured script, if any, and returns its path
+func (p *Packager) withDesktopRefresh(script, name, format, kind string) (string, error) {
+	header := "#!/bin/sh\n"
+	body := desktopRefresh
+	if script != "" {
+		data, err := os.ReadFile(script)
+		if err != nil {
+			return "", fmt.Errorf("failed to read %s script: %w", kind, err)
+		}
+		user := string(data)
+		if strings.HasPrefix(user, "#!") {
+			line, rest, _ := strings.Cut(user, "\n")
+			header, user = line+"\n", rest
+		}
+		body += "\n" + user
+	}
+
+	path := filepath.Join(p.distDir, fmt.Sprintf("nfpm-%s-%s-%s.sh", name, format, kind))
+	if err := os.WriteFile(path, []byte(header+body), 0755); err != nil {
+		return "", err
+	}
+	return path, nil
 }
 
 // resolveGUIIconPath returns a usable icon path, generating one that reflects
@@ -553,9 +770,15 @@ func (p *Packager) runFpmMulti(ctx context.Context, binaries []artifact.Artifact
 		bindir = "/usr/bin"
 	}
 
+	// fpm calls the Arch Linux format "pacman"
+	fpmFormat := format
+	if format == "archlinux" {
+		fpmFormat = "pacman"
+	}
+
 	args := []string{
 		"-s", "dir",
-		"-t", format,
+		"-t", fpmFormat,
 		"-n", name,
 		"-v", version,
 		"-a", arch,
@@ -584,6 +807,22 @@ func (p *Packager) runFpmMulti(ctx context.Context, binaries []artifact.Artifact
 		args = append(args, "-d", dep)
 	}
 
+	// Add maintainer scripts
+	cfg := p.resolvedConfig(format)
+	for _, script := range []struct {
+		flag string
+		path string
+	}{
+		{"--before-install", cfg.Scripts.PreInstall},
+		{"--after-install", cfg.Scripts.PostInstall},
+		{"--before-remove", cfg.Scripts.PreRemove},
+		{"--after-remove", cfg.Scripts.PostRemove},
+	} {
+		if script.path != "" {
+			args = append(args, script.flag, script.path)
+		}
+	}
+
 	// Add all binaries
 	for _, binary := range binaries {
 		args = append(args, binary.Path+"="+binary.Name)
@@ -637,11 +876,48 @@ func normalizeArch(arch, format string) string {
 		default:
 			return arch
 		}
+	case "archlinux":
+		switch arch {
+		case "amd64":
+			return "x86_64"
+		case "386":
+			return "i686"
+		case "arm64":
+			return "aarch64"
+		case "arm":
+			return "armv7h"
+		default:
+			return arch
+		}
 	default:
 		return arch
 	}
 }
 
+// isSupportedFormat reports whether nfpm can build the package format.
+func isSupportedFormat(format string) bool {
+	switch format {
+	case "deb", "rpm", "apk", "archlinux":
+		return true
+	default:
+		return false
+	}
+}
+
+// packageFileName returns the conventional file name for a package.
+func packageFileName(name, version, release, arch, format string) string {
+	if release == "" {
+		release = "1"
+	}
+
+	switch format {
+	case "archlinux":
+		return fmt.Sprintf("%s-%s-%s-%s.pkg.tar.zst", name, version, release, arch)
+	default:
+		return fmt.Sprintf("%s_%s_%s.%s", name, version, arch, format)
+	}
+}
+
 // MultiPackager builds packages for multiple configurations.
 type MultiPackager struct {
 	configs    []config.NFPM
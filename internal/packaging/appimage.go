@@ -0,0 +1,578 @@
+package packaging
+
+import (
+	"bytes"
+	"context"
+	"debug/elf"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	goruntime "runtime"
+	"slices"
+	"strings"
+
+	"github.com/charmbracelet/log"
+	"github.com/oarkflow/releaser/internal/artifact"
+	"github.com/oarkflow/releaser/internal/assets"
+	"github.com/oarkflow/releaser/internal/checksum"
+	"github.com/oarkflow/releaser/internal/config"
+	"github.com/oarkflow/releaser/internal/desktop"
+	"github.com/oarkflow/releaser/internal/fsutil"
+	"github.com/oarkflow/releaser/internal/httpclient"
+	"github.com/oarkflow/releaser/internal/tmpl"
+)
+
+// appImageRuntimeURL is where type 2 runtimes are downloaded from, by
+// release and AppImage architecture
+const appImageRuntimeURL = "https://github.com/AppImage/type2-runtime/releases/download/%s/runtime-%s"
+
+// appImageMachines maps AppImage architectures to the ELF machine their
+// runtime must be built for
+var appImageMachines = map[string]elf.Machine{
+	"x86_64":  elf.EM_X86_64,
+	"i686":    elf.EM_386,
+	"aarch64": elf.EM_AARCH64,
+	"armhf":   elf.EM_ARM,
+}
+
+// AppImageConfig represents AppImage build configuration
+type AppImageConfig struct {
+	ID                string             `yaml:"id,omitempty"`
+	Builds            []string           `yaml:"builds,omitempty"`
+	Name              string             `yaml:"name,omitempty"`
+	Icon              string             `yaml:"icon,omitempty"`
+	Desktop           string             `yaml:"desktop,omitempty"`
+	Description       string             `yaml:"description,omitempty"`
+	Categories        []string           `yaml:"categories,omitempty"`
+	Terminal          bool               `yaml:"terminal,omitempty"`
+	ExtraFiles        []config.ExtraFile `yaml:"extra_files,omitempty"`
+	Architecture      string             `yaml:"architecture,omitempty"`
+	UpdateInformation string             `yaml:"update_information,omitempty"`
+	Runtime           string             `yaml:"runtime,omitempty"`
+	RuntimeVersion    string             `yaml:"runtime_version,omitempty"`
+	RuntimeSHA256     map[string]string  `yaml:"runtime_sha256,omitempty"`
+	// GUIs holds the gui settings of each build, by build ID
+	GUIs map[string]*config.GUIConfig `yaml:"-"`
+	// Localizations translate the desktop file, by language
+	Localizations map[string]config.Localization `yaml:"-"`
+}
+
+// AppImageBuilder creates AppImage packages
+type AppImageBuilder struct {
+	config  AppImageConfig
+	tmplCtx *tmpl.Context
+	manager *artifact.Manager
+	distDir string
+}
+
+// NewAppImageBuilder creates a new AppImage builder
+func NewAppImageBuilder(cfg AppImageConfig, tmplCtx *tmpl.Context, manager *artifact.Manager, distDir string) *AppImageBuilder {
+	return &AppImageBuilder{
+		config:  cfg,
+		tmplCtx: tmplCtx,
+		manager: manager,
+		distDir: distDir,
+	}
+}
+
+// Build creates an AppImage for each selected Linux binary. appimagetool is
+// used when it is installed; otherwise the AppDir is packed with mksquashfs
+// and prefixed with a type 2 runtime for the binary's architecture.
+func (b *AppImageBuilder) Build(ctx context.Context) error {
+	binaries := b.manager.Filter(func(a artifact.Artifact) bool {
+		if a.Type != artifact.TypeBinary || a.Goos != "linux" {
+			return false
+		}
+		if b.config.Architecture != "" && a.Goarch != b.config.Architecture {
+			return false
+		}
+		return len(b.config.Builds) == 0 || slices.Contains(b.config.Builds, a.BuildID)
+	})
+
+	if len(binaries) == 0 {
+		log.Warn("No Linux binaries found for AppImage")
+		return nil
+	}
+
+	tool, _ := exec.LookPath("appimagetool")
+	if tool == "" {
+		if _, err := exec.LookPath("mksquashfs"); err != nil {
+			log.Warn("Skipping AppImage: neither appimagetool nor mksquashfs (squashfs-tools) found")
+			return nil
+		}
+	}
+
+	log.Info("Building AppImage")
+
+	for _, binary := range binaries {
+		if err := b.createAppImage(ctx, binary, tool); err != nil {
+			return fmt.Errorf("failed to create AppImage for %s: %w", binary.Name, err)
+		}
+	}
+
+	return nil
+}
+
+// createAppImage creates a single AppImage, and its .zsync file when update
+// information is configured
+func (b *AppImageBuilder) createAppImage(ctx context.Context, binary artifact.Artifact, tool string) error {
+	arch := appImageArch(binary.Goarch)
+	if arch == "" {
+		log.Warn("Skipping AppImage: unsupported architecture", "binary", binary.Name, "arch", binary.Goarch)
+		return nil
+	}
+
+	name := b.config.Name
+	if name == "" {
+		name = b.tmplCtx.Get("ProjectName")
+	}
+	version := b.tmplCtx.Get("Version")
+
+	appDir := filepath.Join(b.distDir, fmt.Sprintf("%s-%s.AppDir", binary.Name, arch))
+	if err := os.RemoveAll(appDir); err != nil {
+		return err
+	}
+	defer os.RemoveAll(appDir)
+
+	if err := b.assembleAppDir(appDir, binary, name); err != nil {
+		return fmt.Errorf("failed to assemble AppDir: %w", err)
+	}
+
+	updateInfo := ""
+	if b.config.UpdateInformation != "" {
+		artifactCtx := b.tmplCtx.ForArtifact(binary)
+		artifactCtx.Set("AppImageArch", arch)
+		var err error
+		updateInfo, err = artifactCtx.Apply("appimages.update_information", b.config.UpdateInformation)
+		if err != nil {
+			return fmt.Errorf("failed to apply template to update_information: %w", err)
+		}
+	}
+
+	appImageName := fmt.Sprintf("%s-%s-%s.AppImage", name, version, arch)
+	appImagePath := filepath.Join(b.distDir, appImageName)
+	zsyncPath := appImagePath + ".zsync"
+	for _, stale := range []string{appImagePath, zsyncPath} {
+		if err := os.Remove(stale); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+
+	// appimagetool fetches a runtime for ARCH itself; one is only needed
+	// up front when configured, when packing without appimagetool or when
+	// cross-packaging, as older appimagetools embed their own runtime
+	runtimePath := ""
+	if b.config.Runtime != "" || tool == "" || arch != appImageArch(goruntime.GOARCH) {
+		var err error
+		if runtimePath, err = b.runtimeFile(ctx, arch); err != nil {
+			return err
+		}
+	}
+
+	if tool != "" {
+		if err := b.runAppImageTool(ctx, tool, appDir, appImagePath, arch, runtimePath, updateInfo); err != nil {
+			return err
+		}
+	} else {
+		if err := b.packAppImage(ctx, appDir, appImagePath, runtimePath, updateInfo); err != nil {
+			return err
+		}
+	}
+
+	if err := b.manager.Add(artifact.Artifact{
+		Name:    appImageName,
+		Path:    appImagePath,
+		Type:    artifact.TypeLinuxPackage,
+		Goos:    "linux",
+		Goarch:  binary.Goarch,
+		Goarm:   binary.Goarm,
+		BuildID: binary.BuildID,
+		Extra: map[string]interface{}{
+			artifact.ExtraFormat: "appimage",
+		},
+	}); err != nil {
+		return err
+	}
+	log.Info("AppImage created", "name", appImageName)
+
+	if updateInfo == "" {
+		return nil
+	}
+
+	// appimagetool writes the .zsync file itself when zsyncmake is installed
+	if _, err := os.Stat(zsyncPath); err != nil {
+		if err := makeZsync(ctx, appImagePath, zsyncPath); err != nil {
+			log.Warn("Skipping AppImage zsync file", "name", appImageName, "error", err)
+			return nil
+		}
+	}
+	if err := b.manager.Add(artifact.Artifact{
+		Name:    filepath.Base(zsyncPath),
+		Path:    zsyncPath,
+		Type:    artifact.TypeUploadable,
+		Goos:    "linux",
+		Goarch:  binary.Goarch,
+		Goarm:   binary.Goarm,
+		BuildID: binary.BuildID,
+		Extra: map[string]interface{}{
+			artifact.ExtraFormat: "zsync",
+		},
+	}); err != nil {
+		return err
+	}
+	log.Info("AppImage zsync file created", "name", filepath.Base(zsyncPath))
+	return nil
+}
+
+// assembleAppDir lays out the AppDir: the binary in usr/bin, an AppRun
+// entry point, the desktop file and the icon at the root and in usr/share
+func (b *AppImageBuilder) assembleAppDir(appDir string, binary artifact.Artifact, name string) error {
+	gui := b.config.GUIs[binary.BuildID]
+
+	binPath := filepath.Join(appDir, "usr", "bin", binary.Name)
+	if err := fsutil.CopyFile(binary.Path, binPath); err != nil {
+		return fmt.Errorf("failed to copy binary: %w", err)
+	}
+	if err := os.Chmod(binPath, 0755); err != nil {
+		return err
+	}
+
+	appRun := fmt.Sprintf(`#!/bin/sh
+HERE="$(dirname "$(readlink -f "$0")")"
+export PATH="${HERE}/usr/bin:${PATH}"
+exec "${HERE}/usr/bin/%s" "$@"
+`, binary.Name)
+	if err := os.WriteFile(filepath.Join(appDir, "AppRun"), []byte(appRun), 0755); err != nil {
+		return err
+	}
+
+	// The desktop file and icon are named after the binary, which is what
+	// the desktop file's Exec and Icon keys refer to
+	var desktopFile []byte
+	if b.config.Desktop != "" {
+		data, err := os.ReadFile(b.config.Desktop)
+		if err != nil {
+			return fmt.Errorf("failed to read desktop file: %w", err)
+		}
+		desktopFile = data
+	} else {
+		entry, err := b.generateDesktopFile(name, binary.Name, gui)
+		if err != nil {
+			return err
+		}
+		desktopFile = []byte(entry)
+	}
+	for _, path := range []string{
+		filepath.Join(appDir, binary.Name+".desktop"),
+		filepath.Join(appDir, "usr", "share", "applications", binary.Name+".desktop"),
+	} {
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return err
+		}
+		if err := os.WriteFile(path, desktopFile, 0644); err != nil {
+			return err
+		}
+	}
+
+	iconPath := b.config.Icon
+	if iconPath == "" && gui != nil {
+		iconPath = gui.Icon
+	}
+	if iconPath == "" {
+		iconSet, err := assets.EnsureAppIcon(name, b.distDir)
+		if err != nil {
+			return fmt.Errorf("failed to generate default icon: %w", err)
+		}
+		iconPath = iconSet.PNG
+	}
+	return installAppImageIcon(appDir, iconPath, binary.Name)
+}
+
+// generateDesktopFile generates the .desktop file from the AppImage config,
+// falling back to the build's gui settings
+func (b *AppImageBuilder) generateDesktopFile(name, executable string, gui *config.GUIConfig) (string, error) {
+	if b.config.Name == "" && gui != nil && gui.Name != "" {
+		name = gui.Name
+	}
+	entry := desktop.Entry{
+		Name:          name,
+		Comment:       b.config.Description,
+		Exec:          executable,
+		Icon:          executable,
+		Categories:    b.config.Categories,
+		Terminal:      b.config.Terminal,
+		GUI:           gui,
+		Localizations: b.config.Localizations,
+	}
+	if err := entry.Validate(); err != nil {
+		return "", err
+	}
+	return entry.String(), nil
+}
+
+// installAppImageIcon installs the icon at the AppDir root, as .DirIcon and
+// in the hicolor theme
+func installAppImageIcon(appDir, src, name string) error {
+	ext := strings.ToLower(filepath.Ext(src))
+	if ext != ".png" && ext != ".svg" {
+		return fmt.Errorf("unsupported icon %s: AppImages need a png or svg icon", src)
+	}
+
+	if err := fsutil.CopyFile(src, filepath.Join(appDir, name+ext)); err != nil {
+		return fmt.Errorf("failed to copy icon: %w", err)
+	}
+	if err := os.Symlink(name+ext, filepath.Join(appDir, ".DirIcon")); err != nil {
+		return err
+	}
+	_, err := desktop.InstallIcons(filepath.Join(appDir, "usr", "share", "icons", "hicolor"), src, name)
+	return err
+}
+
+// runAppImageTool packs the AppDir with appimagetool
+func (b *AppImageBuilder) runAppImageTool(ctx context.Context, tool, appDir, output, arch, runtimePath, updateInfo string) error {
+	var args []string
+	if runtimePath != "" {
+		args = append(args, "--runtime-file", runtimePath)
+	}
+	if updateInfo != "" {
+		args = append(args, "--updateinformation", updateInfo)
+	}
+	args = append(args, appDir, output)
+
+	cmd := exec.CommandContext(ctx, tool, args...)
+	cmd.Dir = b.distDir
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	cmd.Env = append(os.Environ(), "ARCH="+arch)
+
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("appimagetool failed: %w", err)
+	}
+	return nil
+}
+
+// packAppImage builds the AppImage without appimagetool: the runtime, with
+// the update information embedded, followed by the AppDir as squashfs
+func (b *AppImageBuilder) packAppImage(ctx context.Context, appDir, output, runtimePath, updateInfo string) error {
+	runtimeData, err := os.ReadFile(runtimePath)
+	if err != nil {
+		return fmt.Errorf("failed to read runtime: %w", err)
+	}
+	if updateInfo != "" {
+		if runtimeData, err = embedUpdateInformation(runtimeData, updateInfo); err != nil {
+			return err
+		}
+	}
+
+	squashfsPath := output + ".squashfs"
+	defer os.Remove(squashfsPath)
+
+	cmd := exec.CommandContext(ctx, "mksquashfs", appDir, squashfsPath,
+		"-root-owned", "-noappend", "-no-progress", "-comp", "zstd")
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("mksquashfs failed: %w", err)
+	}
+
+	squashfs, err := os.Open(squashfsPath)
+	if err != nil {
+		return err
+	}
+	defer squashfs.Close()
+
+	out, err := os.OpenFile(output, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0755)
+	if err != nil {
+		return err
+	}
+	if _, err := out.Write(runtimeData); err != nil {
+		out.Close()
+		return err
+	}
+	if _, err := io.Copy(out, squashfs); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+// runtimeFile returns the configured runtime, or the type 2 runtime for
+// arch of the pinned release, downloaded once into dist and checked against
+// its sha256, after checking it matches arch
+func (b *AppImageBuilder) runtimeFile(ctx context.Context, arch string) (string, error) {
+	path := b.config.Runtime
+	if path == "" {
+		version, sum := b.config.RuntimeVersion, b.config.RuntimeSHA256[arch]
+		if version == "" || sum == "" {
+			return "", fmt.Errorf("an AppImage runtime for %s is needed: set runtime, or runtime_version and runtime_sha256.%s", arch, arch)
+		}
+		path = filepath.Join(b.distDir, ".releaser-appimage", version, "runtime-"+arch)
+		if _, err := os.Stat(path); os.IsNotExist(err) {
+			log.Info("Downloading AppImage runtime", "version", version, "arch", arch)
+			if err := downloadFile(ctx, fmt.Sprintf(appImageRuntimeURL, version, arch), path); err != nil {
+				return "", fmt.Errorf("failed to download AppImage runtime for %s: %w", arch, err)
+			}
+		}
+		ok, err := checksum.VerifyChecksum(path, sum, checksum.AlgorithmSHA256)
+		if err != nil {
+			return "", err
+		}
+		if !ok {
+			os.Remove(path)
+			return "", fmt.Errorf("AppImage runtime %s %s does not match runtime_sha256.%s", version, arch, arch)
+		}
+	}
+
+	f, err := elf.Open(path)
+	if err != nil {
+		return "", fmt.Errorf("invalid AppImage runtime %s: %w", path, err)
+	}
+	defer f.Close()
+	if f.Machine != appImageMachines[arch] {
+		return "", fmt.Errorf("AppImage runtime %s is built for %s, not %s", path, f.Machine, arch)
+	}
+	return path, nil
+}
+
+// embedUpdateInformation writes the update information into the runtime's
+// .upd_info section, where AppImageUpdate reads it
+func embedUpdateInformation(runtimeData []byte, updateInfo string) ([]byte, error) {
+	f, err := elf.NewFile(bytes.NewReader(runtimeData))
+	if err != nil {
+		return nil, fmt.Errorf("invalid AppImage runtime: %w", err)
+	}
+	section := f.Section(".upd_info")
+	if section == nil {
+		return nil, fmt.Errorf("AppImage runtime has no .upd_info section")
+	}
+	if uint64(len(updateInfo)) >= section.Size {
+		return nil, fmt.Errorf("update_information is longer than the %d bytes the runtime allows", section.Size-1)
+	}
+
+	data := slices.Clone(runtimeData)
+	copy(data[section.Offset:], updateInfo)
+	return data, nil
+}
+
+// makeZsync writes the .zsync file AppImageUpdate downloads deltas with
+func makeZsync(ctx context.Context, appImagePath, zsyncPath string) error {
+	if _, err := exec.LookPath("zsyncmake"); err != nil {
+		return fmt.Errorf("zsyncmake not found")
+	}
+
+	cmd := exec.CommandContext(ctx, "zsyncmake",
+		"-u", filepath.Base(appImagePath), "-o", zsyncPath, appImagePath)
+	cmd.Dir = filepath.Dir(appImagePath)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("zsyncmake failed: %w", err)
+	}
+	return nil
+}
+
+// downloadFile downloads url to path
+func downloadFile(ctx context.Context, url, path string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := httpclient.Client().Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("GET %s: status %d", url, resp.StatusCode)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	f, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(f, resp.Body); err != nil {
+		f.Close()
+		os.Remove(tmp)
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// appImageArch maps a GOARCH to the architecture name AppImages use
+func appImageArch(goarch string) string {
+	switch goarch {
+	case "amd64":
+		return "x86_64"
+	case "386":
+		return "i686"
+	case "arm64":
+		return "aarch64"
+	case "arm":
+		return "armhf"
+	default:
+		return ""
+	}
+}
+
+// BuildAllAppImages builds all configured AppImage packages
+func BuildAllAppImages(ctx context.Context, cfg *config.Config, tmplCtx *tmpl.Context, manager *artifact.Manager, distDir string) error {
+	if len(cfg.AppImages) == 0 {
+		return nil
+	}
+
+	log.Info("Building AppImage packages", "count", len(cfg.AppImages))
+
+	guis := make(map[string]*config.GUIConfig)
+	for _, build := range cfg.Builds {
+		if build.GUI != nil {
+			guis[build.ID] = build.GUI
+		}
+	}
+
+	for _, appImageCfg := range cfg.AppImages {
+		if appImageCfg.Skip == "true" {
+			log.Info("Skipping AppImage", "id", appImageCfg.ID)
+			continue
+		}
+
+		// Convert categories from string to slice if needed
+		var categories []string
+		if appImageCfg.Categories != "" {
+			categories = strings.Split(strings.TrimSuffix(appImageCfg.Categories, ";"), ";")
+		}
+
+		builder := NewAppImageBuilder(AppImageConfig{
+			ID:                appImageCfg.ID,
+			Builds:            appImageCfg.Builds,
+			Name:              appImageCfg.Name,
+			Icon:              appImageCfg.Icon,
+			Desktop:           appImageCfg.Desktop,
+			Description:       appImageCfg.Description,
+			Categories:        categories,
+			Terminal:          appImageCfg.Terminal,
+			ExtraFiles:        appImageCfg.ExtraFiles,
+			UpdateInformation: appImageCfg.UpdateInformation,
+			Runtime:           appImageCfg.Runtime,
+			RuntimeVersion:    appImageCfg.RuntimeVersion,
+			RuntimeSHA256:     appImageCfg.RuntimeSHA256,
+			GUIs:              guis,
+			Localizations:     cfg.Localizations,
+		}, tmplCtx, manager, distDir)
+
+		if err := builder.Build(ctx); err != nil {
+			return fmt.Errorf("failed to build AppImage %s: %w", appImageCfg.ID, err)
+		}
+	}
+
+	return nil
+}
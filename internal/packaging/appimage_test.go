@@ -0,0 +1,65 @@
+package packaging
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/oarkflow/releaser/internal/checksum"
+	"github.com/oarkflow/releaser/internal/fsutil"
+)
+
+func TestAppImageRuntimeIsVerified(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("the test binary stands in for an ELF runtime")
+	}
+	arch := appImageArch(runtime.GOARCH)
+	exe, err := os.Executable()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sum, err := checksum.CalculateForFile(exe, checksum.AlgorithmSHA256)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		cfg  AppImageConfig
+		err  string
+	}{
+		{name: "pinned", cfg: AppImageConfig{RuntimeVersion: "20250101", RuntimeSHA256: map[string]string{arch: sum}}},
+		{name: "not pinned", cfg: AppImageConfig{RuntimeSHA256: map[string]string{arch: sum}}, err: "runtime_version"},
+		{name: "no sha256", cfg: AppImageConfig{RuntimeVersion: "20250101"}, err: "runtime_sha256." + arch},
+		{name: "mismatch", cfg: AppImageConfig{RuntimeVersion: "20250101", RuntimeSHA256: map[string]string{arch: strings.Repeat("0", 64)}}, err: "does not match"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// The runtime is already downloaded
+			dist := t.TempDir()
+			cached := filepath.Join(dist, ".releaser-appimage", "20250101", "runtime-"+arch)
+			if err := fsutil.CopyFile(exe, cached); err != nil {
+				t.Fatal(err)
+			}
+
+			b := NewAppImageBuilder(tt.cfg, nil, nil, dist)
+			path, err := b.runtimeFile(context.Background(), arch)
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Fatalf("runtimeFile() = %s, %v, want error %q", path, err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if path != cached {
+				t.Errorf("runtimeFile() = %s, want %s", path, cached)
+			}
+		})
+	}
+}
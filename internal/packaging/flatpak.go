@@ -0,0 +1,435 @@
+package packaging
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	goruntime "runtime"
+	"slices"
+	"strings"
+
+	"github.com/charmbracelet/log"
+
+	"github.com/oarkflow/releaser/internal/artifact"
+	"github.com/oarkflow/releaser/internal/assets"
+	"github.com/oarkflow/releaser/internal/config"
+	"github.com/oarkflow/releaser/internal/desktop"
+	"github.com/oarkflow/releaser/internal/tmpl"
+)
+
+// Flatpak defaults
+const (
+	defaultFlatpakRuntime        = "org.freedesktop.Platform"
+	defaultFlatpakRuntimeVersion = "24.08"
+	defaultFlatpakSDK            = "org.freedesktop.Sdk"
+	defaultFlatpakBranch         = "master"
+)
+
+// defaultFlatpakPermissions apply when neither permissions nor finish_args
+// are configured
+var defaultFlatpakPermissions = []string{"network", "ipc", "x11"}
+
+// flatpakPermissions maps permission names to finish-args
+var flatpakPermissions = map[string]string{
+	"network":      "--share=network",
+	"ipc":          "--share=ipc",
+	"x11":          "--socket=x11",
+	"fallback-x11": "--socket=fallback-x11",
+	"wayland":      "--socket=wayland",
+	"pulseaudio":   "--socket=pulseaudio",
+	"ssh-auth":     "--socket=ssh-auth",
+	"dri":          "--device=dri",
+	"devices":      "--device=all",
+	"home":         "--filesystem=home",
+	"host":         "--filesystem=host",
+}
+
+// flatpakPermissionPrefixes maps "name=value" permissions to finish-args
+var flatpakPermissionPrefixes = map[string]string{
+	"filesystem": "--filesystem=",
+	"talk":       "--talk-name=",
+	"own":        "--own-name=",
+	"env":        "--env=",
+}
+
+// FlatpakConfig represents Flatpak build configuration
+type FlatpakConfig struct {
+	ID             string   `yaml:"id,omitempty"`
+	Builds         []string `yaml:"builds,omitempty"`
+	AppID          string   `yaml:"app_id"`
+	Runtime        string   `yaml:"runtime,omitempty"`
+	RuntimeVersion string   `yaml:"runtime_version,omitempty"`
+	SDK            string   `yaml:"sdk,omitempty"`
+	Command        string   `yaml:"command,omitempty"`
+	FinishArgs     []string `yaml:"finish_args,omitempty"`
+	Permissions    []string `yaml:"permissions,omitempty"`
+	Modules        []string `yaml:"modules,omitempty"`
+	Categories     []string `yaml:"categories,omitempty"`
+	Keywords       []string `yaml:"keywords,omitempty"`
+	Manifest       string   `yaml:"manifest,omitempty"`
+	Repo           string   `yaml:"repo,omitempty"`
+	Branch         string   `yaml:"branch,omitempty"`
+	// GUIs holds the gui settings of each build, by build ID
+	GUIs map[string]*config.GUIConfig `yaml:"-"`
+	// Localizations translate the desktop file, by language
+	Localizations map[string]config.Localization `yaml:"-"`
+}
+
+// flatpakManifest is a flatpak-builder manifest
+type flatpakManifest struct {
+	AppID          string        `json:"app-id"`
+	Runtime        string        `json:"runtime"`
+	RuntimeVersion string        `json:"runtime-version"`
+	SDK            string        `json:"sdk"`
+	Command        string        `json:"command"`
+	FinishArgs     []string      `json:"finish-args,omitempty"`
+	Modules        []interface{} `json:"modules"`
+}
+
+// flatpakModule is a manifest module
+type flatpakModule struct {
+	Name          string          `json:"name"`
+	Buildsystem   string          `json:"buildsystem"`
+	BuildCommands []string        `json:"build-commands"`
+	Sources       []flatpakSource `json:"sources"`
+}
+
+// flatpakSource is a module source
+type flatpakSource struct {
+	Type         string `json:"type"`
+	Path         string `json:"path"`
+	DestFilename string `json:"dest-filename,omitempty"`
+}
+
+// FlatpakBuilder creates Flatpak packages
+type FlatpakBuilder struct {
+	config  FlatpakConfig
+	tmplCtx *tmpl.Context
+	manager *artifact.Manager
+	distDir string
+}
+
+// NewFlatpakBuilder creates a new Flatpak builder
+func NewFlatpakBuilder(cfg FlatpakConfig, tmplCtx *tmpl.Context, manager *artifact.Manager, distDir string) *FlatpakBuilder {
+	return &FlatpakBuilder{
+		config:  cfg,
+		tmplCtx: tmplCtx,
+		manager: manager,
+		distDir: distDir,
+	}
+}
+
+// Build creates a Flatpak bundle for each selected Linux binary of the host
+// architecture. Without flatpak-builder only the manifest is written.
+func (b *FlatpakBuilder) Build(ctx context.Context) error {
+	binaries := b.manager.Filter(func(a artifact.Artifact) bool {
+		if a.Type != artifact.TypeBinary || a.Goos != "linux" || a.Goarch != goruntime.GOARCH {
+			return false
+		}
+		return len(b.config.Builds) == 0 || slices.Contains(b.config.Builds, a.BuildID)
+	})
+
+	if len(binaries) == 0 {
+		log.Warn("No Linux binaries found for Flatpak", "arch", goruntime.GOARCH)
+		return nil
+	}
+
+	log.Info("Building Flatpak package")
+
+	for _, binary := range binaries {
+		if err := b.createFlatpak(ctx, binary); err != nil {
+			return fmt.Errorf("failed to create Flatpak for %s: %w", binary.Name, err)
+		}
+	}
+
+	return nil
+}
+
+// createFlatpak creates a single Flatpak bundle
+func (b *FlatpakBuilder) createFlatpak(ctx context.Context, binary artifact.Artifact) error {
+	gui := b.config.GUIs[binary.BuildID]
+	appID := b.appID(gui)
+	version := b.tmplCtx.Get("Version")
+
+	// Generate manifest if not provided
+	manifestPath := b.config.Manifest
+	generated := manifestPath == ""
+	if generated {
+		manifestPath = filepath.Join(b.distDir, appID+".json")
+		if err := b.generateManifest(manifestPath, binary, appID, gui); err != nil {
+			return fmt.Errorf("failed to generate manifest: %w", err)
+		}
+	}
+
+	if _, err := exec.LookPath("flatpak-builder"); err != nil {
+		log.Warn("Skipping Flatpak build: flatpak-builder not found", "manifest", manifestPath)
+		return nil
+	}
+
+	buildDir := filepath.Join(b.distDir, "flatpak-build")
+	if err := os.MkdirAll(buildDir, 0755); err != nil {
+		return err
+	}
+
+	repoDir := b.config.Repo
+	if repoDir == "" {
+		repoDir = filepath.Join(b.distDir, "flatpak-repo")
+	}
+
+	branch := b.config.Branch
+	if branch == "" {
+		branch = defaultFlatpakBranch
+	}
+
+	args := []string{
+		"--force-clean",
+		"--repo=" + repoDir,
+		"--default-branch=" + branch,
+	}
+	// Generated manifests only have local sources, so nothing is fetched
+	if generated {
+		args = append(args, "--disable-download")
+	}
+	args = append(args, buildDir, manifestPath)
+
+	cmd := exec.CommandContext(ctx, "flatpak-builder", args...)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	cmd.Dir = b.distDir
+
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("flatpak-builder failed: %w", err)
+	}
+
+	// Create single-file bundle
+	bundleName := fmt.Sprintf("%s_%s.flatpak", appID, version)
+	bundlePath := filepath.Join(b.distDir, bundleName)
+
+	bundleCmd := exec.CommandContext(ctx, "flatpak", "build-bundle",
+		repoDir, bundlePath, appID, branch)
+	bundleCmd.Stdout = os.Stdout
+	bundleCmd.Stderr = os.Stderr
+
+	if err := bundleCmd.Run(); err != nil {
+		return fmt.Errorf("flatpak build-bundle failed: %w", err)
+	}
+
+	if err := b.manager.Add(artifact.Artifact{
+		Name:    bundleName,
+		Path:    bundlePath,
+		Type:    artifact.TypeLinuxPackage,
+		Goos:    "linux",
+		Goarch:  binary.Goarch,
+		BuildID: binary.BuildID,
+		Extra: map[string]interface{}{
+			artifact.ExtraFormat: "flatpak",
+		},
+	}); err != nil {
+		return err
+	}
+
+	log.Info("Flatpak created", "name", bundleName)
+	return nil
+}
+
+// appID returns the configured app id, the build's macOS bundle id or one
+// derived from the project name
+func (b *FlatpakBuilder) appID(gui *config.GUIConfig) string {
+	if b.config.AppID != "" {
+		return b.config.AppID
+	}
+	if gui != nil && gui.MacOS != nil && gui.MacOS.BundleID != "" {
+		return gui.MacOS.BundleID
+	}
+	return fmt.Sprintf("com.%s.%s", b.tmplCtx.Get("ProjectName"), b.tmplCtx.Get("ProjectName"))
+}
+
+// generateManifest writes a manifest that installs the prebuilt binary
+// with a desktop file and icons staged next to it, so building needs no
+// network access beyond an installed runtime and SDK
+func (b *FlatpakBuilder) generateManifest(path string, binary artifact.Artifact, appID string, gui *config.GUIConfig) error {
+	command := b.config.Command
+	if command == "" {
+		command = binary.Name
+	}
+
+	finishArgs, err := flatpakFinishArgs(b.config.Permissions, b.config.FinishArgs)
+	if err != nil {
+		return err
+	}
+
+	binaryPath, err := filepath.Abs(binary.Path)
+	if err != nil {
+		return err
+	}
+	stageDir, err := filepath.Abs(filepath.Join(b.distDir, "flatpak-"+appID))
+	if err != nil {
+		return err
+	}
+	if err := b.stageDesktopFiles(stageDir, appID, command, gui); err != nil {
+		return err
+	}
+
+	manifest := flatpakManifest{
+		AppID:          appID,
+		Runtime:        valueOr(b.config.Runtime, defaultFlatpakRuntime),
+		RuntimeVersion: valueOr(b.config.RuntimeVersion, defaultFlatpakRuntimeVersion),
+		SDK:            valueOr(b.config.SDK, defaultFlatpakSDK),
+		Command:        command,
+		FinishArgs:     finishArgs,
+	}
+	for _, module := range b.config.Modules {
+		if !filepath.IsAbs(module) {
+			if module, err = filepath.Abs(module); err != nil {
+				return err
+			}
+		}
+		manifest.Modules = append(manifest.Modules, module)
+	}
+	manifest.Modules = append(manifest.Modules, flatpakModule{
+		Name:        b.tmplCtx.Get("ProjectName"),
+		Buildsystem: "simple",
+		BuildCommands: []string{
+			fmt.Sprintf("install -Dm755 %s /app/bin/%s", command, command),
+			"cp -r share /app/",
+		},
+		Sources: []flatpakSource{
+			{Type: "file", Path: binaryPath, DestFilename: command},
+			{Type: "dir", Path: stageDir},
+		},
+	})
+
+	data, err := json.MarshalIndent(manifest, "", "    ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}
+
+// stageDesktopFiles writes the desktop file and icons, named after the app
+// id as Flatpak exports require, under dir/share
+func (b *FlatpakBuilder) stageDesktopFiles(dir, appID, command string, gui *config.GUIConfig) error {
+	if err := os.RemoveAll(dir); err != nil {
+		return err
+	}
+
+	name := b.tmplCtx.Get("ProjectName")
+	if gui != nil && gui.Name != "" {
+		name = gui.Name
+	}
+	entry := desktop.Entry{
+		Name:          name,
+		Exec:          command,
+		Icon:          appID,
+		Categories:    b.config.Categories,
+		Keywords:      b.config.Keywords,
+		GUI:           gui,
+		Localizations: b.config.Localizations,
+	}
+	if err := entry.Validate(); err != nil {
+		return err
+	}
+	desktopPath := filepath.Join(dir, "share", "applications", appID+".desktop")
+	if err := os.MkdirAll(filepath.Dir(desktopPath), 0755); err != nil {
+		return err
+	}
+	if err := os.WriteFile(desktopPath, []byte(entry.String()), 0644); err != nil {
+		return err
+	}
+
+	iconPath := ""
+	if gui != nil {
+		iconPath = gui.Icon
+	}
+	if iconPath == "" {
+		iconSet, err := assets.EnsureAppIcon(name, b.distDir)
+		if err != nil {
+			return fmt.Errorf("failed to generate default icon: %w", err)
+		}
+		iconPath = iconSet.PNG
+	}
+	_, err := desktop.InstallIcons(filepath.Join(dir, "share", "icons", "hicolor"), iconPath, appID)
+	return err
+}
+
+// flatpakFinishArgs converts permission names to finish-args and appends
+// the raw finish_args
+func flatpakFinishArgs(permissions, raw []string) ([]string, error) {
+	if len(permissions) == 0 && len(raw) == 0 {
+		permissions = defaultFlatpakPermissions
+	}
+
+	var args []string
+	for _, permission := range permissions {
+		if arg, ok := flatpakPermissions[permission]; ok {
+			args = append(args, arg)
+			continue
+		}
+		name, value, found := strings.Cut(permission, "=")
+		prefix, ok := flatpakPermissionPrefixes[name]
+		if !found || !ok {
+			return nil, fmt.Errorf("unknown flatpak permission %q", permission)
+		}
+		args = append(args, prefix+value)
+	}
+	return append(args, raw...), nil
+}
+
+// valueOr returns value, or fallback when value is empty
+func valueOr(value, fallback string) string {
+	if value == "" {
+		return fallback
+	}
+	return value
+}
+
+// BuildAllFlatpaks builds all configured Flatpak packages
+func BuildAllFlatpaks(ctx context.Context, cfg *config.Config, tmplCtx *tmpl.Context, manager *artifact.Manager, distDir string) error {
+	if len(cfg.Flatpaks) == 0 {
+		return nil
+	}
+
+	log.Info("Building Flatpak packages", "count", len(cfg.Flatpaks))
+
+	guis := make(map[string]*config.GUIConfig)
+	for _, build := range cfg.Builds {
+		if build.GUI != nil {
+			guis[build.ID] = build.GUI
+		}
+	}
+
+	for _, flatpakCfg := range cfg.Flatpaks {
+		if flatpakCfg.Skip == "true" {
+			log.Info("Skipping Flatpak", "id", flatpakCfg.ID)
+			continue
+		}
+
+		builder := NewFlatpakBuilder(FlatpakConfig{
+			ID:             flatpakCfg.ID,
+			Builds:         flatpakCfg.Builds,
+			AppID:          flatpakCfg.AppID,
+			Runtime:        flatpakCfg.Runtime,
+			RuntimeVersion: flatpakCfg.RuntimeVersion,
+			SDK:            flatpakCfg.SDK,
+			Command:        flatpakCfg.Command,
+			FinishArgs:     flatpakCfg.FinishArgs,
+			Permissions:    flatpakCfg.Permissions,
+			Modules:        flatpakCfg.Modules,
+			Categories:     flatpakCfg.Categories,
+			Keywords:       flatpakCfg.Keywords,
+			Manifest:       flatpakCfg.Manifest,
+			Branch:         flatpakCfg.Branch,
+			GUIs:           guis,
+			Localizations:  cfg.Localizations,
+		}, tmplCtx, manager, distDir)
+
+		if err := builder.Build(ctx); err != nil {
+			return fmt.Errorf("failed to build Flatpak %s: %w", flatpakCfg.ID, err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,425 @@
+package packaging
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/log"
+	"gopkg.in/yaml.v3"
+
+	"github.com/oarkflow/releaser/internal/artifact"
+	"github.com/oarkflow/releaser/internal/config"
+	"github.com/oarkflow/releaser/internal/fsutil"
+	"github.com/oarkflow/releaser/internal/tmpl"
+)
+
+// HelmBuilder packages Helm charts.
+type HelmBuilder struct {
+	config  config.Helm
+	tmplCtx *tmpl.Context
+	manager *artifact.Manager
+	distDir string
+}
+
+// NewHelmBuilder creates a new Helm chart builder.
+func NewHelmBuilder(cfg config.Helm, tmplCtx *tmpl.Context, manager *artifact.Manager, distDir string) *HelmBuilder {
+	return &HelmBuilder{
+		config:  cfg,
+		tmplCtx: tmplCtx,
+		manager: manager,
+		distDir: distDir,
+	}
+}
+
+// Build stages the chart with the release version in Chart.yaml and the
+// image tag in values.yaml, then packages it. Charts are packed natively
+// unless they are signed, which needs helm.
+func (b *HelmBuilder) Build(ctx context.Context) error {
+	chartPath := b.config.ChartPath
+	if chartPath == "" {
+		chartPath = "chart"
+	}
+	if _, err := os.Stat(filepath.Join(chartPath, "Chart.yaml")); err != nil {
+		return fmt.Errorf("no Chart.yaml in %s: %w", chartPath, err)
+	}
+
+	// Chart versions are SemVer without the v of the tag; image tags are
+	// used as rendered
+	version, err := b.apply("version", b.config.Version, "{{ .Version }}")
+	if err != nil {
+		return err
+	}
+	version = strings.TrimPrefix(version, "v")
+	appVersion, err := b.apply("app_version", b.config.AppVersion, "{{ .Version }}")
+	if err != nil {
+		return err
+	}
+	appVersion = strings.TrimPrefix(appVersion, "v")
+
+	log.Info("Packaging Helm chart", "chart", chartPath, "version", version)
+
+	// Stage the chart so the sources are left untouched
+	stageRoot := filepath.Join(b.distDir, "helm", "stage")
+	if err := os.RemoveAll(stageRoot); err != nil {
+		return err
+	}
+	files, err := chartFiles(chartPath)
+	if err != nil {
+		return err
+	}
+	name, err := b.chartName(chartPath)
+	if err != nil {
+		return err
+	}
+	stageDir := filepath.Join(stageRoot, name)
+	for _, rel := range files {
+		if err := fsutil.CopyFile(filepath.Join(chartPath, rel), filepath.Join(stageDir, rel)); err != nil {
+			return fmt.Errorf("failed to stage %s: %w", rel, err)
+		}
+	}
+
+	err = editYAML(filepath.Join(stageDir, "Chart.yaml"), func(doc *yaml.Node) error {
+		setYAMLValue(doc, []string{"version"}, version)
+		setYAMLValue(doc, []string{"appVersion"}, appVersion)
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to update Chart.yaml: %w", err)
+	}
+
+	if b.config.ImageTagPath != "" {
+		tag, err := b.apply("image_tag", b.config.ImageTag, "{{ .Version }}")
+		if err != nil {
+			return err
+		}
+		err = editYAML(filepath.Join(stageDir, "values.yaml"), func(doc *yaml.Node) error {
+			setYAMLValue(doc, strings.Split(b.config.ImageTagPath, "."), tag)
+			return nil
+		})
+		if err != nil {
+			return fmt.Errorf("failed to update values.yaml: %w", err)
+		}
+	}
+
+	outDir := filepath.Join(b.distDir, "helm")
+	chartFile := fmt.Sprintf("%s-%s.tgz", name, version)
+	chartOut := filepath.Join(outDir, chartFile)
+
+	if b.config.Sign.Key != "" {
+		if err := b.helmPackage(ctx, stageDir, outDir); err != nil {
+			return err
+		}
+	} else if err := packChart(chartOut, stageDir, name, b.modTime()); err != nil {
+		return fmt.Errorf("failed to package chart: %w", err)
+	}
+
+	if err := b.manager.Add(artifact.Artifact{
+		Name: chartFile,
+		Path: chartOut,
+		Type: artifact.TypeHelm,
+		Extra: map[string]interface{}{
+			"id":      b.config.ID,
+			"chart":   name,
+			"version": version,
+		},
+	}); err != nil {
+		return err
+	}
+	if b.config.Sign.Key != "" {
+		if err := b.manager.Add(artifact.Artifact{
+			Name:  chartFile + ".prov",
+			Path:  chartOut + ".prov",
+			Type:  artifact.TypeSignature,
+			Extra: map[string]interface{}{"id": b.config.ID},
+		}); err != nil {
+			return err
+		}
+	}
+
+	log.Info("Helm chart packaged", "path", chartOut)
+	return nil
+}
+
+// apply renders a templated setting, falling back to def when unset
+func (b *HelmBuilder) apply(field, value, def string) (string, error) {
+	if value == "" {
+		value = def
+	}
+	return b.tmplCtx.Apply("helms."+field, value)
+}
+
+// chartName reads the chart name from Chart.yaml
+func (b *HelmBuilder) chartName(chartPath string) (string, error) {
+	data, err := os.ReadFile(filepath.Join(chartPath, "Chart.yaml"))
+	if err != nil {
+		return "", err
+	}
+	var meta struct {
+		Name string `yaml:"name"`
+	}
+	if err := yaml.Unmarshal(data, &meta); err != nil {
+		return "", fmt.Errorf("invalid Chart.yaml: %w", err)
+	}
+	if meta.Name == "" {
+		return "", fmt.Errorf("Chart.yaml in %s has no name", chartPath)
+	}
+	return meta.Name, nil
+}
+
+// helmPackage packages and signs the staged chart with helm
+func (b *HelmBuilder) helmPackage(ctx context.Context, stageDir, outDir string) error {
+	if _, err := exec.LookPath("helm"); err != nil {
+		return fmt.Errorf("signing Helm charts requires helm")
+	}
+	args := []string{"package", stageDir, "--destination", outDir, "--sign", "--key", b.config.Sign.Key}
+	if b.config.Sign.Keyring != "" {
+		args = append(args, "--keyring", b.config.Sign.Keyring)
+	}
+	if b.config.Sign.PassphraseFile != "" {
+		args = append(args, "--passphrase-file", b.config.Sign.PassphraseFile)
+	}
+
+	cmd := exec.CommandContext(ctx, "helm", args...)
+	var stderr bytes.Buffer
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("helm package failed: %w\n%s", err, stderr.String())
+	}
+	return nil
+}
+
+// modTime returns the commit date for reproducible charts, or now
+func (b *HelmBuilder) modTime() time.Time {
+	if t, err := time.Parse(time.RFC3339, b.tmplCtx.Get("CommitDate")); err == nil {
+		return t
+	}
+	return time.Now()
+}
+
+// chartFiles lists the chart files not excluded by its .helmignore,
+// relative to the chart directory
+func chartFiles(chartPath string) ([]string, error) {
+	ignore, err := readHelmIgnore(filepath.Join(chartPath, ".helmignore"))
+	if err != nil {
+		return nil, err
+	}
+
+	var files []string
+	err = filepath.Walk(chartPath, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(chartPath, path)
+		if err != nil || rel == "." {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		if ignore.match(rel, info.IsDir()) {
+			if info.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !info.IsDir() {
+			files = append(files, rel)
+		}
+		return nil
+	})
+	return files, err
+}
+
+// helmIgnore holds .helmignore patterns. Patterns ending in / only match
+// directories, patterns with a / match the path, others any path element.
+type helmIgnore []string
+
+// readHelmIgnore reads a .helmignore file, which is optional
+func readHelmIgnore(path string) (helmIgnore, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var patterns helmIgnore
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if strings.HasPrefix(line, "!") {
+			log.Warn("Negated .helmignore patterns are not supported", "pattern", line)
+			continue
+		}
+		patterns = append(patterns, line)
+	}
+	return patterns, scanner.Err()
+}
+
+// match reports whether a slash separated relative path is ignored
+func (h helmIgnore) match(rel string, isDir bool) bool {
+	for _, pattern := range h {
+		dirOnly := strings.HasSuffix(pattern, "/")
+		pattern = strings.TrimSuffix(strings.TrimPrefix(pattern, "/"), "/")
+		if dirOnly && !isDir {
+			continue
+		}
+		if strings.Contains(pattern, "/") {
+			if ok, _ := filepath.Match(pattern, rel); ok {
+				return true
+			}
+			continue
+		}
+		if ok, _ := filepath.Match(pattern, filepath.Base(rel)); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// editYAML rewrites a YAML file through its node tree, which keeps
+// comments and ordering
+func editYAML(path string, edit func(doc *yaml.Node) error) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return err
+	}
+	if doc.Kind == 0 {
+		doc = yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{{Kind: yaml.MappingNode}}}
+	}
+	if err := edit(&doc); err != nil {
+		return err
+	}
+
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(&doc); err != nil {
+		return err
+	}
+	if err := enc.Close(); err != nil {
+		return err
+	}
+	return os.WriteFile(path, buf.Bytes(), 0644)
+}
+
+// setYAMLValue sets a string at a key path of a document, creating missing
+// mappings. Values are double quoted so versions like 1.10 stay strings.
+func setYAMLValue(doc *yaml.Node, path []string, value string) {
+	node := doc
+	if node.Kind == yaml.DocumentNode {
+		node = node.Content[0]
+	}
+	for i, key := range path {
+		var next *yaml.Node
+		for j := 0; j+1 < len(node.Content); j += 2 {
+			if node.Content[j].Value == key {
+				next = node.Content[j+1]
+				break
+			}
+		}
+		last := i == len(path)-1
+		if next == nil || (!last && next.Kind != yaml.MappingNode) {
+			if next == nil {
+				next = &yaml.Node{}
+				node.Content = append(node.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: key}, next)
+			}
+			if !last {
+				*next = yaml.Node{Kind: yaml.MappingNode}
+			}
+		}
+		if last {
+			*next = yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: value, Style: yaml.DoubleQuotedStyle, LineComment: next.LineComment}
+		}
+		node = next
+	}
+}
+
+// packChart writes a chart archive as helm package does: a gzipped tar of
+// the chart's files under a directory named after the chart, Chart.yaml and
+// values.yaml first
+func packChart(out, chartDir, name string, modTime time.Time) error {
+	files, err := chartFiles(chartDir)
+	if err != nil {
+		return err
+	}
+	ordered := make([]string, 0, len(files))
+	for _, first := range []string{"Chart.yaml", "values.yaml"} {
+		for _, f := range files {
+			if f == first {
+				ordered = append(ordered, f)
+			}
+		}
+	}
+	for _, f := range files {
+		if f != "Chart.yaml" && f != "values.yaml" {
+			ordered = append(ordered, f)
+		}
+	}
+
+	if err := os.MkdirAll(filepath.Dir(out), 0755); err != nil {
+		return err
+	}
+	file, err := os.Create(out)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	gw := gzip.NewWriter(file)
+	tw := tar.NewWriter(gw)
+	for _, rel := range ordered {
+		data, err := os.ReadFile(filepath.Join(chartDir, rel))
+		if err != nil {
+			return err
+		}
+		header := &tar.Header{
+			Name:     name + "/" + rel,
+			Mode:     0644,
+			Size:     int64(len(data)),
+			ModTime:  modTime,
+			Typeflag: tar.TypeReg,
+		}
+		if err := tw.WriteHeader(header); err != nil {
+			return err
+		}
+		if _, err := tw.Write(data); err != nil {
+			return err
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	if err := gw.Close(); err != nil {
+		return err
+	}
+	return file.Close()
+}
+
+// BuildAllHelmCharts packages Helm charts for all configurations.
+func BuildAllHelmCharts(ctx context.Context, configs []config.Helm, tmplCtx *tmpl.Context, manager *artifact.Manager, distDir string) error {
+	for i, cfg := range configs {
+		log.Info("Building Helm chart", "index", i+1, "total", len(configs))
+		builder := NewHelmBuilder(cfg, tmplCtx, manager, distDir)
+		if err := builder.Build(ctx); err != nil {
+			return err
+		}
+	}
+	return nil
+}
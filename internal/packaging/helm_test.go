@@ -0,0 +1,78 @@
+package packaging
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/oarkflow/releaser/internal/artifact"
+	"github.com/oarkflow/releaser/internal/config"
+	"github.com/oarkflow/releaser/internal/git"
+	"github.com/oarkflow/releaser/internal/tmpl"
+)
+
+func TestHelmKeepsImageTagPrefix(t *testing.T) {
+	chart := t.TempDir()
+	if err := os.WriteFile(filepath.Join(chart, "Chart.yaml"), []byte("apiVersion: v2\nname: demo\nversion: 0.0.0\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(chart, "values.yaml"), []byte("image:\n  tag: latest\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := config.Helm{
+		ChartPath:    chart,
+		AppVersion:   "{{ .Tag }}",
+		ImageTagPath: "image.tag",
+		ImageTag:     "{{ .Tag }}",
+	}
+	dist := t.TempDir()
+	manager := artifact.NewManager()
+	tmplCtx := tmpl.New(&config.Config{ProjectName: "demo"}, &git.Info{CurrentTag: "v1.2.3"}, false, false)
+	if err := NewHelmBuilder(cfg, tmplCtx, manager, dist).Build(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	files := readChart(t, filepath.Join(dist, "helm", "demo-1.2.3.tgz"))
+	if chartYAML := files["demo/Chart.yaml"]; !strings.Contains(chartYAML, `version: "1.2.3"`) || !strings.Contains(chartYAML, `appVersion: "1.2.3"`) {
+		t.Errorf("Chart.yaml:\n%s\nwant version and appVersion 1.2.3", chartYAML)
+	}
+	if values := files["demo/values.yaml"]; !strings.Contains(values, `tag: "v1.2.3"`) {
+		t.Errorf("values.yaml:\n%s\nwant the image tag v1.2.3", values)
+	}
+}
+
+// readChart returns the files of a packaged chart by name
+func readChart(t *testing.T, path string) map[string]string {
+	t.Helper()
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	files := make(map[string]string)
+	tr := tar.NewReader(gz)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return files
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, err := io.ReadAll(tr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		files[hdr.Name] = string(data)
+	}
+}
@@ -0,0 +1,269 @@
+package packaging
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/charmbracelet/log"
+	"gopkg.in/yaml.v3"
+
+	"github.com/oarkflow/releaser/internal/artifact"
+	"github.com/oarkflow/releaser/internal/config"
+	"github.com/oarkflow/releaser/internal/tmpl"
+)
+
+// KubernetesBuilder renders Kubernetes manifests into install files.
+type KubernetesBuilder struct {
+	config  config.Kubernetes
+	tmplCtx *tmpl.Context
+	manager *artifact.Manager
+	distDir string
+}
+
+// NewKubernetesBuilder creates a new Kubernetes manifests builder.
+func NewKubernetesBuilder(cfg config.Kubernetes, tmplCtx *tmpl.Context, manager *artifact.Manager, distDir string) *KubernetesBuilder {
+	return &KubernetesBuilder{
+		config:  cfg,
+		tmplCtx: tmplCtx,
+		manager: manager,
+		distDir: distDir,
+	}
+}
+
+// Build renders the manifest templates, then builds an install file per
+// variant, with kustomize when it has an overlay and by concatenating the
+// manifests otherwise. Every file is checked to hold Kubernetes objects.
+func (b *KubernetesBuilder) Build(ctx context.Context) error {
+	manifests := b.config.Manifests
+	if manifests == "" {
+		manifests = filepath.Join("deploy", "kubernetes")
+	}
+	if info, err := os.Stat(manifests); err != nil || !info.IsDir() {
+		return fmt.Errorf("manifests directory %s not found", manifests)
+	}
+
+	id := b.config.ID
+	if id == "" {
+		id = filepath.Base(manifests)
+	}
+	renderDir := filepath.Join(b.distDir, "kubernetes", id)
+	if err := os.RemoveAll(renderDir); err != nil {
+		return err
+	}
+	if err := b.render(manifests, renderDir); err != nil {
+		return err
+	}
+
+	variants := b.config.Variants
+	if len(variants) == 0 {
+		name := b.config.Name
+		if name == "" {
+			name = "install.yaml"
+		}
+		variants = []config.K8sVariant{{Name: name, Kustomize: b.config.Kustomize}}
+	}
+
+	seen := make(map[string]bool)
+	for _, variant := range variants {
+		if variant.Name == "" {
+			return fmt.Errorf("kubernetes variant without a name")
+		}
+		if seen[variant.Name] {
+			return fmt.Errorf("duplicate kubernetes variant %s", variant.Name)
+		}
+		seen[variant.Name] = true
+
+		log.Info("Building Kubernetes install file", "file", variant.Name, "overlay", variant.Kustomize)
+
+		var data []byte
+		var err error
+		if variant.Kustomize != "" || hasKustomization(renderDir) {
+			data, err = kustomizeBuild(ctx, filepath.Join(renderDir, variant.Kustomize))
+		} else {
+			data, err = concatManifests(renderDir)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to build %s: %w", variant.Name, err)
+		}
+
+		count, err := validateManifests(data)
+		if err != nil {
+			return fmt.Errorf("invalid manifests in %s: %w", variant.Name, err)
+		}
+
+		out := filepath.Join(b.distDir, variant.Name)
+		if err := os.WriteFile(out, data, 0644); err != nil {
+			return err
+		}
+
+		if err := b.manager.Add(artifact.Artifact{
+			Name: variant.Name,
+			Path: out,
+			Type: artifact.TypeKubernetes,
+			Extra: map[string]interface{}{
+				"id":      b.config.ID,
+				"objects": count,
+			},
+		}); err != nil {
+			return err
+		}
+		log.Info("Kubernetes manifests created", "path", out, "objects", count)
+	}
+	return nil
+}
+
+// render applies the release context to every file of the manifests
+// directory, writing the results under dir
+func (b *KubernetesBuilder) render(manifests, dir string) error {
+	return filepath.Walk(manifests, func(path string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return err
+		}
+		rel, err := filepath.Rel(manifests, path)
+		if err != nil {
+			return err
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		rendered, err := b.tmplCtx.Apply(filepath.ToSlash(path), string(data))
+		if err != nil {
+			return fmt.Errorf("failed to render %s: %w", path, err)
+		}
+		out := filepath.Join(dir, rel)
+		if err := os.MkdirAll(filepath.Dir(out), 0755); err != nil {
+			return err
+		}
+		return os.WriteFile(out, []byte(rendered), 0644)
+	})
+}
+
+// isKustomization reports whether a file name is a kustomization file
+func isKustomization(name string) bool {
+	switch name {
+	case "kustomization.yaml", "kustomization.yml", "Kustomization":
+		return true
+	}
+	return false
+}
+
+// hasKustomization reports whether dir holds a kustomization file
+func hasKustomization(dir string) bool {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return false
+	}
+	for _, entry := range entries {
+		if !entry.IsDir() && isKustomization(entry.Name()) {
+			return true
+		}
+	}
+	return false
+}
+
+// kustomizeBuild runs kustomize build on dir, falling back to kubectl's
+// built-in kustomize
+func kustomizeBuild(ctx context.Context, dir string) ([]byte, error) {
+	var cmd *exec.Cmd
+	if _, err := exec.LookPath("kustomize"); err == nil {
+		cmd = exec.CommandContext(ctx, "kustomize", "build", dir)
+	} else if _, err := exec.LookPath("kubectl"); err == nil {
+		cmd = exec.CommandContext(ctx, "kubectl", "kustomize", dir)
+	} else {
+		return nil, fmt.Errorf("kustomize overlays require kustomize or kubectl")
+	}
+
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("kustomize build failed: %w\n%s", err, stderr.String())
+	}
+	return stdout.Bytes(), nil
+}
+
+// concatManifests joins the YAML files of dir in path order into one
+// multi-document file. Directories holding a kustomization, such as
+// overlays, are skipped.
+func concatManifests(dir string) ([]byte, error) {
+	var buf bytes.Buffer
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			if path != dir && hasKustomization(path) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		ext := filepath.Ext(path)
+		if (ext != ".yaml" && ext != ".yml") || isKustomization(info.Name()) {
+			return nil
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		data = bytes.TrimSpace(data)
+		if len(data) == 0 {
+			return nil
+		}
+		if !bytes.HasPrefix(data, []byte("---")) {
+			buf.WriteString("---\n")
+		}
+		buf.Write(data)
+		buf.WriteString("\n")
+		return nil
+	})
+	return buf.Bytes(), err
+}
+
+// validateManifests checks that data is a stream of YAML documents that
+// each have an apiVersion and kind, returning the number of objects
+func validateManifests(data []byte) (int, error) {
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	count := 0
+	for i := 1; ; i++ {
+		var doc map[string]interface{}
+		err := dec.Decode(&doc)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return 0, fmt.Errorf("document %d: %w", i, err)
+		}
+		if doc == nil {
+			continue
+		}
+		for _, field := range []string{"apiVersion", "kind"} {
+			if value, _ := doc[field].(string); strings.TrimSpace(value) == "" {
+				return 0, fmt.Errorf("document %d has no %s", i, field)
+			}
+		}
+		count++
+	}
+	if count == 0 {
+		return 0, fmt.Errorf("no Kubernetes objects")
+	}
+	return count, nil
+}
+
+// BuildAllKubernetesManifests builds the install files of all configurations.
+func BuildAllKubernetesManifests(ctx context.Context, configs []config.Kubernetes, tmplCtx *tmpl.Context, manager *artifact.Manager, distDir string) error {
+	for i, cfg := range configs {
+		log.Info("Building Kubernetes manifests", "index", i+1, "total", len(configs))
+		builder := NewKubernetesBuilder(cfg, tmplCtx, manager, distDir)
+		if err := builder.Build(ctx); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,113 @@
+package packaging
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/charmbracelet/log"
+	"github.com/oarkflow/releaser/internal/config"
+)
+
+// defaultLanguage is the language of the untranslated strings
+const defaultLanguage = "en"
+
+// windowsLanguage is a language Windows installers can be made in
+type windowsLanguage struct {
+	// NSIS is the name of the NSIS language file
+	NSIS string
+	// LCID is the Windows language identifier
+	LCID int
+	// Codepage is the ANSI code page MSI strings of the language use
+	Codepage int
+	// Culture is the WiX culture of the language
+	Culture string
+}
+
+// windowsLanguages maps localization codes to Windows languages
+var windowsLanguages = map[string]windowsLanguage{
+	"en":    {"English", 1033, 1252, "en-us"},
+	"cs":    {"Czech", 1029, 1250, "cs-cz"},
+	"da":    {"Danish", 1030, 1252, "da-dk"},
+	"de":    {"German", 1031, 1252, "de-de"},
+	"el":    {"Greek", 1032, 1253, "el-gr"},
+	"es":    {"Spanish", 1034, 1252, "es-es"},
+	"fi":    {"Finnish", 1035, 1252, "fi-fi"},
+	"fr":    {"French", 1036, 1252, "fr-fr"},
+	"hu":    {"Hungarian", 1038, 1250, "hu-hu"},
+	"it":    {"Italian", 1040, 1252, "it-it"},
+	"ja":    {"Japanese", 1041, 932, "ja-jp"},
+	"ko":    {"Korean", 1042, 949, "ko-kr"},
+	"nb":    {"Norwegian", 1044, 1252, "nb-no"},
+	"nl":    {"Dutch", 1043, 1252, "nl-nl"},
+	"pl":    {"Polish", 1045, 1250, "pl-pl"},
+	"pt":    {"Portuguese", 2070, 1252, "pt-pt"},
+	"pt_BR": {"PortugueseBR", 1046, 1252, "pt-br"},
+	"ru":    {"Russian", 1049, 1251, "ru-ru"},
+	"sv":    {"Swedish", 1053, 1252, "sv-se"},
+	"tr":    {"Turkish", 1055, 1254, "tr-tr"},
+	"uk":    {"Ukrainian", 1058, 1251, "uk-ua"},
+	"zh_CN": {"SimpChinese", 2052, 936, "zh-cn"},
+	"zh_TW": {"TradChinese", 1028, 950, "zh-tw"},
+}
+
+// lookupWindowsLanguage finds the Windows language of a localization code,
+// trying the language without its region or variant, e.g. de for de_AT
+func lookupWindowsLanguage(code string) (windowsLanguage, bool) {
+	code, _, _ = strings.Cut(code, "@")
+	if lang, ok := windowsLanguages[code]; ok {
+		return lang, true
+	}
+	base, _, _ := strings.Cut(code, "_")
+	lang, ok := windowsLanguages[base]
+	return lang, ok
+}
+
+// localize returns the strings of a language, the untranslated ones
+// standing in for those it doesn't translate
+func localize(localizations map[string]config.Localization, lang string, def config.Localization) config.Localization {
+	l := localizations[lang]
+	if l.Name == "" {
+		l.Name = def.Name
+	}
+	if l.Comment == "" {
+		l.Comment = def.Comment
+	}
+	if l.Description == "" {
+		l.Description = def.Description
+	}
+	return l
+}
+
+// installerLanguage is a language of a multilingual installer with its
+// strings
+type installerLanguage struct {
+	windowsLanguage
+	config.Localization
+}
+
+// installerLanguages returns the languages of a multilingual installer:
+// English with the untranslated strings first, then each localization
+// Windows has a language for
+func installerLanguages(localizations map[string]config.Localization, def config.Localization) []installerLanguage {
+	langs := []installerLanguage{{windowsLanguages[defaultLanguage], localize(localizations, defaultLanguage, def)}}
+	seen := map[int]bool{windowsLanguages[defaultLanguage].LCID: true}
+
+	codes := make([]string, 0, len(localizations))
+	for code := range localizations {
+		codes = append(codes, code)
+	}
+	sort.Strings(codes)
+	for _, code := range codes {
+		lang, ok := lookupWindowsLanguage(code)
+		if !ok {
+			log.Warn("No Windows installer language for localization, skipping it", "language", code)
+			continue
+		}
+		if seen[lang.LCID] {
+			continue
+		}
+		seen[lang.LCID] = true
+		langs = append(langs, installerLanguage{lang, localize(localizations, code, def)})
+	}
+	return langs
+}
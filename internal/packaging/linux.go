@@ -1,266 +1,103 @@
-// Package packaging provides Flatpak and AppImage creation for Linux.
+// Package packaging provides Snap, Flatpak and AppImage creation for Linux.
 package packaging
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"os"
 	"os/exec"
 	"path/filepath"
+	goruntime "runtime"
+	"slices"
+	"sort"
 	"strings"
-	"text/template"
 
 	"github.com/charmbracelet/log"
+	"gopkg.in/yaml.v3"
+
 	"github.com/oarkflow/releaser/internal/artifact"
 	"github.com/oarkflow/releaser/internal/assets"
 	"github.com/oarkflow/releaser/internal/config"
+	"github.com/oarkflow/releaser/internal/desktop"
+	"github.com/oarkflow/releaser/internal/fsutil"
 	"github.com/oarkflow/releaser/internal/tmpl"
 )
 
-// FlatpakConfig represents Flatpak build configuration
-type FlatpakConfig struct {
-	ID             string   `yaml:"id,omitempty"`
-	AppID          string   `yaml:"app_id"`
-	Runtime        string   `yaml:"runtime,omitempty"`
-	RuntimeVersion string   `yaml:"runtime_version,omitempty"`
-	SDK            string   `yaml:"sdk,omitempty"`
-	Command        string   `yaml:"command,omitempty"`
-	Permissions    []string `yaml:"permissions,omitempty"`
-	Manifest       string   `yaml:"manifest,omitempty"`
-	Repo           string   `yaml:"repo,omitempty"`
-	Branch         string   `yaml:"branch,omitempty"`
-}
-
-// FlatpakBuilder creates Flatpak packages
-type FlatpakBuilder struct {
-	config  FlatpakConfig
-	tmplCtx *tmpl.Context
-	manager *artifact.Manager
-	distDir string
-}
-
-// NewFlatpakBuilder creates a new Flatpak builder
-func NewFlatpakBuilder(cfg FlatpakConfig, tmplCtx *tmpl.Context, manager *artifact.Manager, distDir string) *FlatpakBuilder {
-	return &FlatpakBuilder{
-		config:  cfg,
-		tmplCtx: tmplCtx,
-		manager: manager,
-		distDir: distDir,
-	}
-}
-
-// Build creates a Flatpak package
-func (b *FlatpakBuilder) Build(ctx context.Context) error {
-	// Check if flatpak-builder is available
-	if _, err := exec.LookPath("flatpak-builder"); err != nil {
-		log.Warn("Skipping Flatpak: flatpak-builder not found")
-		return nil
-	}
-
-	log.Info("Building Flatpak package")
-
-	// Get Linux binaries
-	binaries := b.manager.Filter(func(a artifact.Artifact) bool {
-		return a.Type == artifact.TypeBinary && a.Goos == "linux" && a.Goarch == "amd64"
-	})
-
-	if len(binaries) == 0 {
-		log.Warn("No Linux amd64 binaries found for Flatpak")
-		return nil
-	}
-
-	for _, binary := range binaries {
-		if err := b.createFlatpak(ctx, binary); err != nil {
-			return fmt.Errorf("failed to create Flatpak for %s: %w", binary.Name, err)
-		}
-	}
-
-	return nil
+// snapArchs maps GOARCH values to snap architectures
+var snapArchs = map[string]string{
+	"amd64":   "amd64",
+	"arm64":   "arm64",
+	"arm":     "armhf",
+	"386":     "i386",
+	"ppc64le": "ppc64el",
+	"s390x":   "s390x",
+	"riscv64": "riscv64",
 }
 
-// createFlatpak creates a single Flatpak package
-func (b *FlatpakBuilder) createFlatpak(ctx context.Context, binary artifact.Artifact) error {
-	appID := b.config.AppID
-	if appID == "" {
-		appID = fmt.Sprintf("com.%s.%s", b.tmplCtx.Get("ProjectName"), b.tmplCtx.Get("ProjectName"))
-	}
-
-	version := b.tmplCtx.Get("Version")
-
-	// Create build directory
-	buildDir := filepath.Join(b.distDir, "flatpak-build")
-	if err := os.MkdirAll(buildDir, 0755); err != nil {
-		return err
-	}
-
-	// Generate manifest if not provided
-	manifestPath := b.config.Manifest
-	if manifestPath == "" {
-		manifestPath = filepath.Join(b.distDir, appID+".json")
-		if err := b.generateManifest(manifestPath, binary, appID); err != nil {
-			return fmt.Errorf("failed to generate manifest: %w", err)
-		}
-	}
-
-	// Create repo directory
-	repoDir := b.config.Repo
-	if repoDir == "" {
-		repoDir = filepath.Join(b.distDir, "flatpak-repo")
-	}
-
-	// Build flatpak
-	args := []string{
-		"--force-clean",
-		"--repo=" + repoDir,
-	}
-
-	if b.config.Branch != "" {
-		args = append(args, "--default-branch="+b.config.Branch)
-	}
-
-	args = append(args, buildDir, manifestPath)
-
-	cmd := exec.CommandContext(ctx, "flatpak-builder", args...)
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
-	cmd.Dir = b.distDir
-
-	if err := cmd.Run(); err != nil {
-		return fmt.Errorf("flatpak-builder failed: %w", err)
-	}
-
-	// Create bundle
-	bundleName := fmt.Sprintf("%s_%s.flatpak", appID, version)
-	bundlePath := filepath.Join(b.distDir, bundleName)
-
-	branch := b.config.Branch
-	if branch == "" {
-		branch = "master"
-	}
-
-	bundleCmd := exec.CommandContext(ctx, "flatpak", "build-bundle",
-		repoDir, bundlePath, appID, branch)
-	bundleCmd.Stdout = os.Stdout
-	bundleCmd.Stderr = os.Stderr
-
-	if err := bundleCmd.Run(); err != nil {
-		return fmt.Errorf("flatpak build-bundle failed: %w", err)
-	}
-
-	// Add artifact
-	b.manager.Add(artifact.Artifact{
-		Name:   bundleName,
-		Path:   bundlePath,
-		Type:   artifact.TypeLinuxPackage,
-		Goos:   "linux",
-		Goarch: binary.Goarch,
-		Extra: map[string]interface{}{
-			"format": "flatpak",
-		},
-	})
+// Default plugs of apps generated for cli and gui builds
+var (
+	snapCLIPlugs = []string{"home", "network"}
+	snapGUIPlugs = []string{"desktop", "desktop-legacy", "wayland", "x11", "opengl", "home", "network"}
+)
 
-	log.Info("Flatpak created", "name", bundleName)
-	return nil
+// ciEnvVars are set by CI services; snapcraft then builds in destructive
+// mode since LXD and multipass are rarely available on CI runners
+var ciEnvVars = []string{"CI", "GITHUB_ACTIONS", "GITLAB_CI", "BUILDKITE", "JENKINS_URL", "TF_BUILD"}
+
+// snapcraftYAML is a snapcraft.yaml project file
+type snapcraftYAML struct {
+	Name          string                            `yaml:"name"`
+	Title         string                            `yaml:"title,omitempty"`
+	Version       string                            `yaml:"version"`
+	Summary       string                            `yaml:"summary"`
+	Description   string                            `yaml:"description"`
+	License       string                            `yaml:"license,omitempty"`
+	Grade         string                            `yaml:"grade"`
+	Confinement   string                            `yaml:"confinement"`
+	Base          string                            `yaml:"base"`
+	Architectures []snapArchitecture                `yaml:"architectures,omitempty"`
+	Platforms     map[string]snapArchitecture       `yaml:"platforms,omitempty"`
+	Layout        map[string]config.SnapcraftLayout `yaml:"layout,omitempty"`
+	Apps          map[string]snapApp                `yaml:"apps"`
+	Parts         map[string]snapPart               `yaml:"parts"`
 }
 
-// generateManifest generates a Flatpak manifest
-func (b *FlatpakBuilder) generateManifest(path string, binary artifact.Artifact, appID string) error {
-	runtime := b.config.Runtime
-	if runtime == "" {
-		runtime = "org.freedesktop.Platform"
-	}
-
-	runtimeVersion := b.config.RuntimeVersion
-	if runtimeVersion == "" {
-		runtimeVersion = "23.08"
-	}
-
-	sdk := b.config.SDK
-	if sdk == "" {
-		sdk = "org.freedesktop.Sdk"
-	}
-
-	command := b.config.Command
-	if command == "" {
-		command = binary.Name
-	}
-
-	permissions := b.config.Permissions
-	if len(permissions) == 0 {
-		permissions = []string{"--share=network", "--share=ipc", "--socket=x11"}
-	}
-
-	manifest := fmt.Sprintf(`{
-    "app-id": "%s",
-    "runtime": "%s",
-    "runtime-version": "%s",
-    "sdk": "%s",
-    "command": "%s",
-    "finish-args": [
-        %s
-    ],
-    "modules": [
-        {
-            "name": "%s",
-            "buildsystem": "simple",
-            "build-commands": [
-                "install -D %s /app/bin/%s"
-            ],
-            "sources": [
-                {
-                    "type": "file",
-                    "path": "%s"
-                }
-            ]
-        }
-    ]
-}`,
-		appID,
-		runtime,
-		runtimeVersion,
-		sdk,
-		command,
-		formatPermissions(permissions),
-		b.tmplCtx.Get("ProjectName"),
-		binary.Name,
-		binary.Name,
-		binary.Path,
-	)
-
-	return os.WriteFile(path, []byte(manifest), 0644)
+// snapArchitecture is where a snap is built and which architecture it is for
+type snapArchitecture struct {
+	BuildOn  []string `yaml:"build-on"`
+	BuildFor []string `yaml:"build-for,omitempty"`
+	RunOn    []string `yaml:"run-on,omitempty"`
 }
 
-// formatPermissions formats permissions for JSON
-func formatPermissions(perms []string) string {
-	quoted := make([]string, len(perms))
-	for i, p := range perms {
-		quoted[i] = fmt.Sprintf(`"%s"`, p)
-	}
-	return strings.Join(quoted, ",\n        ")
+// snapApp is an app of a snap
+type snapApp struct {
+	Command   string   `yaml:"command"`
+	Plugs     []string `yaml:"plugs,omitempty"`
+	Slots     []string `yaml:"slots,omitempty"`
+	Daemon    string   `yaml:"daemon,omitempty"`
+	Completer string   `yaml:"completer,omitempty"`
 }
 
-// AppImageConfig represents AppImage build configuration
-type AppImageConfig struct {
-	ID           string             `yaml:"id,omitempty"`
-	Name         string             `yaml:"name,omitempty"`
-	Icon         string             `yaml:"icon,omitempty"`
-	Desktop      string             `yaml:"desktop,omitempty"`
-	Categories   []string           `yaml:"categories,omitempty"`
-	ExtraFiles   []config.ExtraFile `yaml:"extra_files,omitempty"`
-	Architecture string             `yaml:"architecture,omitempty"`
+// snapPart is a part of a snap
+type snapPart struct {
+	Plugin string `yaml:"plugin"`
+	Source string `yaml:"source"`
 }
 
-// AppImageBuilder creates AppImage packages
-type AppImageBuilder struct {
-	config  AppImageConfig
-	tmplCtx *tmpl.Context
-	manager *artifact.Manager
-	distDir string
+// SnapBuilder creates Snap packages
+type SnapBuilder struct {
+	config        config.Snapcraft
+	guis          map[string]*config.GUIConfig
+	localizations map[string]config.Localization
+	tmplCtx       *tmpl.Context
+	manager       *artifact.Manager
+	distDir       string
 }
 
-// NewAppImageBuilder creates a new AppImage builder
-func NewAppImageBuilder(cfg AppImageConfig, tmplCtx *tmpl.Context, manager *artifact.Manager, distDir string) *AppImageBuilder {
-	return &AppImageBuilder{
+// NewSnapBuilder creates a new Snap builder
+func NewSnapBuilder(cfg config.Snapcraft, tmplCtx *tmpl.Context, manager *artifact.Manager, distDir string) *SnapBuilder {
+	return &SnapBuilder{
 		config:  cfg,
 		tmplCtx: tmplCtx,
 		manager: manager,
@@ -268,393 +105,349 @@ func NewAppImageBuilder(cfg AppImageConfig, tmplCtx *tmpl.Context, manager *arti
 	}
 }
 
-// Build creates an AppImage
-func (b *AppImageBuilder) Build(ctx context.Context) error {
-	// Check if appimagetool is available
-	if _, err := exec.LookPath("appimagetool"); err != nil {
-		log.Warn("Skipping AppImage: appimagetool not found (install from https://github.com/AppImage/AppImageKit)")
+// WithGUIs sets the gui settings of each build, by build ID, used for the
+// plugs and desktop files of generated apps
+func (b *SnapBuilder) WithGUIs(guis map[string]*config.GUIConfig) *SnapBuilder {
+	b.guis = guis
+	return b
+}
+
+// WithLocalizations sets the translations of the desktop files of
+// generated apps, by language
+func (b *SnapBuilder) WithLocalizations(localizations map[string]config.Localization) *SnapBuilder {
+	b.localizations = localizations
+	return b
+}
+
+// Build creates a snap for each architecture of the selected Linux
+// binaries. The snapcraft.yaml is always generated; when snapcraft is
+// missing or cannot build here, it is left in dist with a warning.
+func (b *SnapBuilder) Build(ctx context.Context) error {
+	if b.config.Skip == "true" {
+		log.Info("Skipping Snap build")
 		return nil
 	}
+	if b.config.Mode != "" && b.config.Mode != "destructive" && b.config.Mode != "lxd" {
+		return fmt.Errorf("invalid snapcraft mode %q, valid values are: destructive, lxd", b.config.Mode)
+	}
 
-	log.Info("Building AppImage")
-
-	// Get Linux binaries
-	binaries := b.manager.Filter(func(a artifact.Artifact) bool {
-		return a.Type == artifact.TypeBinary && a.Goos == "linux"
-	})
+	byArch := make(map[string][]artifact.Artifact)
+	for _, a := range b.manager.Filter(func(a artifact.Artifact) bool {
+		if a.Type != artifact.TypeBinary || a.Goos != "linux" || snapArchs[a.Goarch] == "" {
+			return false
+		}
+		return len(b.config.Builds) == 0 || slices.Contains(b.config.Builds, a.BuildID)
+	}) {
+		byArch[a.Goarch] = append(byArch[a.Goarch], a)
+	}
 
-	if len(binaries) == 0 {
-		log.Warn("No Linux binaries found for AppImage")
+	if len(byArch) == 0 {
+		log.Warn("No Linux binaries found for Snap")
 		return nil
 	}
 
-	for _, binary := range binaries {
-		if err := b.createAppImage(ctx, binary); err != nil {
-			return fmt.Errorf("failed to create AppImage for %s: %w", binary.Name, err)
+	log.Info("Building Snap package")
+
+	goarchs := make([]string, 0, len(byArch))
+	for goarch := range byArch {
+		goarchs = append(goarchs, goarch)
+	}
+	sort.Strings(goarchs)
+
+	for _, goarch := range goarchs {
+		if err := b.createSnap(ctx, byArch[goarch]); err != nil {
+			return fmt.Errorf("failed to create Snap for %s: %w", goarch, err)
 		}
 	}
 
 	return nil
 }
 
-// createAppImage creates a single AppImage
-func (b *AppImageBuilder) createAppImage(ctx context.Context, binary artifact.Artifact) error {
+// createSnap generates the snapcraft project for one architecture and packs it
+func (b *SnapBuilder) createSnap(ctx context.Context, binaries []artifact.Artifact) error {
+	goarch := binaries[0].Goarch
+	arch := snapArchs[goarch]
+
 	name := b.config.Name
 	if name == "" {
-		name = b.tmplCtx.Get("ProjectName")
+		name = strings.ToLower(b.tmplCtx.Get("ProjectName"))
 	}
+	version := strings.TrimPrefix(b.tmplCtx.Get("Version"), "v")
 
-	version := b.tmplCtx.Get("Version")
-	arch := binary.Goarch
-	if arch == "amd64" {
-		arch = "x86_64"
-	} else if arch == "386" {
-		arch = "i686"
-	} else if arch == "arm64" {
-		arch = "aarch64"
+	projectDir, err := filepath.Abs(filepath.Join(b.distDir, "snap-"+arch))
+	if err != nil {
+		return err
 	}
-
-	// Create AppDir structure
-	appDir := filepath.Join(b.distDir, name+".AppDir")
-	usrBinDir := filepath.Join(appDir, "usr", "bin")
-	usrShareDir := filepath.Join(appDir, "usr", "share", "applications")
-	usrIconDir := filepath.Join(appDir, "usr", "share", "icons", "hicolor", "256x256", "apps")
-
-	for _, dir := range []string{usrBinDir, usrShareDir, usrIconDir} {
-		if err := os.MkdirAll(dir, 0755); err != nil {
-			return err
-		}
+	if err := os.RemoveAll(projectDir); err != nil {
+		return err
 	}
 
-	// Copy binary
-	if err := copyFile(binary.Path, filepath.Join(usrBinDir, binary.Name)); err != nil {
-		return fmt.Errorf("failed to copy binary: %w", err)
+	project, err := b.stageProject(projectDir, name, version, arch, binaries)
+	if err != nil {
+		return err
 	}
-	if err := os.Chmod(filepath.Join(usrBinDir, binary.Name), 0755); err != nil {
+	data, err := yaml.Marshal(project)
+	if err != nil {
 		return err
 	}
-
-	// Create desktop file
-	desktopFile := b.config.Desktop
-	if desktopFile == "" {
-		desktopContent := b.generateDesktopFile(name, binary.Name)
-		desktopPath := filepath.Join(usrShareDir, name+".desktop")
-		if err := os.WriteFile(desktopPath, []byte(desktopContent), 0644); err != nil {
-			return err
-		}
-		// Also link to AppDir root
-		if err := os.Symlink(filepath.Join("usr", "share", "applications", name+".desktop"),
-			filepath.Join(appDir, name+".desktop")); err != nil {
-			return err
-		}
+	snapcraftPath := filepath.Join(projectDir, "snap", "snapcraft.yaml")
+	if err := os.WriteFile(snapcraftPath, data, 0644); err != nil {
+		return fmt.Errorf("failed to write snapcraft.yaml: %w", err)
 	}
 
-	iconPath := b.config.Icon
-	if iconPath == "" {
-		if iconSet, err := assets.EnsureAppIcon(name, b.distDir); err == nil {
-			iconPath = iconSet.PNG
-		} else {
-			log.Warn("Failed to generate default icon", "error", err)
-		}
+	if _, err := exec.LookPath("snapcraft"); err != nil {
+		log.Warn("Skipping Snap build: snapcraft not found", "snapcraft", snapcraftPath)
+		return nil
 	}
-	if iconPath != "" {
-		ext := filepath.Ext(iconPath)
-		if ext == "" {
-			ext = ".png"
-		}
-		iconDest := filepath.Join(usrIconDir, name+ext)
-		if err := copyFile(iconPath, iconDest); err != nil {
-			log.Warn("Failed to copy icon", "error", err)
-		} else {
-			_ = os.Symlink(filepath.Join("usr", "share", "icons", "hicolor", "256x256", "apps", name+ext),
-				filepath.Join(appDir, name+ext))
-		}
+
+	modes := b.modes()
+	if len(modes) == 0 {
+		log.Warn("Skipping Snap build: outside CI snapcraft needs LXD, set mode: destructive to build on this host",
+			"snapcraft", snapcraftPath)
+		return nil
 	}
 
-	// Create AppRun script
-	appRunPath := filepath.Join(appDir, "AppRun")
-	appRunContent := fmt.Sprintf(`#!/bin/bash
-SELF=$(readlink -f "$0")
-HERE=${SELF%%/*}
-export PATH="${HERE}/usr/bin/:${PATH}"
-exec "%s" "$@"
-`, binary.Name)
-	if err := os.WriteFile(appRunPath, []byte(appRunContent), 0755); err != nil {
+	snapName := fmt.Sprintf("%s_%s_%s.snap", name, version, arch)
+	snapPath := filepath.Join(b.distDir, snapName)
+	if snapPath, err = filepath.Abs(snapPath); err != nil {
 		return err
 	}
 
-	// Run appimagetool
-	appImageName := fmt.Sprintf("%s-%s-%s.AppImage", name, version, arch)
-	appImagePath := filepath.Join(b.distDir, appImageName)
-
-	cmd := exec.CommandContext(ctx, "appimagetool", appDir, appImagePath)
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
-	cmd.Env = append(os.Environ(), "ARCH="+arch)
+	var errs []error
+	for _, mode := range modes {
+		if err := b.pack(ctx, projectDir, snapPath, mode); err != nil {
+			log.Warn("snapcraft failed", "mode", mode, "error", err)
+			errs = append(errs, err)
+			continue
+		}
 
-	if err := cmd.Run(); err != nil {
-		return fmt.Errorf("appimagetool failed: %w", err)
+		if err := b.manager.Add(artifact.Artifact{
+			Name:    snapName,
+			Path:    snapPath,
+			Type:    artifact.TypeLinuxPackage,
+			Goos:    "linux",
+			Goarch:  goarch,
+			Goarm:   binaries[0].Goarm,
+			BuildID: binaries[0].BuildID,
+			Extra: map[string]interface{}{
+				artifact.ExtraFormat: "snap",
+				"id":                 b.config.ID,
+			},
+		}); err != nil {
+			return err
+		}
+		log.Info("Snap created", "name", snapName)
+		return nil
 	}
 
-	// Clean up AppDir
-	os.RemoveAll(appDir)
-
-	// Add artifact
-	b.manager.Add(artifact.Artifact{
-		Name:   appImageName,
-		Path:   appImagePath,
-		Type:   artifact.TypeLinuxPackage,
-		Goos:   "linux",
-		Goarch: binary.Goarch,
-		Extra: map[string]interface{}{
-			"format": "appimage",
-		},
-	})
-
-	log.Info("AppImage created", "name", appImageName)
-	return nil
-}
-
-// generateDesktopFile generates a .desktop file
-func (b *AppImageBuilder) generateDesktopFile(name, executable string) string {
-	categories := b.config.Categories
-	if len(categories) == 0 {
-		categories = []string{"Utility"}
-	}
-
-	return fmt.Sprintf(`[Desktop Entry]
-Type=Application
-Name=%s
-Exec=%s
-Icon=%s
-Categories=%s;
-Terminal=false
-`,
-		name,
-		executable,
-		name,
-		strings.Join(categories, ";"),
-	)
-}
-
-// SnapBuilder creates Snap packages
-type SnapBuilder struct {
-	config  config.Snapcraft
-	tmplCtx *tmpl.Context
-	manager *artifact.Manager
-	distDir string
-}
-
-// NewSnapBuilder creates a new Snap builder
-func NewSnapBuilder(cfg config.Snapcraft, tmplCtx *tmpl.Context, manager *artifact.Manager, distDir string) *SnapBuilder {
-	return &SnapBuilder{
-		config:  cfg,
-		tmplCtx: tmplCtx,
-		manager: manager,
-		distDir: distDir,
+	// An explicit mode is expected to work; the automatic choice only
+	// warns so releases without a usable snapcraft setup still go through
+	if b.config.Mode != "" {
+		return errors.Join(errs...)
 	}
+	log.Warn("Snap not built, build it from the generated snapcraft.yaml on a host with LXD",
+		"snapcraft", snapcraftPath)
+	return nil
 }
 
-// Build creates a Snap package
-func (b *SnapBuilder) Build(ctx context.Context) error {
-	if b.config.Skip == "true" {
-		log.Info("Skipping Snap build")
-		return nil
+// modes returns the snapcraft build modes to try, in order
+func (b *SnapBuilder) modes() []string {
+	if b.config.Mode != "" {
+		return []string{b.config.Mode}
 	}
 
-	// Check if snapcraft is available
-	if _, err := exec.LookPath("snapcraft"); err != nil {
-		log.Warn("Skipping Snap: snapcraft not found")
-		return nil
+	var modes []string
+	if inCI() {
+		modes = append(modes, "destructive")
 	}
-
-	log.Info("Building Snap package")
-
-	// Create snap directory
-	snapDir := filepath.Join(b.distDir, "snap")
-	if err := os.MkdirAll(snapDir, 0755); err != nil {
-		return err
+	if _, err := exec.LookPath("lxd"); err == nil {
+		modes = append(modes, "lxd")
 	}
+	return modes
+}
 
-	// Generate snapcraft.yaml
-	snapcraftPath := filepath.Join(snapDir, "snapcraft.yaml")
-	if err := b.generateSnapcraft(snapcraftPath); err != nil {
-		return fmt.Errorf("failed to generate snapcraft.yaml: %w", err)
+// pack runs snapcraft pack in the project directory
+func (b *SnapBuilder) pack(ctx context.Context, projectDir, output, mode string) error {
+	args := []string{"pack", "--output", output}
+	switch mode {
+	case "destructive":
+		args = append(args, "--destructive-mode")
+	case "lxd":
+		args = append(args, "--use-lxd")
 	}
 
-	// Run snapcraft
-	cmd := exec.CommandContext(ctx, "snapcraft", "--destructive-mode")
-	cmd.Dir = b.distDir
+	cmd := exec.CommandContext(ctx, "snapcraft", args...)
+	cmd.Dir = projectDir
 	cmd.Stdout = os.Stdout
 	cmd.Stderr = os.Stderr
+	return cmd.Run()
+}
 
-	if err := cmd.Run(); err != nil {
-		return fmt.Errorf("snapcraft failed: %w", err)
-	}
-
-	// Find and register snap artifact
-	snapFiles, _ := filepath.Glob(filepath.Join(b.distDir, "*.snap"))
-	for _, snapFile := range snapFiles {
-		b.manager.Add(artifact.Artifact{
-			Name:   filepath.Base(snapFile),
-			Path:   snapFile,
-			Type:   artifact.TypeLinuxPackage,
-			Goos:   "linux",
-			Goarch: "amd64",
-			Extra: map[string]interface{}{
-				"format": "snap",
-			},
-		})
-		log.Info("Snap created", "name", filepath.Base(snapFile))
+// stageProject lays out the snapcraft project: binaries and extra files
+// under root, dumped into the snap, desktop files and icons of gui apps
+// under snap/gui, and returns the snapcraft.yaml contents
+func (b *SnapBuilder) stageProject(dir, name, version, arch string, binaries []artifact.Artifact) (*snapcraftYAML, error) {
+	rootDir := filepath.Join(dir, "root")
+	guiDir := filepath.Join(dir, "snap", "gui")
+	if err := os.MkdirAll(guiDir, 0755); err != nil {
+		return nil, err
 	}
 
-	return nil
-}
-
-// generateSnapcraft generates snapcraft.yaml
-func (b *SnapBuilder) generateSnapcraft(path string) error {
-	tmplText := `name: {{ .Name }}
-version: '{{ .Version }}'
-summary: {{ .Summary }}
-description: |
-  {{ .Description }}
-grade: {{ .Grade }}
-confinement: {{ .Confinement }}
-base: {{ .Base }}
-
-apps:
-{{ range $name, $app := .Apps }}
-  {{ $name }}:
-    command: {{ $app.Command }}
-    plugs:
-{{ range $app.Plugs }}
-      - {{ . }}
-{{ end }}
-{{ if $app.Daemon }}
-    daemon: {{ $app.Daemon }}
-{{ end }}
-{{ end }}
-
-parts:
-  {{ .Name }}:
-    plugin: dump
-    source: .
-    stage-packages:
-      - libc6
-`
-
-	t, err := template.New("snapcraft").Parse(tmplText)
-	if err != nil {
-		return err
+	for _, binary := range binaries {
+		dst := filepath.Join(rootDir, "bin", binary.Name)
+		if err := fsutil.CopyFile(binary.Path, dst); err != nil {
+			return nil, fmt.Errorf("failed to copy binary: %w", err)
+		}
+		if err := os.Chmod(dst, 0755); err != nil {
+			return nil, err
+		}
 	}
 
-	f, err := os.Create(path)
-	if err != nil {
-		return err
+	for _, extra := range b.config.ExtraFiles {
+		dst := filepath.Join(rootDir, extra.Destination)
+		if err := fsutil.CopyFile(extra.Source, dst); err != nil {
+			return nil, fmt.Errorf("failed to copy extra file %s: %w", extra.Source, err)
+		}
+		if extra.Mode != 0 {
+			if err := os.Chmod(dst, os.FileMode(extra.Mode)); err != nil {
+				return nil, err
+			}
+		}
 	}
-	defer f.Close()
 
-	name := b.config.Name
-	if name == "" {
-		name = b.tmplCtx.Get("ProjectName")
+	apps := make(map[string]snapApp)
+	if len(b.config.Apps) > 0 {
+		for appName, app := range b.config.Apps {
+			command := app.Command
+			if !strings.Contains(command, "/") {
+				command = "bin/" + command
+			}
+			if app.Args != "" {
+				command += " " + app.Args
+			}
+			apps[appName] = snapApp{
+				Command:   command,
+				Plugs:     append(slices.Clone(app.Plugs), b.config.Plugs...),
+				Slots:     app.Slots,
+				Daemon:    app.Daemon,
+				Completer: app.Completer,
+			}
+		}
+	} else {
+		for _, binary := range binaries {
+			gui := b.guis[binary.BuildID]
+			plugs := snapCLIPlugs
+			if gui != nil {
+				plugs = snapGUIPlugs
+				if err := b.stageGUI(guiDir, name, binary.Name, gui); err != nil {
+					return nil, err
+				}
+			}
+			apps[binary.Name] = snapApp{
+				Command: "bin/" + binary.Name,
+				Plugs:   append(slices.Clone(plugs), b.config.Plugs...),
+			}
+		}
 	}
 
 	grade := b.config.Grade
 	if grade == "" {
 		grade = "stable"
 	}
-
 	confinement := b.config.Confinement
 	if confinement == "" {
 		confinement = "strict"
 	}
-
 	base := b.config.Base
 	if base == "" {
 		base = "core22"
 	}
-
-	data := map[string]interface{}{
-		"Name":        name,
-		"Version":     strings.TrimPrefix(b.tmplCtx.Get("Version"), "v"),
-		"Summary":     b.config.Summary,
-		"Description": b.config.Description,
-		"Grade":       grade,
-		"Confinement": confinement,
-		"Base":        base,
-		"Apps":        b.config.Apps,
-	}
-
-	return t.Execute(f, data)
-}
-
-// BuildAllFlatpaks builds all configured Flatpak packages
-func BuildAllFlatpaks(ctx context.Context, cfg *config.Config, tmplCtx *tmpl.Context, manager *artifact.Manager, distDir string) error {
-	if len(cfg.Flatpaks) == 0 {
-		return nil
+	summary := b.config.Summary
+	if summary == "" {
+		summary = name
+	}
+	description := b.config.Description
+	if description == "" {
+		description = summary
+	}
+
+	project := &snapcraftYAML{
+		Name:        name,
+		Title:       b.config.Title,
+		Version:     version,
+		Summary:     summary,
+		Description: description,
+		License:     b.config.License,
+		Grade:       grade,
+		Confinement: confinement,
+		Base:        base,
+		Layout:      b.config.Layout,
+		Apps:        apps,
+		Parts: map[string]snapPart{
+			name: {Plugin: "dump", Source: "root"},
+		},
 	}
 
-	log.Info("Building Flatpak packages", "count", len(cfg.Flatpaks))
-
-	for _, flatpakCfg := range cfg.Flatpaks {
-		if flatpakCfg.Skip == "true" {
-			log.Info("Skipping Flatpak", "id", flatpakCfg.ID)
-			continue
-		}
-
-		builder := NewFlatpakBuilder(FlatpakConfig{
-			ID:             flatpakCfg.ID,
-			AppID:          flatpakCfg.AppID,
-			Runtime:        flatpakCfg.Runtime,
-			RuntimeVersion: flatpakCfg.RuntimeVersion,
-			SDK:            flatpakCfg.SDK,
-			Command:        flatpakCfg.Command,
-			Permissions:    flatpakCfg.FinishArgs,
-		}, tmplCtx, manager, distDir)
-
-		if err := builder.Build(ctx); err != nil {
-			return fmt.Errorf("failed to build Flatpak %s: %w", flatpakCfg.ID, err)
+	// Binaries are prebuilt, so any host can build the snap for arch
+	host := snapArchs[goruntime.GOARCH]
+	switch base {
+	case "core18", "core20":
+		project.Architectures = []snapArchitecture{{BuildOn: []string{host}, RunOn: []string{arch}}}
+	case "core22":
+		project.Architectures = []snapArchitecture{{BuildOn: []string{host}, BuildFor: []string{arch}}}
+	default:
+		project.Platforms = map[string]snapArchitecture{
+			arch: {BuildOn: []string{host}, BuildFor: []string{arch}},
 		}
 	}
-
-	return nil
+	return project, nil
 }
 
-// BuildAllAppImages builds all configured AppImage packages
-func BuildAllAppImages(ctx context.Context, cfg *config.Config, tmplCtx *tmpl.Context, manager *artifact.Manager, distDir string) error {
-	if len(cfg.AppImages) == 0 {
-		return nil
+// stageGUI writes the desktop file and icon of a gui app to snap/gui,
+// which snapcraft installs into meta/gui
+func (b *SnapBuilder) stageGUI(guiDir, snapName, appName string, gui *config.GUIConfig) error {
+	displayName := gui.Name
+	if displayName == "" {
+		displayName = appName
+	}
+	command := appName
+	if appName != snapName {
+		command = snapName + "." + appName
 	}
 
-	log.Info("Building AppImage packages", "count", len(cfg.AppImages))
-
-	for _, appImageCfg := range cfg.AppImages {
-		if appImageCfg.Skip == "true" {
-			log.Info("Skipping AppImage", "id", appImageCfg.ID)
-			continue
-		}
+	entry := desktop.Entry{
+		Name:          displayName,
+		Exec:          command,
+		Icon:          fmt.Sprintf("${SNAP}/meta/gui/%s.png", appName),
+		GUI:           gui,
+		Localizations: b.localizations,
+	}
+	if err := entry.Validate(); err != nil {
+		return err
+	}
+	if err := os.WriteFile(filepath.Join(guiDir, appName+".desktop"), []byte(entry.String()), 0644); err != nil {
+		return err
+	}
 
-		// Convert categories from string to slice if needed
-		var categories []string
-		if appImageCfg.Categories != "" {
-			categories = strings.Split(appImageCfg.Categories, ";")
+	iconPath := gui.Icon
+	if iconPath == "" || strings.ToLower(filepath.Ext(iconPath)) != ".png" {
+		iconSet, err := assets.EnsureAppIcon(displayName, b.distDir)
+		if err != nil {
+			return fmt.Errorf("failed to generate default icon: %w", err)
 		}
+		iconPath = iconSet.PNG
+	}
+	return fsutil.CopyFile(iconPath, filepath.Join(guiDir, appName+".png"))
+}
 
-		builder := NewAppImageBuilder(AppImageConfig{
-			ID:         appImageCfg.ID,
-			Name:       appImageCfg.Name,
-			Icon:       appImageCfg.Icon,
-			Desktop:    appImageCfg.Desktop,
-			Categories: categories,
-			ExtraFiles: appImageCfg.ExtraFiles,
-		}, tmplCtx, manager, distDir)
-
-		if err := builder.Build(ctx); err != nil {
-			return fmt.Errorf("failed to build AppImage %s: %w", appImageCfg.ID, err)
+// inCI reports whether releaser runs on a CI service
+func inCI() bool {
+	for _, key := range ciEnvVars {
+		if os.Getenv(key) != "" {
+			return true
 		}
 	}
-
-	return nil
+	return false
 }
 
 // BuildAllSnaps builds all configured Snap packages
@@ -665,13 +458,20 @@ func BuildAllSnaps(ctx context.Context, cfg *config.Config, tmplCtx *tmpl.Contex
 
 	log.Info("Building Snap packages", "count", len(cfg.Snapcrafts))
 
+	guis := make(map[string]*config.GUIConfig)
+	for _, build := range cfg.Builds {
+		if build.GUI != nil {
+			guis[build.ID] = build.GUI
+		}
+	}
+
 	for _, snapCfg := range cfg.Snapcrafts {
 		if snapCfg.Skip == "true" {
 			log.Info("Skipping Snap", "id", snapCfg.ID)
 			continue
 		}
 
-		builder := NewSnapBuilder(snapCfg, tmplCtx, manager, distDir)
+		builder := NewSnapBuilder(snapCfg, tmplCtx, manager, distDir).WithGUIs(guis).WithLocalizations(cfg.Localizations)
 
 		if err := builder.Build(ctx); err != nil {
 			return fmt.Errorf("failed to build Snap %s: %w", snapCfg.ID, err)
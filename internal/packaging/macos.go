@@ -9,10 +9,13 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
+	"strings"
 
 	"github.com/charmbracelet/log"
 	"github.com/oarkflow/releaser/internal/artifact"
 	"github.com/oarkflow/releaser/internal/config"
+	"github.com/oarkflow/releaser/internal/fsutil"
+	"github.com/oarkflow/releaser/internal/redact"
 	"github.com/oarkflow/releaser/internal/tmpl"
 )
 
@@ -34,7 +37,9 @@ func NewPKGBuilder(cfg config.PKG, tmplCtx *tmpl.Context, manager *artifact.Mana
 	}
 }
 
-// Build creates a macOS PKG installer.
+// Build creates a macOS PKG installer per architecture. Without pkgbuild,
+// outside macOS, the staged payload, scripts and a build script are
+// archived into dist so the installer can be built on a Mac later.
 func (b *PKGBuilder) Build(ctx context.Context) error {
 	log.Info("Building macOS PKG installer")
 
@@ -52,15 +57,18 @@ func (b *PKGBuilder) Build(ctx context.Context) error {
 	}
 
 	// Collect darwin binaries (CLI, helpers, etc.)
-	sources = append(sources, b.manager.Filter(func(a artifact.Artifact) bool {
-		return a.Type == artifact.TypeBinary && a.Goos == "darwin" && b.matchesBuild(a.BuildID)
-	})...)
+	sources = append(sources, darwinBinaries(b.manager, b.config.Builds)...)
 
 	if len(sources) == 0 {
 		log.Debug("No darwin artifacts found for PKG creation, skipping")
 		return nil
 	}
 
+	identity, err := b.signIdentity()
+	if err != nil {
+		return err
+	}
+
 	// Group artifacts by architecture so we can bundle multiple binaries together
 	grouped := make(map[string][]artifact.Artifact)
 	for _, src := range sources {
@@ -79,12 +87,12 @@ func (b *PKGBuilder) Build(ctx context.Context) error {
 
 	for arch, artifacts := range grouped {
 		if hasPkgbuild {
-			if err := b.createPKG(ctx, artifacts, arch); err != nil {
+			if err := b.createPKG(ctx, artifacts, arch, identity); err != nil {
 				return fmt.Errorf("failed to create PKG for %s (%s): %w", artifacts[0].Name, arch, err)
 			}
 		} else {
 			// Create an installer package structure for later use on macOS
-			if err := b.createPkgStructure(ctx, artifacts, arch); err != nil {
+			if err := b.createPkgStructure(ctx, artifacts, arch, identity); err != nil {
 				return fmt.Errorf("failed to create PKG structure for %s (%s): %w", artifacts[0].Name, arch, err)
 			}
 		}
@@ -93,8 +101,40 @@ func (b *PKGBuilder) Build(ctx context.Context) error {
 	return nil
 }
 
-// createPkgStructure creates a PKG-like structure when pkgbuild isn't available
-func (b *PKGBuilder) createPkgStructure(ctx context.Context, sources []artifact.Artifact, arch string) error {
+// signIdentity returns the templated signing identity. Installers can only
+// be signed with an installer identity, not an application one.
+func (b *PKGBuilder) signIdentity() (string, error) {
+	if b.config.Sign.Identity == "" {
+		return "", nil
+	}
+	identity, err := b.tmplCtx.Apply("pkgs.sign.identity", b.config.Sign.Identity)
+	if err != nil {
+		return "", fmt.Errorf("failed to apply template to pkg sign identity: %w", err)
+	}
+	if strings.Contains(identity, "Application:") {
+		return "", fmt.Errorf("pkg sign identity %q is an application identity, installers are signed with a \"Developer ID Installer: ...\" identity", identity)
+	}
+	return identity, nil
+}
+
+// pkgLayout is a PKG staged in a directory: the payload in root, the
+// scripts, the component plist and the distribution, all named relative to
+// the directory so the same commands work here and from the build script
+type pkgLayout struct {
+	dir             string
+	name            string
+	version         string
+	identifier      string
+	installLocation string
+	identity        string
+	scripts         bool
+	componentPlist  bool
+	distribution    bool
+	resources       bool
+}
+
+// stage lays out the PKG for sources in dir
+func (b *PKGBuilder) stage(dir string, sources []artifact.Artifact, arch, identity string) (*pkgLayout, error) {
 	name := b.config.Name
 	if name == "" {
 		name = b.tmplCtx.Get("ProjectName")
@@ -110,65 +150,184 @@ func (b *PKGBuilder) createPkgStructure(ctx context.Context, sources []artifact.
 		identifier = fmt.Sprintf("com.example.%s", name)
 	}
 
-	installLocation := b.determineInstallLocation(sources)
+	layout := &pkgLayout{
+		dir:             dir,
+		name:            name,
+		version:         version,
+		identifier:      identifier,
+		installLocation: b.determineInstallLocation(sources),
+		identity:        identity,
+	}
 
-	// Create a tar.gz package with macOS installer structure
-	pkgDirName := fmt.Sprintf("%s_%s_%s_macos_pkg", name, version, arch)
-	pkgDir := filepath.Join(b.distDir, pkgDirName)
-	payloadDir := filepath.Join(pkgDir, "payload")
-	scriptsDir := filepath.Join(pkgDir, "scripts")
+	if err := os.RemoveAll(dir); err != nil {
+		return nil, err
+	}
 
-	for _, dir := range []string{payloadDir, scriptsDir} {
-		if err := os.MkdirAll(dir, 0755); err != nil {
-			return err
+	// pkgbuild applies the root's mode to the install location, so it must
+	// be 0755 or the install location becomes unreadable for other users
+	root := filepath.Join(dir, "root")
+	if err := os.MkdirAll(root, 0755); err != nil {
+		return nil, err
+	}
+	if err := os.Chmod(root, 0755); err != nil {
+		return nil, err
+	}
+	if err := b.stageSources(root, sources, layout.installLocation); err != nil {
+		return nil, err
+	}
+	for _, file := range b.config.ExtraFiles {
+		dst := file.Dst
+		if dst == "" {
+			dst = filepath.Base(file.Src)
+		}
+		if err := copyFilePkg(file.Src, filepath.Join(root, dst)); err != nil {
+			return nil, fmt.Errorf("failed to copy extra file %s: %w", file.Src, err)
 		}
 	}
 
-	if err := b.stageSources(payloadDir, sources, installLocation); err != nil {
-		return err
+	for script, src := range map[string]string{
+		"preinstall":  b.config.Scripts.PreInstall,
+		"postinstall": b.config.Scripts.PostInstall,
+	} {
+		if src == "" {
+			continue
+		}
+		dst := filepath.Join(dir, "scripts", script)
+		if err := copyFilePkg(src, dst); err != nil {
+			return nil, fmt.Errorf("failed to copy %s script: %w", script, err)
+		}
+		if err := os.Chmod(dst, 0755); err != nil {
+			return nil, err
+		}
+		layout.scripts = true
 	}
 
-	if err := b.writeFallbackPostinstall(scriptsDir, installLocation, sources); err != nil {
-		return err
+	componentPlist := filepath.Join(dir, "component.plist")
+	if b.config.ComponentPlist != "" {
+		if err := copyFilePkg(b.config.ComponentPlist, componentPlist); err != nil {
+			return nil, fmt.Errorf("failed to copy component plist: %w", err)
+		}
+		layout.componentPlist = true
+	} else {
+		ok, err := b.generateComponentPlist(componentPlist, sources, layout.installLocation)
+		if err != nil {
+			return nil, err
+		}
+		layout.componentPlist = ok
 	}
 
-	// Create Distribution.xml for productbuild
-	distXml := fmt.Sprintf(`<?xml version="1.0" encoding="utf-8"?>
-<installer-gui-script minSpecVersion="1">
-    <title>%s</title>
-    <organization>%s</organization>
-    <domains enable_localSystem="true"/>
-    <options customize="never" require-scripts="true" rootVolumeOnly="true"/>
-    <pkg-ref id="%s"/>
-    <choices-outline>
-        <line choice="default">
-            <line choice="%s"/>
-        </line>
-    </choices-outline>
-    <choice id="default"/>
-    <choice id="%s" visible="false">
-        <pkg-ref id="%s"/>
-    </choice>
-    <pkg-ref id="%s" version="%s" onConclusion="none">%s.pkg</pkg-ref>
-</installer-gui-script>
-`, name, identifier, identifier, identifier, identifier, identifier, identifier, version, name)
-	if err := os.WriteFile(filepath.Join(pkgDir, "Distribution.xml"), []byte(distXml), 0644); err != nil {
+	if b.config.Distribution != "" {
+		if err := b.writeDistribution(filepath.Join(dir, "distribution.xml"), sources[0], layout, arch); err != nil {
+			return nil, err
+		}
+		layout.distribution = true
+		if b.config.Resources != "" {
+			if err := copyDirPkg(b.config.Resources, filepath.Join(dir, "resources")); err != nil {
+				return nil, fmt.Errorf("failed to copy resources: %w", err)
+			}
+			layout.resources = true
+		}
+	}
+
+	return layout, nil
+}
+
+// writeDistribution renders the distribution template. It can refer to the
+// component package as {{ .PKGComponent }}.
+func (b *PKGBuilder) writeDistribution(path string, source artifact.Artifact, layout *pkgLayout, arch string) error {
+	data, err := os.ReadFile(b.config.Distribution)
+	if err != nil {
+		return fmt.Errorf("failed to read distribution: %w", err)
+	}
+
+	tmplCtx := b.tmplCtx.ForArtifact(source)
+	tmplCtx.Set("Arch", arch)
+	tmplCtx.Set("PKGIdentifier", layout.identifier)
+	tmplCtx.Set("PKGVersion", layout.version)
+	tmplCtx.Set("PKGComponent", "component.pkg")
+	distribution, err := tmplCtx.Apply("pkgs.distribution", string(data))
+	if err != nil {
 		return err
 	}
+	return os.WriteFile(path, []byte(distribution), 0644)
+}
 
-	// Create README
-	readme := fmt.Sprintf(`# %s macOS Installer
+// pkgbuildArgs returns the pkgbuild arguments building the component
+// package; it is signed unless it is wrapped in a product archive
+func (l *pkgLayout) pkgbuildArgs(output, keychain string) []string {
+	args := []string{
+		"--root", "root",
+		"--identifier", l.identifier,
+		"--version", l.version,
+		"--install-location", l.installLocation,
+		"--ownership", "recommended",
+	}
+	if l.scripts {
+		args = append(args, "--scripts", "scripts")
+	}
+	if l.componentPlist {
+		args = append(args, "--component-plist", "component.plist")
+	}
+	if !l.distribution {
+		args = append(args, signArgs(l.identity, keychain)...)
+	}
+	return append(args, output)
+}
+
+// productbuildArgs returns the productbuild arguments wrapping the
+// component package with the distribution
+func (l *pkgLayout) productbuildArgs(output, keychain string) []string {
+	args := []string{"--distribution", "distribution.xml", "--package-path", "."}
+	if l.resources {
+		args = append(args, "--resources", "resources")
+	}
+	args = append(args, signArgs(l.identity, keychain)...)
+	return append(args, output)
+}
 
-This package can be built into a .pkg installer on macOS using:
+// signArgs returns the installer signing arguments
+func signArgs(identity, keychain string) []string {
+	if identity == "" {
+		return nil
+	}
+	args := []string{"--sign", identity}
+	if keychain != "" {
+		args = append(args, "--keychain", keychain)
+	}
+	return args
+}
+
+// createPkgStructure archives the staged PKG with a build.sh that runs
+// pkgbuild and productbuild, when pkgbuild isn't available
+func (b *PKGBuilder) createPkgStructure(ctx context.Context, sources []artifact.Artifact, arch, identity string) error {
+	name := b.config.Name
+	if name == "" {
+		name = b.tmplCtx.Get("ProjectName")
+	}
+	version := b.config.Version
+	if version == "" {
+		version = b.tmplCtx.Get("Version")
+	}
 
-    cd %s
-    pkgbuild --root payload --scripts scripts --identifier %s --version %s %s.pkg
-    productbuild --distribution Distribution.xml --package-path . %s_%s.pkg
+	pkgDirName := fmt.Sprintf("%s_%s_%s_macos_pkg", name, version, arch)
+	pkgDir := filepath.Join(b.distDir, pkgDirName)
+	layout, err := b.stage(pkgDir, sources, arch, identity)
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(pkgDir)
 
-Or install manually (from target root):
-    sudo rsync -a payload/ %s/
-`, name, pkgDirName, identifier, version, name, name, version, installLocation)
-	if err := os.WriteFile(filepath.Join(pkgDir, "README.txt"), []byte(readme), 0644); err != nil {
+	pkgFileName := fmt.Sprintf("%s_%s_%s.pkg", name, version, arch)
+	var script strings.Builder
+	fmt.Fprintf(&script, "#!/bin/sh\n# Builds %s on macOS\nset -e\ncd \"$(dirname \"$0\")\"\n", pkgFileName)
+	if layout.distribution {
+		fmt.Fprintf(&script, "pkgbuild %s\n", shellJoin(layout.pkgbuildArgs("component.pkg", b.config.Sign.Keychain)))
+		fmt.Fprintf(&script, "productbuild %s\n", shellJoin(layout.productbuildArgs(pkgFileName, b.config.Sign.Keychain)))
+		script.WriteString("rm component.pkg\n")
+	} else {
+		fmt.Fprintf(&script, "pkgbuild %s\n", shellJoin(layout.pkgbuildArgs(pkgFileName, b.config.Sign.Keychain)))
+	}
+	if err := os.WriteFile(filepath.Join(pkgDir, "build.sh"), []byte(script.String()), 0755); err != nil {
 		return err
 	}
 
@@ -181,11 +340,8 @@ Or install manually (from target root):
 		return fmt.Errorf("failed to create tar.gz: %w", err)
 	}
 
-	// Clean up the directory
-	os.RemoveAll(pkgDir)
-
 	// Add artifact
-	b.manager.Add(artifact.Artifact{
+	if err := b.manager.Add(artifact.Artifact{
 		Name:   tarFileName,
 		Path:   tarPath,
 		Type:   artifact.TypeArchive,
@@ -196,129 +352,62 @@ Or install manually (from target root):
 			"installer": true,
 			"pkg_ready": true,
 		},
-	})
+	}); err != nil {
+		return err
+	}
 
-	log.Info("macOS PKG installer package created (compile on macOS with pkgbuild)", "name", tarFileName)
+	log.Info("macOS PKG installer package created (run build.sh on macOS)", "name", tarFileName)
 	return nil
 }
 
+// shellJoin joins args for a POSIX shell, quoting those that need it
+func shellJoin(args []string) string {
+	quoted := make([]string, len(args))
+	for i, arg := range args {
+		quoted[i] = arg
+		if arg == "" || strings.ContainsFunc(arg, func(r rune) bool {
+			return !strings.ContainsRune("abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789-_./=:@+,", r)
+		}) {
+			quoted[i] = "'" + strings.ReplaceAll(arg, "'", `'\''`) + "'"
+		}
+	}
+	return strings.Join(quoted, " ")
+}
+
 // createPKG creates a PKG from one or more artifacts of the same architecture.
-func (b *PKGBuilder) createPKG(ctx context.Context, sources []artifact.Artifact, arch string) error {
+func (b *PKGBuilder) createPKG(ctx context.Context, sources []artifact.Artifact, arch, identity string) error {
 	if len(sources) == 0 {
 		return fmt.Errorf("no sources provided for PKG creation")
 	}
 
-	name := b.config.Name
-	if name == "" {
-		name = b.tmplCtx.Get("ProjectName")
-	}
-
-	version := b.config.Version
-	if version == "" {
-		version = b.tmplCtx.Get("Version")
-	}
-
-	identifier := b.config.Identifier
-	if identifier == "" {
-		identifier = fmt.Sprintf("com.example.%s", name)
-	}
-
-	pkgFileName := fmt.Sprintf("%s_%s_%s.pkg", name, version, arch)
-	pkgPath := filepath.Join(b.distDir, pkgFileName)
-
-	installLocation := b.determineInstallLocation(sources)
-
-	// Create a staging directory with ONLY the source artifacts
 	stagingDir, err := os.MkdirTemp("", "pkg-staging-")
 	if err != nil {
 		return fmt.Errorf("failed to create staging directory: %w", err)
 	}
 	defer os.RemoveAll(stagingDir)
 
-	if err := b.stageSources(stagingDir, sources, installLocation); err != nil {
+	layout, err := b.stage(stagingDir, sources, arch, identity)
+	if err != nil {
 		return err
 	}
 
-	componentPlistPath, err := b.generateComponentPlist(sources, installLocation)
+	pkgFileName := fmt.Sprintf("%s_%s_%s.pkg", layout.name, layout.version, arch)
+	pkgPath, err := filepath.Abs(filepath.Join(b.distDir, pkgFileName))
 	if err != nil {
 		return err
 	}
-	if componentPlistPath != "" {
-		defer os.Remove(componentPlistPath)
-	}
 
-	// Create scripts directory if needed
-	var scriptsDir string
-	if b.config.Scripts.PreInstall != "" || b.config.Scripts.PostInstall != "" {
-		var err error
-		scriptsDir, err = os.MkdirTemp("", "pkg-scripts-")
-		if err != nil {
-			return err
-		}
-		defer os.RemoveAll(scriptsDir)
-
-		if b.config.Scripts.PreInstall != "" {
-			if err := copyFile(b.config.Scripts.PreInstall, filepath.Join(scriptsDir, "preinstall")); err != nil {
-				return fmt.Errorf("failed to copy preinstall script: %w", err)
-			}
-			os.Chmod(filepath.Join(scriptsDir, "preinstall"), 0755)
-		}
-		if b.config.Scripts.PostInstall != "" {
-			if err := copyFile(b.config.Scripts.PostInstall, filepath.Join(scriptsDir, "postinstall")); err != nil {
-				return fmt.Errorf("failed to copy postinstall script: %w", err)
-			}
-			os.Chmod(filepath.Join(scriptsDir, "postinstall"), 0755)
-		}
-	}
-
-	// Build component package first
 	componentPkgPath := pkgPath
-	if b.config.Distribution != "" || len(b.config.ExtraFiles) > 0 {
-		componentPkgPath = filepath.Join(b.distDir, fmt.Sprintf("%s-component-%s.pkg", name, arch))
-	}
-
-	// Build pkgbuild arguments - use the staging directory as root
-	args := []string{
-		"--root", stagingDir,
-		"--identifier", identifier,
-		"--version", version,
-		"--install-location", installLocation,
-	}
-
-	if scriptsDir != "" {
-		args = append(args, "--scripts", scriptsDir)
-	}
-	if componentPlistPath != "" {
-		args = append(args, "--component-plist", componentPlistPath)
+	if layout.distribution {
+		componentPkgPath = "component.pkg"
 	}
-
-	// Sign if configured
-	if b.config.Sign.Identity != "" {
-		args = append(args, "--sign", b.config.Sign.Identity)
-		if b.config.Sign.Keychain != "" {
-			args = append(args, "--keychain", b.config.Sign.Keychain)
-		}
-	}
-
-	args = append(args, componentPkgPath)
-
-	// Run pkgbuild
-	cmd := exec.CommandContext(ctx, "pkgbuild", args...)
-	var stderr bytes.Buffer
-	cmd.Stderr = &stderr
-
-	log.Debug("Running pkgbuild", "args", args)
-	if err := cmd.Run(); err != nil {
-		return fmt.Errorf("pkgbuild failed: %w\n%s", err, stderr.String())
+	if err := runPkgTool(ctx, stagingDir, "pkgbuild", layout.pkgbuildArgs(componentPkgPath, b.config.Sign.Keychain)); err != nil {
+		return err
 	}
-
-	// If we need a distribution package, use productbuild
-	if b.config.Distribution != "" || componentPkgPath != pkgPath {
-		if err := b.createDistributionPkg(ctx, componentPkgPath, pkgPath, name, version); err != nil {
-			os.Remove(componentPkgPath)
+	if layout.distribution {
+		if err := runPkgTool(ctx, stagingDir, "productbuild", layout.productbuildArgs(pkgPath, b.config.Sign.Keychain)); err != nil {
 			return err
 		}
-		os.Remove(componentPkgPath)
 	}
 
 	// Notarize if configured
@@ -329,53 +418,31 @@ func (b *PKGBuilder) createPKG(ctx context.Context, sources []artifact.Artifact,
 	}
 
 	// Add artifact
-	b.manager.Add(artifact.Artifact{
+	if err := b.manager.Add(artifact.Artifact{
 		Name:   pkgFileName,
 		Path:   pkgPath,
 		Type:   artifact.TypePKG,
 		Goos:   "darwin",
 		Goarch: arch,
-	})
+	}); err != nil {
+		return err
+	}
 
-	log.Info("PKG created", "name", pkgFileName)
+	log.Info("PKG created", "name", pkgFileName, "signed", identity != "")
 	return nil
 }
 
-// createDistributionPkg creates a distribution package with productbuild.
-func (b *PKGBuilder) createDistributionPkg(ctx context.Context, componentPkg, outputPkg, name, version string) error {
-	args := []string{
-		"--package", componentPkg,
-	}
-
-	// Add distribution file if specified
-	if b.config.Distribution != "" {
-		args = append(args, "--distribution", b.config.Distribution)
-	}
-
-	// Add resources if specified
-	if b.config.Resources != "" {
-		args = append(args, "--resources", b.config.Resources)
-	}
-
-	// Sign if configured
-	if b.config.Sign.Identity != "" {
-		args = append(args, "--sign", b.config.Sign.Identity)
-		if b.config.Sign.Keychain != "" {
-			args = append(args, "--keychain", b.config.Sign.Keychain)
-		}
-	}
-
-	args = append(args, outputPkg)
-
-	cmd := exec.CommandContext(ctx, "productbuild", args...)
+// runPkgTool runs pkgbuild or productbuild in dir
+func runPkgTool(ctx context.Context, dir, tool string, args []string) error {
+	cmd := exec.CommandContext(ctx, tool, args...)
+	cmd.Dir = dir
 	var stderr bytes.Buffer
 	cmd.Stderr = &stderr
 
-	log.Debug("Running productbuild", "args", args)
+	log.Debug("Running "+tool, "args", redact.Args(args))
 	if err := cmd.Run(); err != nil {
-		return fmt.Errorf("productbuild failed: %w\n%s", err, stderr.String())
+		return fmt.Errorf("%s failed: %w\n%s", tool, err, stderr.String())
 	}
-
 	return nil
 }
 
@@ -487,8 +554,11 @@ func (b *PKGBuilder) stageSources(root string, sources []artifact.Artifact, inst
 	return nil
 }
 
-// generateComponentPlist marks bundled apps as non-relocatable so Installer honors our target paths.
-func (b *PKGBuilder) generateComponentPlist(sources []artifact.Artifact, installLocation string) (string, error) {
+// generateComponentPlist writes a component plist for bundled apps, which
+// are not relocatable unless configured so Installer honors our target
+// paths instead of updating copies found elsewhere on the disk. It reports
+// whether there were apps to write it for.
+func (b *PKGBuilder) generateComponentPlist(path string, sources []artifact.Artifact, installLocation string) (bool, error) {
 	var bundlePaths []string
 	for _, source := range sources {
 		if source.Type != artifact.TypeAppBundle {
@@ -497,14 +567,8 @@ func (b *PKGBuilder) generateComponentPlist(sources []artifact.Artifact, install
 		bundlePaths = append(bundlePaths, b.relativeInstallPath(source, installLocation))
 	}
 	if len(bundlePaths) == 0 {
-		return "", nil
-	}
-
-	file, err := os.CreateTemp("", "pkg-component-*.plist")
-	if err != nil {
-		return "", err
+		return false, nil
 	}
-	defer file.Close()
 
 	var buf bytes.Buffer
 	buf.WriteString(`<?xml version="1.0" encoding="UTF-8"?>
@@ -514,7 +578,7 @@ func (b *PKGBuilder) generateComponentPlist(sources []artifact.Artifact, install
 `)
 	for _, relPath := range bundlePaths {
 		fmt.Fprintf(&buf, "\t<dict>\n")
-		buf.WriteString("\t\t<key>BundleIsRelocatable</key>\n\t\t<false/>\n")
+		fmt.Fprintf(&buf, "\t\t<key>BundleIsRelocatable</key>\n\t\t<%t/>\n", b.config.Relocatable)
 		buf.WriteString("\t\t<key>BundleIsVersionChecked</key>\n\t\t<true/>\n")
 		buf.WriteString("\t\t<key>BundleHasStrictIdentifier</key>\n\t\t<true/>\n")
 		buf.WriteString("\t\t<key>BundleOverwriteAction</key>\n\t\t<string>upgrade</string>\n")
@@ -523,197 +587,7 @@ func (b *PKGBuilder) generateComponentPlist(sources []artifact.Artifact, install
 	}
 	buf.WriteString("</array>\n</plist>\n")
 
-	if _, err := file.Write(buf.Bytes()); err != nil {
-		return "", err
-	}
-
-	return file.Name(), nil
-}
-
-// collectBinaryNames extracts binary names for helper scripts.
-func (b *PKGBuilder) collectBinaryNames(sources []artifact.Artifact) []string {
-	var binaries []string
-	for _, src := range sources {
-		if src.Type == artifact.TypeBinary {
-			binaries = append(binaries, filepath.Base(src.Path))
-		}
-	}
-	return binaries
-}
-
-// writeFallbackPostinstall writes a simple postinstall script for the manual PKG structure fallback.
-func (b *PKGBuilder) writeFallbackPostinstall(dir, installLocation string, sources []artifact.Artifact) error {
-	binaryNames := b.collectBinaryNames(sources)
-	if len(binaryNames) == 0 {
-		return nil
-	}
-	script := "#!/bin/bash\nset -e\n"
-	for _, bin := range binaryNames {
-		if installLocation == "/" {
-			script += fmt.Sprintf("chmod +x /usr/local/bin/%s\n", bin)
-		} else {
-			script += fmt.Sprintf("chmod +x %s/%s\n", installLocation, bin)
-		}
-	}
-	script += "exit 0\n"
-	return os.WriteFile(filepath.Join(dir, "postinstall"), []byte(script), 0755)
-}
-
-// UniversalBinaryBuilder creates macOS universal binaries.
-type UniversalBinaryBuilder struct {
-	config  config.UniversalBinary
-	tmplCtx *tmpl.Context
-	manager *artifact.Manager
-	distDir string
-}
-
-// NewUniversalBinaryBuilder creates a new universal binary builder.
-func NewUniversalBinaryBuilder(cfg config.UniversalBinary, tmplCtx *tmpl.Context, manager *artifact.Manager, distDir string) *UniversalBinaryBuilder {
-	return &UniversalBinaryBuilder{
-		config:  cfg,
-		tmplCtx: tmplCtx,
-		manager: manager,
-		distDir: distDir,
-	}
-}
-
-// Build creates macOS universal binaries by combining amd64 and arm64 binaries.
-func (b *UniversalBinaryBuilder) Build(ctx context.Context) error {
-	// Universal binary creation requires lipo (available on macOS or via cctools)
-	lipoPath := "lipo"
-	if _, err := exec.LookPath("lipo"); err != nil {
-		// Try x86_64-apple-darwin-lipo (cctools)
-		if _, err := exec.LookPath("x86_64-apple-darwin-lipo"); err == nil {
-			lipoPath = "x86_64-apple-darwin-lipo"
-		} else {
-			log.Warn("Skipping universal binary creation: lipo not found")
-			return nil
-		}
-	}
-
-	log.Info("Building macOS universal binaries")
-
-	// Get darwin binaries
-	darwinBinaries := b.manager.Filter(func(a artifact.Artifact) bool {
-		return a.Type == artifact.TypeBinary && a.Goos == "darwin"
-	})
-
-	if len(darwinBinaries) == 0 {
-		log.Debug("No darwin binaries found for universal binary creation, skipping")
-		return nil
-	}
-
-	// Group binaries by name
-	binaryGroups := make(map[string][]artifact.Artifact)
-	for _, bin := range darwinBinaries {
-		// Filter by IDs if specified
-		if len(b.config.IDs) > 0 {
-			found := false
-			for _, id := range b.config.IDs {
-				if bin.BuildID == id {
-					found = true
-					break
-				}
-			}
-			if !found {
-				continue
-			}
-		}
-		binaryGroups[bin.Name] = append(binaryGroups[bin.Name], bin)
-	}
-
-	// Create universal binary for each group
-	for name, binaries := range binaryGroups {
-		if len(binaries) < 2 {
-			log.Debug("Skipping universal binary: need both amd64 and arm64", "name", name)
-			continue
-		}
-
-		// Find amd64 and arm64 binaries
-		var amd64Path, arm64Path string
-		for _, bin := range binaries {
-			if bin.Goarch == "amd64" {
-				amd64Path = bin.Path
-			} else if bin.Goarch == "arm64" {
-				arm64Path = bin.Path
-			}
-		}
-
-		if amd64Path == "" || arm64Path == "" {
-			log.Debug("Skipping universal binary: missing architecture", "name", name)
-			continue
-		}
-
-		if err := b.createUniversalBinary(ctx, lipoPath, name, amd64Path, arm64Path); err != nil {
-			return fmt.Errorf("failed to create universal binary for %s: %w", name, err)
-		}
-	}
-
-	return nil
-}
-
-// createUniversalBinary creates a single universal binary.
-func (b *UniversalBinaryBuilder) createUniversalBinary(ctx context.Context, lipoPath, name, amd64Path, arm64Path string) error {
-	// Determine output path
-	nameTemplate := b.config.NameTemplate
-	if nameTemplate == "" {
-		nameTemplate = "{{ .ProjectName }}_universal"
-	}
-
-	outputName, err := b.tmplCtx.Apply(nameTemplate)
-	if err != nil {
-		outputName = name + "_universal"
-	}
-
-	// Create output directory
-	outputDir := filepath.Join(b.distDir, fmt.Sprintf("%s_darwin_universal", outputName))
-	if err := os.MkdirAll(outputDir, 0755); err != nil {
-		return err
-	}
-
-	outputPath := filepath.Join(outputDir, name)
-
-	// Run lipo to create universal binary
-	args := []string{
-		"-create",
-		"-output", outputPath,
-		amd64Path,
-		arm64Path,
-	}
-
-	cmd := exec.CommandContext(ctx, lipoPath, args...)
-	var stderr bytes.Buffer
-	cmd.Stderr = &stderr
-
-	log.Debug("Running lipo", "args", args)
-	if err := cmd.Run(); err != nil {
-		return fmt.Errorf("lipo failed: %w\n%s", err, stderr.String())
-	}
-
-	// Make executable
-	if err := os.Chmod(outputPath, 0755); err != nil {
-		return err
-	}
-
-	// Add artifact
-	b.manager.Add(artifact.Artifact{
-		Name:   name,
-		Path:   outputPath,
-		Type:   artifact.TypeUniversalBinary,
-		Goos:   "darwin",
-		Goarch: "universal",
-	})
-
-	// Replace original binaries if configured
-	if b.config.Replace {
-		// Remove original binaries from artifact list
-		b.manager.Remove(func(a artifact.Artifact) bool {
-			return a.Type == artifact.TypeBinary && a.Goos == "darwin" && a.Name == name
-		})
-	}
-
-	log.Info("Universal binary created", "name", name)
-	return nil
+	return true, os.WriteFile(path, buf.Bytes(), 0644)
 }
 
 // BuildAllPKGs builds PKGs for all configurations.
@@ -728,31 +602,15 @@ func BuildAllPKGs(ctx context.Context, configs []config.PKG, tmplCtx *tmpl.Conte
 	return nil
 }
 
-// BuildAllUniversalBinaries builds universal binaries for all configurations.
-func BuildAllUniversalBinaries(ctx context.Context, configs []config.UniversalBinary, tmplCtx *tmpl.Context, manager *artifact.Manager, distDir string) error {
-	for i, cfg := range configs {
-		log.Info("Building universal binary", "index", i+1, "total", len(configs))
-		builder := NewUniversalBinaryBuilder(cfg, tmplCtx, manager, distDir)
-		if err := builder.Build(ctx); err != nil {
-			return err
-		}
-	}
-	return nil
-}
-
 // copyFilePkg copies a file from src to dst (used in PKG creation).
 func copyFilePkg(src, dst string) error {
 	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
 		return err
 	}
-	data, err := os.ReadFile(src)
-	if err != nil {
-		return err
-	}
-	return os.WriteFile(dst, data, 0755)
+	return fsutil.CopyFile(src, dst)
 }
 
-// copyDirPkg recursively copies a directory.
+// copyDirPkg recursively copies a directory, keeping modes and symlinks.
 func copyDirPkg(src, dst string) error {
 	return filepath.Walk(src, func(path string, info os.FileInfo, err error) error {
 		if err != nil {
@@ -768,14 +626,9 @@ func copyDirPkg(src, dst string) error {
 		if info.IsDir() {
 			return os.MkdirAll(dstPath, info.Mode())
 		}
-
-		data, err := os.ReadFile(path)
-		if err != nil {
-			return err
-		}
-		if err := os.MkdirAll(filepath.Dir(dstPath), 0755); err != nil {
-			return err
+		if info.Mode()&os.ModeSymlink != 0 {
+			return copySymlink(path, dstPath)
 		}
-		return os.WriteFile(dstPath, data, info.Mode())
+		return fsutil.CopyFile(path, dstPath)
 	})
 }
@@ -0,0 +1,101 @@
+package packaging
+
+import (
+	"testing"
+
+	"github.com/oarkflow/releaser/internal/config"
+	"github.com/oarkflow/releaser/internal/manifest"
+	"github.com/oarkflow/releaser/internal/manifest/manifesttest"
+)
+
+// tricky holds the characters each manifest format has to escape
+const tricky = `Say "hi" to $INSTDIR & <it's> \ ‘quoted’`
+
+func TestManifestGolden(t *testing.T) {
+	tests := []struct {
+		file string
+		g    manifest.Generator
+	}{
+		{
+			file: "Info.plist",
+			g: infoPlist{
+				Executable:           "demo",
+				Identifier:           "com.example.demo",
+				Name:                 "Demo & <Co>",
+				Version:              "1.2.3",
+				ShortVersion:         "1.2",
+				Signature:            "????",
+				IconFile:             "demo.icns",
+				HighResolution:       true,
+				MinimumSystemVersion: "11.0",
+			},
+		},
+		{
+			file: "demo.nsi",
+			g: nsisScript{
+				Name: "demo",
+				Languages: []installerLanguage{
+					{windowsLanguages["en"], config.Localization{Name: "demo", Description: tricky}},
+					{windowsLanguages["de"], config.Localization{Name: "demo", Description: `Sag "hallo"`}},
+				},
+				Multilingual:  true,
+				Version:       "1.2.3",
+				FileVersion:   "1.2.3.0",
+				Publisher:     `Demo "Inc" $1`,
+				OutputPath:    `C:\dist\demo_1.2.3_amd64_setup.exe`,
+				InstallDir:    "demo",
+				MainExe:       "demo.exe",
+				LicenseFile:   `C:\src\LICENSE`,
+				Icon:          `C:\src\demo.ico`,
+				Is64:          true,
+				UninstallKey:  `Software\Microsoft\Windows\CurrentVersion\Uninstall\demo`,
+				Files:         []nsisFile{{Src: `C:\dist\demo.exe`, Dst: "demo.exe"}, {Src: `C:\src\docs\$README.md`, Dst: `docs\$README.md`}},
+				Dirs:          []string{"docs"},
+				RemoveDirs:    []string{"docs"},
+				EstimatedSize: 2048,
+			},
+		},
+		{
+			file: "demo.wxs",
+			g: wixDocument{Product: wixProduct{
+				ID:           "*",
+				Name:         tricky,
+				Language:     "1033",
+				Codepage:     "1252",
+				Version:      "1.2.3",
+				Manufacturer: "Demo & Co",
+				UpgradeCode:  "6f1e2d3c-0000-4000-8000-000000000000",
+				Package: wixPackage{
+					InstallerVersion: "500",
+					Compressed:       "yes",
+					InstallScope:     "perMachine",
+					Platform:         "x64",
+					Languages:        "1033",
+					SummaryCodepage:  "1252",
+				},
+				MajorUpgrade:  wixMajorUpgrade{DowngradeErrorMessage: "A newer version of [ProductName] is already installed."},
+				MediaTemplate: wixMedia{EmbedCab: "yes"},
+				Directory: wixDirectory{
+					ID:   "TARGETDIR",
+					Name: "SourceDir",
+					Directories: []*wixDirectory{{
+						ID:   "INSTALLDIR",
+						Name: "demo",
+						Components: []*wixComponent{{
+							ID:    "MainExecutable",
+							GUID:  "6f1e2d3c-0000-4000-8000-000000000001",
+							Files: []wixFile{{ID: "demo.exe", Source: `C:\dist\<demo>.exe`, KeyPath: "yes"}},
+						}},
+					}},
+				},
+				Feature: wixFeature{ID: "Complete", Level: "1", ComponentRefs: []wixRef{{ID: "MainExecutable"}}},
+			}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.file, func(t *testing.T) {
+			manifesttest.AssertGolden(t, tt.file, tt.g)
+		})
+	}
+}
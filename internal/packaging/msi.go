@@ -0,0 +1,921 @@
+package packaging
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/xml"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/charmbracelet/log"
+	"github.com/oarkflow/releaser/internal/artifact"
+	"github.com/oarkflow/releaser/internal/config"
+	"github.com/oarkflow/releaser/internal/fsutil"
+	"github.com/oarkflow/releaser/internal/manifest"
+	"github.com/oarkflow/releaser/internal/tmpl"
+)
+
+// msiPlatforms maps GOARCH values to MSI platforms
+var msiPlatforms = map[string]string{
+	"amd64": "x64",
+	"386":   "x86",
+	"arm64": "arm64",
+}
+
+// msiGUIDNamespace is the UUID namespace generated GUIDs are derived in
+var msiGUIDNamespace = []byte("github.com/oarkflow/releaser/msi")
+
+// MSIBuilder creates Windows MSI installers.
+type MSIBuilder struct {
+	config        config.MSI
+	guis          map[string]*config.GUIConfig
+	localizations map[string]config.Localization
+	tmplCtx       *tmpl.Context
+	manager       *artifact.Manager
+	distDir       string
+}
+
+// NewMSIBuilder creates a new MSI builder.
+func NewMSIBuilder(cfg config.MSI, tmplCtx *tmpl.Context, manager *artifact.Manager, distDir string) *MSIBuilder {
+	return &MSIBuilder{
+		config:  cfg,
+		tmplCtx: tmplCtx,
+		manager: manager,
+		distDir: distDir,
+	}
+}
+
+// WithGUIs sets the gui settings of each build, by build ID, used for the
+// shortcuts and file associations of the main executable
+func (b *MSIBuilder) WithGUIs(guis map[string]*config.GUIConfig) *MSIBuilder {
+	b.guis = guis
+	return b
+}
+
+// WithLocalizations sets the translations the installer's language is
+// picked from
+func (b *MSIBuilder) WithLocalizations(localizations map[string]config.Localization) *MSIBuilder {
+	b.localizations = localizations
+	return b
+}
+
+// language returns the code and the Windows language of the installer
+func (b *MSIBuilder) language() (string, windowsLanguage, error) {
+	code := b.config.Language
+	if code == "" {
+		code = defaultLanguage
+	}
+	lang, ok := lookupWindowsLanguage(code)
+	if !ok {
+		return "", lang, fmt.Errorf("msis.language %q has no Windows installer language", code)
+	}
+	if _, ok := b.localizations[code]; !ok && code != defaultLanguage {
+		return "", lang, fmt.Errorf("msis.language %q is not one of the localizations", code)
+	}
+	return code, lang, nil
+}
+
+// fileSuffix returns what file names end with before their extension,
+// the architecture and the language when one is set
+func (b *MSIBuilder) fileSuffix(goarch string) string {
+	if b.config.Language == "" {
+		return goarch
+	}
+	return goarch + "_" + b.config.Language
+}
+
+// Build creates an MSI installer per architecture with all the selected
+// Windows binaries; the first one is the main executable.
+func (b *MSIBuilder) Build(ctx context.Context) error {
+	log.Info("Building MSI installer")
+
+	if _, _, err := b.language(); err != nil {
+		return err
+	}
+
+	byArch := make(map[string][]artifact.Artifact)
+	for _, a := range b.manager.Filter(func(a artifact.Artifact) bool {
+		return a.Type == artifact.TypeBinary && a.Goos == "windows" && (b.config.Build == "" || a.BuildID == b.config.Build)
+	}) {
+		byArch[a.Goarch] = append(byArch[a.Goarch], a)
+	}
+
+	if len(byArch) == 0 {
+		log.Debug("No Windows binaries found for MSI, skipping")
+		return nil
+	}
+
+	// Check if MSI tools are available (wixl on Linux or WiX on Windows)
+	hasWixl := false
+	hasWix := false
+	if _, err := exec.LookPath("wixl"); err == nil {
+		hasWixl = true
+	}
+	if _, err := exec.LookPath("candle"); err == nil {
+		hasWix = true
+	}
+
+	goarchs := make([]string, 0, len(byArch))
+	for goarch := range byArch {
+		goarchs = append(goarchs, goarch)
+	}
+	sort.Strings(goarchs)
+
+	for _, goarch := range goarchs {
+		binaries := byArch[goarch]
+		if msiPlatforms[goarch] == "" {
+			log.Warn("Skipping MSI: unsupported architecture", "arch", goarch)
+			continue
+		}
+		if hasWixl || hasWix {
+			if err := b.createMSI(ctx, binaries); err != nil {
+				return fmt.Errorf("failed to create MSI for %s: %w", goarch, err)
+			}
+		} else {
+			// Create WXS file for later use on Windows, plus a ZIP installer package
+			if err := b.createWxsAndZip(ctx, binaries); err != nil {
+				return fmt.Errorf("failed to create Windows package for %s: %w", goarch, err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// nameAndVersion returns the product name and the configured version
+func (b *MSIBuilder) nameAndVersion() (string, string) {
+	name := b.config.Name
+	if name == "" {
+		name = b.tmplCtx.Get("ProjectName")
+	}
+
+	version := b.config.ProductVersion
+	if version == "" {
+		version = b.tmplCtx.Get("Version")
+	} else {
+		// Apply template to version string
+		expanded, err := b.tmplCtx.Apply("msis.product_version", version)
+		if err == nil {
+			version = expanded
+		}
+	}
+	return name, version
+}
+
+// createWxsAndZip creates a WXS file and ZIP package when MSI tools aren't available
+func (b *MSIBuilder) createWxsAndZip(ctx context.Context, binaries []artifact.Artifact) error {
+	name, version := b.nameAndVersion()
+	goarch := binaries[0].Goarch
+
+	// Generate WiX source file for later use
+	wxsPath := filepath.Join(b.distDir, fmt.Sprintf("%s_%s.wxs", name, b.fileSuffix(goarch)))
+	if err := b.generateWxs(wxsPath, binaries, name, version); err != nil {
+		return fmt.Errorf("failed to generate WiX source: %w", err)
+	}
+	log.Info("WiX source file created (compile on Windows with WiX Toolset)", "path", wxsPath)
+
+	// Create a ZIP package as portable installer
+	zipFileName := fmt.Sprintf("%s_%s_%s_windows.zip", name, version, b.fileSuffix(goarch))
+	zipPath := filepath.Join(b.distDir, zipFileName)
+
+	// Create temp directory with install structure
+	tmpDir, err := os.MkdirTemp("", "msi-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tmpDir)
+
+	for _, binary := range binaries {
+		if err := fsutil.CopyFile(binary.Path, filepath.Join(tmpDir, filepath.Base(binary.Path))); err != nil {
+			return err
+		}
+	}
+	for _, file := range b.config.ExtraFiles {
+		dst := filepath.Join(tmpDir, extraFileDst(file.Src, file.Dst))
+		info, err := os.Stat(file.Src)
+		if err != nil {
+			return fmt.Errorf("failed to read extra file: %w", err)
+		}
+		if info.IsDir() {
+			err = copyDir(file.Src, dst)
+		} else {
+			err = fsutil.CopyFile(file.Src, dst)
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	// Create install script
+	installScript := fmt.Sprintf(`@echo off
+echo Installing %s...
+xcopy /E /I /Y "%%~dp0." "%%PROGRAMFILES%%\%s\"
+del "%%PROGRAMFILES%%\%s\install.bat"
+echo Installation complete!
+pause
+`, name, b.installDirName(name), b.installDirName(name))
+	os.WriteFile(filepath.Join(tmpDir, "install.bat"), []byte(installScript), 0644)
+
+	// Create ZIP
+	cmd := exec.CommandContext(ctx, "zip", "-r", zipPath, ".")
+	cmd.Dir = tmpDir
+	if err := cmd.Run(); err != nil {
+		// Fallback to tar if zip not available
+		tarPath := filepath.Join(b.distDir, fmt.Sprintf("%s_%s_%s_windows.tar.gz", name, version, b.fileSuffix(goarch)))
+		tarCmd := exec.CommandContext(ctx, "tar", "-czf", tarPath, "-C", tmpDir, ".")
+		if err := tarCmd.Run(); err != nil {
+			return fmt.Errorf("failed to create archive: %w", err)
+		}
+		zipPath = tarPath
+		zipFileName = filepath.Base(tarPath)
+	}
+
+	// Add artifact
+	if err := b.manager.Add(artifact.Artifact{
+		Name:   zipFileName,
+		Path:   zipPath,
+		Type:   artifact.TypeArchive,
+		Goos:   "windows",
+		Goarch: goarch,
+		Extra: map[string]interface{}{
+			"format":    "zip",
+			"installer": true,
+		},
+	}); err != nil {
+		return err
+	}
+
+	log.Info("Windows installer package created (MSI requires WiX Toolset)", "name", zipFileName)
+	return nil
+}
+
+// createMSI creates an MSI installer.
+func (b *MSIBuilder) createMSI(ctx context.Context, binaries []artifact.Artifact) error {
+	name, version := b.nameAndVersion()
+	goarch := binaries[0].Goarch
+
+	msiFileName := fmt.Sprintf("%s_%s_%s.msi", name, version, b.fileSuffix(goarch))
+	msiPath := filepath.Join(b.distDir, msiFileName)
+
+	// Check if custom WXS file is provided
+	wxsPath := b.config.WXS
+	if wxsPath == "" {
+		// Generate WiX source file
+		wxsPath = filepath.Join(b.distDir, fmt.Sprintf("%s_%s.wxs", name, b.fileSuffix(goarch)))
+		if err := b.generateWxs(wxsPath, binaries, name, version); err != nil {
+			return fmt.Errorf("failed to generate WiX source: %w", err)
+		}
+	}
+
+	// Try wixl (GNOME msitools) first, then WiX Toolset
+	platform := msiPlatforms[goarch]
+	if err := b.runWixl(ctx, wxsPath, msiPath, platform); err != nil {
+		log.Debug("wixl not available, trying WiX Toolset", "error", err)
+		if err := b.runWix(ctx, wxsPath, msiPath, platform); err != nil {
+			return fmt.Errorf("failed to create MSI: %w", err)
+		}
+	}
+
+	// Add artifact
+	if err := b.manager.Add(artifact.Artifact{
+		Name:    msiFileName,
+		Path:    msiPath,
+		Type:    artifact.TypeMSI,
+		Goos:    "windows",
+		Goarch:  goarch,
+		BuildID: binaries[0].BuildID,
+	}); err != nil {
+		return err
+	}
+
+	log.Info("MSI created", "name", msiFileName)
+	return nil
+}
+
+// wixDocument is a WiX 3 source file
+type wixDocument struct {
+	XMLName xml.Name   `xml:"Wix"`
+	Xmlns   string     `xml:"xmlns,attr"`
+	Product wixProduct `xml:"Product"`
+}
+
+// Generate renders the WiX source
+func (d wixDocument) Generate() ([]byte, error) {
+	if d.Xmlns == "" {
+		d.Xmlns = "http://schemas.microsoft.com/wix/2006/wi"
+	}
+	data, err := xml.MarshalIndent(d, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), append(data, '\n')...), nil
+}
+
+type wixProduct struct {
+	ID            string          `xml:"Id,attr"`
+	Name          string          `xml:"Name,attr"`
+	Language      string          `xml:"Language,attr"`
+	Codepage      string          `xml:"Codepage,attr"`
+	Version       string          `xml:"Version,attr"`
+	Manufacturer  string          `xml:"Manufacturer,attr"`
+	UpgradeCode   string          `xml:"UpgradeCode,attr"`
+	Package       wixPackage      `xml:"Package"`
+	MajorUpgrade  wixMajorUpgrade `xml:"MajorUpgrade"`
+	MediaTemplate wixMedia        `xml:"MediaTemplate"`
+	Icon          *wixIcon        `xml:"Icon,omitempty"`
+	Properties    []wixProperty   `xml:"Property"`
+	Directory     wixDirectory    `xml:"Directory"`
+	Feature       wixFeature      `xml:"Feature"`
+}
+
+type wixPackage struct {
+	InstallerVersion string `xml:"InstallerVersion,attr"`
+	Compressed       string `xml:"Compressed,attr"`
+	InstallScope     string `xml:"InstallScope,attr"`
+	Platform         string `xml:"Platform,attr"`
+	Languages        string `xml:"Languages,attr"`
+	SummaryCodepage  string `xml:"SummaryCodepage,attr"`
+	Description      string `xml:"Description,attr,omitempty"`
+}
+
+type wixMajorUpgrade struct {
+	DowngradeErrorMessage string `xml:"DowngradeErrorMessage,attr"`
+}
+
+type wixMedia struct {
+	EmbedCab string `xml:"EmbedCab,attr"`
+}
+
+type wixIcon struct {
+	ID         string `xml:"Id,attr"`
+	SourceFile string `xml:"SourceFile,attr"`
+}
+
+type wixProperty struct {
+	ID    string `xml:"Id,attr"`
+	Value string `xml:"Value,attr"`
+}
+
+type wixDirectory struct {
+	ID          string          `xml:"Id,attr"`
+	Name        string          `xml:"Name,attr,omitempty"`
+	Directories []*wixDirectory `xml:"Directory"`
+	Components  []*wixComponent `xml:"Component"`
+}
+
+type wixComponent struct {
+	ID             string             `xml:"Id,attr"`
+	GUID           string             `xml:"Guid,attr"`
+	Files          []wixFile          `xml:"File"`
+	ProgIDs        []wixProgID        `xml:"ProgId"`
+	ServiceInstall *wixServiceInstall `xml:"ServiceInstall,omitempty"`
+	ServiceControl *wixServiceControl `xml:"ServiceControl,omitempty"`
+	Environment    *wixEnvironment    `xml:"Environment,omitempty"`
+	Shortcuts      []wixShortcut      `xml:"Shortcut"`
+	RemoveFolder   *wixRemoveFolder   `xml:"RemoveFolder,omitempty"`
+	RegistryValue  *wixRegistryValue  `xml:"RegistryValue,omitempty"`
+}
+
+type wixFile struct {
+	ID      string `xml:"Id,attr"`
+	Source  string `xml:"Source,attr"`
+	KeyPath string `xml:"KeyPath,attr,omitempty"`
+}
+
+type wixProgID struct {
+	ID          string       `xml:"Id,attr"`
+	Description string       `xml:"Description,attr,omitempty"`
+	Icon        string       `xml:"Icon,attr,omitempty"`
+	Extension   wixExtension `xml:"Extension"`
+}
+
+type wixExtension struct {
+	ID   string  `xml:"Id,attr"`
+	Verb wixVerb `xml:"Verb"`
+}
+
+type wixVerb struct {
+	ID         string `xml:"Id,attr"`
+	Command    string `xml:"Command,attr"`
+	TargetFile string `xml:"TargetFile,attr"`
+	Argument   string `xml:"Argument,attr"`
+}
+
+type wixServiceInstall struct {
+	ID           string `xml:"Id,attr"`
+	Name         string `xml:"Name,attr"`
+	DisplayName  string `xml:"DisplayName,attr,omitempty"`
+	Description  string `xml:"Description,attr,omitempty"`
+	Type         string `xml:"Type,attr"`
+	Start        string `xml:"Start,attr"`
+	ErrorControl string `xml:"ErrorControl,attr"`
+	Account      string `xml:"Account,attr,omitempty"`
+	Arguments    string `xml:"Arguments,attr,omitempty"`
+}
+
+type wixServiceControl struct {
+	ID     string `xml:"Id,attr"`
+	Name   string `xml:"Name,attr"`
+	Start  string `xml:"Start,attr"`
+	Stop   string `xml:"Stop,attr"`
+	Remove string `xml:"Remove,attr"`
+	Wait   string `xml:"Wait,attr"`
+}
+
+type wixEnvironment struct {
+	ID        string `xml:"Id,attr"`
+	Name      string `xml:"Name,attr"`
+	Value     string `xml:"Value,attr"`
+	Permanent string `xml:"Permanent,attr"`
+	Part      string `xml:"Part,attr"`
+	Action    string `xml:"Action,attr"`
+	System    string `xml:"System,attr"`
+}
+
+type wixShortcut struct {
+	ID               string `xml:"Id,attr"`
+	Name             string `xml:"Name,attr"`
+	Description      string `xml:"Description,attr,omitempty"`
+	Target           string `xml:"Target,attr"`
+	Arguments        string `xml:"Arguments,attr,omitempty"`
+	WorkingDirectory string `xml:"WorkingDirectory,attr"`
+	Icon             string `xml:"Icon,attr,omitempty"`
+}
+
+type wixRemoveFolder struct {
+	ID string `xml:"Id,attr"`
+	On string `xml:"On,attr"`
+}
+
+type wixRegistryValue struct {
+	Root    string `xml:"Root,attr"`
+	Key     string `xml:"Key,attr"`
+	Name    string `xml:"Name,attr"`
+	Type    string `xml:"Type,attr"`
+	Value   string `xml:"Value,attr"`
+	KeyPath string `xml:"KeyPath,attr"`
+}
+
+type wixFeature struct {
+	ID            string   `xml:"Id,attr"`
+	Level         string   `xml:"Level,attr"`
+	ComponentRefs []wixRef `xml:"ComponentRef"`
+}
+
+type wixRef struct {
+	ID string `xml:"Id,attr"`
+}
+
+// generateWxs generates a WiX source file installing the binaries, extra
+// files, shortcuts, file associations, service and PATH entry
+func (b *MSIBuilder) generateWxs(path string, binaries []artifact.Artifact, name, version string) error {
+	goarch := binaries[0].Goarch
+	platform := msiPlatforms[goarch]
+
+	productVersion, err := msiVersion(version)
+	if err != nil {
+		return err
+	}
+
+	manufacturer := b.config.Manufacturer
+	if manufacturer == "" {
+		manufacturer = "Unknown"
+	}
+	productName := b.config.ProductName
+	if productName == "" {
+		productName = name
+	}
+
+	// Upgrades find the installed product by its upgrade code, so it must
+	// stay the same across releases; it differs per architecture so the
+	// x86 and x64 installers can be installed side by side
+	identifier := b.config.Identifier
+	if identifier == "" {
+		identifier = manufacturer + "." + name
+	}
+	upgradeCode := b.config.UpgradeCode
+	if upgradeCode == "" {
+		upgradeCode = msiGUID(identifier, platform, "upgrade")
+	}
+
+	programFiles := "ProgramFilesFolder"
+	if platform != "x86" {
+		programFiles = "ProgramFiles64Folder"
+	}
+	installDir := &wixDirectory{ID: "INSTALLDIR", Name: b.installDirName(name)}
+	targetDir := wixDirectory{
+		ID:   "TARGETDIR",
+		Name: "SourceDir",
+		Directories: []*wixDirectory{
+			{ID: programFiles, Directories: []*wixDirectory{installDir}},
+		},
+	}
+
+	code, lang, err := b.language()
+	if err != nil {
+		return err
+	}
+
+	product := wixProduct{
+		ID:           "*",
+		Name:         productName,
+		Language:     strconv.Itoa(lang.LCID),
+		Codepage:     strconv.Itoa(lang.Codepage),
+		Version:      productVersion,
+		Manufacturer: manufacturer,
+		UpgradeCode:  upgradeCode,
+		Package: wixPackage{
+			InstallerVersion: "500",
+			Compressed:       "yes",
+			InstallScope:     "perMachine",
+			Platform:         platform,
+			Languages:        strconv.Itoa(lang.LCID),
+			SummaryCodepage:  strconv.Itoa(lang.Codepage),
+		},
+		MajorUpgrade: wixMajorUpgrade{
+			DowngradeErrorMessage: "A newer version of [ProductName] is already installed.",
+		},
+		MediaTemplate: wixMedia{EmbedCab: "yes"},
+		Feature:       wixFeature{ID: "Complete", Level: "1"},
+	}
+
+	gui := b.guis[binaries[0].BuildID]
+	var windows *config.GUIWindows
+	if gui != nil {
+		windows = gui.Windows
+	}
+
+	// The strings shown to users, in the installer's language
+	untranslated := config.Localization{Name: productName}
+	if gui != nil {
+		untranslated.Comment = gui.Comment
+	}
+	strs := localize(b.localizations, code, untranslated)
+	product.Name = strs.Name
+	if strs.Description != "" {
+		product.Package.Description = strs.Description
+		product.Properties = append(product.Properties, wixProperty{ID: "ARPCOMMENTS", Value: strs.Description})
+	}
+
+	iconPath := b.config.Icon
+	if iconPath == "" && windows != nil {
+		iconPath = windows.Icon
+	}
+	iconID := ""
+	if iconPath != "" {
+		iconID = "ProductIcon.ico"
+		product.Icon = &wixIcon{ID: iconID, SourceFile: iconPath}
+		product.Properties = append(product.Properties, wixProperty{ID: "ARPPRODUCTICON", Value: iconID})
+	}
+
+	addComponent := func(dir *wixDirectory, component *wixComponent) {
+		dir.Components = append(dir.Components, component)
+		product.Feature.ComponentRefs = append(product.Feature.ComponentRefs, wixRef{ID: component.ID})
+	}
+
+	// One component per binary, keyed by the executable
+	fileIDs := make(map[string]string)
+	var mainComponent *wixComponent
+	for i, binary := range binaries {
+		fileID := fmt.Sprintf("exe%d", i)
+		fileIDs[binary.Name] = fileID
+		component := &wixComponent{
+			ID:    fmt.Sprintf("Executable%d", i),
+			GUID:  "*",
+			Files: []wixFile{{ID: fileID, Source: binary.Path, KeyPath: "yes"}},
+		}
+		if i == 0 {
+			mainComponent = component
+		}
+		addComponent(installDir, component)
+	}
+	mainExe := filepath.Base(binaries[0].Path)
+
+	if windows != nil {
+		for i, assoc := range windows.FileAssociations {
+			ext := strings.TrimPrefix(assoc.Extension, ".")
+			icon := fileIDs[binaries[0].Name]
+			if assoc.Icon != "" {
+				icon = fmt.Sprintf("AssocIcon%d", i)
+				mainComponent.Files = append(mainComponent.Files, wixFile{ID: icon, Source: assoc.Icon})
+			}
+			mainComponent.ProgIDs = append(mainComponent.ProgIDs, wixProgID{
+				ID:          identifier + "." + ext,
+				Description: assoc.Description,
+				Icon:        icon,
+				Extension: wixExtension{
+					ID: ext,
+					Verb: wixVerb{
+						ID:         "open",
+						Command:    "Open",
+						TargetFile: fileIDs[binaries[0].Name],
+						Argument:   `"%1"`,
+					},
+				},
+			})
+		}
+	}
+
+	if service := b.config.Service; service != nil {
+		component := mainComponent
+		if service.Binary != "" {
+			component = nil
+			for i, binary := range binaries {
+				if binary.Name == service.Binary || strings.TrimSuffix(binary.Name, ".exe") == service.Binary {
+					component = installDir.Components[i]
+				}
+			}
+			if component == nil {
+				return fmt.Errorf("service binary %s is not one of the installed binaries", service.Binary)
+			}
+		}
+		start := service.Start
+		if start == "" {
+			start = "auto"
+		}
+		component.ServiceInstall = &wixServiceInstall{
+			ID:           "ServiceInstall",
+			Name:         service.Name,
+			DisplayName:  service.DisplayName,
+			Description:  service.Description,
+			Type:         "ownProcess",
+			Start:        start,
+			ErrorControl: "normal",
+			Account:      service.Account,
+			Arguments:    service.Arguments,
+		}
+		component.ServiceControl = &wixServiceControl{
+			ID:     "ServiceControl",
+			Name:   service.Name,
+			Start:  "install",
+			Stop:   "both",
+			Remove: "uninstall",
+			Wait:   "yes",
+		}
+	}
+
+	if b.config.AddToPath {
+		mainComponent.Environment = &wixEnvironment{
+			ID:        "PATH",
+			Name:      "PATH",
+			Value:     "[INSTALLDIR]",
+			Permanent: "no",
+			Part:      "last",
+			Action:    "set",
+			System:    "yes",
+		}
+	}
+
+	if err := b.addExtraFiles(installDir, addComponent); err != nil {
+		return err
+	}
+
+	// Shortcuts: configured ones, or the main executable's for gui builds
+	shortcuts := b.config.Shortcuts
+	if len(shortcuts) == 0 && gui != nil {
+		shortcut := config.MSIShortcut{
+			Name:        strs.Name,
+			Description: strs.Comment,
+			Target:      mainExe,
+			StartMenu:   true,
+		}
+		if windows != nil {
+			shortcut.Desktop = windows.DesktopShortcut
+		}
+		shortcuts = []config.MSIShortcut{shortcut}
+	}
+	registryKey := fmt.Sprintf(`Software\%s\%s`, manufacturer, productName)
+	var startMenu, desktop []wixShortcut
+	for i, s := range shortcuts {
+		target := s.Target
+		if target == "" {
+			target = mainExe
+		}
+		shortcut := wixShortcut{
+			Name:             s.Name,
+			Description:      s.Description,
+			Target:           "[INSTALLDIR]" + strings.ReplaceAll(target, "/", `\`),
+			Arguments:        s.Arguments,
+			WorkingDirectory: "INSTALLDIR",
+			Icon:             iconID,
+		}
+		if s.StartMenu || !s.Desktop {
+			shortcut.ID = fmt.Sprintf("StartMenuShortcut%d", i)
+			startMenu = append(startMenu, shortcut)
+		}
+		if s.Desktop {
+			shortcut.ID = fmt.Sprintf("DesktopShortcut%d", i)
+			desktop = append(desktop, shortcut)
+		}
+	}
+	if len(startMenu) > 0 {
+		folder := strs.Name
+		if windows != nil && windows.StartMenuFolder != "" {
+			folder = windows.StartMenuFolder
+		}
+		programs := &wixDirectory{ID: "ApplicationProgramsFolder", Name: folder}
+		targetDir.Directories = append(targetDir.Directories, &wixDirectory{
+			ID:          "ProgramMenuFolder",
+			Directories: []*wixDirectory{programs},
+		})
+		addComponent(programs, shortcutComponent("StartMenuShortcuts", msiGUID(identifier, platform, "start-menu"), registryKey, startMenu, true))
+	}
+	if len(desktop) > 0 {
+		desktopDir := &wixDirectory{ID: "DesktopFolder", Name: "Desktop"}
+		targetDir.Directories = append(targetDir.Directories, desktopDir)
+		addComponent(desktopDir, shortcutComponent("DesktopShortcuts", msiGUID(identifier, platform, "desktop"), registryKey, desktop, false))
+	}
+
+	product.Directory = targetDir
+
+	return manifest.WriteFile(path, wixDocument{Product: product})
+}
+
+// shortcutComponent groups shortcuts in a component keyed by a per-user
+// registry value, as shortcuts cannot be key paths themselves
+func shortcutComponent(id, guid, registryKey string, shortcuts []wixShortcut, removeFolder bool) *wixComponent {
+	component := &wixComponent{
+		ID:        id,
+		GUID:      guid,
+		Shortcuts: shortcuts,
+		RegistryValue: &wixRegistryValue{
+			Root:    "HKCU",
+			Key:     registryKey,
+			Name:    id,
+			Type:    "integer",
+			Value:   "1",
+			KeyPath: "yes",
+		},
+	}
+	if removeFolder {
+		component.RemoveFolder = &wixRemoveFolder{ID: "Remove" + id, On: "uninstall"}
+	}
+	return component
+}
+
+// addExtraFiles adds the extra files, and the files of extra directories,
+// below the install directory with one component per file
+func (b *MSIBuilder) addExtraFiles(installDir *wixDirectory, addComponent func(*wixDirectory, *wixComponent)) error {
+	dirs := 0
+	files := 0
+	add := func(src, dst string) {
+		dir := installDir
+		parts := strings.Split(filepath.ToSlash(dst), "/")
+		for _, part := range parts[:len(parts)-1] {
+			var next *wixDirectory
+			for _, d := range dir.Directories {
+				if d.Name == part {
+					next = d
+				}
+			}
+			if next == nil {
+				dirs++
+				next = &wixDirectory{ID: fmt.Sprintf("ExtraDir%d", dirs), Name: part}
+				dir.Directories = append(dir.Directories, next)
+			}
+			dir = next
+		}
+		files++
+		addComponent(dir, &wixComponent{
+			ID:    fmt.Sprintf("ExtraFile%d", files),
+			GUID:  "*",
+			Files: []wixFile{{ID: fmt.Sprintf("extra%d", files), Source: src, KeyPath: "yes"}},
+		})
+	}
+
+	for _, file := range b.config.ExtraFiles {
+		dst := extraFileDst(file.Src, file.Dst)
+		info, err := os.Stat(file.Src)
+		if err != nil {
+			return fmt.Errorf("failed to read extra file: %w", err)
+		}
+		if !info.IsDir() {
+			add(file.Src, dst)
+			continue
+		}
+		err = filepath.Walk(file.Src, func(path string, info os.FileInfo, err error) error {
+			if err != nil || info.IsDir() {
+				return err
+			}
+			rel, err := filepath.Rel(file.Src, path)
+			if err != nil {
+				return err
+			}
+			add(path, filepath.Join(dst, rel))
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// installDirName returns the directory name below Program Files
+func (b *MSIBuilder) installDirName(name string) string {
+	if b.config.InstallDir != "" {
+		return b.config.InstallDir
+	}
+	return name
+}
+
+// extraFileDst returns where an extra file goes below the install directory
+func extraFileDst(src, dst string) string {
+	if dst == "" {
+		return filepath.Base(src)
+	}
+	return dst
+}
+
+// msiVersion turns a version into the major.minor.build form MSI accepts,
+// dropping the "v" prefix and prerelease and build metadata
+func msiVersion(version string) (string, error) {
+	v := strings.TrimPrefix(version, "v")
+	if i := strings.IndexAny(v, "-+"); i >= 0 {
+		v = v[:i]
+	}
+	parts := strings.Split(v, ".")
+	if len(parts) > 3 {
+		parts = parts[:3]
+	}
+	for i, limit := range []int{255, 255, 65535} {
+		if i >= len(parts) {
+			break
+		}
+		n, err := strconv.Atoi(parts[i])
+		if err != nil || n < 0 || n > limit {
+			return "", fmt.Errorf("version %s cannot be used for an MSI, which needs major.minor.build with major and minor up to 255 and build up to 65535", version)
+		}
+	}
+	return strings.Join(parts, "."), nil
+}
+
+// msiGUID derives a stable name based (version 5) GUID from the parts
+func msiGUID(parts ...string) string {
+	h := sha1.New()
+	h.Write(msiGUIDNamespace)
+	h.Write([]byte(strings.Join(parts, "/")))
+	sum := h.Sum(nil)
+	sum[6] = (sum[6] & 0x0f) | 0x50
+	sum[8] = (sum[8] & 0x3f) | 0x80
+	return strings.ToUpper(fmt.Sprintf("%x-%x-%x-%x-%x", sum[0:4], sum[4:6], sum[6:8], sum[8:10], sum[10:16]))
+}
+
+// runWixl runs wixl to create MSI.
+func (b *MSIBuilder) runWixl(ctx context.Context, wxsPath, msiPath, platform string) error {
+	cmd := exec.CommandContext(ctx, "wixl", "-a", platform, "-o", msiPath, wxsPath)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	return cmd.Run()
+}
+
+// runWix runs WiX Toolset to create MSI.
+func (b *MSIBuilder) runWix(ctx context.Context, wxsPath, msiPath, platform string) error {
+	wixobjPath := wxsPath + ".wixobj"
+
+	// Compile
+	candle := exec.CommandContext(ctx, "candle", "-arch", platform, "-o", wixobjPath, wxsPath)
+	candle.Stdout = os.Stdout
+	candle.Stderr = os.Stderr
+	if err := candle.Run(); err != nil {
+		return fmt.Errorf("candle failed: %w", err)
+	}
+
+	// Link, with the built-in strings of WiX in the installer's language
+	args := []string{"-o", msiPath}
+	if _, lang, err := b.language(); err == nil {
+		args = append(args, "-cultures:"+lang.Culture)
+	}
+	light := exec.CommandContext(ctx, "light", append(args, wixobjPath)...)
+	light.Stdout = os.Stdout
+	light.Stderr = os.Stderr
+	if err := light.Run(); err != nil {
+		return fmt.Errorf("light failed: %w", err)
+	}
+
+	return nil
+}
+
+// BuildAllMSIs builds MSIs for all configurations.
+func BuildAllMSIs(ctx context.Context, cfg *config.Config, tmplCtx *tmpl.Context, manager *artifact.Manager, distDir string) error {
+	guis := make(map[string]*config.GUIConfig)
+	for _, build := range cfg.Builds {
+		if build.GUI != nil {
+			guis[build.ID] = build.GUI
+		}
+	}
+
+	for i, msiCfg := range cfg.MSIs {
+		log.Info("Building MSI", "index", i+1, "total", len(cfg.MSIs))
+		builder := NewMSIBuilder(msiCfg, tmplCtx, manager, distDir).WithGUIs(guis).WithLocalizations(cfg.Localizations)
+		if err := builder.Build(ctx); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,531 @@
+package packaging
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+
+	"github.com/charmbracelet/log"
+	"github.com/oarkflow/releaser/internal/artifact"
+	"github.com/oarkflow/releaser/internal/config"
+	"github.com/oarkflow/releaser/internal/manifest"
+	"github.com/oarkflow/releaser/internal/tmpl"
+)
+
+// nsisTemplate is the generated installer script. Strings are escaped with
+// esc; $INSTDIR and the other NSIS variables are written outside of it.
+const nsisTemplate = `Unicode true
+!include "MUI2.nsh"
+
+Name "$(ProductName)"
+OutFile "{{ esc .OutputPath }}"
+{{- if .PerUser }}
+InstallDir "$LOCALAPPDATA\Programs\{{ esc .InstallDir }}"
+RequestExecutionLevel user
+{{- else }}
+InstallDir "{{ if .Is64 }}$PROGRAMFILES64{{ else }}$PROGRAMFILES{{ end }}\{{ esc .InstallDir }}"
+RequestExecutionLevel admin
+{{- end }}
+InstallDirRegKey SHCTX "{{ esc .UninstallKey }}" "InstallLocation"
+{{- if .Icon }}
+!define MUI_ICON "{{ esc .Icon }}"
+!define MUI_UNICON "{{ esc .Icon }}"
+{{- end }}
+
+VIProductVersion "{{ .FileVersion }}"
+{{- range .Languages }}
+VIAddVersionKey /LANG={{ .LCID }} "ProductName" "{{ esc .Name }}"
+VIAddVersionKey /LANG={{ .LCID }} "ProductVersion" "{{ esc $.Version }}"
+VIAddVersionKey /LANG={{ .LCID }} "FileVersion" "{{ $.FileVersion }}"
+VIAddVersionKey /LANG={{ .LCID }} "FileDescription" "{{ esc .Description }}"
+{{- if $.Publisher }}
+VIAddVersionKey /LANG={{ .LCID }} "CompanyName" "{{ esc $.Publisher }}"
+{{- end }}
+{{- end }}
+{{- if .Multilingual }}
+
+; The language picked at install time is used by the uninstaller too
+!define MUI_LANGDLL_REGISTRY_ROOT SHCTX
+!define MUI_LANGDLL_REGISTRY_KEY "{{ esc .UninstallKey }}"
+!define MUI_LANGDLL_REGISTRY_VALUENAME "InstallerLanguage"
+{{- end }}
+
+!insertmacro MUI_PAGE_WELCOME
+{{- if .LicenseFile }}
+!insertmacro MUI_PAGE_LICENSE "{{ esc .LicenseFile }}"
+{{- end }}
+!insertmacro MUI_PAGE_DIRECTORY
+!insertmacro MUI_PAGE_INSTFILES
+!insertmacro MUI_PAGE_FINISH
+
+!insertmacro MUI_UNPAGE_CONFIRM
+!insertmacro MUI_UNPAGE_INSTFILES
+
+; The first language is the default one
+{{- range .Languages }}
+!insertmacro MUI_LANGUAGE "{{ .NSIS }}"
+{{- end }}
+{{- if .Multilingual }}
+!insertmacro MUI_RESERVEFILE_LANGDLL
+{{- end }}
+{{ range .Languages }}
+LangString ProductName {{ .LCID }} "{{ esc .Name }}"
+{{- end }}
+
+!macro InitScope
+{{- if .PerUser }}
+  SetShellVarContext current
+{{- else }}
+  UserInfo::GetAccountType
+  Pop $0
+  StrCmp $0 "Admin" +3
+    MessageBox MB_ICONSTOP "Administrator rights are required to install {{ esc .Name }}." /SD IDOK
+    Abort
+  SetShellVarContext all
+{{- end }}
+{{- if .Is64 }}
+  SetRegView 64
+{{- end }}
+!macroend
+
+Function .onInit
+  !insertmacro InitScope
+{{- if .Multilingual }}
+  IfSilent language_done
+  !insertmacro MUI_LANGDLL_DISPLAY
+  language_done:
+{{- end }}
+FunctionEnd
+
+Function un.onInit
+  !insertmacro InitScope
+{{- if .Multilingual }}
+  !insertmacro MUI_UNGETLANGUAGE
+{{- end }}
+FunctionEnd
+
+Section "Install"
+  SetOutPath "$INSTDIR"
+{{- range .Dirs }}
+  CreateDirectory "$INSTDIR\{{ esc . }}"
+{{- end }}
+{{- range .Files }}
+  File "/oname=$INSTDIR\{{ esc .Dst }}" "{{ esc .Src }}"
+{{- end }}
+  WriteUninstaller "$INSTDIR\Uninstall.exe"
+
+  CreateDirectory "$SMPROGRAMS\{{ esc .Name }}"
+  CreateShortcut "$SMPROGRAMS\{{ esc .Name }}\{{ esc .Name }}.lnk" "$INSTDIR\{{ esc .MainExe }}"
+  CreateShortcut "$SMPROGRAMS\{{ esc .Name }}\Uninstall.lnk" "$INSTDIR\Uninstall.exe"
+
+  WriteRegStr SHCTX "{{ esc .UninstallKey }}" "DisplayName" "$(ProductName)"
+  WriteRegStr SHCTX "{{ esc .UninstallKey }}" "DisplayVersion" "{{ esc .Version }}"
+{{- if .Publisher }}
+  WriteRegStr SHCTX "{{ esc .UninstallKey }}" "Publisher" "{{ esc .Publisher }}"
+{{- end }}
+  WriteRegStr SHCTX "{{ esc .UninstallKey }}" "DisplayIcon" "$INSTDIR\{{ esc .MainExe }}"
+  WriteRegStr SHCTX "{{ esc .UninstallKey }}" "InstallLocation" "$INSTDIR"
+  WriteRegStr SHCTX "{{ esc .UninstallKey }}" "UninstallString" '"$INSTDIR\Uninstall.exe"'
+  WriteRegStr SHCTX "{{ esc .UninstallKey }}" "QuietUninstallString" '"$INSTDIR\Uninstall.exe" /S'
+  WriteRegDWORD SHCTX "{{ esc .UninstallKey }}" "EstimatedSize" {{ .EstimatedSize }}
+  WriteRegDWORD SHCTX "{{ esc .UninstallKey }}" "NoModify" 1
+  WriteRegDWORD SHCTX "{{ esc .UninstallKey }}" "NoRepair" 1
+SectionEnd
+
+Section "Uninstall"
+{{- range .Files }}
+  Delete "$INSTDIR\{{ esc .Dst }}"
+{{- end }}
+  Delete "$INSTDIR\Uninstall.exe"
+{{- range .RemoveDirs }}
+  RMDir "$INSTDIR\{{ esc . }}"
+{{- end }}
+  RMDir "$INSTDIR"
+  Delete "$SMPROGRAMS\{{ esc .Name }}\{{ esc .Name }}.lnk"
+  Delete "$SMPROGRAMS\{{ esc .Name }}\Uninstall.lnk"
+  RMDir "$SMPROGRAMS\{{ esc .Name }}"
+  DeleteRegKey SHCTX "{{ esc .UninstallKey }}"
+SectionEnd
+`
+
+// nsisFile is a file the installer writes, Dst being relative to $INSTDIR
+type nsisFile struct {
+	Src string
+	Dst string
+}
+
+// NSISBuilder creates Windows NSIS installers.
+type NSISBuilder struct {
+	config        config.NSIS
+	localizations map[string]config.Localization
+	tmplCtx       *tmpl.Context
+	manager       *artifact.Manager
+	distDir       string
+}
+
+// NewNSISBuilder creates a new NSIS builder.
+func NewNSISBuilder(cfg config.NSIS, tmplCtx *tmpl.Context, manager *artifact.Manager, distDir string) *NSISBuilder {
+	return &NSISBuilder{
+		config:  cfg,
+		tmplCtx: tmplCtx,
+		manager: manager,
+		distDir: distDir,
+	}
+}
+
+// WithLocalizations sets the translations of the installer's name and
+// description; the installer asks which language to install in
+func (b *NSISBuilder) WithLocalizations(localizations map[string]config.Localization) *NSISBuilder {
+	b.localizations = localizations
+	return b
+}
+
+// Build creates an NSIS installer per architecture with all the selected
+// Windows binaries and the extra files; the first binary is the one the
+// Start Menu shortcut opens.
+func (b *NSISBuilder) Build(ctx context.Context) error {
+	log.Info("Building NSIS installer")
+
+	switch b.config.InstallScope {
+	case "", "perMachine", "perUser":
+	default:
+		return fmt.Errorf("invalid nsis install_scope %q, valid values are: perMachine, perUser", b.config.InstallScope)
+	}
+
+	byArch := make(map[string][]artifact.Artifact)
+	for _, a := range b.manager.Filter(func(a artifact.Artifact) bool {
+		return a.Type == artifact.TypeBinary && a.Goos == "windows" && (b.config.Build == "" || a.BuildID == b.config.Build)
+	}) {
+		byArch[a.Goarch] = append(byArch[a.Goarch], a)
+	}
+
+	if len(byArch) == 0 {
+		log.Debug("No Windows binaries found for NSIS, skipping")
+		return nil
+	}
+
+	// Check if makensis is available
+	hasMakensis := false
+	if _, err := exec.LookPath("makensis"); err == nil {
+		hasMakensis = true
+	}
+
+	goarchs := make([]string, 0, len(byArch))
+	for goarch := range byArch {
+		goarchs = append(goarchs, goarch)
+	}
+	sort.Strings(goarchs)
+
+	for _, goarch := range goarchs {
+		binaries := byArch[goarch]
+		if hasMakensis {
+			if err := b.createNSIS(ctx, binaries); err != nil {
+				return fmt.Errorf("failed to create NSIS installer for %s: %w", goarch, err)
+			}
+		} else {
+			// Generate NSI script for later use
+			if err := b.createNsiScript(binaries); err != nil {
+				return fmt.Errorf("failed to create NSIS script for %s: %w", goarch, err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// paths returns the product name, version, installer and script paths
+func (b *NSISBuilder) paths(goarch string) (name, version, exeFileName, exePath, nsiPath string) {
+	name = b.config.Name
+	if name == "" {
+		name = b.tmplCtx.Get("ProjectName")
+	}
+	version = b.tmplCtx.Get("Version")
+	exeFileName = fmt.Sprintf("%s_%s_%s_setup.exe", name, version, goarch)
+	exePath = filepath.Join(b.distDir, exeFileName)
+	nsiPath = filepath.Join(b.distDir, fmt.Sprintf("%s_%s.nsi", name, goarch))
+	return name, version, exeFileName, exePath, nsiPath
+}
+
+// createNsiScript creates an NSI script when makensis isn't available
+func (b *NSISBuilder) createNsiScript(binaries []artifact.Artifact) error {
+	name, version, _, exePath, nsiPath := b.paths(binaries[0].Goarch)
+
+	if err := b.generateNsi(nsiPath, binaries, name, version, exePath); err != nil {
+		return fmt.Errorf("failed to generate NSIS script: %w", err)
+	}
+
+	log.Info("NSIS script created (compile with makensis on Windows)", "path", nsiPath)
+	return nil
+}
+
+// createNSIS creates an NSIS installer.
+func (b *NSISBuilder) createNSIS(ctx context.Context, binaries []artifact.Artifact) error {
+	goarch := binaries[0].Goarch
+	name, version, exeFileName, exePath, nsiPath := b.paths(goarch)
+
+	// Check if custom script is provided
+	if b.config.Script != "" {
+		nsiPath = b.config.Script
+	} else {
+		if err := b.generateNsi(nsiPath, binaries, name, version, exePath); err != nil {
+			return fmt.Errorf("failed to generate NSIS script: %w", err)
+		}
+	}
+
+	// Run makensis; custom scripts get the version, architecture and
+	// output file as defines
+	outFile, err := filepath.Abs(exePath)
+	if err != nil {
+		return err
+	}
+	args := []string{
+		"-DVERSION=" + version,
+		"-DARCH=" + goarch,
+		"-DOUTFILE=" + outFile,
+	}
+
+	// Add defines
+	keys := make([]string, 0, len(b.config.Defines))
+	for key := range b.config.Defines {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		args = append(args, fmt.Sprintf("-D%s=%s", key, b.config.Defines[key]))
+	}
+
+	args = append(args, nsiPath)
+
+	cmd := exec.CommandContext(ctx, "makensis", args...)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("makensis failed: %w", err)
+	}
+
+	// Add artifact
+	if err := b.manager.Add(artifact.Artifact{
+		Name:    exeFileName,
+		Path:    exePath,
+		Type:    artifact.TypeNSIS,
+		Goos:    "windows",
+		Goarch:  goarch,
+		BuildID: binaries[0].BuildID,
+	}); err != nil {
+		return err
+	}
+
+	log.Info("NSIS installer created", "name", exeFileName)
+	return nil
+}
+
+// generateNsi generates an NSIS script installing the binaries and extra
+// files, with an uninstaller registered in Apps & Features.
+func (b *NSISBuilder) generateNsi(nsiPath string, binaries []artifact.Artifact, name, version, outputPath string) error {
+	files, size, err := b.files(binaries)
+	if err != nil {
+		return err
+	}
+
+	// Directories are created parents first and removed children first
+	dirSet := make(map[string]bool)
+	for _, file := range files {
+		for dir := path.Dir(strings.ReplaceAll(file.Dst, `\`, "/")); dir != "."; dir = path.Dir(dir) {
+			dirSet[strings.ReplaceAll(dir, "/", `\`)] = true
+		}
+	}
+	dirs := make([]string, 0, len(dirSet))
+	for dir := range dirSet {
+		dirs = append(dirs, dir)
+	}
+	sort.Strings(dirs)
+	removeDirs := make([]string, len(dirs))
+	for i, dir := range dirs {
+		removeDirs[len(dirs)-1-i] = dir
+	}
+
+	absPath := func(p string) (string, error) {
+		if p == "" {
+			return "", nil
+		}
+		return filepath.Abs(p)
+	}
+	outputPath, err = absPath(outputPath)
+	if err != nil {
+		return err
+	}
+	licenseFile, err := absPath(b.config.LicenseFile)
+	if err != nil {
+		return err
+	}
+	icon, err := absPath(b.config.Icon)
+	if err != nil {
+		return err
+	}
+
+	goarch := binaries[0].Goarch
+	languages := installerLanguages(b.localizations, config.Localization{
+		Name:        name,
+		Description: name + " installer",
+	})
+	return manifest.WriteFile(nsiPath, nsisScript{
+		Name:          name,
+		Languages:     languages,
+		Multilingual:  len(languages) > 1,
+		Version:       version,
+		FileVersion:   fileVersion(version),
+		Publisher:     b.config.Publisher,
+		OutputPath:    outputPath,
+		InstallDir:    name,
+		MainExe:       filepath.Base(binaries[0].Path),
+		LicenseFile:   licenseFile,
+		Icon:          icon,
+		PerUser:       b.config.InstallScope == "perUser",
+		Is64:          goarch == "amd64" || goarch == "arm64",
+		UninstallKey:  `Software\Microsoft\Windows\CurrentVersion\Uninstall\` + name,
+		Files:         files,
+		Dirs:          dirs,
+		RemoveDirs:    removeDirs,
+		EstimatedSize: size / 1024,
+	})
+}
+
+// nsisScript is a generated NSIS installer script
+type nsisScript struct {
+	Name         string
+	Languages    []installerLanguage
+	Multilingual bool
+	Version      string
+	// FileVersion is the version in the four numbers VIProductVersion takes
+	FileVersion string
+	Publisher   string
+	// OutputPath, LicenseFile and Icon are absolute paths
+	OutputPath   string
+	InstallDir   string
+	MainExe      string
+	LicenseFile  string
+	Icon         string
+	PerUser      bool
+	Is64         bool
+	UninstallKey string
+	Files        []nsisFile
+	// Dirs are created parents first and RemoveDirs removed children first
+	Dirs       []string
+	RemoveDirs []string
+	// EstimatedSize is in KiB
+	EstimatedSize int64
+}
+
+// Generate renders the script from nsisTemplate
+func (s nsisScript) Generate() ([]byte, error) {
+	nsiTmpl, err := template.New("nsi").Funcs(template.FuncMap{"esc": nsisEscape}).Parse(nsisTemplate)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	if err := nsiTmpl.Execute(&buf, s); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// files lists the binaries and the extra files, directories expanded, with
+// absolute sources and their total size
+func (b *NSISBuilder) files(binaries []artifact.Artifact) ([]nsisFile, int64, error) {
+	var files []nsisFile
+	var size int64
+	add := func(src, dst string) error {
+		abs, err := filepath.Abs(src)
+		if err != nil {
+			return err
+		}
+		info, err := os.Stat(abs)
+		if err != nil {
+			return err
+		}
+		size += info.Size()
+		files = append(files, nsisFile{Src: abs, Dst: strings.ReplaceAll(filepath.ToSlash(dst), "/", `\`)})
+		return nil
+	}
+
+	for _, binary := range binaries {
+		if err := add(binary.Path, filepath.Base(binary.Path)); err != nil {
+			return nil, 0, err
+		}
+	}
+	for _, file := range b.config.ExtraFiles {
+		dst := extraFileDst(file.Src, file.Dst)
+		info, err := os.Stat(file.Src)
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to read extra file: %w", err)
+		}
+		if !info.IsDir() {
+			if err := add(file.Src, dst); err != nil {
+				return nil, 0, err
+			}
+			continue
+		}
+		err = filepath.Walk(file.Src, func(src string, info os.FileInfo, err error) error {
+			if err != nil || info.IsDir() {
+				return err
+			}
+			rel, err := filepath.Rel(file.Src, src)
+			if err != nil {
+				return err
+			}
+			return add(src, filepath.Join(dst, rel))
+		})
+		if err != nil {
+			return nil, 0, err
+		}
+	}
+	return files, size, nil
+}
+
+// nsisEscape escapes a value for a double quoted NSIS string
+func nsisEscape(s string) string {
+	s = strings.ReplaceAll(s, "$", "$$")
+	return strings.ReplaceAll(s, `"`, `$\"`)
+}
+
+// fileVersion turns a version into the four part numeric form of Windows
+// version resources, dropping prerelease and build metadata
+func fileVersion(version string) string {
+	v := strings.TrimPrefix(version, "v")
+	if i := strings.IndexAny(v, "-+"); i >= 0 {
+		v = v[:i]
+	}
+	parts := strings.Split(v, ".")
+	numbers := make([]string, 4)
+	for i := range numbers {
+		numbers[i] = "0"
+		if i < len(parts) {
+			if n, err := strconv.Atoi(parts[i]); err == nil && n >= 0 && n <= 65535 {
+				numbers[i] = strconv.Itoa(n)
+			}
+		}
+	}
+	return strings.Join(numbers, ".")
+}
+
+// BuildAllNSIS builds NSIS installers for all configurations.
+func BuildAllNSIS(ctx context.Context, cfg *config.Config, tmplCtx *tmpl.Context, manager *artifact.Manager, distDir string) error {
+	for i, nsisCfg := range cfg.NSISs {
+		log.Info("Building NSIS", "index", i+1, "total", len(cfg.NSISs))
+		builder := NewNSISBuilder(nsisCfg, tmplCtx, manager, distDir).WithLocalizations(cfg.Localizations)
+		if err := builder.Build(ctx); err != nil {
+			return err
+		}
+	}
+	return nil
+}
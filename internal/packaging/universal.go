@@ -0,0 +1,276 @@
+package packaging
+
+import (
+	"context"
+	"debug/macho"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/charmbracelet/log"
+	"github.com/oarkflow/releaser/internal/artifact"
+	"github.com/oarkflow/releaser/internal/config"
+	"github.com/oarkflow/releaser/internal/tmpl"
+)
+
+// fatAlign is the log2 alignment of the slices of a universal binary, the
+// 16KiB arm64 page size lipo uses
+const fatAlign = 14
+
+// UniversalBinaryBuilder creates macOS universal binaries.
+type UniversalBinaryBuilder struct {
+	config  config.UniversalBinary
+	tmplCtx *tmpl.Context
+	manager *artifact.Manager
+	distDir string
+}
+
+// NewUniversalBinaryBuilder creates a new universal binary builder.
+func NewUniversalBinaryBuilder(cfg config.UniversalBinary, tmplCtx *tmpl.Context, manager *artifact.Manager, distDir string) *UniversalBinaryBuilder {
+	return &UniversalBinaryBuilder{
+		config:  cfg,
+		tmplCtx: tmplCtx,
+		manager: manager,
+		distDir: distDir,
+	}
+}
+
+// Build merges the darwin binaries of each build into a universal binary.
+// The Mach-O slices are combined directly, so lipo isn't needed.
+func (b *UniversalBinaryBuilder) Build(ctx context.Context) error {
+	log.Info("Building macOS universal binaries")
+
+	// Group binaries by build and name
+	groups := make(map[string][]artifact.Artifact)
+	for _, bin := range b.manager.Filter(func(a artifact.Artifact) bool {
+		return a.Type == artifact.TypeBinary && a.Goos == "darwin" && b.selected(a.BuildID)
+	}) {
+		key := bin.BuildID + "/" + bin.Name
+		groups[key] = append(groups[key], bin)
+	}
+
+	if len(groups) == 0 {
+		log.Debug("No darwin binaries found for universal binary creation, skipping")
+		return nil
+	}
+
+	keys := make([]string, 0, len(groups))
+	for key := range groups {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		binaries := groups[key]
+		if len(binaries) < 2 {
+			log.Debug("Skipping universal binary: need more than one architecture", "name", binaries[0].Name)
+			continue
+		}
+		binaries = b.perCPU(binaries)
+		if len(binaries) < 2 {
+			log.Debug("Skipping universal binary: need more than one architecture", "name", binaries[0].Name)
+			continue
+		}
+
+		if err := b.createUniversalBinary(binaries); err != nil {
+			return fmt.Errorf("failed to create universal binary for %s: %w", binaries[0].Name, err)
+		}
+	}
+
+	return nil
+}
+
+// selected reports whether the binaries of a build are merged
+func (b *UniversalBinaryBuilder) selected(buildID string) bool {
+	if len(b.config.IDs) == 0 {
+		return true
+	}
+	for _, id := range b.config.IDs {
+		if id == buildID {
+			return true
+		}
+	}
+	return false
+}
+
+// perCPU returns one binary per architecture, sorted by architecture. A
+// universal binary holds a single amd64 slice, so of the goamd64 variants of
+// a build the configured one is taken, or the lowest, which runs on the
+// most machines.
+func (b *UniversalBinaryBuilder) perCPU(binaries []artifact.Artifact) []artifact.Artifact {
+	sort.SliceStable(binaries, func(i, j int) bool {
+		if binaries[i].Goarch != binaries[j].Goarch {
+			return binaries[i].Goarch < binaries[j].Goarch
+		}
+		return binaries[i].Goamd64 < binaries[j].Goamd64
+	})
+
+	selected := make([]artifact.Artifact, 0, len(binaries))
+	for _, bin := range binaries {
+		if bin.Goarch == "amd64" && b.config.Goamd64 != "" && bin.Goamd64 != "" && bin.Goamd64 != b.config.Goamd64 {
+			continue
+		}
+		if n := len(selected); n > 0 && selected[n-1].Goarch == bin.Goarch {
+			continue
+		}
+		selected = append(selected, bin)
+	}
+	return selected
+}
+
+// createUniversalBinary merges the binaries of one build into a single
+// universal binary.
+func (b *UniversalBinaryBuilder) createUniversalBinary(binaries []artifact.Artifact) error {
+	universal := artifact.Artifact{
+		Name:    binaries[0].Name,
+		Type:    artifact.TypeUniversalBinary,
+		Goos:    "darwin",
+		Goarch:  "universal",
+		BuildID: binaries[0].BuildID,
+	}
+
+	nameTemplate := b.config.NameTemplate
+	if nameTemplate == "" {
+		nameTemplate = "{{ .ArtifactName }}"
+	}
+	name, err := b.tmplCtx.ForArtifact(universal).Apply("universal_binaries.name_template", nameTemplate)
+	if err != nil {
+		return err
+	}
+
+	dirName := universal.BuildID
+	if dirName == "" {
+		dirName = universal.Name
+	}
+	outputDir := filepath.Join(b.distDir, dirName+"_darwin_universal")
+	if err := os.MkdirAll(outputDir, 0755); err != nil {
+		return err
+	}
+	outputPath := filepath.Join(outputDir, name)
+
+	inputs := make([]string, len(binaries))
+	for i, bin := range binaries {
+		inputs[i] = bin.Path
+	}
+	if err := mergeMachO(outputPath, inputs); err != nil {
+		return err
+	}
+
+	universal.Name = name
+	universal.Path = outputPath
+
+	// Replace the merged binaries so later stages only see the universal one
+	if b.config.Replace {
+		merged := make(map[string]bool, len(inputs))
+		for _, input := range inputs {
+			merged[input] = true
+		}
+		b.manager.Replace(func(a artifact.Artifact) bool {
+			return a.Type == artifact.TypeBinary && merged[a.Path]
+		}, universal)
+	} else {
+		if err := b.manager.Add(universal); err != nil {
+			return err
+		}
+	}
+
+	log.Info("Universal binary created", "name", name, "path", outputPath)
+	return nil
+}
+
+// fatSlice is one architecture of a universal binary
+type fatSlice struct {
+	path   string
+	cpu    macho.Cpu
+	subCpu uint32
+	size   int64
+	offset int64
+}
+
+// mergeMachO writes a universal (fat) Mach-O file made of the given thin
+// Mach-O binaries, as lipo -create does.
+func mergeMachO(output string, inputs []string) error {
+	slices := make([]fatSlice, 0, len(inputs))
+	seen := make(map[macho.Cpu]string)
+	for _, input := range inputs {
+		f, err := macho.Open(input)
+		if err != nil {
+			return fmt.Errorf("%s is not a thin Mach-O binary: %w", input, err)
+		}
+		cpu, subCpu := f.Cpu, f.SubCpu
+		f.Close()
+		if other, ok := seen[cpu]; ok {
+			return fmt.Errorf("%s and %s have the same architecture %s", other, input, cpu)
+		}
+		seen[cpu] = input
+
+		info, err := os.Stat(input)
+		if err != nil {
+			return err
+		}
+		slices = append(slices, fatSlice{path: input, cpu: cpu, subCpu: subCpu, size: info.Size()})
+	}
+
+	// The fat header and its arch table are followed by the slices, each
+	// aligned to fatAlign; the format only has 32 bit offsets
+	align := int64(1) << fatAlign
+	offset := int64(8 + 20*len(slices))
+	for i := range slices {
+		offset = (offset + align - 1) &^ (align - 1)
+		slices[i].offset = offset
+		offset += slices[i].size
+	}
+	if offset > 1<<32-1 {
+		return fmt.Errorf("universal binary is larger than 4GiB")
+	}
+
+	out, err := os.OpenFile(output, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0755)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+
+	header := []uint32{macho.MagicFat, uint32(len(slices))}
+	for _, s := range slices {
+		header = append(header, uint32(s.cpu), s.subCpu, uint32(s.offset), uint32(s.size), fatAlign)
+	}
+	if err := binary.Write(out, binary.BigEndian, header); err != nil {
+		return err
+	}
+
+	for _, s := range slices {
+		if _, err := out.Seek(s.offset, io.SeekStart); err != nil {
+			return err
+		}
+		in, err := os.Open(s.path)
+		if err != nil {
+			return err
+		}
+		_, err = io.Copy(out, in)
+		in.Close()
+		if err != nil {
+			return fmt.Errorf("failed to write %s slice: %w", s.cpu, err)
+		}
+	}
+
+	if err := out.Close(); err != nil {
+		return err
+	}
+	// Make executable whatever the umask
+	return os.Chmod(output, 0755)
+}
+
+// BuildAllUniversalBinaries builds universal binaries for all configurations.
+func BuildAllUniversalBinaries(ctx context.Context, configs []config.UniversalBinary, tmplCtx *tmpl.Context, manager *artifact.Manager, distDir string) error {
+	for i, cfg := range configs {
+		log.Info("Building universal binary", "index", i+1, "total", len(configs))
+		builder := NewUniversalBinaryBuilder(cfg, tmplCtx, manager, distDir)
+		if err := builder.Build(ctx); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,101 @@
+package packaging
+
+import (
+	"context"
+	"debug/macho"
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/oarkflow/releaser/internal/artifact"
+	"github.com/oarkflow/releaser/internal/config"
+	"github.com/oarkflow/releaser/internal/git"
+	"github.com/oarkflow/releaser/internal/tmpl"
+)
+
+// thinMachO writes a Mach-O executable header of cpu without load commands
+func thinMachO(t *testing.T, path string, cpu macho.Cpu) {
+	t.Helper()
+	header := []uint32{macho.Magic64, uint32(cpu), 0, uint32(macho.TypeExec), 0, 0, 0, 0}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if err := binary.Write(f, binary.LittleEndian, header); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestUniversalBinaryPicksOneAmd64Variant(t *testing.T) {
+	tests := []struct {
+		name    string
+		goamd64 string
+		want    string
+	}{
+		{name: "lowest by default", want: "v1"},
+		{name: "configured", goamd64: "v3", want: "v3"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dist := t.TempDir()
+			manager := artifact.NewManager()
+			add := func(arch, variant string, cpu macho.Cpu) {
+				path := filepath.Join(dist, "demo_darwin_"+arch+variant, "demo")
+				thinMachO(t, path, cpu)
+				if err := manager.Add(artifact.Artifact{
+					Name:    "demo",
+					Path:    path,
+					Type:    artifact.TypeBinary,
+					Goos:    "darwin",
+					Goarch:  arch,
+					Goamd64: variant,
+					BuildID: "demo",
+				}); err != nil {
+					t.Fatal(err)
+				}
+			}
+			add("amd64", "v3", macho.CpuAmd64)
+			add("amd64", "v1", macho.CpuAmd64)
+			add("amd64", "v2", macho.CpuAmd64)
+			add("arm64", "", macho.CpuArm64)
+
+			cfg := config.UniversalBinary{Replace: true, Goamd64: tt.goamd64}
+			tmplCtx := tmpl.New(&config.Config{ProjectName: "demo"}, &git.Info{CurrentTag: "v1.2.3"}, false, false)
+			if err := NewUniversalBinaryBuilder(cfg, tmplCtx, manager, dist).Build(context.Background()); err != nil {
+				t.Fatal(err)
+			}
+
+			fat, err := macho.OpenFat(filepath.Join(dist, "demo_darwin_universal", "demo"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer fat.Close()
+			if len(fat.Arches) != 2 || fat.Arches[0].Cpu != macho.CpuAmd64 || fat.Arches[1].Cpu != macho.CpuArm64 {
+				t.Errorf("universal binary has %d slices", len(fat.Arches))
+			}
+
+			// Only the merged variant is replaced by the universal binary
+			var amd64 []string
+			for _, a := range manager.Filter(func(a artifact.Artifact) bool { return a.Type == artifact.TypeBinary }) {
+				if a.Goarch == "arm64" {
+					t.Errorf("arm64 binary %s was not replaced", a.Path)
+				}
+				amd64 = append(amd64, a.Goamd64)
+			}
+			if len(amd64) != 2 {
+				t.Fatalf("remaining amd64 binaries = %v", amd64)
+			}
+			for _, variant := range amd64 {
+				if variant == tt.want {
+					t.Errorf("merged variant %s is still registered, remaining %v", tt.want, amd64)
+				}
+			}
+		})
+	}
+}
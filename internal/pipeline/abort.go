@@ -0,0 +1,48 @@
+package pipeline
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/charmbracelet/log"
+)
+
+// abortedMarker is written to dist when a build is interrupted. The next
+// build starts from a clean dist, and publish refuses the partial state.
+const abortedMarker = ".releaser-aborted"
+
+// stagingDirs match the directories packagers assemble in dist and remove
+// once they are done
+var stagingDirs = []string{"*.AppDir", "*_macos_pkg"}
+
+// abort records a build interrupted during stage: it drops the saved state,
+// removes staging directories left in dist and writes the aborted marker
+func (p *Pipeline) abort(stage string, cause error) error {
+	log.Warn("Build aborted, cleaning up", "stage", stage)
+
+	if err := os.Remove(filepath.Join(p.distDir, ".releaser-state.json")); err != nil && !os.IsNotExist(err) {
+		log.Warn("Failed to remove saved state", "error", err)
+	}
+	for _, pattern := range stagingDirs {
+		matches, _ := filepath.Glob(filepath.Join(p.distDir, pattern))
+		for _, dir := range matches {
+			if err := os.RemoveAll(dir); err != nil {
+				log.Warn("Failed to remove staging directory", "path", dir, "error", err)
+			}
+		}
+	}
+
+	marker := fmt.Sprintf("stage: %s\ntime: %s\nerror: %v\n", stage, time.Now().Format(time.RFC3339), cause)
+	if err := os.WriteFile(filepath.Join(p.distDir, abortedMarker), []byte(marker), 0644); err != nil {
+		log.Warn("Failed to write aborted marker", "error", err)
+	}
+	return fmt.Errorf("aborted during %s: %w", stage, cause)
+}
+
+// aborted reports whether the last build in dist was interrupted
+func (p *Pipeline) aborted() bool {
+	_, err := os.Stat(filepath.Join(p.distDir, abortedMarker))
+	return err == nil
+}
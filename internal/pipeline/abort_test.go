@@ -0,0 +1,73 @@
+package pipeline
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"go.uber.org/goleak"
+)
+
+const abortConfig = `project_name: demo
+dist: dist
+builds:
+  - id: demo
+    main: .
+    binary: demo
+    goos: [linux]
+    goarch: [amd64]
+    hooks:
+      pre: touch started && exec sleep 30
+`
+
+func TestBuildAllAbortsOnCancel(t *testing.T) {
+	defer goleak.VerifyNone(t, goleak.IgnoreCurrent())
+
+	workspace := t.TempDir()
+	for name, content := range map[string]string{
+		".releaser.yaml": abortConfig,
+		"go.mod":         "module example.com/demo\n\ngo 1.24\n",
+		"main.go":        "package main\n\nfunc main() {}\n",
+	} {
+		if err := os.WriteFile(filepath.Join(workspace, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	t.Chdir(workspace)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	p, err := New(ctx, ReleaseOptions{Snapshot: true, SkipCache: true, Parallelism: 1, Skip: []string{"sign"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Interrupt the build once the pre hook runs
+	go func() {
+		for ctx.Err() == nil {
+			if _, err := os.Stat(filepath.Join(workspace, "started")); err == nil {
+				cancel()
+				return
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	}()
+
+	start := time.Now()
+	err = p.BuildAll(ctx)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("BuildAll() = %v, want context.Canceled", err)
+	}
+	if elapsed := time.Since(start); elapsed > 20*time.Second {
+		t.Errorf("BuildAll() returned after %s, the hook was not stopped", elapsed)
+	}
+	if !p.aborted() {
+		t.Error("no aborted marker in dist")
+	}
+	if _, err := os.Stat(filepath.Join(p.distDir, ".releaser-state.json")); !os.IsNotExist(err) {
+		t.Errorf("state of the aborted build was saved: %v", err)
+	}
+}
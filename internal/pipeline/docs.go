@@ -0,0 +1,60 @@
+package pipeline
+
+import (
+	"context"
+	"path/filepath"
+
+	"github.com/charmbracelet/log"
+
+	"github.com/oarkflow/releaser/internal/config"
+	"github.com/oarkflow/releaser/internal/publish"
+)
+
+// docsConfigured reports whether the config has a docs section
+func docsConfigured(cfg config.Docs) bool {
+	return cfg.Install != "" || cfg.Manifest != "" || len(cfg.Templates) > 0 || cfg.Directory != "" ||
+		cfg.Repository.Owner != "" || cfg.Repository.Git.URL != ""
+}
+
+// stableRelease reports whether docs describe the release: not a snapshot,
+// nightly or prerelease
+func (p *Pipeline) stableRelease() bool {
+	snapshot, _ := p.templateCtx.GetValue("IsSnapshot").(bool)
+	nightly, _ := p.templateCtx.GetValue("IsNightly").(bool)
+	return !snapshot && !nightly && !p.templateCtx.IsPrerelease()
+}
+
+// docs renders the version docs into dist/docs to preview them; publish
+// renders them again with the published release and pushes them
+func (p *Pipeline) docs(ctx context.Context) error {
+	if !docsConfigured(p.config.Docs) {
+		return nil
+	}
+	off, err := p.disabled("docs.disable", p.config.Docs.Disable)
+	if err != nil {
+		return err
+	}
+	if off {
+		return nil
+	}
+	if _, err := publish.NewDocsPublisher(p.config, p.templateCtx, p.distDir).Render(p.artifacts.List()); err != nil {
+		return err
+	}
+	log.Info("Rendered docs", "dir", filepath.Join(p.distDir, "docs"))
+	return nil
+}
+
+// publishDocs pushes the docs of stable releases
+func (p *Pipeline) publishDocs(ctx context.Context, s *summary) error {
+	if !docsConfigured(p.config.Docs) {
+		return nil
+	}
+	if !p.stableRelease() {
+		log.Info("Skipping docs, not a stable release", "tag", p.templateCtx.Get("Tag"))
+		s.record("docs", "skipped", nil)
+		return nil
+	}
+	return s.run(ctx, "docs", p.config.Docs.Disable, func() error {
+		return publish.NewDocsPublisher(p.config, p.templateCtx, p.distDir).Publish(ctx, p.artifacts.List())
+	})
+}
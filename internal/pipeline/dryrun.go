@@ -0,0 +1,693 @@
+package pipeline
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/oarkflow/releaser/internal/archive"
+	"github.com/oarkflow/releaser/internal/artifact"
+	"github.com/oarkflow/releaser/internal/builder"
+	"github.com/oarkflow/releaser/internal/deps"
+	"github.com/oarkflow/releaser/internal/github"
+	"github.com/oarkflow/releaser/internal/nfpm"
+	"github.com/oarkflow/releaser/internal/publish"
+	"github.com/oarkflow/releaser/internal/redact"
+	"github.com/oarkflow/releaser/internal/tmpl"
+)
+
+// noValue is what text/template renders for a missing map key
+const noValue = "<no value>"
+
+// planNode is a node in the tree of things a dry run would produce
+type planNode struct {
+	label    string
+	children []*planNode
+}
+
+// add appends a child node and returns it
+func (n *planNode) add(format string, args ...interface{}) *planNode {
+	child := &planNode{label: fmt.Sprintf(format, args...)}
+	n.children = append(n.children, child)
+	return child
+}
+
+// write prints the children of n as an indented tree
+func (n *planNode) write(w io.Writer, prefix string) {
+	for i, child := range n.children {
+		branch, indent := "├── ", "│   "
+		if i == len(n.children)-1 {
+			branch, indent = "└── ", "    "
+		}
+		fmt.Fprintf(w, "%s%s%s\n", prefix, branch, child.label)
+		child.write(w, prefix+indent)
+	}
+}
+
+// dryRun walks the pipeline resolving templates and checking inputs,
+// collecting every problem instead of stopping at the first one.
+type dryRun struct {
+	p    *Pipeline
+	root *planNode
+	errs []error
+	// githubAppChecked reports the GitHub App settings only once
+	githubAppChecked bool
+	// generated are the directories archives' generated commands would
+	// write, whose files don't exist yet
+	generated []string
+}
+
+// DryRun resolves every template and checks referenced files, tools and
+// credentials without compiling, packaging, running docker or publishing.
+// It prints what the release would produce and reports all problems found.
+func (p *Pipeline) DryRun(ctx context.Context) error {
+	d := &dryRun{p: p, root: &planNode{}}
+
+	for _, err := range p.validateGit(ctx) {
+		d.fail("git: %w", err)
+	}
+
+	binaries := d.builds()
+	d.generatedDirs()
+	if err := p.checkCollisions(binaries); err != nil {
+		d.fail("%w", err)
+	}
+	if !p.skipped("archive") {
+		d.archives(binaries)
+	}
+	if !p.skipped("nfpm") {
+		d.linuxPackages(binaries)
+	}
+	d.platformPackages()
+	if !p.skipped("docker") {
+		d.dockers()
+	}
+	d.extraFiles()
+	if !p.skipped("checksum") {
+		d.checksums()
+	}
+	d.updateFeeds()
+	d.tools()
+	d.requirements()
+	if !p.skipped("publish") {
+		d.publishers()
+	}
+
+	out := os.Stdout
+	fmt.Fprintf(out, "%s %s (dry run)\n", p.config.ProjectName, p.templateCtx.Get("Tag"))
+	d.root.write(out, "")
+
+	if len(d.errs) == 0 {
+		fmt.Fprintln(out, "\n✓ No problems found")
+		return nil
+	}
+
+	fmt.Fprintf(out, "\n✗ %d problem(s) found:\n", len(d.errs))
+	for _, err := range d.errs {
+		fmt.Fprintf(out, "  - %s\n", err)
+	}
+	return fmt.Errorf("dry run found %d problem(s)", len(d.errs))
+}
+
+// fail records a problem
+func (d *dryRun) fail(format string, args ...interface{}) {
+	d.errs = append(d.errs, fmt.Errorf(format, args...))
+}
+
+// apply renders the template of a config field, recording execution errors
+// and references to missing values. It returns the raw template when
+// rendering fails.
+func (d *dryRun) apply(tmplCtx *tmpl.Context, source, s string) string {
+	out, err := tmplCtx.Apply(source, s)
+	if err != nil {
+		d.fail("%w", err)
+		return s
+	}
+	if strings.Contains(out, noValue) {
+		d.fail("%s: template %q references a missing value", source, s)
+	}
+	return out
+}
+
+// off reports whether a section is disabled, recording template errors
+func (d *dryRun) off(what, source, value string) bool {
+	off, err := d.p.disabled(source, value)
+	if err != nil {
+		d.fail("%s: %w", what, err)
+	}
+	return off
+}
+
+// skipsPrerelease reports whether a section with skip_prerelease set is
+// skipped for this release
+func (d *dryRun) skipsPrerelease(skip bool) bool {
+	return skip && d.p.templateCtx.IsPrerelease()
+}
+
+// checkFile records a problem when a referenced path or glob matches nothing
+func (d *dryRun) checkFile(what, path string) {
+	if path == "" {
+		return
+	}
+	path = d.apply(d.p.templateCtx, what, path)
+	for _, dir := range d.generated {
+		if within(filepath.Clean(path), dir) {
+			return
+		}
+	}
+	matches, err := filepath.Glob(path)
+	if err != nil {
+		d.fail("%s: invalid pattern %s: %w", what, path, err)
+		return
+	}
+	if len(matches) == 0 {
+		d.fail("%s: %s does not exist", what, path)
+	}
+}
+
+// requireEnv records a problem when none of the variables is set
+func (d *dryRun) requireEnv(what string, names ...string) {
+	for _, name := range names {
+		if os.Getenv(name) != "" {
+			return
+		}
+	}
+	d.fail("%s: %s is not set", what, strings.Join(names, " or "))
+}
+
+// requireGitHubToken records a problem when neither GITHUB_TOKEN nor a
+// usable GitHub App is configured
+func (d *dryRun) requireGitHubToken(what string) {
+	auth := d.p.config.Release.GitHub.Auth
+	if auth.AppID == "" {
+		d.requireEnv(what, "GITHUB_TOKEN")
+		return
+	}
+	if d.githubAppChecked {
+		return
+	}
+	d.githubAppChecked = true
+	app, err := githubApp(auth, d.p.templateCtx)
+	if err == nil {
+		err = github.ValidatePrivateKey(app.PrivateKey)
+	}
+	if err != nil {
+		d.fail("%s: %v", what, err)
+	}
+}
+
+// rel returns path relative to the working directory for display
+func rel(path string) string {
+	cwd, err := os.Getwd()
+	if err != nil {
+		return path
+	}
+	if r, err := filepath.Rel(cwd, path); err == nil && !strings.HasPrefix(r, "..") {
+		return r
+	}
+	return path
+}
+
+// within reports whether path is dir or inside it
+func within(path, dir string) bool {
+	r, err := filepath.Rel(dir, path)
+	return err == nil && r != ".." && !strings.HasPrefix(r, ".."+string(filepath.Separator))
+}
+
+// builds resolves binary names and paths for every build and target
+func (d *dryRun) builds() []artifact.Artifact {
+	p := d.p
+	node := d.root.add("builds")
+
+	var binaries []artifact.Artifact
+	for _, build := range p.config.Builds {
+		if build.Skip || !p.selected(build.ID) {
+			continue
+		}
+
+		buildNode := node.add("%s (%s)", build.ID, builderName(build.Builder))
+		switch build.Builder {
+		case "", "go", "rust", "node", "npm", "yarn", "pnpm", "deno", "bun", "dotnet", "prebuilt":
+		case "gomobile":
+			for _, platform := range build.Platforms {
+				if platform != "android" && platform != "ios" {
+					d.fail("build %s: unknown gomobile platform %s", build.ID, platform)
+				}
+			}
+			buildNode.add("%s", rel(filepath.Join(p.distDir, build.ID+"_<platform>")))
+			continue
+		default:
+			d.fail("build %s: unknown builder: %s", build.ID, build.Builder)
+		}
+
+		for _, target := range p.selectedTargets(build) {
+			if err := builder.CheckTarget(build.Builder, target); err != nil {
+				d.fail("build %s: %w", build.ID, err)
+				continue
+			}
+			binary, err := p.binaryName(build, target)
+			if err != nil {
+				d.fail("build %s for %s: %w", build.ID, target, err)
+				continue
+			}
+			if strings.Contains(binary, noValue) {
+				d.fail("build %s for %s: binary name references a missing value", build.ID, target)
+			}
+			path := filepath.Join(p.distDir, build.ID+"_"+target.String(), binary)
+			buildNode.add("%s", rel(path))
+			binaries = append(binaries, artifact.Artifact{
+				Name:    binary,
+				Path:    path,
+				Type:    artifact.TypeBinary,
+				Goos:    target.OS,
+				Goarch:  target.Arch,
+				Goarm:   target.Arm,
+				Goamd64: target.Amd64,
+				Gomips:  target.Mips,
+				BuildID: build.ID,
+			})
+		}
+
+		if build.GUI != nil {
+			d.checkFile(fmt.Sprintf("build %s gui icon", build.ID), build.GUI.Icon)
+			if build.GUI.Windows != nil {
+				d.checkFile(fmt.Sprintf("build %s windows icon", build.ID), build.GUI.Windows.Icon)
+			}
+		}
+	}
+	if len(binaries) == 0 && p.single != nil {
+		d.fail("%w", p.targetNotFound())
+	}
+	return binaries
+}
+
+// configName identifies a config entry by its id or position
+func configName(id string, index int) string {
+	if id == "" {
+		return fmt.Sprintf("#%d", index+1)
+	}
+	return id
+}
+
+// builderName returns the display name of a builder
+func builderName(builder string) string {
+	if builder == "" {
+		return "go"
+	}
+	return builder
+}
+
+// generatedDirs lists the commands generating files for archives and
+// packages
+func (d *dryRun) generatedDirs() {
+	dirs, err := d.p.generatedDirs()
+	if err != nil {
+		d.fail("%w", err)
+	}
+	if len(dirs) == 0 {
+		return
+	}
+	node := d.root.add("generated")
+	for _, g := range dirs {
+		d.generated = append(d.generated, g.dir)
+		dir := node.add("%s", rel(g.dir))
+		for _, cmd := range g.cmds {
+			dir.add("%s", cmd.Cmd)
+		}
+	}
+}
+
+// archives resolves archive names and checks the files they include
+func (d *dryRun) archives(binaries []artifact.Artifact) {
+	p := d.p
+	if len(p.config.Archives) == 0 {
+		return
+	}
+	node := d.root.add("archives")
+	creator := p.archiveCreator()
+	for i, cfg := range p.config.Archives {
+		name := configName(cfg.ID, i)
+		if !p.selected(cfg.ID, cfg.Builds...) || d.off("archive "+name, fmt.Sprintf("archives[%d].disable", i), cfg.Disable) {
+			continue
+		}
+		for _, bins := range archive.Targets(cfg, binaries) {
+			key := bins[0].Platform()
+			path, format, err := creator.Path(fmt.Sprintf("archives[%d]", i), cfg, bins[0])
+			if err != nil {
+				d.fail("archive %s for %s: %w", name, key, err)
+				continue
+			}
+			if strings.Contains(path, noValue) {
+				d.fail("archive %s for %s: name template references a missing value", name, key)
+			}
+			switch format {
+			case "tar.gz", "tgz", "tar.xz", "txz", "tar", "zip", "binary":
+			default:
+				d.fail("archive %s: unsupported archive format: %s", name, format)
+			}
+			node.add("%s", rel(path))
+		}
+		for _, file := range cfg.Files {
+			d.checkFile(fmt.Sprintf("archive %s file", name), file.Src)
+		}
+	}
+}
+
+// linuxPackages resolves nfpm package names and checks their contents
+func (d *dryRun) linuxPackages(binaries []artifact.Artifact) {
+	p := d.p
+	if len(p.config.NFPMs) == 0 {
+		return
+	}
+	node := d.root.add("packages")
+
+	seen := make(map[string]bool)
+	var arches []string
+	for _, bin := range binaries {
+		if bin.Goos != "linux" || seen[bin.Goarch] {
+			continue
+		}
+		seen[bin.Goarch] = true
+		arches = append(arches, bin.Goarch)
+	}
+	sort.Strings(arches)
+
+	for i, cfg := range p.config.NFPMs {
+		id := configName(cfg.ID, i)
+		if !p.selected(cfg.ID, cfg.Builds...) || d.off("nfpm "+id, fmt.Sprintf("nfpms[%d].disable", i), cfg.Disable) {
+			continue
+		}
+		names, err := nfpm.NewPackagerWithConfig(cfg, p.config, p.templateCtx, p.artifacts, p.distDir).Plan(arches)
+		if err != nil {
+			d.fail("nfpm %s: %w", id, err)
+			continue
+		}
+		for _, name := range names {
+			node.add("%s", rel(filepath.Join(p.distDir, name)))
+		}
+		for _, content := range cfg.Contents {
+			switch {
+			case content.SymlinkTo != "":
+				continue
+			case content.Type == "dir", content.Type == "symlink", content.Type == "ghost":
+				continue
+			}
+			d.checkFile("nfpm "+id+" content", content.Src)
+		}
+		for _, script := range []string{cfg.Scripts.PreInstall, cfg.Scripts.PostInstall, cfg.Scripts.PreRemove, cfg.Scripts.PostRemove} {
+			d.checkFile("nfpm "+id+" script", script)
+		}
+	}
+}
+
+// platformPackages checks the icons, scripts and extra files referenced by
+// macOS and Windows packages
+func (d *dryRun) platformPackages() {
+	p := d.p
+	node := &planNode{label: "installers"}
+
+	named := func(kind, source, id, nameTemplate string) {
+		label := id
+		if nameTemplate != "" {
+			label = d.apply(p.templateCtx, source, nameTemplate)
+		}
+		node.add("%s: %s", kind, label)
+	}
+
+	for i, cfg := range p.config.AppBundles {
+		named("app bundle", fmt.Sprintf("app_bundles[%d].name", i), cfg.ID, cfg.Name)
+		d.checkFile("app bundle "+cfg.ID+" icon", cfg.Icon)
+		for _, file := range cfg.ExtraFiles {
+			d.checkFile("app bundle "+cfg.ID+" extra file", file.Src)
+		}
+	}
+	for i, cfg := range p.config.DMGs {
+		named("dmg", fmt.Sprintf("dmgs[%d].name_template", i), cfg.ID, cfg.NameTemplate)
+		d.checkFile("dmg "+cfg.ID+" icon", cfg.Icon)
+		d.checkFile("dmg "+cfg.ID+" background", cfg.Background)
+	}
+	for i, cfg := range p.config.PKGs {
+		named("pkg", fmt.Sprintf("pkgs[%d].name_template", i), cfg.ID, cfg.NameTemplate)
+		d.checkFile("pkg "+cfg.ID+" preinstall script", cfg.Scripts.PreInstall)
+		d.checkFile("pkg "+cfg.ID+" postinstall script", cfg.Scripts.PostInstall)
+		d.checkFile("pkg "+cfg.ID+" component plist", cfg.ComponentPlist)
+		d.checkFile("pkg "+cfg.ID+" distribution", cfg.Distribution)
+		for _, file := range cfg.ExtraFiles {
+			d.checkFile("pkg "+cfg.ID+" extra file", file.Src)
+		}
+	}
+	for i, cfg := range p.config.MSIs {
+		named("msi", fmt.Sprintf("msis[%d].name_template", i), cfg.ID, cfg.NameTemplate)
+		d.checkFile("msi "+cfg.ID+" wxs", cfg.WXS)
+		d.checkFile("msi "+cfg.ID+" icon", cfg.Icon)
+		d.checkFile("msi "+cfg.ID+" license", cfg.License)
+		for _, file := range cfg.ExtraFiles {
+			d.checkFile("msi "+cfg.ID+" extra file", file.Src)
+		}
+	}
+	for i, cfg := range p.config.NSISs {
+		named("nsis", fmt.Sprintf("nsiss[%d].name_template", i), cfg.ID, cfg.NameTemplate)
+		d.checkFile("nsis "+cfg.ID+" script", cfg.Script)
+		d.checkFile("nsis "+cfg.ID+" license file", cfg.LicenseFile)
+		d.checkFile("nsis "+cfg.ID+" icon", cfg.Icon)
+		for _, file := range cfg.ExtraFiles {
+			d.checkFile("nsis "+cfg.ID+" extra file", file.Src)
+		}
+	}
+
+	if len(node.children) > 0 {
+		d.root.children = append(d.root.children, node)
+	}
+}
+
+// dockers resolves image tags and checks Dockerfiles
+func (d *dryRun) dockers() {
+	p := d.p
+	if len(p.config.Dockers) == 0 && len(p.config.DockerManifests) == 0 {
+		return
+	}
+	node := d.root.add("docker")
+
+	for i, cfg := range p.config.Dockers {
+		id := configName(cfg.ID, i)
+		if cfg.Skip == "true" || d.off("docker "+id, fmt.Sprintf("dockers[%d].disable", i), cfg.Disable) {
+			continue
+		}
+		if !cfg.SkipBuild {
+			dockerfile := cfg.Dockerfile
+			if dockerfile == "" {
+				dockerfile = "Dockerfile"
+			}
+			d.checkFile("docker "+id+" dockerfile", dockerfile)
+		}
+		for _, file := range cfg.ExtraFiles {
+			d.checkFile("docker "+id+" extra file", file)
+		}
+		if len(cfg.ImageTemplates) == 0 {
+			d.fail("docker %s: no image_templates configured", id)
+		}
+		for j, image := range cfg.ImageTemplates {
+			node.add("%s", d.apply(p.templateCtx, fmt.Sprintf("dockers[%d].image_templates[%d]", i, j), image))
+		}
+	}
+
+	for i, cfg := range p.config.DockerManifests {
+		name := d.apply(p.templateCtx, fmt.Sprintf("docker_manifests[%d].name_template", i), cfg.NameTemplate)
+		manifest := node.add("%s (manifest)", name)
+		for j, image := range cfg.ImageTemplates {
+			manifest.add("%s", d.apply(p.templateCtx, fmt.Sprintf("docker_manifests[%d].image_templates[%d]", i, j), image))
+		}
+	}
+}
+
+// extraFiles resolves the extra files the release attaches
+func (d *dryRun) extraFiles() {
+	p := d.p
+	if len(p.config.ExtraFiles) == 0 {
+		return
+	}
+	files, err := p.resolveExtraFiles()
+	if err != nil {
+		d.fail("%w", err)
+		return
+	}
+	node := d.root.add("extra files")
+	for _, a := range files {
+		node.add("%s (%s)", rel(filepath.Join(p.distDir, a.Name)), a.Type)
+	}
+}
+
+// checksums resolves the checksum file name
+func (d *dryRun) checksums() {
+	p := d.p
+	if p.config.Checksum.Disable {
+		return
+	}
+	name := p.config.Checksum.NameTemplate
+	if name == "" {
+		name = "checksums.txt"
+	}
+	name = d.apply(p.templateCtx, "checksum.name_template", name)
+	d.root.add("checksums").add("%s", rel(filepath.Join(p.distDir, name)))
+}
+
+// updateFeeds resolves the update feed names and the appcast to append to
+func (d *dryRun) updateFeeds() {
+	p := d.p
+	cfg := p.config.Updates
+	if (!cfg.Appcast.Enabled && !cfg.Manifest.Enabled) || d.off("updates", "updates.disable", cfg.Disable) || d.skipsPrerelease(cfg.SkipPrerelease) {
+		return
+	}
+	node := d.root.add("update feeds")
+	if cfg.Appcast.Enabled {
+		name := cfg.Appcast.Name
+		if name == "" {
+			name = "appcast.xml"
+		}
+		name = rel(filepath.Join(p.distDir, name))
+		if cfg.Appcast.URL != "" {
+			node.add("%s (appends to %s)", name, d.apply(p.templateCtx, "updates.appcast.url", cfg.Appcast.URL))
+		} else {
+			node.add("%s", name)
+		}
+	}
+	if cfg.Manifest.Enabled {
+		name := cfg.Manifest.Name
+		if name == "" {
+			name = "latest.json"
+		}
+		node.add("%s", rel(filepath.Join(p.distDir, name)))
+	}
+}
+
+// tools checks that the tools the release needs are in PATH. Tools that the
+// pipeline installs on demand, and optional ones unless deps.fail_fast is
+// set, are reported but not treated as problems.
+func (d *dryRun) tools() {
+	p := d.p
+	checks, err := p.toolChecks()
+	if err != nil {
+		d.fail("%w", err)
+		return
+	}
+	node := d.root.add("tools")
+	for _, c := range checks {
+		switch {
+		case c.ok:
+			node.add("%s ✓", c.tool)
+		case deps.Installing() && c.install() != "":
+			node.add("%s missing, will be installed", c.tool)
+		case c.soft && !p.config.Deps.FailFast:
+			node.add("%s missing, optional", c.tool)
+		default:
+			node.add("%s missing", c.tool)
+			if install := c.install(); install != "" {
+				d.fail("required tool %s not found in PATH, install it with: %s", c.tool, install)
+			} else {
+				d.fail("required tool %s not found in PATH", c.tool)
+			}
+		}
+	}
+}
+
+// requirements checks the declared go version and, unless publishing is
+// skipped, the declared variables and the credentials of the publishers
+func (d *dryRun) requirements() {
+	p := d.p
+	if problem := checkGoVersion(p.config.Requirements.MinGo); problem != "" {
+		d.fail("requirements: %s", problem)
+	}
+	if p.skipped("publish") {
+		return
+	}
+	for _, name := range p.config.Requirements.Env {
+		d.requireEnv("requirements.env", name)
+	}
+	for _, req := range publish.Requirements(p.config) {
+		if d.off(req.NeededBy, req.NeededBy+" disable", req.Disable) {
+			continue
+		}
+		if len(req.Env) == 1 && req.Env[0] == "GITHUB_TOKEN" {
+			d.requireGitHubToken(req.NeededBy)
+			continue
+		}
+		d.requireEnv(req.NeededBy, req.Env...)
+	}
+}
+
+// publishers resolves publisher targets
+func (d *dryRun) publishers() {
+	p := d.p
+	node := &planNode{label: "publish"}
+
+	if gh := p.config.Release.GitHub; gh.Owner != "" && !d.off("release", "release.disable", p.config.Release.Disable) {
+		owner := d.apply(p.templateCtx, "release.github.owner", gh.Owner)
+		name := d.apply(p.templateCtx, "release.github.name", gh.Name)
+		if name == "" {
+			d.fail("release github: name is required")
+		}
+		node.add("github release %s/%s@%s", owner, name, p.templateCtx.Get("Tag"))
+	}
+	for i, cfg := range p.config.Brews {
+		if d.off("brew "+cfg.Name, fmt.Sprintf("brews[%d].disable", i), cfg.Disable) || d.skipsPrerelease(cfg.SkipPrerelease) {
+			continue
+		}
+		node.add("homebrew formula %s", d.apply(p.templateCtx, fmt.Sprintf("brews[%d].name", i), cfg.Name))
+	}
+	for i, cfg := range p.config.Casks {
+		if d.off("homebrew cask", fmt.Sprintf("casks[%d].disable", i), cfg.Disable) {
+			continue
+		}
+		node.add("homebrew cask")
+	}
+	for i, cfg := range p.config.Scoops {
+		if d.off("scoop", fmt.Sprintf("scoops[%d].disable", i), cfg.Disable) || d.skipsPrerelease(cfg.SkipPrerelease) {
+			continue
+		}
+		node.add("scoop manifest")
+	}
+	for i, cfg := range p.config.Wingets {
+		if d.off("winget", fmt.Sprintf("wingets[%d].disable", i), cfg.Disable) || d.skipsPrerelease(cfg.SkipPrerelease) {
+			continue
+		}
+		node.add("winget manifest")
+	}
+	for i, cfg := range p.config.NPMs {
+		if d.off("npm "+cfg.Name, fmt.Sprintf("npms[%d].disable", i), cfg.Disable) {
+			continue
+		}
+		if cfg.PlatformPackages {
+			node.add("npm platform packages %s", d.apply(p.templateCtx, fmt.Sprintf("npms[%d].name", i), cfg.Name))
+		} else {
+			node.add("npm package %s", d.apply(p.templateCtx, fmt.Sprintf("npms[%d].name", i), cfg.Name))
+		}
+	}
+	for i, cfg := range p.config.Milestones {
+		if !cfg.Close {
+			continue
+		}
+		name := "{{ .Tag }}"
+		if cfg.NameTemplate != "" {
+			name = cfg.NameTemplate
+		}
+		node.add("close milestone %s", d.apply(p.templateCtx, fmt.Sprintf("milestones[%d].name_template", i), name))
+	}
+
+	if cfg := p.config.Docs; docsConfigured(cfg) && p.stableRelease() && !d.off("docs", "docs.disable", cfg.Disable) {
+		if repo := cfg.Repository; repo.Owner != "" {
+			node.add("docs %s/%s", repo.Owner, repo.Name)
+		} else if repo.Git.URL != "" {
+			node.add("docs %s", redact.String(repo.Git.URL))
+		} else {
+			node.add("docs in %s", filepath.Join(p.distDir, "docs"))
+		}
+	}
+
+	if len(node.children) > 0 {
+		d.root.children = append(d.root.children, node)
+	}
+}
@@ -0,0 +1,44 @@
+package pipeline
+
+import (
+	"context"
+
+	"github.com/charmbracelet/log"
+
+	"github.com/oarkflow/releaser/internal/artifact"
+	"github.com/oarkflow/releaser/internal/plugin"
+)
+
+// notify sends an event to the plugins with the artifacts of the given
+// types, or all artifacts when none are given
+func (p *Pipeline) notify(ctx context.Context, event string, types []artifact.Type, cause error) error {
+	artifacts := p.artifacts.All()
+	if len(types) > 0 {
+		var filters []artifact.FilterFunc
+		for _, t := range types {
+			filters = append(filters, artifact.ByType(t))
+		}
+		artifacts = p.artifacts.Filter(artifact.Or(filters...))
+	}
+	e := plugin.Event{
+		Event:       event,
+		ProjectName: p.config.ProjectName,
+		Tag:         p.templateCtx.Get("Tag"),
+		Version:     p.templateCtx.Get("Version"),
+		Snapshot:    p.options.Snapshot,
+		Artifacts:   artifacts,
+	}
+	if cause != nil {
+		e.Error = cause.Error()
+	}
+	return p.plugins.Dispatch(ctx, e)
+}
+
+// failed reports a failed release to the plugins and returns err. They run
+// even when the release was interrupted.
+func (p *Pipeline) failed(ctx context.Context, err error) error {
+	if notifyErr := p.notify(context.WithoutCancel(ctx), plugin.EventReleaseFailed, nil, err); notifyErr != nil {
+		log.Warn("Failed to report the failed release", "error", notifyErr)
+	}
+	return err
+}
@@ -0,0 +1,70 @@
+package pipeline
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/charmbracelet/log"
+
+	"github.com/oarkflow/releaser/internal/config"
+	"github.com/oarkflow/releaser/internal/hook"
+)
+
+// generated runs the generated commands of the enabled archives, e.g. shell
+// completions or man pages, before anything archives or packages them, so
+// nfpm contents can install the same files
+func (p *Pipeline) generated(ctx context.Context) error {
+	dirs, err := p.generatedDirs()
+	if err != nil {
+		return err
+	}
+	workDir, _ := os.Getwd()
+	runner := hook.NewRunner(p.templateCtx, workDir)
+	for _, d := range dirs {
+		if err := os.MkdirAll(d.dir, 0755); err != nil {
+			return fmt.Errorf("%s: %w", d.source, err)
+		}
+		log.Info("Generating files", "dir", d.dir)
+		if err := runner.RunHooks(ctx, d.cmds); err != nil {
+			return fmt.Errorf("%s: %w", d.source, err)
+		}
+	}
+	return nil
+}
+
+// generatedDir is a directory written by the commands of an archive's
+// generated entry
+type generatedDir struct {
+	source string
+	dir    string
+	cmds   []config.Hook
+}
+
+// generatedDirs resolves the generated directories of the selected, enabled
+// archives
+func (p *Pipeline) generatedDirs() ([]generatedDir, error) {
+	creator := p.archiveCreator()
+	var dirs []generatedDir
+	for i, cfg := range p.config.Archives {
+		if len(cfg.Generated) == 0 || !p.selected(cfg.ID, cfg.Builds...) {
+			continue
+		}
+		off, err := p.disabled(fmt.Sprintf("archives[%d].disable", i), cfg.Disable)
+		if err != nil {
+			return nil, fmt.Errorf("archive %s: %w", cfg.ID, err)
+		}
+		if off {
+			continue
+		}
+		for j, g := range cfg.Generated {
+			source := fmt.Sprintf("archives[%d].generated[%d]", i, j)
+			dir, err := creator.GeneratedDir(source, g)
+			if err != nil {
+				return nil, err
+			}
+			dirs = append(dirs, generatedDir{source: source, dir: dir, cmds: g.Cmds})
+		}
+	}
+	return dirs, nil
+}
@@ -0,0 +1,160 @@
+package pipeline
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/oarkflow/releaser/internal/artifact"
+)
+
+// Dist layouts
+const (
+	layoutFlat     = "flat"
+	layoutByTarget = "by-target"
+	layoutByType   = "by-type"
+)
+
+// intermediate reports whether artifacts of a type are only inputs of
+// later stages, so cleanup_dist_dirs may remove them after the build.
+// Binaries stay while npm, PyPI or gem publishers still package them.
+func (p *Pipeline) intermediate(t artifact.Type) bool {
+	switch t {
+	case artifact.TypeBinary, artifact.TypeUniversalBinary:
+		return len(p.config.NPMs) == 0 && len(p.config.PyPIs) == 0 && len(p.config.Gems) == 0
+	case artifact.TypeDirectory, artifact.TypeLibrary, artifact.TypeHeader, artifact.TypePkgConfig:
+		return true
+	}
+	return false
+}
+
+// layoutDir returns the directory of dist an artifact belongs in, or ""
+// for the root of dist
+func layoutDir(layout string, a artifact.Artifact) string {
+	switch layout {
+	case layoutByTarget:
+		if a.Goos == "" || a.Goarch == "" {
+			return ""
+		}
+		return a.Target().String()
+	case layoutByType:
+		return strings.ToLower(strings.ReplaceAll(string(a.Type), " ", "-"))
+	}
+	return ""
+}
+
+// layoutDist moves the final artifacts written to the root of dist into
+// the directories of the dist_layout. Intermediate artifacts, artifacts
+// outside dist and ones already moved by a previous run stay where they
+// are.
+func (p *Pipeline) layoutDist() error {
+	layout := p.config.DistLayout
+	switch layout {
+	case "", layoutFlat:
+		return nil
+	case layoutByTarget, layoutByType:
+	default:
+		return fmt.Errorf("unknown dist_layout %q: use flat, by-target or by-type", layout)
+	}
+
+	root, err := filepath.Abs(p.distDir)
+	if err != nil {
+		return err
+	}
+
+	moved := make(map[string]string)
+	for _, a := range p.artifacts.List() {
+		if p.intermediate(a.Type) {
+			continue
+		}
+		if _, ok := moved[a.Path]; ok {
+			continue
+		}
+		abs, err := filepath.Abs(a.Path)
+		if err != nil || filepath.Dir(abs) != root {
+			continue
+		}
+		// Images and other artifacts without a file in dist
+		if _, err := os.Lstat(abs); err != nil {
+			continue
+		}
+		dir := layoutDir(layout, a)
+		if dir == "" {
+			continue
+		}
+		dst := filepath.Join(p.distDir, dir, filepath.Base(a.Path))
+		if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+			return err
+		}
+		if err := os.Rename(a.Path, dst); err != nil {
+			return fmt.Errorf("failed to move %s to %s: %w", a.Name, dir, err)
+		}
+		moved[a.Path] = dst
+	}
+
+	p.artifacts.Update(func(a artifact.Artifact) bool {
+		_, ok := moved[a.Path]
+		return ok
+	}, func(a *artifact.Artifact) {
+		a.Path = moved[a.Path]
+	})
+	return nil
+}
+
+// cleanupDistDirs removes the intermediate directories left inside dist,
+// such as build outputs and staging directories, and unregisters the
+// artifacts they held so the saved state and publishers don't expect them.
+// Directories that are, or hold, an artifact still to be published, such
+// as app bundles, charts or dist_layout directories, are kept.
+func (p *Pipeline) cleanupDistDirs() error {
+	root, err := filepath.Abs(p.distDir)
+	if err != nil {
+		return err
+	}
+	keep := make(map[string]bool)
+	for _, a := range p.artifacts.List() {
+		if p.intermediate(a.Type) {
+			continue
+		}
+		abs, err := filepath.Abs(a.Path)
+		if err != nil {
+			continue
+		}
+		rel, err := filepath.Rel(root, abs)
+		if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			continue
+		}
+		keep[strings.SplitN(filepath.ToSlash(rel), "/", 2)[0]] = true
+	}
+
+	entries, err := os.ReadDir(p.distDir)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		return err
+	}
+	removed := make(map[string]bool)
+	defer func() {
+		p.artifacts.Remove(func(a artifact.Artifact) bool {
+			abs, err := filepath.Abs(a.Path)
+			if err != nil || a.Path == "" {
+				return false
+			}
+			rel, err := filepath.Rel(root, abs)
+			return err == nil && removed[strings.SplitN(filepath.ToSlash(rel), "/", 2)[0]]
+		})
+	}()
+	for _, entry := range entries {
+		if !entry.IsDir() || keep[entry.Name()] {
+			continue
+		}
+		if err := os.RemoveAll(filepath.Join(p.distDir, entry.Name())); err != nil {
+			return err
+		}
+		removed[entry.Name()] = true
+	}
+	return nil
+}
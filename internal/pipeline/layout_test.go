@@ -0,0 +1,136 @@
+package pipeline
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/oarkflow/releaser/internal/artifact"
+	"github.com/oarkflow/releaser/internal/config"
+)
+
+const cleanupConfig = `project_name: demo
+dist: dist
+cleanup_dist_dirs: true
+builds:
+  - id: demo
+    main: .
+    binary: demo
+    goos: [linux]
+    goarch: [amd64]
+archives:
+  - id: default
+    format: tar.gz
+`
+
+func TestCleanupDistDirsBeforeSavingState(t *testing.T) {
+	workspace := t.TempDir()
+	for name, content := range map[string]string{
+		".releaser.yaml": cleanupConfig,
+		"go.mod":         "module example.com/demo\n\ngo 1.24\n",
+		"main.go":        "package main\n\nfunc main() {}\n",
+	} {
+		if err := os.WriteFile(filepath.Join(workspace, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	t.Chdir(workspace)
+
+	p, err := New(context.Background(), ReleaseOptions{Snapshot: true, SkipCache: true, Parallelism: 1, Skip: []string{"sign"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := p.BuildAll(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := os.Stat(filepath.Join(p.distDir, "demo_linux_amd64")); !os.IsNotExist(err) {
+		t.Errorf("build directory was not removed: %v", err)
+	}
+	if binaries := p.artifacts.Filter(artifact.ByType(artifact.TypeBinary)); len(binaries) != 0 {
+		t.Errorf("removed binaries are still registered: %+v", binaries)
+	}
+
+	// Publish restores the state without missing files
+	publish := statePipeline(t, p.config, p.configPath, p.distDir, nil)
+	if err := publish.loadState(); err != nil {
+		t.Fatalf("restoring the state of the cleaned dist: %v", err)
+	}
+	if archives := publish.artifacts.Filter(artifact.ByType(artifact.TypeArchive)); len(archives) != 1 {
+		t.Errorf("restored archives = %+v", archives)
+	}
+	for _, a := range publish.artifacts.List() {
+		if a.Type == artifact.TypeBinary {
+			t.Errorf("restored removed binary %s", a.Path)
+		}
+	}
+}
+
+func TestCleanupDistDirsKeepsPublishedDirs(t *testing.T) {
+	dist := t.TempDir()
+	files := map[string]string{
+		"demo_linux_amd64/demo":                  "binary",
+		"demo_darwin_arm64/demo":                 "binary",
+		"darwin/Demo.app/Contents/MacOS/demo":    "binary",
+		"demo_linux_amd64.tar.gz":                "archive",
+		"demo_linux_amd64.AppDir/usr/bin/demo":   "staging",
+		"charts/demo-1.2.3.tgz":                  "chart",
+		"charts/demo/Chart.yaml":                 "chart",
+		"demo_linux_amd64.AppDir/AppRun":         "staging",
+		"demo_darwin_arm64/demo.dSYM/Info.plist": "debug",
+	}
+	for name, content := range files {
+		path := filepath.Join(dist, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	p := statePipeline(t, &config.Config{ProjectName: "demo", CleanupDistDirs: true}, "", dist, nil)
+	for _, a := range []artifact.Artifact{
+		{Name: "demo", Path: filepath.Join(dist, "demo_linux_amd64", "demo"), Type: artifact.TypeBinary, Goos: "linux", Goarch: "amd64"},
+		{Name: "demo", Path: filepath.Join(dist, "demo_darwin_arm64", "demo"), Type: artifact.TypeBinary, Goos: "darwin", Goarch: "arm64"},
+		{Name: "Demo.app", Path: filepath.Join(dist, "darwin", "Demo.app"), Type: artifact.TypeAppBundle},
+		{Name: "demo_linux_amd64.tar.gz", Path: filepath.Join(dist, "demo_linux_amd64.tar.gz"), Type: artifact.TypeArchive},
+		{Name: "demo-1.2.3.tgz", Path: filepath.Join(dist, "charts", "demo-1.2.3.tgz"), Type: artifact.TypeHelm},
+		{Name: "ghcr.io/oarkflow/demo:1.2.3", Type: artifact.TypeDockerImage},
+	} {
+		if err := p.artifacts.Add(a); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := p.cleanupDistDirs(); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, dir := range []string{"demo_linux_amd64", "demo_darwin_arm64", "demo_linux_amd64.AppDir"} {
+		if _, err := os.Stat(filepath.Join(dist, dir)); !os.IsNotExist(err) {
+			t.Errorf("%s was not removed: %v", dir, err)
+		}
+	}
+	for _, path := range []string{"darwin/Demo.app/Contents/MacOS/demo", "demo_linux_amd64.tar.gz", "charts/demo/Chart.yaml"} {
+		if _, err := os.Stat(filepath.Join(dist, filepath.FromSlash(path))); err != nil {
+			t.Errorf("%s was removed: %v", path, err)
+		}
+	}
+
+	var names []string
+	for _, a := range p.artifacts.List() {
+		names = append(names, a.Name)
+	}
+	want := []string{"Demo.app", "demo_linux_amd64.tar.gz", "demo-1.2.3.tgz", "ghcr.io/oarkflow/demo:1.2.3"}
+	if len(names) != len(want) {
+		t.Fatalf("artifacts = %v, want %v", names, want)
+	}
+	for i := range want {
+		if names[i] != want[i] {
+			t.Errorf("artifacts = %v, want %v", names, want)
+			break
+		}
+	}
+}
@@ -0,0 +1,102 @@
+package pipeline
+
+import (
+	"context"
+	"os"
+	"strings"
+
+	"github.com/charmbracelet/log"
+
+	"github.com/oarkflow/releaser/internal/artifact"
+	"github.com/oarkflow/releaser/internal/changelog"
+)
+
+// releaseNotes generates the changelog and builds the release body from
+// release.notes, saved with the state as the ReleaseNotes template field
+func (p *Pipeline) releaseNotes(ctx context.Context) error {
+	generated, err := changelog.NewFromConfig(p.config, changelog.Options{}).Generate(ctx)
+	if err != nil {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		log.Warn("Failed to generate the changelog", "error", err)
+	}
+	p.templateCtx.Set("Changelog", strings.TrimSpace(generated))
+	p.templateCtx.Set("Checksums", p.checksums())
+
+	notes := p.config.Release.Notes
+	body := p.templateCtx.Get("Changelog")
+	if notes.FromFileTemplate != "" {
+		if fromFile, ok, err := p.notesFromFile(notes.FromFileTemplate); err != nil {
+			return err
+		} else if ok {
+			body = fromFile
+		}
+	}
+
+	parts := []string{body}
+	if notes.HeaderTemplate != "" {
+		header, err := p.templateCtx.Apply("release.notes.header_template", notes.HeaderTemplate)
+		if err != nil {
+			return err
+		}
+		parts = append([]string{header}, parts...)
+	}
+	if notes.FooterTemplate != "" {
+		footer, err := p.templateCtx.Apply("release.notes.footer_template", notes.FooterTemplate)
+		if err != nil {
+			return err
+		}
+		parts = append(parts, footer)
+	}
+
+	var kept []string
+	for _, part := range parts {
+		if part = strings.TrimSpace(part); part != "" {
+			kept = append(kept, part)
+		}
+	}
+	p.templateCtx.Set("ReleaseNotes", strings.Join(kept, "\n\n"))
+	return nil
+}
+
+// notesFromFile renders the hand-written notes file, appending the
+// changelog unless the file places it. A missing file is reported as not
+// found so the changelog is used instead.
+func (p *Pipeline) notesFromFile(pathTemplate string) (string, bool, error) {
+	path, err := p.templateCtx.Apply("release.notes.from_file_template", pathTemplate)
+	if err != nil {
+		return "", false, err
+	}
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		log.Warn("Release notes file not found, using the changelog", "path", path)
+		return "", false, nil
+	}
+	if err != nil {
+		return "", false, err
+	}
+
+	body, err := p.templateCtx.Apply(path, string(data))
+	if err != nil {
+		return "", false, err
+	}
+	if !strings.Contains(string(data), ".Changelog") {
+		if changelog := p.templateCtx.Get("Changelog"); changelog != "" {
+			body = strings.TrimSpace(body) + "\n\n" + changelog
+		}
+	}
+	log.Info("Using release notes", "path", path)
+	return body, true, nil
+}
+
+// checksums returns the content of the checksums file, or an empty string
+// when none was generated
+func (p *Pipeline) checksums() string {
+	for _, a := range p.artifacts.Filter(artifact.ByType(artifact.TypeChecksum)) {
+		if data, err := os.ReadFile(a.Path); err == nil {
+			return strings.TrimSpace(string(data))
+		}
+	}
+	return ""
+}
@@ -5,6 +5,8 @@ package pipeline
 
 import (
 	"context"
+	"crypto/rand"
+	"encoding/base64"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -12,12 +14,15 @@ import (
 	"os/exec"
 	"path/filepath"
 	"runtime"
+	"sort"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
 
 	"github.com/charmbracelet/log"
 
+	"github.com/oarkflow/releaser"
 	"github.com/oarkflow/releaser/internal/announce"
 	"github.com/oarkflow/releaser/internal/archive"
 	"github.com/oarkflow/releaser/internal/artifact"
@@ -27,18 +32,31 @@ import (
 	"github.com/oarkflow/releaser/internal/config"
 	"github.com/oarkflow/releaser/internal/deps"
 	"github.com/oarkflow/releaser/internal/docker"
+	"github.com/oarkflow/releaser/internal/env"
+	"github.com/oarkflow/releaser/internal/fsutil"
 	"github.com/oarkflow/releaser/internal/git"
+	"github.com/oarkflow/releaser/internal/github"
 	"github.com/oarkflow/releaser/internal/hook"
+	"github.com/oarkflow/releaser/internal/httpclient"
 	"github.com/oarkflow/releaser/internal/nfpm"
 	"github.com/oarkflow/releaser/internal/packaging"
+	"github.com/oarkflow/releaser/internal/plugin"
+	"github.com/oarkflow/releaser/internal/provenance"
 	"github.com/oarkflow/releaser/internal/publish"
+	"github.com/oarkflow/releaser/internal/redact"
+	"github.com/oarkflow/releaser/internal/retry"
+	"github.com/oarkflow/releaser/internal/sbom"
+	"github.com/oarkflow/releaser/internal/secrets"
 	"github.com/oarkflow/releaser/internal/sign"
+	"github.com/oarkflow/releaser/internal/target"
 	"github.com/oarkflow/releaser/internal/tmpl"
+	"github.com/oarkflow/releaser/internal/upx"
 )
 
 // ReleaseOptions contains options for the release pipeline
 type ReleaseOptions struct {
 	ConfigFile   string
+	Profile      string
 	Prepare      bool
 	Snapshot     bool
 	Nightly      bool
@@ -52,6 +70,25 @@ type ReleaseOptions struct {
 	Parallelism  int
 	Timeout      string
 	Silent       bool
+	DryRun       bool
+	// GroupLogs wraps the logs of each stage in GitHub Actions groups
+	GroupLogs bool
+
+	// Skip lists stages to skip; see SkipStages
+	Skip []string
+	// IDs limits builds, archives and nfpms to these config ids
+	IDs []string
+
+	// AutoTag tags an untagged HEAD with the next Bump version before
+	// releasing; the tag is pushed unless publishing is skipped
+	AutoTag bool
+	Bump    string
+	SignTag bool
+	// AllowDirty permits releasing and tagging with uncommitted changes
+	AllowDirty bool
+	// FromState continues a prepared release, restoring the template context
+	// from dist, so the git repository is optional
+	FromState bool
 }
 
 // Pipeline orchestrates the release process
@@ -62,9 +99,20 @@ type Pipeline struct {
 	gitInfo     *git.Info
 	templateCtx *tmpl.Context
 	buildCache  *cache.BuildCache
+	configPath  string
 	distDir     string
 	startTime   time.Time
-	mu          sync.Mutex
+	skip        map[string]bool
+	// single is the --single-target pattern, nil when building every target
+	single *target.Target
+	// garbleSeed is the random obfuscation seed shared by all targets of
+	// the release
+	garbleSeed string
+	// requirementsChecked is set once the requirements were checked
+	requirementsChecked bool
+	// plugins are notified of pipeline events
+	plugins *plugin.Dispatcher
+	mu      sync.Mutex
 }
 
 // New creates a new release pipeline
@@ -75,24 +123,85 @@ func New(ctx context.Context, opts ReleaseOptions) (*Pipeline, error) {
 		cfgPath = findConfigFile()
 	}
 
+	skip, err := parseSkip(opts)
+	if err != nil {
+		return nil, err
+	}
+	single, err := parseSingleTarget(opts.SingleTarget, skip)
+	if err != nil {
+		return nil, err
+	}
+
 	cfg, err := config.Load(cfgPath)
 	if err != nil {
 		return nil, fmt.Errorf("failed to load config: %w", err)
 	}
 
+	// Profiles overlay the merged config before it is validated
+	if err := applyProfile(cfg, opts); err != nil {
+		return nil, err
+	}
+
 	if err := cfg.Validate(); err != nil {
 		return nil, fmt.Errorf("invalid config: %w", err)
 	}
 
+	if err := checkIDs(cfg, opts.IDs); err != nil {
+		return nil, err
+	}
+
+	if err := configureHTTP(cfg.HTTP); err != nil {
+		return nil, fmt.Errorf("http: %w", err)
+	}
+	github.Configure(github.URLs{API: cfg.GitHubURLs.API, Upload: cfg.GitHubURLs.Upload, Download: cfg.GitHubURLs.Download})
+
+	// Fetch keyring and secret manager references once, before anything
+	// reads the config
+	if err := secrets.ResolveAll(ctx, cfg); err != nil {
+		return nil, err
+	}
+
+	// Tag before reading git info so the release picks up the new tag
+	if opts.AutoTag && !opts.Snapshot && !opts.Nightly && !opts.DryRun {
+		if err := autoTag(ctx, cfg, opts, skip); err != nil {
+			return nil, err
+		}
+	}
+
 	// Get git information
 	gitInfo, err := git.GetInfo(ctx)
-	if err != nil && !opts.Snapshot {
+	if err != nil && !opts.Snapshot && !opts.FromState {
 		return nil, fmt.Errorf("failed to get git info: %w", err)
 	}
 
+	// Load env files first so the template context and config env see them
+	if err := env.LoadFiles(cfg.EnvFiles); err != nil {
+		return nil, err
+	}
+
 	// Create template context
 	templateCtx := tmpl.New(cfg, gitInfo, opts.Snapshot, opts.Nightly)
 
+	deps.Configure(cfg.Deps.AutoInstall)
+
+	// Export the global env to every process the pipeline spawns
+	if err := env.Expand(cfg.Env, templateCtx); err != nil {
+		return nil, err
+	}
+	// Redact credentials from the env, including what env_files and the
+	// env section set, and from sensitive config fields in all output
+	redact.Env(cfg.MaskEnv)
+	redact.Config(cfg)
+	log.SetOutput(env.NewMaskingWriter(os.Stderr, nil))
+
+	// Releases authenticating as a GitHub App get their token up front;
+	// builds that publish nothing leave it alone
+	if !opts.Snapshot && !opts.DryRun && !opts.Prepare && !skip["publish"] {
+		if err := useGitHubApp(ctx, cfg.Release.GitHub, templateCtx); err != nil {
+			return nil, err
+		}
+	}
+
 	// Create artifact manager
 	artifacts := artifact.NewManager()
 
@@ -105,7 +214,7 @@ func New(ctx context.Context, opts ReleaseOptions) (*Pipeline, error) {
 
 	// Initialize build cache if not skipped
 	var buildCache *cache.BuildCache
-	if !opts.SkipCache {
+	if !skip["cache"] {
 		cacheOpts := cache.DefaultOptions()
 		buildCache, _ = cache.NewBuildCache(cacheOpts)
 		if buildCache != nil {
@@ -113,50 +222,247 @@ func New(ctx context.Context, opts ReleaseOptions) (*Pipeline, error) {
 		}
 	}
 
-	return &Pipeline{
+	plugins, err := plugin.NewDispatcher(cfg.Plugins)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &Pipeline{
 		config:      cfg,
 		options:     opts,
 		artifacts:   artifacts,
 		gitInfo:     gitInfo,
 		templateCtx: templateCtx,
 		buildCache:  buildCache,
+		configPath:  cfgPath,
 		distDir:     distDir,
 		startTime:   time.Now(),
-	}, nil
+		skip:        skip,
+		single:      single,
+		plugins:     plugins,
+	}
+
+	// Publishing replaces this with the URL the forge returns
+	p.templateCtx.Set("ReleaseURL", p.releaseURL())
+	// The notes stage fills these in
+	p.templateCtx.Set("Changelog", "")
+	p.templateCtx.Set("Checksums", "")
+	p.setRepository()
+	if err := p.checkTemplates(); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// configureHTTP applies the http settings to every outbound request
+func configureHTTP(cfg config.HTTPConfig) error {
+	var timeout, initialDelay, maxDelay time.Duration
+	for _, d := range []struct {
+		name  string
+		value string
+		out   *time.Duration
+	}{
+		{"timeout", cfg.Timeout, &timeout},
+		{"retry.initial_delay", cfg.Retry.InitialDelay, &initialDelay},
+		{"retry.max_delay", cfg.Retry.MaxDelay, &maxDelay},
+	} {
+		if d.value == "" {
+			continue
+		}
+		parsed, err := time.ParseDuration(d.value)
+		if err != nil {
+			return fmt.Errorf("invalid %s %q: %w", d.name, d.value, err)
+		}
+		*d.out = parsed
+	}
+	retry.SetDefaults(cfg.Retry.Attempts, initialDelay, maxDelay)
+	return httpclient.Configure(httpclient.Options{CABundle: cfg.CABundle, Timeout: timeout})
+}
+
+// useGitHubApp authenticates GitHub requests as the configured app, if any
+func useGitHubApp(ctx context.Context, repo config.ReleaseRepo, tmplCtx *tmpl.Context) error {
+	auth := repo.Auth
+	if auth.AppID == "" {
+		return nil
+	}
+	app, err := githubApp(auth, tmplCtx)
+	if err != nil {
+		return err
+	}
+	owner, _ := tmplCtx.Apply("release.github.owner", repo.Owner)
+	name, _ := tmplCtx.Apply("release.github.name", repo.Name)
+	return github.UseApp(ctx, app, owner, name)
+}
+
+// githubApp renders the app settings and loads its private key
+func githubApp(auth config.GitHubAppAuth, tmplCtx *tmpl.Context) (github.App, error) {
+	var app github.App
+	for _, f := range []struct {
+		name  string
+		value string
+		out   *int64
+	}{
+		{"app_id", auth.AppID, &app.ID},
+		{"installation_id", auth.InstallationID, &app.InstallationID},
+	} {
+		rendered, err := tmplCtx.Apply("release.github.auth."+f.name, f.value)
+		if err != nil {
+			return app, fmt.Errorf("github app %s: %w", f.name, err)
+		}
+		if rendered == "" {
+			continue
+		}
+		if *f.out, err = strconv.ParseInt(strings.TrimSpace(rendered), 10, 64); err != nil {
+			return app, fmt.Errorf("github app: invalid %s %q", f.name, rendered)
+		}
+	}
+
+	key := auth.PrivateKey
+	if auth.PrivateKeyEnv != "" {
+		key = os.Getenv(auth.PrivateKeyEnv)
+		if key == "" {
+			return app, fmt.Errorf("github app: %s is not set", auth.PrivateKeyEnv)
+		}
+	}
+	key, err := tmplCtx.Apply("release.github.auth.private_key", key)
+	if err != nil {
+		return app, fmt.Errorf("github app private_key: %w", err)
+	}
+	switch {
+	case key == "":
+		return app, fmt.Errorf("github app: private_key or private_key_env is required")
+	case strings.Contains(key, "-----BEGIN"):
+		app.PrivateKey = []byte(key)
+	default:
+		data, err := os.ReadFile(key)
+		if err != nil {
+			return app, fmt.Errorf("github app: failed to read private key: %w", err)
+		}
+		app.PrivateKey = data
+	}
+	return app, nil
+}
+
+// setRepository exposes the release repository to templates as .RepoOwner,
+// .RepoName, .RepoURL and .ReleaseDownloadURL, so default download URLs work without
+// GITHUB_OWNER and GITHUB_REPO. It uses release.github, then those env
+// vars, then the origin remote.
+func (p *Pipeline) setRepository() {
+	web := github.CurrentURLs().Download
+	owner, _ := p.templateCtx.Apply("release.github.owner", p.config.Release.GitHub.Owner)
+	name, _ := p.templateCtx.Apply("release.github.name", p.config.Release.GitHub.Name)
+	if owner == "" || name == "" {
+		owner, name = os.Getenv("GITHUB_OWNER"), os.Getenv("GITHUB_REPO")
+	}
+	if (owner == "" || name == "") && p.gitInfo != nil {
+		if h, o, n, ok := git.ParseRemote(p.gitInfo.URL); ok {
+			web, owner, name = "https://"+h, o, n
+		}
+	}
+
+	repoURL, downloadURL := "", ""
+	if owner != "" && name != "" {
+		repoURL = fmt.Sprintf("%s/%s/%s", web, owner, name)
+		downloadURL = repoURL + "/releases/download/" + p.templateCtx.Get("Tag")
+	}
+	p.templateCtx.Set("RepoOwner", owner)
+	p.templateCtx.Set("RepoName", name)
+	p.templateCtx.Set("RepoURL", repoURL)
+	p.templateCtx.Set("ReleaseDownloadURL", downloadURL)
+}
+
+// releaseURL derives the release page URL from the release config
+func (p *Pipeline) releaseURL() string {
+	gitlab := os.Getenv("GITLAB_URL")
+	if gitlab == "" {
+		gitlab = "https://gitlab.com"
+	}
+	gitea := os.Getenv("GITEA_URL")
+	if gitea == "" {
+		gitea = "https://gitea.com"
+	}
+
+	repos := []struct {
+		name string
+		repo config.ReleaseRepo
+		path string
+	}{
+		{"github", p.config.Release.GitHub, github.CurrentURLs().Download + "/%s/%s/releases/tag/%s"},
+		{"gitlab", p.config.Release.GitLab, strings.TrimSuffix(gitlab, "/") + "/%s/%s/-/releases/%s"},
+		{"gitea", p.config.Release.Gitea, strings.TrimSuffix(gitea, "/") + "/%s/%s/releases/tag/%s"},
+	}
+	for _, r := range repos {
+		if r.repo.Owner == "" || r.repo.Name == "" {
+			continue
+		}
+		owner, err := p.templateCtx.Apply("release."+r.name+".owner", r.repo.Owner)
+		if err != nil {
+			continue
+		}
+		name, err := p.templateCtx.Apply("release."+r.name+".name", r.repo.Name)
+		if err != nil {
+			continue
+		}
+		return fmt.Sprintf(r.path, owner, name, p.templateCtx.Get("Tag"))
+	}
+	return ""
 }
 
 // Run executes the full release pipeline
 func (p *Pipeline) Run(ctx context.Context) error {
+	if p.options.DryRun {
+		return p.DryRun(ctx)
+	}
+	if err := p.run(ctx); err != nil {
+		return p.failed(ctx, err)
+	}
+	return nil
+}
+
+// run releases, building, publishing and announcing
+func (p *Pipeline) run(ctx context.Context) error {
 	log.Info("Starting release pipeline", "project", p.config.ProjectName)
 
-	// Run before hooks
-	if err := p.runHooks(ctx, p.config.Before, "before"); err != nil {
+	// Refuse to release binaries that don't match the tag
+	if errs := p.validateGit(ctx); len(errs) > 0 {
+		return fmt.Errorf("git validation failed: %w", errors.Join(errs...))
+	}
+
+	// Fail before the hooks when a credential or tool is missing
+	if err := p.checkRequirements(true); err != nil {
 		return err
 	}
 
+	// Run before hooks
+	if !p.skipped("before") {
+		endGroup := p.group("before")
+		err := p.runHooks(ctx, p.config.Before, "before")
+		endGroup()
+		if err != nil {
+			return err
+		}
+	}
+
 	// Build all artifacts (binaries, archives, packages, checksums, docker)
 	if err := p.BuildAll(ctx); err != nil {
 		return err
 	}
 
-	// If prepare mode, stop here
+	// If prepare mode, stop here; BuildAll saved the state
 	if p.options.Prepare {
-		if err := p.saveState(); err != nil {
-			return err
-		}
 		log.Info("Release prepared. Use 'releaser publish' and 'releaser announce' to continue.")
 		return nil
 	}
 
 	// Publish artifacts
-	if !p.options.SkipPublish {
+	if !p.skipped("publish") {
 		if err := p.Publish(ctx); err != nil {
 			return err
 		}
 	}
 
 	// Announce release
-	if !p.options.SkipAnnounce {
+	if !p.skipped("announce") {
 		if err := p.Announce(ctx); err != nil {
 			return err
 		}
@@ -177,21 +483,27 @@ func (p *Pipeline) Run(ctx context.Context) error {
 func (p *Pipeline) BuildAll(ctx context.Context) error {
 	var allErrors []error
 
-	if p.config.CleanupDistDirs {
-		defer func() {
-			if err := p.cleanupDistDirs(); err != nil {
-				log.Warn("Failed to remove dist folders", "error", err)
-			}
-		}()
+	if err := p.checkRequirements(true); err != nil {
+		return err
 	}
 
-	// Clean dist directory if requested
-	if p.options.Clean {
+	// Clean dist directory if requested or left by an interrupted build
+	if p.aborted() && !p.options.Clean {
+		log.Warn("The previous build was aborted, cleaning dist", "path", p.distDir)
+	}
+	if p.options.Clean || p.aborted() {
 		if err := p.clean(); err != nil {
 			allErrors = append(allErrors, fmt.Errorf("clean failed: %w", err))
 		}
 	}
 
+	// Fail fast if signing cannot succeed
+	if !p.skipped("sign") && !p.options.Snapshot {
+		if err := p.Preflight(); err != nil {
+			return err
+		}
+	}
+
 	// Create dist directory
 	if err := os.MkdirAll(p.distDir, 0755); err != nil {
 		allErrors = append(allErrors, fmt.Errorf("failed to create dist directory: %w", err))
@@ -199,50 +511,45 @@ func (p *Pipeline) BuildAll(ctx context.Context) error {
 		return fmt.Errorf("setup failed: %v", allErrors)
 	}
 
-	// Build artifacts
-	if err := p.Build(ctx); err != nil {
-		allErrors = append(allErrors, err)
-	}
-
-	// Clean up temporary object files
-	_ = os.Remove("-" + ".o")
-
-	// Create archives
-	if err := p.archive(ctx); err != nil {
-		allErrors = append(allErrors, err)
-	}
-
-	// Create packages (nfpm, snapcraft, etc.)
-	if err := p.packages(ctx); err != nil {
-		allErrors = append(allErrors, err)
-	}
-
-	// Create platform-specific packages (macOS, Windows)
-	if err := p.platformPackages(ctx); err != nil {
-		allErrors = append(allErrors, err)
+	for _, st := range p.stages() {
+		if st.skip != "" && p.skipped(st.skip) {
+			log.Debug("Skipping stage", "stage", st.name)
+			continue
+		}
+		if ctx.Err() != nil {
+			return p.abort(st.name, ctx.Err())
+		}
+		endGroup := p.group(st.name)
+		err := st.run(ctx)
+		endGroup()
+		if err != nil {
+			if ctx.Err() != nil {
+				return p.abort(st.name, ctx.Err())
+			}
+			allErrors = append(allErrors, err)
+			continue
+		}
+		if st.event != "" {
+			if err := p.notify(ctx, st.event, st.produces, nil); err != nil {
+				allErrors = append(allErrors, err)
+			}
+		}
 	}
 
-	// Create checksums
-	if err := p.checksum(ctx); err != nil {
+	if err := p.layoutDist(); err != nil {
 		allErrors = append(allErrors, err)
 	}
 
-	// Sign artifacts
-	if !p.options.SkipSign {
-		if err := p.sign(ctx); err != nil {
-			allErrors = append(allErrors, err)
+	// Remove intermediate folders before the state records their artifacts
+	if p.config.CleanupDistDirs {
+		if err := p.cleanupDistDirs(); err != nil {
+			log.Warn("Failed to remove dist folders", "error", err)
 		}
 	}
 
-	// Build Docker images
-	if !p.options.SkipDocker {
-		if err := p.docker(ctx); err != nil {
-			allErrors = append(allErrors, err)
-		}
-
-		if err := p.dockerExports(); err != nil {
-			allErrors = append(allErrors, err)
-		}
+	// Save state for publish, announce and rerunning single stages
+	if err := p.saveState(); err != nil {
+		allErrors = append(allErrors, err)
 	}
 
 	if len(allErrors) > 0 {
@@ -268,60 +575,70 @@ func (p *Pipeline) Build(ctx context.Context) error {
 	}
 
 	// Create a build context with overall timeout to prevent deadlocks
-	var buildCtx context.Context
-	var cancel context.CancelFunc
-	if p.options.Timeout != "" {
-		timeout, err := time.ParseDuration(p.options.Timeout)
-		if err != nil {
-			// Default timeout if parsing fails
-			timeout = 2 * time.Hour
-		}
-		buildCtx, cancel = context.WithTimeout(ctx, timeout)
-	} else {
-		// Default 2 hour timeout for builds
-		buildCtx, cancel = context.WithTimeout(ctx, 2*time.Hour)
+	overall, err := stageTimeout(p.options.Timeout, p.config.Timeouts.Build, 2*time.Hour)
+	if err != nil {
+		return fmt.Errorf("invalid build timeout: %w", err)
+	}
+	perTarget, err := stageTimeout("", p.config.Timeouts.PerTarget, 30*time.Minute)
+	if err != nil {
+		return fmt.Errorf("invalid per-target timeout: %w", err)
 	}
+	buildCtx, cancel := withTimeout(ctx, overall)
 	defer cancel()
 
-	// Filter targets if single-target mode
-	targets := p.getTargets()
-	if p.options.SingleTarget != "" {
-		filtered := []BuildTarget{}
-		for _, t := range targets {
-			if t.String() == p.options.SingleTarget {
-				filtered = append(filtered, t)
-			}
+	// Fail on targets a builder cannot produce before building any
+	targets := make([][]target.Target, len(p.config.Builds))
+	count := 0
+	for i, build := range p.config.Builds {
+		if build.Skip || !p.selected(build.ID) {
+			continue
 		}
-		if len(filtered) == 0 {
-			return fmt.Errorf("target %s not found", p.options.SingleTarget)
+		targets[i] = p.selectedTargets(build)
+		count += len(targets[i])
+		for _, t := range targets[i] {
+			if err := builder.CheckTarget(build.Builder, t); err != nil {
+				return fmt.Errorf("build %s: %w", build.ID, err)
+			}
 		}
-		targets = filtered
+	}
+	if count == 0 && p.single != nil {
+		return p.targetNotFound()
+	}
+	// Fail before building when two outputs would overwrite each other
+	if err := p.checkCollisions(p.plannedBinaries()); err != nil {
+		return err
 	}
 
 	// Build each target
 	sem := make(chan struct{}, p.options.Parallelism)
-	errCh := make(chan error, len(targets))
+	errCh := make(chan error, count)
 	var wg sync.WaitGroup
 
 	// Use the build context with timeout for all operations
 	ctx = buildCtx
 
-	for _, build := range p.config.Builds {
-		if build.Skip {
+	// gomobile builds bind all architectures of a platform at once
+	var mobile []config.Build
+	for i, build := range p.config.Builds {
+		if build.Skip || !p.selected(build.ID) {
+			continue
+		}
+		build, err := p.obfuscation(build)
+		if err != nil {
+			return err
+		}
+		if build.Builder == "gomobile" {
+			mobile = append(mobile, build)
 			continue
 		}
 
-		for _, target := range targets {
-			if !p.shouldBuild(build, target) {
-				continue
-			}
-
+		for _, t := range targets[i] {
 			wg.Add(1)
-			go func(b config.Build, t BuildTarget) {
+			go func(b config.Build, t target.Target) {
 				defer wg.Done()
 
 				// Create a timeout context for this build to prevent deadlock
-				buildCtx, cancel := context.WithTimeout(ctx, 30*time.Minute)
+				buildCtx, cancel := withTimeout(ctx, perTarget)
 				defer cancel()
 
 				// Acquire semaphore with context awareness to prevent deadlock
@@ -338,6 +655,12 @@ func (p *Pipeline) Build(ctx context.Context) error {
 				defer func() { <-sem }()
 
 				if err := p.buildTarget(buildCtx, b, t); err != nil {
+					name := fmt.Sprintf("build %s for %s", b.ID, t.String())
+					if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+						err = timeoutError(ctx, err, name, "build", overall)
+					} else {
+						err = timeoutError(buildCtx, err, name, "per_target", perTarget)
+					}
 					if p.options.Silent {
 						log.Error(fmt.Sprintf("Build failed for %s %s: %s", b.ID, t.String(), err.Error()))
 					} else {
@@ -347,7 +670,7 @@ func (p *Pipeline) Build(ctx context.Context) error {
 						}
 					}
 				}
-			}(build, target)
+			}(build, t)
 		}
 	}
 
@@ -376,6 +699,12 @@ func (p *Pipeline) Build(ctx context.Context) error {
 
 collect_done:
 
+	for _, build := range mobile {
+		if err := p.buildMobile(ctx, build); err != nil {
+			errs = append(errs, fmt.Errorf("build %s failed: %w", build.ID, err))
+		}
+	}
+
 	if len(errs) > 0 {
 		if p.options.Silent {
 			log.Warn("Some builds failed", "count", len(errs))
@@ -390,41 +719,143 @@ collect_done:
 
 // Publish publishes all artifacts
 func (p *Pipeline) Publish(ctx context.Context) error {
+	defer p.group("publish")()
 	log.Info("Publishing artifacts")
 
+	if err := p.checkRequirements(false); err != nil {
+		return err
+	}
+
+	limit, err := stageTimeout("", p.config.Timeouts.Publish, 0)
+	if err != nil {
+		return fmt.Errorf("invalid publish timeout: %w", err)
+	}
+	ctx, cancel := withTimeout(ctx, limit)
+	defer cancel()
+
 	// Load state if continuing from prepare
-	if err := p.loadState(); err != nil {
-		log.Debug("No saved state found, using current artifacts")
+	if p.artifacts.Count() == 0 {
+		if err := p.loadState(); err != nil {
+			if !errors.Is(err, errNoState) {
+				return err
+			}
+			log.Debug("No saved state found, using current artifacts")
+		}
 	}
 
+	// Each publisher runs even when another failed, unless
+	// publish.fail_fast is set; the summary lists what happened
+	s := p.newSummary()
+	defer s.write(os.Stderr, "Publish summary")
+
 	// Publish to release platforms
-	if err := p.publishRelease(ctx); err != nil {
-		return err
+	if err := p.publishRelease(ctx, s); err != nil {
+		return timeoutError(ctx, err, "publish", "publish", limit)
 	}
 
 	// Publish Docker images
-	if !p.options.SkipDocker {
-		if err := p.publishDocker(ctx); err != nil {
-			return err
+	if !p.skipped("docker") && len(p.config.Dockers) > 0 {
+		if err := s.run(ctx, "docker", "", func() error {
+			return p.withDockerTimeout(ctx, p.publishDocker)
+		}); err != nil {
+			return timeoutError(ctx, err, "publish", "publish", limit)
 		}
 	}
 
 	// Publish to package managers
-	if err := p.publishPackages(ctx); err != nil {
-		return err
+	if err := p.publishPackages(ctx, s); err != nil {
+		return timeoutError(ctx, err, "publish", "publish", limit)
 	}
 
+	// Update the docs once everything they list is published
+	if err := p.publishDocs(ctx, s); err != nil {
+		return timeoutError(ctx, err, "publish", "publish", limit)
+	}
+
+	if len(p.config.Milestones) > 0 {
+		if err := s.run(ctx, "milestones", "", func() error {
+			return p.closeMilestones(ctx)
+		}); err != nil {
+			return timeoutError(ctx, err, "publish", "publish", limit)
+		}
+	}
+
+	if err := s.err(); err != nil {
+		return timeoutError(ctx, err, "publish", "publish", limit)
+	}
+	if err := p.notify(ctx, plugin.EventPublishDone, nil, nil); err != nil {
+		return err
+	}
 	log.Info("Publishing completed")
 	return nil
 }
 
+// withDockerTimeout runs a docker stage under timeouts.docker
+func (p *Pipeline) withDockerTimeout(ctx context.Context, fn func(context.Context) error) error {
+	limit, err := stageTimeout("", p.config.Timeouts.Docker, 0)
+	if err != nil {
+		return fmt.Errorf("invalid docker timeout: %w", err)
+	}
+	dockerCtx, cancel := withTimeout(ctx, limit)
+	defer cancel()
+
+	err = fn(dockerCtx)
+	if ctx.Err() != nil {
+		// The enclosing stage ran out of time; let it report the error
+		return err
+	}
+	return timeoutError(dockerCtx, err, "docker", "docker", limit)
+}
+
+// stageTimeout resolves a stage time limit. The override (from the CLI)
+// wins over the configured value, and def applies when neither is set. A
+// zero duration means no limit.
+func stageTimeout(override, configured string, def time.Duration) (time.Duration, error) {
+	value := override
+	if value == "" {
+		value = configured
+	}
+	if value == "" {
+		return def, nil
+	}
+	return time.ParseDuration(value)
+}
+
+// withTimeout bounds ctx by d, leaving it unbounded when d is zero
+func withTimeout(ctx context.Context, d time.Duration) (context.Context, context.CancelFunc) {
+	if d <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, d)
+}
+
+// timeoutError names the stage and limit when err was caused by ctx
+// reaching its deadline
+func timeoutError(ctx context.Context, err error, stage, key string, d time.Duration) error {
+	if err == nil || !errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return err
+	}
+	return fmt.Errorf("%s exceeded the %s limit (timeouts.%s): %w", stage, d, key, err)
+}
+
 // Announce announces the release
 func (p *Pipeline) Announce(ctx context.Context) error {
+	defer p.group("announce")()
 	log.Info("Announcing release")
 
 	// Load state if continuing from prepare
-	if err := p.loadState(); err != nil {
-		log.Debug("No saved state found")
+	if p.artifacts.Count() == 0 {
+		if err := p.loadState(); err != nil {
+			if !errors.Is(err, errNoState) {
+				return err
+			}
+			log.Debug("No saved state found")
+		}
+	}
+
+	// Only announce a release users can go install
+	if err := p.goModProxy(ctx); err != nil {
+		return fmt.Errorf("gomod: %w", err)
 	}
 
 	// Run announcements
@@ -439,49 +870,69 @@ func (p *Pipeline) Announce(ctx context.Context) error {
 // Continue continues from a prepared release
 func (p *Pipeline) Continue(ctx context.Context) error {
 	if err := p.Publish(ctx); err != nil {
-		return err
+		return p.failed(ctx, err)
 	}
-	return p.Announce(ctx)
+	if err := p.Announce(ctx); err != nil {
+		return p.failed(ctx, err)
+	}
+	return nil
 }
 
-// BuildTarget represents a build target (OS/arch combination)
-type BuildTarget struct {
-	OS    string
-	Arch  string
-	Arm   string
-	Amd64 string
-	Mips  string
-}
+// applyProfile merges the requested profile over the config. Without
+// --profile, a profile named after the release type is used when defined.
+func applyProfile(cfg *config.Config, opts ReleaseOptions) error {
+	name := opts.Profile
+	if name == "" {
+		switch {
+		case opts.Snapshot:
+			name = "snapshot"
+		case opts.Nightly:
+			name = "nightly"
+		default:
+			name = "stable"
+		}
+		if _, ok := cfg.Profiles[name]; !ok {
+			return nil
+		}
+	}
 
-// String returns the target as a string
-func (t BuildTarget) String() string {
-	s := t.OS + "_" + t.Arch
-	if t.Arm != "" {
-		s += "_" + t.Arm
+	if err := cfg.ApplyProfile(name); err != nil {
+		return err
 	}
-	if t.Amd64 != "" {
-		s += "_" + t.Amd64
+	log.Info("Using config profile", "profile", name)
+	return nil
+}
+
+// parseSingleTarget parses --single-target, where current names the
+// running OS and architecture. Docker images and Linux packages need linux
+// binaries, so their stages are skipped for other targets.
+func parseSingleTarget(value string, skip map[string]bool) (*target.Target, error) {
+	if value == "" {
+		return nil, nil
+	}
+	t := target.Current()
+	if value != "current" {
+		var err error
+		if t, err = target.Parse(value); err != nil {
+			return nil, err
+		}
+	}
+	if t.OS != "linux" {
+		for _, stage := range []string{"docker", "compose", "nfpm"} {
+			if !skip[stage] {
+				log.Info("Skipping stage for single target", "stage", stage, "target", t.String())
+				skip[stage] = true
+			}
+		}
 	}
-	return s
+	return &t, nil
 }
 
 // findConfigFile looks for a configuration file
 func findConfigFile() string {
-	candidates := []string{
-		".releaser.yaml",
-		".releaser.yml",
-		"releaser.yaml",
-		"releaser.yml",
-		".goreleaser.yaml",
-		".goreleaser.yml",
-	}
-
-	for _, c := range candidates {
-		if _, err := os.Stat(c); err == nil {
-			return c
-		}
+	if path := config.Find(); path != "" {
+		return path
 	}
-
 	return ".releaser.yaml"
 }
 
@@ -491,154 +942,179 @@ func (p *Pipeline) clean() error {
 	return os.RemoveAll(p.distDir)
 }
 
-// cleanupDistDirs removes any directories left inside the dist folder, leaving only final artifacts.
-func (p *Pipeline) cleanupDistDirs() error {
-	entries, err := os.ReadDir(p.distDir)
-	if err != nil {
-		if errors.Is(err, os.ErrNotExist) {
-			return nil
-		}
-		return err
+// runHooks runs before/after hooks: nested before hooks, simple commands,
+// hooks with options, then nested after hooks
+func (p *Pipeline) runHooks(ctx context.Context, hooks config.Hooks, phase string) error {
+	workDir, _ := os.Getwd()
+	runner := hook.NewRunner(p.templateCtx, workDir)
+
+	groups := [][]config.Hook{
+		hooks.Before,
+		hook.FromStrings(hooks.Commands),
+		hooks.Hooks,
+		hooks.After,
 	}
-	for _, entry := range entries {
-		if !entry.IsDir() {
+	for _, group := range groups {
+		if len(group) == 0 {
 			continue
 		}
-		if err := os.RemoveAll(filepath.Join(p.distDir, entry.Name())); err != nil {
-			return err
-		}
-	}
-	return nil
-}
-
-// runHooks runs before/after hooks
-func (p *Pipeline) runHooks(ctx context.Context, hooks config.Hooks, phase string) error {
-	// Run simple commands
-	for _, cmd := range hooks.Commands {
-		log.Debug("Running hook", "phase", phase, "cmd", cmd)
-		if err := p.runCommand(ctx, cmd, ""); err != nil {
-			return fmt.Errorf("hook %s failed: %w", cmd, err)
+		log.Debug("Running hooks", "phase", phase, "count", len(group))
+		if err := runner.RunHooks(ctx, group); err != nil {
+			return fmt.Errorf("%s hook failed: %w", phase, err)
 		}
 	}
 
-	// Run hooks with options
-	for _, hook := range hooks.Hooks {
-		log.Debug("Running hook", "phase", phase, "cmd", hook.Cmd)
-		if err := p.runCommand(ctx, hook.Cmd, hook.Dir); err != nil {
-			return fmt.Errorf("hook %s failed: %w", hook.Cmd, err)
-		}
+	if failures := runner.Failures(); len(failures) > 0 {
+		log.Warn("Some hooks failed", "phase", phase, "failed", len(failures))
 	}
 
 	return nil
 }
 
-// runCommand runs a shell command
-func (p *Pipeline) runCommand(ctx context.Context, cmd, dir string) error {
-	// Template the command
-	cmd, err := p.templateCtx.Apply(cmd)
-	if err != nil {
-		return err
-	}
-
-	log.Debug("Running command", "cmd", cmd, "dir", dir)
-
-	// Determine working directory
-	workDir := dir
-	if workDir == "" {
-		workDir, _ = os.Getwd()
-	}
+// defaultGoos and defaultGoarch are built when a build lists none
+var (
+	defaultGoos   = []string{"linux", "darwin", "windows"}
+	defaultGoarch = []string{"amd64", "arm64"}
+)
 
-	// Get shell from environment
-	shell := os.Getenv("SHELL")
-	if shell == "" {
-		if runtime.GOOS == "windows" {
-			shell = "powershell.exe"
-		} else {
-			shell = "/bin/sh"
+// getTargets returns the targets of a build: each goos and goarch pair Go
+// supports, multiplied by the goarm, goamd64 and gomips lists for arm,
+// amd64 and mips architectures, without the ignored ones. A targets list
+// replaces the matrix.
+func (p *Pipeline) getTargets(build config.Build) []target.Target {
+	if len(build.Targets) > 0 {
+		var targets []target.Target
+		for _, name := range build.Targets {
+			// Config validation rejects names that do not parse
+			t, err := target.Parse(name)
+			if err != nil || ignored(build, t) {
+				continue
+			}
+			targets = append(targets, t)
 		}
+		return targets
 	}
 
-	// Run command through shell
-	var execCmd *exec.Cmd
-	if runtime.GOOS == "windows" {
-		execCmd = exec.CommandContext(ctx, shell, "-Command", cmd)
-	} else {
-		execCmd = exec.CommandContext(ctx, shell, "-c", cmd)
+	goos, goarch := build.Goos, build.Goarch
+	if len(goos) == 0 {
+		goos = defaultGoos
 	}
-	execCmd.Dir = workDir
-	execCmd.Stdout = os.Stdout
-	execCmd.Stderr = os.Stderr
-	execCmd.Env = os.Environ()
-
-	if err := execCmd.Run(); err != nil {
-		return fmt.Errorf("command failed: %w", err)
+	if len(goarch) == 0 {
+		goarch = defaultGoarch
 	}
 
-	return nil
-}
-
-// getTargets returns all build targets
-func (p *Pipeline) getTargets() []BuildTarget {
-	var targets []BuildTarget
-
-	// Default targets if none specified
-	defaultGoos := []string{"linux", "darwin", "windows"}
-	defaultGoarch := []string{"amd64", "arm64"}
-
-	for _, goos := range defaultGoos {
-		for _, goarch := range defaultGoarch {
-			targets = append(targets, BuildTarget{
-				OS:   goos,
-				Arch: goarch,
-			})
+	var targets []target.Target
+	for _, targetOS := range goos {
+		for _, arch := range goarch {
+			if !target.Valid(targetOS, arch) {
+				log.Debug("Skipping unsupported target", "build", build.ID, "goos", targetOS, "goarch", arch)
+				continue
+			}
+			for _, t := range variants(build, target.Target{OS: targetOS, Arch: arch}) {
+				if !ignored(build, t) {
+					targets = append(targets, t)
+				}
+			}
 		}
 	}
+	return targets
+}
 
+// variants expands a target into one per goarm, goamd64 or gomips value of
+// the build, or returns it unchanged when the build lists none for its
+// architecture
+func variants(build config.Build, t target.Target) []target.Target {
+	var values []string
+	switch t.Arch {
+	case "arm":
+		values = build.Goarm
+	case "amd64":
+		values = build.Goamd64
+	case "mips", "mipsle", "mips64", "mips64le":
+		values = build.Gomips
+	}
+	if len(values) == 0 {
+		return []target.Target{t}
+	}
+
+	targets := make([]target.Target, 0, len(values))
+	for _, v := range values {
+		variant := t
+		switch t.Arch {
+		case "arm":
+			variant.Arm = v
+		case "amd64":
+			variant.Amd64 = v
+		default:
+			variant.Mips = v
+		}
+		targets = append(targets, variant)
+	}
 	return targets
 }
 
-// shouldBuild checks if a target should be built
-func (p *Pipeline) shouldBuild(build config.Build, target BuildTarget) bool {
-	// Check OS filter
-	if len(build.Goos) > 0 {
-		found := false
-		for _, goos := range build.Goos {
-			if goos == target.OS {
-				found = true
-				break
-			}
+// ignored reports whether an ignore rule of the build matches the target.
+// Rules match on goos and goarch, and on goarm and gomips when set.
+func ignored(build config.Build, t target.Target) bool {
+	for _, ignore := range build.Ignore {
+		if ignore.Goos != t.OS || ignore.Goarch != t.Arch {
+			continue
 		}
-		if !found {
-			return false
+		if (ignore.Goarm != "" && ignore.Goarm != t.Arm) || (ignore.Gomips != "" && ignore.Gomips != t.Mips) {
+			continue
 		}
+		return true
 	}
+	return false
+}
 
-	// Check arch filter
-	if len(build.Goarch) > 0 {
-		found := false
-		for _, goarch := range build.Goarch {
-			if goarch == target.Arch {
-				found = true
-				break
-			}
-		}
-		if !found {
-			return false
+// selectedTargets returns the targets of a build, narrowed to
+// --single-target
+func (p *Pipeline) selectedTargets(build config.Build) []target.Target {
+	targets := p.getTargets(build)
+	if p.single == nil {
+		return targets
+	}
+	var filtered []target.Target
+	for _, t := range targets {
+		if t.Matches(*p.single) {
+			filtered = append(filtered, t)
 		}
 	}
+	return filtered
+}
 
-	// Check ignore rules
-	for _, ignore := range build.Ignore {
-		if ignore.Goos == target.OS && ignore.Goarch == target.Arch {
-			return false
+// targetNotFound returns the error for a --single-target no build
+// produces, listing the targets of the selected builds
+func (p *Pipeline) targetNotFound() error {
+	seen := make(map[string]bool)
+	var available []string
+	for _, build := range p.config.Builds {
+		if build.Skip || !p.selected(build.ID) {
+			continue
 		}
+		for _, t := range p.getTargets(build) {
+			if !seen[t.String()] {
+				seen[t.String()] = true
+				available = append(available, t.String())
+			}
+		}
+	}
+	sort.Strings(available)
+	if len(available) == 0 {
+		return fmt.Errorf("target %s not found: no build produces any target", p.options.SingleTarget)
 	}
+	return fmt.Errorf("target %s not found, available targets: %s", p.options.SingleTarget, strings.Join(available, ", "))
+}
 
-	return true
+// buildsFor reports whether the release builds binaries for goos, which
+// is false for other OSes than the one --single-target selects
+func (p *Pipeline) buildsFor(goos string) bool {
+	return p.single == nil || p.single.OS == goos
 }
 
 // buildTarget builds a single target
-func (p *Pipeline) buildTarget(ctx context.Context, build config.Build, target BuildTarget) error {
+func (p *Pipeline) buildTarget(ctx context.Context, build config.Build, target target.Target) error {
 	log.Info("Starting build", "build", build.ID, "target", target.String(), "builder", build.Builder)
 
 	// Create output directory
@@ -648,22 +1124,9 @@ func (p *Pipeline) buildTarget(ctx context.Context, build config.Build, target B
 		return fmt.Errorf("failed to create output directory: %w", err)
 	}
 
-	// Determine binary name
-	binary := build.Binary
-	if binary == "" {
-		binary = p.config.ProjectName
-		log.Debug("Using project name as binary name", "name", binary)
-	}
-	if target.OS == "windows" {
-		binary += ".exe"
-		log.Debug("Adding Windows extension", "binary", binary)
-	}
-
-	// Template the binary name
-	log.Debug("Templating binary name", "template", binary)
-	binary, err := p.templateCtx.Apply(binary)
+	binary, err := p.binaryName(build, target)
 	if err != nil {
-		return fmt.Errorf("failed to template binary name: %w", err)
+		return err
 	}
 	log.Debug("Final binary name", "name", binary)
 
@@ -676,38 +1139,74 @@ func (p *Pipeline) buildTarget(ctx context.Context, build config.Build, target B
 		workDir, _ = os.Getwd()
 	}
 
-	// Check build cache
+	// Check build cache, which holds single binaries only
 	cacheKey := ""
-	if p.buildCache != nil && !p.options.SkipCache {
+	if p.buildCache != nil && !p.skipped("cache") && !builder.PublishesDirectory(build) && !builder.IsLibrary(build.Buildmode) {
 		log.Debug("Checking build cache")
 
 		// Generate cache key from build config, target, and source hash
 		sourcePatterns := []string{"*.go", "go.mod", "go.sum"}
-		if build.Builder == "rust" {
+		switch build.Builder {
+		case "rust":
 			sourcePatterns = []string{"*.rs", "Cargo.toml", "Cargo.lock"}
+		case "node", "npm", "yarn", "pnpm", "deno", "bun":
+			sourcePatterns = []string{"*.js", "*.mjs", "*.cjs", "*.ts", "package.json", "package-lock.json", "yarn.lock", "pnpm-lock.yaml", "deno.json", "deno.lock", "bun.lockb", "bun.lock"}
+		case "dotnet":
+			sourcePatterns = []string{"*.cs", "*.fs", "*.vb", "*.csproj", "*.fsproj", "*.vbproj", "*.sln", "Directory.Build.props", "packages.lock.json"}
 		}
 		log.Debug("Generating cache key", "patterns", sourcePatterns)
-		cacheKey = p.buildCache.BuildKey(target.OS, target.Arch, binary, sourcePatterns)
+		settings := obfuscationKey(build.Obfuscation)
+		if v := target.Variant(); v != "" {
+			settings = append(settings, v)
+		}
+		cacheKey = p.buildCache.BuildKey(target.OS, target.Arch, binary, sourcePatterns, settings...)
 
 		// Check if we have a cached binary
 		if cachedPath, found := p.buildCache.GetBinary(cacheKey); found {
 			log.Info("Cache hit - using cached binary", "target", target.String(), "cache_key", cacheKey)
-			if err := copyFile(cachedPath, outputPath); err == nil {
+			// The cache holds binaries as built, so per-artifact hooks run
+			// on cache hits too
+			if err := p.runArtifactHooks(ctx, build, target, outputPath, workDir, false); err != nil {
+				return err
+			}
+			// Caches written before modes were kept hold binaries as 0644
+			if err := fsutil.CopyFile(cachedPath, outputPath); err == nil && os.Chmod(outputPath, 0755) == nil {
+				if err := p.runArtifactHooks(ctx, build, target, outputPath, workDir, true); err != nil {
+					return err
+				}
+				// The reverse map only depends on the settings in the key
+				if build.Obfuscation.Enabled && build.Obfuscation.ReverseMap {
+					flags, err := builder.GarbleFlags(build.Obfuscation, p.templateCtx)
+					if err != nil {
+						return err
+					}
+					if err := builder.WriteReverseMap(build.Obfuscation, target, outputPath, flags); err != nil {
+						return fmt.Errorf("failed to write reverse map: %w", err)
+					}
+				}
 				// Register artifact
 				p.mu.Lock()
-				p.artifacts.Add(artifact.Artifact{
+				err := p.artifacts.Add(artifact.Artifact{
 					Name:    binary,
 					Path:    outputPath,
 					Type:    artifact.TypeBinary,
 					Goos:    target.OS,
 					Goarch:  target.Arch,
 					Goarm:   target.Arm,
+					Goamd64: target.Amd64,
+					Gomips:  target.Mips,
 					BuildID: build.ID,
 					Extra: map[string]interface{}{
 						"cached": true,
 					},
 				})
+				if m := reverseMap(build, binary, outputPath, target); m != nil && err == nil {
+					err = p.artifacts.Add(*m)
+				}
 				p.mu.Unlock()
+				if err != nil {
+					return err
+				}
 				log.Info("Build completed using cache", "build", build.ID, "target", target.String())
 				return nil
 			} else {
@@ -725,6 +1224,10 @@ func (p *Pipeline) buildTarget(ctx context.Context, build config.Build, target B
 		return err
 	}
 
+	if err := p.runArtifactHooks(ctx, build, target, outputPath, workDir, false); err != nil {
+		return err
+	}
+
 	log.Info("Building from source", "build", build.ID, "target", target.String(), "builder", build.Builder)
 
 	// Build based on builder type
@@ -736,6 +1239,12 @@ func (p *Pipeline) buildTarget(ctx context.Context, build config.Build, target B
 	case "rust":
 		log.Debug("Using Rust builder")
 		buildErr = p.buildRust(ctx, build, target, outputPath)
+	case "node", "npm", "yarn", "pnpm":
+		log.Debug("Using Node.js builder")
+		buildErr = p.buildNode(ctx, build, target, outputPath)
+	case "deno", "bun", "dotnet":
+		log.Debug("Using compiler builder", "builder", build.Builder)
+		buildErr = p.buildWith(ctx, builder.GetBuilder(build.Builder), build, target, outputPath)
 	case "prebuilt":
 		log.Debug("Using prebuilt builder")
 		buildErr = p.copyPrebuilt(ctx, build, target, outputPath)
@@ -749,7 +1258,7 @@ func (p *Pipeline) buildTarget(ctx context.Context, build config.Build, target B
 	}
 
 	// Cache the built binary
-	if p.buildCache != nil && cacheKey != "" && !p.options.SkipCache {
+	if p.buildCache != nil && cacheKey != "" && !p.skipped("cache") {
 		log.Debug("Caching built binary")
 		if err := p.buildCache.PutBinary(cacheKey, outputPath, target.OS, target.Arch); err != nil {
 			log.Warn("Failed to cache binary", "error", err)
@@ -758,23 +1267,299 @@ func (p *Pipeline) buildTarget(ctx context.Context, build config.Build, target B
 		}
 	}
 
-	// Register artifact
-	p.mu.Lock()
-	p.artifacts.Add(artifact.Artifact{
+	if err := p.runArtifactHooks(ctx, build, target, outputPath, workDir, true); err != nil {
+		return err
+	}
+
+	// Register artifact, directory builds being archived as a whole
+	a := artifact.Artifact{
 		Name:    binary,
 		Path:    outputPath,
 		Type:    artifact.TypeBinary,
 		Goos:    target.OS,
 		Goarch:  target.Arch,
 		Goarm:   target.Arm,
+		Goamd64: target.Amd64,
+		Gomips:  target.Mips,
 		BuildID: build.ID,
-	})
-	p.mu.Unlock()
+	}
+	if builder.PublishesDirectory(build) {
+		a.Path = outputDir
+		a.Type = artifact.TypeDirectory
+		a.Extra = map[string]interface{}{"binary": outputPath}
+	}
+	artifacts := []artifact.Artifact{a}
+	if builder.IsLibrary(build.Buildmode) {
+		if build.Library.PkgConfig {
+			if err := p.writePkgConfig(build, target, outputPath); err != nil {
+				return err
+			}
+		}
+		artifacts = libraryArtifacts(a)
+	}
+	if m := reverseMap(build, binary, outputPath, target); m != nil {
+		artifacts = append(artifacts, *m)
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for _, a := range artifacts {
+		if err := p.artifacts.Add(a); err != nil {
+			return err
+		}
+	}
 
 	log.Info("Build completed successfully", "build", build.ID, "target", target.String(), "output", outputPath)
 	return nil
 }
 
+// runArtifactHooks runs a build's pre_per_artifact or post_per_artifact
+// hooks for one binary, failing its target when a hook fails
+func (p *Pipeline) runArtifactHooks(ctx context.Context, build config.Build, target target.Target, outputPath, workDir string, post bool) error {
+	hooks, phase := build.Hooks.PrePerArtifact, "pre_per_artifact"
+	if post {
+		hooks, phase = build.Hooks.PostPerArtifact, "post_per_artifact"
+	}
+	if len(hooks) == 0 {
+		return nil
+	}
+
+	tmplCtx := p.templateCtx.ForArtifact(artifact.Artifact{
+		Name:    filepath.Base(outputPath),
+		Path:    outputPath,
+		Type:    artifact.TypeBinary,
+		Goos:    target.OS,
+		Goarch:  target.Arch,
+		Goarm:   target.Arm,
+		Goamd64: target.Amd64,
+		Gomips:  target.Mips,
+		BuildID: build.ID,
+	})
+	log.Debug("Running per-artifact hooks", "phase", phase, "build", build.ID, "target", target.String())
+	if err := hook.NewRunner(tmplCtx, workDir).RunHooks(ctx, hooks); err != nil {
+		return fmt.Errorf("%s hook failed for %s: %w", phase, target.String(), err)
+	}
+	return nil
+}
+
+// binaryName returns the templated binary name for a target. The template
+// sees the target's .Os, .Arch, .Arm and .Amd64.
+func (p *Pipeline) binaryName(build config.Build, target target.Target) (string, error) {
+	binary := build.Binary
+	if binary == "" {
+		binary = p.config.ProjectName
+	}
+	if builder.IsLibrary(build.Buildmode) {
+		binary = builder.LibraryName(build.Buildmode, target.OS, binary)
+	} else if target.OS == "windows" {
+		binary += ".exe"
+	}
+
+	tmplCtx := p.templateCtx.WithArtifact(binary, target.OS, target.Arch, target.Arm, target.Amd64)
+	binary, err := tmplCtx.Apply(p.buildSource(build)+".binary", binary)
+	if err != nil {
+		return "", fmt.Errorf("failed to template binary name: %w", err)
+	}
+	return binary, nil
+}
+
+// obfuscation resolves the seed of an obfuscated build: one random seed for
+// the whole release, or the rendered seed template
+func (p *Pipeline) obfuscation(build config.Build) (config.Build, error) {
+	if !build.Obfuscation.Enabled {
+		return build, nil
+	}
+	switch seed := build.Obfuscation.Seed; seed {
+	case "", "random":
+		if p.garbleSeed == "" {
+			b := make([]byte, 16)
+			if _, err := rand.Read(b); err != nil {
+				return build, err
+			}
+			p.garbleSeed = base64.RawStdEncoding.EncodeToString(b)
+		}
+		build.Obfuscation.Seed = p.garbleSeed
+	default:
+		seed, err := p.templateCtx.Apply(p.buildSource(build)+".obfuscation.seed", seed)
+		if err != nil {
+			return build, fmt.Errorf("failed to template obfuscation seed: %w", err)
+		}
+		if strings.TrimSpace(seed) == "" {
+			return build, fmt.Errorf("build %s: obfuscation seed is empty", build.ID)
+		}
+		build.Obfuscation.Seed = strings.TrimSpace(seed)
+	}
+	return build, nil
+}
+
+// obfuscationKey returns the obfuscation settings that must be part of the
+// cache key, so an obfuscated build never reuses a plain or differently
+// seeded binary
+func obfuscationKey(cfg config.GoObfuscationConfig) []string {
+	if !cfg.Enabled {
+		return nil
+	}
+	enabled := func(b *bool) string {
+		return fmt.Sprint(b == nil || *b)
+	}
+	return []string{
+		"obfuscation", cfg.Tool, cfg.Seed,
+		"literals=" + enabled(cfg.Literals), "tiny=" + enabled(cfg.Tiny),
+		strings.Join(cfg.Flags, " "), strings.Join(cfg.Env, " "),
+	}
+}
+
+// reverseMap returns the private artifact of the reverse map written for
+// an obfuscated binary, if any
+func reverseMap(build config.Build, binary, outputPath string, target target.Target) *artifact.Artifact {
+	if !build.Obfuscation.Enabled || !build.Obfuscation.ReverseMap {
+		return nil
+	}
+	path := builder.ReverseMapPath(outputPath)
+	if _, err := os.Stat(path); err != nil {
+		return nil
+	}
+	return &artifact.Artifact{
+		Name:    filepath.Base(path),
+		Path:    path,
+		Type:    artifact.TypeObfuscationMap,
+		Goos:    target.OS,
+		Goarch:  target.Arch,
+		Goarm:   target.Arm,
+		Goamd64: target.Amd64,
+		Gomips:  target.Mips,
+		BuildID: build.ID,
+		Extra: map[string]interface{}{
+			"binary":  binary,
+			"private": true,
+		},
+	}
+}
+
+// defaultPkgConfig is the pkg-config file written for library builds
+const defaultPkgConfig = `prefix=/usr/local
+libdir=${prefix}/lib
+includedir=${prefix}/include
+
+Name: {{ .ProjectName }}
+Description: {{ .ProjectName }} library
+Version: {{ .Version }}
+Libs: -L${libdir} -l{{ .LibName }}
+{{- if ne .Os "windows" }}
+Libs.private: -lpthread
+{{- end }}
+Cflags: -I${includedir}
+`
+
+// writePkgConfig writes the pkg-config file of a library next to it, from
+// the build's template or the default one. LibName is the name -l takes.
+func (p *Pipeline) writePkgConfig(build config.Build, target target.Target, library string) error {
+	name := strings.TrimSuffix(filepath.Base(library), filepath.Ext(library))
+	libName := strings.TrimPrefix(name, "lib")
+
+	tmpl := build.Library.PkgConfigTemplate
+	if tmpl == "" {
+		tmpl = defaultPkgConfig
+	}
+	tmplCtx := p.templateCtx.WithArtifact(filepath.Base(library), target.OS, target.Arch, target.Arm, target.Amd64)
+	tmplCtx.Set("LibName", libName)
+	content, err := tmplCtx.Apply(p.buildSource(build)+".library.pkg_config_template", tmpl)
+	if err != nil {
+		return fmt.Errorf("failed to template pkg-config file: %w", err)
+	}
+	return os.WriteFile(filepath.Join(filepath.Dir(library), libName+".pc"), []byte(content), 0644)
+}
+
+// libraryArtifacts returns the artifacts of a library build: the library,
+// the header Go wrote next to it and the pkg-config file, when present
+func libraryArtifacts(lib artifact.Artifact) []artifact.Artifact {
+	lib.Type = artifact.TypeLibrary
+	artifacts := []artifact.Artifact{lib}
+
+	base := strings.TrimSuffix(lib.Path, filepath.Ext(lib.Path))
+	pc := filepath.Join(filepath.Dir(lib.Path), strings.TrimPrefix(filepath.Base(base), "lib")+".pc")
+	for _, file := range []struct {
+		path string
+		typ  artifact.Type
+	}{{base + ".h", artifact.TypeHeader}, {pc, artifact.TypePkgConfig}} {
+		if _, err := os.Stat(file.path); err != nil {
+			continue
+		}
+		a := lib
+		a.Name = filepath.Base(file.path)
+		a.Path = file.path
+		a.Type = file.typ
+		artifacts = append(artifacts, a)
+	}
+	return artifacts
+}
+
+// buildSource names a build in template errors, e.g. builds[0]
+func (p *Pipeline) buildSource(build config.Build) string {
+	for i := range p.config.Builds {
+		if p.config.Builds[i].ID == build.ID {
+			return fmt.Sprintf("builds[%d]", i)
+		}
+	}
+	return "builds." + build.ID
+}
+
+// buildMobile binds a gomobile build for each of its platforms, registering
+// an .aar for android and a zipped .xcframework for ios. iOS needs Xcode, so
+// it is skipped on other systems.
+func (p *Pipeline) buildMobile(ctx context.Context, build config.Build) error {
+	platforms := build.Platforms
+	if len(platforms) == 0 {
+		platforms = []string{"android", "ios"}
+	}
+	name := build.Binary
+	if name == "" {
+		name = "{{ .ProjectName }}-{{ .Version }}"
+	}
+
+	for _, platform := range platforms {
+		var ext string
+		var typ artifact.Type
+		switch platform {
+		case "android":
+			ext, typ = ".aar", artifact.TypeAndroidLibrary
+		case "ios":
+			if runtime.GOOS != "darwin" {
+				log.Warn("Skipping iOS build, it needs macOS with Xcode", "build", build.ID)
+				continue
+			}
+			ext, typ = ".xcframework.zip", artifact.TypeXCFramework
+		default:
+			return fmt.Errorf("unknown gomobile platform %s, use android or ios", platform)
+		}
+
+		tmplCtx := p.templateCtx.WithArtifact("", platform, "", "", "")
+		base, err := tmplCtx.Apply(p.buildSource(build)+".binary", name)
+		if err != nil {
+			return fmt.Errorf("failed to template binary name: %w", err)
+		}
+		output := filepath.Join(p.distDir, build.ID+"_"+platform, base+ext)
+
+		log.Info("Building from source", "build", build.ID, "platform", platform, "builder", build.Builder)
+		if err := builder.NewGomobileBuilder().Build(ctx, build, builder.Target{OS: platform}, output, p.templateCtx); err != nil {
+			return fmt.Errorf("%s: %w", platform, err)
+		}
+
+		p.mu.Lock()
+		err = p.artifacts.Add(artifact.Artifact{
+			Name:    base + ext,
+			Path:    output,
+			Type:    typ,
+			Goos:    platform,
+			BuildID: build.ID,
+		})
+		p.mu.Unlock()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // runBuildInstalls executes install hooks defined for a build prior to compiling.
 func (p *Pipeline) runBuildInstalls(ctx context.Context, build config.Build, workDir string) error {
 	if len(build.Install) == 0 {
@@ -802,8 +1587,12 @@ func (p *Pipeline) runBuildInstalls(ctx context.Context, build config.Build, wor
 			Env:      step.Env,
 			Output:   step.Output,
 			If:       step.If,
-			FailFast: step.FailFast,
-			Shell:    step.Shell,
+			FailFast: strconv.FormatBool(step.FailFast),
+			Shell:    strconv.FormatBool(step.Shell),
+		}
+		// Install steps are quiet unless asked to show output
+		if h.Output == "" {
+			h.Output = hook.OutputDiscard
 		}
 
 		exec := hook.NewExecutor([]config.Hook{h}, p.templateCtx, workDir)
@@ -833,6 +1622,9 @@ func (p *Pipeline) installOpenGL(ctx context.Context) error {
 		log.Debug("pkg-config reports 'gl' present; skipping install")
 		return nil
 	}
+	if deps.Offline {
+		return fmt.Errorf("OpenGL development headers are missing and installing them is disabled offline")
+	}
 
 	goos := runtime.GOOS
 	switch goos {
@@ -920,7 +1712,7 @@ func runLogged(ctx context.Context, args []string) error {
 	if len(args) == 0 {
 		return nil
 	}
-	log.Info("Running install command", "cmd", strings.Join(args, " "))
+	log.Info("Running install command", "cmd", strings.Join(redact.Args(args), " "))
 	cmd := exec.CommandContext(ctx, args[0], args[1:]...)
 	cmd.Stdout = os.Stdout
 	cmd.Stderr = os.Stderr
@@ -929,55 +1721,49 @@ func runLogged(ctx context.Context, args []string) error {
 }
 
 // buildGo builds a Go binary
-func (p *Pipeline) buildGo(ctx context.Context, build config.Build, target BuildTarget, output string) error {
+func (p *Pipeline) buildGo(ctx context.Context, build config.Build, target target.Target, output string) error {
 	log.Debug("Building Go binary", "output", output)
 
 	goBuilder := builder.NewGoBuilder()
-	builderTarget := builder.Target{
-		OS:    target.OS,
-		Arch:  target.Arch,
-		Arm:   target.Arm,
-		Amd64: target.Amd64,
-		Mips:  target.Mips,
-	}
 
-	return goBuilder.Build(ctx, build, builderTarget, output, p.templateCtx)
+	return goBuilder.Build(ctx, build, target, output, p.templateCtx)
 }
 
 // buildRust builds a Rust binary
-func (p *Pipeline) buildRust(ctx context.Context, build config.Build, target BuildTarget, output string) error {
+func (p *Pipeline) buildRust(ctx context.Context, build config.Build, target target.Target, output string) error {
 	log.Debug("Building Rust binary", "output", output)
 
 	rustBuilder := builder.NewRustBuilder()
-	builderTarget := builder.Target{
-		OS:    target.OS,
-		Arch:  target.Arch,
-		Arm:   target.Arm,
-		Amd64: target.Amd64,
-		Mips:  target.Mips,
-	}
 
-	return rustBuilder.Build(ctx, build, builderTarget, output, p.templateCtx)
+	return rustBuilder.Build(ctx, build, target, output, p.templateCtx)
+}
+
+// buildNode builds a Node.js executable
+func (p *Pipeline) buildNode(ctx context.Context, build config.Build, target target.Target, output string) error {
+	log.Debug("Building Node.js executable", "output", output)
+
+	nodeBuilder := builder.NewNodeBuilder()
+
+	return nodeBuilder.Build(ctx, build, target, output, p.templateCtx)
+}
+
+// buildWith builds a binary with a builder that needs no pipeline state
+func (p *Pipeline) buildWith(ctx context.Context, b builder.Builder, build config.Build, target target.Target, output string) error {
+
+	return b.Build(ctx, build, target, output, p.templateCtx)
 }
 
 // copyPrebuilt copies a prebuilt binary
-func (p *Pipeline) copyPrebuilt(ctx context.Context, build config.Build, target BuildTarget, output string) error {
+func (p *Pipeline) copyPrebuilt(ctx context.Context, build config.Build, target target.Target, output string) error {
 	log.Debug("Copying prebuilt binary", "output", output)
 
 	prebuiltBuilder := builder.NewPrebuiltBuilder()
-	builderTarget := builder.Target{
-		OS:    target.OS,
-		Arch:  target.Arch,
-		Arm:   target.Arm,
-		Amd64: target.Amd64,
-		Mips:  target.Mips,
-	}
 
-	return prebuiltBuilder.Build(ctx, build, builderTarget, output, p.templateCtx)
+	return prebuiltBuilder.Build(ctx, build, target, output, p.templateCtx)
 }
 
 // archive creates archives from built artifacts
-func (p *Pipeline) archive(_ context.Context) error {
+func (p *Pipeline) archive(ctx context.Context) error {
 	log.Info("Creating archives")
 
 	if len(p.config.Archives) == 0 {
@@ -985,32 +1771,61 @@ func (p *Pipeline) archive(_ context.Context) error {
 		return nil
 	}
 
-	// Get binary artifacts
-	binaries := p.artifacts.Filter(artifact.ByType(artifact.TypeBinary))
+	// Get binary artifacts, universal binaries being archived on their own,
+	// directory builds with all their files and libraries with their headers
+	binaries := p.artifacts.Filter(func(a artifact.Artifact) bool {
+		switch a.Type {
+		case artifact.TypeBinary, artifact.TypeUniversalBinary, artifact.TypeDirectory,
+			artifact.TypeLibrary, artifact.TypeHeader, artifact.TypePkgConfig:
+			// A previous build may have left other targets in dist
+			return p.single == nil || a.Target().Matches(*p.single)
+		}
+		return false
+	})
 	if len(binaries) == 0 {
 		log.Warn("No binaries to archive")
 		return nil
 	}
 
 	// Create archive creator
-	creator := archive.NewCreator(p.distDir, p.templateCtx)
+	creator := p.archiveCreator()
 
-	// Group binaries by target
-	targetBinaries := make(map[string][]artifact.Artifact)
-	for _, bin := range binaries {
-		key := fmt.Sprintf("%s_%s", bin.Goos, bin.Goarch)
-		targetBinaries[key] = append(targetBinaries[key], bin)
-	}
+	// Archive paths already written, so archives can't overwrite each other
+	written := make(map[string]string)
 
 	// Create archive for each configuration and target
-	for _, archiveCfg := range p.config.Archives {
-		for _, bins := range targetBinaries {
-			arch, err := creator.Create(archiveCfg, bins)
+	for i, archiveCfg := range p.config.Archives {
+		if !p.selected(archiveCfg.ID, archiveCfg.Builds...) {
+			continue
+		}
+		off, err := p.disabled(fmt.Sprintf("archives[%d].disable", i), archiveCfg.Disable)
+		if err != nil {
+			return fmt.Errorf("archive %s: %w", archiveCfg.ID, err)
+		}
+		if off {
+			log.Debug("Archive disabled", "id", archiveCfg.ID)
+			continue
+		}
+		// Each archive only gets the binaries of its builds
+		for _, bins := range archive.Targets(archiveCfg, binaries) {
+			source := fmt.Sprintf("archives[%d]", i)
+			path, _, err := creator.Path(source, archiveCfg, bins[0])
+			if err != nil {
+				return fmt.Errorf("failed to create archive: %w", err)
+			}
+			if other, ok := written[path]; ok {
+				return fmt.Errorf("archives %s and %s both create %s, give them distinct name templates", other, archiveCfg.ID, filepath.Base(path))
+			}
+			written[path] = archiveCfg.ID
+
+			arch, err := creator.Create(ctx, source, archiveCfg, bins)
 			if err != nil {
 				return fmt.Errorf("failed to create archive: %w", err)
 			}
 			if arch != nil {
-				p.artifacts.Add(*arch)
+				if err := p.artifacts.Add(*arch); err != nil {
+					return err
+				}
 			}
 		}
 	}
@@ -1022,13 +1837,17 @@ func (p *Pipeline) archive(_ context.Context) error {
 func (p *Pipeline) packages(ctx context.Context) error {
 	log.Info("Creating packages")
 
-	if len(p.config.NFPMs) == 0 {
+	nfpms, err := p.enabledNFPMs()
+	if err != nil {
+		return err
+	}
+	if len(nfpms) == 0 {
 		log.Debug("No package configurations found")
 		return nil
 	}
 
 	// Create nfpm packager with full config for GUI app support
-	packager := nfpm.NewMultiPackagerWithConfig(p.config.NFPMs, p.config, p.templateCtx, p.artifacts, p.distDir)
+	packager := nfpm.NewMultiPackagerWithConfig(nfpms, p.config, p.templateCtx, p.artifacts, p.distDir)
 	return packager.BuildAll(ctx)
 }
 
@@ -1036,64 +1855,57 @@ func (p *Pipeline) packages(ctx context.Context) error {
 func (p *Pipeline) platformPackages(ctx context.Context) error {
 	log.Info("Creating platform-specific packages")
 
-	// Build macOS Universal Binaries first (before App Bundles)
-	if len(p.config.UniversalBinaries) > 0 {
-		if err := packaging.BuildAllUniversalBinaries(ctx, p.config.UniversalBinaries, p.templateCtx, p.artifacts, p.distDir); err != nil {
-			return fmt.Errorf("failed to build Universal Binaries: %w", err)
-		}
-	}
-
 	// Build macOS App Bundles
-	if len(p.config.AppBundles) > 0 {
+	if len(p.config.AppBundles) > 0 && p.buildsFor("darwin") {
 		if err := packaging.BuildAllAppBundles(ctx, p.config.AppBundles, p.templateCtx, p.artifacts, p.distDir); err != nil {
 			return fmt.Errorf("failed to build App Bundles: %w", err)
 		}
 	}
 
 	// Build macOS DMG images
-	if len(p.config.DMGs) > 0 {
+	if len(p.config.DMGs) > 0 && p.buildsFor("darwin") {
 		if err := packaging.BuildAllDMGs(ctx, p.config.DMGs, p.templateCtx, p.artifacts, p.distDir); err != nil {
 			return fmt.Errorf("failed to build DMGs: %w", err)
 		}
 	}
 
 	// Build macOS PKG installers
-	if len(p.config.PKGs) > 0 {
+	if len(p.config.PKGs) > 0 && p.buildsFor("darwin") {
 		if err := packaging.BuildAllPKGs(ctx, p.config.PKGs, p.templateCtx, p.artifacts, p.distDir); err != nil {
 			return fmt.Errorf("failed to build PKGs: %w", err)
 		}
 	}
 
 	// Build Windows MSI installers
-	if len(p.config.MSIs) > 0 {
-		if err := packaging.BuildAllMSIs(ctx, p.config.MSIs, p.templateCtx, p.artifacts, p.distDir); err != nil {
+	if len(p.config.MSIs) > 0 && p.buildsFor("windows") {
+		if err := packaging.BuildAllMSIs(ctx, p.config, p.templateCtx, p.artifacts, p.distDir); err != nil {
 			return fmt.Errorf("failed to build MSIs: %w", err)
 		}
 	}
 
 	// Build Windows NSIS installers
-	if len(p.config.NSISs) > 0 {
-		if err := packaging.BuildAllNSIS(ctx, p.config.NSISs, p.templateCtx, p.artifacts, p.distDir); err != nil {
+	if len(p.config.NSISs) > 0 && p.buildsFor("windows") {
+		if err := packaging.BuildAllNSIS(ctx, p.config, p.templateCtx, p.artifacts, p.distDir); err != nil {
 			return fmt.Errorf("failed to build NSIS installers: %w", err)
 		}
 	}
 
 	// Build Linux Flatpak packages
-	if len(p.config.Flatpaks) > 0 {
+	if len(p.config.Flatpaks) > 0 && p.buildsFor("linux") {
 		if err := packaging.BuildAllFlatpaks(ctx, p.config, p.templateCtx, p.artifacts, p.distDir); err != nil {
 			return fmt.Errorf("failed to build Flatpaks: %w", err)
 		}
 	}
 
 	// Build Linux AppImage packages
-	if len(p.config.AppImages) > 0 {
+	if len(p.config.AppImages) > 0 && p.buildsFor("linux") {
 		if err := packaging.BuildAllAppImages(ctx, p.config, p.templateCtx, p.artifacts, p.distDir); err != nil {
 			return fmt.Errorf("failed to build AppImages: %w", err)
 		}
 	}
 
 	// Build Linux Snap packages
-	if len(p.config.Snapcrafts) > 0 {
+	if len(p.config.Snapcrafts) > 0 && p.buildsFor("linux") {
 		if err := packaging.BuildAllSnaps(ctx, p.config, p.templateCtx, p.artifacts, p.distDir); err != nil {
 			return fmt.Errorf("failed to build Snaps: %w", err)
 		}
@@ -1102,12 +1914,104 @@ func (p *Pipeline) platformPackages(ctx context.Context) error {
 	return nil
 }
 
+// helmCharts packages the Helm charts of enabled configurations
+func (p *Pipeline) helmCharts(ctx context.Context) error {
+	var helms []config.Helm
+	for i, helmCfg := range p.config.Helms {
+		off, err := p.disabled(fmt.Sprintf("helms[%d].disable", i), helmCfg.Disable)
+		if err != nil {
+			return fmt.Errorf("Helm: %w", err)
+		}
+		if !off {
+			helms = append(helms, helmCfg)
+		}
+	}
+	if len(helms) == 0 {
+		return nil
+	}
+	if err := packaging.BuildAllHelmCharts(ctx, helms, p.templateCtx, p.artifacts, p.distDir); err != nil {
+		return fmt.Errorf("failed to build Helm charts: %w", err)
+	}
+	return nil
+}
+
+// kubernetesManifests builds the install files of enabled Kubernetes
+// configurations
+func (p *Pipeline) kubernetesManifests(ctx context.Context) error {
+	var configs []config.Kubernetes
+	for i, k8sCfg := range p.config.Kubernetes {
+		off, err := p.disabled(fmt.Sprintf("kubernetes[%d].disable", i), k8sCfg.Disable)
+		if err != nil {
+			return fmt.Errorf("Kubernetes: %w", err)
+		}
+		if !off {
+			configs = append(configs, k8sCfg)
+		}
+	}
+	if len(configs) == 0 {
+		return nil
+	}
+	if err := packaging.BuildAllKubernetesManifests(ctx, configs, p.templateCtx, p.artifacts, p.distDir); err != nil {
+		return fmt.Errorf("failed to build Kubernetes manifests: %w", err)
+	}
+	return nil
+}
+
+// universalBinaries merges darwin binaries into universal binaries
+func (p *Pipeline) universalBinaries(ctx context.Context) error {
+	// A single target has no other darwin architecture to merge with
+	if len(p.config.UniversalBinaries) == 0 || p.single != nil {
+		return nil
+	}
+	if err := packaging.BuildAllUniversalBinaries(ctx, p.config.UniversalBinaries, p.templateCtx, p.artifacts, p.distDir); err != nil {
+		return fmt.Errorf("failed to build Universal Binaries: %w", err)
+	}
+	return nil
+}
+
+// upx compresses binaries
+func (p *Pipeline) upx(ctx context.Context) error {
+	if len(p.config.UPXs) == 0 {
+		return nil
+	}
+	log.Info("Compressing binaries")
+
+	compressor := upx.NewMultiCompressor(p.config.UPXs, p.templateCtx, p.artifacts, p.distDir)
+	return compressor.RunAll(ctx)
+}
+
+// sbom generates software bills of materials
+func (p *Pipeline) sbom(ctx context.Context) error {
+	if len(p.config.SBOMs) == 0 {
+		return nil
+	}
+	log.Info("Generating SBOMs")
+
+	generator := sbom.NewMultiGenerator(p.config.SBOMs, p.templateCtx, p.artifacts, p.distDir)
+	return generator.RunAll(ctx)
+}
+
 // checksum creates checksums
-func (p *Pipeline) checksum(_ context.Context) error {
+func (p *Pipeline) checksum(ctx context.Context) error {
 	log.Info("Creating checksums")
 
 	generator := checksum.NewGenerator(p.config.Checksum, p.distDir, p.artifacts, p.templateCtx)
-	return generator.Run()
+	return generator.Run(ctx)
+}
+
+// provenance generates SLSA provenance statements
+func (p *Pipeline) provenance(ctx context.Context) error {
+	generator := provenance.NewGenerator(p.config.Provenance, p.distDir, p.artifacts, p.templateCtx, p.startTime, p.provenanceOptions())
+	return generator.Run(ctx)
+}
+
+// provenanceOptions returns the build inputs recorded in provenance
+func (p *Pipeline) provenanceOptions() provenance.Options {
+	opts := provenance.Options{ConfigPath: p.configPath}
+	if _, err := os.Stat("go.mod"); err == nil {
+		opts.GoMod = "go.mod"
+	}
+	return opts
 }
 
 // sign signs artifacts
@@ -1121,35 +2025,221 @@ func (p *Pipeline) sign(ctx context.Context) error {
 
 	signer := sign.NewSigner(p.distDir, p.templateCtx)
 
-	for _, signCfg := range p.config.Signs {
+	for i, signCfg := range p.config.Signs {
+		off, err := p.disabled(fmt.Sprintf("signs[%d].disable", i), signCfg.Disable)
+		if err != nil {
+			return fmt.Errorf("sign %s: %w", signCfg.ID, err)
+		}
+		if off {
+			log.Debug("Signing disabled", "id", signCfg.ID)
+			continue
+		}
 		allArtifacts := p.artifacts.List()
 		signed, err := signer.Sign(ctx, signCfg, allArtifacts)
 		if err != nil {
 			return fmt.Errorf("signing failed: %w", err)
 		}
 		for _, sig := range signed {
-			p.artifacts.Add(*sig)
+			if err := p.artifacts.Add(*sig); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// cosign signs and attests blob artifacts with cosign
+func (p *Pipeline) cosign(ctx context.Context) error {
+	if len(p.config.Cosigns) == 0 {
+		return nil
+	}
+
+	log.Info("Signing artifacts with cosign")
+
+	signer := sign.NewCosignSigner(p.distDir, p.templateCtx).WithProvenance(p.provenanceOptions())
+	for _, cosignCfg := range p.config.Cosigns {
+		signed, err := signer.SignBlobs(ctx, cosignCfg, p.artifacts.List(), p.startTime)
+		if err != nil {
+			return fmt.Errorf("cosign signing failed: %w", err)
+		}
+		for _, sig := range signed {
+			if err := p.artifacts.Add(*sig); err != nil {
+				return err
+			}
 		}
 	}
 
 	return nil
 }
 
+// Preflight verifies that the configured cosign signing can succeed
+// without building anything
+func (p *Pipeline) Preflight() error {
+	if len(p.config.Cosigns) == 0 {
+		return nil
+	}
+	if err := deps.CheckAndInstall("cosign"); err != nil {
+		log.Warn("Cosign not available", "error", err)
+	}
+
+	signer := sign.NewCosignSigner(p.distDir, p.templateCtx)
+	for _, cosignCfg := range p.config.Cosigns {
+		if err := signer.Preflight(cosignCfg); err != nil {
+			return fmt.Errorf("cosign preflight failed: %w", err)
+		}
+	}
+	return nil
+}
+
 // docker builds Docker images
 func (p *Pipeline) docker(ctx context.Context) error {
 	log.Info("Building Docker images")
 
-	if len(p.config.Dockers) == 0 {
+	dockers, err := p.enabledDockers()
+	if err != nil {
+		return err
+	}
+	if len(dockers) == 0 {
 		log.Debug("No Docker configurations found")
 		return nil
 	}
 
-	dockerBuilder := docker.NewMultiBuilder(p.config.Dockers, p.templateCtx, p.artifacts, p.distDir)
+	dockerBuilder := docker.NewMultiBuilder(dockers, p.templateCtx, p.artifacts, p.distDir)
 	return dockerBuilder.BuildAll(ctx)
 }
 
+// composeFiles writes the Docker Compose files of enabled configurations,
+// pinned to the released image
+func (p *Pipeline) composeFiles(ctx context.Context) error {
+	var configs []config.DockerCompose
+	for i, composeCfg := range p.config.DockerComposes {
+		off, err := p.disabled(fmt.Sprintf("docker_composes[%d].disable", i), composeCfg.Disable)
+		if err != nil {
+			return fmt.Errorf("Docker Compose: %w", err)
+		}
+		if !off {
+			configs = append(configs, composeCfg)
+		}
+	}
+	if len(configs) == 0 {
+		return nil
+	}
+	dockers, err := p.enabledDockers()
+	if err != nil {
+		return err
+	}
+	if err := docker.BuildAllComposeFiles(configs, dockers, p.templateCtx, p.artifacts, p.distDir); err != nil {
+		return fmt.Errorf("failed to build Docker Compose files: %w", err)
+	}
+	return nil
+}
+
+// extraFileTypes are the artifact types extra_files can register as
+var extraFileTypes = map[string]artifact.Type{
+	"":           artifact.TypeUploadable,
+	"uploadable": artifact.TypeUploadable,
+	"metadata":   artifact.TypeMetadata,
+	"sbom":       artifact.TypeSBOM,
+}
+
+// resolveExtraFiles expands the extra_files globs into artifacts named by
+// their name templates. Paths are the matched files; extraFiles copies them
+// into dist.
+func (p *Pipeline) resolveExtraFiles() ([]artifact.Artifact, error) {
+	var files []artifact.Artifact
+	names := make(map[string]string)
+	for i, extra := range p.config.ExtraFiles {
+		source := fmt.Sprintf("extra_files[%d]", i)
+		typ, ok := extraFileTypes[strings.ToLower(extra.Type)]
+		if !ok {
+			return nil, fmt.Errorf("%s: unknown type %q, use uploadable, metadata or sbom", source, extra.Type)
+		}
+		glob, err := p.templateCtx.Apply(source+".glob", extra.Glob)
+		if err != nil {
+			return nil, fmt.Errorf("failed to template extra file glob: %w", err)
+		}
+		matches, err := filepath.Glob(glob)
+		if err != nil {
+			return nil, fmt.Errorf("%s: invalid glob %q: %w", source, glob, err)
+		}
+		if len(matches) == 0 {
+			log.Warn("Extra files glob matched no files", "glob", glob)
+			continue
+		}
+		for _, match := range matches {
+			info, err := os.Stat(match)
+			if err != nil {
+				return nil, err
+			}
+			if info.IsDir() {
+				return nil, fmt.Errorf("%s: %s is a directory, release directories through archives instead", source, match)
+			}
+			name := filepath.Base(match)
+			if extra.NameTemplate != "" {
+				name, err = p.templateCtx.WithArtifactInfo(name, "", "", "", "").Apply(source+".name_template", extra.NameTemplate)
+				if err != nil {
+					return nil, fmt.Errorf("failed to template extra file name: %w", err)
+				}
+				if name = strings.TrimSpace(name); name == "" || strings.ContainsAny(name, `/\`) {
+					return nil, fmt.Errorf("%s: invalid name %q for %s", source, name, match)
+				}
+			}
+			if other, ok := names[name]; ok {
+				return nil, fmt.Errorf("%s: %s and %s are both named %s", source, other, match, name)
+			}
+			names[name] = match
+			files = append(files, artifact.Artifact{
+				Name:  name,
+				Path:  match,
+				Type:  typ,
+				Extra: map[string]interface{}{"extra_file": true},
+			})
+		}
+	}
+	return files, nil
+}
+
+// extraFiles copies the extra files into dist and registers them, so the
+// checksum, sign and publish stages pick them up
+func (p *Pipeline) extraFiles(ctx context.Context) error {
+	if len(p.config.ExtraFiles) == 0 {
+		return nil
+	}
+	// A rerun replaces the extra files of the previous run
+	p.artifacts.Remove(func(a artifact.Artifact) bool {
+		extra, _ := a.Extra["extra_file"].(bool)
+		return extra
+	})
+
+	files, err := p.resolveExtraFiles()
+	if err != nil {
+		return err
+	}
+	for _, a := range files {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if _, ok := p.artifacts.FindByName(a.Name); ok {
+			return fmt.Errorf("extra file %s has the name of another artifact", a.Name)
+		}
+		dst := filepath.Join(p.distDir, a.Name)
+		if abs, _ := filepath.Abs(a.Path); abs != dst {
+			if err := fsutil.CopyFile(a.Path, dst); err != nil {
+				return fmt.Errorf("failed to copy extra file %s: %w", a.Path, err)
+			}
+		}
+		a.Path = dst
+		if err := p.artifacts.Add(a); err != nil {
+			return err
+		}
+		log.Info("Added extra file", "name", a.Name, "type", a.Type)
+	}
+	return nil
+}
+
 // dockerExports exports built Docker images into tar/tar.gz artifacts.
-func (p *Pipeline) dockerExports() error {
+func (p *Pipeline) dockerExports(ctx context.Context) error {
 	if len(p.config.DockerExports) == 0 {
 		return nil
 	}
@@ -1157,38 +2247,48 @@ func (p *Pipeline) dockerExports() error {
 	log.Info("Exporting Docker images")
 
 	exports := make([]config.DockerExportConfig, 0, len(p.config.DockerExports))
-	for _, exp := range p.config.DockerExports {
-		image, err := p.templateCtx.Apply(exp.Image)
+	for i, exp := range p.config.DockerExports {
+		source := fmt.Sprintf("docker_exports[%d]", i)
+		image, err := p.templateCtx.Apply(source+".image", exp.Image)
 		if err != nil {
 			return fmt.Errorf("failed to template docker export image for %s: %w", exp.ID, err)
 		}
 
-		output, err := p.templateCtx.Apply(exp.Output)
+		output, err := p.templateCtx.Apply(source+".output", exp.Output)
 		if err != nil {
 			return fmt.Errorf("failed to template docker export output for %s: %w", exp.ID, err)
 		}
 
 		format := exp.Format
 		if format != "" {
-			format, err = p.templateCtx.Apply(format)
+			format, err = p.templateCtx.Apply(source+".format", format)
 			if err != nil {
 				return fmt.Errorf("failed to template docker export format for %s: %w", exp.ID, err)
 			}
 		}
 
+		input, err := p.templateCtx.Apply(source+".input", exp.Input)
+		if err != nil {
+			return fmt.Errorf("failed to template docker export input for %s: %w", exp.ID, err)
+		}
+
 		exports = append(exports, config.DockerExportConfig{
-			ID:     exp.ID,
-			Image:  image,
-			Format: format,
-			Output: output,
+			ID:       exp.ID,
+			Image:    image,
+			Format:   format,
+			Output:   output,
+			CLI:      exp.CLI,
+			IDs:      exp.IDs,
+			Input:    input,
+			Platform: exp.Platform,
 		})
 	}
 
-	return docker.ExportAll(exports)
+	return docker.ExportAll(ctx, exports, p.artifacts)
 }
 
 // publishRelease publishes to release platforms
-func (p *Pipeline) publishRelease(ctx context.Context) error {
+func (p *Pipeline) publishRelease(ctx context.Context, s *summary) error {
 	log.Info("Publishing release")
 
 	allArtifacts := p.artifacts.List()
@@ -1199,36 +2299,120 @@ func (p *Pipeline) publishRelease(ctx context.Context) error {
 
 	// Publish to GitHub
 	if p.config.Release.GitHub.Owner != "" {
-		publisher := publish.NewGitHubPublisher(p.config.Release, p.templateCtx)
-		if err := publisher.Publish(ctx, allArtifacts); err != nil {
-			return fmt.Errorf("GitHub publish failed: %w", err)
+		if err := s.run(ctx, "github", p.config.Release.Disable, func() error {
+			return publish.NewGitHubPublisher(p.config.Release, p.templateCtx).WithParallelism(p.options.Parallelism).Publish(ctx, allArtifacts)
+		}); err != nil {
+			return err
 		}
 	}
 
 	// Publish to Homebrew
-	for _, brewCfg := range p.config.Brews {
-		publisher := publish.NewHomebrewPublisher(brewCfg, p.templateCtx)
-		if err := publisher.Publish(ctx, allArtifacts); err != nil {
-			return fmt.Errorf("Homebrew publish failed: %w", err)
+	for i, brewCfg := range p.config.Brews {
+		if err := s.runUnlessPrerelease(ctx, itemName("brews", i, len(p.config.Brews)), brewCfg.Disable, brewCfg.SkipPrerelease, func() error {
+			return publish.NewHomebrewPublisher(brewCfg, p.templateCtx).Publish(ctx, allArtifacts)
+		}); err != nil {
+			return err
+		}
+	}
+
+	// Publish Homebrew casks
+	for i, caskCfg := range p.config.Casks {
+		if err := s.run(ctx, itemName("casks", i, len(p.config.Casks)), caskCfg.Disable, func() error {
+			return publish.NewCaskPublisher(caskCfg, p.templateCtx).Publish(ctx, allArtifacts)
+		}); err != nil {
+			return err
+		}
+	}
+
+	// Publish to blob storage
+	for i, blobCfg := range p.config.Blobs {
+		files := publish.BlobArtifacts(blobCfg, allArtifacts)
+		if len(files) == 0 {
+			log.Warn("No artifacts to upload to blob storage", "bucket", blobCfg.Bucket)
+			continue
+		}
+		if err := s.run(ctx, itemName("blobs", i, len(p.config.Blobs)), "", func() error {
+			publisher, err := publish.NewBlobPublisher(blobCfg, p.templateCtx)
+			if err != nil {
+				return err
+			}
+			paths := make([]string, 0, len(files))
+			for _, a := range files {
+				paths = append(paths, a.Path)
+			}
+			if err := publish.Confirm(publish.Action{
+				Description: fmt.Sprintf("Upload %d files to the %s bucket %s", len(files), blobCfg.Provider, blobCfg.Bucket),
+				Files:       paths,
+			}); err != nil {
+				return err
+			}
+			return publisher.Publish(ctx, files)
+		}); err != nil {
+			return err
 		}
 	}
 
 	return nil
 }
 
+// closeMilestones closes the milestones configured with close: true, in
+// the release repository unless another one is set
+func (p *Pipeline) closeMilestones(ctx context.Context) error {
+	for _, cfg := range p.config.Milestones {
+		if !cfg.Close {
+			continue
+		}
+		if cfg.Repo.Owner == "" && cfg.Repo.Name == "" {
+			cfg.Repo.Owner = p.config.Release.GitHub.Owner
+			cfg.Repo.Name = p.config.Release.GitHub.Name
+		}
+		if err := publish.NewMilestoneCloser(cfg, p.templateCtx).Close(ctx); err != nil {
+			if cfg.FailOnError || errors.Is(err, publish.ErrDeclined) || errors.Is(err, publish.ErrQuit) {
+				return fmt.Errorf("milestone: %w", err)
+			}
+			log.Warn("Failed to close milestone", "error", err)
+		}
+	}
+	return nil
+}
+
 // publishDocker publishes Docker images
 func (p *Pipeline) publishDocker(ctx context.Context) error {
 	log.Info("Publishing Docker images")
 
-	if len(p.config.Dockers) == 0 {
+	dockers, err := p.enabledDockers()
+	if err != nil {
+		return err
+	}
+	if len(dockers) == 0 {
 		return nil
 	}
 
-	dockerBuilder := docker.NewMultiBuilder(p.config.Dockers, p.templateCtx, p.artifacts, p.distDir)
+	var images []string
+	for _, a := range p.artifacts.Filter(artifact.ByType(artifact.TypeDockerImage)) {
+		images = append(images, a.Name)
+	}
+	if err := publish.Confirm(publish.Action{Description: "Push the Docker images and manifests", Files: images}); err != nil {
+		return err
+	}
+
+	dockerBuilder := docker.NewMultiBuilder(dockers, p.templateCtx, p.artifacts, p.distDir).
+		WithParallelism(p.options.Parallelism)
 	if err := dockerBuilder.PushAll(ctx); err != nil {
+		// Record the images pushed so publishing again skips them
+		if saveErr := p.saveState(); saveErr != nil {
+			log.Warn("Failed to record pushed Docker images", "error", saveErr)
+		}
 		return err
 	}
 
+	// Create and push multi-arch manifests
+	for _, manifestCfg := range p.config.DockerManifests {
+		if err := docker.NewManifestBuilder(manifestCfg, p.templateCtx, p.artifacts).Run(ctx); err != nil {
+			return fmt.Errorf("docker manifest failed: %w", err)
+		}
+	}
+
 	// Sign Docker images if configured
 	if len(p.config.DockerSigns) > 0 {
 		signer := docker.NewDockerSigner(p.config.DockerSigns, p.templateCtx, p.artifacts)
@@ -1237,116 +2421,149 @@ func (p *Pipeline) publishDocker(ctx context.Context) error {
 		}
 	}
 
+	// Sign and attest pushed images with cosign
+	if !p.skipped("sign") {
+		signer := sign.NewCosignSigner(p.distDir, p.templateCtx).WithProvenance(p.provenanceOptions())
+		for _, cosignCfg := range p.config.Cosigns {
+			if err := signer.SignImages(ctx, cosignCfg, p.artifacts.List(), p.startTime); err != nil {
+				return fmt.Errorf("cosign image signing failed: %w", err)
+			}
+		}
+	}
+
 	return nil
 }
 
 // publishPackages publishes to package managers
-func (p *Pipeline) publishPackages(ctx context.Context) error {
+func (p *Pipeline) publishPackages(ctx context.Context, s *summary) error {
 	log.Info("Publishing packages")
 
 	allArtifacts := p.artifacts.List()
 
 	// Publish to NPM
-	for _, npmCfg := range p.config.NPMs {
-		publisher := publish.NewNPMPublisher(npmCfg, p.templateCtx)
-		if err := publisher.Publish(ctx, allArtifacts); err != nil {
-			return fmt.Errorf("NPM publish failed: %w", err)
-		}
-	}
-
-	// Publish to CloudSmith
-	for _, cloudsmithCfg := range p.config.CloudSmiths {
-		publisher := publish.NewCloudSmithPublisher(cloudsmithCfg, p.templateCtx)
-		if err := publisher.Publish(ctx, allArtifacts); err != nil {
-			return fmt.Errorf("CloudSmith publish failed: %w", err)
+	for i, npmCfg := range p.config.NPMs {
+		if err := s.run(ctx, itemName("npms", i, len(p.config.NPMs)), npmCfg.Disable, func() error {
+			return publish.NewNPMPublisher(npmCfg, p.templateCtx, p.distDir).Publish(ctx, allArtifacts)
+		}); err != nil {
+			return err
 		}
 	}
 
-	// Publish to Fury
-	for _, furyCfg := range p.config.Furies {
-		publisher := publish.NewFuryPublisher(furyCfg, p.templateCtx)
-		if err := publisher.Publish(ctx, allArtifacts); err != nil {
-			return fmt.Errorf("Fury publish failed: %w", err)
+	// Publish to Scoop
+	for i, scoopCfg := range p.config.Scoops {
+		if err := s.runUnlessPrerelease(ctx, itemName("scoops", i, len(p.config.Scoops)), scoopCfg.Disable, scoopCfg.SkipPrerelease, func() error {
+			return publish.NewScoopPublisher(scoopCfg, p.templateCtx).Publish(ctx, allArtifacts)
+		}); err != nil {
+			return err
 		}
 	}
 
-	// Publish to Scoop
-	for _, scoopCfg := range p.config.Scoops {
-		publisher := publish.NewScoopPublisher(scoopCfg, p.templateCtx)
-		if err := publisher.Publish(ctx, allArtifacts); err != nil {
-			return fmt.Errorf("Scoop publish failed: %w", err)
+	// Publish to the Snap Store
+	for i, snapCfg := range p.config.Snapcrafts {
+		if err := s.run(ctx, itemName("snapcrafts", i, len(p.config.Snapcrafts)), "", func() error {
+			return publish.NewSnapcraftPublisher(snapCfg, p.templateCtx).Publish(ctx, allArtifacts)
+		}); err != nil {
+			return err
 		}
 	}
 
 	// Publish to AUR
-	for _, aurCfg := range p.config.AURs {
-		publisher := publish.NewAURPublisher(aurCfg, p.templateCtx, p.artifacts)
-		if err := publisher.Publish(ctx, allArtifacts); err != nil {
-			return fmt.Errorf("AUR publish failed: %w", err)
+	for i, aurCfg := range p.config.AURs {
+		if err := s.runUnlessPrerelease(ctx, itemName("aurs", i, len(p.config.AURs)), aurCfg.Disable, aurCfg.SkipPrerelease, func() error {
+			return publish.NewAURPublisher(aurCfg, p.templateCtx, p.artifacts).Publish(ctx, allArtifacts)
+		}); err != nil {
+			return err
 		}
 	}
 
 	// Publish to Chocolatey
-	for _, chocoCfg := range p.config.Chocolateys {
-		publisher := publish.NewChocolateyPublisher(chocoCfg, p.templateCtx, p.artifacts)
-		if err := publisher.Publish(ctx, allArtifacts); err != nil {
-			return fmt.Errorf("Chocolatey publish failed: %w", err)
+	for i, chocoCfg := range p.config.Chocolateys {
+		if err := s.runUnlessPrerelease(ctx, itemName("chocolateys", i, len(p.config.Chocolateys)), chocoCfg.Disable, chocoCfg.SkipPrerelease, func() error {
+			return publish.NewChocolateyPublisher(chocoCfg, p.templateCtx, p.artifacts).Publish(ctx, allArtifacts)
+		}); err != nil {
+			return err
 		}
 	}
 
 	// Publish to Winget
-	for _, wingetCfg := range p.config.Wingets {
-		publisher := publish.NewWingetPublisher(wingetCfg, p.templateCtx, p.artifacts)
-		if err := publisher.Publish(ctx, allArtifacts); err != nil {
-			return fmt.Errorf("Winget publish failed: %w", err)
+	for i, wingetCfg := range p.config.Wingets {
+		if err := s.runUnlessPrerelease(ctx, itemName("wingets", i, len(p.config.Wingets)), wingetCfg.Disable, wingetCfg.SkipPrerelease, func() error {
+			return publish.NewWingetPublisher(wingetCfg, p.templateCtx, p.artifacts).Publish(ctx, allArtifacts)
+		}); err != nil {
+			return err
 		}
 	}
 
 	// Publish to crates.io
-	for _, crateCfg := range p.config.Crates {
-		publisher := publish.NewCratePublisher(crateCfg, p.templateCtx)
-		if err := publisher.Publish(ctx, allArtifacts); err != nil {
-			return fmt.Errorf("Crate publish failed: %w", err)
+	for i, crateCfg := range p.config.Crates {
+		if err := s.run(ctx, itemName("crates", i, len(p.config.Crates)), crateCfg.Disable, func() error {
+			return publish.NewCratePublisher(crateCfg, p.templateCtx).Publish(ctx, allArtifacts)
+		}); err != nil {
+			return err
 		}
 	}
 
 	// Publish to PyPI
-	for _, pypiCfg := range p.config.PyPIs {
-		publisher := publish.NewPyPIPublisher(pypiCfg, p.templateCtx)
-		if err := publisher.Publish(ctx, allArtifacts); err != nil {
-			return fmt.Errorf("PyPI publish failed: %w", err)
+	for i, pypiCfg := range p.config.PyPIs {
+		if err := s.run(ctx, itemName("pypis", i, len(p.config.PyPIs)), pypiCfg.Disable, func() error {
+			return publish.NewPyPIPublisher(pypiCfg, p.templateCtx, p.artifacts, p.distDir).Publish(ctx, allArtifacts)
+		}); err != nil {
+			return err
 		}
 	}
 
 	// Publish to Maven Central
-	for _, mavenCfg := range p.config.Mavens {
-		publisher := publish.NewMavenPublisher(mavenCfg, p.templateCtx)
-		if err := publisher.Publish(ctx, allArtifacts); err != nil {
-			return fmt.Errorf("Maven publish failed: %w", err)
+	for i, mavenCfg := range p.config.Mavens {
+		if err := s.run(ctx, itemName("mavens", i, len(p.config.Mavens)), mavenCfg.Disable, func() error {
+			return publish.NewMavenPublisher(mavenCfg, p.templateCtx).Publish(ctx, allArtifacts)
+		}); err != nil {
+			return err
 		}
 	}
 
 	// Publish to NuGet
-	for _, nugetCfg := range p.config.NuGets {
-		publisher := publish.NewNuGetPublisher(nugetCfg, p.templateCtx)
-		if err := publisher.Publish(ctx, allArtifacts); err != nil {
-			return fmt.Errorf("NuGet publish failed: %w", err)
+	for i, nugetCfg := range p.config.NuGets {
+		if err := s.run(ctx, itemName("nugets", i, len(p.config.NuGets)), nugetCfg.Disable, func() error {
+			return publish.NewNuGetPublisher(nugetCfg, p.templateCtx).Publish(ctx, allArtifacts)
+		}); err != nil {
+			return err
 		}
 	}
 
 	// Publish to RubyGems
-	for _, gemCfg := range p.config.Gems {
-		publisher := publish.NewGemPublisher(gemCfg, p.templateCtx)
-		if err := publisher.Publish(ctx, allArtifacts); err != nil {
-			return fmt.Errorf("Gem publish failed: %w", err)
+	for i, gemCfg := range p.config.Gems {
+		if err := s.run(ctx, itemName("gems", i, len(p.config.Gems)), gemCfg.Disable, func() error {
+			return publish.NewGemPublisher(gemCfg, p.templateCtx, p.artifacts, p.distDir).Publish(ctx, allArtifacts)
+		}); err != nil {
+			return err
+		}
+	}
+
+	// Publish to CloudSmith and Fury after PyPI and RubyGems, which may
+	// build wheels and gems for them
+	for i, cloudsmithCfg := range p.config.CloudSmiths {
+		if err := s.run(ctx, itemName("cloudsmiths", i, len(p.config.CloudSmiths)), cloudsmithCfg.Disable, func() error {
+			return publish.NewCloudSmithPublisher(cloudsmithCfg, p.templateCtx).Publish(ctx, p.artifacts.List())
+		}); err != nil {
+			return err
+		}
+	}
+
+	// Publish to Fury
+	for i, furyCfg := range p.config.Furies {
+		if err := s.run(ctx, itemName("furies", i, len(p.config.Furies)), furyCfg.Disable, func() error {
+			return publish.NewFuryPublisher(furyCfg, p.templateCtx).Publish(ctx, p.artifacts.List())
+		}); err != nil {
+			return err
 		}
 	}
 
 	// Publish Helm charts
-	for _, helmCfg := range p.config.Helms {
-		publisher := publish.NewHelmPublisher(helmCfg, p.templateCtx)
-		if err := publisher.Publish(ctx, allArtifacts); err != nil {
-			return fmt.Errorf("Helm publish failed: %w", err)
+	for i, helmCfg := range p.config.Helms {
+		if err := s.run(ctx, itemName("helms", i, len(p.config.Helms)), helmCfg.Disable, func() error {
+			return publish.NewHelmPublisher(helmCfg, p.templateCtx, p.distDir).Publish(ctx, allArtifacts)
+		}); err != nil {
+			return err
 		}
 	}
 
@@ -1357,27 +2574,83 @@ func (p *Pipeline) publishPackages(ctx context.Context) error {
 func (p *Pipeline) runAnnouncements(ctx context.Context) error {
 	log.Info("Running announcements")
 
-	announcer := announce.NewAnnouncer(p.config.Announce, p.templateCtx)
-	return announcer.Run(ctx)
+	cfg := p.config.Announce
+	if cfg.Jira.Pattern == "" {
+		// Update the tickets the changelog links
+		cfg.Jira.Pattern = p.config.Changelog.TicketLinks.Pattern
+	}
+	announcer := announce.NewAnnouncer(cfg, p.templateCtx).WithArtifacts(p.artifacts, p.distDir)
+	err := announcer.Run(ctx)
+
+	s := p.newSummary()
+	s.announcements(announcer.Results())
+	s.write(os.Stderr, "Announce summary")
+	return err
+}
+
+// goModProxy warms up the Go module proxy and verifies go install for the
+// released tag, per the gomod settings. Snapshots and nightlies aren't
+// tagged releases and are skipped.
+func (p *Pipeline) goModProxy(ctx context.Context) error {
+	cfg := p.config.GoMod
+	if !cfg.ProxyWarmup && !cfg.Verify {
+		return nil
+	}
+	if p.options.Snapshot || p.options.Nightly {
+		log.Debug("Skipping the Go module proxy for an untagged release")
+		return nil
+	}
+	off, err := p.disabled("gomod.disable", cfg.Disable)
+	if err != nil || off {
+		return err
+	}
+
+	// go install builds the main package of the first go build by default
+	main := ""
+	for _, build := range p.config.Builds {
+		if build.Builder == "" || build.Builder == "go" {
+			main = build.Main
+			break
+		}
+	}
+	return publish.NewGoModProxy(cfg, p.templateCtx).WithMain(main).Run(ctx)
 }
 
 // StateFile represents the saved pipeline state
 type StateFile struct {
+	// StateVersion is the schema version, see stateVersion
+	StateVersion string `json:"state_version"`
+	// ReleaserVersion is the version of releaser that saved the state
+	ReleaserVersion string `json:"releaser_version,omitempty"`
+
 	Version   string              `json:"version"`
 	Tag       string              `json:"tag"`
 	Artifacts []artifact.Artifact `json:"artifacts"`
 	Timestamp time.Time           `json:"timestamp"`
+	// Context is the template data of the prepare run
+	Context map[string]interface{} `json:"context,omitempty"`
+	// Env holds the non-secret environment variables the config references
+	Env map[string]string `json:"env,omitempty"`
+	// Files holds the size and digest of artifact files by saved path;
+	// paths inside dist are saved relative to it
+	Files map[string]FileCheck `json:"files,omitempty"`
 }
 
 // saveState saves the pipeline state for later continuation
 func (p *Pipeline) saveState() error {
 	log.Debug("Saving pipeline state")
 
+	artifacts, files := p.portableArtifacts()
 	state := StateFile{
-		Version:   p.templateCtx.Get("Version"),
-		Tag:       p.templateCtx.Get("Tag"),
-		Artifacts: p.artifacts.List(),
-		Timestamp: time.Now(),
+		StateVersion:    stateVersion,
+		ReleaserVersion: releaser.Version,
+		Version:         p.templateCtx.Get("Version"),
+		Tag:             p.templateCtx.Get("Tag"),
+		Artifacts:       artifacts,
+		Timestamp:       time.Now(),
+		Context:         p.stateContext(),
+		Env:             p.stateEnv(),
+		Files:           files,
 	}
 
 	data, err := json.MarshalIndent(state, "", "  ")
@@ -1386,7 +2659,7 @@ func (p *Pipeline) saveState() error {
 	}
 
 	statePath := filepath.Join(p.distDir, ".releaser-state.json")
-	if err := os.WriteFile(statePath, data, 0644); err != nil {
+	if err := writeFileAtomic(statePath, data, 0644); err != nil {
 		return fmt.Errorf("failed to write state file: %w", err)
 	}
 
@@ -1396,135 +2669,45 @@ func (p *Pipeline) saveState() error {
 
 // loadState loads the pipeline state from a previous prepare
 func (p *Pipeline) loadState() error {
+	if p.aborted() {
+		return fmt.Errorf("the build in %s was aborted, run it again before publishing", p.distDir)
+	}
 	statePath := filepath.Join(p.distDir, ".releaser-state.json")
 
 	data, err := os.ReadFile(statePath)
 	if err != nil {
 		if os.IsNotExist(err) {
-			return fmt.Errorf("no saved state found")
+			return errNoState
 		}
 		return fmt.Errorf("failed to read state file: %w", err)
 	}
 
-	var state StateFile
-	if err := json.Unmarshal(data, &state); err != nil {
-		return fmt.Errorf("failed to unmarshal state: %w", err)
-	}
-
-	// Restore artifacts
-	for _, a := range state.Artifacts {
-		p.artifacts.Add(a)
-	}
-
-	log.Info("Pipeline state loaded", "artifacts", len(state.Artifacts), "timestamp", state.Timestamp)
-	return nil
-}
-
-// copyFile copies a file from src to dst
-func copyFile(src, dst string) error {
-	data, err := os.ReadFile(src)
-	if err != nil {
-		return err
-	}
-	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+	// Check the schema version before decoding the rest, which may have
+	// changed shape in another major version
+	if err := checkStateVersion(data, statePath); err != nil {
 		return err
 	}
-	return os.WriteFile(dst, data, 0755)
-}
-
-// ensureBuildDependencies checks and installs required build tools
-func (p *Pipeline) ensureBuildDependencies() error {
-	log.Info("Checking build dependencies")
-
-	// Collect all targets that need cross-compilation
-	var crossTargets []string
-	needsCGO := false
-	needsPackaging := len(p.config.NFPMs) > 0
-	needsDocker := len(p.config.Dockers) > 0 && !p.options.SkipDocker
-	needsSigning := !p.options.SkipSign && len(p.config.Signs) > 0
-	needsSBOM := len(p.config.SBOMs) > 0
-
-	for _, build := range p.config.Builds {
-		if build.Skip {
-			continue
-		}
-
-		// Check if this build requires CGO
-		if build.Cgo.Enabled {
-			needsCGO = true
 
-			// Collect targets for CGO cross-compilation
-			for _, goos := range build.Goos {
-				for _, goarch := range build.Goarch {
-					target := goos + "_" + goarch
-					crossTargets = append(crossTargets, target)
-				}
-			}
-		}
-	}
-
-	// Ensure Fyne GUI dependencies if needed
-	for _, build := range p.config.Builds {
-		if build.Type == "gui" && build.Cgo.Enabled {
-			if err := deps.DetectAndInstallForFyne(); err != nil {
-				log.Warn("Could not install Fyne dependencies", "error", err)
-			}
-			break
-		}
-	}
-
-	// Ensure cross-compilers for CGO builds
-	if needsCGO && len(crossTargets) > 0 {
-		if err := deps.EnsureCrossCompilers(crossTargets); err != nil {
-			log.Warn("Could not install all cross-compilers", "error", err)
-		}
-	}
-
-	// Ensure packaging tools
-	if needsPackaging {
-		if err := deps.CheckAndInstall("nfpm"); err != nil {
-			return fmt.Errorf("nfpm required for packaging: %w", err)
-		}
-	}
-
-	// Ensure Docker
-	if needsDocker {
-		if err := deps.CheckAndInstall("docker"); err != nil {
-			log.Warn("Docker not available, skipping docker builds", "error", err)
-		}
-	}
-
-	// Ensure signing tools
-	if needsSigning {
-		// Check which signing method is configured
-		for _, signCfg := range p.config.Signs {
-			switch signCfg.Cmd {
-			case "cosign":
-				if err := deps.CheckAndInstall("cosign"); err != nil {
-					log.Warn("Cosign not available", "error", err)
-				}
-			case "gpg", "":
-				if err := deps.CheckAndInstall("gpg"); err != nil {
-					log.Warn("GPG not available", "error", err)
-				}
-			}
-		}
+	var state StateFile
+	if err := json.Unmarshal(data, &state); err != nil {
+		return fmt.Errorf("failed to unmarshal state %s, run prepare again: %w", statePath, err)
 	}
 
-	// Ensure SBOM tools
-	if needsSBOM {
-		if err := deps.CheckAndInstall("syft"); err != nil {
-			log.Warn("Syft not available, SBOM generation may be skipped", "error", err)
+	// Restore the template context so publishing renders the prepared
+	// version even on another machine or outside the repository
+	p.templateCtx.Restore(state.Context)
+	for key, value := range state.Env {
+		if _, ok := os.LookupEnv(key); !ok {
+			os.Setenv(key, value)
+			p.templateCtx.SetEnv(key, value)
 		}
 	}
 
-	// Check for UPX if compression is enabled in upx config
-	if len(p.config.UPXs) > 0 {
-		if err := deps.CheckAndInstall("upx"); err != nil {
-			log.Warn("UPX not available, binary compression disabled", "error", err)
-		}
+	// Restore artifacts
+	if err := p.restoreArtifacts(state.Artifacts, state.Files); err != nil {
+		return err
 	}
 
-	log.Info("Dependency check completed")
+	log.Info("Pipeline state loaded", "artifacts", len(state.Artifacts), "timestamp", state.Timestamp)
 	return nil
 }
@@ -0,0 +1,120 @@
+package pipeline
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+
+	"github.com/oarkflow/releaser/internal/archive"
+	"github.com/oarkflow/releaser/internal/artifact"
+	"github.com/oarkflow/releaser/internal/builder"
+)
+
+// archiveCreator returns the archive creator of the release. With several
+// builds, default archive names include the archive or build id.
+func (p *Pipeline) archiveCreator() *archive.Creator {
+	creator := archive.NewCreator(p.distDir, p.templateCtx)
+	creator.IncludeID = len(p.config.Builds) > 1
+	return creator
+}
+
+// plannedBinaries returns the binaries the selected builds produce, without
+// building anything. Targets whose name does not render are left out, the
+// build reports them.
+func (p *Pipeline) plannedBinaries() []artifact.Artifact {
+	var binaries []artifact.Artifact
+	for _, build := range p.config.Builds {
+		if build.Skip || !p.selected(build.ID) || build.Builder == "gomobile" {
+			continue
+		}
+		for _, t := range p.selectedTargets(build) {
+			binary, err := p.binaryName(build, t)
+			if err != nil {
+				continue
+			}
+			typ := artifact.TypeBinary
+			if builder.PublishesDirectory(build) || builder.IsLibrary(build.Buildmode) {
+				typ = artifact.TypeDirectory
+			}
+			binaries = append(binaries, artifact.Artifact{
+				Name:    binary,
+				Path:    filepath.Join(p.distDir, build.ID+"_"+t.String(), binary),
+				Type:    typ,
+				Goos:    t.OS,
+				Goarch:  t.Arch,
+				Goarm:   t.Arm,
+				Goamd64: t.Amd64,
+				Gomips:  t.Mips,
+				BuildID: build.ID,
+			})
+		}
+	}
+	return binaries
+}
+
+// checkCollisions fails when two entries of the resolved plan write the
+// same file: two builds the same binary, two archives the same archive, or
+// two builds a binary of the same name into one archive
+func (p *Pipeline) checkCollisions(binaries []artifact.Artifact) error {
+	built := make(map[string]string)
+	for _, bin := range binaries {
+		if other, ok := built[bin.Path]; ok && other != bin.BuildID {
+			return fmt.Errorf("builds %s and %s both write %s", other, bin.BuildID, bin.Path)
+		}
+		built[bin.Path] = bin.BuildID
+	}
+
+	if p.skipped("archive") {
+		return nil
+	}
+	creator := p.archiveCreator()
+	written := make(map[string]string)
+	for i, cfg := range p.config.Archives {
+		name := configName(cfg.ID, i)
+		if !p.selected(cfg.ID, cfg.Builds...) {
+			continue
+		}
+		if off, err := p.disabled(fmt.Sprintf("archives[%d].disable", i), cfg.Disable); err != nil || off {
+			continue
+		}
+		for _, bins := range archive.Targets(cfg, binaries) {
+			path, _, err := creator.Path(fmt.Sprintf("archives[%d]", i), cfg, bins[0])
+			if err != nil {
+				continue
+			}
+			if other, ok := written[path]; ok {
+				return fmt.Errorf("archives %s and %s both create %s, give them distinct name templates", other, name, filepath.Base(path))
+			}
+			written[path] = name
+
+			files := make(map[string]string)
+			for _, bin := range bins {
+				if bin.Type != artifact.TypeBinary {
+					continue
+				}
+				if other, ok := files[bin.Name]; ok && other != bin.BuildID {
+					return fmt.Errorf("archive %s: builds %s and %s both put %s in %s, give them distinct binary names or separate archives", name, other, bin.BuildID, bin.Name, filepath.Base(path))
+				}
+				files[bin.Name] = bin.BuildID
+			}
+		}
+	}
+	return nil
+}
+
+// checkTemplates checks the fields of the config's name templates against
+// an artifact's template context, reporting every broken template at once
+// instead of failing on the first one a stage renders
+func (p *Pipeline) checkTemplates() error {
+	tmplCtx := p.templateCtx.ForArtifact(artifact.Artifact{})
+	var errs []error
+	for _, t := range p.config.Templates() {
+		if err := tmplCtx.Check(t.Source, t.Text); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("invalid config: %w", errors.Join(errs...))
+	}
+	return nil
+}
@@ -0,0 +1,137 @@
+package pipeline
+
+import (
+	"bytes"
+	"fmt"
+	"go/version"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/charmbracelet/log"
+
+	"github.com/oarkflow/releaser/internal/deps"
+	"github.com/oarkflow/releaser/internal/publish"
+)
+
+// RequirementsError lists every unmet requirement of a release
+type RequirementsError struct {
+	Problems []string
+}
+
+func (e *RequirementsError) Error() string {
+	return "requirements not met:\n  - " + strings.Join(e.Problems, "\n  - ")
+}
+
+// publishes reports whether the run publishes, so publisher credentials
+// are needed
+func (p *Pipeline) publishes() bool {
+	return !p.options.Snapshot && !p.options.Prepare && !p.options.DryRun && !p.skipped("publish")
+}
+
+// envRequirements returns the declared environment variables and the
+// credentials of the enabled publishers, each as the variables satisfying
+// it and what needs it
+func (p *Pipeline) envRequirements() ([]publish.Requirement, error) {
+	var reqs []publish.Requirement
+	for _, name := range p.config.Requirements.Env {
+		reqs = append(reqs, publish.Requirement{NeededBy: "requirements.env", Env: []string{name}})
+	}
+	for _, req := range publish.Requirements(p.config) {
+		off, err := p.disabled(req.NeededBy+" disable", req.Disable)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", req.NeededBy, err)
+		}
+		if !off {
+			reqs = append(reqs, req)
+		}
+	}
+	return reqs, nil
+}
+
+// checkRequirements fails when a declared or publisher requirement is not
+// met, listing all of them. Credentials are only checked on runs that
+// publish, and the declared tools and go version only when building. It
+// runs once per pipeline.
+func (p *Pipeline) checkRequirements(building bool) error {
+	if p.requirementsChecked {
+		return nil
+	}
+	p.requirementsChecked = true
+
+	var problems []string
+	if p.publishes() {
+		reqs, err := p.envRequirements()
+		if err != nil {
+			return err
+		}
+		problems = append(problems, missingEnv(reqs)...)
+	}
+	if building {
+		for _, tool := range p.config.Requirements.Tools {
+			// Tools releaser installs on demand are left to the dependency check
+			if deps.IsAvailable(tool) || (deps.Installing() && deps.InstallCommand(tool) != "") {
+				continue
+			}
+			problem := fmt.Sprintf("tool %s is not in PATH", tool)
+			if install := deps.InstallCommand(tool); install != "" {
+				problem += ", install it with: " + install
+			}
+			problems = append(problems, problem)
+		}
+		if problem := checkGoVersion(p.config.Requirements.MinGo); problem != "" {
+			problems = append(problems, problem)
+		}
+	}
+
+	if len(problems) > 0 {
+		return &RequirementsError{Problems: problems}
+	}
+	log.Debug("Requirements met")
+	return nil
+}
+
+// missingEnv describes the requirements none of whose variables is set.
+// Variables needed by several entries are reported once.
+func missingEnv(reqs []publish.Requirement) []string {
+	var problems []string
+	index := make(map[string]int)
+	for _, req := range reqs {
+		set := false
+		for _, name := range req.Env {
+			set = set || os.Getenv(name) != ""
+		}
+		if set {
+			continue
+		}
+		key := strings.Join(req.Env, " or ")
+		if i, ok := index[key]; ok {
+			problems[i] += ", " + req.NeededBy
+			continue
+		}
+		index[key] = len(problems)
+		problems = append(problems, fmt.Sprintf("%s is not set, needed by %s", key, req.NeededBy))
+	}
+	return problems
+}
+
+// checkGoVersion describes why the installed go is older than min, or
+// returns an empty string when it is recent enough or min is unset
+func checkGoVersion(min string) string {
+	if min == "" {
+		return ""
+	}
+	want := "go" + strings.TrimPrefix(min, "go")
+	if !version.IsValid(want) {
+		return fmt.Sprintf("min_go %q is not a go version", min)
+	}
+	out, err := exec.Command("go", "env", "GOVERSION").Output()
+	if err != nil {
+		return fmt.Sprintf("go %s or newer is required, but go was not found", strings.TrimPrefix(want, "go"))
+	}
+	have := string(bytes.TrimSpace(out))
+	if version.IsValid(have) && version.Compare(have, want) < 0 {
+		return fmt.Sprintf("go %s or newer is required, found %s", strings.TrimPrefix(want, "go"), strings.TrimPrefix(have, "go"))
+	}
+	return ""
+}
@@ -0,0 +1,58 @@
+package pipeline
+
+import (
+	"os"
+
+	"github.com/oarkflow/releaser/internal/artifact"
+	"github.com/oarkflow/releaser/internal/checksum"
+	"github.com/oarkflow/releaser/internal/cicd"
+)
+
+// Result describes what a run released, for CI integrations
+type Result struct {
+	ProjectName string
+	Tag         string
+	Version     string
+	ReleaseURL  string
+	// Notes is the release body built by the notes stage
+	Notes     string
+	Artifacts []artifact.Artifact
+	// Checksums maps artifact names to their checksum in the checksums file
+	Checksums map[string]string
+}
+
+// Result returns the release produced by the run
+func (p *Pipeline) Result() Result {
+	r := Result{
+		ProjectName: p.config.ProjectName,
+		Tag:         p.templateCtx.Get("Tag"),
+		Version:     p.templateCtx.Get("Version"),
+		ReleaseURL:  p.templateCtx.Get("ReleaseURL"),
+		Notes:       p.templateCtx.Get("ReleaseNotes"),
+		Artifacts:   p.artifacts.List(),
+		Checksums:   make(map[string]string),
+	}
+	if p.options.Snapshot {
+		// Snapshots are never published
+		r.ReleaseURL = ""
+	}
+	for _, a := range p.artifacts.Filter(artifact.ByType(artifact.TypeChecksum)) {
+		entries, err := checksum.ParseFile(a.Path)
+		if err != nil {
+			continue
+		}
+		for _, e := range entries {
+			r.Checksums[e.Name] = e.Sum
+		}
+	}
+	return r
+}
+
+// group starts a collapsible log section in GitHub Actions, ended by the
+// returned func
+func (p *Pipeline) group(name string) func() {
+	if !p.options.GroupLogs {
+		return func() {}
+	}
+	return cicd.Group(os.Stdout, name)
+}
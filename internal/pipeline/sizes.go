@@ -0,0 +1,365 @@
+package pipeline
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	neturl "net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/charmbracelet/log"
+
+	"github.com/oarkflow/releaser/internal/artifact"
+	"github.com/oarkflow/releaser/internal/config"
+	"github.com/oarkflow/releaser/internal/github"
+	"github.com/oarkflow/releaser/internal/httpclient"
+)
+
+// sizesFile is the report of artifact sizes written to dist. The release
+// uploads it, so the next release compares against it.
+const sizesFile = "sizes.json"
+
+// sizedTypes are the artifacts whose sizes are reported
+var sizedTypes = []artifact.Type{
+	artifact.TypeBinary, artifact.TypeUniversalBinary, artifact.TypeLibrary,
+	artifact.TypeArchive, artifact.TypeSourceArchive, artifact.TypeLinuxPackage,
+	artifact.TypeDMG, artifact.TypePKG, artifact.TypeMSI, artifact.TypeNSIS,
+	artifact.TypeAppImage, artifact.TypeFlatpak, artifact.TypeSnap,
+	artifact.TypeDockerImageArchive,
+}
+
+// sizeReport lists the sizes of the artifacts of a release
+type sizeReport struct {
+	Version   string      `json:"version"`
+	Tag       string      `json:"tag"`
+	Artifacts []sizeEntry `json:"artifacts"`
+}
+
+// sizeEntry is the size of an artifact. Name is its path inside dist,
+// which tells apart binaries of the same name built for several targets.
+type sizeEntry struct {
+	Name    string        `json:"name"`
+	Type    artifact.Type `json:"type,omitempty"`
+	BuildID string        `json:"build_id,omitempty"`
+	Size    int64         `json:"size"`
+}
+
+// sizeSource provides the sizes of the previous release
+type sizeSource interface {
+	// previous returns the report of the previous release, or nil when
+	// there is none
+	previous(ctx context.Context) (*sizeReport, error)
+}
+
+// githubSizes reads the sizes of a GitHub release: its sizes.json when it
+// has one, the sizes of its assets otherwise
+type githubSizes struct {
+	owner, repo, tag string
+}
+
+func (s githubSizes) previous(ctx context.Context) (*sizeReport, error) {
+	var release struct {
+		Assets []struct {
+			ID   int64  `json:"id"`
+			Name string `json:"name"`
+			Size int64  `json:"size"`
+		} `json:"assets"`
+	}
+	client := github.New(os.Getenv("GITHUB_TOKEN"))
+	err := client.Do(ctx, "GET", fmt.Sprintf("/repos/%s/%s/releases/tags/%s", s.owner, s.repo, neturl.PathEscape(s.tag)), nil, &release)
+	if github.IsNotFound(err) {
+		log.Info("No release of the previous tag to compare sizes with", "tag", s.tag)
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	report := &sizeReport{Tag: s.tag, Version: strings.TrimPrefix(s.tag, "v")}
+	for _, asset := range release.Assets {
+		if asset.Name == sizesFile {
+			data, err := client.Download(ctx, fmt.Sprintf("/repos/%s/%s/releases/assets/%d", s.owner, s.repo, asset.ID))
+			if err != nil {
+				return nil, fmt.Errorf("failed to download %s of %s: %w", sizesFile, s.tag, err)
+			}
+			return parseSizeReport(data, sizesFile+" of "+s.tag)
+		}
+		report.Artifacts = append(report.Artifacts, sizeEntry{Name: asset.Name, Size: asset.Size})
+	}
+	return report, nil
+}
+
+// fileSizes reads the sizes.json of the previous release from a path or
+// URL, for releases that can't reach GitHub
+type fileSizes struct {
+	location string
+}
+
+func (s fileSizes) previous(ctx context.Context) (*sizeReport, error) {
+	if !strings.HasPrefix(s.location, "https://") && !strings.HasPrefix(s.location, "http://") {
+		data, err := os.ReadFile(s.location)
+		if err != nil {
+			return nil, err
+		}
+		return parseSizeReport(data, s.location)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.location, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := httpclient.Client().Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch %s: %w", s.location, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 400 {
+		return nil, fmt.Errorf("failed to fetch %s: status %d", s.location, resp.StatusCode)
+	}
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	return parseSizeReport(data, s.location)
+}
+
+// parseSizeReport decodes a sizes.json
+func parseSizeReport(data []byte, source string) (*sizeReport, error) {
+	var report sizeReport
+	if err := json.Unmarshal(data, &report); err != nil {
+		return nil, fmt.Errorf("invalid size report %s: %w", source, err)
+	}
+	return &report, nil
+}
+
+// sizeSource returns where the sizes of the previous release come from
+// per size_report.previous, nil when they aren't compared
+func (p *Pipeline) sizeSource() sizeSource {
+	switch previous := p.config.SizeReport.Previous; previous {
+	case "none":
+		return nil
+	case "", "github":
+		owner, repo, tag := p.templateCtx.Get("RepoOwner"), p.templateCtx.Get("RepoName"), p.templateCtx.Get("PreviousTag")
+		if owner == "" || repo == "" || tag == "" || tag == p.templateCtx.Get("Tag") {
+			log.Debug("No previous GitHub release to compare sizes with")
+			return nil
+		}
+		return githubSizes{owner: owner, repo: repo, tag: tag}
+	default:
+		return fileSizes{location: previous}
+	}
+}
+
+// sizes writes the sizes of binaries, archives and packages to sizes.json,
+// prints how they changed since the previous release and fails when one
+// breaks size_limits
+func (p *Pipeline) sizes(ctx context.Context) error {
+	// A rerun replaces the report of the previous run
+	p.artifacts.Remove(func(a artifact.Artifact) bool {
+		report, _ := a.Extra["size_report"].(bool)
+		return report
+	})
+
+	off, err := p.disabled("size_report.disable", p.config.SizeReport.Disable)
+	if err != nil {
+		return err
+	}
+	if off {
+		return nil
+	}
+
+	report := sizeReport{Version: p.templateCtx.Get("Version"), Tag: p.templateCtx.Get("Tag")}
+	for _, a := range p.artifacts.Filter(func(a artifact.Artifact) bool { return slices.Contains(sizedTypes, a.Type) }) {
+		info, err := os.Stat(a.Path)
+		if err != nil || !info.Mode().IsRegular() {
+			continue
+		}
+		report.Artifacts = append(report.Artifacts, sizeEntry{Name: p.sizeName(a), Type: a.Type, BuildID: a.BuildID, Size: info.Size()})
+		p.artifacts.Update(func(b artifact.Artifact) bool {
+			return b.Path == a.Path && b.Type == a.Type
+		}, func(b *artifact.Artifact) {
+			if b.Extra == nil {
+				b.Extra = make(map[string]interface{})
+			}
+			b.Extra[artifact.ExtraSize] = info.Size()
+		})
+	}
+	if len(report.Artifacts) == 0 {
+		return nil
+	}
+	sort.Slice(report.Artifacts, func(i, j int) bool { return report.Artifacts[i].Name < report.Artifacts[j].Name })
+
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return err
+	}
+	file := filepath.Join(p.distDir, sizesFile)
+	if err := os.WriteFile(file, append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("failed to write size report: %w", err)
+	}
+	if err := p.artifacts.Add(artifact.Artifact{
+		Name:  sizesFile,
+		Path:  file,
+		Type:  artifact.TypeMetadata,
+		Extra: map[string]interface{}{"size_report": true},
+	}); err != nil {
+		return err
+	}
+
+	var previous map[string]int64
+	if source := p.sizeSource(); source != nil {
+		prev, err := source.previous(ctx)
+		if err != nil {
+			if _, local := source.(fileSizes); local {
+				return fmt.Errorf("failed to read the sizes of the previous release: %w", err)
+			}
+			log.Warn("Failed to read the sizes of the previous release, not comparing", "error", err)
+		} else if prev != nil {
+			previous = previousSizes(prev, report.Version)
+		}
+	}
+
+	writeSizeTable(os.Stderr, report, previous)
+	return checkSizeLimits(p.config.SizeLimits, report, previous)
+}
+
+// sizeName returns the name of an artifact in the size report: its path
+// inside dist, or its name outside it
+func (p *Pipeline) sizeName(a artifact.Artifact) string {
+	rel, err := filepath.Rel(p.distDir, a.Path)
+	if err != nil || strings.HasPrefix(rel, "..") {
+		return a.Name
+	}
+	return filepath.ToSlash(rel)
+}
+
+// previousSizes indexes the sizes of the previous release by name, with
+// its version replaced by version so versioned archive names match
+func previousSizes(prev *sizeReport, version string) map[string]int64 {
+	sizes := make(map[string]int64)
+	for _, e := range prev.Artifacts {
+		name := e.Name
+		if prev.Version != "" && version != "" {
+			name = strings.ReplaceAll(name, prev.Version, version)
+		}
+		sizes[name] = e.Size
+	}
+	return sizes
+}
+
+// previousSize returns the size of an entry in the previous release. An
+// artifact in a directory of dist also matches a release asset of its
+// name, which is uploaded without the directory.
+func previousSize(previous map[string]int64, e sizeEntry) (int64, bool) {
+	if size, ok := previous[e.Name]; ok {
+		return size, true
+	}
+	size, ok := previous[path.Base(e.Name)]
+	return size, ok
+}
+
+// writeSizeTable prints the sizes and, with a previous release, how much
+// each changed
+func writeSizeTable(w io.Writer, report sizeReport, previous map[string]int64) {
+	fmt.Fprintf(w, "\nArtifact sizes:\n")
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	for _, e := range report.Artifacts {
+		change := ""
+		if previous != nil {
+			change = "new"
+			if prev, ok := previousSize(previous, e); ok {
+				change = formatChange(e.Size, prev)
+			}
+		}
+		fmt.Fprintf(tw, "  %s\t%s\t%s\n", e.Name, formatSize(e.Size), change)
+	}
+	tw.Flush()
+}
+
+// formatChange formats the change from prev to size, e.g. +1.2 MB (+8.5%)
+func formatChange(size, prev int64) string {
+	delta := size - prev
+	sign := "+"
+	if delta < 0 {
+		sign = "-"
+	}
+	magnitude := delta
+	if magnitude < 0 {
+		magnitude = -magnitude
+	}
+	if prev == 0 {
+		return sign + formatSize(magnitude)
+	}
+	return fmt.Sprintf("%s%s (%+.1f%%)", sign, formatSize(magnitude), float64(delta)/float64(prev)*100)
+}
+
+// checkSizeLimits reports every artifact breaking size_limits. Growth
+// limits only apply to artifacts of the previous release.
+func checkSizeLimits(limits []config.SizeLimit, report sizeReport, previous map[string]int64) error {
+	var errs []error
+	for _, e := range report.Artifacts {
+		for i, limit := range limits {
+			if len(limit.IDs) > 0 && !slices.Contains(limit.IDs, e.BuildID) {
+				continue
+			}
+			if limit.Glob != "" {
+				if ok, _ := path.Match(limit.Glob, path.Base(e.Name)); !ok {
+					continue
+				}
+			}
+
+			if limit.Max != "" {
+				max, err := config.ParseSize(limit.Max)
+				if err != nil {
+					return fmt.Errorf("size_limits[%d].max: %w", i, err)
+				}
+				if e.Size > max {
+					errs = append(errs, fmt.Errorf("%s is %s, over the limit of %s (size_limits[%d])", e.Name, formatSize(e.Size), formatSize(max), i))
+				}
+			}
+
+			prev, ok := previousSize(previous, e)
+			if limit.MaxGrowth == "" || !ok {
+				continue
+			}
+			growth, percent, err := limit.Growth()
+			if err != nil {
+				return fmt.Errorf("size_limits[%d].max_growth: %w", i, err)
+			}
+			allowed := int64(growth)
+			if percent {
+				allowed = int64(float64(prev) * growth / 100)
+			}
+			if e.Size-prev > allowed {
+				errs = append(errs, fmt.Errorf("%s grew %s since the previous release, more than the %s allowed (size_limits[%d])", e.Name, formatChange(e.Size, prev), limit.MaxGrowth, i))
+			}
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("%d artifacts exceed size_limits: %w", len(errs), errors.Join(errs...))
+	}
+	return nil
+}
+
+// formatSize formats bytes as a human readable size
+func formatSize(bytes int64) string {
+	const unit = 1024
+	if bytes < unit {
+		return fmt.Sprintf("%d B", bytes)
+	}
+	div, exp := int64(unit), 0
+	for n := bytes / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %cB", float64(bytes)/float64(div), "KMGTPE"[exp])
+}
@@ -0,0 +1,98 @@
+package pipeline
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/oarkflow/releaser/internal/config"
+)
+
+// SkipStages are the valid --skip values
+var SkipStages = []string{
+	"announce", "archive", "before", "cache", "checksum", "compose", "docker",
+	"helm", "kubernetes", "nfpm", "publish", "sbom", "sign", "sizes", "upx", "validate",
+}
+
+// parseSkip builds the set of skipped stages from --skip values, which may
+// be repeated or comma separated, and the individual --skip-* options.
+func parseSkip(opts ReleaseOptions) (map[string]bool, error) {
+	skip := make(map[string]bool)
+	for _, value := range opts.Skip {
+		for _, stage := range strings.Split(value, ",") {
+			stage = strings.ToLower(strings.TrimSpace(stage))
+			if stage == "" {
+				continue
+			}
+			if !slices.Contains(SkipStages, stage) {
+				return nil, fmt.Errorf("invalid --skip value %q, valid values are: %s", stage, strings.Join(SkipStages, ", "))
+			}
+			skip[stage] = true
+		}
+	}
+
+	legacy := map[string]bool{
+		"publish":  opts.SkipPublish,
+		"sign":     opts.SkipSign,
+		"docker":   opts.SkipDocker,
+		"announce": opts.SkipAnnounce,
+		"cache":    opts.SkipCache,
+	}
+	for stage, set := range legacy {
+		if set {
+			skip[stage] = true
+		}
+	}
+	return skip, nil
+}
+
+// skipped reports whether a stage was skipped on the command line
+func (p *Pipeline) skipped(stage string) bool {
+	return p.skip[stage]
+}
+
+// disabled reports whether a config section's disable setting renders to
+// "true", so sections can be turned off per release, e.g. for snapshots.
+func (p *Pipeline) disabled(source, value string) (bool, error) {
+	if value == "" {
+		return false, nil
+	}
+	out, err := p.templateCtx.Apply(source, value)
+	if err != nil {
+		return false, err
+	}
+	return strings.TrimSpace(out) == "true", nil
+}
+
+// enabledDockers returns the docker configs that are not disabled
+func (p *Pipeline) enabledDockers() ([]config.Docker, error) {
+	var dockers []config.Docker
+	for i, cfg := range p.config.Dockers {
+		off, err := p.disabled(fmt.Sprintf("dockers[%d].disable", i), cfg.Disable)
+		if err != nil {
+			return nil, fmt.Errorf("docker %s: %w", cfg.ID, err)
+		}
+		if !off {
+			dockers = append(dockers, cfg)
+		}
+	}
+	return dockers, nil
+}
+
+// enabledNFPMs returns the nfpm configs that are not disabled
+func (p *Pipeline) enabledNFPMs() ([]config.NFPM, error) {
+	var nfpms []config.NFPM
+	for i, cfg := range p.config.NFPMs {
+		if !p.selected(cfg.ID, cfg.Builds...) {
+			continue
+		}
+		off, err := p.disabled(fmt.Sprintf("nfpms[%d].disable", i), cfg.Disable)
+		if err != nil {
+			return nil, fmt.Errorf("nfpm %s: %w", cfg.ID, err)
+		}
+		if !off {
+			nfpms = append(nfpms, cfg)
+		}
+	}
+	return nfpms, nil
+}
@@ -0,0 +1,257 @@
+package pipeline
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/charmbracelet/log"
+
+	"github.com/oarkflow/releaser/internal/artifact"
+	"github.com/oarkflow/releaser/internal/builder"
+	"github.com/oarkflow/releaser/internal/config"
+	"github.com/oarkflow/releaser/internal/plugin"
+)
+
+// stage is a step of the build pipeline that can also be run on its own
+type stage struct {
+	name string
+	// skip is the --skip value that disables the stage, if any
+	skip string
+	// produces lists the artifact types the stage adds, which are dropped
+	// from restored state before the stage is rerun
+	produces []artifact.Type
+	// event is sent to plugins with the produced artifacts once the stage
+	// succeeds
+	event string
+	run   func(context.Context) error
+}
+
+// stages returns the build stages in the order BuildAll runs them
+func (p *Pipeline) stages() []stage {
+	return []stage{
+		{name: "build", produces: []artifact.Type{
+			artifact.TypeBinary, artifact.TypeDirectory, artifact.TypeLibrary, artifact.TypeHeader, artifact.TypePkgConfig,
+			artifact.TypeAndroidLibrary, artifact.TypeXCFramework,
+		}, event: plugin.EventBuildSuccess, run: func(ctx context.Context) error {
+			err := p.Build(ctx)
+			// Clean up temporary object files
+			_ = os.Remove("-" + ".o")
+			return err
+		}},
+		// Compress binaries before they are archived and packaged
+		{name: "upx", skip: "upx", run: p.upx},
+		// Merge darwin binaries before anything archives or packages them
+		{name: "universal", produces: []artifact.Type{artifact.TypeUniversalBinary}, run: p.universalBinaries},
+		// Generate completions and man pages for both archives and packages
+		{name: "generate", run: p.generated},
+		{name: "archive", skip: "archive", produces: []artifact.Type{artifact.TypeArchive}, event: plugin.EventArchiveCreated, run: p.archive},
+		{name: "nfpm", skip: "nfpm", produces: []artifact.Type{artifact.TypeLinuxPackage}, run: p.packages},
+		{name: "packages", produces: []artifact.Type{
+			artifact.TypeAppBundle, artifact.TypeDMG, artifact.TypePKG,
+			artifact.TypeMSI, artifact.TypeNSIS, artifact.TypeFlatpak, artifact.TypeAppImage, artifact.TypeSnap,
+		}, run: p.platformPackages},
+		{name: "helm", skip: "helm", produces: []artifact.Type{artifact.TypeHelm}, run: p.helmCharts},
+		{name: "kubernetes", skip: "kubernetes", produces: []artifact.Type{artifact.TypeKubernetes}, run: p.kubernetesManifests},
+		// Build images and exports before checksums so exports are checksummed and signed
+		{name: "docker", skip: "docker", produces: []artifact.Type{artifact.TypeDockerImage, artifact.TypeDockerImageArchive}, run: func(ctx context.Context) error {
+			return p.withDockerTimeout(ctx, func(ctx context.Context) error {
+				if err := p.docker(ctx); err != nil {
+					return err
+				}
+				return p.dockerExports(ctx)
+			})
+		}},
+		{name: "compose", skip: "compose", produces: []artifact.Type{artifact.TypeDockerCompose}, run: p.composeFiles},
+		// Register extra files before they are checksummed and signed
+		{name: "extra_files", run: p.extraFiles},
+		{name: "sbom", skip: "sbom", produces: []artifact.Type{artifact.TypeSBOM}, run: p.sbom},
+		// Report sizes once every binary, archive and package exists
+		{name: "sizes", skip: "sizes", run: p.sizes},
+		{name: "checksum", skip: "checksum", produces: []artifact.Type{artifact.TypeChecksum}, run: p.checksum},
+		{name: "provenance", produces: []artifact.Type{artifact.TypeProvenance}, run: p.provenance},
+		{name: "sign", skip: "sign", produces: []artifact.Type{artifact.TypeSignature, artifact.TypeCertificate, artifact.TypeAttestation}, run: func(ctx context.Context) error {
+			return errors.Join(p.sign(ctx), p.cosign(ctx))
+		}},
+		// Feeds list the signed downloads with their checksums
+		{name: "updates", run: p.updateFeeds},
+		// Notes come last so the footer can include the checksums
+		{name: "notes", run: p.releaseNotes},
+		// Preview the docs publish pushes
+		{name: "docs", run: p.docs},
+	}
+}
+
+// StageNames returns the stages accepted by RunStage.
+func (p *Pipeline) StageNames() []string {
+	var names []string
+	for _, st := range p.stages() {
+		names = append(names, st.name)
+	}
+	return names
+}
+
+// RunStage runs a single build stage against the artifacts of a previous
+// build, restored from the saved state or, failing that, by scanning dist
+// for binaries. The stage's previous outputs are replaced.
+func (p *Pipeline) RunStage(ctx context.Context, name string) error {
+	var st *stage
+	for _, candidate := range p.stages() {
+		if candidate.name == name {
+			st = &candidate
+			break
+		}
+	}
+	if st == nil {
+		return fmt.Errorf("unknown stage %q, valid stages are: %s", name, strings.Join(p.StageNames(), ", "))
+	}
+
+	if err := os.MkdirAll(p.distDir, 0755); err != nil {
+		return fmt.Errorf("failed to create dist directory: %w", err)
+	}
+
+	if err := p.loadState(); err != nil {
+		if !errors.Is(err, errNoState) {
+			return err
+		}
+		log.Debug("No saved state found, scanning dist for binaries")
+		if err := p.scanDist(); err != nil {
+			return err
+		}
+	}
+	// The artifacts of other builds sit the stage out and are added back
+	// before saving, so the state still holds the whole release
+	var others []artifact.Artifact
+	if len(p.options.IDs) > 0 {
+		other := func(a artifact.Artifact) bool {
+			return a.BuildID != "" && !slices.Contains(p.options.IDs, a.BuildID)
+		}
+		others = p.artifacts.Filter(other)
+		p.artifacts.Remove(other)
+	}
+	for _, t := range st.produces {
+		p.artifacts.Remove(artifact.ByType(t))
+	}
+
+	log.Info("Running stage", "stage", st.name, "artifacts", p.artifacts.Count())
+	if err := st.run(ctx); err != nil {
+		return fmt.Errorf("stage %s failed: %w", st.name, err)
+	}
+	if st.event != "" {
+		if err := p.notify(ctx, st.event, st.produces, nil); err != nil {
+			return err
+		}
+	}
+
+	for _, a := range others {
+		if err := p.artifacts.Add(a); err != nil {
+			return err
+		}
+	}
+	return p.saveState()
+}
+
+// scanDist registers the binaries of earlier builds found in dist
+func (p *Pipeline) scanDist() error {
+	for _, build := range p.config.Builds {
+		if build.Skip || !p.selected(build.ID) {
+			continue
+		}
+		for _, target := range p.getTargets(build) {
+			binary, err := p.binaryName(build, target)
+			if err != nil {
+				return err
+			}
+			path := filepath.Join(p.distDir, build.ID+"_"+target.String(), binary)
+			if _, err := os.Stat(path); err != nil {
+				continue
+			}
+			a := artifact.Artifact{
+				Name:    binary,
+				Path:    path,
+				Type:    artifact.TypeBinary,
+				Goos:    target.OS,
+				Goarch:  target.Arch,
+				Goarm:   target.Arm,
+				Goamd64: target.Amd64,
+				Gomips:  target.Mips,
+				BuildID: build.ID,
+			}
+			if builder.PublishesDirectory(build) {
+				a.Path = filepath.Dir(path)
+				a.Type = artifact.TypeDirectory
+				a.Extra = map[string]interface{}{"binary": path}
+			}
+			if builder.IsLibrary(build.Buildmode) {
+				for _, a := range libraryArtifacts(a) {
+					if err := p.artifacts.Add(a); err != nil {
+						return err
+					}
+				}
+				continue
+			}
+			if err := p.artifacts.Add(a); err != nil {
+				return err
+			}
+		}
+	}
+
+	if p.artifacts.Count() == 0 {
+		return fmt.Errorf("no saved state or binaries found in %s, run a build first", p.distDir)
+	}
+	log.Info("Found binaries in dist", "count", p.artifacts.Count())
+	return nil
+}
+
+// Artifacts returns the artifacts of the last build, read from the state
+// saved in dist or, without one, the binaries found in dist
+func (p *Pipeline) Artifacts() ([]artifact.Artifact, error) {
+	if err := p.loadState(); err != nil {
+		if !errors.Is(err, errNoState) {
+			return nil, err
+		}
+		log.Debug("No saved state found, scanning dist for binaries")
+		if err := p.scanDist(); err != nil {
+			return nil, err
+		}
+	}
+	return p.artifacts.List(), nil
+}
+
+// selected reports whether a config entry passes the --id filter, either by
+// its own id or by one of the builds it consumes
+func (p *Pipeline) selected(id string, builds ...string) bool {
+	if len(p.options.IDs) == 0 || slices.Contains(p.options.IDs, id) {
+		return true
+	}
+	for _, build := range builds {
+		if slices.Contains(p.options.IDs, build) {
+			return true
+		}
+	}
+	return false
+}
+
+// checkIDs rejects --id values that match no build, archive or nfpm
+func checkIDs(cfg *config.Config, ids []string) error {
+	for _, id := range ids {
+		found := false
+		for _, build := range cfg.Builds {
+			found = found || build.ID == id
+		}
+		for _, a := range cfg.Archives {
+			found = found || a.ID == id
+		}
+		for _, n := range cfg.NFPMs {
+			found = found || n.ID == id
+		}
+		if !found {
+			return fmt.Errorf("--id %s matches no build, archive or nfpm", id)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,50 @@
+package pipeline
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/oarkflow/releaser/internal/artifact"
+	"github.com/oarkflow/releaser/internal/config"
+)
+
+func TestRunStageKeepsOtherBuilds(t *testing.T) {
+	dist := t.TempDir()
+	prepare := statePipeline(t, &config.Config{ProjectName: "demo"}, "", dist, nil)
+	for _, id := range []string{"api", "web"} {
+		path := filepath.Join(dist, id+"_linux_amd64.tar.gz")
+		if err := os.WriteFile(path, []byte(id), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := prepare.artifacts.Add(artifact.Artifact{Name: filepath.Base(path), Path: path, Type: artifact.TypeArchive, BuildID: id}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := prepare.saveState(); err != nil {
+		t.Fatal(err)
+	}
+
+	p := statePipeline(t, prepare.config, "", dist, nil)
+	p.options.IDs = []string{"api"}
+	if err := p.RunStage(context.Background(), "checksum"); err != nil {
+		t.Fatal(err)
+	}
+
+	// The state of the full release survives a run for one build
+	publish := statePipeline(t, p.config, "", dist, nil)
+	if err := publish.loadState(); err != nil {
+		t.Fatal(err)
+	}
+	builds := map[string]bool{}
+	for _, a := range publish.artifacts.Filter(artifact.ByType(artifact.TypeArchive)) {
+		builds[a.BuildID] = true
+	}
+	if !builds["api"] || !builds["web"] {
+		t.Errorf("restored archives of builds %v, want api and web", builds)
+	}
+	if sums := publish.artifacts.Filter(artifact.ByType(artifact.TypeChecksum)); len(sums) != 1 {
+		t.Errorf("restored checksums = %+v", sums)
+	}
+}
@@ -0,0 +1,228 @@
+package pipeline
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/oarkflow/releaser/internal/artifact"
+	"github.com/oarkflow/releaser/internal/checksum"
+	"github.com/oarkflow/releaser/internal/redact"
+)
+
+// errNoState is returned by loadState when dist holds no saved state
+var errNoState = errors.New("no saved state found")
+
+// stateVersion is the schema version of the state file. The major version
+// changes when fields change meaning or shape, and a state of another major
+// version is refused; the minor version changes when fields are added,
+// which older releasers ignore.
+const stateVersion = "1.0"
+
+// checkStateVersion refuses a state file saved with another major schema
+// version. States from before versioning count as version 0.
+func checkStateVersion(data []byte, path string) error {
+	var header struct {
+		StateVersion    string `json:"state_version"`
+		ReleaserVersion string `json:"releaser_version"`
+	}
+	if err := json.Unmarshal(data, &header); err != nil {
+		return fmt.Errorf("state file %s is corrupt, run prepare again: %w", path, err)
+	}
+
+	saved := header.StateVersion
+	if saved == "" {
+		saved = "0"
+	}
+	savedMajor, _, _ := strings.Cut(saved, ".")
+	major, _, _ := strings.Cut(stateVersion, ".")
+	if savedMajor == major {
+		return nil
+	}
+
+	by := "an older releaser"
+	if header.ReleaserVersion != "" {
+		by = "releaser " + header.ReleaserVersion
+	}
+	return fmt.Errorf("state file %s has version %s, saved by %s, but this releaser reads version %s: run prepare again with this releaser",
+		path, saved, by, stateVersion)
+}
+
+// writeFileAtomic writes a file through a temporary file in the same
+// directory renamed over it, so a crash never leaves it half written
+func writeFileAtomic(path string, data []byte, perm os.FileMode) error {
+	f, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	tmp := f.Name()
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		os.Remove(tmp)
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		os.Remove(tmp)
+		return err
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	if err := os.Chmod(tmp, perm); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// FileCheck records the size and digest of an artifact file when the state
+// is saved, so a restored dist can be verified before publishing
+type FileCheck struct {
+	Size   int64  `json:"size"`
+	SHA256 string `json:"sha256"`
+}
+
+// localTemplateKeys are template fields that describe the current process
+// rather than the release, so they are not saved with the state
+var localTemplateKeys = []string{"Env", "Now", "Os", "Arch", "Runtime", "Artifacts"}
+
+// envRefRe finds environment variables referenced by config templates
+var envRefRe = regexp.MustCompile(`(?:\.Env\.|env\s+")([A-Za-z_][A-Za-z0-9_]*)`)
+
+// stateContext returns the template data to save with the state
+func (p *Pipeline) stateContext() map[string]interface{} {
+	data := p.templateCtx.Data()
+	for _, key := range localTemplateKeys {
+		delete(data, key)
+	}
+	return data
+}
+
+// stateEnv returns the environment variables referenced by the config or
+// set by its env section, leaving out masked and secret-looking ones
+func (p *Pipeline) stateEnv() map[string]string {
+	var names []string
+	if raw, err := os.ReadFile(p.configPath); err == nil {
+		for _, m := range envRefRe.FindAllStringSubmatch(string(raw), -1) {
+			names = append(names, m[1])
+		}
+	}
+	for _, entry := range p.config.Env {
+		if key, _, ok := strings.Cut(entry, "="); ok {
+			names = append(names, key)
+		}
+	}
+
+	env := make(map[string]string)
+	for _, name := range names {
+		if slices.Contains(p.config.MaskEnv, name) || redact.IsSecretName(name) {
+			continue
+		}
+		if value, ok := os.LookupEnv(name); ok {
+			env[name] = value
+		}
+	}
+	return env
+}
+
+// portableArtifacts returns the artifacts with paths inside dist made
+// relative to it, so dist can be moved to another workspace, and the size
+// and digest of each artifact file keyed by its saved path. Directories,
+// such as app bundles, are made relative too but have no check.
+func (p *Pipeline) portableArtifacts() ([]artifact.Artifact, map[string]FileCheck) {
+	artifacts := p.artifacts.List()
+	checks := make(map[string]FileCheck)
+	for i := range artifacts {
+		a := &artifacts[i]
+		if a.Path == "" {
+			continue
+		}
+		path := a.Path
+		a.Path = p.statePath(path)
+
+		info, err := os.Stat(path)
+		if err != nil || !info.Mode().IsRegular() {
+			continue
+		}
+		sum := a.Checksum("sha256")
+		if sum == "" {
+			if sum, err = checksum.CalculateForFile(path, checksum.AlgorithmSHA256); err != nil {
+				continue
+			}
+		}
+		checks[a.Path] = FileCheck{Size: info.Size(), SHA256: sum}
+	}
+	return artifacts, checks
+}
+
+// statePath returns the path an artifact is saved under: relative to dist
+// for files inside it, absolute otherwise, since restoreArtifacts resolves
+// relative paths against dist
+func (p *Pipeline) statePath(path string) string {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return path
+	}
+	dist, err := filepath.Abs(p.distDir)
+	if err != nil {
+		return abs
+	}
+	rel, err := filepath.Rel(dist, abs)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return abs
+	}
+	return filepath.ToSlash(rel)
+}
+
+// restoreArtifacts resolves saved artifact paths against the current dist
+// and verifies that each file is unchanged since the state was saved
+func (p *Pipeline) restoreArtifacts(artifacts []artifact.Artifact, checks map[string]FileCheck) error {
+	var problems []string
+	for _, a := range artifacts {
+		saved := a.Path
+		if saved != "" && !filepath.IsAbs(saved) {
+			a.Path = filepath.Join(p.distDir, filepath.FromSlash(saved))
+		}
+
+		if check, ok := checks[saved]; ok {
+			if problem := verifyFile(a.Path, check); problem != "" {
+				problems = append(problems, fmt.Sprintf("%s: %s", a.Path, problem))
+			}
+		}
+		if err := p.artifacts.Add(a); err != nil {
+			return err
+		}
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("artifact missing or modified since it was prepared:\n  %s", strings.Join(problems, "\n  "))
+	}
+	return nil
+}
+
+// verifyFile describes how a file differs from its recorded check, or
+// returns an empty string when it matches
+func verifyFile(path string, check FileCheck) string {
+	info, err := os.Stat(path)
+	if err != nil {
+		return "missing"
+	}
+	if info.Size() != check.Size {
+		return fmt.Sprintf("size %d, expected %d", info.Size(), check.Size)
+	}
+	sum, err := checksum.CalculateForFile(path, checksum.AlgorithmSHA256)
+	if err != nil {
+		return fmt.Sprintf("unreadable: %v", err)
+	}
+	if sum != check.SHA256 {
+		return "sha256 does not match"
+	}
+	return ""
+}
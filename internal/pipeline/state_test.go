@@ -0,0 +1,183 @@
+package pipeline
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/oarkflow/releaser/internal/artifact"
+	"github.com/oarkflow/releaser/internal/config"
+	"github.com/oarkflow/releaser/internal/git"
+	"github.com/oarkflow/releaser/internal/tmpl"
+)
+
+// statePipeline returns a pipeline that saves and loads state in distDir,
+// as prepare and publish do, with the given git state
+func statePipeline(t *testing.T, cfg *config.Config, configPath, distDir string, gitInfo *git.Info) *Pipeline {
+	t.Helper()
+	return &Pipeline{
+		config:      cfg,
+		artifacts:   artifact.NewManager(),
+		templateCtx: tmpl.New(cfg, gitInfo, false, false),
+		configPath:  configPath,
+		distDir:     distDir,
+	}
+}
+
+func TestStateRestoresTemplateContext(t *testing.T) {
+	t.Setenv("DEPLOY_TARGET", "production")
+
+	workspace := t.TempDir()
+	configPath := filepath.Join(workspace, ".releaser.yaml")
+	if err := os.WriteFile(configPath, []byte("release:\n  name_template: '{{ .Env.DEPLOY_TARGET }}'\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	cfg := &config.Config{
+		ProjectName: "demo",
+		Variables:   map[string]interface{}{"channel": "stable"},
+	}
+
+	dist := filepath.Join(workspace, "dist")
+	if err := os.MkdirAll(dist, 0755); err != nil {
+		t.Fatal(err)
+	}
+	archive := filepath.Join(dist, "demo_linux_amd64.tar.gz")
+	if err := os.WriteFile(archive, []byte("archive"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	prepare := statePipeline(t, cfg, configPath, dist, &git.Info{
+		CurrentTag:  "v1.2.3",
+		PreviousTag: "v1.2.2",
+		Commit:      "0123456789abcdef0123456789abcdef01234567",
+		ShortCommit: "0123456",
+		Branch:      "main",
+	})
+	if err := prepare.artifacts.Add(artifact.Artifact{Name: "demo_linux_amd64.tar.gz", Path: archive, Type: artifact.TypeArchive}); err != nil {
+		t.Fatal(err)
+	}
+
+	templates := []string{
+		"{{ .ProjectName }} {{ .Version }}",
+		"{{ .Tag }} {{ .PreviousTag }} {{ .Major }}.{{ .Minor }}.{{ .Patch }}",
+		"{{ .FullCommit }} {{ .ShortCommit }} {{ .Branch }}",
+		"{{ .Date }} {{ .Timestamp }}",
+		"{{ .Var.channel }} {{ .Env.DEPLOY_TARGET }}",
+	}
+	want := make([]string, len(templates))
+	for i, text := range templates {
+		out, err := prepare.templateCtx.Apply("test", text)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want[i] = out
+	}
+
+	if err := prepare.saveState(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Publish on another machine: dist is moved, there is no repository and
+	// the environment variable is not set
+	moved := filepath.Join(t.TempDir(), "dist")
+	if err := os.Rename(dist, moved); err != nil {
+		t.Fatal(err)
+	}
+	os.Unsetenv("DEPLOY_TARGET")
+
+	publish := statePipeline(t, cfg, configPath, moved, nil)
+	if err := publish.loadState(); err != nil {
+		t.Fatal(err)
+	}
+
+	for i, text := range templates {
+		got, err := publish.templateCtx.Apply("test", text)
+		if err != nil {
+			t.Errorf("%s: %v", text, err)
+			continue
+		}
+		if got != want[i] {
+			t.Errorf("%s renders %q after publish, %q after prepare", text, got, want[i])
+		}
+	}
+
+	restored := publish.artifacts.List()
+	if len(restored) != 1 || restored[0].Path != filepath.Join(moved, "demo_linux_amd64.tar.gz") {
+		t.Errorf("restored artifacts = %+v", restored)
+	}
+}
+
+func TestPortableArtifacts(t *testing.T) {
+	workspace := t.TempDir()
+	dist := filepath.Join(workspace, "dist")
+	bundle := filepath.Join(dist, "darwin", "Demo.app")
+	if err := os.MkdirAll(filepath.Join(bundle, "Contents"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	archive := filepath.Join(dist, "demo.tar.gz")
+	if err := os.WriteFile(archive, []byte("archive"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	license := filepath.Join(workspace, "LICENSE")
+	if err := os.WriteFile(license, []byte("license"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	p := statePipeline(t, &config.Config{ProjectName: "demo"}, "", dist, nil)
+	for _, a := range []artifact.Artifact{
+		{Name: "demo.tar.gz", Path: archive, Type: artifact.TypeArchive},
+		{Name: "Demo.app", Path: bundle, Type: artifact.TypeAppBundle},
+		{Name: "demo.deb", Path: filepath.Join(dist, "demo.deb"), Type: artifact.TypeLinuxPackage},
+		{Name: "LICENSE", Path: license, Type: artifact.TypeUploadable},
+		{Name: "ghcr.io/oarkflow/demo:1.2.3", Type: artifact.TypeDockerImage},
+	} {
+		if err := p.artifacts.Add(a); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	artifacts, checks := p.portableArtifacts()
+	wantPaths := map[string]string{
+		"demo.tar.gz":                 "demo.tar.gz",
+		"Demo.app":                    "darwin/Demo.app",
+		"demo.deb":                    "demo.deb",
+		"LICENSE":                     license,
+		"ghcr.io/oarkflow/demo:1.2.3": "",
+	}
+	for _, a := range artifacts {
+		if a.Path != wantPaths[a.Name] {
+			t.Errorf("%s saved as %q, want %q", a.Name, a.Path, wantPaths[a.Name])
+		}
+	}
+
+	// Only regular files can be verified
+	if len(checks) != 2 {
+		t.Errorf("checks = %v, want demo.tar.gz and LICENSE", checks)
+	}
+	if check, ok := checks["demo.tar.gz"]; !ok || check.Size != int64(len("archive")) {
+		t.Errorf("demo.tar.gz check = %+v", check)
+	}
+	if _, ok := checks[license]; !ok {
+		t.Errorf("LICENSE has no check")
+	}
+
+	// Saved paths resolve against the dist they are restored into
+	moved := filepath.Join(t.TempDir(), "dist")
+	restore := statePipeline(t, &config.Config{ProjectName: "demo"}, "", moved, nil)
+	if err := os.Rename(dist, moved); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(moved, "demo.tar.gz"), []byte("modified"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	err := restore.restoreArtifacts(artifacts, checks)
+	if err == nil || !strings.Contains(err.Error(), filepath.Join(moved, "demo.tar.gz")+": size 8, expected 7") {
+		t.Errorf("restoring a modified artifact: %v", err)
+	}
+	for _, a := range restore.artifacts.List() {
+		if a.Name == "Demo.app" && a.Path != filepath.Join(moved, "darwin", "Demo.app") {
+			t.Errorf("Demo.app restored to %s", a.Path)
+		}
+	}
+}
@@ -0,0 +1,138 @@
+package pipeline
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/charmbracelet/log"
+
+	"github.com/oarkflow/releaser/internal/announce"
+	"github.com/oarkflow/releaser/internal/publish"
+	"github.com/oarkflow/releaser/internal/redact"
+)
+
+// outcome is the result of one publisher or announcer
+type outcome struct {
+	name   string
+	status string
+	err    error
+}
+
+// summary runs publishers independently and records their outcomes, so
+// one failing publisher doesn't keep the others from publishing
+type summary struct {
+	p        *Pipeline
+	failFast bool
+	outcomes []outcome
+}
+
+// newSummary returns a summary for the publish stage
+func (p *Pipeline) newSummary() *summary {
+	return &summary{p: p, failFast: p.config.Publish.FailFast}
+}
+
+// run runs a publisher unless its disable setting renders to true. A
+// failure is recorded and only returned with publish.fail_fast, or when the
+// release was interrupted.
+func (s *summary) run(ctx context.Context, name, disable string, fn func() error) error {
+	off, err := s.p.disabled(name+".disable", disable)
+	if err == nil && off {
+		s.record(name, "skipped", nil)
+		return nil
+	}
+	if err == nil {
+		err = fn()
+	}
+	if err == nil {
+		s.record(name, "succeeded", nil)
+		return nil
+	}
+	// Declined at publish --confirm
+	if errors.Is(err, publish.ErrDeclined) {
+		s.record(name, "skipped", nil)
+		return nil
+	}
+	if errors.Is(err, publish.ErrQuit) {
+		s.record(name, "skipped", nil)
+		return publish.ErrQuit
+	}
+
+	s.record(name, "failed", err)
+	if s.failFast || ctx.Err() != nil {
+		return fmt.Errorf("%s: %w", name, err)
+	}
+	log.Error("Publishing failed, continuing with the other publishers", "publisher", name, "error", err)
+	return nil
+}
+
+// runUnlessPrerelease runs a publisher like run, skipping it for
+// prereleases when its skip_prerelease is set
+func (s *summary) runUnlessPrerelease(ctx context.Context, name, disable string, skipPrerelease bool, fn func() error) error {
+	if skipPrerelease && s.p.templateCtx.IsPrerelease() {
+		log.Info("Skipping for prerelease", "publisher", name, "tag", s.p.templateCtx.Get("Tag"))
+		s.record(name, "skipped", nil)
+		return nil
+	}
+	return s.run(ctx, name, disable, fn)
+}
+
+// record adds an outcome
+func (s *summary) record(name, status string, err error) {
+	s.outcomes = append(s.outcomes, outcome{name: name, status: status, err: err})
+}
+
+// announcements adds the outcomes of the announcers
+func (s *summary) announcements(results []announce.Result) {
+	for _, r := range results {
+		s.record(r.Name, r.Status, r.Err)
+	}
+}
+
+// err joins the recorded failures
+func (s *summary) err() error {
+	var errs []error
+	for _, o := range s.outcomes {
+		if o.status == "failed" {
+			errs = append(errs, fmt.Errorf("%s: %w", o.name, o.err))
+		}
+	}
+	if len(errs) == 0 {
+		return nil
+	}
+	return fmt.Errorf("%d of %d publishers failed: %w", len(errs), len(s.outcomes), errors.Join(errs...))
+}
+
+// write prints the outcomes as a table
+func (s *summary) write(w io.Writer, title string) {
+	if len(s.outcomes) == 0 {
+		return
+	}
+	fmt.Fprintf(w, "\n%s:\n", title)
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	for _, o := range s.outcomes {
+		detail := ""
+		if o.err != nil {
+			detail = redact.String(firstLine(o.err.Error()))
+		}
+		fmt.Fprintf(tw, "  %s\t%s\t%s\n", o.name, o.status, detail)
+	}
+	tw.Flush()
+}
+
+// itemName names the i-th of n configs of a publisher, e.g. brews[1]
+func itemName(kind string, i, n int) string {
+	if n == 1 {
+		return kind
+	}
+	return fmt.Sprintf("%s[%d]", kind, i)
+}
+
+// firstLine returns the first line of s
+func firstLine(s string) string {
+	line, _, _ := strings.Cut(s, "\n")
+	return line
+}
@@ -0,0 +1,122 @@
+package pipeline
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/charmbracelet/log"
+
+	"github.com/oarkflow/releaser/internal/config"
+	"github.com/oarkflow/releaser/internal/git"
+	"github.com/oarkflow/releaser/internal/tmpl"
+)
+
+// defaultTagMessage annotates tags when git.tag_message is not set
+const defaultTagMessage = "{{ .ProjectName }} {{ .Tag }}"
+
+// TagOptions configures creating the next release tag
+type TagOptions struct {
+	// Bump is the part of the latest tag to increment: major, minor or patch
+	Bump string
+	// Sign creates a signed tag instead of an annotated one
+	Sign bool
+	// Push pushes the tag to Remote
+	Push   bool
+	Remote string
+	// AllowDirty tags even when the worktree has uncommitted changes
+	AllowDirty bool
+}
+
+// Tag creates the next tag on HEAD by bumping the latest tag, v0.0.0 when
+// there is none, and returns it.
+func Tag(ctx context.Context, cfg *config.Config, opts TagOptions) (string, error) {
+	info, err := git.GetInfo(ctx)
+	if err != nil {
+		return "", err
+	}
+	if info.TreeState == "dirty" && !opts.AllowDirty {
+		return "", fmt.Errorf("refusing to tag: the worktree has uncommitted changes, use --allow-dirty to tag anyway")
+	}
+	if info.CurrentTag != "" {
+		return "", fmt.Errorf("HEAD is already tagged %s", info.CurrentTag)
+	}
+
+	latest := info.LatestTag
+	if latest == "" {
+		latest = "v0.0.0"
+	}
+	bump := opts.Bump
+	if bump == "" {
+		bump = "patch"
+	}
+	next, err := git.BumpVersion(latest, bump)
+	if err != nil {
+		return "", fmt.Errorf("failed to bump %s: %w", latest, err)
+	}
+
+	messageTemplate := cfg.Git.TagMessage
+	if messageTemplate == "" {
+		messageTemplate = defaultTagMessage
+	}
+	tmplCtx := tmpl.New(cfg, info, false, false)
+	tmplCtx.SetTag(next)
+	tmplCtx.Set("PreviousTag", info.LatestTag)
+	message, err := tmplCtx.Apply("git.tag_message", messageTemplate)
+	if err != nil {
+		return "", err
+	}
+
+	if err := git.CreateTag(ctx, next, message, opts.Sign); err != nil {
+		return "", err
+	}
+	log.Info("Created tag", "tag", next, "previous", info.LatestTag)
+
+	if opts.Push {
+		remote := opts.Remote
+		if remote == "" {
+			remote = "origin"
+		}
+		if err := git.PushTag(ctx, remote, next); err != nil {
+			return "", err
+		}
+		log.Info("Pushed tag", "tag", next, "remote", remote)
+	}
+	return next, nil
+}
+
+// autoTag creates the next tag for release --auto-tag when HEAD is untagged
+func autoTag(ctx context.Context, cfg *config.Config, opts ReleaseOptions, skip map[string]bool) error {
+	info, err := git.GetInfo(ctx)
+	if err != nil {
+		return err
+	}
+	if info.CurrentTag != "" {
+		log.Debug("HEAD is already tagged", "tag", info.CurrentTag)
+		return nil
+	}
+
+	_, err = Tag(ctx, cfg, TagOptions{
+		Bump:       opts.Bump,
+		Sign:       opts.SignTag,
+		Push:       !opts.Prepare && !skip["publish"],
+		AllowDirty: opts.AllowDirty,
+	})
+	return err
+}
+
+// LoadConfig loads the config and applies the selected profile, for
+// commands that do not run the pipeline
+func LoadConfig(opts ReleaseOptions) (*config.Config, error) {
+	cfgPath := opts.ConfigFile
+	if cfgPath == "" {
+		cfgPath = findConfigFile()
+	}
+	cfg, err := config.Load(cfgPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load config: %w", err)
+	}
+	if err := applyProfile(cfg, opts); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
@@ -0,0 +1,237 @@
+package pipeline
+
+import (
+	"fmt"
+	"io"
+	"runtime"
+	"slices"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/charmbracelet/log"
+
+	"github.com/oarkflow/releaser/internal/builder"
+	"github.com/oarkflow/releaser/internal/deps"
+)
+
+// toolCheck is a tool the release needs
+type toolCheck struct {
+	// tool is the binary, which is also the deps.CommonTools key of tools
+	// releaser knows how to install
+	tool     string
+	neededBy []string
+	ok       bool
+	// soft tools only degrade their stage when missing, unless
+	// deps.fail_fast is set
+	soft bool
+}
+
+// install returns the command installing the tool on this OS, if known
+func (c toolCheck) install() string {
+	return deps.InstallCommand(c.tool)
+}
+
+// missing returns the tool as a deps.Missing
+func (c toolCheck) missing() deps.Missing {
+	return deps.Missing{Tool: c.tool, NeededBy: strings.Join(c.neededBy, ", "), Install: c.install()}
+}
+
+// toolChecks returns the tools the selected builds and enabled stages need
+func (p *Pipeline) toolChecks() ([]toolCheck, error) {
+	var checks []toolCheck
+	index := make(map[string]int)
+	add := func(tool, neededBy string, ok, soft bool) {
+		if i, found := index[tool]; found {
+			c := &checks[i]
+			if !slices.Contains(c.neededBy, neededBy) {
+				c.neededBy = append(c.neededBy, neededBy)
+			}
+			c.ok = c.ok && ok
+			c.soft = c.soft && soft
+			return
+		}
+		index[tool] = len(checks)
+		checks = append(checks, toolCheck{tool: tool, neededBy: []string{neededBy}, ok: ok, soft: soft})
+	}
+
+	for _, build := range p.config.Builds {
+		if build.Skip || !p.selected(build.ID) {
+			continue
+		}
+		switch name := builderName(build.Builder); name {
+		case "go":
+			add("go", "builds", deps.IsAvailable("go"), false)
+		case "rust":
+			add("cargo", "builds", deps.IsAvailable("cargo"), false)
+		case "node", "npm", "yarn", "pnpm":
+			add("node", "builds", deps.IsAvailable("node"), false)
+			pm := name
+			if pm == "node" {
+				pm = "npm"
+			}
+			add(pm, "builds", deps.IsAvailable(pm), false)
+		case "deno", "bun", "dotnet", "gomobile":
+			add(name, "builds", deps.IsAvailable(name), false)
+		}
+		if build.Obfuscation.Enabled && (build.Obfuscation.Tool == "" || build.Obfuscation.Tool == "garble") {
+			add("garble", "obfuscation", deps.IsAvailable("garble"), false)
+		}
+		if build.Cgo.Enabled || builder.IsLibrary(build.Buildmode) {
+			for _, goos := range build.Goos {
+				for _, goarch := range build.Goarch {
+					if goos == runtime.GOOS && goarch == runtime.GOARCH {
+						continue
+					}
+					add("zig", "cgo "+goos+"/"+goarch, builder.HasCrossCompiler(goos, goarch), true)
+				}
+			}
+		}
+	}
+
+	if len(p.config.NFPMs) > 0 && !p.skipped("nfpm") {
+		add("nfpm", "nfpms", deps.IsAvailable("nfpm") || deps.IsAvailable("fpm"), false)
+	}
+	if len(p.config.Dockers) > 0 && !p.skipped("docker") {
+		// podman and nerdctl satisfy the docker requirement as well
+		add("docker", "dockers", deps.ContainerCLIAvailable(), false)
+	}
+	if !p.skipped("sign") {
+		for i, cfg := range p.config.Signs {
+			off, err := p.disabled(fmt.Sprintf("signs[%d].disable", i), cfg.Disable)
+			if err != nil {
+				return nil, fmt.Errorf("sign %s: %w", cfg.ID, err)
+			}
+			if off || (cfg.Method != "" && cfg.Method != "cmd") {
+				// Built-in signers need no tool
+				continue
+			}
+			tool := cfg.Cmd
+			if tool == "" {
+				tool = "gpg"
+			}
+			add(tool, "signs", deps.IsAvailable(tool), false)
+		}
+		if len(p.config.Cosigns) > 0 {
+			add("cosign", "cosigns", deps.IsAvailable("cosign"), false)
+		}
+	}
+	if len(p.config.SBOMs) > 0 && !p.skipped("sbom") {
+		add("syft", "sboms", deps.IsAvailable("syft"), true)
+	}
+	if len(p.config.UPXs) > 0 && !p.skipped("upx") {
+		add("upx", "upx", deps.IsAvailable("upx"), true)
+	}
+	if p.config.GoMod.Verify && !p.skipped("announce") && !p.options.Snapshot && !p.options.Nightly {
+		add("go", "gomod.verify", deps.IsAvailable("go"), false)
+	}
+	for _, tool := range p.config.Requirements.Tools {
+		add(tool, "requirements.tools", deps.IsAvailable(tool), false)
+	}
+	return checks, nil
+}
+
+// ensureBuildDependencies installs the missing tools the release needs
+// where allowed. Tools that stay missing fail the build at once, listed
+// together, except soft ones, which only warn unless deps.fail_fast is set.
+func (p *Pipeline) ensureBuildDependencies() error {
+	log.Info("Checking build dependencies")
+
+	// Ensure Fyne GUI dependencies if needed
+	for _, build := range p.config.Builds {
+		if build.Type == "gui" && build.Cgo.Enabled {
+			if err := deps.DetectAndInstallForFyne(); err != nil {
+				if p.config.Deps.FailFast {
+					return err
+				}
+				log.Warn("Could not install Fyne dependencies", "error", err)
+			}
+			break
+		}
+	}
+
+	checks, err := p.toolChecks()
+	if err != nil {
+		return err
+	}
+	var missing []deps.Missing
+	for _, c := range checks {
+		if c.ok {
+			continue
+		}
+		// Optional tools that fail to install come back without an error
+		if c.install() != "" {
+			if err := deps.CheckAndInstall(c.tool); err == nil && deps.IsAvailable(c.tool) {
+				continue
+			}
+		}
+		if c.soft && !p.config.Deps.FailFast {
+			log.Warn("Tool not available, its stage will be degraded", "tool", c.tool, "needed_by", strings.Join(c.neededBy, ", "))
+			continue
+		}
+		missing = append(missing, c.missing())
+	}
+	if len(missing) > 0 {
+		return &deps.MissingError{Tools: missing}
+	}
+
+	log.Info("Dependency check completed")
+	return nil
+}
+
+// Doctor prints a table of the tools the current config needs and whether
+// they are installed, with the command installing each missing one
+func (p *Pipeline) Doctor(w io.Writer) error {
+	checks, err := p.toolChecks()
+	if err != nil {
+		return err
+	}
+	if len(checks) == 0 {
+		fmt.Fprintln(w, "The config needs no external tools")
+		return nil
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "TOOL\tSTATUS\tNEEDED BY\tINSTALL")
+	var missing []deps.Missing
+	for _, c := range checks {
+		status, install := "ok", ""
+		if !c.ok {
+			status = "missing"
+			if c.soft {
+				status = "missing (optional)"
+			}
+			install = c.install()
+			if install == "" {
+				install = "install it and add it to PATH"
+			}
+			if !c.soft || p.config.Deps.FailFast {
+				missing = append(missing, c.missing())
+			}
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", c.tool, status, strings.Join(c.neededBy, ", "), install)
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(w, "\nMissing tools: %s\n", installMode())
+	if len(missing) > 0 {
+		return fmt.Errorf("%d required tool(s) missing", len(missing))
+	}
+	fmt.Fprintln(w, "✓ All required tools are installed")
+	return nil
+}
+
+// installMode describes what happens to missing tools
+func installMode() string {
+	switch {
+	case deps.Offline:
+		return "never installed (offline)"
+	case deps.AutoInstall:
+		return "installed automatically"
+	case deps.PromptForInstall:
+		return "installed after asking"
+	default:
+		return "never installed (auto_install is off)"
+	}
+}
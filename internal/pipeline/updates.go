@@ -0,0 +1,47 @@
+package pipeline
+
+import (
+	"context"
+
+	"github.com/charmbracelet/log"
+
+	"github.com/oarkflow/releaser/internal/artifact"
+	"github.com/oarkflow/releaser/internal/publish"
+)
+
+// updateFeeds renders the appcast and update manifest into dist and
+// registers them, so the release and blobs upload them
+func (p *Pipeline) updateFeeds(ctx context.Context) error {
+	cfg := p.config.Updates
+	if !cfg.Appcast.Enabled && !cfg.Manifest.Enabled {
+		return nil
+	}
+	// A rerun replaces the feeds of the previous run
+	p.artifacts.Remove(func(a artifact.Artifact) bool {
+		feed, _ := a.Extra["update_feed"].(bool)
+		return feed
+	})
+
+	off, err := p.disabled("updates.disable", cfg.Disable)
+	if err != nil {
+		return err
+	}
+	if off {
+		return nil
+	}
+	if cfg.SkipPrerelease && p.templateCtx.IsPrerelease() {
+		log.Info("Skipping update feeds for prerelease", "tag", p.templateCtx.Get("Tag"))
+		return nil
+	}
+
+	feeds, err := publish.NewUpdateFeeds(cfg, p.templateCtx, p.distDir).Render(ctx, p.artifacts.List())
+	if err != nil {
+		return err
+	}
+	for _, a := range feeds {
+		if err := p.artifacts.Add(a); err != nil {
+			return err
+		}
+	}
+	return nil
+}
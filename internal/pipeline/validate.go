@@ -0,0 +1,81 @@
+package pipeline
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/oarkflow/releaser/internal/git"
+)
+
+// defaultTagPattern accepts semver tags with an optional "v" prefix
+const defaultTagPattern = `^v?\d+\.\d+\.\d+(-[0-9A-Za-z.-]+)?(\+[0-9A-Za-z.-]+)?$`
+
+// maxListedFiles bounds the changed files shown in the dirty tree error
+const maxListedFiles = 10
+
+// validateGit checks that a release is built from a clean worktree at a
+// tag matching git.tag_pattern. Snapshots and nightlies are not validated.
+func (p *Pipeline) validateGit(ctx context.Context) []error {
+	if p.options.Snapshot || p.options.Nightly || p.skipped("validate") {
+		return nil
+	}
+
+	var errs []error
+	if !p.options.AllowDirty {
+		files, err := git.ChangedFiles(ctx)
+		if err != nil {
+			errs = append(errs, err)
+		} else if len(files) > 0 {
+			errs = append(errs, dirtyError(files))
+		}
+	}
+
+	tag := p.gitInfo.CurrentTag
+	if tag == "" {
+		if p.gitInfo.LatestTag == "" {
+			return append(errs, fmt.Errorf("HEAD %s is not tagged and no tags exist; tag it or use --snapshot", p.gitInfo.ShortCommit))
+		}
+		tag = p.gitInfo.LatestTag
+		tagCommit, err := git.TagCommit(ctx, tag)
+		if err != nil {
+			return append(errs, err)
+		}
+		errs = append(errs, fmt.Errorf("HEAD %s is not at tag %s, which points to %s", p.gitInfo.ShortCommit, tag, short(tagCommit)))
+	}
+
+	pattern := p.config.Git.TagPattern
+	if pattern == "" {
+		pattern = defaultTagPattern
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return append(errs, fmt.Errorf("invalid git.tag_pattern: %w", err))
+	}
+	if !re.MatchString(tag) {
+		errs = append(errs, fmt.Errorf("tag %s does not match %s", tag, pattern))
+	}
+	return errs
+}
+
+// dirtyError lists the uncommitted changes that block a release
+func dirtyError(files []string) error {
+	listed := files
+	if len(listed) > maxListedFiles {
+		listed = listed[:maxListedFiles]
+	}
+	msg := "worktree has uncommitted changes:\n  " + strings.Join(listed, "\n  ")
+	if more := len(files) - len(listed); more > 0 {
+		msg += fmt.Sprintf("\n  ... and %d more", more)
+	}
+	return fmt.Errorf("%s\ncommit or stash them, or use --allow-dirty or --snapshot", msg)
+}
+
+// short abbreviates a commit hash like git.Info.ShortCommit
+func short(commit string) string {
+	if len(commit) > 8 {
+		return commit[:8]
+	}
+	return commit
+}
@@ -0,0 +1,136 @@
+package plugin
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"slices"
+	"time"
+
+	"github.com/charmbracelet/log"
+
+	"github.com/oarkflow/releaser/internal/artifact"
+	"github.com/oarkflow/releaser/internal/config"
+	"github.com/oarkflow/releaser/internal/redact"
+)
+
+// Events sent to the plugins of the config
+const (
+	EventBuildSuccess   = "build.success"
+	EventArchiveCreated = "archive.created"
+	EventPublishDone    = "publish.done"
+	EventReleaseFailed  = "release.failed"
+)
+
+// Events lists every event plugins can subscribe to
+var Events = []string{EventBuildSuccess, EventArchiveCreated, EventPublishDone, EventReleaseFailed}
+
+// defaultTimeout bounds a plugin run without a timeout
+const defaultTimeout = 5 * time.Minute
+
+// Event is written as JSON to the stdin of the plugins subscribed to it.
+// Artifacts use the representation of `releaser artifacts list -o json`.
+type Event struct {
+	Event       string              `json:"event"`
+	ProjectName string              `json:"project_name"`
+	Tag         string              `json:"tag"`
+	Version     string              `json:"version"`
+	Snapshot    bool                `json:"snapshot"`
+	Artifacts   []artifact.Artifact `json:"artifacts"`
+	// Error is the failure of a release.failed event
+	Error string `json:"error,omitempty"`
+}
+
+// Dispatcher runs the configured plugins for the events they subscribe to
+type Dispatcher struct {
+	plugins  []config.Plugin
+	timeouts []time.Duration
+}
+
+// NewDispatcher checks the plugin configs and returns their dispatcher
+func NewDispatcher(plugins []config.Plugin) (*Dispatcher, error) {
+	d := &Dispatcher{}
+	for i, p := range plugins {
+		if p.Cmd == "" {
+			return nil, fmt.Errorf("plugins[%d]: cmd is required", i)
+		}
+		for _, event := range p.Events {
+			if !slices.Contains(Events, event) {
+				return nil, fmt.Errorf("plugins[%d]: unknown event %q, valid events are: %v", i, event, Events)
+			}
+		}
+		switch p.OnError {
+		case "", "warn", "fail":
+		default:
+			return nil, fmt.Errorf("plugins[%d]: on_error must be warn or fail, not %q", i, p.OnError)
+		}
+		timeout := defaultTimeout
+		if p.Timeout != "" {
+			parsed, err := time.ParseDuration(p.Timeout)
+			if err != nil {
+				return nil, fmt.Errorf("plugins[%d]: invalid timeout: %w", i, err)
+			}
+			timeout = parsed
+		}
+		if p.Name == "" {
+			p.Name = p.Cmd
+		}
+		d.plugins = append(d.plugins, p)
+		d.timeouts = append(d.timeouts, timeout)
+	}
+	return d, nil
+}
+
+// Dispatch runs the plugins subscribed to the event in order. Failures of
+// plugins with on_error: fail are returned, the others are logged.
+func (d *Dispatcher) Dispatch(ctx context.Context, e Event) error {
+	if e.Artifacts == nil {
+		e.Artifacts = []artifact.Artifact{}
+	}
+	input, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	for i, p := range d.plugins {
+		if len(p.Events) > 0 && !slices.Contains(p.Events, e.Event) {
+			continue
+		}
+		log.Debug("Running plugin", "plugin", p.Name, "event", e.Event)
+		if err := run(ctx, p, d.timeouts[i], e.Event, input); err != nil {
+			err = fmt.Errorf("plugin %s failed on %s: %w", p.Name, e.Event, err)
+			if p.OnError != "fail" {
+				log.Warn("Plugin failed", "plugin", p.Name, "event", e.Event, "error", err)
+				continue
+			}
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// run runs a plugin with the event on stdin, its output going to the
+// release log with secrets redacted
+func run(ctx context.Context, p config.Plugin, timeout time.Duration, event string, input []byte) error {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	output := redact.Writer(os.Stderr)
+	defer output.Close()
+
+	cmd := exec.CommandContext(ctx, p.Cmd, p.Args...)
+	cmd.Stdin = bytes.NewReader(input)
+	cmd.Stdout = output
+	cmd.Stderr = output
+	cmd.Env = append(os.Environ(), "RELEASER_EVENT="+event)
+	err := cmd.Run()
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return fmt.Errorf("timed out after %s", timeout)
+	}
+	return err
+}
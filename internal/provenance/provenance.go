@@ -0,0 +1,490 @@
+/*
+Package provenance generates SLSA build provenance for release artifacts.
+
+Each statement is an in-toto v1 statement:
+
+	{
+	  "_type": "https://in-toto.io/Statement/v1",
+	  "subject": [{"name": "<artifact>", "digest": {"sha256": "<hex>"}}],
+	  "predicateType": "https://slsa.dev/provenance/v1",
+	  "predicate": {
+	    "buildDefinition": {
+	      "buildType": "https://github.com/oarkflow/releaser/buildtypes/release/v1",
+	      "externalParameters": {"tag": "...", "version": "...", "commit": "...", "configDigest": {"sha256": "..."}},
+	      "internalParameters": {"GITHUB_RUN_ID": "...", ...},
+	      "resolvedDependencies": [
+	        {"uri": "git+<repo>@refs/tags/<tag>", "digest": {"gitCommit": "..."}},
+	        {"uri": "pkg:golang/<module>@<version>", "name": "<module>", "digest": {"goDirhash1": "h1:..."}}
+	      ]
+	    },
+	    "runDetails": {
+	      "builder": {"id": "..."},
+	      "metadata": {"invocationId": "...", "startedOn": "...", "finishedOn": "..."}
+	    }
+	  }
+	}
+*/
+package provenance
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/log"
+
+	"github.com/oarkflow/releaser/internal/artifact"
+	"github.com/oarkflow/releaser/internal/config"
+	"github.com/oarkflow/releaser/internal/tmpl"
+)
+
+const (
+	// StatementType is the in-toto v1 statement type
+	StatementType = "https://in-toto.io/Statement/v1"
+
+	// PredicateType is the SLSA v1 provenance predicate type
+	PredicateType = "https://slsa.dev/provenance/v1"
+
+	// BuildType identifies builds performed by releaser
+	BuildType = "https://github.com/oarkflow/releaser/buildtypes/release/v1"
+
+	// DefaultBuilderID is used when no CI system is detected
+	DefaultBuilderID = "https://github.com/oarkflow/releaser"
+)
+
+// Statement is an in-toto v1 statement
+type Statement struct {
+	Type          string    `json:"_type"`
+	Subject       []Subject `json:"subject"`
+	PredicateType string    `json:"predicateType"`
+	Predicate     Predicate `json:"predicate"`
+}
+
+// Subject is an artifact covered by a statement
+type Subject struct {
+	Name   string            `json:"name"`
+	Digest map[string]string `json:"digest"`
+}
+
+// Options carries build inputs that are not part of the template context
+type Options struct {
+	// BuilderID overrides the detected builder identity
+	BuilderID string
+	// ConfigPath is the release configuration file, recorded by digest
+	ConfigPath string
+	// GoMod is the go.mod whose requirements are recorded as dependencies
+	GoMod string
+}
+
+// Predicate is a SLSA v1 provenance predicate
+type Predicate struct {
+	BuildDefinition BuildDefinition `json:"buildDefinition"`
+	RunDetails      RunDetails      `json:"runDetails"`
+}
+
+// BuildDefinition describes the inputs of the build
+type BuildDefinition struct {
+	BuildType            string                 `json:"buildType"`
+	ExternalParameters   map[string]interface{} `json:"externalParameters"`
+	InternalParameters   map[string]interface{} `json:"internalParameters,omitempty"`
+	ResolvedDependencies []ResourceDescriptor   `json:"resolvedDependencies,omitempty"`
+}
+
+// ResourceDescriptor identifies a build input
+type ResourceDescriptor struct {
+	URI    string            `json:"uri,omitempty"`
+	Name   string            `json:"name,omitempty"`
+	Digest map[string]string `json:"digest,omitempty"`
+}
+
+// RunDetails describes the build execution
+type RunDetails struct {
+	Builder  Builder       `json:"builder"`
+	Metadata BuildMetadata `json:"metadata"`
+}
+
+// Builder identifies the build platform
+type Builder struct {
+	ID string `json:"id"`
+}
+
+// BuildMetadata carries invocation identifiers and timestamps
+type BuildMetadata struct {
+	InvocationID string     `json:"invocationId,omitempty"`
+	StartedOn    *time.Time `json:"startedOn,omitempty"`
+	FinishedOn   *time.Time `json:"finishedOn,omitempty"`
+}
+
+// NewPredicate builds a provenance predicate from the release context
+func NewPredicate(tmplCtx *tmpl.Context, startedOn time.Time, opts Options) Predicate {
+	finishedOn := time.Now().UTC()
+	startedOn = startedOn.UTC()
+
+	external := map[string]interface{}{
+		"tag":     tmplCtx.Get("Tag"),
+		"version": tmplCtx.Get("Version"),
+	}
+
+	var deps []ResourceDescriptor
+	if commit := tmplCtx.Get("FullCommit"); commit != "" {
+		source := ResourceDescriptor{Digest: map[string]string{"gitCommit": commit}}
+		if url := tmplCtx.Get("GitURL"); url != "" {
+			source.URI = "git+" + url
+			if tag := tmplCtx.Get("Tag"); tag != "" {
+				source.URI += "@refs/tags/" + tag
+			}
+		}
+		deps = append(deps, source)
+		external["commit"] = commit
+	}
+
+	if opts.ConfigPath != "" {
+		if sum, err := fileSHA256(opts.ConfigPath); err == nil {
+			external["configDigest"] = map[string]string{"sha256": sum}
+		}
+	}
+
+	if opts.GoMod != "" {
+		modules, err := goModules(opts.GoMod)
+		if err != nil {
+			log.Debug("Could not read go modules for provenance", "error", err)
+		}
+		deps = append(deps, modules...)
+	}
+
+	builderID, invocationID, internal := detectCI()
+	if opts.BuilderID != "" {
+		builderID = opts.BuilderID
+	}
+
+	return Predicate{
+		BuildDefinition: BuildDefinition{
+			BuildType:            BuildType,
+			ExternalParameters:   external,
+			InternalParameters:   internal,
+			ResolvedDependencies: deps,
+		},
+		RunDetails: RunDetails{
+			Builder: Builder{ID: builderID},
+			Metadata: BuildMetadata{
+				InvocationID: invocationID,
+				StartedOn:    &startedOn,
+				FinishedOn:   &finishedOn,
+			},
+		},
+	}
+}
+
+// detectCI returns the builder id, invocation id and CI parameters of the
+// current CI environment
+func detectCI() (string, string, map[string]interface{}) {
+	switch {
+	case os.Getenv("GITHUB_ACTIONS") == "true":
+		server := os.Getenv("GITHUB_SERVER_URL")
+		repo := os.Getenv("GITHUB_REPOSITORY")
+		runID := os.Getenv("GITHUB_RUN_ID")
+		internal := map[string]interface{}{
+			"GITHUB_RUN_ID":       runID,
+			"GITHUB_RUN_ATTEMPT":  os.Getenv("GITHUB_RUN_ATTEMPT"),
+			"GITHUB_WORKFLOW":     os.Getenv("GITHUB_WORKFLOW"),
+			"GITHUB_WORKFLOW_REF": os.Getenv("GITHUB_WORKFLOW_REF"),
+			"GITHUB_EVENT_NAME":   os.Getenv("GITHUB_EVENT_NAME"),
+			"GITHUB_REF":          os.Getenv("GITHUB_REF"),
+			"GITHUB_SHA":          os.Getenv("GITHUB_SHA"),
+		}
+		invocation := fmt.Sprintf("%s/%s/actions/runs/%s/attempts/%s", server, repo, runID, os.Getenv("GITHUB_RUN_ATTEMPT"))
+		return server + "/actions/runner", invocation, internal
+	case os.Getenv("GITLAB_CI") == "true":
+		internal := map[string]interface{}{
+			"CI_PIPELINE_ID":     os.Getenv("CI_PIPELINE_ID"),
+			"CI_JOB_ID":          os.Getenv("CI_JOB_ID"),
+			"CI_COMMIT_REF_NAME": os.Getenv("CI_COMMIT_REF_NAME"),
+			"CI_COMMIT_SHA":      os.Getenv("CI_COMMIT_SHA"),
+		}
+		return os.Getenv("CI_SERVER_URL") + "/gitlab-runner", os.Getenv("CI_JOB_URL"), internal
+	default:
+		return DefaultBuilderID, "", nil
+	}
+}
+
+// goModules returns the requirements of a go.mod as resolved dependencies,
+// with digests taken from the adjacent go.sum when available
+func goModules(goMod string) ([]ResourceDescriptor, error) {
+	data, err := os.ReadFile(goMod)
+	if err != nil {
+		return nil, err
+	}
+
+	sums := goSums(filepath.Join(filepath.Dir(goMod), "go.sum"))
+
+	var modules []ResourceDescriptor
+	inRequire := false
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if i := strings.Index(line, "//"); i >= 0 {
+			line = strings.TrimSpace(line[:i])
+		}
+
+		switch {
+		case line == "require (":
+			inRequire = true
+			continue
+		case inRequire && line == ")":
+			inRequire = false
+			continue
+		case strings.HasPrefix(line, "require "):
+			line = strings.TrimSpace(strings.TrimPrefix(line, "require "))
+		case !inRequire:
+			continue
+		}
+
+		fields := strings.Fields(line)
+		if len(fields) < 2 {
+			continue
+		}
+		module := ResourceDescriptor{
+			URI:  fmt.Sprintf("pkg:golang/%s@%s", fields[0], fields[1]),
+			Name: fields[0],
+		}
+		if sum, ok := sums[fields[0]+"@"+fields[1]]; ok {
+			module.Digest = map[string]string{"goDirhash1": sum}
+		}
+		modules = append(modules, module)
+	}
+
+	return modules, nil
+}
+
+// goSums maps module@version to its h1: module hash from go.sum. The hash
+// is a sha256 over a listing of the module's files, not over the module
+// zip, so it is recorded as it is in go.sum rather than as a sha256 digest.
+func goSums(path string) map[string]string {
+	sums := make(map[string]string)
+
+	f, err := os.Open(path)
+	if err != nil {
+		return sums
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) != 3 || strings.HasSuffix(fields[1], "/go.mod") || !strings.HasPrefix(fields[2], "h1:") {
+			continue
+		}
+		sums[fields[0]+"@"+fields[1]] = fields[2]
+	}
+
+	return sums
+}
+
+// fileSHA256 returns the hex sha256 of a file
+func fileSHA256(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// Generator writes provenance statements for release artifacts
+type Generator struct {
+	config    config.Provenance
+	distDir   string
+	manager   *artifact.Manager
+	tmplCtx   *tmpl.Context
+	startedOn time.Time
+	opts      Options
+}
+
+// NewGenerator creates a new provenance generator
+func NewGenerator(cfg config.Provenance, distDir string, manager *artifact.Manager, tmplCtx *tmpl.Context, startedOn time.Time, opts Options) *Generator {
+	if opts.BuilderID == "" {
+		opts.BuilderID = cfg.BuilderID
+	}
+	return &Generator{
+		config:    cfg,
+		distDir:   distDir,
+		manager:   manager,
+		tmplCtx:   tmplCtx,
+		startedOn: startedOn,
+		opts:      opts,
+	}
+}
+
+// Run writes the statements and registers them as artifacts, stopping
+// when ctx is done
+func (g *Generator) Run(ctx context.Context) error {
+	if !g.config.Enabled {
+		return nil
+	}
+
+	log.Info("Generating provenance")
+
+	subjects := g.manager.Filter(g.matches)
+	if len(subjects) == 0 {
+		log.Warn("No artifacts to generate provenance for")
+		return nil
+	}
+
+	predicate := NewPredicate(g.tmplCtx, g.startedOn, g.opts)
+
+	if !g.config.Split {
+		var statement []Subject
+		for _, a := range subjects {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			subject, err := newSubject(a)
+			if err != nil {
+				return err
+			}
+			statement = append(statement, subject)
+		}
+
+		nameTemplate := g.config.NameTemplate
+		if nameTemplate == "" {
+			nameTemplate = "{{ .ProjectName }}_{{ .Version }}.intoto.json"
+		}
+		name, err := g.tmplCtx.Apply("provenance.name_template", nameTemplate)
+		if err != nil {
+			return fmt.Errorf("failed to apply provenance name template: %w", err)
+		}
+		return g.write(name, statement, predicate, artifact.Artifact{})
+	}
+
+	for _, a := range subjects {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		subject, err := newSubject(a)
+		if err != nil {
+			return err
+		}
+
+		nameTemplate := g.config.NameTemplate
+		if nameTemplate == "" {
+			nameTemplate = "{{ .ArtifactName }}.intoto.json"
+		}
+		name, err := g.tmplCtx.ForArtifact(a).Apply("provenance.name_template", nameTemplate)
+		if err != nil {
+			return fmt.Errorf("failed to apply provenance name template: %w", err)
+		}
+		if err := g.write(name, []Subject{subject}, predicate, a); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// write writes a statement to the dist directory and registers it
+func (g *Generator) write(name string, subjects []Subject, predicate Predicate, source artifact.Artifact) error {
+	statement := Statement{
+		Type:          StatementType,
+		Subject:       subjects,
+		PredicateType: PredicateType,
+		Predicate:     predicate,
+	}
+
+	data, err := json.MarshalIndent(statement, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	path := filepath.Join(g.distDir, name)
+	if err := os.WriteFile(path, append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("failed to write provenance: %w", err)
+	}
+
+	extra := map[string]interface{}{"subjects": len(subjects)}
+	if source.Name != "" {
+		extra["signed_artifact"] = source.Name
+	}
+	if err := g.manager.Add(artifact.Artifact{
+		Name:    name,
+		Path:    path,
+		Type:    artifact.TypeProvenance,
+		Goos:    source.Goos,
+		Goarch:  source.Goarch,
+		BuildID: source.BuildID,
+		Extra:   extra,
+	}); err != nil {
+		return err
+	}
+
+	log.Info("Provenance generated", "file", name, "subjects", len(subjects))
+	return nil
+}
+
+// matches reports whether an artifact is a provenance subject
+func (g *Generator) matches(a artifact.Artifact) bool {
+	if a.Path == "" {
+		return false
+	}
+
+	if len(g.config.IDs) > 0 {
+		found := false
+		for _, id := range g.config.IDs {
+			if a.BuildID == id {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+
+	switch g.config.Artifacts {
+	case "archive", "archives":
+		return a.Type == artifact.TypeArchive
+	case "binary", "binaries":
+		return a.Type == artifact.TypeBinary || a.Type == artifact.TypeUniversalBinary
+	case "package", "packages":
+		switch a.Type {
+		case artifact.TypePackage, artifact.TypeLinuxPackage, artifact.TypeDMG, artifact.TypePKG,
+			artifact.TypeMSI, artifact.TypeNSIS, artifact.TypeAppImage, artifact.TypeSnap, artifact.TypeFlatpak:
+			return true
+		}
+		return false
+	case "source":
+		return a.Type == artifact.TypeSourceArchive
+	}
+
+	switch a.Type {
+	case artifact.TypeChecksum, artifact.TypeSignature, artifact.TypeCertificate,
+		artifact.TypeAttestation, artifact.TypeProvenance, artifact.TypeDockerImage, artifact.TypeDockerManifest:
+		return false
+	}
+	return true
+}
+
+// newSubject returns the subject for an artifact, reusing the checksum
+// recorded by the checksum stage when it is a sha256
+func newSubject(a artifact.Artifact) (Subject, error) {
+	sum := a.Checksum("sha256")
+	if sum == "" {
+		var err error
+		sum, err = fileSHA256(a.Path)
+		if err != nil {
+			return Subject{}, fmt.Errorf("failed to hash %s: %w", a.Name, err)
+		}
+	}
+	return Subject{Name: a.Name, Digest: map[string]string{"sha256": sum}}, nil
+}
@@ -0,0 +1,270 @@
+package provenance
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/oarkflow/releaser/internal/artifact"
+	"github.com/oarkflow/releaser/internal/config"
+	"github.com/oarkflow/releaser/internal/git"
+	"github.com/oarkflow/releaser/internal/tmpl"
+)
+
+var sha256Hex = regexp.MustCompile(`^[0-9a-f]{64}$`)
+
+// clearCI hides the CI environment of the machine running the tests
+func clearCI(t *testing.T) {
+	t.Helper()
+	t.Setenv("GITHUB_ACTIONS", "")
+	t.Setenv("GITLAB_CI", "")
+}
+
+// releaseArtifacts writes a binary and an archive to dir and registers them
+// along with a checksums file, which is never a subject
+func releaseArtifacts(t *testing.T, dir string) *artifact.Manager {
+	t.Helper()
+	manager := artifact.NewManager()
+	for _, a := range []artifact.Artifact{
+		{Name: "demo", Type: artifact.TypeBinary, Goos: "linux", Goarch: "amd64", BuildID: "demo"},
+		{Name: "demo_linux_amd64.tar.gz", Type: artifact.TypeArchive, Goos: "linux", Goarch: "amd64", BuildID: "demo"},
+		{Name: "checksums.txt", Type: artifact.TypeChecksum},
+	} {
+		a.Path = filepath.Join(dir, a.Name)
+		if err := os.WriteFile(a.Path, []byte(a.Name), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := manager.Add(a); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return manager
+}
+
+// validateStatement checks a statement against the in-toto v1 statement
+// structure and the SLSA v1 provenance predicate, decoding it without the
+// package's types so renamed fields are caught
+func validateStatement(t *testing.T, path string) map[string]any {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var doc map[string]any
+	if err := json.Unmarshal(data, &doc); err != nil {
+		t.Fatalf("statement is not JSON: %v", err)
+	}
+
+	if doc["_type"] != "https://in-toto.io/Statement/v1" {
+		t.Errorf("_type = %v", doc["_type"])
+	}
+	if doc["predicateType"] != "https://slsa.dev/provenance/v1" {
+		t.Errorf("predicateType = %v", doc["predicateType"])
+	}
+
+	subjects, _ := doc["subject"].([]any)
+	if len(subjects) == 0 {
+		t.Fatal("statement has no subjects")
+	}
+	for _, s := range subjects {
+		subject, _ := s.(map[string]any)
+		if name, _ := subject["name"].(string); name == "" {
+			t.Errorf("subject without a name: %v", s)
+		}
+		digest, _ := subject["digest"].(map[string]any)
+		if sum, _ := digest["sha256"].(string); !sha256Hex.MatchString(sum) {
+			t.Errorf("subject %v has no hex sha256 digest", subject["name"])
+		}
+	}
+
+	predicate, _ := doc["predicate"].(map[string]any)
+	definition, _ := predicate["buildDefinition"].(map[string]any)
+	if definition["buildType"] != BuildType {
+		t.Errorf("buildType = %v", definition["buildType"])
+	}
+	if _, ok := definition["externalParameters"].(map[string]any); !ok {
+		t.Error("buildDefinition has no externalParameters")
+	}
+	details, _ := predicate["runDetails"].(map[string]any)
+	builder, _ := details["builder"].(map[string]any)
+	if id, _ := builder["id"].(string); id == "" {
+		t.Error("runDetails has no builder id")
+	}
+	metadata, _ := details["metadata"].(map[string]any)
+	for _, key := range []string{"startedOn", "finishedOn"} {
+		if _, err := time.Parse(time.RFC3339, metadata[key].(string)); err != nil {
+			t.Errorf("metadata %s: %v", key, err)
+		}
+	}
+	return doc
+}
+
+func TestGeneratorStatements(t *testing.T) {
+	clearCI(t)
+
+	tests := []struct {
+		name string
+		cfg  config.Provenance
+		// subjects maps each statement to the subjects it must cover
+		subjects map[string][]string
+	}{
+		{
+			name: "single",
+			cfg:  config.Provenance{Enabled: true},
+			subjects: map[string][]string{
+				"demo_1.2.3.intoto.json": {"demo", "demo_linux_amd64.tar.gz"},
+			},
+		},
+		{
+			name: "split",
+			cfg:  config.Provenance{Enabled: true, Split: true},
+			subjects: map[string][]string{
+				"demo.intoto.json":                    {"demo"},
+				"demo_linux_amd64.tar.gz.intoto.json": {"demo_linux_amd64.tar.gz"},
+			},
+		},
+		{
+			name: "archives",
+			cfg:  config.Provenance{Enabled: true, Artifacts: "archives"},
+			subjects: map[string][]string{
+				"demo_1.2.3.intoto.json": {"demo_linux_amd64.tar.gz"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			manager := releaseArtifacts(t, dir)
+			tmplCtx := tmpl.New(&config.Config{ProjectName: "demo"}, &git.Info{CurrentTag: "v1.2.3", Commit: "0123456789abcdef0123456789abcdef01234567"}, false, false)
+
+			gen := NewGenerator(tt.cfg, dir, manager, tmplCtx, time.Now(), Options{})
+			if err := gen.Run(context.Background()); err != nil {
+				t.Fatal(err)
+			}
+
+			registered := manager.Filter(artifact.ByType(artifact.TypeProvenance))
+			if len(registered) != len(tt.subjects) {
+				t.Fatalf("registered %d statements, want %d", len(registered), len(tt.subjects))
+			}
+			for _, a := range registered {
+				want, ok := tt.subjects[a.Name]
+				if !ok {
+					t.Errorf("unexpected statement %s", a.Name)
+					continue
+				}
+				doc := validateStatement(t, a.Path)
+				var got []string
+				for _, s := range doc["subject"].([]any) {
+					got = append(got, s.(map[string]any)["name"].(string))
+				}
+				if len(got) != len(want) {
+					t.Errorf("%s covers %v, want %v", a.Name, got, want)
+					continue
+				}
+				for i := range want {
+					if got[i] != want[i] {
+						t.Errorf("%s covers %v, want %v", a.Name, got, want)
+						break
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestSubjectUsesRecordedChecksum(t *testing.T) {
+	recorded := "5891b5b522d5df086d0ff0b110fbd9d21bb4fc7163af34d08286a2e846f6be03"
+	subject, err := newSubject(artifact.Artifact{
+		Name:  "demo",
+		Path:  filepath.Join(t.TempDir(), "missing"),
+		Extra: map[string]interface{}{artifact.ExtraChecksum: "sha256:" + recorded},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if subject.Digest["sha256"] != recorded {
+		t.Errorf("digest = %s, want %s", subject.Digest["sha256"], recorded)
+	}
+}
+
+func TestPredicateInvocation(t *testing.T) {
+	clearCI(t)
+	t.Setenv("GITHUB_ACTIONS", "true")
+	t.Setenv("GITHUB_SERVER_URL", "https://github.com")
+	t.Setenv("GITHUB_REPOSITORY", "oarkflow/demo")
+	t.Setenv("GITHUB_RUN_ID", "42")
+	t.Setenv("GITHUB_RUN_ATTEMPT", "1")
+
+	dir := t.TempDir()
+	configPath := filepath.Join(dir, ".releaser.yaml")
+	if err := os.WriteFile(configPath, []byte("project_name: demo\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	goMod := filepath.Join(dir, "go.mod")
+	if err := os.WriteFile(goMod, []byte(`module example.com/demo
+
+go 1.24
+
+require example.com/direct v1.0.0
+
+require (
+	example.com/indirect v0.2.0 // indirect
+)
+`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "go.sum"), []byte(
+		"example.com/direct v1.0.0 h1:AAECAwQFBgcICQoLDA0ODxAREhMUFRYXGBkaGxwdHh8=\n"+
+			"example.com/direct v1.0.0/go.mod h1://////////////////////////////////////////8=\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tmplCtx := tmpl.New(&config.Config{ProjectName: "demo"}, &git.Info{CurrentTag: "v1.2.3", Commit: "abc123"}, false, false)
+	predicate := NewPredicate(tmplCtx, time.Now(), Options{ConfigPath: configPath, GoMod: goMod})
+
+	if got := predicate.RunDetails.Builder.ID; got != "https://github.com/actions/runner" {
+		t.Errorf("builder id = %s", got)
+	}
+	if got := predicate.RunDetails.Metadata.InvocationID; got != "https://github.com/oarkflow/demo/actions/runs/42/attempts/1" {
+		t.Errorf("invocation id = %s", got)
+	}
+	if got := predicate.BuildDefinition.InternalParameters["GITHUB_RUN_ID"]; got != "42" {
+		t.Errorf("GITHUB_RUN_ID = %v", got)
+	}
+
+	external := predicate.BuildDefinition.ExternalParameters
+	if external["tag"] != "v1.2.3" || external["commit"] != "abc123" {
+		t.Errorf("external parameters = %v", external)
+	}
+	if digest, _ := external["configDigest"].(map[string]string); !sha256Hex.MatchString(digest["sha256"]) {
+		t.Errorf("config digest = %v", external["configDigest"])
+	}
+
+	want := []ResourceDescriptor{
+		{Digest: map[string]string{"gitCommit": "abc123"}},
+		{URI: "pkg:golang/example.com/direct@v1.0.0", Name: "example.com/direct", Digest: map[string]string{
+			"goDirhash1": "h1:AAECAwQFBgcICQoLDA0ODxAREhMUFRYXGBkaGxwdHh8=",
+		}},
+		{URI: "pkg:golang/example.com/indirect@v0.2.0", Name: "example.com/indirect"},
+	}
+	got := predicate.BuildDefinition.ResolvedDependencies
+	if len(got) != len(want) {
+		t.Fatalf("dependencies = %+v, want %+v", got, want)
+	}
+	for i := range want {
+		if got[i].URI != want[i].URI || got[i].Name != want[i].Name || len(got[i].Digest) != len(want[i].Digest) {
+			t.Errorf("dependency %d = %+v, want %+v", i, got[i], want[i])
+			continue
+		}
+		for k, v := range want[i].Digest {
+			if got[i].Digest[k] != v {
+				t.Errorf("dependency %d digest %s = %s, want %s", i, k, got[i].Digest[k], v)
+			}
+		}
+	}
+}
@@ -20,6 +20,7 @@ import (
 
 	"github.com/oarkflow/releaser/internal/artifact"
 	"github.com/oarkflow/releaser/internal/config"
+	"github.com/oarkflow/releaser/internal/httpclient"
 	"github.com/oarkflow/releaser/internal/tmpl"
 )
 
@@ -94,7 +95,7 @@ func (p *S3Publisher) uploadFile(ctx context.Context, a artifact.Artifact, bucke
 	// Determine object key
 	key := a.Name
 	if p.config.Directory != "" {
-		dir, _ := p.tmplCtx.Apply(p.config.Directory)
+		dir, _ := p.tmplCtx.Apply("blobs.directory", p.config.Directory)
 		key = filepath.Join(dir, a.Name)
 	}
 
@@ -119,7 +120,7 @@ func (p *S3Publisher) uploadFile(ctx context.Context, a artifact.Artifact, bucke
 	signV4(req, accessKey, secretKey, region, "s3", content)
 
 	// Send request
-	resp, err := http.DefaultClient.Do(req)
+	resp, err := httpclient.Client().Do(req)
 	if err != nil {
 		return err
 	}
@@ -263,7 +264,7 @@ func (p *GCSPublisher) uploadFile(ctx context.Context, a artifact.Artifact, buck
 	// Determine object name
 	objectName := a.Name
 	if p.config.Directory != "" {
-		dir, _ := p.tmplCtx.Apply(p.config.Directory)
+		dir, _ := p.tmplCtx.Apply("blobs.directory", p.config.Directory)
 		objectName = dir + "/" + a.Name
 	}
 
@@ -280,7 +281,7 @@ func (p *GCSPublisher) uploadFile(ctx context.Context, a artifact.Artifact, buck
 	req.Header.Set("Content-Type", "application/octet-stream")
 	req.Header.Set("Content-Length", fmt.Sprintf("%d", len(content)))
 
-	resp, err := http.DefaultClient.Do(req)
+	resp, err := httpclient.Client().Do(req)
 	if err != nil {
 		return err
 	}
@@ -353,7 +354,7 @@ func (p *AzureBlobPublisher) uploadFile(ctx context.Context, a artifact.Artifact
 	// Determine blob name
 	blobName := a.Name
 	if p.config.Directory != "" {
-		dir, _ := p.tmplCtx.Apply(p.config.Directory)
+		dir, _ := p.tmplCtx.Apply("blobs.directory", p.config.Directory)
 		blobName = dir + "/" + a.Name
 	}
 
@@ -377,7 +378,7 @@ func (p *AzureBlobPublisher) uploadFile(ctx context.Context, a artifact.Artifact
 	// Sign request (simplified - production should use proper SharedKey auth)
 	signAzure(req, accountName, accountKey, container, blobName)
 
-	resp, err := http.DefaultClient.Do(req)
+	resp, err := httpclient.Client().Do(req)
 	if err != nil {
 		return err
 	}
@@ -462,3 +463,47 @@ func (p *MinioPublisher) Publish(ctx context.Context, artifacts []artifact.Artif
 	s3 := NewS3Publisher(p.config, p.tmplCtx)
 	return s3.Publish(ctx, artifacts)
 }
+
+// NewBlobPublisher returns the publisher of the blob's provider: s3 (the
+// default), gcs, azblob or minio
+func NewBlobPublisher(cfg config.Blob, tmplCtx *tmpl.Context) (Publisher, error) {
+	switch cfg.Provider {
+	case "", "s3":
+		return NewS3Publisher(cfg, tmplCtx), nil
+	case "gcs":
+		return NewGCSPublisher(cfg, tmplCtx), nil
+	case "azblob", "azure":
+		return NewAzureBlobPublisher(cfg, tmplCtx), nil
+	case "minio":
+		return NewMinioPublisher(cfg, tmplCtx), nil
+	default:
+		return nil, fmt.Errorf("unknown blob provider %q", cfg.Provider)
+	}
+}
+
+// BlobArtifacts returns the files a blob uploads: artifacts matching its
+// ids, leaving out private ones unless include_private is set
+func BlobArtifacts(cfg config.Blob, artifacts []artifact.Artifact) []artifact.Artifact {
+	var files []artifact.Artifact
+	for _, a := range artifacts {
+		switch a.Type {
+		case artifact.TypeDirectory, artifact.TypeDockerImage, artifact.TypeDockerManifest:
+			continue
+		}
+		if a.Path == "" || !matchesIDs(a, cfg.IDs) {
+			continue
+		}
+		if isPrivate(a) && !cfg.IncludePrivate {
+			continue
+		}
+		files = append(files, a)
+	}
+	return files
+}
+
+// isPrivate reports whether an artifact must stay out of public release
+// targets, like the reverse map of an obfuscated build
+func isPrivate(a artifact.Artifact) bool {
+	private, _ := a.Extra["private"].(bool)
+	return private
+}
@@ -0,0 +1,249 @@
+package publish
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/log"
+
+	"github.com/oarkflow/releaser/internal/artifact"
+	"github.com/oarkflow/releaser/internal/config"
+	"github.com/oarkflow/releaser/internal/manifest"
+	"github.com/oarkflow/releaser/internal/tmpl"
+)
+
+// CaskPublisher publishes Homebrew casks to a tap
+type CaskPublisher struct {
+	config  config.Cask
+	tmplCtx *tmpl.Context
+}
+
+func init() {
+	RegisterRequirements(func(cfg *config.Config) []Requirement {
+		var reqs []Requirement
+		for _, cask := range cfg.Casks {
+			if cask.SkipUpload != "true" && needsGitHubToken(tapRepository(cask.Tap, cask.Repository)) {
+				reqs = append(reqs, Requirement{NeededBy: neededBy("homebrew cask", cask.Name), Env: []string{"GITHUB_TOKEN"}, Disable: cask.Disable})
+			}
+		}
+		return reqs
+	})
+}
+
+// NewCaskPublisher creates a new Homebrew cask publisher
+func NewCaskPublisher(cfg config.Cask, tmplCtx *tmpl.Context) *CaskPublisher {
+	return &CaskPublisher{
+		config:  cfg,
+		tmplCtx: tmplCtx,
+	}
+}
+
+// Publish generates the cask and pushes it into the tap's Casks directory
+func (p *CaskPublisher) Publish(ctx context.Context, artifacts []artifact.Artifact) error {
+	if p.config.SkipUpload == "true" {
+		log.Info("Homebrew cask upload disabled, skipping")
+		return nil
+	}
+
+	log.Info("Publishing Homebrew cask")
+
+	cask, err := p.generateCask(artifacts)
+	if err != nil {
+		return err
+	}
+
+	tap := tapRepository(p.config.Tap, p.config.Repository)
+	if tap.Owner == "" && tap.Git.URL == "" {
+		return fmt.Errorf("Homebrew tap repository is required")
+	}
+
+	log.Debug("Generated Homebrew cask", "cask", cask)
+
+	name := p.caskName()
+	directory := p.config.Directory
+	if directory == "" {
+		directory = "Casks"
+	}
+	caskPath := fmt.Sprintf("%s/%s.rb", directory, name)
+
+	commitMsg := fmt.Sprintf("Update %s cask to %s", name, p.tmplCtx.Get("Version"))
+	if p.config.CommitMsgTemplate != "" {
+		commitMsg, _ = p.tmplCtx.Apply("casks.commit_msg_template", p.config.CommitMsgTemplate)
+	}
+
+	if err := commitToRepo(ctx, p.tmplCtx, tap, caskPath, cask, commitMsg, p.config.CommitAuthor); err != nil {
+		return fmt.Errorf("failed to push cask: %w", err)
+	}
+
+	log.Info("Homebrew cask published", "repo", repoLabel(tap), "path", caskPath)
+	return nil
+}
+
+// caskName returns the cask token
+func (p *CaskPublisher) caskName() string {
+	name := p.config.Name
+	if name == "" {
+		name = p.tmplCtx.Get("ProjectName")
+	}
+	return strings.ToLower(name)
+}
+
+// caskArtifacts picks the per-arch darwin artifacts, preferring DMGs over archives
+func (p *CaskPublisher) caskArtifacts(artifacts []artifact.Artifact) map[string]artifact.Artifact {
+	selected := make(map[string]artifact.Artifact)
+
+	for _, want := range []artifact.Type{artifact.TypeArchive, artifact.TypeDMG} {
+		for _, a := range sortedArtifacts(artifacts) {
+			if a.Goos != "darwin" || a.Type != want || !matchesIDs(a, p.config.IDs) {
+				continue
+			}
+			if prev, ok := selected[a.Goarch]; ok && prev.Type == want {
+				continue
+			}
+			// DMGs are considered last so they replace any archive for the same arch
+			selected[a.Goarch] = a
+		}
+	}
+
+	return selected
+}
+
+// generateCask generates the cask Ruby file
+func (p *CaskPublisher) generateCask(artifacts []artifact.Artifact) (string, error) {
+	selected := p.caskArtifacts(artifacts)
+	if len(selected) == 0 {
+		return "", fmt.Errorf("no darwin DMG or archive found for Homebrew cask")
+	}
+
+	urlTemplate := p.config.URLTemplate
+	if urlTemplate == "" {
+		urlTemplate = "{{ .ReleaseDownloadURL }}/{{ .ArtifactName }}"
+	}
+
+	type source struct {
+		url    string
+		sha256 string
+	}
+
+	sources := make(map[string]source)
+	for arch, a := range selected {
+		tmplCtx := p.tmplCtx.ForArtifact(a)
+		url, err := tmplCtx.Apply("casks.url_template", urlTemplate)
+		if err != nil {
+			return "", fmt.Errorf("failed to apply url template: %w", err)
+		}
+		sum, err := artifactSHA256(a, artifacts)
+		if err != nil {
+			return "", fmt.Errorf("failed to hash %s: %w", a.Name, err)
+		}
+		sources[arch] = source{url: url, sha256: sum}
+	}
+
+	displayName := p.config.DisplayName
+	if displayName == "" {
+		displayName = p.tmplCtx.Get("ProjectName")
+	}
+
+	var cask strings.Builder
+	cask.WriteString(fmt.Sprintf("cask %s do\n", manifest.RubyString(p.caskName())))
+	cask.WriteString(fmt.Sprintf("  version %s\n", manifest.RubyString(p.tmplCtx.Get("Version"))))
+	cask.WriteString(fmt.Sprintf("  name %s\n", manifest.RubyString(displayName)))
+	if p.config.Description != "" {
+		cask.WriteString(fmt.Sprintf("  desc %s\n", manifest.RubyString(p.config.Description)))
+	}
+	if p.config.Homepage != "" {
+		cask.WriteString(fmt.Sprintf("  homepage %s\n", manifest.RubyString(p.config.Homepage)))
+	}
+	cask.WriteString("\n")
+
+	universal, hasUniversal := sources["universal"]
+	arm, hasArm := sources["arm64"]
+	intel, hasIntel := sources["amd64"]
+
+	switch {
+	case hasArm || hasIntel:
+		if hasArm {
+			cask.WriteString("  on_arm do\n")
+			cask.WriteString(fmt.Sprintf("    url %s\n", manifest.RubyString(arm.url)))
+			cask.WriteString(fmt.Sprintf("    sha256 %s\n", manifest.RubyString(arm.sha256)))
+			cask.WriteString("  end\n")
+		}
+		if hasIntel {
+			cask.WriteString("  on_intel do\n")
+			cask.WriteString(fmt.Sprintf("    url %s\n", manifest.RubyString(intel.url)))
+			cask.WriteString(fmt.Sprintf("    sha256 %s\n", manifest.RubyString(intel.sha256)))
+			cask.WriteString("  end\n")
+		}
+	case hasUniversal:
+		cask.WriteString(fmt.Sprintf("  url %s\n", manifest.RubyString(universal.url)))
+		cask.WriteString(fmt.Sprintf("  sha256 %s\n", manifest.RubyString(universal.sha256)))
+	default:
+		return "", fmt.Errorf("no amd64, arm64 or universal darwin artifact found for Homebrew cask")
+	}
+	cask.WriteString("\n")
+
+	for _, dep := range p.config.Depends {
+		cask.WriteString(fmt.Sprintf("  depends_on formula: %s\n", manifest.RubyString(dep)))
+	}
+	for _, conflict := range p.config.Conflicts {
+		cask.WriteString(fmt.Sprintf("  conflicts_with cask: %s\n", manifest.RubyString(conflict)))
+	}
+
+	if p.config.App != "" {
+		cask.WriteString(fmt.Sprintf("  app %s\n", manifest.RubyString(p.config.App)))
+	}
+	for _, bin := range p.config.Binaries {
+		cask.WriteString(fmt.Sprintf("  binary %s\n", manifest.RubyString(bin)))
+	}
+
+	if stanza := caskStanza("uninstall", p.config.Uninstall); stanza != "" {
+		cask.WriteString("\n" + stanza)
+	}
+	if stanza := caskStanza("zap", p.config.Zap); stanza != "" {
+		cask.WriteString("\n" + stanza)
+	}
+
+	if p.config.Caveats != "" {
+		cask.WriteString("\n  caveats <<~EOS\n")
+		for _, line := range strings.Split(strings.TrimSpace(p.config.Caveats), "\n") {
+			cask.WriteString(fmt.Sprintf("    %s\n", line))
+		}
+		cask.WriteString("  EOS\n")
+	}
+
+	cask.WriteString("end\n")
+
+	return cask.String(), nil
+}
+
+// caskStanza renders an uninstall or zap stanza
+func caskStanza(name string, cfg config.CaskUninstall) string {
+	var directives []string
+	add := func(key string, values []string) {
+		if len(values) == 0 {
+			return
+		}
+		quoted := make([]string, len(values))
+		for i, v := range values {
+			quoted[i] = manifest.RubyString(v)
+		}
+		if len(quoted) == 1 {
+			directives = append(directives, fmt.Sprintf("%s: %s", key, quoted[0]))
+			return
+		}
+		directives = append(directives, fmt.Sprintf("%s: [\n      %s,\n    ]", key, strings.Join(quoted, ",\n      ")))
+	}
+
+	add("launchctl", cfg.LaunchCtl)
+	add("quit", cfg.Quit)
+	add("login_item", cfg.LoginItem)
+	add("delete", cfg.Delete)
+	add("trash", cfg.Trash)
+
+	if len(directives) == 0 {
+		return ""
+	}
+
+	return fmt.Sprintf("  %s %s\n", name, strings.Join(directives, ",\n    "))
+}
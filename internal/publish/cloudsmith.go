@@ -0,0 +1,228 @@
+package publish
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+
+	"github.com/charmbracelet/log"
+
+	"github.com/oarkflow/releaser/internal/artifact"
+	"github.com/oarkflow/releaser/internal/config"
+	"github.com/oarkflow/releaser/internal/httpclient"
+	"github.com/oarkflow/releaser/internal/retry"
+	"github.com/oarkflow/releaser/internal/tmpl"
+)
+
+// cloudsmithFormats maps package formats to Cloudsmith's upload formats
+var cloudsmithFormats = map[string]string{
+	"deb":    "deb",
+	"rpm":    "rpm",
+	"apk":    "alpine",
+	"python": "python",
+	"gem":    "ruby",
+}
+
+// CloudSmithPublisher publishes to CloudSmith
+type CloudSmithPublisher struct {
+	config  config.CloudSmith
+	tmplCtx *tmpl.Context
+}
+
+func init() {
+	RegisterRequirements(func(cfg *config.Config) []Requirement {
+		var reqs []Requirement
+		for _, cs := range cfg.CloudSmiths {
+			if cs.SkipUpload != "true" {
+				reqs = append(reqs, Requirement{NeededBy: neededBy("cloudsmith", cs.Owner+"/"+cs.Repository), Env: []string{"CLOUDSMITH_API_KEY"}, Disable: cs.Disable})
+			}
+		}
+		return reqs
+	})
+}
+
+// NewCloudSmithPublisher creates a new CloudSmith publisher
+func NewCloudSmithPublisher(cfg config.CloudSmith, tmplCtx *tmpl.Context) *CloudSmithPublisher {
+	return &CloudSmithPublisher{
+		config:  cfg,
+		tmplCtx: tmplCtx,
+	}
+}
+
+// Publish uploads the deb, rpm, apk, wheel and gem artifacts selected by
+// ids and formats. Each package is uploaded as a file, then created from it
+// in the repository.
+func (p *CloudSmithPublisher) Publish(ctx context.Context, artifacts []artifact.Artifact) error {
+	if p.config.SkipUpload == "true" {
+		log.Info("Skipping CloudSmith publish")
+		return nil
+	}
+	if p.config.Owner == "" || p.config.Repository == "" {
+		return fmt.Errorf("cloudsmith owner and repository are required")
+	}
+
+	packages := p.packages(artifacts)
+	if len(packages) == 0 {
+		log.Warn("No packages to publish to CloudSmith", "owner", p.config.Owner, "repo", p.config.Repository)
+		return nil
+	}
+
+	// Check distributions up front rather than after some uploads
+	for _, a := range packages {
+		if _, err := p.distribution(packageFormat(a)); err != nil {
+			return fmt.Errorf("cannot upload %s: %w", a.Name, err)
+		}
+	}
+
+	token := os.Getenv("CLOUDSMITH_API_KEY")
+	if token == "" {
+		return fmt.Errorf("CLOUDSMITH_API_KEY is required")
+	}
+
+	if err := Confirm(Action{
+		Description: fmt.Sprintf("Upload %d packages to Cloudsmith", len(packages)),
+		URL:         fmt.Sprintf("https://cloudsmith.io/~%s/repos/%s", p.config.Owner, p.config.Repository),
+		Files:       artifactPaths(packages),
+	}); err != nil {
+		return err
+	}
+
+	log.Info("Publishing to CloudSmith", "owner", p.config.Owner, "repo", p.config.Repository, "packages", len(packages))
+	for _, a := range packages {
+		if err := p.uploadPackage(ctx, token, a); err != nil {
+			return fmt.Errorf("failed to upload %s: %w", a.Name, err)
+		}
+	}
+	return nil
+}
+
+// packages returns the artifacts Cloudsmith takes that match the ids and
+// formats filters
+func (p *CloudSmithPublisher) packages(artifacts []artifact.Artifact) []artifact.Artifact {
+	var packages []artifact.Artifact
+	for _, a := range artifacts {
+		format := packageFormat(a)
+		if _, ok := cloudsmithFormats[format]; !ok || !matchesIDs(a, p.config.IDs) {
+			continue
+		}
+		if len(p.config.Formats) > 0 && !slices.Contains(p.config.Formats, format) {
+			continue
+		}
+		packages = append(packages, a)
+	}
+	return packages
+}
+
+// distribution returns the distribution of a format, which deb, rpm and apk
+// packages need, e.g. ubuntu/jammy
+func (p *CloudSmithPublisher) distribution(format string) (string, error) {
+	distribution := p.config.Distributions[format]
+	if distribution == "" {
+		distribution = p.config.Distribution
+	}
+	if distribution == "" && (format == "deb" || format == "rpm" || format == "apk") {
+		return "", fmt.Errorf("%s packages need a distribution, set distribution or distributions.%s", format, format)
+	}
+	return p.tmplCtx.Apply("cloudsmiths.distribution", distribution)
+}
+
+// uploadPackage uploads the package file, then creates the package from it
+func (p *CloudSmithPublisher) uploadPackage(ctx context.Context, token string, a artifact.Artifact) error {
+	data, err := os.ReadFile(a.Path)
+	if err != nil {
+		return err
+	}
+	sum := sha256.Sum256(data)
+	name := filepath.Base(a.Path)
+
+	var uploaded struct {
+		Identifier string `json:"identifier"`
+	}
+	fileURL := fmt.Sprintf("https://upload.cloudsmith.io/%s/%s/%s", p.config.Owner, p.config.Repository, name)
+	err = p.request(ctx, "PUT", fileURL, token, data, map[string]string{
+		"Content-Sha256": hex.EncodeToString(sum[:]),
+		"Content-Type":   "application/octet-stream",
+	}, &uploaded)
+	if err != nil {
+		return fmt.Errorf("file upload failed: %w", err)
+	}
+	if uploaded.Identifier == "" {
+		return fmt.Errorf("file upload returned no identifier")
+	}
+
+	format := packageFormat(a)
+	create := map[string]interface{}{
+		"package_file": uploaded.Identifier,
+		"republish":    p.config.Republish,
+	}
+	distribution, err := p.distribution(format)
+	if err != nil {
+		return err
+	}
+	if distribution != "" {
+		create["distribution"] = distribution
+	}
+	if format == "deb" {
+		component := p.config.Component
+		if component == "" {
+			component = "main"
+		}
+		create["component"] = component
+	}
+	body, err := json.Marshal(create)
+	if err != nil {
+		return err
+	}
+
+	createURL := fmt.Sprintf("https://api.cloudsmith.io/v1/packages/%s/%s/upload/%s/", p.config.Owner, p.config.Repository, cloudsmithFormats[format])
+	if err := p.request(ctx, "POST", createURL, token, body, map[string]string{"Content-Type": "application/json"}, nil); err != nil {
+		return fmt.Errorf("package creation failed: %w", err)
+	}
+
+	log.Info("Uploaded to CloudSmith", "name", a.Name, "format", cloudsmithFormats[format])
+	return nil
+}
+
+// request sends an authenticated request, retrying rate limits and server
+// errors, and decodes the JSON response into out when given
+func (p *CloudSmithPublisher) request(ctx context.Context, method, url, token string, data []byte, headers map[string]string, out interface{}) error {
+	return retry.Do(ctx, retry.DefaultOptions(method+" "+url), func(int) error {
+		req, err := http.NewRequestWithContext(ctx, method, url, bytes.NewReader(data))
+		if err != nil {
+			return retry.Permanent(err)
+		}
+		req.Header.Set("X-Api-Key", token)
+		for key, value := range headers {
+			req.Header.Set(key, value)
+		}
+
+		resp, err := httpclient.Client().Do(req)
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+
+		switch {
+		case resp.StatusCode < 300:
+			if out != nil {
+				if err := json.Unmarshal(body, out); err != nil {
+					return retry.Permanent(fmt.Errorf("invalid response: %w", err))
+				}
+			}
+			return nil
+		case resp.StatusCode >= 500 || resp.StatusCode == http.StatusTooManyRequests:
+			return fmt.Errorf("%s: %s", resp.Status, body)
+		default:
+			return retry.Permanent(fmt.Errorf("%s: %s", resp.Status, body))
+		}
+	})
+}
@@ -0,0 +1,136 @@
+package publish
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+
+	"github.com/oarkflow/releaser/internal/artifact"
+	"github.com/oarkflow/releaser/internal/redact"
+)
+
+// Action is a change a publisher is about to make: creating a release,
+// pushing to a tap, uploading a package
+type Action struct {
+	// Description says what happens, e.g. "Push Formula/st.rb to acme/tap"
+	Description string
+	// URL is where the change goes, a repository, registry or release
+	URL string
+	// Command is the command line run to make the change, if any
+	Command []string
+	// Files are the files uploaded or written
+	Files []string
+	// Content is the rendered manifest, such as a formula or PKGBUILD
+	Content string
+}
+
+// Confirmer approves the actions of publishers before they run. It returns
+// ErrDeclined to skip an action and ErrQuit to stop publishing.
+type Confirmer interface {
+	Confirm(a Action) error
+}
+
+var (
+	// ErrDeclined skips a declined action; its publisher is reported skipped
+	ErrDeclined = errors.New("declined at the confirmation prompt")
+	// ErrQuit stops publishing at the confirmation prompt
+	ErrQuit = errors.New("publishing stopped at the confirmation prompt")
+)
+
+// Confirmation approves every change publishers make when set, as releaser
+// publish --confirm does; nil approves all of them
+var Confirmation Confirmer
+
+// Confirm asks Confirmation to approve an action
+func Confirm(a Action) error {
+	if Confirmation == nil {
+		return nil
+	}
+	return Confirmation.Confirm(a)
+}
+
+// Prompt asks about each action on a terminal, answering y to run it, n to
+// skip it, a to run it and every later one, or q to stop publishing
+type Prompt struct {
+	in  *bufio.Reader
+	out io.Writer
+
+	mu  sync.Mutex
+	all bool
+}
+
+// NewPrompt creates a prompt reading answers from in and writing the
+// planned actions to out
+func NewPrompt(in io.Reader, out io.Writer) *Prompt {
+	return &Prompt{in: bufio.NewReader(in), out: out}
+}
+
+// Confirm shows the action and waits for an answer. Publishers uploading
+// in parallel are asked one at a time.
+func (p *Prompt) Confirm(a Action) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.all {
+		return nil
+	}
+
+	fmt.Fprint(p.out, redact.String(describe(a)))
+	for {
+		fmt.Fprint(p.out, "Proceed? [y]es, [n]o, [a]ll, [q]uit: ")
+		answer, err := p.in.ReadString('\n')
+		if err != nil && answer == "" {
+			// Input closed, nothing more can be approved
+			fmt.Fprintln(p.out)
+			return ErrQuit
+		}
+		switch strings.ToLower(strings.TrimSpace(answer)) {
+		case "y", "yes":
+			return nil
+		case "n", "no":
+			return ErrDeclined
+		case "a", "all":
+			p.all = true
+			return nil
+		case "q", "quit":
+			return ErrQuit
+		}
+	}
+}
+
+// artifactPaths returns the paths of artifacts, for actions uploading them
+func artifactPaths(artifacts []artifact.Artifact) []string {
+	paths := make([]string, 0, len(artifacts))
+	for _, a := range artifacts {
+		paths = append(paths, a.Path)
+	}
+	return paths
+}
+
+// describe renders an action for the prompt
+func describe(a Action) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "\n%s\n", a.Description)
+	if a.URL != "" {
+		fmt.Fprintf(&b, "  URL: %s\n", a.URL)
+	}
+	if len(a.Command) > 0 {
+		fmt.Fprintf(&b, "  Command: %s\n", strings.Join(a.Command, " "))
+	}
+	if len(a.Files) > 0 {
+		b.WriteString("  Files:\n")
+		for _, f := range a.Files {
+			fmt.Fprintf(&b, "    %s\n", f)
+		}
+	}
+	if a.Content != "" {
+		b.WriteString("  Content:\n")
+		for _, line := range strings.Split(strings.TrimRight(a.Content, "\n"), "\n") {
+			fmt.Fprintf(&b, "    | %s\n", line)
+		}
+	}
+	return b.String()
+}
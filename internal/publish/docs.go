@@ -0,0 +1,380 @@
+package publish
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/log"
+
+	"github.com/oarkflow/releaser/internal/artifact"
+	"github.com/oarkflow/releaser/internal/config"
+	"github.com/oarkflow/releaser/internal/tmpl"
+)
+
+// docsArtifactTypes are the downloads listed by the docs
+var docsArtifactTypes = []artifact.Type{
+	artifact.TypeArchive, artifact.TypeLinuxPackage, artifact.TypeDMG, artifact.TypePKG,
+	artifact.TypeMSI, artifact.TypeNSIS, artifact.TypeAppImage, artifact.TypeSnap, artifact.TypeFlatpak,
+}
+
+// DocsManifest is the JSON version manifest of the docs
+type DocsManifest struct {
+	ProjectName string          `json:"project_name"`
+	Version     string          `json:"version"`
+	Tag         string          `json:"tag"`
+	Date        string          `json:"date,omitempty"`
+	ReleaseURL  string          `json:"release_url,omitempty"`
+	Install     []InstallMethod `json:"install"`
+	Downloads   []Download      `json:"downloads"`
+}
+
+// InstallMethod is how a publisher's users install the release
+type InstallMethod struct {
+	Method  string `json:"method"`
+	Command string `json:"command"`
+}
+
+// Download is a release file of the docs
+type Download struct {
+	Name   string `json:"name"`
+	Type   string `json:"type"`
+	OS     string `json:"os,omitempty"`
+	Arch   string `json:"arch,omitempty"`
+	URL    string `json:"url"`
+	SHA256 string `json:"sha256"`
+}
+
+// DocsPublisher renders the version docs of a release and pushes them to
+// the docs repository
+type DocsPublisher struct {
+	config  *config.Config
+	tmplCtx *tmpl.Context
+	distDir string
+}
+
+func init() {
+	RegisterRequirements(func(cfg *config.Config) []Requirement {
+		if repo := cfg.Docs.Repository; repo.Owner != "" && needsGitHubToken(repo) {
+			return []Requirement{{NeededBy: "docs", Env: []string{"GITHUB_TOKEN"}, Disable: cfg.Docs.Disable}}
+		}
+		return nil
+	})
+}
+
+// NewDocsPublisher creates a docs publisher. It reads the whole config to
+// list the install command of every publisher.
+func NewDocsPublisher(cfg *config.Config, tmplCtx *tmpl.Context, distDir string) *DocsPublisher {
+	return &DocsPublisher{
+		config:  cfg,
+		tmplCtx: tmplCtx,
+		distDir: distDir,
+	}
+}
+
+// Render renders the docs into dist/docs and returns them keyed by their
+// path in the docs repository. The output only depends on the release, so
+// rendering the same tag again gives the same files.
+func (p *DocsPublisher) Render(artifacts []artifact.Artifact) (map[string]string, error) {
+	cfg := p.config.Docs
+	manifest, err := p.manifest(artifacts)
+	if err != nil {
+		return nil, err
+	}
+
+	install := make(map[string]string)
+	for _, m := range manifest.Install {
+		install[m.Method] = m.Command
+	}
+	p.tmplCtx.Set("Install", install)
+
+	files := make(map[string]string)
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	files[orDefault(cfg.Manifest, "versions.json")] = string(data) + "\n"
+	files[orDefault(cfg.Install, "install.md")] = renderInstall(manifest)
+
+	for i, t := range cfg.Templates {
+		source := fmt.Sprintf("docs.templates[%d]", i)
+		content := t.Content
+		if t.Src != "" {
+			src, err := p.tmplCtx.Apply(source+".src", t.Src)
+			if err != nil {
+				return nil, err
+			}
+			raw, err := os.ReadFile(src)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", source, err)
+			}
+			content = string(raw)
+		}
+		dst := t.Dst
+		if dst == "" {
+			dst = strings.TrimSuffix(filepath.Base(t.Src), ".tmpl")
+		}
+		if dst, err = p.tmplCtx.Apply(source+".dst", dst); err != nil {
+			return nil, err
+		}
+		if dst == "" || dst == "." {
+			return nil, fmt.Errorf("%s: dst is required", source)
+		}
+		if files[dst], err = p.tmplCtx.Apply(source, content); err != nil {
+			return nil, err
+		}
+	}
+
+	dir, err := p.tmplCtx.Apply("docs.directory", cfg.Directory)
+	if err != nil {
+		return nil, err
+	}
+	docs := make(map[string]string, len(files))
+	for name, content := range files {
+		name = path.Join(dir, filepath.ToSlash(name))
+		target := filepath.Join(p.distDir, "docs", filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+			return nil, err
+		}
+		if err := os.WriteFile(target, []byte(content), 0644); err != nil {
+			return nil, err
+		}
+		docs[name] = content
+	}
+	return docs, nil
+}
+
+// Publish renders the docs and commits the changed files to the docs
+// repository
+func (p *DocsPublisher) Publish(ctx context.Context, artifacts []artifact.Artifact) error {
+	files, err := p.Render(artifacts)
+	if err != nil {
+		return err
+	}
+	repo := p.config.Docs.Repository
+	if repo.Owner == "" && repo.Git.URL == "" {
+		log.Info("Docs rendered, no docs repository to push to", "dir", filepath.Join(p.distDir, "docs"))
+		return nil
+	}
+
+	message := fmt.Sprintf("Update %s docs to %s", p.tmplCtx.Get("ProjectName"), p.tmplCtx.Get("Version"))
+	if p.config.Docs.CommitMsgTemplate != "" {
+		if message, err = p.tmplCtx.Apply("docs.commit_msg_template", p.config.Docs.CommitMsgTemplate); err != nil {
+			return err
+		}
+	}
+	if err := commitFilesToRepo(ctx, p.tmplCtx, repo, files, message, p.config.Docs.CommitAuthor); err != nil {
+		return fmt.Errorf("failed to push docs: %w", err)
+	}
+
+	log.Info("Docs published", "repo", repoLabel(repo), "files", len(files))
+	return nil
+}
+
+// manifest collects the version, install methods and downloads of the
+// release
+func (p *DocsPublisher) manifest(artifacts []artifact.Artifact) (DocsManifest, error) {
+	m := DocsManifest{
+		ProjectName: p.tmplCtx.Get("ProjectName"),
+		Version:     p.tmplCtx.Get("Version"),
+		Tag:         p.tmplCtx.Get("Tag"),
+		ReleaseURL:  p.tmplCtx.Get("ReleaseURL"),
+		Downloads:   []Download{},
+	}
+	// The commit date, unlike the time of the run, is the same every run
+	if date, err := time.Parse(time.RFC3339, p.tmplCtx.Get("CommitDate")); err == nil {
+		m.Date = date.UTC().Format("2006-01-02")
+	}
+
+	install, err := p.installMethods()
+	if err != nil {
+		return m, err
+	}
+	m.Install = install
+
+	baseURL := p.tmplCtx.Get("ReleaseDownloadURL")
+	for _, a := range artifacts {
+		if !isDocsArtifact(a) {
+			continue
+		}
+		sum, err := artifactSHA256(a, artifacts)
+		if err != nil {
+			return m, fmt.Errorf("failed to hash %s: %w", a.Name, err)
+		}
+		m.Downloads = append(m.Downloads, Download{
+			Name:   a.Name,
+			Type:   string(a.Type),
+			OS:     a.Goos,
+			Arch:   a.Goarch,
+			URL:    baseURL + "/" + a.Name,
+			SHA256: sum,
+		})
+	}
+	sort.Slice(m.Downloads, func(i, j int) bool {
+		return m.Downloads[i].Name < m.Downloads[j].Name
+	})
+	return m, nil
+}
+
+// isDocsArtifact reports whether the artifact is a download of the docs
+func isDocsArtifact(a artifact.Artifact) bool {
+	for _, t := range docsArtifactTypes {
+		if a.Type == t {
+			return true
+		}
+	}
+	return false
+}
+
+// installMethods returns the install command of each enabled Homebrew,
+// Scoop, Winget, npm and Docker publisher
+func (p *DocsPublisher) installMethods() ([]InstallMethod, error) {
+	methods := []InstallMethod{}
+	projectName := p.tmplCtx.Get("ProjectName")
+
+	for i, brew := range p.config.Brews {
+		off, err := p.disabled(fmt.Sprintf("brews[%d].disable", i), brew.Disable)
+		if err != nil {
+			return nil, err
+		}
+		if off {
+			continue
+		}
+		owner, name := repoOwnerName(tapRepository(brew.Tap, brew.Repository))
+		formula := orDefault(brew.Name, projectName)
+		methods = append(methods, InstallMethod{
+			Method:  "homebrew",
+			Command: fmt.Sprintf("brew install %s/%s/%s", owner, strings.TrimPrefix(name, "homebrew-"), formula),
+		})
+	}
+
+	for i, scoop := range p.config.Scoops {
+		off, err := p.disabled(fmt.Sprintf("scoops[%d].disable", i), scoop.Disable)
+		if err != nil {
+			return nil, err
+		}
+		if off {
+			continue
+		}
+		bucket := tapRepository(scoop.Bucket, scoop.Repository)
+		owner, name := repoOwnerName(bucket)
+		url := bucket.Git.URL
+		if url == "" {
+			url = fmt.Sprintf("https://github.com/%s/%s", owner, name)
+		}
+		bucketName := strings.TrimPrefix(name, "scoop-")
+		methods = append(methods, InstallMethod{
+			Method:  "scoop",
+			Command: fmt.Sprintf("scoop bucket add %s %s\nscoop install %s/%s", bucketName, url, bucketName, orDefault(scoop.Name, projectName)),
+		})
+	}
+
+	for i, winget := range p.config.Wingets {
+		off, err := p.disabled(fmt.Sprintf("wingets[%d].disable", i), winget.Disable)
+		if err != nil {
+			return nil, err
+		}
+		if off {
+			continue
+		}
+		if winget.PackageIdentifier != "" {
+			methods = append(methods, InstallMethod{Method: "winget", Command: "winget install --id " + winget.PackageIdentifier})
+		}
+	}
+
+	for i, npm := range p.config.NPMs {
+		off, err := p.disabled(fmt.Sprintf("npms[%d].disable", i), npm.Disable)
+		if err != nil {
+			return nil, err
+		}
+		if off {
+			continue
+		}
+		name := (&NPMPublisher{config: npm, tmplCtx: p.tmplCtx}).packageName()
+		methods = append(methods, InstallMethod{Method: "npm", Command: fmt.Sprintf("npm install -g %s@%s", name, p.tmplCtx.Get("Version"))})
+	}
+
+	for i, d := range p.config.Dockers {
+		off, err := p.disabled(fmt.Sprintf("dockers[%d].disable", i), d.Disable)
+		if err != nil {
+			return nil, err
+		}
+		if off {
+			continue
+		}
+		if len(d.ImageTemplates) == 0 {
+			continue
+		}
+		image, err := p.tmplCtx.Apply(fmt.Sprintf("dockers[%d].image_templates[0]", i), d.ImageTemplates[0])
+		if err != nil {
+			return nil, err
+		}
+		methods = append(methods, InstallMethod{Method: "docker", Command: "docker pull " + image})
+	}
+	return methods, nil
+}
+
+// disabled evaluates a publisher's disable template
+func (p *DocsPublisher) disabled(source, disable string) (bool, error) {
+	if disable == "" {
+		return false, nil
+	}
+	out, err := p.tmplCtx.Apply(source, disable)
+	if err != nil {
+		return false, err
+	}
+	return strings.TrimSpace(out) == "true", nil
+}
+
+// repoOwnerName returns the owner and name of a repository, from its git
+// URL when no owner is configured
+func repoOwnerName(repo config.RepoRef) (string, string) {
+	if repo.Owner == "" && repo.Git.URL != "" {
+		return repoPath(repo.Git.URL)
+	}
+	return repo.Owner, repo.Name
+}
+
+// renderInstall renders the install instructions of the manifest
+func renderInstall(m DocsManifest) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# Installing %s\n\n", m.ProjectName)
+	fmt.Fprintf(&b, "The latest release is **%s**", m.Version)
+	if m.Date != "" {
+		fmt.Fprintf(&b, ", released on %s", m.Date)
+	}
+	b.WriteString(".")
+	if m.ReleaseURL != "" {
+		fmt.Fprintf(&b, " See the [release notes](%s).", m.ReleaseURL)
+	}
+	b.WriteString("\n")
+
+	titles := map[string]string{"homebrew": "Homebrew", "scoop": "Scoop", "winget": "Winget", "npm": "npm", "docker": "Docker"}
+	for _, method := range m.Install {
+		fmt.Fprintf(&b, "\n## %s\n\n```sh\n%s\n```\n", titles[method.Method], method.Command)
+	}
+
+	if len(m.Downloads) > 0 {
+		b.WriteString("\n## Downloads\n\n")
+		b.WriteString("| File | OS | Arch | SHA256 |\n")
+		b.WriteString("| --- | --- | --- | --- |\n")
+		for _, d := range m.Downloads {
+			fmt.Fprintf(&b, "| [%s](%s) | %s | %s | `%s` |\n", d.Name, d.URL, d.OS, d.Arch, d.SHA256)
+		}
+	}
+	return b.String()
+}
+
+// orDefault returns value, or def when it is empty
+func orDefault(value, def string) string {
+	if value == "" {
+		return def
+	}
+	return value
+}
@@ -0,0 +1,154 @@
+package publish
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+
+	"github.com/charmbracelet/log"
+
+	"github.com/oarkflow/releaser/internal/artifact"
+	"github.com/oarkflow/releaser/internal/config"
+	"github.com/oarkflow/releaser/internal/httpclient"
+	"github.com/oarkflow/releaser/internal/retry"
+	"github.com/oarkflow/releaser/internal/tmpl"
+)
+
+// furyFormats are the package formats Gemfury hosts
+var furyFormats = []string{"deb", "rpm", "python", "gem"}
+
+// FuryPublisher publishes to Fury.io
+type FuryPublisher struct {
+	config  config.Fury
+	tmplCtx *tmpl.Context
+}
+
+func init() {
+	RegisterRequirements(func(cfg *config.Config) []Requirement {
+		var reqs []Requirement
+		for _, fury := range cfg.Furies {
+			if fury.SkipUpload != "true" {
+				reqs = append(reqs, Requirement{NeededBy: neededBy("fury", fury.Account), Env: []string{"FURY_TOKEN"}, Disable: fury.Disable})
+			}
+		}
+		return reqs
+	})
+}
+
+// NewFuryPublisher creates a new Fury publisher
+func NewFuryPublisher(cfg config.Fury, tmplCtx *tmpl.Context) *FuryPublisher {
+	return &FuryPublisher{
+		config:  cfg,
+		tmplCtx: tmplCtx,
+	}
+}
+
+// Publish pushes the deb, rpm, wheel and gem artifacts selected by ids and
+// formats to the account with the Gemfury push API
+func (p *FuryPublisher) Publish(ctx context.Context, artifacts []artifact.Artifact) error {
+	if p.config.SkipUpload == "true" {
+		log.Info("Skipping Fury.io publish")
+		return nil
+	}
+	if p.config.Account == "" {
+		return fmt.Errorf("fury account is required")
+	}
+
+	packages := p.packages(artifacts)
+	if len(packages) == 0 {
+		log.Warn("No packages to publish to Fury.io", "account", p.config.Account)
+		return nil
+	}
+
+	token := os.Getenv("FURY_TOKEN")
+	if token == "" {
+		return fmt.Errorf("FURY_TOKEN is required")
+	}
+
+	if err := Confirm(Action{
+		Description: fmt.Sprintf("Upload %d packages to Gemfury", len(packages)),
+		URL:         fmt.Sprintf("https://manage.fury.io/dashboard/%s", p.config.Account),
+		Files:       artifactPaths(packages),
+	}); err != nil {
+		return err
+	}
+
+	log.Info("Publishing to Fury.io", "account", p.config.Account, "packages", len(packages))
+	for _, a := range packages {
+		if err := p.uploadPackage(ctx, token, a); err != nil {
+			return fmt.Errorf("failed to upload %s: %w", a.Name, err)
+		}
+	}
+	return nil
+}
+
+// packages returns the artifacts Gemfury takes that match the ids and
+// formats filters
+func (p *FuryPublisher) packages(artifacts []artifact.Artifact) []artifact.Artifact {
+	var packages []artifact.Artifact
+	for _, a := range artifacts {
+		format := packageFormat(a)
+		if !slices.Contains(furyFormats, format) || !matchesIDs(a, p.config.IDs) {
+			continue
+		}
+		if len(p.config.Formats) > 0 && !slices.Contains(p.config.Formats, format) {
+			continue
+		}
+		packages = append(packages, a)
+	}
+	return packages
+}
+
+// uploadPackage uploads a package to Fury.io
+func (p *FuryPublisher) uploadPackage(ctx context.Context, token string, a artifact.Artifact) error {
+	data, err := os.ReadFile(a.Path)
+	if err != nil {
+		return err
+	}
+
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	part, err := writer.CreateFormFile("package", filepath.Base(a.Path))
+	if err != nil {
+		return err
+	}
+	if _, err := part.Write(data); err != nil {
+		return err
+	}
+	if err := writer.Close(); err != nil {
+		return err
+	}
+
+	url := fmt.Sprintf("https://push.fury.io/%s/", p.config.Account)
+	return retry.Do(ctx, retry.DefaultOptions("upload "+a.Name), func(int) error {
+		req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(body.Bytes()))
+		if err != nil {
+			return retry.Permanent(err)
+		}
+		req.SetBasicAuth(token, "")
+		req.Header.Set("Content-Type", writer.FormDataContentType())
+
+		resp, err := httpclient.Client().Do(req)
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+		respBody, _ := io.ReadAll(resp.Body)
+
+		switch {
+		case resp.StatusCode < 300:
+			log.Info("Uploaded to Fury.io", "name", a.Name)
+			return nil
+		case resp.StatusCode >= 500 || resp.StatusCode == http.StatusTooManyRequests:
+			return fmt.Errorf("upload failed: %s: %s", resp.Status, respBody)
+		default:
+			return retry.Permanent(fmt.Errorf("upload failed: %s: %s", resp.Status, respBody))
+		}
+	})
+}
@@ -0,0 +1,396 @@
+package publish
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/charmbracelet/log"
+
+	"github.com/oarkflow/releaser/internal/artifact"
+	"github.com/oarkflow/releaser/internal/httpclient"
+	"github.com/oarkflow/releaser/internal/retry"
+)
+
+// gemLauncher is the Ruby executable of a binary gem. RubyGems loads
+// executables as Ruby, so it replaces itself with the binary.
+const gemLauncher = `#!/usr/bin/env ruby
+exe = File.expand_path(%s, __dir__)
+exec(exe, *ARGV)
+`
+
+// gemSpecTemplate is the YAML Gem::Specification stored in metadata.gz.
+// Strings go through quote.
+const gemSpecTemplate = `--- !ruby/object:Gem::Specification
+name: {{ quote .Name }}
+version: !ruby/object:Gem::Version
+  version: {{ quote .Version }}
+platform: {{ quote .Platform }}
+authors:
+{{- range .Authors }}
+- {{ quote . }}
+{{- else }} []
+{{- end }}
+autorequire:
+bindir: bin
+cert_chain: []
+date: {{ .Date }}
+dependencies: []
+description: {{ quote .Summary }}
+email:
+executables:
+{{- range .Executables }}
+- {{ quote . }}
+{{- end }}
+extensions: []
+extra_rdoc_files: []
+files:
+{{- range .Files }}
+- {{ quote . }}
+{{- end }}
+homepage: {{ if .Homepage }}{{ quote .Homepage }}{{ end }}
+licenses:
+{{- if .License }}
+- {{ quote .License }}
+{{- else }} []
+{{- end }}
+metadata: {}
+post_install_message:
+rdoc_options: []
+require_paths:
+- lib
+required_ruby_version: !ruby/object:Gem::Requirement
+  requirements:
+  - - ">="
+    - !ruby/object:Gem::Version
+      version: '0'
+required_rubygems_version: !ruby/object:Gem::Requirement
+  requirements:
+  - - ">="
+    - !ruby/object:Gem::Version
+      version: '0'
+requirements: []
+rubygems_version: 3.4.10
+signing_key:
+specification_version: 4
+summary: {{ quote .Summary }}
+test_files: []
+`
+
+// gemFile is a file of a gem's data.tar.gz
+type gemFile struct {
+	name string
+	data []byte
+	mode int64
+}
+
+// binaryGems builds a gem per platform with the selected binaries behind
+// Ruby launchers, and pushes them unless skip_upload is set.
+func (p *GemPublisher) binaryGems(ctx context.Context, artifacts []artifact.Artifact) error {
+	platforms := make(map[string][]artifact.Artifact)
+	for _, a := range artifacts {
+		if (a.Type != artifact.TypeBinary && a.Type != artifact.TypeUniversalBinary) || !matchesIDs(a, p.config.IDs) {
+			continue
+		}
+		platform := gemPlatform(a)
+		if platform == "" {
+			log.Debug("No gem platform for binary, skipping", "binary", a.Name, "os", a.Goos, "arch", a.Goarch)
+			continue
+		}
+		platforms[platform] = append(platforms[platform], a)
+	}
+	if len(platforms) == 0 {
+		return fmt.Errorf("no binaries found for gems")
+	}
+
+	dir := filepath.Join(p.distDir, "gems")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+
+	names := make([]string, 0, len(platforms))
+	for platform := range platforms {
+		names = append(names, platform)
+	}
+	sort.Strings(names)
+
+	var gems []string
+	for _, platform := range names {
+		binaries := platforms[platform]
+		path, err := p.buildGem(dir, platform, binaries)
+		if err != nil {
+			return fmt.Errorf("failed to build gem for %s: %w", platform, err)
+		}
+		if err := p.manager.Add(artifact.Artifact{
+			Name:    filepath.Base(path),
+			Path:    path,
+			Type:    artifact.TypeGem,
+			Goos:    binaries[0].Goos,
+			Goarch:  binaries[0].Goarch,
+			Goarm:   binaries[0].Goarm,
+			BuildID: binaries[0].BuildID,
+		}); err != nil {
+			return err
+		}
+		log.Info("Gem created", "path", path)
+		gems = append(gems, path)
+	}
+
+	if p.config.SkipUpload == "true" {
+		log.Info("Skipping gem push")
+		return nil
+	}
+
+	apiKey := p.config.APIKey
+	if apiKey == "" {
+		apiKey = os.Getenv("GEM_HOST_API_KEY")
+	}
+	if apiKey == "" {
+		return fmt.Errorf("gem API key is required, set api_key or GEM_HOST_API_KEY")
+	}
+	for _, gem := range gems {
+		if err := p.pushGem(ctx, gem, apiKey); err != nil {
+			return fmt.Errorf("failed to push %s: %w", filepath.Base(gem), err)
+		}
+	}
+
+	log.Info("Gems published successfully", "count", len(gems))
+	return nil
+}
+
+// buildGem writes the gem of one platform into dir. A gem is a tar of the
+// gzipped spec, the gzipped data tar and their checksums.
+func (p *GemPublisher) buildGem(dir, platform string, binaries []artifact.Artifact) (string, error) {
+	name := p.config.Name
+	if name == "" {
+		name = p.tmplCtx.Get("ProjectName")
+	}
+	version := gemVersion(p.tmplCtx.Get("Version"))
+	date := sourceDate(p.tmplCtx)
+
+	var files []gemFile
+	var executables []string
+	for _, bin := range binaries {
+		data, err := os.ReadFile(bin.Path)
+		if err != nil {
+			return "", err
+		}
+		exe := filepath.Base(bin.Path)
+		script := strings.TrimSuffix(bin.Name, ".exe")
+		target, _ := json.Marshal("../libexec/" + exe)
+		files = append(files,
+			gemFile{name: "bin/" + script, data: []byte(fmt.Sprintf(gemLauncher, target)), mode: 0755},
+			gemFile{name: "libexec/" + exe, data: data, mode: 0755},
+		)
+		executables = append(executables, script)
+	}
+	fileNames := make([]string, len(files))
+	for i, f := range files {
+		fileNames[i] = f.name
+	}
+	sort.Strings(fileNames)
+
+	summary := p.config.Summary
+	if summary == "" {
+		summary = name
+	}
+	specTmpl, err := template.New("gemspec").Funcs(template.FuncMap{"quote": yamlQuote}).Parse(gemSpecTemplate)
+	if err != nil {
+		return "", err
+	}
+	var spec bytes.Buffer
+	err = specTmpl.Execute(&spec, map[string]interface{}{
+		"Name":        name,
+		"Version":     version,
+		"Platform":    platform,
+		"Authors":     p.config.Authors,
+		"Date":        date.Truncate(24 * time.Hour).Format("2006-01-02 15:04:05.000000000 Z"),
+		"Summary":     summary,
+		"Executables": executables,
+		"Files":       fileNames,
+		"Homepage":    p.config.Homepage,
+		"License":     p.config.License,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	metadata, err := gzipBytes(spec.Bytes(), date)
+	if err != nil {
+		return "", err
+	}
+
+	var dataTar bytes.Buffer
+	tw := tar.NewWriter(&dataTar)
+	for _, f := range files {
+		if err := writeTarFile(tw, f.name, f.data, f.mode, date); err != nil {
+			return "", err
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return "", err
+	}
+	data, err := gzipBytes(dataTar.Bytes(), date)
+	if err != nil {
+		return "", err
+	}
+
+	// checksums.yaml lists the digests of the two archives
+	type gemEntry struct {
+		name string
+		data []byte
+	}
+	entries := []gemEntry{{"metadata.gz", metadata}, {"data.tar.gz", data}}
+	var checksums strings.Builder
+	checksums.WriteString("---\nSHA256:\n")
+	for _, entry := range entries {
+		sum := sha256.Sum256(entry.data)
+		fmt.Fprintf(&checksums, "  %s: %s\n", entry.name, hex.EncodeToString(sum[:]))
+	}
+	checksums.WriteString("SHA512:\n")
+	for _, entry := range entries {
+		sum := sha512.Sum512(entry.data)
+		fmt.Fprintf(&checksums, "  %s: %s\n", entry.name, hex.EncodeToString(sum[:]))
+	}
+	checksumsGz, err := gzipBytes([]byte(checksums.String()), date)
+	if err != nil {
+		return "", err
+	}
+
+	path := filepath.Join(dir, fmt.Sprintf("%s-%s-%s.gem", name, version, platform))
+	out, err := os.Create(path)
+	if err != nil {
+		return "", err
+	}
+	defer out.Close()
+
+	gw := tar.NewWriter(out)
+	for _, entry := range append(entries, gemEntry{"checksums.yaml.gz", checksumsGz}) {
+		if err := writeTarFile(gw, entry.name, entry.data, 0444, date); err != nil {
+			return "", err
+		}
+	}
+	if err := gw.Close(); err != nil {
+		return "", err
+	}
+	return path, out.Close()
+}
+
+// pushGem pushes a gem with the RubyGems API
+func (p *GemPublisher) pushGem(ctx context.Context, path, apiKey string) error {
+	host := strings.TrimSuffix(p.config.Host, "/")
+	if host == "" {
+		host = "https://rubygems.org"
+	}
+
+	if err := Confirm(Action{
+		Description: fmt.Sprintf("Push %s to RubyGems", filepath.Base(path)),
+		URL:         host,
+		Files:       []string{path},
+	}); err != nil {
+		return err
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	return retry.Do(ctx, retry.DefaultOptions("push "+filepath.Base(path)), func(int) error {
+		req, err := http.NewRequestWithContext(ctx, "POST", host+"/api/v1/gems", bytes.NewReader(data))
+		if err != nil {
+			return retry.Permanent(err)
+		}
+		req.Header.Set("Authorization", apiKey)
+		req.Header.Set("Content-Type", "application/octet-stream")
+
+		resp, err := httpclient.Client().Do(req)
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+
+		switch {
+		case resp.StatusCode < 300:
+			log.Info("Pushed gem", "name", filepath.Base(path))
+			return nil
+		case resp.StatusCode >= 500:
+			return fmt.Errorf("push failed: %s: %s", resp.Status, body)
+		default:
+			return retry.Permanent(fmt.Errorf("push failed: %s: %s", resp.Status, body))
+		}
+	})
+}
+
+// gemPlatform returns the RubyGems platform of a binary, or "" if there is
+// none for its target
+func gemPlatform(a artifact.Artifact) string {
+	switch a.Goos {
+	case "linux":
+		return map[string]string{"amd64": "x86_64-linux", "arm64": "aarch64-linux", "386": "x86-linux", "arm": "arm-linux"}[a.Goarch]
+	case "darwin":
+		return map[string]string{"amd64": "x86_64-darwin", "arm64": "arm64-darwin", "universal": "universal-darwin"}[a.Goarch]
+	case "windows":
+		return map[string]string{"amd64": "x64-mingw-ucrt", "386": "x86-mingw32", "arm64": "aarch64-mingw-ucrt"}[a.Goarch]
+	}
+	return ""
+}
+
+// gemVersion turns a semantic version into the form RubyGems stores,
+// prereleases being marked with ".pre." and build metadata dropped
+func gemVersion(version string) string {
+	v := strings.TrimPrefix(version, "v")
+	v, _, _ = strings.Cut(v, "+")
+	return strings.ReplaceAll(v, "-", ".pre.")
+}
+
+// yamlQuote quotes a string for YAML; JSON strings are valid YAML scalars
+func yamlQuote(s string) string {
+	quoted, _ := json.Marshal(s)
+	return string(quoted)
+}
+
+// gzipBytes compresses data with a fixed modification time
+func gzipBytes(data []byte, modTime time.Time) ([]byte, error) {
+	var buf bytes.Buffer
+	gw := gzip.NewWriter(&buf)
+	gw.ModTime = modTime
+	if _, err := gw.Write(data); err != nil {
+		return nil, err
+	}
+	if err := gw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// writeTarFile writes a regular file to a tar
+func writeTarFile(tw *tar.Writer, name string, data []byte, mode int64, modTime time.Time) error {
+	header := &tar.Header{
+		Name:     name,
+		Mode:     mode,
+		Size:     int64(len(data)),
+		ModTime:  modTime,
+		Typeflag: tar.TypeReg,
+	}
+	if err := tw.WriteHeader(header); err != nil {
+		return err
+	}
+	_, err := tw.Write(data)
+	return err
+}
@@ -0,0 +1,472 @@
+package publish
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/charmbracelet/log"
+
+	"github.com/oarkflow/releaser/internal/config"
+	"github.com/oarkflow/releaser/internal/github"
+	"github.com/oarkflow/releaser/internal/redact"
+	"github.com/oarkflow/releaser/internal/tmpl"
+)
+
+// gitCommit is a commit of files pushed to a repository over git
+type gitCommit struct {
+	Files   map[string]string
+	Message string
+	Author  config.CommitAuthor
+
+	// Base is the branch to start from; the default branch when empty
+	Base string
+
+	// Branch is the branch to push; Base when empty
+	Branch string
+
+	// Force overwrites the pushed branch, for pull request branches
+	Force bool
+}
+
+// tapRepository returns the repository a tap-style publisher pushes to: the
+// tap, or the repository when no tap is configured
+func tapRepository(tap, repository config.RepoRef) config.RepoRef {
+	if tap.Owner == "" && tap.Git.URL == "" && (repository.Owner != "" || repository.Git.URL != "") {
+		return repository
+	}
+	return tap
+}
+
+// needsGitHubToken reports whether pushing to the repository goes through
+// the GitHub API: always without repository.git, and for pull requests
+func needsGitHubToken(repo config.RepoRef) bool {
+	return repo.Git.URL == "" || repo.PullRequest.Enabled
+}
+
+// repoLabel names the repository in logs: owner/name, or the git URL
+func repoLabel(repo config.RepoRef) string {
+	if repo.Owner != "" {
+		return fmt.Sprintf("%s/%s", repo.Owner, repo.Name)
+	}
+	return redact.String(repo.Git.URL)
+}
+
+// commitToRepo writes a file to a tap-style repository: over git when
+// repository.git.url is set, through the GitHub API otherwise
+func commitToRepo(ctx context.Context, tmplCtx *tmpl.Context, repo config.RepoRef, path, content, message string, author config.CommitAuthor) error {
+	if err := confirmCommit(repo, map[string]string{path: content}, message); err != nil {
+		return err
+	}
+	if repo.Git.URL == "" {
+		token := os.Getenv("GITHUB_TOKEN")
+		if token == "" {
+			return fmt.Errorf("GITHUB_TOKEN is required, or set repository.git.url to push over git")
+		}
+		if author.Signing.Enabled {
+			log.Warn("Commits through the GitHub API are not signed, set repository.git.url to sign them")
+		}
+		return commitToGitHubRepo(ctx, github.New(token), tmplCtx, repo, path, content, message, author)
+	}
+
+	files := map[string]string{path: content}
+	if !repo.PullRequest.Enabled {
+		return pushToGitRepo(ctx, tmplCtx, repo, gitCommit{Files: files, Message: message, Author: author, Base: repo.Branch})
+	}
+
+	pr, err := pullRequestFromConfig(tmplCtx, repo, message)
+	if err != nil {
+		return err
+	}
+	if pr.Branch == "" {
+		pr.Branch = defaultBranchName(tmplCtx, path)
+	}
+	pr.Files = files
+	pr.Author = author
+	_, err = pushPullRequest(ctx, tmplCtx, repo, pr)
+	return err
+}
+
+// commitFilesToRepo commits files to a repository in one commit over git,
+// or file by file through the GitHub API. Files that are already up to
+// date are left alone, so pushing the same files again changes nothing.
+func commitFilesToRepo(ctx context.Context, tmplCtx *tmpl.Context, repo config.RepoRef, files map[string]string, message string, author config.CommitAuthor) error {
+	paths := make([]string, 0, len(files))
+	for path := range files {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	if err := confirmCommit(repo, files, message); err != nil {
+		return err
+	}
+	if repo.Git.URL != "" {
+		if !repo.PullRequest.Enabled {
+			return pushToGitRepo(ctx, tmplCtx, repo, gitCommit{Files: files, Message: message, Author: author, Base: repo.Branch})
+		}
+		pr, err := pullRequestFromConfig(tmplCtx, repo, message)
+		if err != nil {
+			return err
+		}
+		if pr.Branch == "" {
+			pr.Branch = defaultBranchName(tmplCtx, "docs")
+		}
+		pr.Files = files
+		pr.Author = author
+		_, err = pushPullRequest(ctx, tmplCtx, repo, pr)
+		return err
+	}
+
+	token := os.Getenv("GITHUB_TOKEN")
+	if token == "" {
+		return fmt.Errorf("GITHUB_TOKEN is required, or set repository.git.url to push over git")
+	}
+	client := github.New(token)
+	if repo.PullRequest.Enabled {
+		pr, err := pullRequestFromConfig(tmplCtx, repo, message)
+		if err != nil {
+			return err
+		}
+		if pr.Branch == "" {
+			pr.Branch = defaultBranchName(tmplCtx, "docs")
+		}
+		pr.Files = files
+		pr.Author = author
+		_, err = openGitHubPullRequest(ctx, client, pr)
+		return err
+	}
+
+	for _, path := range paths {
+		sha, err := getGitHubFileSHA(ctx, client, repo.Owner, repo.Name, path, repo.Branch)
+		if err != nil {
+			return fmt.Errorf("failed to check %s: %w", path, err)
+		}
+		if sha == gitBlobSHA(files[path]) {
+			log.Debug("File is already up to date", "path", path)
+			continue
+		}
+		if err := putGitHubFile(ctx, client, repo.Owner, repo.Name, repo.Branch, path, files[path], message, sha, author); err != nil {
+			return fmt.Errorf("failed to update %s: %w", path, err)
+		}
+	}
+	return nil
+}
+
+// confirmCommit asks to approve committing files to a repository, showing
+// their content
+func confirmCommit(repo config.RepoRef, files map[string]string, message string) error {
+	paths := make([]string, 0, len(files))
+	for path := range files {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	var content strings.Builder
+	for _, path := range paths {
+		if len(paths) > 1 {
+			fmt.Fprintf(&content, "==> %s\n", path)
+		}
+		content.WriteString(files[path])
+		if !strings.HasSuffix(files[path], "\n") {
+			content.WriteString("\n")
+		}
+	}
+
+	description := fmt.Sprintf("Commit %q to %s", message, repoLabel(repo))
+	if repo.PullRequest.Enabled {
+		description = fmt.Sprintf("Open a pull request %q against %s", message, repoLabel(repo))
+	}
+	url := redact.String(repo.Git.URL)
+	if url == "" {
+		url = fmt.Sprintf("https://github.com/%s/%s", repo.Owner, repo.Name)
+	}
+	return Confirm(Action{Description: description, URL: url, Files: paths, Content: content.String()})
+}
+
+// gitBlobSHA returns the git object ID of a file's content, which the
+// contents API reports as the file's sha
+func gitBlobSHA(content string) string {
+	h := sha1.New()
+	fmt.Fprintf(h, "blob %d\x00", len(content))
+	h.Write([]byte(content))
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// pushPullRequest pushes the files of a pull request to its branch over
+// git and opens the pull request through the GitHub API. The git URL may
+// point at a fork of the upstream repository.
+func pushPullRequest(ctx context.Context, tmplCtx *tmpl.Context, repo config.RepoRef, pr githubPullRequest) (string, error) {
+	token := os.Getenv("GITHUB_TOKEN")
+	if token == "" {
+		return "", fmt.Errorf("GITHUB_TOKEN is required to open the pull request")
+	}
+	remote, err := tmplCtx.Apply("repository.git.url", repo.Git.URL)
+	if err != nil {
+		return "", err
+	}
+	headOwner, headRepo := repoPath(remote)
+	if pr.Owner == "" {
+		pr.Owner, pr.Repo = headOwner, headRepo
+	}
+
+	client := github.New(token)
+	base, err := pullRequestBase(ctx, client, pr)
+	if err != nil {
+		return "", err
+	}
+
+	// A fork starts from its own default branch, the upstream base may
+	// not exist in it
+	start := base
+	if headOwner != pr.Owner {
+		start = ""
+	}
+	if err := pushToGitRepo(ctx, tmplCtx, repo, gitCommit{
+		Files:   pr.Files,
+		Message: pr.Message,
+		Author:  pr.Author,
+		Base:    start,
+		Branch:  pr.Branch,
+		Force:   true,
+	}); err != nil {
+		return "", err
+	}
+	return proposeGitHubPullRequest(ctx, client, pr, headOwner, base)
+}
+
+// pushToGitRepo clones the repository, writes the files, commits them as
+// the author, signed with commit_author.signing, and pushes the commit
+func pushToGitRepo(ctx context.Context, tmplCtx *tmpl.Context, repo config.RepoRef, commit gitCommit) error {
+	remote, err := tmplCtx.Apply("repository.git.url", repo.Git.URL)
+	if err != nil {
+		return err
+	}
+
+	tmpDir, err := os.MkdirTemp("", "releaser-git-*")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tmpDir)
+
+	env, err := gitEnv(tmplCtx, repo.Git, tmpDir)
+	if err != nil {
+		return err
+	}
+	auth, err := tokenEnv(tmplCtx, remote, repo.Token)
+	if err != nil {
+		return err
+	}
+	env = append(env, auth...)
+
+	dir := filepath.Join(tmpDir, "repo")
+	run := func(args ...string) error {
+		cmd := exec.CommandContext(ctx, "git", args...)
+		cmd.Env = env
+		if args[0] != "clone" {
+			cmd.Dir = dir
+		}
+		if out, err := cmd.CombinedOutput(); err != nil {
+			return fmt.Errorf("git %s: %w: %s", args[0], err, redact.String(strings.TrimSpace(string(out))))
+		}
+		return nil
+	}
+
+	clone := []string{"clone", "--depth", "1"}
+	if commit.Base != "" {
+		clone = append(clone, "--branch", commit.Base)
+	}
+	if err := run(append(clone, remote, dir)...); err != nil {
+		return err
+	}
+	branch := commit.Branch
+	if branch != "" && branch != commit.Base {
+		if err := run("checkout", "-B", branch); err != nil {
+			return err
+		}
+	}
+
+	paths := make([]string, 0, len(commit.Files))
+	for path := range commit.Files {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	for _, path := range paths {
+		target := filepath.Join(dir, filepath.FromSlash(path))
+		if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+			return err
+		}
+		if err := os.WriteFile(target, []byte(commit.Files[path]), 0644); err != nil {
+			return err
+		}
+	}
+	if err := run(append([]string{"add", "--"}, paths...)...); err != nil {
+		return err
+	}
+
+	diff := exec.CommandContext(ctx, "git", "diff", "--cached", "--quiet")
+	diff.Dir = dir
+	if diff.Run() == nil {
+		log.Info("Repository is already up to date", "files", strings.Join(paths, ", "))
+		return nil
+	}
+
+	args, err := commitArgs(tmplCtx, commit.Author)
+	if err != nil {
+		return err
+	}
+	if err := run(append(args, "-m", commit.Message)...); err != nil {
+		return err
+	}
+
+	push := []string{"push"}
+	if commit.Force {
+		push = append(push, "--force")
+	}
+	ref := "HEAD"
+	if branch != "" {
+		ref = "HEAD:refs/heads/" + branch
+	}
+	if err := run(append(push, "origin", ref)...); err != nil {
+		return err
+	}
+
+	log.Info("Pushed over git", "repo", redact.String(remote), "files", strings.Join(paths, ", "))
+	return nil
+}
+
+// commitArgs returns the git commit command with the author and the
+// signing settings
+func commitArgs(tmplCtx *tmpl.Context, author config.CommitAuthor) ([]string, error) {
+	name := author.Name
+	if name == "" {
+		name = "Releaser"
+	}
+	email := author.Email
+	if email == "" {
+		email = "releaser@example.com"
+	}
+	args := []string{"-c", "user.name=" + name, "-c", "user.email=" + email}
+
+	signing := author.Signing
+	if !signing.Enabled {
+		return append(args, "commit"), nil
+	}
+	switch signing.Format {
+	case "", "openpgp", "x509", "ssh":
+	default:
+		return nil, fmt.Errorf("commit_author.signing.format must be openpgp, x509 or ssh, got %q", signing.Format)
+	}
+	if signing.Key != "" {
+		key, err := tmplCtx.Apply("commit_author.signing.key", signing.Key)
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, "-c", "user.signingkey="+key)
+	}
+	if signing.Program != "" {
+		args = append(args, "-c", "gpg.program="+signing.Program)
+	}
+	if signing.Format != "" {
+		args = append(args, "-c", "gpg.format="+signing.Format)
+	}
+	return append(args, "commit", "--gpg-sign"), nil
+}
+
+// gitEnv returns the environment of the git commands. ssh authenticates
+// with ssh_command, or with private_key, written to dir when it is the key
+// itself rather than a path.
+func gitEnv(tmplCtx *tmpl.Context, git config.GitRepoRef, dir string) ([]string, error) {
+	env := append(os.Environ(), "GIT_TERMINAL_PROMPT=0")
+	switch {
+	case git.SSHCommand != "":
+		command, err := tmplCtx.Apply("repository.git.ssh_command", git.SSHCommand)
+		if err != nil {
+			return nil, err
+		}
+		env = append(env, "GIT_SSH_COMMAND="+command)
+	case git.PrivateKey != "":
+		key, err := tmplCtx.Apply("repository.git.private_key", git.PrivateKey)
+		if err != nil {
+			return nil, err
+		}
+		if strings.Contains(key, "PRIVATE KEY") {
+			path := filepath.Join(dir, "id_key")
+			if err := os.WriteFile(path, []byte(strings.TrimSpace(key)+"\n"), 0600); err != nil {
+				return nil, err
+			}
+			key = path
+		}
+		// Unknown hosts are trusted on first use; a changed host key fails
+		env = append(env, "GIT_SSH_COMMAND=ssh -i "+key+" -o IdentitiesOnly=yes -o StrictHostKeyChecking=accept-new")
+	}
+	return env, nil
+}
+
+// tokenEnv returns the environment authenticating git with a token over
+// https: the repository token, or the GITLAB_TOKEN, GITEA_TOKEN or
+// GITHUB_TOKEN env var depending on the host. The token is sent as an
+// http.extraHeader set through GIT_CONFIG_* so that it appears neither in
+// the command line nor in the cloned .git/config. Other URLs need nothing.
+func tokenEnv(tmplCtx *tmpl.Context, remote, token string) ([]string, error) {
+	u, err := url.Parse(remote)
+	if err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.User != nil {
+		return nil, nil
+	}
+
+	if token != "" {
+		if token, err = tmplCtx.Apply("repository.token", token); err != nil {
+			return nil, err
+		}
+	} else {
+		switch {
+		case strings.Contains(u.Host, "gitlab"):
+			token = os.Getenv("GITLAB_TOKEN")
+		case strings.Contains(u.Host, "gitea"), strings.Contains(u.Host, "codeberg"):
+			token = os.Getenv("GITEA_TOKEN")
+		default:
+			token = os.Getenv("GITHUB_TOKEN")
+		}
+	}
+	if token == "" {
+		return nil, errors.New("repository.git.url is https but no token is set, set repository.token or use an ssh URL")
+	}
+	redact.Add(token)
+
+	// GitLab wants the oauth2 user, GitHub and Gitea take the token as user
+	credentials := token + ":"
+	if strings.Contains(u.Host, "gitlab") {
+		credentials = "oauth2:" + token
+	}
+	header := "Authorization: Basic " + base64.StdEncoding.EncodeToString([]byte(credentials))
+	redact.Add(header)
+	return []string{
+		"GIT_CONFIG_COUNT=1",
+		"GIT_CONFIG_KEY_0=http." + u.Scheme + "://" + u.Host + "/.extraHeader",
+		"GIT_CONFIG_VALUE_0=" + header,
+	}, nil
+}
+
+// repoPath returns the owner and name of a repository URL, e.g. acme and
+// homebrew-tap for git@github.com:acme/homebrew-tap.git
+func repoPath(remote string) (string, string) {
+	path := remote
+	if u, err := url.Parse(remote); err == nil && u.Host != "" {
+		path = u.Path
+	} else if _, rest, ok := strings.Cut(remote, ":"); ok {
+		path = rest
+	}
+	parts := strings.Split(strings.Trim(strings.TrimSuffix(path, ".git"), "/"), "/")
+	if len(parts) < 2 {
+		return "", ""
+	}
+	return parts[len(parts)-2], parts[len(parts)-1]
+}
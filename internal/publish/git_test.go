@@ -0,0 +1,84 @@
+package publish
+
+import (
+	"encoding/base64"
+	"strings"
+	"testing"
+
+	"github.com/oarkflow/releaser/internal/config"
+)
+
+func TestTokenEnv(t *testing.T) {
+	t.Setenv("GITHUB_TOKEN", "ghp-token")
+	t.Setenv("GITLAB_TOKEN", "glpat-token")
+
+	tests := []struct {
+		name   string
+		remote string
+		token  string
+		key    string
+		want   string
+	}{
+		{name: "github", remote: "https://github.com/acme/homebrew-tap.git", key: "http.https://github.com/.extraHeader", want: "ghp-token:"},
+		{name: "gitlab", remote: "https://gitlab.example.com/acme/tap.git", key: "http.https://gitlab.example.com/.extraHeader", want: "oauth2:glpat-token"},
+		{name: "repository token", remote: "https://git.example.com/acme/tap.git", token: "{{ .ProjectName }}-token", key: "http.https://git.example.com/.extraHeader", want: "demo-token:"},
+		{name: "ssh", remote: "git@github.com:acme/homebrew-tap.git"},
+		{name: "credentials in the URL", remote: "https://bot@github.com/acme/homebrew-tap.git"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env, err := tokenEnv(testTemplateContext(t), tt.remote, tt.token)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.want == "" {
+				if env != nil {
+					t.Errorf("tokenEnv() = %v, want nothing", env)
+				}
+				return
+			}
+
+			vars := map[string]string{}
+			for _, kv := range env {
+				k, v, _ := strings.Cut(kv, "=")
+				vars[k] = v
+			}
+			if vars["GIT_CONFIG_COUNT"] != "1" || vars["GIT_CONFIG_KEY_0"] != tt.key {
+				t.Errorf("tokenEnv() = %v", env)
+			}
+			encoded, ok := strings.CutPrefix(vars["GIT_CONFIG_VALUE_0"], "Authorization: Basic ")
+			if !ok {
+				t.Fatalf("header = %q", vars["GIT_CONFIG_VALUE_0"])
+			}
+			credentials, err := base64.StdEncoding.DecodeString(encoded)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(credentials) != tt.want {
+				t.Errorf("credentials = %q, want %q", credentials, tt.want)
+			}
+		})
+	}
+
+	t.Setenv("GITHUB_TOKEN", "")
+	if _, err := tokenEnv(testTemplateContext(t), "https://github.com/acme/tap.git", ""); err == nil {
+		t.Error("tokenEnv() without a token succeeded")
+	}
+}
+
+func TestGitEnvChecksHostKeys(t *testing.T) {
+	env, err := gitEnv(testTemplateContext(t), config.GitRepoRef{PrivateKey: "/keys/id_ed25519"}, t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var command string
+	for _, kv := range env {
+		if v, ok := strings.CutPrefix(kv, "GIT_SSH_COMMAND="); ok {
+			command = v
+		}
+	}
+	if !strings.Contains(command, "StrictHostKeyChecking=accept-new") || strings.Contains(command, "StrictHostKeyChecking=no") {
+		t.Errorf("GIT_SSH_COMMAND = %q", command)
+	}
+}
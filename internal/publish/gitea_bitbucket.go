@@ -15,6 +15,7 @@ import (
 
 	"github.com/oarkflow/releaser/internal/artifact"
 	"github.com/oarkflow/releaser/internal/config"
+	"github.com/oarkflow/releaser/internal/httpclient"
 	"github.com/oarkflow/releaser/internal/tmpl"
 )
 
@@ -59,8 +60,8 @@ func (p *BitbucketPublisher) Publish(ctx context.Context, artifacts []artifact.A
 	}
 
 	// Apply templates
-	workspace, _ = p.tmplCtx.Apply(workspace)
-	repo, _ = p.tmplCtx.Apply(repo)
+	workspace, _ = p.tmplCtx.Apply("release.github.owner", workspace)
+	repo, _ = p.tmplCtx.Apply("release.github.name", repo)
 
 	log.Info("Publishing to Bitbucket Downloads", "workspace", workspace, "repo", repo)
 
@@ -111,7 +112,7 @@ func (p *BitbucketPublisher) uploadDownload(ctx context.Context, workspace, repo
 	req.SetBasicAuth(p.username, p.password)
 	req.Header.Set("Content-Type", "multipart/form-data; boundary="+boundary)
 
-	resp, err := http.DefaultClient.Do(req)
+	resp, err := httpclient.Client().Do(req)
 	if err != nil {
 		return err
 	}
@@ -163,8 +164,8 @@ func (p *GiteaPublisher) Publish(ctx context.Context, artifacts []artifact.Artif
 	}
 
 	// Apply templates
-	owner, _ = p.tmplCtx.Apply(owner)
-	repo, _ = p.tmplCtx.Apply(repo)
+	owner, _ = p.tmplCtx.Apply("release.gitea.owner", owner)
+	repo, _ = p.tmplCtx.Apply("release.gitea.name", repo)
 
 	log.Info("Publishing to Gitea Releases", "owner", owner, "repo", repo, "base_url", p.baseURL)
 
@@ -194,7 +195,7 @@ func (p *GiteaPublisher) getOrCreateRelease(ctx context.Context, owner, repo, ta
 	req, _ := http.NewRequestWithContext(ctx, "GET", url, nil)
 	req.Header.Set("Authorization", "token "+p.token)
 
-	resp, err := http.DefaultClient.Do(req)
+	resp, err := httpclient.Client().Do(req)
 	if err != nil {
 		return 0, err
 	}
@@ -216,14 +217,14 @@ func (p *GiteaPublisher) getOrCreateRelease(ctx context.Context, owner, repo, ta
 	if name == "" {
 		name = tag
 	}
-	name, _ = p.tmplCtx.Apply(name)
+	name, _ = p.tmplCtx.Apply("release.name_template", name)
 
 	body := map[string]interface{}{
 		"tag_name":   tag,
 		"name":       name,
-		"body":       p.tmplCtx.Get("Changelog"),
+		"body":       p.tmplCtx.Get("ReleaseNotes"),
 		"draft":      p.config.Draft,
-		"prerelease": p.config.Prerelease == "true",
+		"prerelease": markPrerelease(p.config, p.tmplCtx),
 	}
 
 	if p.config.TargetCommitish != "" {
@@ -237,7 +238,7 @@ func (p *GiteaPublisher) getOrCreateRelease(ctx context.Context, owner, repo, ta
 	req.Header.Set("Authorization", "token "+p.token)
 	req.Header.Set("Content-Type", "application/json")
 
-	resp, err = http.DefaultClient.Do(req)
+	resp, err = httpclient.Client().Do(req)
 	if err != nil {
 		return 0, err
 	}
@@ -282,7 +283,7 @@ func (p *GiteaPublisher) uploadAsset(ctx context.Context, owner, repo string, re
 	req.Header.Set("Content-Type", "application/octet-stream")
 	req.ContentLength = stat.Size()
 
-	resp, err := http.DefaultClient.Do(req)
+	resp, err := httpclient.Client().Do(req)
 	if err != nil {
 		return err
 	}
@@ -0,0 +1,317 @@
+package publish
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/log"
+
+	"github.com/oarkflow/releaser/internal/config"
+	"github.com/oarkflow/releaser/internal/github"
+	"github.com/oarkflow/releaser/internal/retry"
+	"github.com/oarkflow/releaser/internal/tmpl"
+)
+
+// githubPullRequest describes a set of files to commit on a branch and
+// propose to an upstream repository as a pull request
+type githubPullRequest struct {
+	// Owner and Repo identify the upstream repository
+	Owner string
+	Repo  string
+
+	// Base is the branch the pull request targets; defaults to the
+	// upstream default branch
+	Base string
+
+	// Fork commits to a fork of the upstream repository instead of a
+	// branch in the upstream itself
+	Fork bool
+
+	// ForkOwner is the organization to fork into; empty forks into the
+	// account that owns the token
+	ForkOwner string
+
+	// Branch is the head branch name
+	Branch string
+
+	// Files maps repository paths to their new content
+	Files map[string]string
+
+	Message string
+	Author  config.CommitAuthor
+	Title   string
+	Body    string
+	Draft   bool
+}
+
+// openGitHubPullRequest commits the files onto the head branch in a single
+// commit and opens a pull request against the upstream. If a pull request for
+// the branch is already open, the branch is updated and the existing pull
+// request URL is returned.
+func openGitHubPullRequest(ctx context.Context, client *github.Client, pr githubPullRequest) (string, error) {
+	// Resolve the base branch and its head commit
+	base, err := pullRequestBase(ctx, client, pr)
+	if err != nil {
+		return "", err
+	}
+
+	var baseRef struct {
+		Object struct {
+			SHA string `json:"sha"`
+		} `json:"object"`
+	}
+	if err := client.Do(ctx, "GET", fmt.Sprintf("/repos/%s/%s/git/ref/heads/%s", pr.Owner, pr.Repo, base), nil, &baseRef); err != nil {
+		return "", fmt.Errorf("failed to get base branch %s: %w", base, err)
+	}
+	baseSHA := baseRef.Object.SHA
+
+	// Determine where the head branch lives
+	headOwner, headRepo := pr.Owner, pr.Repo
+	if pr.Fork && pr.ForkOwner != pr.Owner {
+		owner, repo, err := forkGitHubRepo(ctx, client, pr.Owner, pr.Repo, pr.ForkOwner)
+		if err != nil {
+			return "", err
+		}
+		headOwner, headRepo = owner, repo
+	}
+	repoURL := fmt.Sprintf("/repos/%s/%s", headOwner, headRepo)
+
+	// Build a tree on top of the base commit with all files
+	var baseCommit struct {
+		Tree struct {
+			SHA string `json:"sha"`
+		} `json:"tree"`
+	}
+	if err := client.Do(ctx, "GET", fmt.Sprintf("/repos/%s/%s/git/commits/%s", pr.Owner, pr.Repo, baseSHA), nil, &baseCommit); err != nil {
+		return "", fmt.Errorf("failed to get base commit: %w", err)
+	}
+
+	paths := make([]string, 0, len(pr.Files))
+	for path := range pr.Files {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	entries := make([]map[string]string, 0, len(paths))
+	for _, path := range paths {
+		entries = append(entries, map[string]string{
+			"path":    path,
+			"mode":    "100644",
+			"type":    "blob",
+			"content": pr.Files[path],
+		})
+	}
+
+	var tree struct {
+		SHA string `json:"sha"`
+	}
+	if err := client.Do(ctx, "POST", repoURL+"/git/trees", map[string]interface{}{
+		"base_tree": baseCommit.Tree.SHA,
+		"tree":      entries,
+	}, &tree); err != nil {
+		return "", fmt.Errorf("failed to create tree: %w", err)
+	}
+
+	commitBody := map[string]interface{}{
+		"message": pr.Message,
+		"tree":    tree.SHA,
+		"parents": []string{baseSHA},
+	}
+	if pr.Author.Name != "" && pr.Author.Email != "" {
+		commitBody["author"] = map[string]string{
+			"name":  pr.Author.Name,
+			"email": pr.Author.Email,
+		}
+	}
+
+	var commit struct {
+		SHA string `json:"sha"`
+	}
+	if err := client.Do(ctx, "POST", repoURL+"/git/commits", commitBody, &commit); err != nil {
+		return "", fmt.Errorf("failed to create commit: %w", err)
+	}
+
+	// Create the head branch, or move it if it already exists
+	err = client.Do(ctx, "POST", repoURL+"/git/refs", map[string]string{
+		"ref": "refs/heads/" + pr.Branch,
+		"sha": commit.SHA,
+	}, nil)
+	if github.HasStatus(err, http.StatusUnprocessableEntity) {
+		log.Debug("Branch already exists, updating", "branch", pr.Branch)
+		err = client.Do(ctx, "PATCH", repoURL+"/git/refs/heads/"+pr.Branch, map[string]interface{}{
+			"sha":   commit.SHA,
+			"force": true,
+		}, nil)
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to update branch %s: %w", pr.Branch, err)
+	}
+
+	return proposeGitHubPullRequest(ctx, client, pr, headOwner, base)
+}
+
+// pullRequestBase returns the branch a pull request targets, the upstream
+// default branch unless configured
+func pullRequestBase(ctx context.Context, client *github.Client, pr githubPullRequest) (string, error) {
+	if pr.Base != "" {
+		return pr.Base, nil
+	}
+	var upstream struct {
+		DefaultBranch string `json:"default_branch"`
+	}
+	if err := client.Do(ctx, "GET", fmt.Sprintf("/repos/%s/%s", pr.Owner, pr.Repo), nil, &upstream); err != nil {
+		return "", fmt.Errorf("failed to get repository %s/%s: %w", pr.Owner, pr.Repo, err)
+	}
+	return upstream.DefaultBranch, nil
+}
+
+// proposeGitHubPullRequest opens a pull request for a head branch that is
+// already pushed, or returns the URL of the one open for it
+func proposeGitHubPullRequest(ctx context.Context, client *github.Client, pr githubPullRequest, headOwner, base string) (string, error) {
+	// Reuse an open pull request for the same branch
+	head := fmt.Sprintf("%s:%s", headOwner, pr.Branch)
+	var existing []struct {
+		HTMLURL string `json:"html_url"`
+	}
+	if err := client.Do(ctx, "GET", fmt.Sprintf("/repos/%s/%s/pulls?state=open&head=%s&base=%s", pr.Owner, pr.Repo, head, base), nil, &existing); err == nil && len(existing) > 0 {
+		log.Info("Updated existing pull request", "url", existing[0].HTMLURL)
+		return existing[0].HTMLURL, nil
+	}
+
+	var created struct {
+		HTMLURL string `json:"html_url"`
+	}
+	if err := client.Do(ctx, "POST", fmt.Sprintf("/repos/%s/%s/pulls", pr.Owner, pr.Repo), map[string]interface{}{
+		"title": pr.Title,
+		"body":  pr.Body,
+		"head":  head,
+		"base":  base,
+		"draft": pr.Draft,
+	}, &created); err != nil {
+		return "", fmt.Errorf("failed to open pull request: %w", err)
+	}
+
+	log.Info("Opened pull request", "url", created.HTMLURL)
+	return created.HTMLURL, nil
+}
+
+// commitToGitHubRepo writes a single file to the repository, either as a
+// direct commit or, when pull requests are enabled, on a branch proposed
+// through a pull request
+func commitToGitHubRepo(ctx context.Context, client *github.Client, tmplCtx *tmpl.Context, repo config.RepoRef, path, content, message string, author config.CommitAuthor) error {
+	if !repo.PullRequest.Enabled {
+		sha, err := getGitHubFileSHA(ctx, client, repo.Owner, repo.Name, path, repo.Branch)
+		if err != nil {
+			return fmt.Errorf("failed to check %s: %w", path, err)
+		}
+		if sha == "" {
+			log.Debug("No existing file found, will create new one", "path", path)
+		}
+		return putGitHubFile(ctx, client, repo.Owner, repo.Name, repo.Branch, path, content, message, sha, author)
+	}
+
+	pr, err := pullRequestFromConfig(tmplCtx, repo, message)
+	if err != nil {
+		return err
+	}
+	if pr.Branch == "" {
+		pr.Branch = defaultBranchName(tmplCtx, path)
+	}
+	pr.Files = map[string]string{path: content}
+	pr.Author = author
+
+	_, err = openGitHubPullRequest(ctx, client, pr)
+	return err
+}
+
+// defaultBranchName names the pull request branch of a file, e.g.
+// releaser-tool-v1.2.0 for Formula/tool.rb
+func defaultBranchName(tmplCtx *tmpl.Context, path string) string {
+	name := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	return sanitizeBranchName(fmt.Sprintf("releaser-%s-%s", name, tmplCtx.Get("Tag")))
+}
+
+// pullRequestFromConfig renders the templated branch, title and body of a
+// pull request configuration. The title defaults to the commit message and
+// the branch is left empty when no template is configured.
+func pullRequestFromConfig(tmplCtx *tmpl.Context, repo config.RepoRef, message string) (githubPullRequest, error) {
+	pr := githubPullRequest{
+		Owner:   repo.Owner,
+		Repo:    repo.Name,
+		Base:    repo.PullRequest.Base,
+		Message: message,
+		Title:   message,
+		Draft:   repo.PullRequest.Draft,
+	}
+
+	if repo.PullRequest.BranchTemplate != "" {
+		branch, err := tmplCtx.Apply("repository.pull_request.branch_template", repo.PullRequest.BranchTemplate)
+		if err != nil {
+			return pr, fmt.Errorf("failed to apply pull request branch template: %w", err)
+		}
+		pr.Branch = sanitizeBranchName(branch)
+	}
+
+	if repo.PullRequest.TitleTemplate != "" {
+		title, err := tmplCtx.Apply("repository.pull_request.title_template", repo.PullRequest.TitleTemplate)
+		if err != nil {
+			return pr, fmt.Errorf("failed to apply pull request title template: %w", err)
+		}
+		pr.Title = title
+	}
+
+	if repo.PullRequest.BodyTemplate != "" {
+		body, err := tmplCtx.Apply("repository.pull_request.body_template", repo.PullRequest.BodyTemplate)
+		if err != nil {
+			return pr, fmt.Errorf("failed to apply pull request body template: %w", err)
+		}
+		pr.Body = body
+	}
+
+	return pr, nil
+}
+
+// forkGitHubRepo forks the repository (or finds the existing fork) and waits
+// until it is ready to accept commits
+func forkGitHubRepo(ctx context.Context, client *github.Client, owner, repo, organization string) (string, string, error) {
+	body := map[string]interface{}{}
+	if organization != "" {
+		body["organization"] = organization
+	}
+
+	var fork struct {
+		Name  string `json:"name"`
+		Owner struct {
+			Login string `json:"login"`
+		} `json:"owner"`
+	}
+	if err := client.Do(ctx, "POST", fmt.Sprintf("/repos/%s/%s/forks", owner, repo), body, &fork); err != nil {
+		return "", "", fmt.Errorf("failed to fork %s/%s: %w", owner, repo, err)
+	}
+
+	log.Debug("Using fork", "repo", fmt.Sprintf("%s/%s", fork.Owner.Login, fork.Name))
+
+	// Forking is asynchronous; wait until the fork is reachable
+	opts := retry.DefaultOptions("wait for fork")
+	opts.Attempts = 10
+	opts.MaxDelay = 10 * time.Second
+	err := retry.Do(ctx, opts, func(int) error {
+		return client.Do(ctx, "GET", fmt.Sprintf("/repos/%s/%s/git/refs/heads", fork.Owner.Login, fork.Name), nil, nil)
+	})
+	if err != nil {
+		return "", "", fmt.Errorf("fork %s/%s did not become ready: %w", fork.Owner.Login, fork.Name, err)
+	}
+
+	return fork.Owner.Login, fork.Name, nil
+}
+
+// sanitizeBranchName replaces characters git does not allow in ref names
+func sanitizeBranchName(name string) string {
+	replacer := strings.NewReplacer(" ", "-", "~", "-", "^", "-", ":", "-", "?", "-", "*", "-", "[", "-", "\\", "-", "..", "-")
+	return strings.Trim(replacer.Replace(name), "/.")
+}
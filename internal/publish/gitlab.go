@@ -17,6 +17,7 @@ import (
 
 	"github.com/oarkflow/releaser/internal/artifact"
 	"github.com/oarkflow/releaser/internal/config"
+	"github.com/oarkflow/releaser/internal/httpclient"
 	"github.com/oarkflow/releaser/internal/tmpl"
 )
 
@@ -57,8 +58,8 @@ func (p *GitLabPublisher) Publish(ctx context.Context, artifacts []artifact.Arti
 	}
 
 	// Apply templates
-	owner, _ = p.tmplCtx.Apply(owner)
-	repo, _ = p.tmplCtx.Apply(repo)
+	owner, _ = p.tmplCtx.Apply("release.gitlab.owner", owner)
+	repo, _ = p.tmplCtx.Apply("release.gitlab.name", repo)
 
 	projectPath := url.PathEscape(owner + "/" + repo)
 
@@ -90,7 +91,7 @@ func (p *GitLabPublisher) getOrCreateRelease(ctx context.Context, projectPath, t
 	req, _ := http.NewRequestWithContext(ctx, "GET", apiURL, nil)
 	req.Header.Set("PRIVATE-TOKEN", p.token)
 
-	resp, err := http.DefaultClient.Do(req)
+	resp, err := httpclient.Client().Do(req)
 	if err != nil {
 		return "", err
 	}
@@ -114,9 +115,9 @@ func (p *GitLabPublisher) getOrCreateRelease(ctx context.Context, projectPath, t
 	if name == "" {
 		name = tag
 	}
-	name, _ = p.tmplCtx.Apply(name)
+	name, _ = p.tmplCtx.Apply("release.name_template", name)
 
-	description := p.tmplCtx.Get("Changelog")
+	description := p.tmplCtx.Get("ReleaseNotes")
 	if description == "" {
 		description = fmt.Sprintf("Release %s", tag)
 	}
@@ -134,7 +135,7 @@ func (p *GitLabPublisher) getOrCreateRelease(ctx context.Context, projectPath, t
 	req.Header.Set("PRIVATE-TOKEN", p.token)
 	req.Header.Set("Content-Type", "application/json")
 
-	resp, err = http.DefaultClient.Do(req)
+	resp, err = httpclient.Client().Do(req)
 	if err != nil {
 		return "", err
 	}
@@ -182,7 +183,7 @@ func (p *GitLabPublisher) uploadAndLinkAsset(ctx context.Context, projectPath, t
 	req.Header.Set("PRIVATE-TOKEN", p.token)
 	req.ContentLength = stat.Size()
 
-	resp, err := http.DefaultClient.Do(req)
+	resp, err := httpclient.Client().Do(req)
 	if err != nil {
 		return err
 	}
@@ -208,7 +209,7 @@ func (p *GitLabPublisher) uploadAndLinkAsset(ctx context.Context, projectPath, t
 	req.Header.Set("PRIVATE-TOKEN", p.token)
 	req.Header.Set("Content-Type", "application/json")
 
-	resp, err = http.DefaultClient.Do(req)
+	resp, err = httpclient.Client().Do(req)
 	if err != nil {
 		return err
 	}
@@ -0,0 +1,242 @@
+package publish
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/log"
+
+	"github.com/oarkflow/releaser/internal/config"
+	"github.com/oarkflow/releaser/internal/httpclient"
+	"github.com/oarkflow/releaser/internal/tmpl"
+)
+
+// defaultGoProxy is the module proxy go install uses by default
+const defaultGoProxy = "https://proxy.golang.org"
+
+// goProxyPollInterval is how often the proxy is asked for the tag while it
+// doesn't serve it yet
+var goProxyPollInterval = 10 * time.Second
+
+// GoModProxy gets a released tag into the Go module proxy and checks that
+// go install works for it, so users can install the release once it is
+// announced
+type GoModProxy struct {
+	config  config.GoMod
+	tmplCtx *tmpl.Context
+	main    string
+}
+
+// NewGoModProxy creates the Go module proxy step
+func NewGoModProxy(cfg config.GoMod, tmplCtx *tmpl.Context) *GoModProxy {
+	return &GoModProxy{config: cfg, tmplCtx: tmplCtx}
+}
+
+// WithMain sets the package verified when gomod.main is not set
+func (g *GoModProxy) WithMain(main string) *GoModProxy {
+	g.main = main
+	return g
+}
+
+// Run warms up the proxy and verifies the install, as configured
+func (g *GoModProxy) Run(ctx context.Context) error {
+	module, err := g.module()
+	if err != nil {
+		return err
+	}
+	// Tags of modules in subdirectories are prefixed with their directory
+	tag := g.tmplCtx.Get("Tag")
+	version := path.Base(tag)
+	if !strings.HasPrefix(version, "v") {
+		return fmt.Errorf("tag %s is not a Go module version, which starts with v", tag)
+	}
+
+	if g.config.ProxyWarmup {
+		if err := g.warmup(ctx, module, version); err != nil {
+			return err
+		}
+	}
+	if g.config.Verify {
+		if err := g.verify(ctx, module, version); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// module returns the configured module path or the one of go.mod
+func (g *GoModProxy) module() (string, error) {
+	if g.config.Module != "" {
+		return g.tmplCtx.Apply("gomod.module", g.config.Module)
+	}
+	data, err := os.ReadFile("go.mod")
+	if err != nil {
+		return "", fmt.Errorf("set gomod.module, go.mod can't be read: %w", err)
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if i := strings.Index(line, "//"); i >= 0 {
+			line = strings.TrimSpace(line[:i])
+		}
+		if module, ok := strings.CutPrefix(line, "module "); ok {
+			return strings.Trim(strings.TrimSpace(module), `"`), nil
+		}
+	}
+	return "", fmt.Errorf("no module directive in go.mod, set gomod.module")
+}
+
+// proxy returns the module proxy URL without a trailing slash
+func (g *GoModProxy) proxy() string {
+	if g.config.Proxy == "" {
+		return defaultGoProxy
+	}
+	return strings.TrimSuffix(g.config.Proxy, "/")
+}
+
+// warmup requests the version from the proxy until it serves it, then
+// fetches its zip so the proxy caches the module
+func (g *GoModProxy) warmup(ctx context.Context, module, version string) error {
+	timeout := 10 * time.Minute
+	if g.config.Timeout != "" {
+		var err error
+		if timeout, err = time.ParseDuration(g.config.Timeout); err != nil {
+			return fmt.Errorf("invalid gomod.timeout %q: %w", g.config.Timeout, err)
+		}
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	base := fmt.Sprintf("%s/%s/@v/%s", g.proxy(), escapeModulePath(module), escapeModulePath(version))
+	log.Info("Warming up the Go module proxy", "module", module, "version", version, "proxy", g.proxy())
+	for {
+		status, err := getDiscard(ctx, base+".info")
+		if err == nil && status == http.StatusOK {
+			break
+		}
+		if err == nil {
+			err = fmt.Errorf("status %d", status)
+		}
+		log.Info("Go module proxy doesn't serve the version yet, waiting", "version", version, "error", err, "retry", goProxyPollInterval)
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("the proxy didn't serve %s@%s within %s: %w", module, version, timeout, err)
+		case <-time.After(goProxyPollInterval):
+		}
+	}
+
+	status, err := getDiscard(ctx, base+".zip")
+	if err != nil {
+		return fmt.Errorf("failed to fetch the zip of %s@%s from the proxy: %w", module, version, err)
+	}
+	if status != http.StatusOK {
+		return fmt.Errorf("failed to fetch the zip of %s@%s from the proxy: status %d", module, version, status)
+	}
+	log.Info("Go module proxy serves the version", "module", module, "version", version)
+	return nil
+}
+
+// verify runs go install for the version in a clean GOPATH and module
+// cache, through the proxy and checksum database users install from
+func (g *GoModProxy) verify(ctx context.Context, module, version string) error {
+	goBin, err := exec.LookPath("go")
+	if err != nil {
+		return fmt.Errorf("go not found in PATH, it is needed to verify the module installs")
+	}
+
+	dir, err := os.MkdirTemp("", "releaser-gomod-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+
+	pkg := g.config.Main
+	if pkg == "" {
+		pkg = g.main
+	}
+	if strings.HasSuffix(pkg, ".go") {
+		pkg = path.Dir(pkg)
+	}
+	if pkg = strings.Trim(path.Clean("/"+pkg), "/"); pkg != "" {
+		pkg = module + "/" + pkg
+	} else {
+		pkg = module
+	}
+
+	log.Info("Verifying go install", "package", pkg+"@"+version)
+	cmd := exec.CommandContext(ctx, goBin, "install", pkg+"@"+version)
+	// Outside any module, so no go.mod or go.work of the repository applies
+	cmd.Dir = dir
+	cmd.Env = append(goEnv(os.Environ()),
+		"GOPATH="+dir,
+		"GOMODCACHE="+dir+"/pkg/mod",
+		"GOBIN="+dir+"/bin",
+		// Module cache files are read-only unless told otherwise, which
+		// would keep the temporary directory from being removed
+		"GOFLAGS=-modcacherw",
+		"GOWORK=off",
+		"GO111MODULE=on",
+		"GOPROXY="+g.proxy(),
+	)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("go install %s@%s failed: %w", pkg, version, err)
+	}
+	log.Info("Module installs with go install", "package", pkg+"@"+version)
+	return nil
+}
+
+// goEnv drops the variables that let go install bypass the proxy or the
+// checksum database, so the install is verified the way users run it
+func goEnv(environ []string) []string {
+	var env []string
+	for _, kv := range environ {
+		name, _, _ := strings.Cut(kv, "=")
+		switch name {
+		case "GOPATH", "GOMODCACHE", "GOBIN", "GOFLAGS", "GOWORK", "GO111MODULE", "GOPROXY",
+			"GOPRIVATE", "GONOPROXY", "GONOSUMDB", "GOSUMDB", "GOINSECURE":
+			continue
+		}
+		env = append(env, kv)
+	}
+	return env
+}
+
+// getDiscard sends a GET request and returns its status, discarding the body
+func getDiscard(ctx context.Context, url string) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return 0, err
+	}
+	resp, err := httpclient.Client().Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	if _, err := io.Copy(io.Discard, resp.Body); err != nil {
+		return 0, err
+	}
+	return resp.StatusCode, nil
+}
+
+// escapeModulePath escapes a module path or version for proxy URLs, where
+// each upper-case letter is written as ! and its lower-case form
+func escapeModulePath(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		if r >= 'A' && r <= 'Z' {
+			b.WriteByte('!')
+			r += 'a' - 'A'
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
@@ -0,0 +1,303 @@
+package publish
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/log"
+	"gopkg.in/yaml.v3"
+
+	"github.com/oarkflow/releaser/internal/artifact"
+	"github.com/oarkflow/releaser/internal/config"
+	"github.com/oarkflow/releaser/internal/httpclient"
+	"github.com/oarkflow/releaser/internal/retry"
+	"github.com/oarkflow/releaser/internal/tmpl"
+)
+
+// helmIndex is a chart repository index.yaml
+type helmIndex struct {
+	APIVersion string                              `yaml:"apiVersion"`
+	Entries    map[string][]map[string]interface{} `yaml:"entries"`
+	Generated  string                              `yaml:"generated"`
+}
+
+// HelmPublisher pushes the charts packaged by the helm stage to an OCI
+// registry or a chart repository
+type HelmPublisher struct {
+	config  config.Helm
+	tmplCtx *tmpl.Context
+	distDir string
+}
+
+// NewHelmPublisher creates a new Helm publisher. The merged index of chart
+// repositories is written to distDir.
+func NewHelmPublisher(cfg config.Helm, tmplCtx *tmpl.Context, distDir string) *HelmPublisher {
+	return &HelmPublisher{
+		config:  cfg,
+		tmplCtx: tmplCtx,
+		distDir: distDir,
+	}
+}
+
+// Publish pushes the charts with helm push to oci:// repositories, or
+// uploads them with a merged index.yaml to other repositories
+func (p *HelmPublisher) Publish(ctx context.Context, artifacts []artifact.Artifact) error {
+	if p.config.SkipUpload == "true" {
+		log.Info("Skipping Helm publish")
+		return nil
+	}
+
+	var charts []artifact.Artifact
+	for _, a := range artifacts {
+		if id, _ := a.Extra["id"].(string); a.Type == artifact.TypeHelm && id == p.config.ID {
+			charts = append(charts, a)
+		}
+	}
+	if len(charts) == 0 {
+		log.Warn("No Helm charts to publish", "id", p.config.ID)
+		return nil
+	}
+	if p.config.Repository == "" {
+		log.Info("No Helm repository configured, charts are only attached to the release")
+		return nil
+	}
+
+	repository, err := p.tmplCtx.Apply("helms.repository", p.config.Repository)
+	if err != nil {
+		return fmt.Errorf("failed to apply repository template: %w", err)
+	}
+	repository = strings.TrimSuffix(repository, "/")
+
+	username, password := p.credentials()
+	for _, chart := range charts {
+		if err := Confirm(Action{
+			Description: fmt.Sprintf("Push the Helm chart %s", chart.Name),
+			URL:         repository,
+			Files:       []string{chart.Path},
+		}); err != nil {
+			return err
+		}
+		log.Info("Publishing Helm chart", "chart", chart.Name, "repository", repository)
+		if strings.HasPrefix(repository, "oci://") {
+			err = p.pushOCI(ctx, repository, chart, username, password)
+		} else {
+			err = p.pushRepo(ctx, repository, chart, username, password)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to publish %s: %w", chart.Name, err)
+		}
+	}
+
+	log.Info("Helm chart published successfully")
+	return nil
+}
+
+// credentials returns the repository username and password
+func (p *HelmPublisher) credentials() (string, string) {
+	username := p.config.Username
+	if username == "" {
+		username = os.Getenv("HELM_REPO_USERNAME")
+	}
+	password := p.config.Password
+	if password == "" {
+		password = os.Getenv("HELM_REPO_PASSWORD")
+	}
+	return username, password
+}
+
+// pushOCI pushes a chart to an OCI registry, logging in first when
+// credentials are configured
+func (p *HelmPublisher) pushOCI(ctx context.Context, repository string, chart artifact.Artifact, username, password string) error {
+	helm, err := exec.LookPath("helm")
+	if err != nil {
+		return fmt.Errorf("helm not found in PATH, it is needed to push to OCI registries")
+	}
+
+	if username != "" {
+		host, _, _ := strings.Cut(strings.TrimPrefix(repository, "oci://"), "/")
+		login := exec.CommandContext(ctx, helm, "registry", "login", host, "--username", username, "--password-stdin")
+		login.Stdin = strings.NewReader(password)
+		login.Stdout = os.Stdout
+		login.Stderr = os.Stderr
+		if err := login.Run(); err != nil {
+			return fmt.Errorf("helm registry login failed: %w", err)
+		}
+	}
+
+	cmd := exec.CommandContext(ctx, helm, "push", chart.Path, repository)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("helm push failed: %w", err)
+	}
+	return nil
+}
+
+// pushRepo adds the chart to the repository's index.yaml and uploads the
+// chart, its provenance file if any and the index with HTTP PUT
+func (p *HelmPublisher) pushRepo(ctx context.Context, repository string, chart artifact.Artifact, username, password string) error {
+	index, err := p.fetchIndex(ctx, repository, username, password)
+	if err != nil {
+		return err
+	}
+
+	entry, err := chartMetadata(chart.Path)
+	if err != nil {
+		return err
+	}
+	data, err := os.ReadFile(chart.Path)
+	if err != nil {
+		return err
+	}
+	sum := sha256.Sum256(data)
+	entry["created"] = time.Now().UTC().Format(time.RFC3339Nano)
+	entry["digest"] = hex.EncodeToString(sum[:])
+	entry["urls"] = []string{repository + "/" + chart.Name}
+
+	// Replace any entry of the same version, newest first
+	name, _ := entry["name"].(string)
+	version := fmt.Sprint(entry["version"])
+	versions := []map[string]interface{}{entry}
+	for _, existing := range index.Entries[name] {
+		if fmt.Sprint(existing["version"]) != version {
+			versions = append(versions, existing)
+		}
+	}
+	index.Entries[name] = versions
+	index.Generated = time.Now().UTC().Format(time.RFC3339Nano)
+
+	indexData, err := yaml.Marshal(index)
+	if err != nil {
+		return err
+	}
+	indexPath := filepath.Join(p.distDir, "helm", "index.yaml")
+	if err := os.MkdirAll(filepath.Dir(indexPath), 0755); err != nil {
+		return err
+	}
+	if err := os.WriteFile(indexPath, indexData, 0644); err != nil {
+		return err
+	}
+
+	uploads := []string{chart.Path}
+	if _, err := os.Stat(chart.Path + ".prov"); err == nil {
+		uploads = append(uploads, chart.Path+".prov")
+	}
+	// The index goes last so it never references a missing chart
+	uploads = append(uploads, indexPath)
+	for _, path := range uploads {
+		if err := p.upload(ctx, repository+"/"+filepath.Base(path), path, username, password); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// fetchIndex downloads the repository's index.yaml, or starts a new one
+func (p *HelmPublisher) fetchIndex(ctx context.Context, repository, username, password string) (*helmIndex, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", repository+"/index.yaml", nil)
+	if err != nil {
+		return nil, err
+	}
+	if username != "" {
+		req.SetBasicAuth(username, password)
+	}
+	resp, err := httpclient.Client().Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch index.yaml: %w", err)
+	}
+	defer resp.Body.Close()
+
+	index := &helmIndex{APIVersion: "v1"}
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		log.Info("No index.yaml in Helm repository, creating one", "repository", repository)
+	case resp.StatusCode >= 300:
+		body, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("failed to fetch index.yaml: %s: %s", resp.Status, body)
+	default:
+		if err := yaml.NewDecoder(resp.Body).Decode(index); err != nil && err != io.EOF {
+			return nil, fmt.Errorf("invalid index.yaml: %w", err)
+		}
+	}
+	if index.Entries == nil {
+		index.Entries = make(map[string][]map[string]interface{})
+	}
+	return index, nil
+}
+
+// upload PUTs a file to the repository
+func (p *HelmPublisher) upload(ctx context.Context, url, path, username, password string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	return retry.Do(ctx, retry.DefaultOptions("upload "+filepath.Base(path)), func(int) error {
+		req, err := http.NewRequestWithContext(ctx, "PUT", url, bytes.NewReader(data))
+		if err != nil {
+			return retry.Permanent(err)
+		}
+		if username != "" {
+			req.SetBasicAuth(username, password)
+		}
+		resp, err := httpclient.Client().Do(req)
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+
+		switch {
+		case resp.StatusCode < 300:
+			log.Info("Uploaded to Helm repository", "file", filepath.Base(path))
+			return nil
+		case resp.StatusCode >= 500:
+			return fmt.Errorf("upload failed: %s: %s", resp.Status, body)
+		default:
+			return retry.Permanent(fmt.Errorf("upload failed: %s: %s", resp.Status, body))
+		}
+	})
+}
+
+// chartMetadata reads Chart.yaml from a packaged chart
+func chartMetadata(path string) (map[string]interface{}, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	gr, err := gzip.NewReader(file)
+	if err != nil {
+		return nil, err
+	}
+	tr := tar.NewReader(gr)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return nil, fmt.Errorf("no Chart.yaml in %s", filepath.Base(path))
+		}
+		if err != nil {
+			return nil, err
+		}
+		if _, name, _ := strings.Cut(header.Name, "/"); name != "Chart.yaml" {
+			continue
+		}
+		meta := make(map[string]interface{})
+		if err := yaml.NewDecoder(tr).Decode(&meta); err != nil {
+			return nil, fmt.Errorf("invalid Chart.yaml: %w", err)
+		}
+		return meta, nil
+	}
+}
@@ -0,0 +1,153 @@
+package publish
+
+import (
+	"testing"
+
+	"github.com/oarkflow/releaser/internal/config"
+	"github.com/oarkflow/releaser/internal/manifest"
+	"github.com/oarkflow/releaser/internal/manifest/manifesttest"
+)
+
+// tricky holds the characters each manifest format has to escape
+const tricky = `Say "hi" to #{name}, $HOME & <it's> \ ‘quoted’`
+
+func testAURPackage() *aurPackage {
+	return &aurPackage{
+		Name:        "demo-bin",
+		Version:     "1.2.3",
+		Release:     "1",
+		Description: tricky,
+		URL:         "https://example.com/demo?a=1&b=2",
+		Arch:        []string{"x86_64", "aarch64"},
+		License:     []string{"MIT"},
+		Depends:     []string{"glibc"},
+		OptDepends:  []string{"git: for 'demo sync'"},
+		Conflicts:   []string{"demo"},
+		Provides:    []string{"demo"},
+		Sources: map[string][]aurSource{
+			"x86_64":  {{File: "demo-1.2.3-x86_64.tar.gz", URL: "https://example.com/demo_linux_amd64.tar.gz", SHA256: "aaaa"}},
+			"aarch64": {{File: "demo-1.2.3-aarch64.tar.gz", URL: "https://example.com/demo_linux_arm64.tar.gz", SHA256: "bbbb"}},
+		},
+	}
+}
+
+func TestManifestGolden(t *testing.T) {
+	tests := []struct {
+		file string
+		g    manifest.Generator
+	}{
+		{
+			file: "demo.rb",
+			g: formula{
+				ClassName:   "Demo",
+				Description: tricky,
+				Homepage:    "https://example.com/demo",
+				Version:     "1.2.3",
+				License:     "MIT",
+				Sources: map[string]formulaSource{
+					"darwin_amd64": {URL: "https://example.com/demo_darwin_amd64.tar.gz", SHA256: "aaaa"},
+					"darwin_arm64": {URL: "https://example.com/demo_darwin_arm64.tar.gz", SHA256: "bbbb"},
+					"linux_amd64":  {URL: "https://example.com/demo_linux_amd64.tar.gz?token=#{x}", SHA256: "cccc"},
+					"linux_arm":    {URL: "https://example.com/demo_linux_armv7.tar.gz", SHA256: "dddd"},
+				},
+				Dependencies: []config.BrewDependency{{Name: "git"}, {Name: "xz", Type: "optional", OS: "linux"}},
+				Conflicts:    []string{`demo"legacy`},
+				Install:      `bin.install "demo"`,
+				Caveats:      "Run demo init",
+				Test:         `system "#{bin}/demo", "--version"`,
+			},
+		},
+		{
+			file: "PKGBUILD",
+			g: pkgbuild{
+				aurPackage:  testAURPackage(),
+				Maintainers: []string{"Jane Doe <jane@example.com>\n# injected"},
+				Functions:   []shellFunction{{Name: "package", Body: `install -Dm755 "./demo" "$pkgdir/usr/bin/demo"`}},
+			},
+		},
+		{file: "SRCINFO", g: srcinfo{testAURPackage()}},
+		{
+			file: "demo.nuspec",
+			g: nuspec{
+				Metadata: nuspecMetadata{
+					ID:          "demo",
+					Version:     "1.2.3",
+					Title:       "Demo & Co",
+					Authors:     "Jane <jane@example.com>",
+					ProjectURL:  "https://example.com/demo?a=1&b=2",
+					Tags:        "cli release",
+					Description: tricky,
+				},
+				Files: []nuspecFile{{Src: `tools\**`, Target: "tools"}},
+			},
+		},
+		{
+			file: "chocolateyinstall.ps1",
+			g:    chocolateyInstall{URL: "https://example.com/it's/demo_windows_amd64.zip?a=$x", SHA256: "aaaa"},
+		},
+		{
+			file: "Demo.Demo.installer.yaml",
+			g: wingetManifest{"installer", wingetInstallerManifest{
+				PackageIdentifier: "Demo.Demo",
+				PackageVersion:    "1.2.3",
+				ReleaseDate:       "2026-01-02",
+				Installers: []wingetInstallerItem{
+					{
+						Architecture:         "x64",
+						InstallerType:        "zip",
+						NestedInstallerType:  "portable",
+						NestedInstallerFiles: []wingetNestedFile{{RelativeFilePath: "demo.exe", PortableCommandAlias: "demo"}},
+						InstallerUrl:         "https://example.com/demo_windows_amd64.zip#frag",
+						InstallerSha256:      "AAAA",
+					},
+					{
+						Architecture:    "arm64",
+						InstallerType:   "msi",
+						InstallerUrl:    "https://example.com/demo_windows_arm64.msi",
+						InstallerSha256: "BBBB",
+					},
+				},
+				ManifestType:    "installer",
+				ManifestVersion: wingetManifestVersion,
+			}},
+		},
+		{
+			file: "Demo.Demo.locale.en-US.yaml",
+			g: wingetManifest{"defaultLocale", wingetLocaleManifest{
+				PackageIdentifier: "Demo.Demo",
+				PackageVersion:    "1.2.3",
+				PackageLocale:     "en-US",
+				Publisher:         "Demo: Inc",
+				PackageName:       "demo",
+				License:           "MIT",
+				ShortDescription:  tricky,
+				Description:       "First line\nsecond line",
+				Tags:              []string{"cli", "yes"},
+				ManifestType:      "defaultLocale",
+				ManifestVersion:   wingetManifestVersion,
+			}},
+		},
+		{
+			file: "demo.json",
+			g: func() scoopManifest {
+				m := scoopManifest{
+					Version:     "1.2.3",
+					Description: tricky,
+					Homepage:    "https://example.com/demo?a=1&b=2",
+					License:     "MIT",
+					Persist:     []string{"data"},
+					Shortcuts:   [][]string{{"demo.exe", "Demo <GUI>"}},
+				}
+				m.Architecture.Bit64 = &scoopArchitecture{URL: "https://example.com/demo_windows_amd64.zip", Bin: "demo.exe", Hash: "aaaa"}
+				m.Architecture.Bit32 = &scoopArchitecture{URL: "https://example.com/demo_windows_386.zip", Bin: "demo.exe", Hash: "bbbb"}
+				return m
+			}(),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.file, func(t *testing.T) {
+			manifesttest.AssertGolden(t, tt.file, tt.g)
+		})
+	}
+}
@@ -0,0 +1,100 @@
+package publish
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/charmbracelet/log"
+
+	"github.com/oarkflow/releaser/internal/config"
+	"github.com/oarkflow/releaser/internal/github"
+	"github.com/oarkflow/releaser/internal/tmpl"
+)
+
+// MilestoneCloser closes the GitHub milestone of a released version
+type MilestoneCloser struct {
+	config  config.Milestone
+	tmplCtx *tmpl.Context
+}
+
+func init() {
+	RegisterRequirements(func(cfg *config.Config) []Requirement {
+		var reqs []Requirement
+		for _, m := range cfg.Milestones {
+			if m.Close && m.Repo.Token == "" {
+				reqs = append(reqs, Requirement{NeededBy: "milestone", Env: []string{"GITHUB_TOKEN"}})
+			}
+		}
+		return reqs
+	})
+}
+
+// NewMilestoneCloser creates a new milestone closer
+func NewMilestoneCloser(cfg config.Milestone, tmplCtx *tmpl.Context) *MilestoneCloser {
+	return &MilestoneCloser{config: cfg, tmplCtx: tmplCtx}
+}
+
+// githubMilestone is a milestone as returned by the GitHub API
+type githubMilestone struct {
+	Number int    `json:"number"`
+	Title  string `json:"title"`
+}
+
+// Close closes the open milestone named by name_template, {{ .Tag }} by
+// default. A missing milestone is only logged.
+func (m *MilestoneCloser) Close(ctx context.Context) error {
+	owner, err := m.tmplCtx.Apply("milestones.repo.owner", m.config.Repo.Owner)
+	if err != nil {
+		return err
+	}
+	repo, err := m.tmplCtx.Apply("milestones.repo.name", m.config.Repo.Name)
+	if err != nil {
+		return err
+	}
+	if owner == "" || repo == "" {
+		return fmt.Errorf("milestone repository owner and name are required")
+	}
+
+	nameTemplate := m.config.NameTemplate
+	if nameTemplate == "" {
+		nameTemplate = "{{ .Tag }}"
+	}
+	name, err := m.tmplCtx.Apply("milestones.name_template", nameTemplate)
+	if err != nil {
+		return fmt.Errorf("failed to apply milestone name template: %w", err)
+	}
+
+	token := m.config.Repo.Token
+	if token == "" {
+		token = os.Getenv("GITHUB_TOKEN")
+	}
+	if token == "" {
+		return fmt.Errorf("GITHUB_TOKEN is required to close milestones")
+	}
+	client := github.New(token)
+
+	milestones, err := github.List[githubMilestone](ctx, client, fmt.Sprintf("/repos/%s/%s/milestones?state=open", owner, repo))
+	if err != nil {
+		return fmt.Errorf("failed to list milestones: %w", err)
+	}
+	for _, milestone := range milestones {
+		if milestone.Title != name {
+			continue
+		}
+		if err := Confirm(Action{
+			Description: fmt.Sprintf("Close the milestone %s of %s/%s", name, owner, repo),
+			URL:         fmt.Sprintf("https://github.com/%s/%s/milestone/%d", owner, repo, milestone.Number),
+		}); err != nil {
+			return err
+		}
+		if err := client.Do(ctx, "PATCH", fmt.Sprintf("/repos/%s/%s/milestones/%d", owner, repo, milestone.Number), map[string]string{"state": "closed"}, nil); err != nil {
+			return fmt.Errorf("failed to close milestone %s: %w", name, err)
+		}
+		log.Info("Closed milestone", "repo", owner+"/"+repo, "milestone", name)
+		return nil
+	}
+
+	log.Warn("No open milestone found", "repo", owner+"/"+repo, "milestone", name)
+	return nil
+}
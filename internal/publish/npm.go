@@ -0,0 +1,323 @@
+package publish
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/charmbracelet/log"
+
+	"github.com/oarkflow/releaser/internal/artifact"
+)
+
+// npmShim is the bin script of the meta package. It runs the binary of the
+// platform package npm installed, so no postinstall script is needed.
+const npmShim = `#!/usr/bin/env node
+"use strict";
+const { spawnSync } = require("child_process");
+
+const packages = %s;
+const key = process.platform + "-" + process.arch;
+const pkg = packages[key];
+if (!pkg) {
+  console.error(%s + ": unsupported platform " + key);
+  process.exit(1);
+}
+
+const exe = %s + (process.platform === "win32" ? ".exe" : "");
+let bin;
+try {
+  bin = require.resolve(pkg + "/bin/" + exe);
+} catch (e) {
+  console.error(%s + ": " + pkg + " is not installed, reinstall without --omit=optional");
+  process.exit(1);
+}
+
+const result = spawnSync(bin, process.argv.slice(2), { stdio: "inherit" });
+if (result.error) {
+  throw result.error;
+}
+process.exit(result.status === null ? 1 : result.status);
+`
+
+// npmPlatform is a platform package's target
+type npmPlatform struct {
+	os  string
+	cpu []string
+}
+
+// suffix returns the package name suffix, e.g. linux-x64
+func (t npmPlatform) suffix() string {
+	if len(t.cpu) > 1 {
+		return t.os + "-universal"
+	}
+	return t.os + "-" + t.cpu[0]
+}
+
+// registry returns the registry URL and token
+func (p *NPMPublisher) registry() (string, string) {
+	registry := p.config.Registry
+	if registry == "" {
+		registry = "https://registry.npmjs.org"
+	}
+	token := p.config.Token
+	if token == "" {
+		token = os.Getenv("NPM_TOKEN")
+	}
+	return strings.TrimSuffix(registry, "/"), token
+}
+
+// packageName returns the meta package name, scoped when configured
+func (p *NPMPublisher) packageName() string {
+	name := p.config.Name
+	if name == "" {
+		name = p.tmplCtx.Get("ProjectName")
+	}
+	if p.config.Scope != "" {
+		return "@" + strings.TrimPrefix(p.config.Scope, "@") + "/" + name
+	}
+	return name
+}
+
+// platformPackages publishes a package per platform with its binaries,
+// then the meta package that pulls the matching one in through
+// optionalDependencies pinned to the release version
+func (p *NPMPublisher) platformPackages(ctx context.Context, artifacts []artifact.Artifact) error {
+	name := p.packageName()
+	version := strings.TrimPrefix(p.tmplCtx.Get("Version"), "v")
+
+	platforms := make(map[string][]artifact.Artifact)
+	targets := make(map[string]npmPlatform)
+	for _, a := range artifacts {
+		if (a.Type != artifact.TypeBinary && a.Type != artifact.TypeUniversalBinary) || !matchesIDs(a, p.config.IDs) {
+			continue
+		}
+		target, ok := npmTarget(a)
+		if !ok {
+			log.Debug("No npm platform for binary, skipping", "binary", a.Name, "os", a.Goos, "arch", a.Goarch)
+			continue
+		}
+		pkg := name + "-" + target.suffix()
+		platforms[pkg] = append(platforms[pkg], a)
+		targets[pkg] = target
+	}
+	if len(platforms) == 0 {
+		return fmt.Errorf("no binaries found for npm platform packages")
+	}
+
+	pkgs := make([]string, 0, len(platforms))
+	for pkg := range platforms {
+		pkgs = append(pkgs, pkg)
+	}
+	sort.Strings(pkgs)
+
+	root := filepath.Join(p.distDir, "npm")
+	if err := os.RemoveAll(root); err != nil {
+		return err
+	}
+
+	var dirs []string
+	optional := make(map[string]string, len(pkgs))
+	selectors := make(map[string]string)
+	commands := make(map[string]bool)
+	for _, pkg := range pkgs {
+		target := targets[pkg]
+		dir := filepath.Join(root, strings.ReplaceAll(strings.TrimPrefix(pkg, "@"), "/", "-"))
+		for _, bin := range platforms[pkg] {
+			exe := filepath.Base(bin.Path)
+			if err := copyExecutable(bin.Path, filepath.Join(dir, "bin", exe)); err != nil {
+				return err
+			}
+			commands[strings.TrimSuffix(exe, ".exe")] = true
+		}
+		manifest := p.manifest(pkg, version)
+		manifest["os"] = []string{target.os}
+		manifest["cpu"] = target.cpu
+		manifest["files"] = []string{"bin/"}
+		manifest["preferUnplugged"] = true
+		if err := writeJSONFile(filepath.Join(dir, "package.json"), manifest); err != nil {
+			return err
+		}
+
+		optional[pkg] = version
+		for _, cpu := range target.cpu {
+			// A per-arch package wins over a universal one
+			if key := target.os + "-" + cpu; selectors[key] == "" || len(target.cpu) == 1 {
+				selectors[key] = pkg
+			}
+		}
+		dirs = append(dirs, dir)
+	}
+
+	// The meta package gets a shim per binary name
+	metaDir := filepath.Join(root, strings.ReplaceAll(strings.TrimPrefix(name, "@"), "/", "-"))
+	selectorJSON, err := json.MarshalIndent(selectors, "", "  ")
+	if err != nil {
+		return err
+	}
+	bins := make(map[string]string, len(commands))
+	for command := range commands {
+		quoted, _ := json.Marshal(command)
+		shim := fmt.Sprintf(npmShim, selectorJSON, quoted, quoted, quoted)
+		if err := os.MkdirAll(filepath.Join(metaDir, "bin"), 0755); err != nil {
+			return err
+		}
+		if err := os.WriteFile(filepath.Join(metaDir, "bin", command+".js"), []byte(shim), 0755); err != nil {
+			return err
+		}
+		bins[command] = "bin/" + command + ".js"
+	}
+	manifest := p.manifest(name, version)
+	manifest["bin"] = bins
+	manifest["files"] = []string{"bin/"}
+	manifest["optionalDependencies"] = optional
+	if len(p.config.Dependencies) > 0 {
+		manifest["dependencies"] = p.config.Dependencies
+	}
+	if len(p.config.Keywords) > 0 {
+		manifest["keywords"] = p.config.Keywords
+	}
+	for k, v := range p.config.ExtraFields {
+		manifest[k] = v
+	}
+	if err := writeJSONFile(filepath.Join(metaDir, "package.json"), manifest); err != nil {
+		return err
+	}
+	log.Info("npm packages created", "dir", root, "platforms", len(pkgs))
+
+	if p.config.SkipUpload == "true" {
+		log.Info("Skipping npm publish")
+		return nil
+	}
+	registry, token := p.registry()
+	if token == "" {
+		return fmt.Errorf("NPM_TOKEN is required")
+	}
+
+	// Platform packages go first so the meta package never points at
+	// missing versions
+	for _, dir := range append(dirs, metaDir) {
+		if err := p.npmPublish(ctx, dir, registry, token); err != nil {
+			return fmt.Errorf("failed to publish %s: %w", filepath.Base(dir), err)
+		}
+	}
+
+	log.Info("NPM packages published successfully", "name", name, "version", version, "platforms", len(pkgs))
+	return nil
+}
+
+// manifest returns the package.json fields shared by all packages
+func (p *NPMPublisher) manifest(name, version string) map[string]interface{} {
+	manifest := map[string]interface{}{
+		"name":    name,
+		"version": version,
+	}
+	if p.config.Description != "" {
+		manifest["description"] = p.config.Description
+	}
+	if p.config.Homepage != "" {
+		manifest["homepage"] = p.config.Homepage
+	}
+	if p.config.License != "" {
+		manifest["license"] = p.config.License
+	}
+	if url := p.tmplCtx.Get("GitURL"); url != "" {
+		// npm provenance checks the package against its source repository
+		manifest["repository"] = map[string]string{"type": "git", "url": "git+" + strings.TrimPrefix(url, "git+")}
+	}
+	return manifest
+}
+
+// npmPublish runs npm publish in dir, with provenance when running in
+// GitHub Actions with an OIDC token
+func (p *NPMPublisher) npmPublish(ctx context.Context, dir, registry, token string) error {
+	npmrc := fmt.Sprintf("//%s/:_authToken=%s\n", strings.TrimPrefix(strings.TrimPrefix(registry, "https://"), "http://"), token)
+	if err := os.WriteFile(filepath.Join(dir, ".npmrc"), []byte(npmrc), 0600); err != nil {
+		return fmt.Errorf("failed to write .npmrc: %w", err)
+	}
+	// The token must not stay in dist
+	defer os.Remove(filepath.Join(dir, ".npmrc"))
+
+	args := []string{"publish", "--registry", registry}
+	if p.config.Access != "" {
+		args = append(args, "--access", p.config.Access)
+	}
+	if p.config.Tag != "" {
+		args = append(args, "--tag", p.config.Tag)
+	}
+	if p.config.Provenance != "false" && os.Getenv("GITHUB_ACTIONS") == "true" && os.Getenv("ACTIONS_ID_TOKEN_REQUEST_URL") != "" {
+		args = append(args, "--provenance")
+	}
+
+	manifest, _ := os.ReadFile(filepath.Join(dir, "package.json"))
+	if err := Confirm(Action{
+		Description: fmt.Sprintf("Publish %s to npm", filepath.Base(dir)),
+		URL:         registry,
+		Command:     append([]string{"npm"}, args...),
+		Files:       []string{dir},
+		Content:     string(manifest),
+	}); err != nil {
+		return err
+	}
+
+	cmd := exec.CommandContext(ctx, "npm", args...)
+	cmd.Dir = dir
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("npm publish failed: %w", err)
+	}
+	return nil
+}
+
+// npmTarget returns the npm os and cpu of a binary
+func npmTarget(a artifact.Artifact) (npmPlatform, bool) {
+	osName := map[string]string{
+		"linux": "linux", "darwin": "darwin", "windows": "win32", "freebsd": "freebsd",
+		"openbsd": "openbsd", "netbsd": "netbsd", "android": "android", "aix": "aix",
+	}[a.Goos]
+	if osName == "" {
+		return npmPlatform{}, false
+	}
+	if a.Goarch == "universal" {
+		return npmPlatform{os: osName, cpu: []string{"x64", "arm64"}}, true
+	}
+	cpu := map[string]string{
+		"amd64": "x64", "arm64": "arm64", "386": "ia32", "arm": "arm", "ppc64": "ppc64",
+		"ppc64le": "ppc64", "s390x": "s390x", "riscv64": "riscv64", "loong64": "loong64",
+		"mips": "mips", "mipsle": "mipsel",
+	}[a.Goarch]
+	if cpu == "" {
+		return npmPlatform{}, false
+	}
+	return npmPlatform{os: osName, cpu: []string{cpu}}, true
+}
+
+// copyExecutable copies a binary, keeping it executable
+func copyExecutable(src, dst string) error {
+	data, err := os.ReadFile(src)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(dst, data, 0755)
+}
+
+// writeJSONFile writes v as indented JSON
+func writeJSONFile(path string, v interface{}) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0644)
+}
@@ -4,23 +4,29 @@ Package publish provides publishing functionality for Releaser.
 package publish
 
 import (
-	"bytes"
 	"context"
+	"crypto/sha256"
 	"encoding/base64"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
-	"mime/multipart"
-	"net/http"
+	neturl "net/url"
 	"os"
-	"os/exec"
 	"path/filepath"
+	"sort"
 	"strings"
+	"sync"
 
 	"github.com/charmbracelet/log"
 
 	"github.com/oarkflow/releaser/internal/artifact"
+	"github.com/oarkflow/releaser/internal/checksum"
 	"github.com/oarkflow/releaser/internal/config"
+	"github.com/oarkflow/releaser/internal/github"
+	"github.com/oarkflow/releaser/internal/manifest"
+	"github.com/oarkflow/releaser/internal/retry"
 	"github.com/oarkflow/releaser/internal/tmpl"
 )
 
@@ -31,18 +37,48 @@ type Publisher interface {
 
 // GitHubPublisher publishes to GitHub Releases
 type GitHubPublisher struct {
-	config  config.Release
-	tmplCtx *tmpl.Context
-	token   string
+	config      config.Release
+	tmplCtx     *tmpl.Context
+	token       string
+	client      *github.Client
+	parallelism int
+}
+
+func init() {
+	RegisterRequirements(func(cfg *config.Config) []Requirement {
+		if cfg.Release.GitHub.Owner == "" {
+			return nil
+		}
+		return []Requirement{{NeededBy: "github release", Env: []string{"GITHUB_TOKEN"}, Disable: cfg.Release.Disable}}
+	})
 }
 
 // NewGitHubPublisher creates a new GitHub publisher
 func NewGitHubPublisher(cfg config.Release, tmplCtx *tmpl.Context) *GitHubPublisher {
+	token := os.Getenv("GITHUB_TOKEN")
 	return &GitHubPublisher{
-		config:  cfg,
-		tmplCtx: tmplCtx,
-		token:   os.Getenv("GITHUB_TOKEN"),
+		config:      cfg,
+		tmplCtx:     tmplCtx,
+		token:       token,
+		client:      github.New(token),
+		parallelism: 4,
+	}
+}
+
+// WithParallelism bounds the number of concurrent asset uploads
+func (p *GitHubPublisher) WithParallelism(n int) *GitHubPublisher {
+	if n > 0 {
+		p.parallelism = n
 	}
+	return p
+}
+
+// githubAsset is a release asset as returned by the GitHub API
+type githubAsset struct {
+	ID    int64  `json:"id"`
+	Name  string `json:"name"`
+	Size  int64  `json:"size"`
+	State string `json:"state"`
 }
 
 // Publish publishes artifacts to GitHub Releases
@@ -59,163 +95,286 @@ func (p *GitHubPublisher) Publish(ctx context.Context, artifacts []artifact.Arti
 	}
 
 	// Apply templates
-	owner, _ = p.tmplCtx.Apply(owner)
-	repo, _ = p.tmplCtx.Apply(repo)
+	owner, _ = p.tmplCtx.Apply("release.github.owner", owner)
+	repo, _ = p.tmplCtx.Apply("release.github.name", repo)
 
 	log.Info("Publishing to GitHub Releases", "owner", owner, "repo", repo)
 
-	// Get or create release
 	tag := p.tmplCtx.Get("Tag")
+	var files []string
+	for _, a := range artifacts {
+		if a.Type != artifact.TypeDirectory && !isPrivate(a) {
+			files = append(files, a.Path)
+		}
+	}
+	if err := Confirm(Action{
+		Description: fmt.Sprintf("Create or update the GitHub release %s of %s/%s and upload its assets", tag, owner, repo),
+		URL:         fmt.Sprintf("%s/%s/%s/releases/tag/%s", github.CurrentURLs().Download, owner, repo, tag),
+		Files:       files,
+	}); err != nil {
+		return err
+	}
+
+	// Get or create release
 	releaseID, err := p.getOrCreateRelease(ctx, owner, repo, tag)
 	if err != nil {
 		return err
 	}
 
-	// Upload assets
+	// List assets that already exist so re-runs can skip them
+	existing, err := p.listAssets(ctx, owner, repo, releaseID)
+	if err != nil {
+		return fmt.Errorf("failed to list release assets: %w", err)
+	}
+
+	// Upload assets with bounded concurrency
+	sem := make(chan struct{}, p.parallelism)
+	errCh := make(chan error, len(artifacts))
+	var wg sync.WaitGroup
+
 	for _, a := range artifacts {
-		if err := p.uploadAsset(ctx, owner, repo, releaseID, a); err != nil {
-			return fmt.Errorf("failed to upload %s: %w", a.Name, err)
+		// Directory builds are released through their archives, private
+		// artifacts only go to blobs that include them
+		if a.Type == artifact.TypeDirectory || isPrivate(a) {
+			continue
 		}
+		wg.Add(1)
+		go func(a artifact.Artifact) {
+			defer wg.Done()
+
+			select {
+			case sem <- struct{}{}:
+			case <-ctx.Done():
+				errCh <- fmt.Errorf("failed to upload %s: %w", a.Name, ctx.Err())
+				return
+			}
+			defer func() { <-sem }()
+
+			if err := p.publishAsset(ctx, owner, repo, releaseID, a, existing[a.Name]); err != nil {
+				errCh <- fmt.Errorf("failed to upload %s: %w", a.Name, err)
+			}
+		}(a)
+	}
+
+	wg.Wait()
+	close(errCh)
+
+	var errs []error
+	for err := range errCh {
+		errs = append(errs, err)
+	}
+	if len(errs) > 0 {
+		return errors.Join(errs...)
 	}
 
 	log.Info("Published to GitHub Releases")
 	return nil
 }
 
-// getOrCreateRelease gets or creates a GitHub release
-func (p *GitHubPublisher) getOrCreateRelease(ctx context.Context, owner, repo, tag string) (int64, error) {
-	// Try to get existing release
-	url := fmt.Sprintf("https://api.github.com/repos/%s/%s/releases/tags/%s", owner, repo, tag)
-	req, _ := http.NewRequestWithContext(ctx, "GET", url, nil)
-	req.Header.Set("Authorization", "token "+p.token)
-	req.Header.Set("Accept", "application/vnd.github.v3+json")
-
-	resp, err := http.DefaultClient.Do(req)
+// publishAsset uploads a single asset, skipping it when an identical asset already
+// exists and replacing it when the existing asset is incomplete or corrupted.
+func (p *GitHubPublisher) publishAsset(ctx context.Context, owner, repo string, releaseID int64, a artifact.Artifact, existing *githubAsset) error {
+	stat, err := os.Stat(a.Path)
 	if err != nil {
-		return 0, err
+		return err
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode == 200 {
-		var release struct {
-			ID int64 `json:"id"`
+	if existing != nil {
+		if existing.State == "uploaded" && existing.Size == stat.Size() {
+			log.Info("Asset already uploaded, skipping", "name", a.Name)
+			return nil
 		}
-		if err := json.NewDecoder(resp.Body).Decode(&release); err != nil {
-			return 0, err
+		log.Warn("Replacing incomplete release asset", "name", a.Name, "state", existing.State, "size", existing.Size)
+		if err := p.deleteAsset(ctx, owner, repo, existing.ID); err != nil {
+			return fmt.Errorf("failed to delete existing asset: %w", err)
 		}
+	}
+
+	return retry.Do(ctx, retry.DefaultOptions("upload "+a.Name), func(attempt int) error {
+		uploaded, err := p.uploadAsset(ctx, owner, repo, releaseID, a)
+		if err != nil {
+			// A partially created asset blocks the retry with "already_exists"
+			p.deleteAssetByName(ctx, owner, repo, releaseID, a.Name)
+			return err
+		}
+
+		if uploaded.Size != stat.Size() {
+			if err := p.deleteAsset(ctx, owner, repo, uploaded.ID); err != nil {
+				log.Warn("Failed to delete corrupted asset", "name", a.Name, "error", err)
+			}
+			return fmt.Errorf("uploaded size %d does not match local size %d", uploaded.Size, stat.Size())
+		}
+
+		return nil
+	})
+}
+
+// listAssets returns the assets of a release keyed by name
+func (p *GitHubPublisher) listAssets(ctx context.Context, owner, repo string, releaseID int64) (map[string]*githubAsset, error) {
+	list, err := github.List[githubAsset](ctx, p.client, fmt.Sprintf("/repos/%s/%s/releases/%d/assets", owner, repo, releaseID))
+	if err != nil {
+		return nil, err
+	}
+
+	assets := make(map[string]*githubAsset, len(list))
+	for i := range list {
+		assets[list[i].Name] = &list[i]
+	}
+	return assets, nil
+}
+
+// deleteAsset deletes a release asset
+func (p *GitHubPublisher) deleteAsset(ctx context.Context, owner, repo string, assetID int64) error {
+	err := p.client.Do(ctx, "DELETE", fmt.Sprintf("/repos/%s/%s/releases/assets/%d", owner, repo, assetID), nil, nil)
+	if github.IsNotFound(err) {
+		return nil
+	}
+	return err
+}
+
+// deleteAssetByName removes a leftover asset after a failed upload, ignoring errors
+func (p *GitHubPublisher) deleteAssetByName(ctx context.Context, owner, repo string, releaseID int64, name string) {
+	assets, err := p.listAssets(ctx, owner, repo, releaseID)
+	if err != nil {
+		return
+	}
+	if existing, ok := assets[name]; ok {
+		if err := p.deleteAsset(ctx, owner, repo, existing.ID); err != nil {
+			log.Debug("Failed to delete leftover asset", "name", name, "error", err)
+		}
+	}
+}
+
+// getOrCreateRelease gets or creates a GitHub release
+func (p *GitHubPublisher) getOrCreateRelease(ctx context.Context, owner, repo, tag string) (int64, error) {
+	var release struct {
+		ID      int64  `json:"id"`
+		HTMLURL string `json:"html_url"`
+	}
+
+	// Try to get existing release
+	err := p.client.Do(ctx, "GET", fmt.Sprintf("/repos/%s/%s/releases/tags/%s", owner, repo, neturl.PathEscape(tag)), nil, &release)
+	if err == nil {
+		p.tmplCtx.Set("ReleaseURL", release.HTMLURL)
 		return release.ID, nil
 	}
+	if !github.IsNotFound(err) {
+		return 0, fmt.Errorf("failed to get release: %w", err)
+	}
 
 	// Create new release
 	name := p.config.NameTemplate
 	if name == "" {
 		name = tag
 	}
-	name, _ = p.tmplCtx.Apply(name)
+	name, _ = p.tmplCtx.Apply("release.name_template", name)
 
 	body := map[string]interface{}{
 		"tag_name":               tag,
 		"name":                   name,
 		"draft":                  p.config.Draft,
-		"prerelease":             p.config.Prerelease == "true",
+		"prerelease":             markPrerelease(p.config, p.tmplCtx),
 		"generate_release_notes": false,
 	}
+	if notes := p.tmplCtx.Get("ReleaseNotes"); notes != "" {
+		body["body"] = notes
+	}
 
 	if p.config.TargetCommitish != "" {
 		body["target_commitish"] = p.config.TargetCommitish
 	}
 
-	bodyJSON, _ := json.Marshal(body)
-	url = fmt.Sprintf("https://api.github.com/repos/%s/%s/releases", owner, repo)
-	req, _ = http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(bodyJSON))
-	req.Header.Set("Authorization", "token "+p.token)
-	req.Header.Set("Accept", "application/vnd.github.v3+json")
-	req.Header.Set("Content-Type", "application/json")
-
-	resp, err = http.DefaultClient.Do(req)
-	if err != nil {
-		return 0, err
+	if err := p.client.Do(ctx, "POST", fmt.Sprintf("/repos/%s/%s/releases", owner, repo), body, &release); err != nil {
+		return 0, fmt.Errorf("failed to create release: %w", err)
 	}
-	defer resp.Body.Close()
+	p.tmplCtx.Set("ReleaseURL", release.HTMLURL)
 
-	if resp.StatusCode != 201 {
-		body, _ := io.ReadAll(resp.Body)
-		return 0, fmt.Errorf("failed to create release: %s", body)
-	}
+	return release.ID, nil
+}
 
-	var release struct {
-		ID int64 `json:"id"`
+// markPrerelease reports whether the release is marked as a prerelease:
+// release.prerelease renders to "true", or is "auto" and the tag has a
+// semver prerelease, e.g. v1.3.0-rc.1
+func markPrerelease(cfg config.Release, tmplCtx *tmpl.Context) bool {
+	value, err := tmplCtx.Apply("release.prerelease", cfg.Prerelease)
+	if err != nil {
+		log.Warn("Failed to apply the prerelease template", "error", err)
+		return false
 	}
-	if err := json.NewDecoder(resp.Body).Decode(&release); err != nil {
-		return 0, err
+	switch strings.TrimSpace(value) {
+	case "auto":
+		return tmplCtx.IsPrerelease()
+	case "true":
+		return true
+	default:
+		return false
 	}
-
-	return release.ID, nil
 }
 
 // uploadAsset uploads an asset to a GitHub release
-func (p *GitHubPublisher) uploadAsset(ctx context.Context, owner, repo string, releaseID int64, a artifact.Artifact) error {
+func (p *GitHubPublisher) uploadAsset(ctx context.Context, owner, repo string, releaseID int64, a artifact.Artifact) (*githubAsset, error) {
 	log.Debug("Uploading asset", "name", a.Name)
 
 	file, err := os.Open(a.Path)
 	if err != nil {
-		return err
+		return nil, retry.Permanent(err)
 	}
 	defer file.Close()
 
 	stat, err := file.Stat()
 	if err != nil {
-		return err
+		return nil, retry.Permanent(err)
 	}
 
-	url := fmt.Sprintf("https://uploads.github.com/repos/%s/%s/releases/%d/assets?name=%s",
-		owner, repo, releaseID, a.Name)
-
-	req, _ := http.NewRequestWithContext(ctx, "POST", url, file)
-	req.Header.Set("Authorization", "token "+p.token)
-	req.Header.Set("Content-Type", "application/octet-stream")
-	req.ContentLength = stat.Size()
-
-	resp, err := http.DefaultClient.Do(req)
-	if err != nil {
-		return err
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != 201 {
-		body, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("failed to upload asset: %s", body)
+	path := fmt.Sprintf("/repos/%s/%s/releases/%d/assets?name=%s", owner, repo, releaseID, neturl.QueryEscape(a.Name))
+	var uploaded githubAsset
+	if err := p.client.Upload(ctx, path, file, stat.Size(), &uploaded); err != nil {
+		err = fmt.Errorf("failed to upload asset: %w", err)
+		if github.HasStatus(err, 401) || github.HasStatus(err, 403) || github.HasStatus(err, 404) {
+			return nil, retry.Permanent(err)
+		}
+		return nil, err
 	}
 
-	return nil
+	return &uploaded, nil
 }
 
 // NPMPublisher publishes to NPM registries
 type NPMPublisher struct {
 	config  config.NPM
 	tmplCtx *tmpl.Context
+	distDir string
+}
+
+func init() {
+	RegisterRequirements(func(cfg *config.Config) []Requirement {
+		var reqs []Requirement
+		for _, npm := range cfg.NPMs {
+			if npm.Token == "" && npm.SkipUpload != "true" {
+				reqs = append(reqs, Requirement{NeededBy: neededBy("npm", npm.Name), Env: []string{"NPM_TOKEN"}, Disable: npm.Disable})
+			}
+		}
+		return reqs
+	})
 }
 
-// NewNPMPublisher creates a new NPM publisher
-func NewNPMPublisher(cfg config.NPM, tmplCtx *tmpl.Context) *NPMPublisher {
+// NewNPMPublisher creates a new NPM publisher. Platform packages are
+// written to distDir.
+func NewNPMPublisher(cfg config.NPM, tmplCtx *tmpl.Context, distDir string) *NPMPublisher {
 	return &NPMPublisher{
 		config:  cfg,
 		tmplCtx: tmplCtx,
+		distDir: distDir,
 	}
 }
 
 // Publish publishes to NPM
 func (p *NPMPublisher) Publish(ctx context.Context, artifacts []artifact.Artifact) error {
-	registry := p.config.Registry
-	if registry == "" {
-		registry = "https://registry.npmjs.org"
-	}
-
-	token := p.config.Token
-	if token == "" {
-		token = os.Getenv("NPM_TOKEN")
+	if p.config.PlatformPackages {
+		return p.platformPackages(ctx, artifacts)
 	}
 
+	registry, token := p.registry()
 	if token == "" {
 		return fmt.Errorf("NPM_TOKEN is required")
 	}
@@ -289,29 +448,8 @@ func (p *NPMPublisher) Publish(ctx context.Context, artifacts []artifact.Artifac
 		}
 	}
 
-	// Create .npmrc for authentication
-	npmrcContent := fmt.Sprintf("//%s/:_authToken=%s\n", strings.TrimPrefix(strings.TrimPrefix(registry, "https://"), "http://"), token)
-	npmrcPath := filepath.Join(tmpDir, ".npmrc")
-	if err := os.WriteFile(npmrcPath, []byte(npmrcContent), 0600); err != nil {
-		return fmt.Errorf("failed to write .npmrc: %w", err)
-	}
-
-	// Run npm publish
-	args := []string{"publish", "--registry", registry}
-	if p.config.Access != "" {
-		args = append(args, "--access", p.config.Access)
-	}
-	if p.config.Tag != "" {
-		args = append(args, "--tag", p.config.Tag)
-	}
-
-	cmd := exec.CommandContext(ctx, "npm", args...)
-	cmd.Dir = tmpDir
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
-
-	if err := cmd.Run(); err != nil {
-		return fmt.Errorf("npm publish failed: %w", err)
+	if err := p.npmPublish(ctx, tmpDir, registry, token); err != nil {
+		return err
 	}
 
 	log.Info("NPM package published successfully", "name", pkg["name"], "version", pkg["version"])
@@ -324,6 +462,18 @@ type HomebrewPublisher struct {
 	tmplCtx *tmpl.Context
 }
 
+func init() {
+	RegisterRequirements(func(cfg *config.Config) []Requirement {
+		var reqs []Requirement
+		for _, brew := range cfg.Brews {
+			if needsGitHubToken(tapRepository(brew.Tap, brew.Repository)) {
+				reqs = append(reqs, Requirement{NeededBy: neededBy("homebrew", brew.Name), Env: []string{"GITHUB_TOKEN"}, Disable: brew.Disable})
+			}
+		}
+		return reqs
+	})
+}
+
 // NewHomebrewPublisher creates a new Homebrew publisher
 func NewHomebrewPublisher(cfg config.Brew, tmplCtx *tmpl.Context) *HomebrewPublisher {
 	return &HomebrewPublisher{
@@ -343,23 +493,13 @@ func (p *HomebrewPublisher) Publish(ctx context.Context, artifacts []artifact.Ar
 	}
 
 	// Determine tap repository
-	tap := p.config.Tap
-	if tap.Owner == "" && p.config.Repository.Owner != "" {
-		tap = p.config.Repository
-	}
-
-	if tap.Owner == "" {
+	tap := tapRepository(p.config.Tap, p.config.Repository)
+	if tap.Owner == "" && tap.Git.URL == "" {
 		return fmt.Errorf("Homebrew tap repository is required")
 	}
 
 	log.Debug("Generated Homebrew formula", "formula", formula)
 
-	// Push to tap repository using GitHub API
-	token := os.Getenv("GITHUB_TOKEN")
-	if token == "" {
-		return fmt.Errorf("GITHUB_TOKEN is required for Homebrew tap push")
-	}
-
 	// Determine formula file path
 	name := p.config.Name
 	if name == "" {
@@ -370,89 +510,63 @@ func (p *HomebrewPublisher) Publish(ctx context.Context, artifacts []artifact.Ar
 		formulaPath = fmt.Sprintf("%s/%s.rb", p.config.Directory, name)
 	}
 
-	// Get current file SHA (for updates)
-	sha, err := p.getFileSHA(ctx, token, tap.Owner, tap.Name, formulaPath)
-	if err != nil {
-		log.Debug("No existing formula found, will create new one", "error", err)
-	}
-
 	// Commit the formula
 	commitMsg := fmt.Sprintf("Update %s to %s", name, p.tmplCtx.Get("Version"))
 	if p.config.CommitMsgTemplate != "" {
-		commitMsg, _ = p.tmplCtx.Apply(p.config.CommitMsgTemplate)
+		commitMsg, _ = p.tmplCtx.Apply("brews.commit_msg_template", p.config.CommitMsgTemplate)
 	}
 
-	if err := p.pushFormula(ctx, token, tap.Owner, tap.Name, formulaPath, formula, commitMsg, sha); err != nil {
+	if err := commitToRepo(ctx, p.tmplCtx, tap, formulaPath, formula, commitMsg, p.config.CommitAuthor); err != nil {
 		return fmt.Errorf("failed to push formula: %w", err)
 	}
 
-	log.Info("Homebrew formula published", "repo", fmt.Sprintf("%s/%s", tap.Owner, tap.Name), "path", formulaPath)
+	log.Info("Homebrew formula published", "repo", repoLabel(tap), "path", formulaPath)
 	return nil
 }
 
-// getFileSHA gets the SHA of an existing file in a GitHub repository
-func (p *HomebrewPublisher) getFileSHA(ctx context.Context, token, owner, repo, path string) (string, error) {
-	url := fmt.Sprintf("https://api.github.com/repos/%s/%s/contents/%s", owner, repo, path)
-
-	req, _ := http.NewRequestWithContext(ctx, "GET", url, nil)
-	req.Header.Set("Authorization", "token "+token)
-	req.Header.Set("Accept", "application/vnd.github.v3+json")
-
-	resp, err := http.DefaultClient.Do(req)
-	if err != nil {
-		return "", err
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != 200 {
-		return "", fmt.Errorf("file not found")
+// getGitHubFileSHA gets the SHA of an existing file in a GitHub repository,
+// or "" when it does not exist. An empty ref reads from the default branch.
+func getGitHubFileSHA(ctx context.Context, client *github.Client, owner, repo, path, ref string) (string, error) {
+	url := fmt.Sprintf("/repos/%s/%s/contents/%s", owner, repo, path)
+	if ref != "" {
+		url += "?ref=" + neturl.QueryEscape(ref)
 	}
 
 	var result struct {
 		SHA string `json:"sha"`
 	}
-	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+	err := client.Do(ctx, "GET", url, nil, &result)
+	if github.IsNotFound(err) {
+		return "", nil
+	}
+	if err != nil {
 		return "", err
 	}
-
 	return result.SHA, nil
 }
 
-// pushFormula pushes the formula to the tap repository
-func (p *HomebrewPublisher) pushFormula(ctx context.Context, token, owner, repo, path, content, message, sha string) error {
-	url := fmt.Sprintf("https://api.github.com/repos/%s/%s/contents/%s", owner, repo, path)
-
-	// Base64 encode the content
-	encodedContent := encodeBase64(content)
-
+// putGitHubFile creates or updates a file in a GitHub repository via the
+// contents API. An empty branch commits to the default branch.
+func putGitHubFile(ctx context.Context, client *github.Client, owner, repo, branch, path, content, message, sha string, author config.CommitAuthor) error {
 	body := map[string]interface{}{
 		"message": message,
-		"content": encodedContent,
+		"content": encodeBase64(content),
 	}
 
 	if sha != "" {
 		body["sha"] = sha
 	}
-
-	bodyJSON, _ := json.Marshal(body)
-
-	req, _ := http.NewRequestWithContext(ctx, "PUT", url, bytes.NewReader(bodyJSON))
-	req.Header.Set("Authorization", "token "+token)
-	req.Header.Set("Accept", "application/vnd.github.v3+json")
-	req.Header.Set("Content-Type", "application/json")
-
-	resp, err := http.DefaultClient.Do(req)
-	if err != nil {
-		return err
+	if branch != "" {
+		body["branch"] = branch
 	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode >= 400 {
-		respBody, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("GitHub API error: %s", respBody)
+	if author.Name != "" && author.Email != "" {
+		body["committer"] = map[string]string{
+			"name":  author.Name,
+			"email": author.Email,
+		}
 	}
 
-	return nil
+	return client.Do(ctx, "PUT", fmt.Sprintf("/repos/%s/%s/contents/%s", owner, repo, path), body, nil)
 }
 
 // encodeBase64 encodes a string to base64
@@ -460,266 +574,385 @@ func encodeBase64(s string) string {
 	return base64.StdEncoding.EncodeToString([]byte(s))
 }
 
-// generateFormula generates a Homebrew formula
-func (p *HomebrewPublisher) generateFormula(artifacts []artifact.Artifact) (string, error) {
-	name := p.config.Name
-	if name == "" {
-		name = p.tmplCtx.Get("ProjectName")
+// fileSHA256 returns the hex-encoded SHA256 digest of a local file
+func fileSHA256(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
 	}
+	defer f.Close()
 
-	// Capitalize first letter for Ruby class name
-	className := strings.Title(strings.ReplaceAll(name, "-", ""))
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
 
-	var formula strings.Builder
-	formula.WriteString(fmt.Sprintf("class %s < Formula\n", className))
-	formula.WriteString(fmt.Sprintf("  desc \"%s\"\n", p.config.Description))
-	formula.WriteString(fmt.Sprintf("  homepage \"%s\"\n", p.config.Homepage))
-	formula.WriteString(fmt.Sprintf("  version \"%s\"\n", p.tmplCtx.Get("Version")))
-	formula.WriteString(fmt.Sprintf("  license \"%s\"\n", p.config.License))
-	formula.WriteString("\n")
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
 
-	// Add URLs for each platform
-	for _, a := range artifacts {
-		if a.Type != artifact.TypeArchive {
+// artifactSHA256 returns the SHA256 digest of an artifact, preferring the
+// value recorded by the checksum stage over hashing the file again. The
+// algorithm of a checksums file that does not record one follows from the
+// length of its checksums.
+func artifactSHA256(a artifact.Artifact, artifacts []artifact.Artifact) (string, error) {
+	if sum := a.Checksum("sha256"); sum != "" {
+		return sum, nil
+	}
+
+	for _, c := range artifacts {
+		if c.Type != artifact.TypeChecksum {
 			continue
 		}
-
-		urlTemplate := p.config.URLTemplate
-		if urlTemplate == "" {
-			urlTemplate = "https://github.com/{{ .Env.GITHUB_OWNER }}/{{ .Env.GITHUB_REPO }}/releases/download/{{ .Tag }}/{{ .ArtifactName }}"
+		if algo, ok := c.Extra["algorithm"].(string); ok && algo != string(checksum.AlgorithmSHA256) {
+			continue
 		}
-
-		tmplCtx := p.tmplCtx.WithArtifact(a.Name, a.Goos, a.Goarch, a.Goarm, a.Goamd64)
-		url, _ := tmplCtx.Apply(urlTemplate)
-
-		if a.Goos == "darwin" && a.Goarch == "amd64" {
-			formula.WriteString(fmt.Sprintf("  url \"%s\"\n", url))
-		} else if a.Goos == "darwin" && a.Goarch == "arm64" {
-			formula.WriteString(fmt.Sprintf("  on_arm do\n"))
-			formula.WriteString(fmt.Sprintf("    url \"%s\"\n", url))
-			formula.WriteString(fmt.Sprintf("  end\n"))
+		entries, err := checksum.ParseFile(c.Path)
+		if err != nil {
+			return "", fmt.Errorf("failed to read checksums %s: %w", c.Name, err)
 		}
-	}
-
-	// Add dependencies
-	for _, dep := range p.config.Dependencies {
-		if dep.Type == "" {
-			formula.WriteString(fmt.Sprintf("  depends_on \"%s\"\n", dep.Name))
-		} else {
-			formula.WriteString(fmt.Sprintf("  depends_on \"%s\" => :%s\n", dep.Name, dep.Type))
+		for _, e := range entries {
+			if e.Name != a.Name {
+				continue
+			}
+			if e.Algorithm != checksum.AlgorithmSHA256 {
+				return "", fmt.Errorf("checksum of %s in %s is %s, not sha256", a.Name, c.Name, e.Algorithm)
+			}
+			return e.Sum, nil
 		}
 	}
 
-	// Add install section
-	if p.config.Install != "" {
-		formula.WriteString("\n  def install\n")
-		formula.WriteString(fmt.Sprintf("    %s\n", p.config.Install))
-		formula.WriteString("  end\n")
-	}
+	return fileSHA256(a.Path)
+}
 
-	// Add test section
-	if p.config.Test != "" {
-		formula.WriteString("\n  test do\n")
-		formula.WriteString(fmt.Sprintf("    %s\n", p.config.Test))
-		formula.WriteString("  end\n")
+// matchesIDs reports whether the artifact belongs to one of the given build
+// IDs or, for archives, is one of the given archive IDs
+func matchesIDs(a artifact.Artifact, ids []string) bool {
+	if len(ids) == 0 {
+		return true
 	}
-
-	// Add caveats
-	if p.config.Caveats != "" {
-		formula.WriteString("\n  def caveats\n")
-		formula.WriteString(fmt.Sprintf("    <<~EOS\n      %s\n    EOS\n", p.config.Caveats))
-		formula.WriteString("  end\n")
+	archiveID, _ := a.Extra["id"].(string)
+	for _, id := range ids {
+		if a.BuildID == id || (archiveID != "" && archiveID == id) {
+			return true
+		}
 	}
-
-	formula.WriteString("end\n")
-
-	return formula.String(), nil
+	return false
 }
 
-// DockerPublisher publishes Docker images
-type DockerPublisher struct {
-	config  config.Docker
-	tmplCtx *tmpl.Context
-}
-
-// NewDockerPublisher creates a new Docker publisher
-func NewDockerPublisher(cfg config.Docker, tmplCtx *tmpl.Context) *DockerPublisher {
-	return &DockerPublisher{
-		config:  cfg,
-		tmplCtx: tmplCtx,
+// matchesVariant reports whether an arm or amd64 artifact is the goarm and
+// goamd64 variant a publisher asks for, when it asks for one
+func matchesVariant(a artifact.Artifact, goarm, goamd64 string) bool {
+	if a.Goarch == "arm" && goarm != "" && a.Goarm != goarm {
+		return false
 	}
+	if a.Goarch == "amd64" && goamd64 != "" && a.Goamd64 != "" && a.Goamd64 != goamd64 {
+		return false
+	}
+	return true
 }
 
-// Publish pushes Docker images
-func (p *DockerPublisher) Publish(ctx context.Context, artifacts []artifact.Artifact) error {
-	log.Info("Pushing Docker images")
-
-	// TODO: Implement Docker push
-
-	return nil
-}
-
-// CloudSmithPublisher publishes to CloudSmith
-type CloudSmithPublisher struct {
-	config  config.CloudSmith
-	tmplCtx *tmpl.Context
+// sortedArtifacts returns the artifacts ordered by platform, variant and
+// name. Publishers taking the first match of a platform then pick the same
+// artifact every run, and the lowest goarm and goamd64, which run on the
+// most machines, unless configured otherwise.
+func sortedArtifacts(artifacts []artifact.Artifact) []artifact.Artifact {
+	sorted := append([]artifact.Artifact(nil), artifacts...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		a, b := sorted[i], sorted[j]
+		if a.Goos != b.Goos {
+			return a.Goos < b.Goos
+		}
+		if a.Goarch != b.Goarch {
+			return a.Goarch < b.Goarch
+		}
+		if a.Goarm != b.Goarm {
+			return a.Goarm < b.Goarm
+		}
+		if a.Goamd64 != b.Goamd64 {
+			return a.Goamd64 < b.Goamd64
+		}
+		return a.Name < b.Name
+	})
+	return sorted
 }
 
-// NewCloudSmithPublisher creates a new CloudSmith publisher
-func NewCloudSmithPublisher(cfg config.CloudSmith, tmplCtx *tmpl.Context) *CloudSmithPublisher {
-	return &CloudSmithPublisher{
-		config:  cfg,
-		tmplCtx: tmplCtx,
+// packageFormat returns the repository format of an artifact: deb, rpm, apk
+// or archlinux for Linux packages, python for wheels and gem for gems, or ""
+// for artifacts package repositories do not take
+func packageFormat(a artifact.Artifact) string {
+	switch a.Type {
+	case artifact.TypeLinuxPackage:
+		if format, _ := a.Extra[artifact.ExtraFormat].(string); format != "" {
+			return format
+		}
+		switch ext := filepath.Ext(a.Path); ext {
+		case ".deb", ".rpm", ".apk":
+			return ext[1:]
+		}
+	case artifact.TypePyPI:
+		return "python"
+	case artifact.TypeGem:
+		return "gem"
 	}
+	return ""
 }
 
-// Publish publishes packages to CloudSmith
-func (p *CloudSmithPublisher) Publish(ctx context.Context, artifacts []artifact.Artifact) error {
-	log.Info("Publishing to CloudSmith", "owner", p.config.Owner, "repo", p.config.Repository)
+// generateFormula generates a Homebrew formula
+func (p *HomebrewPublisher) generateFormula(artifacts []artifact.Artifact) (string, error) {
+	name := p.config.Name
+	if name == "" {
+		name = p.tmplCtx.Get("ProjectName")
+	}
 
-	token := os.Getenv("CLOUDSMITH_API_KEY")
-	if token == "" {
-		return fmt.Errorf("CLOUDSMITH_API_KEY is required")
+	// Collect one archive per platform, keyed by goos and goarch
+	urlTemplate := p.config.URLTemplate
+	if urlTemplate == "" {
+		urlTemplate = "{{ .ReleaseDownloadURL }}/{{ .ArtifactName }}"
 	}
 
-	for _, a := range artifacts {
-		if a.Type != artifact.TypeLinuxPackage {
+	sources := make(map[string]formulaSource)
+	for _, a := range sortedArtifacts(artifacts) {
+		if a.Type != artifact.TypeArchive || (a.Goos != "darwin" && a.Goos != "linux") {
+			continue
+		}
+		if !matchesIDs(a, p.config.IDs) || !matchesVariant(a, p.config.Goarm, p.config.Goamd64) {
 			continue
 		}
 
-		if err := p.uploadPackage(ctx, token, a); err != nil {
-			return fmt.Errorf("failed to upload %s: %w", a.Name, err)
+		key := a.Goos + "_" + a.Goarch
+		if _, ok := sources[key]; ok {
+			continue
 		}
-	}
 
-	return nil
-}
+		tmplCtx := p.tmplCtx.ForArtifact(a)
+		url, err := tmplCtx.Apply("brews.url_template", urlTemplate)
+		if err != nil {
+			return "", fmt.Errorf("failed to apply url template: %w", err)
+		}
 
-// uploadPackage uploads a package to CloudSmith
-func (p *CloudSmithPublisher) uploadPackage(ctx context.Context, token string, a artifact.Artifact) error {
-	log.Debug("Uploading package", "name", a.Name)
+		sum, err := artifactSHA256(a, artifacts)
+		if err != nil {
+			log.Warn("Failed to compute sha256, omitting platform from formula", "artifact", a.Name, "error", err)
+			continue
+		}
 
-	file, err := os.Open(a.Path)
-	if err != nil {
-		return err
+		sources[key] = formulaSource{URL: url, SHA256: sum}
 	}
-	defer file.Close()
-
-	var body bytes.Buffer
-	writer := multipart.NewWriter(&body)
 
-	part, err := writer.CreateFormFile("package_file", filepath.Base(a.Path))
-	if err != nil {
-		return err
+	if len(sources) == 0 {
+		return "", fmt.Errorf("no darwin or linux archives found for Homebrew formula")
 	}
 
-	if _, err := io.Copy(part, file); err != nil {
-		return err
+	doc := formula{
+		// Capitalize first letter for Ruby class name
+		ClassName:        strings.Title(strings.ReplaceAll(name, "-", "")),
+		Description:      p.config.Description,
+		Homepage:         p.config.Homepage,
+		Version:          p.tmplCtx.Get("Version"),
+		License:          p.config.License,
+		DownloadStrategy: p.config.DownloadStrategy,
+		Sources:          sources,
+		Dependencies:     p.config.Dependencies,
+		Conflicts:        p.config.Conflicts,
+		Install:          p.config.Install,
+		ExtraInstall:     p.config.ExtraInstall,
+		PostInstall:      p.config.PostInstall,
+		Caveats:          p.config.Caveats,
+		Test:             p.config.Test,
 	}
-
-	if p.config.Distribution != "" {
-		writer.WriteField("distribution", p.config.Distribution)
+	if doc.Install == "" {
+		doc.Install = fmt.Sprintf("bin.install %s", manifest.RubyString(name))
 	}
 
-	writer.Close()
-
-	url := fmt.Sprintf("https://upload.cloudsmith.io/%s/%s/",
-		p.config.Owner, p.config.Repository)
-
-	req, _ := http.NewRequestWithContext(ctx, "POST", url, &body)
-	req.Header.Set("X-Api-Key", token)
-	req.Header.Set("Content-Type", writer.FormDataContentType())
-
-	resp, err := http.DefaultClient.Do(req)
+	data, err := doc.Generate()
 	if err != nil {
-		return err
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode >= 400 {
-		respBody, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("upload failed: %s", respBody)
+		return "", err
 	}
-
-	return nil
+	return string(data), nil
 }
 
-// FuryPublisher publishes to Fury.io
-type FuryPublisher struct {
-	config  config.Fury
-	tmplCtx *tmpl.Context
+// formula is a Homebrew formula
+type formula struct {
+	ClassName   string
+	Description string
+	Homepage    string
+	Version     string
+	License     string
+	// DownloadStrategy is Ruby code and rendered as it is
+	DownloadStrategy string
+	// Sources are keyed by goos and goarch, e.g. darwin_arm64
+	Sources      map[string]formulaSource
+	Dependencies []config.BrewDependency
+	Conflicts    []string
+	// Install, ExtraInstall, PostInstall, Caveats and Test are Ruby code and
+	// rendered as they are
+	Install      string
+	ExtraInstall string
+	PostInstall  string
+	Caveats      string
+	Test         string
 }
 
-// NewFuryPublisher creates a new Fury publisher
-func NewFuryPublisher(cfg config.Fury, tmplCtx *tmpl.Context) *FuryPublisher {
-	return &FuryPublisher{
-		config:  cfg,
-		tmplCtx: tmplCtx,
-	}
+// formulaSource is the archive of a formula for one platform
+type formulaSource struct {
+	URL    string
+	SHA256 string
 }
 
-// Publish publishes packages to Fury.io
-func (p *FuryPublisher) Publish(ctx context.Context, artifacts []artifact.Artifact) error {
-	log.Info("Publishing to Fury.io", "account", p.config.Account)
-
-	token := os.Getenv("FURY_TOKEN")
-	if token == "" {
-		return fmt.Errorf("FURY_TOKEN is required")
+// Generate renders the formula with its values in escaped Ruby strings
+func (f formula) Generate() ([]byte, error) {
+	var buf strings.Builder
+	buf.WriteString(fmt.Sprintf("class %s < Formula\n", f.ClassName))
+	buf.WriteString(fmt.Sprintf("  desc %s\n", manifest.RubyString(f.Description)))
+	buf.WriteString(fmt.Sprintf("  homepage %s\n", manifest.RubyString(f.Homepage)))
+	buf.WriteString(fmt.Sprintf("  version %s\n", manifest.RubyString(f.Version)))
+	if f.License != "" {
+		buf.WriteString(fmt.Sprintf("  license %s\n", manifest.RubyString(f.License)))
+	}
+
+	// Add per-platform URLs, skipping any platform without an archive
+	writeSource := func(indent string, src formulaSource) {
+		if f.DownloadStrategy != "" {
+			buf.WriteString(fmt.Sprintf("%surl %s, using: %s\n", indent, manifest.RubyString(src.URL), f.DownloadStrategy))
+		} else {
+			buf.WriteString(fmt.Sprintf("%surl %s\n", indent, manifest.RubyString(src.URL)))
+		}
+		buf.WriteString(fmt.Sprintf("%ssha256 %s\n", indent, manifest.RubyString(src.SHA256)))
+	}
+	writeArch := func(block, key string) {
+		src, ok := f.Sources[key]
+		if !ok {
+			return
+		}
+		buf.WriteString(fmt.Sprintf("    %s do\n", block))
+		writeSource("      ", src)
+		buf.WriteString("    end\n")
 	}
 
-	for _, a := range artifacts {
-		if a.Type != artifact.TypeLinuxPackage {
+	for _, goos := range []string{"darwin", "linux"} {
+		_, hasIntel := f.Sources[goos+"_amd64"]
+		_, hasArm64 := f.Sources[goos+"_arm64"]
+		arm, hasArm := f.Sources[goos+"_arm"]
+		if goos != "linux" {
+			hasArm = false
+		}
+		if !hasIntel && !hasArm64 && !hasArm {
 			continue
 		}
 
-		if err := p.uploadPackage(ctx, token, a); err != nil {
-			return fmt.Errorf("failed to upload %s: %w", a.Name, err)
+		block := "on_macos"
+		if goos == "linux" {
+			block = "on_linux"
 		}
-	}
-
-	return nil
-}
 
-// uploadPackage uploads a package to Fury.io
-func (p *FuryPublisher) uploadPackage(ctx context.Context, token string, a artifact.Artifact) error {
-	log.Debug("Uploading package", "name", a.Name)
+		buf.WriteString(fmt.Sprintf("\n  %s do\n", block))
+		writeArch("on_intel", goos+"_amd64")
+		switch {
+		case hasArm64 && hasArm:
+			// 32-bit ARM Linux gets the goarm archive
+			buf.WriteString("    on_arm do\n      if Hardware::CPU.is_64_bit?\n")
+			writeSource("        ", f.Sources[goos+"_arm64"])
+			buf.WriteString("      else\n")
+			writeSource("        ", arm)
+			buf.WriteString("      end\n    end\n")
+		case hasArm:
+			buf.WriteString("    on_arm do\n      unless Hardware::CPU.is_64_bit?\n")
+			writeSource("        ", arm)
+			buf.WriteString("      end\n    end\n")
+		default:
+			writeArch("on_arm", goos+"_arm64")
+		}
+		buf.WriteString("  end\n")
+	}
 
-	file, err := os.Open(a.Path)
-	if err != nil {
-		return err
+	// Add dependencies
+	if len(f.Dependencies) > 0 || len(f.Conflicts) > 0 {
+		buf.WriteString("\n")
+	}
+	for _, dep := range f.Dependencies {
+		line := fmt.Sprintf("depends_on %s", manifest.RubyString(dep.Name))
+		if dep.Type != "" {
+			line = fmt.Sprintf("depends_on %s => :%s", manifest.RubyString(dep.Name), dep.Type)
+		}
+		switch dep.OS {
+		case "mac", "macos", "darwin":
+			buf.WriteString(fmt.Sprintf("  on_macos do\n    %s\n  end\n", line))
+		case "linux":
+			buf.WriteString(fmt.Sprintf("  on_linux do\n    %s\n  end\n", line))
+		default:
+			buf.WriteString(fmt.Sprintf("  %s\n", line))
+		}
+	}
+	for _, conflict := range f.Conflicts {
+		buf.WriteString(fmt.Sprintf("  conflicts_with %s\n", manifest.RubyString(conflict)))
 	}
-	defer file.Close()
 
-	var body bytes.Buffer
-	writer := multipart.NewWriter(&body)
+	// Add install section
+	buf.WriteString("\n  def install\n")
+	writeRubyBlock(&buf, f.Install, "    ")
+	if f.ExtraInstall != "" {
+		writeRubyBlock(&buf, f.ExtraInstall, "    ")
+	}
+	buf.WriteString("  end\n")
 
-	part, err := writer.CreateFormFile("package", filepath.Base(a.Path))
-	if err != nil {
-		return err
+	// Add post install section
+	if f.PostInstall != "" {
+		buf.WriteString("\n  def post_install\n")
+		writeRubyBlock(&buf, f.PostInstall, "    ")
+		buf.WriteString("  end\n")
 	}
 
-	if _, err := io.Copy(part, file); err != nil {
-		return err
+	// Add caveats
+	if f.Caveats != "" {
+		buf.WriteString("\n  def caveats\n")
+		buf.WriteString("    <<~EOS\n")
+		writeRubyBlock(&buf, f.Caveats, "      ")
+		buf.WriteString("    EOS\n")
+		buf.WriteString("  end\n")
 	}
 
-	writer.Close()
+	// Add test section
+	if f.Test != "" {
+		buf.WriteString("\n  test do\n")
+		writeRubyBlock(&buf, f.Test, "    ")
+		buf.WriteString("  end\n")
+	}
 
-	url := fmt.Sprintf("https://push.fury.io/%s/", p.config.Account)
+	buf.WriteString("end\n")
 
-	req, _ := http.NewRequestWithContext(ctx, "POST", url, &body)
-	req.SetBasicAuth(token, "")
-	req.Header.Set("Content-Type", writer.FormDataContentType())
+	return []byte(buf.String()), nil
+}
 
-	resp, err := http.DefaultClient.Do(req)
-	if err != nil {
-		return err
+// writeRubyBlock writes a possibly multi-line snippet, indenting every line
+func writeRubyBlock(b *strings.Builder, text, indent string) {
+	for _, line := range strings.Split(strings.TrimRight(text, "\n"), "\n") {
+		if strings.TrimSpace(line) == "" {
+			b.WriteString("\n")
+			continue
+		}
+		b.WriteString(indent + line + "\n")
 	}
-	defer resp.Body.Close()
+}
 
-	if resp.StatusCode >= 400 {
-		respBody, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("upload failed: %s", respBody)
+// DockerPublisher publishes Docker images
+type DockerPublisher struct {
+	config  config.Docker
+	tmplCtx *tmpl.Context
+}
+
+// NewDockerPublisher creates a new Docker publisher
+func NewDockerPublisher(cfg config.Docker, tmplCtx *tmpl.Context) *DockerPublisher {
+	return &DockerPublisher{
+		config:  cfg,
+		tmplCtx: tmplCtx,
 	}
+}
+
+// Publish pushes Docker images
+func (p *DockerPublisher) Publish(ctx context.Context, artifacts []artifact.Artifact) error {
+	log.Info("Pushing Docker images")
+
+	// TODO: Implement Docker push
 
 	return nil
 }
@@ -0,0 +1,58 @@
+package publish
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/oarkflow/releaser/internal/config"
+	"github.com/oarkflow/releaser/internal/github"
+)
+
+// recordConfirmer declines every action and records it
+type recordConfirmer struct {
+	actions []Action
+}
+
+func (r *recordConfirmer) Confirm(a Action) error {
+	r.actions = append(r.actions, a)
+	return ErrDeclined
+}
+
+func TestGitHubConfirmURL(t *testing.T) {
+	t.Setenv("GITHUB_TOKEN", "test-token")
+	t.Cleanup(func() {
+		Confirmation = nil
+		github.Configure(github.URLs{})
+	})
+
+	tests := []struct {
+		name string
+		urls github.URLs
+		want string
+	}{
+		{name: "github.com", want: "https://github.com/oarkflow/demo/releases/tag/v1.2.3"},
+		{
+			name: "enterprise",
+			urls: github.URLs{API: "https://github.example.com/api/v3/"},
+			want: "https://github.example.com/oarkflow/demo/releases/tag/v1.2.3",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			github.Configure(tt.urls)
+			confirmer := &recordConfirmer{}
+			Confirmation = confirmer
+
+			cfg := config.Release{GitHub: config.ReleaseRepo{Owner: "oarkflow", Name: "demo"}}
+			err := NewGitHubPublisher(cfg, testTemplateContext(t)).Publish(context.Background(), nil)
+			if !errors.Is(err, ErrDeclined) {
+				t.Fatalf("Publish() = %v, want it declined", err)
+			}
+			if len(confirmer.actions) != 1 || confirmer.actions[0].URL != tt.want {
+				t.Errorf("confirmed %+v, want URL %s", confirmer.actions, tt.want)
+			}
+		})
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
+	"strings"
 
 	"github.com/charmbracelet/log"
 	"github.com/oarkflow/releaser/internal/artifact"
@@ -76,6 +77,14 @@ func (p *CratePublisher) Publish(ctx context.Context, artifacts []artifact.Artif
 		args = append(args, "--manifest-path", p.config.ManifestPath)
 	}
 
+	if err := Confirm(Action{
+		Description: "Publish the crate",
+		URL:         p.config.Registry,
+		Command:     append([]string{"cargo"}, args...),
+	}); err != nil {
+		return err
+	}
+
 	cmd := exec.CommandContext(ctx, "cargo", args...)
 	cmd.Stdout = os.Stdout
 	cmd.Stderr = os.Stderr
@@ -103,18 +112,27 @@ func (p *CratePublisher) Publish(ctx context.Context, artifacts []artifact.Artif
 type PyPIPublisher struct {
 	config  config.PyPI
 	tmplCtx *tmpl.Context
+	manager *artifact.Manager
+	distDir string
 }
 
-// NewPyPIPublisher creates a new PyPI publisher
-func NewPyPIPublisher(cfg config.PyPI, tmplCtx *tmpl.Context) *PyPIPublisher {
+// NewPyPIPublisher creates a new PyPI publisher. Wheels built from binaries
+// are written to distDir and registered with manager.
+func NewPyPIPublisher(cfg config.PyPI, tmplCtx *tmpl.Context, manager *artifact.Manager, distDir string) *PyPIPublisher {
 	return &PyPIPublisher{
 		config:  cfg,
 		tmplCtx: tmplCtx,
+		manager: manager,
+		distDir: distDir,
 	}
 }
 
 // Publish publishes to PyPI
 func (p *PyPIPublisher) Publish(ctx context.Context, artifacts []artifact.Artifact) error {
+	if p.config.Binary {
+		return p.binaryWheels(ctx, artifacts)
+	}
+
 	if p.config.SkipUpload == "true" {
 		log.Info("Skipping PyPI publish")
 		return nil
@@ -157,28 +175,22 @@ func (p *PyPIPublisher) Publish(ctx context.Context, artifacts []artifact.Artifa
 
 	args = append(args, distFiles...)
 
+	if err := Confirm(Action{
+		Description: "Upload distributions to PyPI",
+		URL:         repository,
+		Command:     append([]string{"twine"}, args...),
+		Files:       distFiles,
+	}); err != nil {
+		return err
+	}
+
 	cmd := exec.CommandContext(ctx, twine, args...)
 	cmd.Stdout = os.Stdout
 	cmd.Stderr = os.Stderr
 
 	// Set credentials
 	env := os.Environ()
-	username := p.config.Username
-	if username == "" {
-		username = os.Getenv("TWINE_USERNAME")
-	}
-	if username == "" {
-		username = "__token__"
-	}
-
-	password := p.config.Password
-	if password == "" {
-		password = os.Getenv("TWINE_PASSWORD")
-		if password == "" {
-			password = os.Getenv("PYPI_TOKEN")
-		}
-	}
-
+	username, password := p.credentials()
 	if username != "" {
 		env = append(env, "TWINE_USERNAME="+username)
 	}
@@ -220,6 +232,20 @@ func (p *MavenPublisher) Publish(ctx context.Context, artifacts []artifact.Artif
 	mvn, mvnErr := exec.LookPath("mvn")
 	gradle, gradleErr := exec.LookPath("gradle")
 
+	// Android libraries built by the release are deployed as files
+	var aars []artifact.Artifact
+	for _, a := range artifacts {
+		if a.Type == artifact.TypeAndroidLibrary {
+			aars = append(aars, a)
+		}
+	}
+	if len(aars) > 0 {
+		if mvnErr != nil {
+			return fmt.Errorf("mvn is required to deploy Android libraries")
+		}
+		return p.deployFiles(ctx, mvn, aars)
+	}
+
 	if mvnErr != nil && gradleErr != nil {
 		return fmt.Errorf("neither mvn nor gradle found in PATH")
 	}
@@ -240,10 +266,80 @@ func (p *MavenPublisher) Publish(ctx context.Context, artifacts []artifact.Artif
 		cmd = exec.CommandContext(ctx, gradle, args...)
 	}
 
+	if err := Confirm(Action{
+		Description: "Deploy to the Maven repository",
+		URL:         p.config.Repository,
+		Command:     append([]string{filepath.Base(cmd.Path)}, cmd.Args[1:]...),
+	}); err != nil {
+		return err
+	}
+
 	cmd.Stdout = os.Stdout
 	cmd.Stderr = os.Stderr
 
-	// Set credentials
+	cmd.Env = p.env()
+
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("maven deploy failed: %w", err)
+	}
+
+	log.Info("Maven package published successfully")
+	return nil
+}
+
+// deployFiles deploys each artifact with mvn deploy:deploy-file to the
+// repository, whose credentials are the "release" server in settings.xml
+func (p *MavenPublisher) deployFiles(ctx context.Context, mvn string, artifacts []artifact.Artifact) error {
+	if p.config.GroupID == "" || p.config.Repository == "" {
+		return fmt.Errorf("group_id and repository are required to deploy %s", artifacts[0].Name)
+	}
+	version := strings.TrimPrefix(p.tmplCtx.Get("Version"), "v")
+	repository := p.config.Repository
+	if snapshot, _ := p.tmplCtx.GetValue("IsSnapshot").(bool); snapshot && p.config.SnapshotRepo != "" {
+		repository = p.config.SnapshotRepo
+	}
+
+	for _, a := range artifacts {
+		artifactID := p.config.ArtifactID
+		if artifactID == "" {
+			artifactID = a.BuildID
+		}
+		args := []string{
+			"deploy:deploy-file", "-B",
+			"-Dfile=" + a.Path,
+			"-DgroupId=" + p.config.GroupID,
+			"-DartifactId=" + artifactID,
+			"-Dversion=" + version,
+			"-Dpackaging=" + strings.TrimPrefix(filepath.Ext(a.Path), "."),
+			"-Durl=" + repository,
+			"-DrepositoryId=release",
+		}
+
+		if err := Confirm(Action{
+			Description: fmt.Sprintf("Deploy %s to the Maven repository", a.Name),
+			URL:         repository,
+			Command:     append([]string{"mvn"}, args...),
+			Files:       []string{a.Path},
+		}); err != nil {
+			return err
+		}
+
+		log.Info("Deploying to Maven repository", "file", a.Name, "artifact", p.config.GroupID+":"+artifactID+":"+version)
+		cmd := exec.CommandContext(ctx, mvn, args...)
+		cmd.Stdout = os.Stdout
+		cmd.Stderr = os.Stderr
+		cmd.Env = p.env()
+		if err := cmd.Run(); err != nil {
+			return fmt.Errorf("maven deploy of %s failed: %w", a.Name, err)
+		}
+	}
+
+	log.Info("Maven artifacts published successfully", "count", len(artifacts))
+	return nil
+}
+
+// env returns the environment with the credentials settings.xml reads
+func (p *MavenPublisher) env() []string {
 	env := os.Environ()
 	if p.config.Username != "" {
 		env = append(env, "MAVEN_USERNAME="+p.config.Username)
@@ -254,14 +350,7 @@ func (p *MavenPublisher) Publish(ctx context.Context, artifacts []artifact.Artif
 	if p.config.GPGPassphrase != "" {
 		env = append(env, "GPG_PASSPHRASE="+p.config.GPGPassphrase)
 	}
-	cmd.Env = env
-
-	if err := cmd.Run(); err != nil {
-		return fmt.Errorf("maven deploy failed: %w", err)
-	}
-
-	log.Info("Maven package published successfully")
-	return nil
+	return env
 }
 
 // NuGetPublisher publishes .NET packages to NuGet
@@ -310,6 +399,14 @@ func (p *NuGetPublisher) Publish(ctx context.Context, artifacts []artifact.Artif
 
 	for _, pkg := range nupkgs {
 		args := []string{"nuget", "push", pkg, "--source", source}
+		if err := Confirm(Action{
+			Description: fmt.Sprintf("Push %s to NuGet", filepath.Base(pkg)),
+			URL:         source,
+			Command:     append([]string{"dotnet"}, args...),
+			Files:       []string{pkg},
+		}); err != nil {
+			return err
+		}
 		if apiKey != "" {
 			args = append(args, "--api-key", apiKey)
 		}
@@ -331,18 +428,40 @@ func (p *NuGetPublisher) Publish(ctx context.Context, artifacts []artifact.Artif
 type GemPublisher struct {
 	config  config.Gem
 	tmplCtx *tmpl.Context
+	manager *artifact.Manager
+	distDir string
 }
 
-// NewGemPublisher creates a new Gem publisher
-func NewGemPublisher(cfg config.Gem, tmplCtx *tmpl.Context) *GemPublisher {
+func init() {
+	RegisterRequirements(func(cfg *config.Config) []Requirement {
+		var reqs []Requirement
+		for _, gem := range cfg.Gems {
+			// Gems pushed with the gem CLI may use its saved credentials
+			if gem.Binary && gem.APIKey == "" && gem.SkipUpload != "true" {
+				reqs = append(reqs, Requirement{NeededBy: neededBy("gem", gem.Name), Env: []string{"GEM_HOST_API_KEY"}, Disable: gem.Disable})
+			}
+		}
+		return reqs
+	})
+}
+
+// NewGemPublisher creates a new Gem publisher. Gems built from binaries are
+// written to distDir and registered with manager.
+func NewGemPublisher(cfg config.Gem, tmplCtx *tmpl.Context, manager *artifact.Manager, distDir string) *GemPublisher {
 	return &GemPublisher{
 		config:  cfg,
 		tmplCtx: tmplCtx,
+		manager: manager,
+		distDir: distDir,
 	}
 }
 
 // Publish publishes to RubyGems
 func (p *GemPublisher) Publish(ctx context.Context, artifacts []artifact.Artifact) error {
+	if p.config.Binary {
+		return p.binaryGems(ctx, artifacts)
+	}
+
 	if p.config.SkipUpload == "true" {
 		log.Info("Skipping Gem publish")
 		return nil
@@ -381,6 +500,14 @@ func (p *GemPublisher) Publish(ctx context.Context, artifacts []artifact.Artifac
 		if p.config.Host != "" {
 			args = append(args, "--host", p.config.Host)
 		}
+		if err := Confirm(Action{
+			Description: fmt.Sprintf("Push %s to RubyGems", gemFile),
+			URL:         p.config.Host,
+			Command:     append([]string{"gem"}, args...),
+			Files:       []string{gemFile},
+		}); err != nil {
+			return err
+		}
 		if apiKey != "" {
 			args = append(args, "--key", apiKey)
 		}
@@ -397,87 +524,3 @@ func (p *GemPublisher) Publish(ctx context.Context, artifacts []artifact.Artifac
 	log.Info("Gem published successfully")
 	return nil
 }
-
-// HelmPublisher publishes Helm charts
-type HelmPublisher struct {
-	config  config.Helm
-	tmplCtx *tmpl.Context
-}
-
-// NewHelmPublisher creates a new Helm publisher
-func NewHelmPublisher(cfg config.Helm, tmplCtx *tmpl.Context) *HelmPublisher {
-	return &HelmPublisher{
-		config:  cfg,
-		tmplCtx: tmplCtx,
-	}
-}
-
-// Publish publishes Helm chart
-func (p *HelmPublisher) Publish(ctx context.Context, artifacts []artifact.Artifact) error {
-	if p.config.SkipUpload == "true" {
-		log.Info("Skipping Helm publish")
-		return nil
-	}
-
-	helm, err := exec.LookPath("helm")
-	if err != nil {
-		return fmt.Errorf("helm not found in PATH")
-	}
-
-	log.Info("Publishing Helm chart")
-
-	chartPath := p.config.ChartPath
-	if chartPath == "" {
-		chartPath = "chart"
-	}
-
-	// Package the chart
-	version := p.tmplCtx.Get("Version")
-	packageArgs := []string{"package", chartPath}
-	if version != "" {
-		packageArgs = append(packageArgs, "--version", version)
-	}
-	if p.config.AppVersion != "" {
-		packageArgs = append(packageArgs, "--app-version", p.config.AppVersion)
-	}
-
-	packageCmd := exec.CommandContext(ctx, helm, packageArgs...)
-	packageCmd.Stdout = os.Stdout
-	packageCmd.Stderr = os.Stderr
-	if err := packageCmd.Run(); err != nil {
-		return fmt.Errorf("helm package failed: %w", err)
-	}
-
-	// Push to repository if configured
-	if p.config.Repository != "" {
-		// Find the packaged chart
-		charts, _ := filepath.Glob("*.tgz")
-		if len(charts) == 0 {
-			return fmt.Errorf("no packaged charts found")
-		}
-
-		for _, chart := range charts {
-			pushArgs := []string{"push", chart, p.config.Repository}
-
-			pushCmd := exec.CommandContext(ctx, helm, pushArgs...)
-			pushCmd.Stdout = os.Stdout
-			pushCmd.Stderr = os.Stderr
-
-			env := os.Environ()
-			if p.config.Username != "" {
-				env = append(env, "HELM_REPO_USERNAME="+p.config.Username)
-			}
-			if p.config.Password != "" {
-				env = append(env, "HELM_REPO_PASSWORD="+p.config.Password)
-			}
-			pushCmd.Env = env
-
-			if err := pushCmd.Run(); err != nil {
-				return fmt.Errorf("helm push failed for %s: %w", chart, err)
-			}
-		}
-	}
-
-	log.Info("Helm chart published successfully")
-	return nil
-}
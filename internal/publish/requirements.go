@@ -0,0 +1,56 @@
+package publish
+
+import (
+	"sync"
+
+	"github.com/oarkflow/releaser/internal/config"
+)
+
+// Requirement is a credential a publisher reads from the environment. It is
+// met when any of Env is set.
+type Requirement struct {
+	// NeededBy names the config entry, e.g. "chocolatey mytool"
+	NeededBy string
+	Env      []string
+	// Disable is the disable template of the entry; a disabled entry needs
+	// nothing
+	Disable string
+}
+
+// RequirementsFunc returns the requirements of one kind of publisher
+type RequirementsFunc func(cfg *config.Config) []Requirement
+
+var (
+	requirementsMu    sync.Mutex
+	requirementsFuncs []RequirementsFunc
+)
+
+// RegisterRequirements adds the requirements of a publisher to the checks
+// run before a release builds anything. Publishers register next to the
+// code reading the credential, so the checks follow the publishers.
+func RegisterRequirements(fn RequirementsFunc) {
+	requirementsMu.Lock()
+	defer requirementsMu.Unlock()
+	requirementsFuncs = append(requirementsFuncs, fn)
+}
+
+// Requirements returns the requirements of every configured publisher
+func Requirements(cfg *config.Config) []Requirement {
+	requirementsMu.Lock()
+	funcs := append([]RequirementsFunc(nil), requirementsFuncs...)
+	requirementsMu.Unlock()
+
+	var reqs []Requirement
+	for _, fn := range funcs {
+		reqs = append(reqs, fn(cfg)...)
+	}
+	return reqs
+}
+
+// neededBy names a config entry for a requirement
+func neededBy(kind, name string) string {
+	if name == "" {
+		return kind
+	}
+	return kind + " " + name
+}
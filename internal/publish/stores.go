@@ -5,18 +5,21 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"encoding/xml"
 	"fmt"
-	"io"
-	"net/http"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"strings"
+	"time"
 
 	"github.com/charmbracelet/log"
+	"gopkg.in/yaml.v3"
 
 	"github.com/oarkflow/releaser/internal/artifact"
 	"github.com/oarkflow/releaser/internal/config"
+	"github.com/oarkflow/releaser/internal/github"
+	"github.com/oarkflow/releaser/internal/manifest"
 	"github.com/oarkflow/releaser/internal/tmpl"
 )
 
@@ -41,6 +44,21 @@ func (p *SnapcraftPublisher) Publish(ctx context.Context, artifacts []artifact.A
 		return nil
 	}
 
+	// Find the snaps of this config, one per architecture
+	var snaps []artifact.Artifact
+	for _, a := range artifacts {
+		if format, _ := a.Extra[artifact.ExtraFormat].(string); format != "snap" {
+			continue
+		}
+		if id, _ := a.Extra["id"].(string); id == p.config.ID {
+			snaps = append(snaps, a)
+		}
+	}
+	if len(snaps) == 0 {
+		log.Warn("No snaps to publish", "id", p.config.ID)
+		return nil
+	}
+
 	// Check for snapcraft CLI
 	if _, err := exec.LookPath("snapcraft"); err != nil {
 		return fmt.Errorf("snapcraft CLI not found: %w", err)
@@ -48,12 +66,7 @@ func (p *SnapcraftPublisher) Publish(ctx context.Context, artifacts []artifact.A
 
 	log.Info("Publishing to Snap Store")
 
-	// Find snap artifacts
-	for _, a := range artifacts {
-		if !strings.HasSuffix(a.Name, ".snap") {
-			continue
-		}
-
+	for _, a := range snaps {
 		if err := p.publishSnap(ctx, a); err != nil {
 			return fmt.Errorf("failed to publish %s: %w", a.Name, err)
 		}
@@ -63,9 +76,11 @@ func (p *SnapcraftPublisher) Publish(ctx context.Context, artifacts []artifact.A
 	return nil
 }
 
-// publishSnap publishes a single snap package
+// publishSnap uploads a single snap, a revision for its architecture, and
+// releases it to the channels, templated per artifact so they can depend
+// on .Arch
 func (p *SnapcraftPublisher) publishSnap(ctx context.Context, a artifact.Artifact) error {
-	log.Debug("Publishing snap", "name", a.Name)
+	log.Debug("Publishing snap", "name", a.Name, "arch", a.Goarch)
 
 	// Determine channels
 	channels := p.config.ChannelTemplates
@@ -74,16 +89,34 @@ func (p *SnapcraftPublisher) publishSnap(ctx context.Context, a artifact.Artifac
 	}
 
 	// Apply templates to channels
+	artifactCtx := p.tmplCtx.ForArtifact(a)
 	var resolvedChannels []string
-	for _, ch := range channels {
-		resolved, _ := p.tmplCtx.Apply(ch)
-		resolvedChannels = append(resolvedChannels, resolved)
+	for i, ch := range channels {
+		resolved, err := artifactCtx.Apply(fmt.Sprintf("snapcrafts.channel_templates[%d]", i), ch)
+		if err != nil {
+			return fmt.Errorf("failed to apply template to channel %s: %w", ch, err)
+		}
+		if resolved = strings.TrimSpace(resolved); resolved != "" {
+			resolvedChannels = append(resolvedChannels, resolved)
+		}
+	}
+	if len(resolvedChannels) == 0 {
+		log.Info("No channels for snap, skipping", "name", a.Name)
+		return nil
 	}
 
 	// Build snapcraft push command
 	args := []string{"upload", "--release=" + strings.Join(resolvedChannels, ",")}
 	args = append(args, a.Path)
 
+	if err := Confirm(Action{
+		Description: fmt.Sprintf("Upload %s to the Snap Store", a.Name),
+		Command:     append([]string{"snapcraft"}, args...),
+		Files:       []string{a.Path},
+	}); err != nil {
+		return err
+	}
+
 	cmd := exec.CommandContext(ctx, "snapcraft", args...)
 	cmd.Stdout = os.Stdout
 	cmd.Stderr = os.Stderr
@@ -92,7 +125,7 @@ func (p *SnapcraftPublisher) publishSnap(ctx context.Context, a artifact.Artifac
 		return fmt.Errorf("snapcraft upload failed: %w", err)
 	}
 
-	log.Info("Snap published", "name", a.Name, "channels", resolvedChannels)
+	log.Info("Snap published", "name", a.Name, "arch", a.Goarch, "channels", resolvedChannels)
 	return nil
 }
 
@@ -122,7 +155,12 @@ func (p *AURPublisher) Publish(ctx context.Context, artifacts []artifact.Artifac
 	log.Info("Publishing to AUR", "package", p.config.Name)
 
 	// Generate PKGBUILD
-	pkgbuild, err := p.generatePKGBUILD(artifacts)
+	pkg, err := p.buildPackage(artifacts)
+	if err != nil {
+		return fmt.Errorf("failed to generate PKGBUILD: %w", err)
+	}
+
+	pkgbuild, err := p.generatePKGBUILD(pkg)
 	if err != nil {
 		return fmt.Errorf("failed to generate PKGBUILD: %w", err)
 	}
@@ -151,8 +189,17 @@ func (p *AURPublisher) Publish(ctx context.Context, artifacts []artifact.Artifac
 	}
 
 	// Generate .SRCINFO
-	if err := p.generateSRCINFO(ctx, tmpDir); err != nil {
-		log.Warn("Failed to generate .SRCINFO", "error", err)
+	if err := p.generateSRCINFO(ctx, tmpDir, pkg); err != nil {
+		return fmt.Errorf("failed to generate .SRCINFO: %w", err)
+	}
+
+	if err := Confirm(Action{
+		Description: fmt.Sprintf("Push %s %s to the AUR", p.config.Name, p.tmplCtx.Get("Version")),
+		URL:         gitURL,
+		Files:       []string{"PKGBUILD", ".SRCINFO"},
+		Content:     pkgbuild,
+	}); err != nil {
+		return err
 	}
 
 	// Commit and push
@@ -164,80 +211,355 @@ func (p *AURPublisher) Publish(ctx context.Context, artifacts []artifact.Artifac
 	return nil
 }
 
-// generatePKGBUILD generates an Arch Linux PKGBUILD file
-func (p *AURPublisher) generatePKGBUILD(artifacts []artifact.Artifact) (string, error) {
+// aurPackage holds the fields shared by PKGBUILD and .SRCINFO
+type aurPackage struct {
+	Name        string
+	Version     string
+	Release     string
+	Description string
+	URL         string
+	Arch        []string
+	License     []string
+	Depends     []string
+	MakeDepends []string
+	OptDepends  []string
+	Conflicts   []string
+	Provides    []string
+	Replaces    []string
+
+	// Sources are keyed by architecture; the empty key holds
+	// architecture-independent sources
+	Sources map[string][]aurSource
+}
+
+// aurSource is a single PKGBUILD source entry
+type aurSource struct {
+	File   string
+	URL    string
+	SHA256 string
+}
+
+// aurArchitectures maps Go architectures to Arch Linux architectures
+var aurArchitectures = map[string]string{
+	"amd64": "x86_64",
+	"arm64": "aarch64",
+}
+
+// buildPackage collects the package metadata and sources for the configured mode
+func (p *AURPublisher) buildPackage(artifacts []artifact.Artifact) (*aurPackage, error) {
 	name := p.config.Name
 	if name == "" {
 		name = p.tmplCtx.Get("ProjectName")
 	}
 
-	version := strings.TrimPrefix(p.tmplCtx.Get("Version"), "v")
 	desc := p.config.Description
 	if desc == "" {
 		desc = name
 	}
 
-	// Find Linux amd64 archive
-	var sourceURL string
-	for _, a := range artifacts {
-		if a.Goos == "linux" && a.Goarch == "amd64" && a.Type == artifact.TypeArchive {
-			urlTemplate := p.config.URLTemplate
-			if urlTemplate == "" {
-				urlTemplate = "https://github.com/{{ .Env.GITHUB_OWNER }}/{{ .Env.GITHUB_REPO }}/releases/download/{{ .Tag }}/{{ .ArtifactName }}"
+	pkg := &aurPackage{
+		Name:        name,
+		Version:     strings.ReplaceAll(strings.TrimPrefix(p.tmplCtx.Get("Version"), "v"), "-", "_"),
+		Release:     "1",
+		Description: desc,
+		URL:         p.config.Homepage,
+		Depends:     p.config.Depends,
+		MakeDepends: p.config.MakeDepends,
+		OptDepends:  p.config.OptDepends,
+		Conflicts:   p.config.Conflicts,
+		Provides:    p.config.Provides,
+		Replaces:    p.config.Replaces,
+		Sources:     make(map[string][]aurSource),
+	}
+	if p.config.License != "" {
+		pkg.License = []string{p.config.License}
+	}
+
+	switch p.config.Mode {
+	case "", "binary":
+		urlTemplate := p.config.URLTemplate
+		if urlTemplate == "" {
+			urlTemplate = "{{ .ReleaseDownloadURL }}/{{ .ArtifactName }}"
+		}
+
+		for _, goarch := range []string{"amd64", "arm64"} {
+			for _, a := range sortedArtifacts(artifacts) {
+				if a.Goos != "linux" || a.Goarch != goarch || a.Type != artifact.TypeArchive || !matchesIDs(a, p.config.IDs) {
+					continue
+				}
+				if !matchesVariant(a, "", p.config.Goamd64) {
+					continue
+				}
+
+				tmplCtx := p.tmplCtx.ForArtifact(a)
+				url, err := tmplCtx.Apply("aurs.url_template", urlTemplate)
+				if err != nil {
+					return nil, fmt.Errorf("failed to apply url template: %w", err)
+				}
+
+				sum, err := artifactSHA256(a, artifacts)
+				if err != nil {
+					return nil, fmt.Errorf("failed to hash %s: %w", a.Name, err)
+				}
+
+				arch := aurArchitectures[goarch]
+				pkg.Arch = append(pkg.Arch, arch)
+				pkg.Sources[arch] = []aurSource{{
+					File:   fmt.Sprintf("%s-%s-%s%s", name, pkg.Version, arch, archiveExt(a.Name)),
+					URL:    url,
+					SHA256: sum,
+				}}
+				break
+			}
+		}
+
+		if len(pkg.Arch) == 0 {
+			return nil, fmt.Errorf("no Linux amd64 or arm64 archive found for AUR")
+		}
+
+	case "source":
+		// makepkg checks the download against sha256sums, so the source is
+		// the uploaded source archive rather than a tarball the forge
+		// generates, which has other bytes
+		var source *artifact.Artifact
+		for _, a := range sortedArtifacts(artifacts) {
+			if a.Type == artifact.TypeSourceArchive {
+				source = &a
+				break
 			}
-			tmplCtx := p.tmplCtx.WithArtifact(a.Name, a.Goos, a.Goarch, a.Goarm, a.Goamd64)
-			sourceURL, _ = tmplCtx.Apply(urlTemplate)
-			break
 		}
+		if source == nil {
+			return nil, fmt.Errorf("no source archive found for the AUR source package, set source.enabled")
+		}
+
+		urlTemplate := p.config.SourceURLTemplate
+		if urlTemplate == "" {
+			urlTemplate = "{{ .ReleaseDownloadURL }}/{{ .ArtifactName }}"
+		}
+		url, err := p.tmplCtx.ForArtifact(*source).Apply("aurs.source_url_template", urlTemplate)
+		if err != nil {
+			return nil, fmt.Errorf("failed to apply source url template: %w", err)
+		}
+		sum, err := artifactSHA256(*source, artifacts)
+		if err != nil {
+			return nil, fmt.Errorf("failed to hash %s: %w", source.Name, err)
+		}
+
+		pkg.Arch = []string{"x86_64", "aarch64"}
+		pkg.Sources[""] = []aurSource{{
+			File:   fmt.Sprintf("%s-%s%s", name, pkg.Version, archiveExt(source.Name)),
+			URL:    url,
+			SHA256: sum,
+		}}
+
+	default:
+		return nil, fmt.Errorf("unknown AUR mode %q, expected binary or source", p.config.Mode)
 	}
 
-	if sourceURL == "" {
-		return "", fmt.Errorf("no Linux amd64 archive found for AUR")
+	return pkg, nil
+}
+
+// archiveExt returns the archive extension of a file name, including compound
+// extensions such as .tar.gz
+func archiveExt(name string) string {
+	for _, ext := range []string{".tar.gz", ".tar.xz", ".tar.zst", ".tar.bz2"} {
+		if strings.HasSuffix(name, ext) {
+			return ext
+		}
 	}
+	return filepath.Ext(name)
+}
+
+// pkgbuild is an Arch Linux PKGBUILD
+type pkgbuild struct {
+	*aurPackage
+	Maintainers  []string
+	Contributors []string
+	// Functions are rendered after the variables in order, package last
+	Functions []shellFunction
+}
+
+// shellFunction is a PKGBUILD function
+type shellFunction struct {
+	Name string
+	Body string
+}
+
+// Generate renders the PKGBUILD, single quoting every value so that the
+// shell doesn't expand anything in it
+func (p pkgbuild) Generate() ([]byte, error) {
+	// Comments end at the line break
+	comment := strings.NewReplacer("\r", " ", "\n", " ")
 
 	var buf strings.Builder
-	buf.WriteString("# Maintainer: ")
-	for i, m := range p.config.Maintainers {
-		if i > 0 {
-			buf.WriteString(", ")
+	for _, m := range p.Maintainers {
+		buf.WriteString(fmt.Sprintf("# Maintainer: %s\n", comment.Replace(m)))
+	}
+	for _, c := range p.Contributors {
+		buf.WriteString(fmt.Sprintf("# Contributor: %s\n", comment.Replace(c)))
+	}
+	if len(p.Maintainers)+len(p.Contributors) > 0 {
+		buf.WriteString("\n")
+	}
+
+	buf.WriteString(fmt.Sprintf("pkgname=%s\n", manifest.ShellQuote(p.Name)))
+	buf.WriteString(fmt.Sprintf("pkgver=%s\n", manifest.ShellQuote(p.Version)))
+	buf.WriteString(fmt.Sprintf("pkgrel=%s\n", manifest.ShellQuote(p.Release)))
+	buf.WriteString(fmt.Sprintf("pkgdesc=%s\n", manifest.ShellQuote(p.Description)))
+	buf.WriteString(fmt.Sprintf("arch=(%s)\n", manifest.ShellQuoteAll(p.Arch)))
+	buf.WriteString(fmt.Sprintf("url=%s\n", manifest.ShellQuote(p.URL)))
+	if len(p.License) > 0 {
+		buf.WriteString(fmt.Sprintf("license=(%s)\n", manifest.ShellQuoteAll(p.License)))
+	}
+
+	for _, field := range []struct {
+		key    string
+		values []string
+	}{
+		{"depends", p.Depends},
+		{"makedepends", p.MakeDepends},
+		{"optdepends", p.OptDepends},
+		{"conflicts", p.Conflicts},
+		{"provides", p.Provides},
+		{"replaces", p.Replaces},
+	} {
+		if len(field.values) > 0 {
+			buf.WriteString(fmt.Sprintf("%s=(%s)\n", field.key, manifest.ShellQuoteAll(field.values)))
 		}
-		buf.WriteString(m)
 	}
-	buf.WriteString("\n")
 
-	buf.WriteString(fmt.Sprintf("pkgname=%s\n", name))
-	buf.WriteString(fmt.Sprintf("pkgver=%s\n", version))
-	buf.WriteString("pkgrel=1\n")
-	buf.WriteString(fmt.Sprintf("pkgdesc=\"%s\"\n", desc))
-	buf.WriteString("arch=('x86_64')\n")
-	buf.WriteString(fmt.Sprintf("url=\"%s\"\n", p.config.Homepage))
-	buf.WriteString(fmt.Sprintf("license=('%s')\n", p.config.License))
+	for _, arch := range append([]string{""}, p.Arch...) {
+		sources, ok := p.Sources[arch]
+		if !ok {
+			continue
+		}
+		suffix := ""
+		if arch != "" {
+			suffix = "_" + arch
+		}
 
-	if len(p.config.Depends) > 0 {
-		buf.WriteString(fmt.Sprintf("depends=('%s')\n", strings.Join(p.config.Depends, "' '")))
-	}
-	if len(p.config.OptDepends) > 0 {
-		buf.WriteString(fmt.Sprintf("optdepends=('%s')\n", strings.Join(p.config.OptDepends, "' '")))
-	}
-	if len(p.config.Conflicts) > 0 {
-		buf.WriteString(fmt.Sprintf("conflicts=('%s')\n", strings.Join(p.config.Conflicts, "' '")))
+		var entries, sums []string
+		for _, src := range sources {
+			entries = append(entries, fmt.Sprintf("%s::%s", src.File, src.URL))
+			sums = append(sums, src.SHA256)
+		}
+		buf.WriteString(fmt.Sprintf("source%s=(%s)\n", suffix, manifest.ShellQuoteAll(entries)))
+		buf.WriteString(fmt.Sprintf("sha256sums%s=(%s)\n", suffix, manifest.ShellQuoteAll(sums)))
 	}
-	if len(p.config.Provides) > 0 {
-		buf.WriteString(fmt.Sprintf("provides=('%s')\n", strings.Join(p.config.Provides, "' '")))
+
+	// Function bodies are shell code and rendered as they are
+	for _, fn := range p.Functions {
+		buf.WriteString(fmt.Sprintf("\n%s() {\n%s}\n", fn.Name, indentShell(fn.Body)))
 	}
 
-	buf.WriteString(fmt.Sprintf("source=(\"%s\")\n", sourceURL))
-	buf.WriteString("sha256sums=('SKIP')\n")
-	buf.WriteString("\n")
+	return []byte(buf.String()), nil
+}
+
+// generatePKGBUILD generates an Arch Linux PKGBUILD file
+func (p *AURPublisher) generatePKGBUILD(pkg *aurPackage) (string, error) {
+	doc := pkgbuild{
+		aurPackage:   pkg,
+		Maintainers:  p.config.Maintainers,
+		Contributors: p.config.Contributors,
+	}
+
+	// Build functions are only rendered for source packages
+	if p.config.Mode == "source" {
+		for _, fn := range []shellFunction{
+			{"prepare", p.config.Prepare},
+			{"build", p.config.Build},
+			{"check", p.config.Check},
+		} {
+			if fn.Body == "" {
+				continue
+			}
+			body, err := p.tmplCtx.Apply("aurs."+fn.Name, fn.Body)
+			if err != nil {
+				return "", fmt.Errorf("failed to apply %s template: %w", fn.Name, err)
+			}
+			doc.Functions = append(doc.Functions, shellFunction{fn.Name, body})
+		}
+	}
 
 	// Package function
 	packageFunc := p.config.Package
 	if packageFunc == "" {
-		packageFunc = fmt.Sprintf(`install -Dm755 %s "$pkgdir/usr/bin/%s"`, name, name)
+		packageFunc = fmt.Sprintf(`install -Dm755 "./%s" "$pkgdir/usr/bin/%s"`, p.tmplCtx.Get("ProjectName"), p.tmplCtx.Get("ProjectName"))
+	}
+	packageFunc, err := p.tmplCtx.Apply("aurs.package", packageFunc)
+	if err != nil {
+		return "", fmt.Errorf("failed to apply package template: %w", err)
+	}
+	doc.Functions = append(doc.Functions, shellFunction{"package", packageFunc})
+
+	data, err := doc.Generate()
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// indentShell indents every line of a shell snippet for a PKGBUILD function body
+func indentShell(body string) string {
+	var buf strings.Builder
+	for _, line := range strings.Split(strings.TrimRight(body, "\n"), "\n") {
+		if strings.TrimSpace(line) == "" {
+			buf.WriteString("\n")
+			continue
+		}
+		buf.WriteString("    " + line + "\n")
+	}
+	return buf.String()
+}
+
+// srcinfo is the .SRCINFO of an AUR package
+type srcinfo struct {
+	*aurPackage
+}
+
+// Generate renders .SRCINFO the way makepkg --printsrcinfo would
+func (pkg srcinfo) Generate() ([]byte, error) {
+	var buf strings.Builder
+	field := func(key, value string) {
+		buf.WriteString(fmt.Sprintf("\t%s = %s\n", key, value))
+	}
+	fields := func(key string, values []string) {
+		for _, v := range values {
+			field(key, v)
+		}
+	}
+
+	buf.WriteString(fmt.Sprintf("pkgbase = %s\n", pkg.Name))
+	field("pkgdesc", pkg.Description)
+	field("pkgver", pkg.Version)
+	field("pkgrel", pkg.Release)
+	if pkg.URL != "" {
+		field("url", pkg.URL)
+	}
+	fields("arch", pkg.Arch)
+	fields("license", pkg.License)
+	fields("makedepends", pkg.MakeDepends)
+	fields("depends", pkg.Depends)
+	fields("optdepends", pkg.OptDepends)
+	fields("provides", pkg.Provides)
+	fields("conflicts", pkg.Conflicts)
+	fields("replaces", pkg.Replaces)
+
+	for _, arch := range append([]string{""}, pkg.Arch...) {
+		suffix := ""
+		if arch != "" {
+			suffix = "_" + arch
+		}
+		for _, src := range pkg.Sources[arch] {
+			field("source"+suffix, fmt.Sprintf("%s::%s", src.File, src.URL))
+		}
+		for _, src := range pkg.Sources[arch] {
+			field("sha256sums"+suffix, src.SHA256)
+		}
 	}
-	buf.WriteString(fmt.Sprintf("package() {\n    %s\n}\n", packageFunc))
 
-	return buf.String(), nil
+	buf.WriteString(fmt.Sprintf("\npkgname = %s\n", pkg.Name))
+	return []byte(buf.String()), nil
 }
 
 // cloneAUR clones the AUR repository
@@ -275,18 +597,23 @@ func (p *AURPublisher) cloneAUR(ctx context.Context, dir, gitURL string) error {
 	return nil
 }
 
-// generateSRCINFO generates .SRCINFO from PKGBUILD
-func (p *AURPublisher) generateSRCINFO(ctx context.Context, dir string) error {
-	cmd := exec.CommandContext(ctx, "makepkg", "--printsrcinfo")
-	cmd.Dir = dir
+// generateSRCINFO generates .SRCINFO from PKGBUILD, using makepkg when it is
+// installed and rendering it directly otherwise
+func (p *AURPublisher) generateSRCINFO(ctx context.Context, dir string, pkg *aurPackage) error {
+	srcInfoPath := filepath.Join(dir, ".SRCINFO")
 
-	output, err := cmd.Output()
-	if err != nil {
-		return err
+	if _, err := exec.LookPath("makepkg"); err == nil {
+		cmd := exec.CommandContext(ctx, "makepkg", "--printsrcinfo")
+		cmd.Dir = dir
+
+		output, err := cmd.Output()
+		if err == nil {
+			return os.WriteFile(srcInfoPath, output, 0644)
+		}
+		log.Warn("makepkg --printsrcinfo failed, generating .SRCINFO directly", "error", err)
 	}
 
-	srcInfoPath := filepath.Join(dir, ".SRCINFO")
-	return os.WriteFile(srcInfoPath, output, 0644)
+	return manifest.WriteFile(srcInfoPath, srcinfo{pkg})
 }
 
 // commitAndPush commits and pushes to AUR
@@ -317,7 +644,7 @@ func (p *AURPublisher) commitAndPush(ctx context.Context, dir string) error {
 	if commitMsg == "" {
 		commitMsg = fmt.Sprintf("Update to %s", p.tmplCtx.Get("Version"))
 	}
-	commitMsg, _ = p.tmplCtx.Apply(commitMsg)
+	commitMsg, _ = p.tmplCtx.Apply("aurs.commit_msg_template", commitMsg)
 
 	commitCmd := exec.CommandContext(ctx, "git", "commit", "-m", commitMsg)
 	commitCmd.Dir = dir
@@ -347,6 +674,18 @@ type ChocolateyPublisher struct {
 	manager *artifact.Manager
 }
 
+func init() {
+	RegisterRequirements(func(cfg *config.Config) []Requirement {
+		var reqs []Requirement
+		for _, choco := range cfg.Chocolateys {
+			if choco.APIKey == "" && choco.SkipPublish != "true" {
+				reqs = append(reqs, Requirement{NeededBy: neededBy("chocolatey", choco.Name), Env: []string{"CHOCOLATEY_API_KEY"}, Disable: choco.Disable})
+			}
+		}
+		return reqs
+	})
+}
+
 // NewChocolateyPublisher creates a new Chocolatey publisher
 func NewChocolateyPublisher(cfg config.Chocolatey, tmplCtx *tmpl.Context, manager *artifact.Manager) *ChocolateyPublisher {
 	return &ChocolateyPublisher{
@@ -405,6 +744,52 @@ func (p *ChocolateyPublisher) Publish(ctx context.Context, artifacts []artifact.
 	return nil
 }
 
+// nuspec is a NuGet package specification
+type nuspec struct {
+	XMLName  xml.Name       `xml:"package"`
+	Xmlns    string         `xml:"xmlns,attr"`
+	Metadata nuspecMetadata `xml:"metadata"`
+	Files    []nuspecFile   `xml:"files>file"`
+}
+
+// nuspecMetadata is the metadata of a nuspec; optional elements are left
+// out when they are empty
+type nuspecMetadata struct {
+	ID                       string `xml:"id"`
+	Version                  string `xml:"version"`
+	Title                    string `xml:"title,omitempty"`
+	Authors                  string `xml:"authors"`
+	ProjectURL               string `xml:"projectUrl,omitempty"`
+	IconURL                  string `xml:"iconUrl,omitempty"`
+	Copyright                string `xml:"copyright,omitempty"`
+	LicenseURL               string `xml:"licenseUrl,omitempty"`
+	RequireLicenseAcceptance bool   `xml:"requireLicenseAcceptance"`
+	ProjectSourceURL         string `xml:"projectSourceUrl,omitempty"`
+	DocsURL                  string `xml:"docsUrl,omitempty"`
+	Tags                     string `xml:"tags,omitempty"`
+	Summary                  string `xml:"summary,omitempty"`
+	Description              string `xml:"description"`
+	BugTrackerURL            string `xml:"bugTrackerUrl,omitempty"`
+}
+
+// nuspecFile is a file included in a NuGet package
+type nuspecFile struct {
+	Src    string `xml:"src,attr"`
+	Target string `xml:"target,attr"`
+}
+
+// Generate renders the nuspec
+func (n nuspec) Generate() ([]byte, error) {
+	if n.Xmlns == "" {
+		n.Xmlns = "http://schemas.microsoft.com/packaging/2015/06/nuspec.xsd"
+	}
+	data, err := xml.MarshalIndent(n, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal nuspec: %w", err)
+	}
+	return append([]byte(`<?xml version="1.0" encoding="utf-8"?>`+"\n"), append(data, '\n')...), nil
+}
+
 // generateNuspec generates a Chocolatey nuspec file
 func (p *ChocolateyPublisher) generateNuspec(dir string, artifacts []artifact.Artifact) error {
 	name := p.config.Name
@@ -412,49 +797,53 @@ func (p *ChocolateyPublisher) generateNuspec(dir string, artifacts []artifact.Ar
 		name = p.tmplCtx.Get("ProjectName")
 	}
 
-	version := strings.TrimPrefix(p.tmplCtx.Get("Version"), "v")
+	doc := nuspec{
+		Metadata: nuspecMetadata{
+			ID:                       name,
+			Version:                  strings.TrimPrefix(p.tmplCtx.Get("Version"), "v"),
+			Title:                    p.config.Title,
+			Authors:                  p.config.Authors,
+			ProjectURL:               p.config.ProjectURL,
+			IconURL:                  p.config.IconURL,
+			Copyright:                p.config.Copyright,
+			LicenseURL:               p.config.LicenseURL,
+			RequireLicenseAcceptance: p.config.RequireLicenseAcceptance,
+			ProjectSourceURL:         p.config.ProjectSourceURL,
+			DocsURL:                  p.config.DocsURL,
+			Tags:                     p.config.Tags,
+			Summary:                  p.config.Summary,
+			Description:              p.config.Description,
+			BugTrackerURL:            p.config.BugTrackerURL,
+		},
+		Files: []nuspecFile{{Src: `tools\**`, Target: "tools"}},
+	}
+
+	return manifest.WriteFile(filepath.Join(dir, name+".nuspec"), doc)
+}
 
-	nuspec := fmt.Sprintf(`<?xml version="1.0" encoding="utf-8"?>
-<package xmlns="http://schemas.microsoft.com/packaging/2015/06/nuspec.xsd">
-  <metadata>
-    <id>%s</id>
-    <version>%s</version>
-    <title>%s</title>
-    <authors>%s</authors>
-    <projectUrl>%s</projectUrl>
-    <iconUrl>%s</iconUrl>
-    <copyright>%s</copyright>
-    <licenseUrl>%s</licenseUrl>
-    <requireLicenseAcceptance>%t</requireLicenseAcceptance>
-    <projectSourceUrl>%s</projectSourceUrl>
-    <docsUrl>%s</docsUrl>
-    <tags>%s</tags>
-    <summary>%s</summary>
-    <description>%s</description>
-    <bugTrackerUrl>%s</bugTrackerUrl>
-  </metadata>
-  <files>
-    <file src="tools\**" target="tools" />
-  </files>
-</package>`,
-		name,
-		version,
-		p.config.Title,
-		p.config.Authors,
-		p.config.ProjectURL,
-		p.config.IconURL,
-		p.config.Copyright,
-		p.config.LicenseURL,
-		p.config.RequireLicenseAcceptance,
-		p.config.ProjectSourceURL,
-		p.config.DocsURL,
-		p.config.Tags,
-		p.config.Summary,
-		p.config.Description,
-		p.config.BugTrackerURL,
-	)
-
-	return os.WriteFile(filepath.Join(dir, name+".nuspec"), []byte(nuspec), 0644)
+// chocolateyInstall is the chocolateyinstall.ps1 of a Chocolatey package
+type chocolateyInstall struct {
+	URL    string
+	SHA256 string
+}
+
+// Generate renders the install script with its values in single quoted
+// PowerShell strings
+func (c chocolateyInstall) Generate() ([]byte, error) {
+	return []byte(fmt.Sprintf(`$ErrorActionPreference = 'Stop'
+$toolsDir = "$(Split-Path -parent $MyInvocation.MyCommand.Definition)"
+$url = %s
+
+$packageArgs = @{
+    packageName    = $env:ChocolateyPackageName
+    unzipLocation  = $toolsDir
+    url64bit       = $url
+    checksum64     = %s
+    checksumType64 = 'sha256'
+}
+
+Install-ChocolateyZipPackage @packageArgs
+`, manifest.PowerShellString(c.URL), manifest.PowerShellString(c.SHA256))), nil
 }
 
 // generateInstallScript generates chocolateyinstall.ps1
@@ -466,15 +855,22 @@ func (p *ChocolateyPublisher) generateInstallScript(dir string, artifacts []arti
 	}
 
 	// Find Windows archive
-	var downloadURL string
-	for _, a := range artifacts {
-		if a.Goos == "windows" && a.Goarch == "amd64" && a.Type == artifact.TypeArchive {
+	var downloadURL, downloadSHA256 string
+	for _, a := range sortedArtifacts(artifacts) {
+		if a.Goos == "windows" && a.Goarch == "amd64" && a.Type == artifact.TypeArchive &&
+			matchesIDs(a, p.config.IDs) && matchesVariant(a, p.config.Goarm, p.config.Goamd64) {
 			urlTemplate := p.config.URLTemplate
 			if urlTemplate == "" {
-				urlTemplate = "https://github.com/{{ .Env.GITHUB_OWNER }}/{{ .Env.GITHUB_REPO }}/releases/download/{{ .Tag }}/{{ .ArtifactName }}"
+				urlTemplate = "{{ .ReleaseDownloadURL }}/{{ .ArtifactName }}"
+			}
+			tmplCtx := p.tmplCtx.ForArtifact(a)
+			downloadURL, _ = tmplCtx.Apply("chocolateys.url_template", urlTemplate)
+
+			sum, err := artifactSHA256(a, artifacts)
+			if err != nil {
+				return fmt.Errorf("failed to hash %s: %w", a.Name, err)
 			}
-			tmplCtx := p.tmplCtx.WithArtifact(a.Name, a.Goos, a.Goarch, a.Goarm, a.Goamd64)
-			downloadURL, _ = tmplCtx.Apply(urlTemplate)
+			downloadSHA256 = sum
 			break
 		}
 	}
@@ -483,20 +879,7 @@ func (p *ChocolateyPublisher) generateInstallScript(dir string, artifacts []arti
 		return fmt.Errorf("no Windows amd64 archive found for Chocolatey")
 	}
 
-	installScript := fmt.Sprintf(`$ErrorActionPreference = 'Stop'
-$toolsDir = "$(Split-Path -parent $MyInvocation.MyCommand.Definition)"
-$url = '%s'
-
-$packageArgs = @{
-    packageName   = $env:ChocolateyPackageName
-    unzipLocation = $toolsDir
-    url           = $url
-}
-
-Install-ChocolateyZipPackage @packageArgs
-`, downloadURL)
-
-	return os.WriteFile(filepath.Join(toolsDir, "chocolateyinstall.ps1"), []byte(installScript), 0644)
+	return manifest.WriteFile(filepath.Join(toolsDir, "chocolateyinstall.ps1"), chocolateyInstall{downloadURL, downloadSHA256})
 }
 
 // packNuget creates a nupkg file
@@ -536,6 +919,21 @@ func (p *ChocolateyPublisher) pushNuget(ctx context.Context, nupkgPath, apiKey s
 		source = "https://push.chocolatey.org/"
 	}
 
+	name := p.config.Name
+	if name == "" {
+		name = p.tmplCtx.Get("ProjectName")
+	}
+	nuspec, _ := os.ReadFile(filepath.Join(filepath.Dir(nupkgPath), name+".nuspec"))
+	if err := Confirm(Action{
+		Description: fmt.Sprintf("Push %s to Chocolatey", filepath.Base(nupkgPath)),
+		URL:         source,
+		Command:     []string{"choco", "push", filepath.Base(nupkgPath), "--source", source},
+		Files:       []string{nupkgPath},
+		Content:     string(nuspec),
+	}); err != nil {
+		return err
+	}
+
 	cmd := exec.CommandContext(ctx, "choco", "push", nupkgPath,
 		"--source", source,
 		"--api-key", apiKey)
@@ -552,6 +950,18 @@ type WingetPublisher struct {
 	manager *artifact.Manager
 }
 
+func init() {
+	RegisterRequirements(func(cfg *config.Config) []Requirement {
+		var reqs []Requirement
+		for _, winget := range cfg.Wingets {
+			if winget.SkipUpload != "true" {
+				reqs = append(reqs, Requirement{NeededBy: neededBy("winget", winget.Name), Env: []string{"GITHUB_TOKEN"}, Disable: winget.Disable})
+			}
+		}
+		return reqs
+	})
+}
+
 // NewWingetPublisher creates a new Winget publisher
 func NewWingetPublisher(cfg config.Winget, tmplCtx *tmpl.Context, manager *artifact.Manager) *WingetPublisher {
 	return &WingetPublisher{
@@ -561,13 +971,17 @@ func NewWingetPublisher(cfg config.Winget, tmplCtx *tmpl.Context, manager *artif
 	}
 }
 
-// Publish publishes to winget-pkgs repository
+// Publish opens a pull request against the winget-pkgs repository
 func (p *WingetPublisher) Publish(ctx context.Context, artifacts []artifact.Artifact) error {
 	if p.config.SkipUpload == "true" {
 		log.Info("Winget upload disabled, skipping")
 		return nil
 	}
 
+	if p.config.PackageIdentifier == "" {
+		return fmt.Errorf("winget package_identifier is required")
+	}
+
 	token := os.Getenv("GITHUB_TOKEN")
 	if token == "" {
 		return fmt.Errorf("GITHUB_TOKEN is required for Winget publish")
@@ -575,13 +989,13 @@ func (p *WingetPublisher) Publish(ctx context.Context, artifacts []artifact.Arti
 
 	log.Info("Publishing to Winget", "package", p.config.PackageIdentifier)
 
-	// Generate manifest
-	manifest, err := p.generateManifest(artifacts)
+	// Generate manifests
+	manifests, err := p.generateManifests(artifacts)
 	if err != nil {
 		return fmt.Errorf("failed to generate manifest: %w", err)
 	}
 
-	// Push to repository
+	// Determine upstream repository
 	repo := p.config.Repository
 	if repo.Owner == "" {
 		repo.Owner = "microsoft"
@@ -589,126 +1003,317 @@ func (p *WingetPublisher) Publish(ctx context.Context, artifacts []artifact.Arti
 	}
 
 	version := strings.TrimPrefix(p.tmplCtx.Get("Version"), "v")
-	manifestPath := fmt.Sprintf("manifests/%s/%s/%s/%s.yaml",
-		strings.ToLower(string(p.config.PackageIdentifier[0])),
-		strings.ReplaceAll(p.config.PackageIdentifier, ".", "/"),
-		version,
-		p.config.PackageIdentifier,
-	)
-
-	if err := p.pushManifest(ctx, token, repo, manifestPath, manifest); err != nil {
-		return fmt.Errorf("failed to push manifest: %w", err)
+
+	commitMsg := fmt.Sprintf("New version: %s version %s", p.config.PackageIdentifier, version)
+	if p.config.CommitMsgTemplate != "" {
+		commitMsg, _ = p.tmplCtx.Apply("wingets.commit_msg_template", p.config.CommitMsgTemplate)
+	}
+
+	pr, err := pullRequestFromConfig(p.tmplCtx, repo, commitMsg)
+	if err != nil {
+		return err
+	}
+	if pr.Branch == "" {
+		pr.Branch = sanitizeBranchName(fmt.Sprintf("%s-%s", p.config.PackageIdentifier, version))
 	}
+	if pr.Body == "" {
+		pr.Body = fmt.Sprintf("Automated release of %s %s.", p.config.PackageIdentifier, version)
+	}
+	pr.Fork = true
+	pr.ForkOwner = p.config.Fork
+	pr.Files = manifests
+	pr.Author = p.config.CommitAuthor
 
-	log.Info("Published to Winget", "package", p.config.PackageIdentifier)
+	// Winget always goes through a pull request
+	prRepo := repo
+	prRepo.PullRequest.Enabled = true
+	if err := confirmCommit(prRepo, manifests, commitMsg); err != nil {
+		return err
+	}
+
+	// With repository.git the fork is pushed over git, e.g. with a deploy
+	// key, and only the pull request goes through the API
+	var url string
+	if repo.Git.URL != "" {
+		url, err = pushPullRequest(ctx, p.tmplCtx, repo, pr)
+	} else {
+		url, err = openGitHubPullRequest(ctx, github.New(token), pr)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to open pull request: %w", err)
+	}
+
+	log.Info("Published to Winget", "package", p.config.PackageIdentifier, "pr", url)
 	return nil
 }
 
-// generateManifest generates a Winget manifest
-func (p *WingetPublisher) generateManifest(artifacts []artifact.Artifact) (string, error) {
+// wingetManifestVersion is the winget manifest schema version generated
+const wingetManifestVersion = "1.6.0"
+
+// wingetVersionManifest is the version manifest of the multi-file layout
+type wingetVersionManifest struct {
+	PackageIdentifier string `yaml:"PackageIdentifier"`
+	PackageVersion    string `yaml:"PackageVersion"`
+	DefaultLocale     string `yaml:"DefaultLocale"`
+	ManifestType      string `yaml:"ManifestType"`
+	ManifestVersion   string `yaml:"ManifestVersion"`
+}
+
+// wingetInstallerManifest is the installer manifest of the multi-file layout
+type wingetInstallerManifest struct {
+	PackageIdentifier string                `yaml:"PackageIdentifier"`
+	PackageVersion    string                `yaml:"PackageVersion"`
+	InstallerType     string                `yaml:"InstallerType,omitempty"`
+	ReleaseDate       string                `yaml:"ReleaseDate,omitempty"`
+	Installers        []wingetInstallerItem `yaml:"Installers"`
+	ManifestType      string                `yaml:"ManifestType"`
+	ManifestVersion   string                `yaml:"ManifestVersion"`
+}
+
+// wingetNestedFile is a portable executable inside a zip installer
+type wingetNestedFile struct {
+	RelativeFilePath     string `yaml:"RelativeFilePath"`
+	PortableCommandAlias string `yaml:"PortableCommandAlias,omitempty"`
+}
+
+// wingetInstallerItem is a single per-architecture installer; zip
+// installers name the portable executable they contain
+type wingetInstallerItem struct {
+	Architecture         string             `yaml:"Architecture"`
+	InstallerType        string             `yaml:"InstallerType,omitempty"`
+	NestedInstallerType  string             `yaml:"NestedInstallerType,omitempty"`
+	NestedInstallerFiles []wingetNestedFile `yaml:"NestedInstallerFiles,omitempty"`
+	InstallerUrl         string             `yaml:"InstallerUrl"`
+	InstallerSha256      string             `yaml:"InstallerSha256"`
+}
+
+// wingetLocaleManifest is the default locale manifest of the multi-file layout
+type wingetLocaleManifest struct {
+	PackageIdentifier   string   `yaml:"PackageIdentifier"`
+	PackageVersion      string   `yaml:"PackageVersion"`
+	PackageLocale       string   `yaml:"PackageLocale"`
+	Publisher           string   `yaml:"Publisher"`
+	PublisherUrl        string   `yaml:"PublisherUrl,omitempty"`
+	PublisherSupportUrl string   `yaml:"PublisherSupportUrl,omitempty"`
+	Author              string   `yaml:"Author,omitempty"`
+	PackageName         string   `yaml:"PackageName"`
+	PackageUrl          string   `yaml:"PackageUrl,omitempty"`
+	License             string   `yaml:"License"`
+	LicenseUrl          string   `yaml:"LicenseUrl,omitempty"`
+	Copyright           string   `yaml:"Copyright,omitempty"`
+	CopyrightUrl        string   `yaml:"CopyrightUrl,omitempty"`
+	ShortDescription    string   `yaml:"ShortDescription"`
+	Description         string   `yaml:"Description,omitempty"`
+	Moniker             string   `yaml:"Moniker,omitempty"`
+	Tags                []string `yaml:"Tags,omitempty"`
+	ReleaseNotes        string   `yaml:"ReleaseNotes,omitempty"`
+	ReleaseNotesUrl     string   `yaml:"ReleaseNotesUrl,omitempty"`
+	ManifestType        string   `yaml:"ManifestType"`
+	ManifestVersion     string   `yaml:"ManifestVersion"`
+}
+
+// generateManifests generates the version, installer and default locale
+// manifests keyed by their path in the winget-pkgs repository
+func (p *WingetPublisher) generateManifests(artifacts []artifact.Artifact) (map[string]string, error) {
+	id := p.config.PackageIdentifier
 	version := strings.TrimPrefix(p.tmplCtx.Get("Version"), "v")
 
-	// Find Windows installer
-	var installerURL string
-	for _, a := range artifacts {
-		if a.Goos == "windows" && a.Goarch == "amd64" {
-			urlTemplate := p.config.URLTemplate
-			if urlTemplate == "" {
-				urlTemplate = "https://github.com/{{ .Env.GITHUB_OWNER }}/{{ .Env.GITHUB_REPO }}/releases/download/{{ .Tag }}/{{ .ArtifactName }}"
+	name := p.config.Name
+	if name == "" {
+		name = p.tmplCtx.Get("ProjectName")
+	}
+
+	binary := p.config.Binary
+	if binary == "" {
+		binary = p.tmplCtx.Get("ProjectName")
+	}
+
+	urlTemplate := p.config.URLTemplate
+	if urlTemplate == "" {
+		urlTemplate = "{{ .ReleaseDownloadURL }}/{{ .ArtifactName }}"
+	}
+
+	// Collect one installer per architecture: MSI and NSIS installers
+	// take precedence over zip archives
+	wingetArch := map[string]string{"amd64": "x64", "386": "x86", "arm64": "arm64"}
+	installerTypes := map[artifact.Type]string{
+		artifact.TypeArchive: "zip",
+		artifact.TypeNSIS:    "nullsoft",
+		artifact.TypeMSI:     "wix",
+	}
+
+	selected := make(map[string]artifact.Artifact)
+	for _, want := range []artifact.Type{artifact.TypeArchive, artifact.TypeNSIS, artifact.TypeMSI} {
+		for _, a := range sortedArtifacts(artifacts) {
+			if a.Goos != "windows" || a.Type != want || !matchesIDs(a, p.config.IDs) {
+				continue
 			}
-			tmplCtx := p.tmplCtx.WithArtifact(a.Name, a.Goos, a.Goarch, a.Goarm, a.Goamd64)
-			installerURL, _ = tmplCtx.Apply(urlTemplate)
-			break
+			if _, ok := wingetArch[a.Goarch]; !ok {
+				continue
+			}
+			if want == artifact.TypeArchive && !strings.HasSuffix(a.Name, ".zip") {
+				continue
+			}
+			if !matchesVariant(a, p.config.Goarm, p.config.Goamd64) {
+				continue
+			}
+			// The first of a type wins; a later type replaces it
+			if prev, ok := selected[a.Goarch]; ok && prev.Type == want {
+				continue
+			}
+			selected[a.Goarch] = a
 		}
 	}
 
-	if installerURL == "" {
-		return "", fmt.Errorf("no Windows artifact found for Winget")
-	}
-
-	manifest := fmt.Sprintf(`PackageIdentifier: %s
-PackageVersion: %s
-PackageLocale: en-US
-Publisher: %s
-PublisherUrl: %s
-PublisherSupportUrl: %s
-Author: %s
-PackageName: %s
-PackageUrl: %s
-License: %s
-LicenseUrl: %s
-Copyright: %s
-CopyrightUrl: %s
-ShortDescription: %s
-Description: %s
-Tags:
-%s
-Installers:
-  - Architecture: x64
-    InstallerUrl: %s
-    InstallerSha256:
-    InstallerType: zip
-ManifestType: singleton
-ManifestVersion: 1.0.0
-`,
-		p.config.PackageIdentifier,
-		version,
-		p.config.Publisher,
-		p.config.PublisherURL,
-		p.config.PublisherSupportURL,
-		p.config.Publisher,
-		p.config.Name,
-		p.config.Homepage,
-		p.config.License,
-		p.config.LicenseURL,
-		p.config.Copyright,
-		p.config.CopyrightURL,
-		p.config.ShortDescription,
-		p.config.Description,
-		formatTags(p.config.Tags),
-		installerURL,
-	)
-
-	return manifest, nil
-}
-
-// formatTags formats tags for YAML
-func formatTags(tags []string) string {
-	var buf strings.Builder
-	for _, tag := range tags {
-		buf.WriteString(fmt.Sprintf("  - %s\n", tag))
+	if len(selected) == 0 {
+		return nil, fmt.Errorf("no Windows zip archive or installer found for Winget")
 	}
-	return buf.String()
-}
-
-// pushManifest pushes the manifest to the repository
-func (p *WingetPublisher) pushManifest(ctx context.Context, token string, repo config.RepoRef, path, content string) error {
-	url := fmt.Sprintf("https://api.github.com/repos/%s/%s/contents/%s", repo.Owner, repo.Name, path)
 
-	body := map[string]interface{}{
-		"message": fmt.Sprintf("Add %s version %s", p.config.PackageIdentifier, p.tmplCtx.Get("Version")),
-		"content": encodeBase64(content),
-		"branch":  "main",
+	installer := wingetInstallerManifest{
+		PackageIdentifier: id,
+		PackageVersion:    version,
+		ReleaseDate:       time.Now().UTC().Format("2006-01-02"),
+		ManifestType:      "installer",
+		ManifestVersion:   wingetManifestVersion,
 	}
 
-	bodyJSON, _ := json.Marshal(body)
-	req, _ := http.NewRequestWithContext(ctx, "PUT", url, bytes.NewReader(bodyJSON))
-	req.Header.Set("Authorization", "token "+token)
-	req.Header.Set("Accept", "application/vnd.github.v3+json")
-	req.Header.Set("Content-Type", "application/json")
+	for _, goarch := range []string{"amd64", "arm64", "386"} {
+		a, ok := selected[goarch]
+		if !ok {
+			continue
+		}
+
+		tmplCtx := p.tmplCtx.ForArtifact(a)
+		url, err := tmplCtx.Apply("wingets.url_template", urlTemplate)
+		if err != nil {
+			return nil, fmt.Errorf("failed to apply url template: %w", err)
+		}
 
-	resp, err := http.DefaultClient.Do(req)
-	if err != nil {
-		return err
+		sum, err := artifactSHA256(a, artifacts)
+		if err != nil {
+			return nil, fmt.Errorf("failed to hash %s: %w", a.Name, err)
+		}
+
+		item := wingetInstallerItem{
+			Architecture:    wingetArch[goarch],
+			InstallerType:   installerTypes[a.Type],
+			InstallerUrl:    url,
+			InstallerSha256: strings.ToUpper(sum),
+		}
+		if a.Type == artifact.TypeArchive {
+			item.NestedInstallerType = "portable"
+			item.NestedInstallerFiles = []wingetNestedFile{{
+				RelativeFilePath:     binary + ".exe",
+				PortableCommandAlias: binary,
+			}}
+		}
+		installer.Installers = append(installer.Installers, item)
+	}
+
+	publisher := p.config.Publisher
+	if publisher == "" {
+		publisher = strings.SplitN(id, ".", 2)[0]
+	}
+
+	shortDescription := p.config.ShortDescription
+	if shortDescription == "" {
+		shortDescription = p.config.Description
+	}
+
+	releaseNotes := p.config.ReleaseNotes
+	if releaseNotes != "" {
+		releaseNotes, _ = p.tmplCtx.Apply("wingets.release_notes", releaseNotes)
+	}
+	releaseNotesURL := p.config.ReleaseNotesURL
+	if releaseNotesURL != "" {
+		releaseNotesURL, _ = p.tmplCtx.Apply("wingets.release_notes_url", releaseNotesURL)
+	}
+
+	locale := wingetLocaleManifest{
+		PackageIdentifier:   id,
+		PackageVersion:      version,
+		PackageLocale:       "en-US",
+		Publisher:           publisher,
+		PublisherUrl:        p.config.PublisherURL,
+		PublisherSupportUrl: p.config.PublisherSupportURL,
+		Author:              p.config.Publisher,
+		PackageName:         name,
+		PackageUrl:          p.config.Homepage,
+		License:             p.config.License,
+		LicenseUrl:          p.config.LicenseURL,
+		Copyright:           p.config.Copyright,
+		CopyrightUrl:        p.config.CopyrightURL,
+		ShortDescription:    shortDescription,
+		Description:         p.config.Description,
+		Moniker:             p.config.Moniker,
+		Tags:                p.config.Tags,
+		ReleaseNotes:        releaseNotes,
+		ReleaseNotesUrl:     releaseNotesURL,
+		ManifestType:        "defaultLocale",
+		ManifestVersion:     wingetManifestVersion,
+	}
+
+	versionManifest := wingetVersionManifest{
+		PackageIdentifier: id,
+		PackageVersion:    version,
+		DefaultLocale:     "en-US",
+		ManifestType:      "version",
+		ManifestVersion:   wingetManifestVersion,
+	}
+
+	dir := p.config.Path
+	if dir == "" {
+		dir = fmt.Sprintf("manifests/%s/%s/%s",
+			strings.ToLower(string(id[0])),
+			strings.ReplaceAll(id, ".", "/"),
+			version,
+		)
+	}
+
+	manifests := make(map[string]string)
+	for _, m := range []struct {
+		file   string
+		schema string
+		value  interface{}
+	}{
+		{id + ".yaml", "version", versionManifest},
+		{id + ".installer.yaml", "installer", installer},
+		{id + ".locale.en-US.yaml", "defaultLocale", locale},
+	} {
+		content, err := wingetManifest{m.schema, m.value}.Generate()
+		if err != nil {
+			return nil, err
+		}
+		manifests[dir+"/"+m.file] = string(content)
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode >= 400 {
-		respBody, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("GitHub API error: %s", respBody)
+	return manifests, nil
+}
+
+// wingetManifest is one of the manifests of a winget package version
+type wingetManifest struct {
+	// Schema is the manifest type in the schema URL, e.g. installer
+	Schema string
+	// Value is the manifest, marshaled as YAML
+	Value interface{}
+}
+
+// Generate renders the manifest with the schema header winget validation
+// expects
+func (m wingetManifest) Generate() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteString(fmt.Sprintf("# yaml-language-server: $schema=https://aka.ms/winget-manifest.%s.%s.schema.json\n\n", m.Schema, wingetManifestVersion))
+
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(m.Value); err != nil {
+		return nil, err
+	}
+	if err := enc.Close(); err != nil {
+		return nil, err
 	}
 
-	return nil
+	return buf.Bytes(), nil
 }
 
 // ScoopPublisher publishes to Scoop bucket
@@ -717,6 +1322,18 @@ type ScoopPublisher struct {
 	tmplCtx *tmpl.Context
 }
 
+func init() {
+	RegisterRequirements(func(cfg *config.Config) []Requirement {
+		var reqs []Requirement
+		for _, scoop := range cfg.Scoops {
+			if scoop.SkipUpload != "true" && needsGitHubToken(scoop.Repository) {
+				reqs = append(reqs, Requirement{NeededBy: neededBy("scoop", scoop.Name), Env: []string{"GITHUB_TOKEN"}, Disable: scoop.Disable})
+			}
+		}
+		return reqs
+	})
+}
+
 // NewScoopPublisher creates a new Scoop publisher
 func NewScoopPublisher(cfg config.Scoop, tmplCtx *tmpl.Context) *ScoopPublisher {
 	return &ScoopPublisher{
@@ -732,11 +1349,6 @@ func (p *ScoopPublisher) Publish(ctx context.Context, artifacts []artifact.Artif
 		return nil
 	}
 
-	token := os.Getenv("GITHUB_TOKEN")
-	if token == "" {
-		return fmt.Errorf("GITHUB_TOKEN is required for Scoop publish")
-	}
-
 	log.Info("Publishing to Scoop", "package", p.config.Name)
 
 	// Generate manifest
@@ -760,11 +1372,17 @@ func (p *ScoopPublisher) Publish(ctx context.Context, artifacts []artifact.Artif
 
 	// Push to repository
 	repo := p.config.Repository
-	if repo.Owner == "" || repo.Name == "" {
-		return fmt.Errorf("scoop repository owner and name are required")
+	if (repo.Owner == "" || repo.Name == "") && repo.Git.URL == "" {
+		return fmt.Errorf("scoop repository owner and name, or repository.git.url, are required")
+	}
+
+	commitMsg := p.config.CommitMsgTemplate
+	if commitMsg == "" {
+		commitMsg = fmt.Sprintf("Update %s to %s", manifestName, p.tmplCtx.Get("Version"))
 	}
+	commitMsg, _ = p.tmplCtx.Apply("scoops.commit_msg_template", commitMsg)
 
-	if err := p.pushManifest(ctx, token, repo, manifestPath, manifest); err != nil {
+	if err := commitToRepo(ctx, p.tmplCtx, repo, manifestPath, manifest, commitMsg, p.config.CommitAuthor); err != nil {
 		return fmt.Errorf("failed to push manifest: %w", err)
 	}
 
@@ -780,21 +1398,36 @@ func (p *ScoopPublisher) generateManifest(artifacts []artifact.Artifact) (string
 		name = p.tmplCtx.Get("ProjectName")
 	}
 
-	// Find Windows 64-bit and 32-bit archives
-	var url64, url32 string
-	for _, a := range artifacts {
+	// Find Windows 64-bit and 32-bit archives, the first of each
+	var url64, url32, hash64, hash32 string
+	for _, a := range sortedArtifacts(artifacts) {
 		if a.Goos == "windows" && a.Type == artifact.TypeArchive {
+			if a.Goarch != "amd64" && a.Goarch != "386" {
+				continue
+			}
+			if !matchesIDs(a, p.config.IDs) || !matchesVariant(a, p.config.Goarm, p.config.Goamd64) {
+				continue
+			}
+			if (a.Goarch == "amd64" && url64 != "") || (a.Goarch == "386" && url32 != "") {
+				continue
+			}
+
 			urlTemplate := p.config.URLTemplate
 			if urlTemplate == "" {
-				urlTemplate = "https://github.com/{{ .Env.GITHUB_OWNER }}/{{ .Env.GITHUB_REPO }}/releases/download/{{ .Tag }}/{{ .ArtifactName }}"
+				urlTemplate = "{{ .ReleaseDownloadURL }}/{{ .ArtifactName }}"
+			}
+			tmplCtx := p.tmplCtx.ForArtifact(a)
+			url, _ := tmplCtx.Apply("scoops.url_template", urlTemplate)
+
+			sum, err := artifactSHA256(a, artifacts)
+			if err != nil {
+				return "", fmt.Errorf("failed to hash %s: %w", a.Name, err)
 			}
-			tmplCtx := p.tmplCtx.WithArtifact(a.Name, a.Goos, a.Goarch, a.Goarm, a.Goamd64)
-			url, _ := tmplCtx.Apply(urlTemplate)
 
 			if a.Goarch == "amd64" {
-				url64 = url
-			} else if a.Goarch == "386" {
-				url32 = url
+				url64, hash64 = url, sum
+			} else {
+				url32, hash32 = url, sum
 			}
 		}
 	}
@@ -803,114 +1436,58 @@ func (p *ScoopPublisher) generateManifest(artifacts []artifact.Artifact) (string
 		return "", fmt.Errorf("no Windows amd64 archive found for Scoop")
 	}
 
-	manifest := map[string]interface{}{
-		"version":     version,
-		"description": p.config.Description,
-		"homepage":    p.config.Homepage,
-		"license":     p.config.License,
-		"architecture": map[string]interface{}{
-			"64bit": map[string]interface{}{
-				"url":  url64,
-				"bin":  name + ".exe",
-				"hash": "",
-			},
-		},
+	doc := scoopManifest{
+		Version:     version,
+		Description: p.config.Description,
+		Homepage:    p.config.Homepage,
+		License:     p.config.License,
+		Depends:     p.config.Depends,
+		Persist:     p.config.Persist,
+		Shortcuts:   p.config.Shortcuts,
 	}
-
+	doc.Architecture.Bit64 = &scoopArchitecture{URL: url64, Bin: name + ".exe", Hash: hash64}
 	if url32 != "" {
-		arch := manifest["architecture"].(map[string]interface{})
-		arch["32bit"] = map[string]interface{}{
-			"url":  url32,
-			"bin":  name + ".exe",
-			"hash": "",
-		}
+		doc.Architecture.Bit32 = &scoopArchitecture{URL: url32, Bin: name + ".exe", Hash: hash32}
 	}
 
-	if len(p.config.Depends) > 0 {
-		manifest["depends"] = p.config.Depends
-	}
-
-	if len(p.config.Persist) > 0 {
-		manifest["persist"] = p.config.Persist
-	}
-
-	if len(p.config.Shortcuts) > 0 {
-		manifest["shortcuts"] = p.config.Shortcuts
-	}
-
-	jsonData, err := json.MarshalIndent(manifest, "", "    ")
+	data, err := doc.Generate()
 	if err != nil {
 		return "", err
 	}
-
-	return string(jsonData), nil
+	return string(data), nil
 }
 
-// pushManifest pushes the Scoop manifest to the repository
-func (p *ScoopPublisher) pushManifest(ctx context.Context, token string, repo config.RepoRef, path, content string) error {
-	url := fmt.Sprintf("https://api.github.com/repos/%s/%s/contents/%s", repo.Owner, repo.Name, path)
-
-	// Check if file exists
-	req, _ := http.NewRequestWithContext(ctx, "GET", url, nil)
-	req.Header.Set("Authorization", "token "+token)
-	resp, err := http.DefaultClient.Do(req)
-	if err != nil {
-		return err
-	}
-
-	var sha string
-	if resp.StatusCode == 200 {
-		var existing map[string]interface{}
-		json.NewDecoder(resp.Body).Decode(&existing)
-		if s, ok := existing["sha"].(string); ok {
-			sha = s
-		}
-	}
-	resp.Body.Close()
-
-	// Create/Update file
-	commitMsg := p.config.CommitMsgTemplate
-	if commitMsg == "" {
-		commitMsg = fmt.Sprintf("Update %s to %s", p.config.Name, p.tmplCtx.Get("Version"))
-	}
-	commitMsg, _ = p.tmplCtx.Apply(commitMsg)
-
-	body := map[string]interface{}{
-		"message": commitMsg,
-		"content": encodeBase64(content),
-	}
-	if sha != "" {
-		body["sha"] = sha
-	}
-
-	// Add commit author if configured
-	if p.config.CommitAuthor.Name != "" || p.config.CommitAuthor.Email != "" {
-		committer := make(map[string]string)
-		if p.config.CommitAuthor.Name != "" {
-			committer["name"] = p.config.CommitAuthor.Name
-		}
-		if p.config.CommitAuthor.Email != "" {
-			committer["email"] = p.config.CommitAuthor.Email
-		}
-		body["committer"] = committer
-	}
-
-	bodyJSON, _ := json.Marshal(body)
-	req, _ = http.NewRequestWithContext(ctx, "PUT", url, bytes.NewReader(bodyJSON))
-	req.Header.Set("Authorization", "token "+token)
-	req.Header.Set("Accept", "application/vnd.github.v3+json")
-	req.Header.Set("Content-Type", "application/json")
+// scoopManifest is a Scoop app manifest, its fields in the alphabetical
+// order Scoop buckets keep them in
+type scoopManifest struct {
+	Architecture struct {
+		Bit32 *scoopArchitecture `json:"32bit,omitempty"`
+		Bit64 *scoopArchitecture `json:"64bit,omitempty"`
+	} `json:"architecture"`
+	Depends     []string   `json:"depends,omitempty"`
+	Description string     `json:"description"`
+	Homepage    string     `json:"homepage"`
+	License     string     `json:"license"`
+	Persist     []string   `json:"persist,omitempty"`
+	Shortcuts   [][]string `json:"shortcuts,omitempty"`
+	Version     string     `json:"version"`
+}
 
-	resp, err = http.DefaultClient.Do(req)
-	if err != nil {
-		return err
-	}
-	defer resp.Body.Close()
+// scoopArchitecture is the download of a Scoop app for one architecture
+type scoopArchitecture struct {
+	Bin  string `json:"bin"`
+	Hash string `json:"hash"`
+	URL  string `json:"url"`
+}
 
-	if resp.StatusCode >= 400 {
-		respBody, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("GitHub API error: %s", respBody)
+// Generate renders the manifest as JSON
+func (m scoopManifest) Generate() ([]byte, error) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "    ")
+	if err := enc.Encode(m); err != nil {
+		return nil, err
 	}
-
-	return nil
+	return bytes.TrimRight(buf.Bytes(), "\n"), nil
 }
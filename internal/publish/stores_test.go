@@ -0,0 +1,218 @@
+package publish
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/oarkflow/releaser/internal/artifact"
+	"github.com/oarkflow/releaser/internal/checksum"
+	"github.com/oarkflow/releaser/internal/config"
+	"github.com/oarkflow/releaser/internal/git"
+	"github.com/oarkflow/releaser/internal/tmpl"
+)
+
+// fixtureContent is the archive the manifests point at, fixtureSHA256 its
+// digest
+const (
+	fixtureContent = "fixture archive\n"
+	fixtureSHA256  = "b82d14bd3717287c78a2e1351107a49a925192cae59c0f844437eed8a0d6caef"
+)
+
+func testTemplateContext(t *testing.T) *tmpl.Context {
+	t.Helper()
+	ctx := tmpl.New(&config.Config{ProjectName: "demo"}, &git.Info{CurrentTag: "v1.2.3"}, false, false)
+	ctx.Set("ReleaseDownloadURL", "https://example.com/download/v1.2.3")
+	return ctx
+}
+
+// windowsArchive writes the fixture file as a Windows amd64 zip archive
+func windowsArchive(t *testing.T) artifact.Artifact {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "demo_windows_amd64.zip")
+	if err := os.WriteFile(path, []byte(fixtureContent), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return artifact.Artifact{
+		Name:   "demo_windows_amd64.zip",
+		Path:   path,
+		Type:   artifact.TypeArchive,
+		Goos:   "windows",
+		Goarch: "amd64",
+	}
+}
+
+func TestWindowsManifestsEmbedSHA256(t *testing.T) {
+	sources := map[string]func(t *testing.T) []artifact.Artifact{
+		// The checksum stage records the digest on the artifact, here with
+		// another algorithm configured; the file is removed afterwards so
+		// only the recorded digest can end up in the manifest
+		"recorded": func(t *testing.T) []artifact.Artifact {
+			a := windowsArchive(t)
+			manager := artifact.NewManager()
+			if err := manager.Add(a); err != nil {
+				t.Fatal(err)
+			}
+			gen := checksum.NewGenerator(config.Checksum{Algorithm: "sha512"}, t.TempDir(), manager, nil)
+			if err := gen.Run(context.Background()); err != nil {
+				t.Fatal(err)
+			}
+			artifacts := manager.Filter(artifact.ByType(artifact.TypeArchive))
+			if sum := artifacts[0].Checksum("sha256"); sum != fixtureSHA256 {
+				t.Fatalf("checksum stage recorded sha256 %q, want %q", sum, fixtureSHA256)
+			}
+			if err := os.Remove(a.Path); err != nil {
+				t.Fatal(err)
+			}
+			return artifacts
+		},
+		// Without the checksum stage the file is hashed
+		"computed": func(t *testing.T) []artifact.Artifact {
+			return []artifact.Artifact{windowsArchive(t)}
+		},
+	}
+
+	renderers := map[string]func(t *testing.T, artifacts []artifact.Artifact) string{
+		"scoop": func(t *testing.T, artifacts []artifact.Artifact) string {
+			p := NewScoopPublisher(config.Scoop{}, testTemplateContext(t))
+			out, err := p.generateManifest(artifacts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			return out
+		},
+		"chocolatey": func(t *testing.T, artifacts []artifact.Artifact) string {
+			p := NewChocolateyPublisher(config.Chocolatey{}, testTemplateContext(t), nil)
+			dir := t.TempDir()
+			if err := p.generateInstallScript(dir, artifacts); err != nil {
+				t.Fatal(err)
+			}
+			data, err := os.ReadFile(filepath.Join(dir, "tools", "chocolateyinstall.ps1"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			return string(data)
+		},
+		"winget": func(t *testing.T, artifacts []artifact.Artifact) string {
+			p := NewWingetPublisher(config.Winget{PackageIdentifier: "Demo.Demo"}, testTemplateContext(t), nil)
+			files, err := p.generateManifests(artifacts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var all strings.Builder
+			for _, content := range files {
+				all.WriteString(content)
+			}
+			return all.String()
+		},
+	}
+
+	want := map[string]string{
+		"scoop":      `"hash": "` + fixtureSHA256 + `"`,
+		"chocolatey": `checksum64     = '` + fixtureSHA256 + `'`,
+		"winget":     "InstallerSha256: " + strings.ToUpper(fixtureSHA256),
+	}
+
+	for source, artifacts := range sources {
+		for name, render := range renderers {
+			t.Run(name+"/"+source, func(t *testing.T) {
+				out := render(t, artifacts(t))
+				if !strings.Contains(out, want[name]) {
+					t.Errorf("manifest does not contain %q:\n%s", want[name], out)
+				}
+			})
+		}
+	}
+}
+
+func TestAURSourcePackage(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "demo-1.2.3.tar.gz")
+	if err := os.WriteFile(path, []byte(fixtureContent), 0644); err != nil {
+		t.Fatal(err)
+	}
+	source := artifact.Artifact{Name: "demo-1.2.3.tar.gz", Path: path, Type: artifact.TypeSourceArchive}
+
+	tests := []struct {
+		name      string
+		template  string
+		artifacts []artifact.Artifact
+		want      string
+		wantErr   string
+	}{
+		{name: "uploaded source archive", artifacts: []artifact.Artifact{source}, want: "https://example.com/download/v1.2.3/demo-1.2.3.tar.gz"},
+		{
+			name:      "source url template",
+			template:  "https://mirror.example.com/{{ .ProjectName }}/{{ .ArtifactName }}",
+			artifacts: []artifact.Artifact{source},
+			want:      "https://mirror.example.com/demo/demo-1.2.3.tar.gz",
+		},
+		{name: "no source archive", wantErr: "no source archive found"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := config.AUR{Mode: "source", SourceURLTemplate: tt.template}
+			pkg, err := NewAURPublisher(cfg, testTemplateContext(t), artifact.NewManager()).buildPackage(tt.artifacts)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("buildPackage() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			want := []aurSource{{File: "demo-1.2.3.tar.gz", URL: tt.want, SHA256: fixtureSHA256}}
+			if got := pkg.Sources[""]; len(got) != 1 || got[0] != want[0] {
+				t.Errorf("sources = %+v, want %+v", got, want)
+			}
+		})
+	}
+}
+
+func TestArtifactSHA256FromChecksumsFile(t *testing.T) {
+	sha512 := strings.Repeat("ab", 64)
+	other := strings.Repeat("0", 64)
+
+	tests := []struct {
+		name  string
+		extra map[string]interface{}
+		lines string
+		want  string
+		err   string
+	}{
+		{name: "sha256 without algorithm", lines: other + "  demo_windows_amd64.zip\n", want: other},
+		{name: "sha256", extra: map[string]interface{}{"algorithm": "sha256"}, lines: other + " *demo_windows_amd64.zip\n", want: other},
+		{name: "sha512 without algorithm", lines: sha512 + "  demo_windows_amd64.zip\n", err: "not sha256"},
+		{name: "other algorithm", extra: map[string]interface{}{"algorithm": "sha512"}, lines: sha512 + "  demo_windows_amd64.zip\n", want: fixtureSHA256},
+		{name: "not listed", lines: other + "  demo_linux_amd64.tar.gz\n", want: fixtureSHA256},
+		{name: "unknown algorithm", lines: "abc123  demo_windows_amd64.zip\n", err: "no known algorithm"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "checksums.txt")
+			if err := os.WriteFile(path, []byte(tt.lines), 0644); err != nil {
+				t.Fatal(err)
+			}
+			sums := artifact.Artifact{Name: "checksums.txt", Path: path, Type: artifact.TypeChecksum, Extra: tt.extra}
+			a := windowsArchive(t)
+
+			got, err := artifactSHA256(a, []artifact.Artifact{a, sums})
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Fatalf("artifactSHA256() = %q, %v, want error %q", got, err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("artifactSHA256() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
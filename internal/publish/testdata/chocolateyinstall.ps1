@@ -0,0 +1,13 @@
+$ErrorActionPreference = 'Stop'
+$toolsDir = "$(Split-Path -parent $MyInvocation.MyCommand.Definition)"
+$url = 'https://example.com/it''s/demo_windows_amd64.zip?a=$x'
+
+$packageArgs = @{
+    packageName    = $env:ChocolateyPackageName
+    unzipLocation  = $toolsDir
+    url64bit       = $url
+    checksum64     = 'aaaa'
+    checksumType64 = 'sha256'
+}
+
+Install-ChocolateyZipPackage @packageArgs
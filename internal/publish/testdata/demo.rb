@@ -0,0 +1,50 @@
+class Demo < Formula
+  desc "Say \"hi\" to \#{name}, $HOME & <it's> \\ ‘quoted’"
+  homepage "https://example.com/demo"
+  version "1.2.3"
+  license "MIT"
+
+  on_macos do
+    on_intel do
+      url "https://example.com/demo_darwin_amd64.tar.gz"
+      sha256 "aaaa"
+    end
+    on_arm do
+      url "https://example.com/demo_darwin_arm64.tar.gz"
+      sha256 "bbbb"
+    end
+  end
+
+  on_linux do
+    on_intel do
+      url "https://example.com/demo_linux_amd64.tar.gz?token=\#{x}"
+      sha256 "cccc"
+    end
+    on_arm do
+      unless Hardware::CPU.is_64_bit?
+        url "https://example.com/demo_linux_armv7.tar.gz"
+        sha256 "dddd"
+      end
+    end
+  end
+
+  depends_on "git"
+  on_linux do
+    depends_on "xz" => :optional
+  end
+  conflicts_with "demo\"legacy"
+
+  def install
+    bin.install "demo"
+  end
+
+  def caveats
+    <<~EOS
+      Run demo init
+    EOS
+  end
+
+  test do
+    system "#{bin}/demo", "--version"
+  end
+end
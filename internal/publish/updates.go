@@ -0,0 +1,431 @@
+package publish
+
+import (
+	"context"
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/log"
+
+	"github.com/oarkflow/releaser/internal/artifact"
+	"github.com/oarkflow/releaser/internal/config"
+	"github.com/oarkflow/releaser/internal/httpclient"
+	"github.com/oarkflow/releaser/internal/tmpl"
+)
+
+// UpdatesID is the artifact ID of the update feeds, for blobs to select
+// them with ids
+const UpdatesID = "updates"
+
+const sparkleNamespace = "http://www.andymatuschak.org/xml-namespaces/sparkle"
+
+var (
+	// appcastItemRe matches an item of an appcast with its indentation and
+	// trailing newline
+	appcastItemRe = regexp.MustCompile(`(?s)[ \t]*<item\b[^>]*>.*?</item>[ \t]*\n?`)
+	// appcastVersionRe finds the sparkle:version of an item, an element or,
+	// in older feeds, an enclosure attribute
+	appcastVersionRe = regexp.MustCompile(`<sparkle:version>\s*([^<]*?)\s*</sparkle:version>|sparkle:version="([^"]*)"`)
+	// appcastChannelEndRe matches the end of the channel with its
+	// indentation
+	appcastChannelEndRe = regexp.MustCompile(`[ \t]*</channel>`)
+)
+
+// updatePriority orders the downloads of each OS by how well they install
+// an update, best first
+var updatePriority = map[string][]artifact.Type{
+	"darwin":  {artifact.TypeDMG, artifact.TypePKG, artifact.TypeArchive},
+	"windows": {artifact.TypeMSI, artifact.TypeNSIS, artifact.TypeArchive},
+	"linux":   {artifact.TypeAppImage, artifact.TypeArchive},
+}
+
+// UpdateManifest is the generic update feed, latest.json
+type UpdateManifest struct {
+	Version   string                    `json:"version"`
+	NotesURL  string                    `json:"notes_url,omitempty"`
+	PubDate   string                    `json:"pub_date,omitempty"`
+	Platforms map[string]UpdatePlatform `json:"platforms"`
+}
+
+// UpdatePlatform is the download of a platform of the update manifest
+type UpdatePlatform struct {
+	Name   string `json:"name"`
+	URL    string `json:"url"`
+	SHA256 string `json:"sha256"`
+}
+
+// UpdateFeeds renders the feeds desktop apps poll for new versions
+type UpdateFeeds struct {
+	config  config.Updates
+	tmplCtx *tmpl.Context
+	distDir string
+}
+
+// NewUpdateFeeds creates an update feed renderer.
+func NewUpdateFeeds(cfg config.Updates, tmplCtx *tmpl.Context, distDir string) *UpdateFeeds {
+	return &UpdateFeeds{
+		config:  cfg,
+		tmplCtx: tmplCtx,
+		distDir: distDir,
+	}
+}
+
+// Render writes the enabled feeds to dist and returns them as metadata
+// artifacts with the ID UpdatesID
+func (u *UpdateFeeds) Render(ctx context.Context, artifacts []artifact.Artifact) ([]artifact.Artifact, error) {
+	var feeds []artifact.Artifact
+	if u.config.Appcast.Enabled {
+		name := orDefault(u.config.Appcast.Name, "appcast.xml")
+		content, err := u.appcast(ctx, artifacts)
+		if err != nil {
+			return nil, fmt.Errorf("appcast: %w", err)
+		}
+		feed, err := u.write(name, content)
+		if err != nil {
+			return nil, err
+		}
+		feeds = append(feeds, feed)
+	}
+	if u.config.Manifest.Enabled {
+		name := orDefault(u.config.Manifest.Name, "latest.json")
+		content, err := u.manifest(artifacts)
+		if err != nil {
+			return nil, fmt.Errorf("update manifest: %w", err)
+		}
+		feed, err := u.write(name, content)
+		if err != nil {
+			return nil, err
+		}
+		feeds = append(feeds, feed)
+	}
+	return feeds, nil
+}
+
+// write writes a feed to dist
+func (u *UpdateFeeds) write(name string, content []byte) (artifact.Artifact, error) {
+	path := filepath.Join(u.distDir, name)
+	if err := os.WriteFile(path, content, 0644); err != nil {
+		return artifact.Artifact{}, err
+	}
+	log.Info("Generated update feed", "file", path)
+	return artifact.Artifact{
+		Name:  name,
+		Path:  path,
+		Type:  artifact.TypeMetadata,
+		Extra: map[string]interface{}{"id": UpdatesID, "update_feed": true},
+	}, nil
+}
+
+// appcast adds an item for the release's DMG to the appcast served at
+// appcast.url, or starts one. An item of the same version is replaced, so
+// rendering the release again gives the same feed.
+func (u *UpdateFeeds) appcast(ctx context.Context, artifacts []artifact.Artifact) ([]byte, error) {
+	cfg := u.config.Appcast
+	var dmgs []artifact.Artifact
+	for _, a := range artifacts {
+		if a.Type == artifact.TypeDMG && matchesIDs(a, cfg.IDs) && !isPrivate(a) {
+			dmgs = append(dmgs, a)
+		}
+	}
+	switch len(dmgs) {
+	case 0:
+		return nil, fmt.Errorf("no DMG to add")
+	case 1:
+	default:
+		var names []string
+		for _, a := range dmgs {
+			names = append(names, a.Name)
+		}
+		return nil, fmt.Errorf("several DMGs (%s), set appcast.ids to pick one", strings.Join(names, ", "))
+	}
+	dmg := dmgs[0]
+
+	version, err := u.tmplCtx.Apply("updates.appcast.version", orDefault(cfg.Version, "{{ .Version }}"))
+	if err != nil {
+		return nil, err
+	}
+	shortVersion, err := u.tmplCtx.Apply("updates.appcast.short_version", cfg.ShortVersion)
+	if err != nil {
+		return nil, err
+	}
+	minimumSystemVersion, err := u.tmplCtx.Apply("updates.appcast.minimum_system_version", cfg.MinimumSystemVersion)
+	if err != nil {
+		return nil, err
+	}
+	downloadURL, err := u.downloadURL(dmg)
+	if err != nil {
+		return nil, err
+	}
+	notesURL, err := u.notesURL()
+	if err != nil {
+		return nil, err
+	}
+	info, err := os.Stat(dmg.Path)
+	if err != nil {
+		return nil, err
+	}
+	signature, err := u.edSignature(dmg.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	var item strings.Builder
+	item.WriteString("    <item>\n")
+	writeXMLElement(&item, "title", u.tmplCtx.Get("Version"))
+	if date, err := time.Parse(time.RFC3339, u.tmplCtx.Get("CommitDate")); err == nil {
+		writeXMLElement(&item, "pubDate", date.UTC().Format(time.RFC1123Z))
+	}
+	writeXMLElement(&item, "sparkle:version", version)
+	writeXMLElement(&item, "sparkle:shortVersionString", shortVersion)
+	writeXMLElement(&item, "sparkle:minimumSystemVersion", minimumSystemVersion)
+	writeXMLElement(&item, "sparkle:releaseNotesLink", notesURL)
+	// Sparkle only offers items of a channel to apps that opt in to it,
+	// which keeps prereleases from stable users
+	if u.tmplCtx.IsPrerelease() {
+		channel, _, _ := strings.Cut(u.tmplCtx.Get("Prerelease"), ".")
+		writeXMLElement(&item, "sparkle:channel", orDefault(channel, "beta"))
+	}
+	fmt.Fprintf(&item, `      <enclosure url="%s" length="%d" type="application/octet-stream"`, xmlEscape(downloadURL), info.Size())
+	if signature != "" {
+		fmt.Fprintf(&item, ` sparkle:edSignature="%s"`, signature)
+	}
+	item.WriteString("/>\n    </item>\n")
+
+	existing, err := u.fetchAppcast(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if existing == "" {
+		title, err := u.tmplCtx.Apply("updates.appcast.title", orDefault(cfg.Title, u.tmplCtx.Get("ProjectName")))
+		if err != nil {
+			return nil, err
+		}
+		var feed strings.Builder
+		feed.WriteString("<?xml version=\"1.0\" encoding=\"utf-8\"?>\n")
+		fmt.Fprintf(&feed, "<rss version=\"2.0\" xmlns:sparkle=\"%s\">\n  <channel>\n", sparkleNamespace)
+		fmt.Fprintf(&feed, "    <title>%s</title>\n", xmlEscape(title))
+		feed.WriteString(item.String())
+		feed.WriteString("  </channel>\n</rss>\n")
+		return []byte(feed.String()), nil
+	}
+	return mergeAppcast(existing, version, item.String())
+}
+
+// mergeAppcast inserts item above the items of feed, dropping those of the
+// same version
+func mergeAppcast(feed, version, item string) ([]byte, error) {
+	if !strings.Contains(feed, sparkleNamespace) || !appcastChannelEndRe.MatchString(feed) {
+		return nil, fmt.Errorf("existing feed is not a Sparkle appcast")
+	}
+	feed = appcastItemRe.ReplaceAllStringFunc(feed, func(existing string) string {
+		for _, m := range appcastVersionRe.FindAllStringSubmatch(existing, -1) {
+			if m[1]+m[2] == version {
+				return ""
+			}
+		}
+		return existing
+	})
+	at := appcastChannelEndRe.FindStringIndex(feed)[0]
+	if loc := appcastItemRe.FindStringIndex(feed); loc != nil {
+		at = loc[0]
+	}
+	return []byte(feed[:at] + item + feed[at:]), nil
+}
+
+// fetchAppcast downloads the appcast served at appcast.url, or returns ""
+// when there is none yet
+func (u *UpdateFeeds) fetchAppcast(ctx context.Context) (string, error) {
+	url, err := u.tmplCtx.Apply("updates.appcast.url", u.config.Appcast.URL)
+	if err != nil || url == "" {
+		return "", err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return "", err
+	}
+	resp, err := httpclient.Client().Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to fetch %s: %w", url, err)
+	}
+	defer resp.Body.Close()
+
+	// A 403 may hide a missing object in a bucket, but it may as well be a
+	// denied read of the feed, which starting over would truncate
+	if resp.StatusCode == http.StatusForbidden && u.config.Appcast.ForbiddenIsMissing {
+		log.Warn("Appcast is forbidden, starting one as if it were missing", "url", url)
+		return "", nil
+	}
+	if resp.StatusCode == http.StatusForbidden {
+		return "", fmt.Errorf("failed to fetch %s: status 403; set appcast.forbidden_is_missing if the bucket hides missing objects", url)
+	}
+	if resp.StatusCode == http.StatusNotFound {
+		log.Info("No appcast yet, starting one", "url", url)
+		return "", nil
+	}
+	if resp.StatusCode >= 400 {
+		return "", fmt.Errorf("failed to fetch %s: status %d", url, resp.StatusCode)
+	}
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", fmt.Errorf("failed to fetch %s: %w", url, err)
+	}
+	return string(data), nil
+}
+
+// edSignature signs a file with the EdDSA key of appcast.ed_key or
+// $SPARKLE_PRIVATE_KEY, returning "" without a key
+func (u *UpdateFeeds) edSignature(path string) (string, error) {
+	key, err := u.tmplCtx.Apply("updates.appcast.ed_key", u.config.Appcast.EdKey)
+	if err != nil {
+		return "", err
+	}
+	if key == "" {
+		key = os.Getenv("SPARKLE_PRIVATE_KEY")
+	}
+	if key == "" {
+		log.Warn("No EdDSA key for the appcast, Sparkle 2 rejects unsigned updates", "env", "SPARKLE_PRIVATE_KEY")
+		return "", nil
+	}
+	if data, err := os.ReadFile(key); err == nil {
+		key = string(data)
+	}
+	raw, err := base64.StdEncoding.DecodeString(strings.TrimSpace(key))
+	if err != nil {
+		return "", fmt.Errorf("invalid EdDSA key: %w", err)
+	}
+	// generate_keys exports the 32-byte seed; older keys hold the public
+	// key after it
+	var private ed25519.PrivateKey
+	switch len(raw) {
+	case ed25519.SeedSize:
+		private = ed25519.NewKeyFromSeed(raw)
+	case ed25519.PrivateKeySize:
+		private = ed25519.PrivateKey(raw)
+	default:
+		return "", fmt.Errorf("invalid EdDSA key: %d bytes, want %d or %d", len(raw), ed25519.SeedSize, ed25519.PrivateKeySize)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(ed25519.Sign(private, data)), nil
+}
+
+// manifest renders latest.json with the best download of each platform
+func (u *UpdateFeeds) manifest(artifacts []artifact.Artifact) ([]byte, error) {
+	notesURL, err := u.notesURL()
+	if err != nil {
+		return nil, err
+	}
+	m := UpdateManifest{
+		Version:   u.tmplCtx.Get("Version"),
+		NotesURL:  notesURL,
+		Platforms: make(map[string]UpdatePlatform),
+	}
+	if date, err := time.Parse(time.RFC3339, u.tmplCtx.Get("CommitDate")); err == nil {
+		m.PubDate = date.UTC().Format(time.RFC3339)
+	}
+
+	type candidate struct {
+		artifact artifact.Artifact
+		rank     int
+	}
+	best := make(map[string]candidate)
+	sorted := append([]artifact.Artifact(nil), artifacts...)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].Name < sorted[j].Name })
+	for _, a := range sorted {
+		if a.Goos == "" || a.Goarch == "" || isPrivate(a) || !matchesIDs(a, u.config.Manifest.IDs) {
+			continue
+		}
+		rank := updateRank(a)
+		if rank < 0 {
+			continue
+		}
+		// A universal download serves both Macs unless one has its own
+		platforms := []string{a.Goos + "-" + a.Goarch}
+		if a.Goarch == "universal" {
+			platforms = []string{"darwin-amd64", "darwin-arm64"}
+			rank++
+		}
+		for _, platform := range platforms {
+			if c, ok := best[platform]; !ok || rank < c.rank {
+				best[platform] = candidate{artifact: a, rank: rank}
+			}
+		}
+	}
+	if len(best) == 0 {
+		return nil, fmt.Errorf("no downloads to list")
+	}
+
+	for platform, c := range best {
+		url, err := u.downloadURL(c.artifact)
+		if err != nil {
+			return nil, err
+		}
+		sum, err := artifactSHA256(c.artifact, artifacts)
+		if err != nil {
+			return nil, fmt.Errorf("failed to hash %s: %w", c.artifact.Name, err)
+		}
+		m.Platforms[platform] = UpdatePlatform{Name: c.artifact.Name, URL: url, SHA256: sum}
+	}
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(data, '\n'), nil
+}
+
+// updateRank returns the position of the artifact's type in the update
+// priority of its OS, in steps of two to leave room for universal
+// downloads, or -1 when it is no update download
+func updateRank(a artifact.Artifact) int {
+	priority, ok := updatePriority[a.Goos]
+	if !ok {
+		priority = []artifact.Type{artifact.TypeArchive}
+	}
+	for i, t := range priority {
+		if a.Type == t {
+			return 2 * i
+		}
+	}
+	return -1
+}
+
+// downloadURL applies updates.url_template to an artifact
+func (u *UpdateFeeds) downloadURL(a artifact.Artifact) (string, error) {
+	urlTemplate := orDefault(u.config.URLTemplate, "{{ .ReleaseDownloadURL }}/{{ .ArtifactName }}")
+	return u.tmplCtx.WithArtifactInfo(a.Name, a.Goos, a.Goarch, a.Goarm, a.Goamd64).Apply("updates.url_template", urlTemplate)
+}
+
+// notesURL returns the release notes link, the release page by default
+func (u *UpdateFeeds) notesURL() (string, error) {
+	return u.tmplCtx.Apply("updates.notes_url", orDefault(u.config.NotesURL, "{{ .ReleaseURL }}"))
+}
+
+// writeXMLElement writes an indented item element unless value is empty
+func writeXMLElement(b *strings.Builder, name, value string) {
+	if value == "" {
+		return
+	}
+	fmt.Fprintf(b, "      <%s>", name)
+	_ = xml.EscapeText(b, []byte(value))
+	fmt.Fprintf(b, "</%s>\n", name)
+}
+
+// xmlEscape escapes text or an attribute value
+func xmlEscape(value string) string {
+	var b strings.Builder
+	_ = xml.EscapeText(&b, []byte(value))
+	return b.String()
+}
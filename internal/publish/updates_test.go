@@ -0,0 +1,61 @@
+package publish
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/oarkflow/releaser/internal/config"
+)
+
+func TestFetchAppcast(t *testing.T) {
+	const feed = `<rss><channel><item><sparkle:version>1.2.2</sparkle:version></item></channel></rss>`
+
+	tests := []struct {
+		name               string
+		status             int
+		forbiddenIsMissing bool
+		want               string
+		wantErr            string
+	}{
+		{name: "existing feed", status: http.StatusOK, want: feed},
+		{name: "missing feed", status: http.StatusNotFound},
+		{name: "forbidden", status: http.StatusForbidden, wantErr: "status 403; set appcast.forbidden_is_missing"},
+		{name: "forbidden is missing", status: http.StatusForbidden, forbiddenIsMissing: true},
+		{name: "server error", status: http.StatusInternalServerError, forbiddenIsMissing: true, wantErr: "status 500"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.status)
+				if tt.status == http.StatusOK {
+					io.WriteString(w, feed)
+				}
+			}))
+			t.Cleanup(srv.Close)
+
+			cfg := config.Updates{Appcast: config.Appcast{
+				Enabled:            true,
+				URL:                srv.URL + "/{{ .ProjectName }}/appcast.xml",
+				ForbiddenIsMissing: tt.forbiddenIsMissing,
+			}}
+			got, err := NewUpdateFeeds(cfg, testTemplateContext(t), t.TempDir()).fetchAppcast(context.Background())
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) || !strings.Contains(err.Error(), srv.URL+"/demo/appcast.xml") {
+					t.Fatalf("fetchAppcast() error = %v, want %q naming the URL", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("fetchAppcast() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,393 @@
+package publish
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/log"
+
+	"github.com/oarkflow/releaser/internal/artifact"
+	"github.com/oarkflow/releaser/internal/httpclient"
+	"github.com/oarkflow/releaser/internal/retry"
+	"github.com/oarkflow/releaser/internal/tmpl"
+)
+
+// wheelLauncher runs the binary named after the console script, so every
+// binary of the wheel gets its own command
+const wheelLauncher = `import os
+import subprocess
+import sys
+
+BINARIES = %s
+
+
+def main():
+    script = os.path.splitext(os.path.basename(sys.argv[0]))[0]
+    exe = os.path.join(os.path.dirname(os.path.abspath(__file__)), "bin", BINARIES.get(script, %s))
+    if sys.platform == "win32":
+        sys.exit(subprocess.call([exe] + sys.argv[1:]))
+    os.execv(exe, [exe] + sys.argv[1:])
+`
+
+// pythonName matches the runs of characters normalized to "_" in wheel file
+// and module names
+var pythonName = regexp.MustCompile(`[-_.]+`)
+
+// wheelFile is a file of a wheel
+type wheelFile struct {
+	name string
+	data []byte
+	mode os.FileMode
+}
+
+// binaryWheels builds a wheel per platform with the selected binaries and
+// a launcher module, and uploads them unless skip_upload is set.
+func (p *PyPIPublisher) binaryWheels(ctx context.Context, artifacts []artifact.Artifact) error {
+	name := p.config.Name
+	if name == "" {
+		name = p.tmplCtx.Get("ProjectName")
+	}
+	version := pep440Version(p.tmplCtx.Get("Version"))
+
+	platforms := make(map[string][]artifact.Artifact)
+	for _, a := range artifacts {
+		if (a.Type != artifact.TypeBinary && a.Type != artifact.TypeUniversalBinary) || !matchesIDs(a, p.config.IDs) {
+			continue
+		}
+		platform := wheelPlatform(a)
+		if platform == "" {
+			log.Debug("No wheel platform tag for binary, skipping", "binary", a.Name, "os", a.Goos, "arch", a.Goarch)
+			continue
+		}
+		platforms[platform] = append(platforms[platform], a)
+	}
+	if len(platforms) == 0 {
+		return fmt.Errorf("no binaries found for PyPI wheels")
+	}
+
+	dir := filepath.Join(p.distDir, "pypi")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+
+	tags := make([]string, 0, len(platforms))
+	for tag := range platforms {
+		tags = append(tags, tag)
+	}
+	sort.Strings(tags)
+
+	var wheels []string
+	for _, tag := range tags {
+		binaries := platforms[tag]
+		path, err := p.buildWheel(dir, name, version, tag, binaries)
+		if err != nil {
+			return fmt.Errorf("failed to build wheel for %s: %w", tag, err)
+		}
+		if err := p.manager.Add(artifact.Artifact{
+			Name:    filepath.Base(path),
+			Path:    path,
+			Type:    artifact.TypePyPI,
+			Goos:    binaries[0].Goos,
+			Goarch:  binaries[0].Goarch,
+			Goarm:   binaries[0].Goarm,
+			BuildID: binaries[0].BuildID,
+		}); err != nil {
+			return err
+		}
+		log.Info("Wheel created", "path", path)
+		wheels = append(wheels, path)
+	}
+
+	if p.config.SkipUpload == "true" {
+		log.Info("Skipping PyPI upload")
+		return nil
+	}
+
+	username, password := p.credentials()
+	if password == "" {
+		return fmt.Errorf("PyPI token is required, set password, TWINE_PASSWORD or PYPI_TOKEN")
+	}
+	for _, wheel := range wheels {
+		if err := p.uploadWheel(ctx, wheel, name, version, username, password); err != nil {
+			return fmt.Errorf("failed to upload %s: %w", filepath.Base(wheel), err)
+		}
+	}
+
+	log.Info("PyPI wheels published successfully", "count", len(wheels))
+	return nil
+}
+
+// buildWheel writes the wheel of one platform into dir
+func (p *PyPIPublisher) buildWheel(dir, name, version, platform string, binaries []artifact.Artifact) (string, error) {
+	module := strings.ToLower(pythonName.ReplaceAllString(name, "_"))
+	distInfo := fmt.Sprintf("%s-%s.dist-info", module, version)
+	tag := "py3-none-" + platform
+
+	scripts := make(map[string]string)
+	var entryPoints strings.Builder
+	entryPoints.WriteString("[console_scripts]\n")
+	var files []wheelFile
+	for _, bin := range binaries {
+		file := filepath.Base(bin.Path)
+		data, err := os.ReadFile(bin.Path)
+		if err != nil {
+			return "", err
+		}
+		files = append(files, wheelFile{name: module + "/bin/" + file, data: data, mode: 0755})
+
+		script := strings.TrimSuffix(bin.Name, ".exe")
+		scripts[script] = file
+		fmt.Fprintf(&entryPoints, "%s = %s:main\n", script, module)
+	}
+
+	// JSON strings and objects are valid Python literals
+	scriptsJSON, err := json.Marshal(scripts)
+	if err != nil {
+		return "", err
+	}
+	defaultJSON, _ := json.Marshal(filepath.Base(binaries[0].Path))
+
+	var metadata strings.Builder
+	fmt.Fprintf(&metadata, "Metadata-Version: 2.1\nName: %s\nVersion: %s\n", name, version)
+	if p.config.Summary != "" {
+		fmt.Fprintf(&metadata, "Summary: %s\n", p.config.Summary)
+	}
+	if p.config.Homepage != "" {
+		fmt.Fprintf(&metadata, "Home-page: %s\n", p.config.Homepage)
+	}
+	if p.config.License != "" {
+		fmt.Fprintf(&metadata, "License: %s\n", p.config.License)
+	}
+	metadata.WriteString("Requires-Python: >=3.7\n")
+
+	files = append(files,
+		wheelFile{name: module + "/__init__.py", data: []byte(fmt.Sprintf(wheelLauncher, scriptsJSON, defaultJSON)), mode: 0644},
+		wheelFile{name: module + "/__main__.py", data: []byte("from . import main\n\nmain()\n"), mode: 0644},
+		wheelFile{name: distInfo + "/METADATA", data: []byte(metadata.String()), mode: 0644},
+		wheelFile{name: distInfo + "/WHEEL", data: []byte("Wheel-Version: 1.0\nGenerator: releaser\nRoot-Is-Purelib: false\nTag: " + tag + "\n"), mode: 0644},
+		wheelFile{name: distInfo + "/entry_points.txt", data: []byte(entryPoints.String()), mode: 0644},
+	)
+
+	// RECORD lists every file with its digest, itself without one
+	var record strings.Builder
+	for _, f := range files {
+		sum := sha256.Sum256(f.data)
+		fmt.Fprintf(&record, "%s,sha256=%s,%d\n", f.name, base64.RawURLEncoding.EncodeToString(sum[:]), len(f.data))
+	}
+	fmt.Fprintf(&record, "%s/RECORD,,\n", distInfo)
+	files = append(files, wheelFile{name: distInfo + "/RECORD", data: []byte(record.String()), mode: 0644})
+
+	path := filepath.Join(dir, fmt.Sprintf("%s-%s-%s.whl", module, version, tag))
+	out, err := os.Create(path)
+	if err != nil {
+		return "", err
+	}
+	defer out.Close()
+
+	modified := sourceDate(p.tmplCtx)
+	zw := zip.NewWriter(out)
+	for _, f := range files {
+		header := &zip.FileHeader{Name: f.name, Method: zip.Deflate, Modified: modified}
+		header.SetMode(f.mode)
+		w, err := zw.CreateHeader(header)
+		if err != nil {
+			return "", err
+		}
+		if _, err := w.Write(f.data); err != nil {
+			return "", err
+		}
+	}
+	if err := zw.Close(); err != nil {
+		return "", err
+	}
+	return path, out.Close()
+}
+
+// uploadWheel uploads a wheel with the upload API twine uses
+func (p *PyPIPublisher) uploadWheel(ctx context.Context, path, name, version, username, password string) error {
+	repository := p.config.Repository
+	if repository == "" {
+		repository = "https://upload.pypi.org/legacy/"
+	}
+
+	if err := Confirm(Action{
+		Description: fmt.Sprintf("Upload %s to PyPI", filepath.Base(path)),
+		URL:         repository,
+		Files:       []string{path},
+	}); err != nil {
+		return err
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	sum := sha256.Sum256(data)
+
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	fields := [][2]string{
+		{":action", "file_upload"},
+		{"protocol_version", "1"},
+		{"metadata_version", "2.1"},
+		{"name", name},
+		{"version", version},
+		{"filetype", "bdist_wheel"},
+		{"pyversion", "py3"},
+		{"sha256_digest", hex.EncodeToString(sum[:])},
+		{"summary", p.config.Summary},
+		{"home_page", p.config.Homepage},
+		{"license", p.config.License},
+	}
+	for _, field := range fields {
+		if err := mw.WriteField(field[0], field[1]); err != nil {
+			return err
+		}
+	}
+	part, err := mw.CreateFormFile("content", filepath.Base(path))
+	if err != nil {
+		return err
+	}
+	if _, err := part.Write(data); err != nil {
+		return err
+	}
+	if err := mw.Close(); err != nil {
+		return err
+	}
+
+	return retry.Do(ctx, retry.DefaultOptions("upload "+filepath.Base(path)), func(int) error {
+		req, err := http.NewRequestWithContext(ctx, "POST", repository, bytes.NewReader(body.Bytes()))
+		if err != nil {
+			return retry.Permanent(err)
+		}
+		req.SetBasicAuth(username, password)
+		req.Header.Set("Content-Type", mw.FormDataContentType())
+
+		resp, err := httpclient.Client().Do(req)
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+		respBody, _ := io.ReadAll(resp.Body)
+
+		switch {
+		case resp.StatusCode < 300:
+			log.Info("Uploaded wheel", "name", filepath.Base(path))
+			return nil
+		case p.config.SkipExisting && (resp.StatusCode == 409 || bytes.Contains(bytes.ToLower(respBody), []byte("already exist"))):
+			log.Info("Wheel already uploaded, skipping", "name", filepath.Base(path))
+			return nil
+		case resp.StatusCode >= 500:
+			return fmt.Errorf("upload failed: %s: %s", resp.Status, respBody)
+		default:
+			return retry.Permanent(fmt.Errorf("upload failed: %s: %s", resp.Status, respBody))
+		}
+	})
+}
+
+// credentials returns the PyPI username and password or token
+func (p *PyPIPublisher) credentials() (string, string) {
+	username := p.config.Username
+	if username == "" {
+		username = os.Getenv("TWINE_USERNAME")
+	}
+	if username == "" {
+		username = "__token__"
+	}
+
+	password := p.config.Password
+	if password == "" {
+		password = os.Getenv("TWINE_PASSWORD")
+		if password == "" {
+			password = os.Getenv("PYPI_TOKEN")
+		}
+	}
+	return username, password
+}
+
+// wheelPlatform returns the platform tag of a binary's wheel, or "" if
+// pip has none for its target. Go binaries are static, so Linux wheels
+// claim both glibc and musl.
+func wheelPlatform(a artifact.Artifact) string {
+	switch a.Goos {
+	case "linux":
+		arch := map[string]string{
+			"amd64":   "x86_64",
+			"arm64":   "aarch64",
+			"386":     "i686",
+			"ppc64le": "ppc64le",
+			"s390x":   "s390x",
+		}[a.Goarch]
+		if a.Goarch == "arm" && (a.Goarm == "" || a.Goarm == "7") {
+			arch = "armv7l"
+		}
+		if arch == "" {
+			return ""
+		}
+		return fmt.Sprintf("manylinux_2_17_%[1]s.manylinux2014_%[1]s.musllinux_1_1_%[1]s", arch)
+	case "darwin":
+		// Go binaries need macOS 11
+		arch := map[string]string{"amd64": "x86_64", "arm64": "arm64", "universal": "universal2"}[a.Goarch]
+		if arch == "" {
+			return ""
+		}
+		return "macosx_11_0_" + arch
+	case "windows":
+		return map[string]string{"amd64": "win_amd64", "386": "win32", "arm64": "win_arm64"}[a.Goarch]
+	}
+	return ""
+}
+
+// pep440Version turns a semantic version into a PEP 440 one: prereleases
+// map to a, b and rc, other suffixes to a dev release, and build metadata
+// is dropped since PyPI rejects local versions
+func pep440Version(version string) string {
+	v := strings.TrimPrefix(version, "v")
+	v, _, _ = strings.Cut(v, "+")
+	base, pre, found := strings.Cut(v, "-")
+	if !found {
+		return base
+	}
+
+	pre = strings.ToLower(pre)
+	for _, label := range []struct{ prefix, tag string }{
+		{"alpha", "a"}, {"beta", "b"}, {"rc", "rc"}, {"pre", "rc"}, {"a", "a"}, {"b", "b"},
+	} {
+		if !strings.HasPrefix(pre, label.prefix) {
+			continue
+		}
+		n := strings.TrimLeft(pre[len(label.prefix):], ".-_")
+		if n == "" {
+			n = "0"
+		}
+		if _, err := strconv.Atoi(n); err == nil {
+			return base + label.tag + n
+		}
+	}
+	return base + ".dev0"
+}
+
+// sourceDate returns the commit date, for reproducible package timestamps,
+// or the current time outside of a repository
+func sourceDate(tmplCtx *tmpl.Context) time.Time {
+	if t, err := time.Parse(time.RFC3339, tmplCtx.Get("CommitDate")); err == nil {
+		return t.UTC()
+	}
+	return time.Now().UTC()
+}
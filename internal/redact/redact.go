@@ -0,0 +1,201 @@
+/*
+Package redact keeps credentials out of everything releaser prints. Secret
+values are registered once, from the environment and from config fields
+tagged secret, and replaced with [redacted] in logs, error output and logged
+command lines.
+*/
+package redact
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Redacted replaces secret values in log and error output
+const Redacted = "[redacted]"
+
+// minLength avoids redacting short values, such as main, v1.2.3 or a
+// true/false toggle, that would blank out unrelated output
+const minLength = 8
+
+// secretNameParts mark variables whose values are credentials
+var secretNameParts = []string{"TOKEN", "SECRET", "PASSWORD", "PASSPHRASE", "KEY", "CREDENTIAL"}
+
+// templateEnvRe finds environment variables referenced by a template
+var templateEnvRe = regexp.MustCompile(`(?:\.Env\.|env\s+")([A-Za-z_][A-Za-z0-9_]*)`)
+
+var (
+	secretsMu sync.RWMutex
+	secrets   = make(map[string]bool)
+	// replacer is rebuilt lazily after secrets change
+	replacer *strings.Replacer
+)
+
+// IsSecretName reports whether a variable name looks like a credential
+func IsSecretName(name string) bool {
+	upper := strings.ToUpper(name)
+	for _, part := range secretNameParts {
+		if strings.Contains(upper, part) {
+			return true
+		}
+	}
+	return false
+}
+
+// Add registers a value to redact. Values shorter than minLength
+// are ignored, since masking them would blank out unrelated output.
+func Add(value string) {
+	value = strings.TrimSpace(value)
+	if len(value) < minLength {
+		return
+	}
+	secretsMu.Lock()
+	defer secretsMu.Unlock()
+	if !secrets[value] {
+		secrets[value] = true
+		replacer = nil
+	}
+}
+
+// Env registers the values of secret-looking environment
+// variables and of the named ones
+func Env(names []string) {
+	for _, kv := range os.Environ() {
+		if name, value, ok := strings.Cut(kv, "="); ok && IsSecretName(name) {
+			Add(value)
+		}
+	}
+	for _, name := range names {
+		Add(os.Getenv(name))
+	}
+}
+
+// Config registers the values of string fields tagged
+// secret:"true" anywhere in v, and the values of the variables named by
+// fields tagged secret:"env". Templated values contribute the variables
+// they reference, since they render to those.
+func Config(v interface{}) {
+	collectSecrets(reflect.ValueOf(v), "")
+}
+
+func collectSecrets(v reflect.Value, tag string) {
+	switch v.Kind() {
+	case reflect.Pointer, reflect.Interface:
+		if !v.IsNil() {
+			collectSecrets(v.Elem(), tag)
+		}
+	case reflect.Struct:
+		t := v.Type()
+		for i := 0; i < v.NumField(); i++ {
+			if t.Field(i).IsExported() {
+				collectSecrets(v.Field(i), t.Field(i).Tag.Get("secret"))
+			}
+		}
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			collectSecrets(v.Index(i), tag)
+		}
+	case reflect.Map:
+		iter := v.MapRange()
+		for iter.Next() {
+			collectSecrets(iter.Value(), tag)
+		}
+	case reflect.String:
+		value := v.String()
+		switch tag {
+		case "true":
+			if !strings.Contains(value, "{{") {
+				Add(value)
+				return
+			}
+			for _, m := range templateEnvRe.FindAllStringSubmatch(value, -1) {
+				Add(os.Getenv(m[1]))
+			}
+		case "env":
+			if value != "" {
+				Add(os.Getenv(value))
+			}
+		}
+	}
+}
+
+// String replaces every registered secret in s with [redacted]
+func String(s string) string {
+	secretsMu.RLock()
+	r := replacer
+	secretsMu.RUnlock()
+	if r == nil {
+		r = buildReplacer()
+	}
+	return r.Replace(s)
+}
+
+// Args returns a copy of a command line with secrets redacted, for
+// logging
+func Args(args []string) []string {
+	redacted := make([]string, len(args))
+	for i, arg := range args {
+		redacted[i] = String(arg)
+	}
+	return redacted
+}
+
+// writer redacts output line by line, so a secret split across writes
+// is still replaced
+type writer struct {
+	w   io.Writer
+	buf []byte
+}
+
+// Writer wraps w so secrets in a command's output are redacted. Close
+// flushes a final unterminated line.
+func Writer(w io.Writer) io.WriteCloser {
+	return &writer{w: w}
+}
+
+func (r *writer) Write(p []byte) (int, error) {
+	r.buf = append(r.buf, p...)
+	if i := bytes.LastIndexByte(r.buf, '\n'); i >= 0 {
+		if _, err := io.WriteString(r.w, String(string(r.buf[:i+1]))); err != nil {
+			return 0, err
+		}
+		r.buf = r.buf[i+1:]
+	}
+	return len(p), nil
+}
+
+func (r *writer) Close() error {
+	if len(r.buf) == 0 {
+		return nil
+	}
+	_, err := io.WriteString(r.w, String(string(r.buf)))
+	r.buf = nil
+	return err
+}
+
+// buildReplacer builds the replacer for the registered secrets, longest
+// first so a secret containing another is redacted whole
+func buildReplacer() *strings.Replacer {
+	secretsMu.Lock()
+	defer secretsMu.Unlock()
+	if replacer != nil {
+		return replacer
+	}
+	values := make([]string, 0, len(secrets))
+	for value := range secrets {
+		values = append(values, value)
+	}
+	sort.Slice(values, func(i, j int) bool { return len(values[i]) > len(values[j]) })
+	pairs := make([]string, 0, 2*len(values))
+	for _, value := range values {
+		pairs = append(pairs, value, Redacted)
+	}
+	replacer = strings.NewReplacer(pairs...)
+	return replacer
+}
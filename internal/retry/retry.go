@@ -0,0 +1,123 @@
+/*
+Package retry provides exponential backoff helpers for flaky network operations.
+*/
+package retry
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/charmbracelet/log"
+)
+
+// Options configures retry behavior
+type Options struct {
+	// Attempts is the maximum number of attempts (including the first one)
+	Attempts int
+
+	// InitialDelay is the delay before the first retry
+	InitialDelay time.Duration
+
+	// MaxDelay caps the delay between attempts
+	MaxDelay time.Duration
+
+	// Name is used for logging
+	Name string
+}
+
+// defaults are the options DefaultOptions returns
+var defaults = Options{
+	Attempts:     5,
+	InitialDelay: time.Second,
+	MaxDelay:     30 * time.Second,
+}
+
+// DefaultOptions returns sensible defaults for network operations
+func DefaultOptions(name string) Options {
+	opts := defaults
+	opts.Name = name
+	return opts
+}
+
+// SetDefaults changes the policy DefaultOptions returns; zero values keep
+// the current setting
+func SetDefaults(attempts int, initialDelay, maxDelay time.Duration) {
+	if attempts > 0 {
+		defaults.Attempts = attempts
+	}
+	if initialDelay > 0 {
+		defaults.InitialDelay = initialDelay
+	}
+	if maxDelay > 0 {
+		defaults.MaxDelay = maxDelay
+	}
+}
+
+// permanentError marks an error that should not be retried
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string {
+	return e.err.Error()
+}
+
+func (e *permanentError) Unwrap() error {
+	return e.err
+}
+
+// Permanent wraps an error so that Do stops retrying immediately
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &permanentError{err: err}
+}
+
+// Do runs fn until it succeeds, returns a permanent error, the attempts are
+// exhausted, or the context is cancelled. The delay doubles after every attempt.
+func Do(ctx context.Context, opts Options, fn func(attempt int) error) error {
+	attempts := opts.Attempts
+	if attempts <= 0 {
+		attempts = 1
+	}
+	delay := opts.InitialDelay
+	if delay <= 0 {
+		delay = time.Second
+	}
+
+	var err error
+	for attempt := 1; attempt <= attempts; attempt++ {
+		err = fn(attempt)
+		if err == nil {
+			return nil
+		}
+
+		var perm *permanentError
+		if errors.As(err, &perm) {
+			return perm.err
+		}
+
+		if attempt == attempts {
+			break
+		}
+
+		log.Warn("Operation failed, retrying", "op", opts.Name, "attempt", attempt, "delay", delay, "error", err)
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+
+		delay *= 2
+		if opts.MaxDelay > 0 && delay > opts.MaxDelay {
+			delay = opts.MaxDelay
+		}
+	}
+
+	return err
+}
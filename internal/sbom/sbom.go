@@ -95,7 +95,7 @@ func (g *Generator) generateSBOM(ctx context.Context, a artifact.Artifact, metho
 	}
 
 	// Add artifact
-	g.manager.Add(artifact.Artifact{
+	if err := g.manager.Add(artifact.Artifact{
 		Name: sbomName,
 		Path: sbomPath,
 		Type: artifact.TypeSBOM,
@@ -104,7 +104,9 @@ func (g *Generator) generateSBOM(ctx context.Context, a artifact.Artifact, metho
 			"method": method,
 			"source": a.Name,
 		},
-	})
+	}); err != nil {
+		return err
+	}
 
 	log.Info("SBOM generated", "name", sbomName)
 	return nil
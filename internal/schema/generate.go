@@ -0,0 +1,134 @@
+package schema
+
+import (
+	"reflect"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/oarkflow/releaser/internal/config"
+)
+
+// unmarshalerType is implemented by config types that also accept a
+// shorthand string, such as hooks and archive files
+var unmarshalerType = reflect.TypeOf((*yaml.Unmarshaler)(nil)).Elem()
+
+// generator builds schemas of Go types, with a definition per named struct
+type generator struct {
+	defs map[string]*Schema
+}
+
+// GenerateSchema generates the JSON Schema of the releaser configuration
+// from the config structs, so it follows every field added to them:
+// properties are named by their yaml tags, fields tagged
+// jsonschema:"required" are required and enum tags list the values of a
+// field.
+func GenerateSchema() *Schema {
+	g := &generator{defs: make(map[string]*Schema)}
+	g.schemaOf(reflect.TypeOf(config.Config{}))
+
+	root := *g.defs["Config"]
+	root.Schema = "https://json-schema.org/draft/2020-12/schema"
+	root.ID = "https://releaser.dev/schema/v1"
+	root.Title = "Releaser Configuration"
+	root.Description = "Schema of the releaser configuration file"
+	// Profiles are partial configs that don't repeat required fields
+	g.defs["Config"].Required = nil
+	root.Defs = g.defs
+	return &root
+}
+
+// schemaOf returns the schema of a type, a reference for named structs
+func (g *generator) schemaOf(t reflect.Type) *Schema {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		// YAML decodes any scalar into a string, so templated fields such
+		// as enabled: true take booleans and numbers too
+		return &Schema{Type: Types{"string", "boolean", "number"}}
+	case reflect.Bool:
+		return &Schema{Type: Types{"boolean"}}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return &Schema{Type: Types{"integer"}}
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: Types{"number"}}
+	case reflect.Slice, reflect.Array:
+		return &Schema{Type: Types{"array"}, Items: g.schemaOf(t.Elem())}
+	case reflect.Map:
+		s := &Schema{Type: Types{"object"}}
+		if t.Elem().Kind() != reflect.Interface {
+			s.AdditionalProperties = g.schemaOf(t.Elem())
+		}
+		return s
+	case reflect.Struct:
+		if t.Name() == "" {
+			return g.structSchema(t)
+		}
+		ref := &Schema{Ref: "#/$defs/" + t.Name()}
+		if _, ok := g.defs[t.Name()]; !ok {
+			// Reserve the name first so recursive types end
+			g.defs[t.Name()] = &Schema{}
+			*g.defs[t.Name()] = *g.structSchema(t)
+		}
+		if reflect.PointerTo(t).Implements(unmarshalerType) {
+			return &Schema{AnyOf: []*Schema{{Type: Types{"string"}}, ref}}
+		}
+		return ref
+	}
+	// Interfaces take any value
+	return &Schema{}
+}
+
+// structSchema returns the object schema of a struct
+func (g *generator) structSchema(t reflect.Type) *Schema {
+	s := &Schema{Type: Types{"object"}, Properties: make(map[string]*Schema)}
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		name, opts, _ := strings.Cut(field.Tag.Get("yaml"), ",")
+		if name == "-" {
+			continue
+		}
+		if strings.Contains(","+opts+",", ",inline,") {
+			inline := g.structSchema(field.Type)
+			for key, prop := range inline.Properties {
+				s.Properties[key] = prop
+			}
+			s.Required = append(s.Required, inline.Required...)
+			continue
+		}
+		if name == "" {
+			name = strings.ToLower(field.Name)
+		}
+
+		prop := g.schemaOf(field.Type)
+		if enum := field.Tag.Get("enum"); enum != "" {
+			values := enumValues(enum)
+			if prop.Type.Is("array") {
+				prop.Items = &Schema{Type: Types{"string"}, Enum: values}
+			} else {
+				prop.Type, prop.Enum = Types{"string"}, values
+			}
+		}
+		s.Properties[name] = prop
+		if field.Tag.Get("jsonschema") == "required" {
+			s.Required = append(s.Required, name)
+		}
+	}
+	return s
+}
+
+// enumValues splits an enum tag
+func enumValues(tag string) []interface{} {
+	var values []interface{}
+	for _, v := range strings.Split(tag, ",") {
+		values = append(values, v)
+	}
+	return values
+}
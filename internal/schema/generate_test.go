@@ -0,0 +1,154 @@
+package schema
+
+import (
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/oarkflow/releaser/internal/builder"
+)
+
+func TestGenerateSchemaRequired(t *testing.T) {
+	s := GenerateSchema()
+
+	// Only fields tagged jsonschema:"required" are required, whether or
+	// not they have omitempty
+	tests := []struct {
+		def  string
+		want []string
+	}{
+		{def: "Hook", want: []string{"cmd"}},
+		{def: "ArchiveFormatOverride", want: []string{"format", "goos"}},
+		{def: "MonorepoProject", want: []string{"name", "path"}},
+		{def: "DockerExportConfig", want: []string{"output"}},
+		{def: "DMGContent", want: nil},
+		{def: "TemplateFile", want: nil},
+		{def: "Build", want: nil},
+	}
+	for _, tt := range tests {
+		def, ok := s.Defs[tt.def]
+		if !ok {
+			t.Errorf("no definition of %s", tt.def)
+			continue
+		}
+		got := append([]string(nil), def.Required...)
+		sort.Strings(got)
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s requires %v, want %v", tt.def, got, tt.want)
+		}
+	}
+
+	// The root requires the project name; profiles, which reuse the
+	// Config definition, require nothing
+	if !reflect.DeepEqual(s.Required, []string{"project_name"}) {
+		t.Errorf("root requires %v", s.Required)
+	}
+	if len(s.Defs["Config"].Required) != 0 {
+		t.Errorf("Config definition requires %v", s.Defs["Config"].Required)
+	}
+}
+
+func TestValidateRequired(t *testing.T) {
+	v := NewValidator(GenerateSchema())
+
+	tests := []struct {
+		name   string
+		config string
+		errors []string
+	}{
+		{
+			name: "optional fields without omitempty",
+			config: `project_name: demo
+version: 2
+docker_exports:
+  - id: image
+    output: dist/image.tar
+`,
+		},
+		{
+			name: "missing required fields",
+			config: `project_name: demo
+docker_exports:
+  - image: ghcr.io/oarkflow/demo
+monorepo:
+  projects:
+    - name: api
+`,
+			errors: []string{"docker_exports[0].output", "monorepo.projects[0].path"},
+		},
+		{
+			name:   "missing project name",
+			config: "dist: out\n",
+			errors: []string{"project_name"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var doc interface{}
+			if err := yaml.Unmarshal([]byte(tt.config), &doc); err != nil {
+				t.Fatal(err)
+			}
+			var paths []string
+			for _, err := range v.Validate(doc).Errors {
+				if !strings.Contains(err.Message, "required") {
+					t.Errorf("unexpected error %v", err)
+				}
+				paths = append(paths, err.Path)
+			}
+			sort.Strings(paths)
+			if !reflect.DeepEqual(paths, tt.errors) {
+				t.Errorf("errors at %v, want %v", paths, tt.errors)
+			}
+		})
+	}
+}
+
+func TestBuilderEnum(t *testing.T) {
+	// Every name a builder accepts, by builder
+	accepted := map[string][]string{
+		"*builder.GoBuilder":       {"go"},
+		"*builder.RustBuilder":     {"rust", "cargo"},
+		"*builder.NodeBuilder":     {"node", "npm", "yarn", "pnpm"},
+		"*builder.DenoBuilder":     {"deno"},
+		"*builder.BunBuilder":      {"bun"},
+		"*builder.DotnetBuilder":   {"dotnet"},
+		"*builder.GomobileBuilder": {"gomobile"},
+		"*builder.PythonBuilder":   {"python", "pip", "poetry", "pyinstaller"},
+		"*builder.JavaBuilder":     {"java", "maven", "mvn", "gradle"},
+		"*builder.PHPBuilder":      {"php", "composer", "phar"},
+		"*builder.PrebuiltBuilder": {"prebuilt"},
+	}
+
+	v := NewValidator(GenerateSchema())
+	for typ, names := range accepted {
+		for _, name := range names {
+			b := builder.GetBuilder(name)
+			if got := reflect.TypeOf(b).String(); got != typ || !b.Supports(name) {
+				t.Errorf("builder %q is handled by %s, want %s", name, got, typ)
+			}
+			doc := map[string]interface{}{
+				"project_name": "demo",
+				"builds":       []interface{}{map[string]interface{}{"builder": name}},
+			}
+			if errs := v.Validate(doc).Errors; len(errs) > 0 {
+				t.Errorf("builder %q does not validate: %v", name, errs)
+			}
+		}
+	}
+
+	// The enum lists no name that falls back to the Go builder
+	build := GenerateSchema().Defs["Build"]
+	if build == nil {
+		t.Fatal("no Build definition in the schema")
+	}
+	for _, value := range build.Properties["builder"].Enum {
+		name := value.(string)
+		if !builder.GetBuilder(name).Supports(name) {
+			t.Errorf("enum value %q is not supported by any builder", name)
+		}
+	}
+}
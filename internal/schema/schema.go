@@ -9,31 +9,66 @@ import (
 
 	"github.com/charmbracelet/log"
 	"gopkg.in/yaml.v3"
+
+	"github.com/oarkflow/releaser/internal/config"
 )
 
 // Schema represents a JSON Schema for validation
 type Schema struct {
-	ID          string             `json:"$id,omitempty"`
-	Schema      string             `json:"$schema,omitempty"`
-	Title       string             `json:"title,omitempty"`
-	Description string             `json:"description,omitempty"`
-	Type        string             `json:"type,omitempty"`
-	Properties  map[string]*Schema `json:"properties,omitempty"`
-	Required    []string           `json:"required,omitempty"`
-	Items       *Schema            `json:"items,omitempty"`
-	Enum        []interface{}      `json:"enum,omitempty"`
-	Default     interface{}        `json:"default,omitempty"`
-	Minimum     *float64           `json:"minimum,omitempty"`
-	Maximum     *float64           `json:"maximum,omitempty"`
-	MinLength   *int               `json:"minLength,omitempty"`
-	MaxLength   *int               `json:"maxLength,omitempty"`
-	Pattern     string             `json:"pattern,omitempty"`
-	Format      string             `json:"format,omitempty"`
-	OneOf       []*Schema          `json:"oneOf,omitempty"`
-	AnyOf       []*Schema          `json:"anyOf,omitempty"`
-	AllOf       []*Schema          `json:"allOf,omitempty"`
-	Ref         string             `json:"$ref,omitempty"`
-	Defs        map[string]*Schema `json:"$defs,omitempty"`
+	ID                   string             `json:"$id,omitempty"`
+	Schema               string             `json:"$schema,omitempty"`
+	Title                string             `json:"title,omitempty"`
+	Description          string             `json:"description,omitempty"`
+	Type                 Types              `json:"type,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	Enum                 []interface{}      `json:"enum,omitempty"`
+	Default              interface{}        `json:"default,omitempty"`
+	Minimum              *float64           `json:"minimum,omitempty"`
+	Maximum              *float64           `json:"maximum,omitempty"`
+	MinLength            *int               `json:"minLength,omitempty"`
+	MaxLength            *int               `json:"maxLength,omitempty"`
+	Pattern              string             `json:"pattern,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	OneOf                []*Schema          `json:"oneOf,omitempty"`
+	AnyOf                []*Schema          `json:"anyOf,omitempty"`
+	AllOf                []*Schema          `json:"allOf,omitempty"`
+	Ref                  string             `json:"$ref,omitempty"`
+	Defs                 map[string]*Schema `json:"$defs,omitempty"`
+}
+
+// Types is the type keyword, the JSON types a value may have. A single
+// type is written as a string.
+type Types []string
+
+// MarshalJSON writes a single type as a string
+func (t Types) MarshalJSON() ([]byte, error) {
+	if len(t) == 1 {
+		return json.Marshal(t[0])
+	}
+	return json.Marshal([]string(t))
+}
+
+// UnmarshalJSON reads a type or a list of types
+func (t *Types) UnmarshalJSON(data []byte) error {
+	var single string
+	if err := json.Unmarshal(data, &single); err == nil {
+		*t = Types{single}
+		return nil
+	}
+	return json.Unmarshal(data, (*[]string)(t))
+}
+
+// Is reports whether the types include typ
+func (t Types) Is(typ string) bool {
+	for _, have := range t {
+		if have == typ {
+			return true
+		}
+	}
+	return false
 }
 
 // ValidationError represents a schema validation error
@@ -86,26 +121,25 @@ func (v *Validator) ValidateFile(path string) *ValidationResult {
 		}
 	}
 
-	var doc interface{}
-	if strings.HasSuffix(path, ".json") {
-		if err := json.Unmarshal(data, &doc); err != nil {
-			return &ValidationResult{
-				Valid: false,
-				Errors: []ValidationError{{
-					Path:    path,
-					Message: fmt.Sprintf("invalid JSON: %v", err),
-				}},
-			}
+	// JSON and TOML are validated as the YAML they load as
+	data, err = config.ToYAML(path, data)
+	if err != nil {
+		return &ValidationResult{
+			Valid: false,
+			Errors: []ValidationError{{
+				Path:    path,
+				Message: err.Error(),
+			}},
 		}
-	} else {
-		if err := yaml.Unmarshal(data, &doc); err != nil {
-			return &ValidationResult{
-				Valid: false,
-				Errors: []ValidationError{{
-					Path:    path,
-					Message: fmt.Sprintf("invalid YAML: %v", err),
-				}},
-			}
+	}
+	var doc interface{}
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return &ValidationResult{
+			Valid: false,
+			Errors: []ValidationError{{
+				Path:    path,
+				Message: fmt.Sprintf("invalid YAML: %v", err),
+			}},
 		}
 	}
 
@@ -122,7 +156,8 @@ func (v *Validator) Validate(doc interface{}) *ValidationResult {
 
 // validate recursively validates a value against a schema
 func (v *Validator) validate(schema *Schema, value interface{}, path string, result *ValidationResult) {
-	if schema == nil {
+	// YAML decodes an empty value as null, which leaves the field unset
+	if schema == nil || value == nil {
 		return
 	}
 
@@ -182,11 +217,15 @@ func (v *Validator) validate(schema *Schema, value interface{}, path string, res
 	}
 
 	// Type validation
-	if schema.Type != "" {
-		if !v.checkType(schema.Type, value) {
+	if len(schema.Type) > 0 {
+		matched := false
+		for _, typ := range schema.Type {
+			matched = matched || v.checkType(typ, value)
+		}
+		if !matched {
 			result.Errors = append(result.Errors, ValidationError{
 				Path:    path,
-				Message: fmt.Sprintf("expected type %s, got %T", schema.Type, value),
+				Message: fmt.Sprintf("expected type %s, got %T", strings.Join(schema.Type, " or "), value),
 				Value:   value,
 			})
 			return
@@ -267,6 +306,13 @@ func (v *Validator) validate(schema *Schema, value interface{}, path string, res
 				}
 			}
 		}
+		if schema.AdditionalProperties != nil {
+			for key, propValue := range obj {
+				if _, ok := schema.Properties[key]; !ok {
+					v.validate(schema.AdditionalProperties, propValue, joinPath(path, key), result)
+				}
+			}
+		}
 	}
 
 	// Array validation
@@ -341,378 +387,6 @@ func joinPath(base, key string) string {
 	return base + "." + key
 }
 
-// GenerateSchema generates a JSON Schema for the releaser configuration
-func GenerateSchema() *Schema {
-	return &Schema{
-		Schema:      "https://json-schema.org/draft/2020-12/schema",
-		ID:          "https://releaser.dev/schema/v1",
-		Title:       "Releaser Configuration",
-		Description: "Schema for releaser.yaml configuration file",
-		Type:        "object",
-		Properties: map[string]*Schema{
-			"project_name": {
-				Type:        "string",
-				Description: "The name of the project",
-			},
-			"version": {
-				Type:        "string",
-				Description: "The version of the project",
-			},
-			"env": {
-				Type:        "array",
-				Description: "Environment variables",
-				Items: &Schema{
-					Type: "string",
-				},
-			},
-			"before": {
-				Ref: "#/$defs/hooks",
-			},
-			"after": {
-				Ref: "#/$defs/hooks",
-			},
-			"builds": {
-				Type:        "array",
-				Description: "Build configurations",
-				Items: &Schema{
-					Ref: "#/$defs/build",
-				},
-			},
-			"archives": {
-				Type:        "array",
-				Description: "Archive configurations",
-				Items: &Schema{
-					Ref: "#/$defs/archive",
-				},
-			},
-			"nfpms": {
-				Type:        "array",
-				Description: "Linux package configurations",
-				Items: &Schema{
-					Ref: "#/$defs/nfpm",
-				},
-			},
-			"checksum": {
-				Ref: "#/$defs/checksum",
-			},
-			"changelog": {
-				Ref: "#/$defs/changelog",
-			},
-			"release": {
-				Ref: "#/$defs/release",
-			},
-			"dockers": {
-				Type:        "array",
-				Description: "Docker image configurations",
-				Items: &Schema{
-					Ref: "#/$defs/docker",
-				},
-			},
-			"brews": {
-				Type:        "array",
-				Description: "Homebrew tap configurations",
-				Items: &Schema{
-					Ref: "#/$defs/brew",
-				},
-			},
-			"signs": {
-				Type:        "array",
-				Description: "Signing configurations",
-				Items: &Schema{
-					Ref: "#/$defs/sign",
-				},
-			},
-			"sboms": {
-				Type:        "array",
-				Description: "SBOM configurations",
-				Items: &Schema{
-					Ref: "#/$defs/sbom",
-				},
-			},
-			"announce": {
-				Ref: "#/$defs/announce",
-			},
-		},
-		Defs: map[string]*Schema{
-			"hooks": {
-				Type: "object",
-				Properties: map[string]*Schema{
-					"hooks": {
-						Type: "array",
-						Items: &Schema{
-							Type: "object",
-							Properties: map[string]*Schema{
-								"cmd": {Type: "string"},
-								"env": {
-									Type:  "array",
-									Items: &Schema{Type: "string"},
-								},
-								"dir": {Type: "string"},
-							},
-							Required: []string{"cmd"},
-						},
-					},
-				},
-			},
-			"build": {
-				Type: "object",
-				Properties: map[string]*Schema{
-					"id":      {Type: "string"},
-					"main":    {Type: "string"},
-					"binary":  {Type: "string"},
-					"dir":     {Type: "string"},
-					"builder": {Type: "string"},
-					"goos": {
-						Type:  "array",
-						Items: &Schema{Type: "string"},
-					},
-					"goarch": {
-						Type:  "array",
-						Items: &Schema{Type: "string"},
-					},
-					"goarm": {
-						Type:  "array",
-						Items: &Schema{Type: "string"},
-					},
-					"ldflags": {
-						Type:  "array",
-						Items: &Schema{Type: "string"},
-					},
-					"flags": {
-						Type:  "array",
-						Items: &Schema{Type: "string"},
-					},
-					"env": {
-						Type:  "array",
-						Items: &Schema{Type: "string"},
-					},
-					"install": {
-						Type: "array",
-						Items: &Schema{
-							Type: "object",
-							Properties: map[string]*Schema{
-								"type":      {Type: "string"},
-								"cmd":       {Type: "string"},
-								"env":       {Type: "object"},
-								"dir":       {Type: "string"},
-								"if":        {Type: "string"},
-								"fail_fast": {Type: "boolean"},
-								"shell":     {Type: "boolean"},
-								"output":    {Type: "string"},
-							},
-						},
-					},
-					"ignore": {
-						Type: "array",
-						Items: &Schema{
-							Type: "object",
-							Properties: map[string]*Schema{
-								"goos":   {Type: "string"},
-								"goarch": {Type: "string"},
-							},
-						},
-					},
-				},
-			},
-			"archive": {
-				Type: "object",
-				Properties: map[string]*Schema{
-					"id":               {Type: "string"},
-					"name_template":    {Type: "string"},
-					"format":           {Type: "string", Enum: []interface{}{"tar.gz", "tgz", "tar.xz", "txz", "tar.zst", "tar", "gz", "zip", "binary"}},
-					"format_overrides": {Type: "array"},
-					"files": {
-						Type:  "array",
-						Items: &Schema{Type: "string"},
-					},
-					"builds": {
-						Type:  "array",
-						Items: &Schema{Type: "string"},
-					},
-				},
-			},
-			"nfpm": {
-				Type: "object",
-				Properties: map[string]*Schema{
-					"id":           {Type: "string"},
-					"package_name": {Type: "string"},
-					"vendor":       {Type: "string"},
-					"homepage":     {Type: "string"},
-					"maintainer":   {Type: "string"},
-					"description":  {Type: "string"},
-					"license":      {Type: "string"},
-					"formats": {
-						Type:  "array",
-						Items: &Schema{Type: "string", Enum: []interface{}{"deb", "rpm", "apk", "archlinux"}},
-					},
-					"dependencies": {
-						Type:  "array",
-						Items: &Schema{Type: "string"},
-					},
-					"recommends": {
-						Type:  "array",
-						Items: &Schema{Type: "string"},
-					},
-					"suggests": {
-						Type:  "array",
-						Items: &Schema{Type: "string"},
-					},
-					"conflicts": {
-						Type:  "array",
-						Items: &Schema{Type: "string"},
-					},
-					"section":  {Type: "string"},
-					"priority": {Type: "string"},
-				},
-			},
-			"checksum": {
-				Type: "object",
-				Properties: map[string]*Schema{
-					"name_template": {Type: "string"},
-					"algorithm":     {Type: "string", Enum: []interface{}{"sha256", "sha512", "sha1", "md5", "sha384", "sha224"}},
-					"extra_files": {
-						Type:  "array",
-						Items: &Schema{Type: "object"},
-					},
-				},
-			},
-			"changelog": {
-				Type: "object",
-				Properties: map[string]*Schema{
-					"sort":    {Type: "string", Enum: []interface{}{"asc", "desc", ""}},
-					"use":     {Type: "string"},
-					"abbrev":  {Type: "integer"},
-					"filters": {Type: "object"},
-					"groups": {
-						Type: "array",
-						Items: &Schema{
-							Type: "object",
-							Properties: map[string]*Schema{
-								"title":  {Type: "string"},
-								"regexp": {Type: "string"},
-								"order":  {Type: "integer"},
-							},
-						},
-					},
-				},
-			},
-			"release": {
-				Type: "object",
-				Properties: map[string]*Schema{
-					"github": {
-						Type: "object",
-						Properties: map[string]*Schema{
-							"owner": {Type: "string"},
-							"name":  {Type: "string"},
-						},
-					},
-					"gitlab": {
-						Type: "object",
-						Properties: map[string]*Schema{
-							"owner": {Type: "string"},
-							"name":  {Type: "string"},
-						},
-					},
-					"gitea": {
-						Type: "object",
-						Properties: map[string]*Schema{
-							"owner": {Type: "string"},
-							"name":  {Type: "string"},
-						},
-					},
-					"draft":         {Type: "boolean"},
-					"prerelease":    {Type: "string"},
-					"make_latest":   {Type: "string"},
-					"name_template": {Type: "string"},
-					"disable":       {Type: "boolean"},
-					"skip_upload":   {Type: "boolean"},
-					"extra_files":   {Type: "array"},
-					"header":        {Type: "string"},
-					"footer":        {Type: "string"},
-				},
-			},
-			"docker": {
-				Type: "object",
-				Properties: map[string]*Schema{
-					"id":         {Type: "string"},
-					"goos":       {Type: "string"},
-					"goarch":     {Type: "string"},
-					"goarm":      {Type: "string"},
-					"dockerfile": {Type: "string"},
-					"image_templates": {
-						Type:  "array",
-						Items: &Schema{Type: "string"},
-					},
-					"build_flag_templates": {
-						Type:  "array",
-						Items: &Schema{Type: "string"},
-					},
-					"extra_files": {
-						Type:  "array",
-						Items: &Schema{Type: "string"},
-					},
-					"use":       {Type: "string"},
-					"skip_push": {Type: "string"},
-				},
-			},
-			"brew": {
-				Type: "object",
-				Properties: map[string]*Schema{
-					"name":        {Type: "string"},
-					"description": {Type: "string"},
-					"homepage":    {Type: "string"},
-					"license":     {Type: "string"},
-					"repository": {
-						Type: "object",
-						Properties: map[string]*Schema{
-							"owner":  {Type: "string"},
-							"name":   {Type: "string"},
-							"branch": {Type: "string"},
-							"token":  {Type: "string"},
-						},
-					},
-					"folder":      {Type: "string"},
-					"install":     {Type: "string"},
-					"test":        {Type: "string"},
-					"caveats":     {Type: "string"},
-					"skip_upload": {Type: "string"},
-				},
-			},
-			"sign": {
-				Type: "object",
-				Properties: map[string]*Schema{
-					"id":        {Type: "string"},
-					"cmd":       {Type: "string"},
-					"args":      {Type: "array", Items: &Schema{Type: "string"}},
-					"artifacts": {Type: "string"},
-					"signature": {Type: "string"},
-					"output":    {Type: "boolean"},
-				},
-			},
-			"sbom": {
-				Type: "object",
-				Properties: map[string]*Schema{
-					"id":        {Type: "string"},
-					"cmd":       {Type: "string"},
-					"args":      {Type: "array", Items: &Schema{Type: "string"}},
-					"artifacts": {Type: "string"},
-					"documents": {Type: "array", Items: &Schema{Type: "string"}},
-				},
-			},
-			"announce": {
-				Type: "object",
-				Properties: map[string]*Schema{
-					"twitter":  {Type: "object"},
-					"slack":    {Type: "object"},
-					"discord":  {Type: "object"},
-					"telegram": {Type: "object"},
-					"webhook":  {Type: "object"},
-				},
-			},
-		},
-	}
-}
-
 // ValidateConfig validates a releaser configuration file
 func ValidateConfig(path string) *ValidationResult {
 	schema := GenerateSchema()
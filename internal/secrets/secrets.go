@@ -0,0 +1,269 @@
+/*
+Package secrets resolves secret references in config values, so credentials
+can stay in a keychain or secret manager instead of environment variables.
+
+A reference is a whole config value, or the value of a KEY=VALUE env entry,
+of the form scheme://path:
+
+	keyring://service/user   OS keyring (macOS Keychain, Secret Service, Windows Credential Manager)
+	op://vault/item/field    1Password CLI
+	pass://path/to/entry     pass, first line of the entry
+	aws-sm://name#key        AWS Secrets Manager, optionally one key of a JSON secret
+
+Resolved values are registered with the redact package, so they never show
+up in output.
+*/
+package secrets
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os/exec"
+	"reflect"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/zalando/go-keyring"
+
+	"github.com/oarkflow/releaser/internal/redact"
+)
+
+// Provider fetches the secret a reference points at. path is the reference
+// without its scheme.
+type Provider interface {
+	Fetch(ctx context.Context, path string) (string, error)
+}
+
+// ProviderFunc adapts a function to Provider
+type ProviderFunc func(ctx context.Context, path string) (string, error)
+
+// Fetch implements Provider
+func (f ProviderFunc) Fetch(ctx context.Context, path string) (string, error) {
+	return f(ctx, path)
+}
+
+var (
+	mu        sync.Mutex
+	providers = map[string]Provider{
+		"keyring": ProviderFunc(fetchKeyring),
+		"op":      ProviderFunc(fetchOnePassword),
+		"pass":    ProviderFunc(fetchPass),
+		"aws-sm":  ProviderFunc(fetchAWS),
+	}
+	// resolved caches values by reference, so each is fetched once
+	resolved = make(map[string]string)
+)
+
+// envEntryRe matches KEY=VALUE env entries
+var envEntryRe = regexp.MustCompile(`^([A-Za-z_][A-Za-z0-9_]*)=(.*)$`)
+
+// Register adds a provider for a scheme, replacing any existing one
+func Register(scheme string, p Provider) {
+	mu.Lock()
+	defer mu.Unlock()
+	providers[scheme] = p
+}
+
+// IsReference reports whether value is a reference to a registered provider
+func IsReference(value string) bool {
+	scheme, _, ok := strings.Cut(value, "://")
+	if !ok {
+		return false
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	_, ok = providers[scheme]
+	return ok
+}
+
+// Resolve returns the secret ref points at. Errors name the reference but
+// never carry any part of the secret.
+func Resolve(ctx context.Context, ref string) (string, error) {
+	scheme, path, _ := strings.Cut(ref, "://")
+
+	mu.Lock()
+	if value, ok := resolved[ref]; ok {
+		mu.Unlock()
+		return value, nil
+	}
+	provider, ok := providers[scheme]
+	mu.Unlock()
+	if !ok {
+		return "", fmt.Errorf("secret %s: unknown scheme %q", ref, scheme)
+	}
+	if path == "" {
+		return "", fmt.Errorf("secret %s: empty path", ref)
+	}
+
+	value, err := provider.Fetch(ctx, path)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve secret %s: %w", ref, err)
+	}
+	if value == "" {
+		return "", fmt.Errorf("failed to resolve secret %s: secret is empty", ref)
+	}
+	redact.Add(value)
+
+	mu.Lock()
+	resolved[ref] = value
+	mu.Unlock()
+	return value, nil
+}
+
+// ResolveAll replaces every reference in the string fields, slices and maps
+// reachable from v, which must be a pointer. KEY=VALUE strings, like env
+// entries, have their value resolved.
+func ResolveAll(ctx context.Context, v interface{}) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Pointer || rv.IsNil() {
+		return errors.New("secrets: ResolveAll needs a non-nil pointer")
+	}
+	return resolveValue(ctx, rv.Elem())
+}
+
+func resolveValue(ctx context.Context, v reflect.Value) error {
+	switch v.Kind() {
+	case reflect.Pointer, reflect.Interface:
+		if v.IsNil() {
+			return nil
+		}
+		if v.Kind() == reflect.Interface {
+			// Values inside interfaces are not settable; resolve a copy
+			elem := reflect.New(v.Elem().Type()).Elem()
+			elem.Set(v.Elem())
+			if err := resolveValue(ctx, elem); err != nil {
+				return err
+			}
+			if v.CanSet() {
+				v.Set(elem)
+			}
+			return nil
+		}
+		return resolveValue(ctx, v.Elem())
+	case reflect.Struct:
+		t := v.Type()
+		for i := 0; i < v.NumField(); i++ {
+			if !t.Field(i).IsExported() {
+				continue
+			}
+			if err := resolveValue(ctx, v.Field(i)); err != nil {
+				return err
+			}
+		}
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			if err := resolveValue(ctx, v.Index(i)); err != nil {
+				return err
+			}
+		}
+	case reflect.Map:
+		iter := v.MapRange()
+		for iter.Next() {
+			elem := reflect.New(iter.Value().Type()).Elem()
+			elem.Set(iter.Value())
+			if err := resolveValue(ctx, elem); err != nil {
+				return err
+			}
+			v.SetMapIndex(iter.Key(), elem)
+		}
+	case reflect.String:
+		value, changed, err := resolveString(ctx, v.String())
+		if err != nil {
+			return err
+		}
+		if changed && v.CanSet() {
+			v.SetString(value)
+		}
+	}
+	return nil
+}
+
+// resolveString resolves a reference, or the value of a KEY=reference entry
+func resolveString(ctx context.Context, s string) (string, bool, error) {
+	if IsReference(s) {
+		value, err := Resolve(ctx, s)
+		return value, err == nil, err
+	}
+	if m := envEntryRe.FindStringSubmatch(s); m != nil && IsReference(m[2]) {
+		value, err := Resolve(ctx, m[2])
+		if err != nil {
+			return "", false, err
+		}
+		return m[1] + "=" + value, true, nil
+	}
+	return s, false, nil
+}
+
+// fetchKeyring reads service/user from the OS keyring
+func fetchKeyring(_ context.Context, path string) (string, error) {
+	service, user, ok := strings.Cut(path, "/")
+	if !ok || service == "" || user == "" {
+		return "", errors.New("expected keyring://service/user")
+	}
+	value, err := keyring.Get(service, user)
+	if errors.Is(err, keyring.ErrNotFound) {
+		return "", fmt.Errorf("no keyring entry for service %q and user %q", service, user)
+	}
+	return value, err
+}
+
+// fetchOnePassword reads a secret with the 1Password CLI
+func fetchOnePassword(ctx context.Context, path string) (string, error) {
+	return run(ctx, "op", "read", "--no-newline", "op://"+path)
+}
+
+// fetchPass reads the password, the first line, of a pass entry
+func fetchPass(ctx context.Context, path string) (string, error) {
+	out, err := run(ctx, "pass", "show", path)
+	if err != nil {
+		return "", err
+	}
+	line, _, _ := strings.Cut(out, "\n")
+	return line, nil
+}
+
+// fetchAWS reads a secret from AWS Secrets Manager with the aws CLI, so
+// profiles, SSO and instance roles work as configured. name#key selects a
+// key of a JSON secret.
+func fetchAWS(ctx context.Context, path string) (string, error) {
+	name, key, hasKey := strings.Cut(path, "#")
+	out, err := run(ctx, "aws", "secretsmanager", "get-secret-value",
+		"--secret-id", name, "--query", "SecretString", "--output", "text")
+	if err != nil || !hasKey {
+		return out, err
+	}
+
+	var fields map[string]interface{}
+	// The decode error may quote the secret, so it is dropped
+	if json.Unmarshal([]byte(out), &fields) != nil {
+		return "", errors.New("secret is not a JSON object")
+	}
+	value, ok := fields[key].(string)
+	if !ok {
+		return "", fmt.Errorf("secret has no string key %q", key)
+	}
+	return value, nil
+}
+
+// run runs a provider CLI and returns its output without the trailing
+// newline. Failures report stderr, never stdout.
+func run(ctx context.Context, name string, args ...string) (string, error) {
+	if _, err := exec.LookPath(name); err != nil {
+		return "", fmt.Errorf("%s is not installed", name)
+	}
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return "", fmt.Errorf("%s: %s", name, msg)
+		}
+		return "", fmt.Errorf("%s: %w", name, err)
+	}
+	return strings.TrimRight(stdout.String(), "\r\n"), nil
+}
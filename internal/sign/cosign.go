@@ -0,0 +1,419 @@
+package sign
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/log"
+
+	"github.com/oarkflow/releaser/internal/artifact"
+	"github.com/oarkflow/releaser/internal/config"
+	"github.com/oarkflow/releaser/internal/github"
+	"github.com/oarkflow/releaser/internal/provenance"
+	"github.com/oarkflow/releaser/internal/redact"
+)
+
+// cosignPredicateType is the cosign alias for SLSA v1 provenance
+const cosignPredicateType = "slsaprovenance1"
+
+// WithProvenance sets the build inputs recorded in attestation predicates
+func (s *CosignSigner) WithProvenance(opts provenance.Options) *CosignSigner {
+	s.provenance = opts
+	return s
+}
+
+// SignBlobs signs the artifacts matched by a cosigns entry with
+// `cosign sign-blob` and, when configured, attests them with a SLSA
+// provenance predicate. The signatures, certificates and attestations are
+// returned as artifacts.
+func (s *CosignSigner) SignBlobs(ctx context.Context, cfg config.Cosign, artifacts []artifact.Artifact, startedOn time.Time) ([]*artifact.Artifact, error) {
+	filter := cfg.Artifacts
+	if filter == "" {
+		filter = "archive"
+	}
+	toSign := filterArtifacts(config.Sign{Artifacts: filter, IDs: cfg.IDs}, artifacts)
+	if len(toSign) == 0 {
+		log.Debug("No artifacts to sign with cosign")
+		return nil, nil
+	}
+
+	var predicatePath string
+	if cfg.Attest {
+		path, err := s.writePredicate(startedOn)
+		if err != nil {
+			return nil, err
+		}
+		predicatePath = path
+	}
+
+	var result []*artifact.Artifact
+	for _, a := range toSign {
+		log.Info("Signing blob with cosign", "name", a.Name)
+
+		sigPath := a.Path + ".sig"
+		certPath := a.Path + ".pem"
+		args := []string{"sign-blob", "--output-signature", sigPath}
+		if isKeyless(cfg) {
+			args = append(args, "--output-certificate", certPath)
+		}
+		args = append(args, s.cosignArgs(cfg)...)
+		args = append(args, a.Path)
+
+		if err := s.runCosign(ctx, cfg, args); err != nil {
+			return nil, fmt.Errorf("cosign sign-blob failed for %s: %w", a.Name, err)
+		}
+		result = append(result, cosignArtifacts(a, artifact.TypeSignature, sigPath, certPath, isKeyless(cfg))...)
+
+		if predicatePath == "" {
+			continue
+		}
+
+		attPath := a.Path + ".intoto.sig"
+		attCertPath := a.Path + ".intoto.pem"
+		args = []string{"attest-blob", "--predicate", predicatePath, "--type", cosignPredicateType, "--output-signature", attPath}
+		if isKeyless(cfg) {
+			args = append(args, "--output-certificate", attCertPath)
+		}
+		args = append(args, s.cosignArgs(cfg)...)
+		args = append(args, a.Path)
+
+		if err := s.runCosign(ctx, cfg, args); err != nil {
+			return nil, fmt.Errorf("cosign attest-blob failed for %s: %w", a.Name, err)
+		}
+		result = append(result, cosignArtifacts(a, artifact.TypeAttestation, attPath, attCertPath, isKeyless(cfg))...)
+	}
+
+	return result, nil
+}
+
+// SignImages signs, and optionally attests, pushed container images. Images
+// default to the docker images built by the release.
+func (s *CosignSigner) SignImages(ctx context.Context, cfg config.Cosign, artifacts []artifact.Artifact, startedOn time.Time) error {
+	images, err := s.images(cfg, artifacts)
+	if err != nil {
+		return err
+	}
+	if len(images) == 0 {
+		return nil
+	}
+
+	var predicatePath string
+	if cfg.Attest {
+		path, err := s.writePredicate(startedOn)
+		if err != nil {
+			return err
+		}
+		predicatePath = path
+	}
+
+	for _, image := range images {
+		log.Info("Signing container image with cosign", "image", image)
+
+		args := append([]string{"sign"}, s.cosignArgs(cfg)...)
+		args = append(args, cfg.RegistryOptions...)
+		args = append(args, image)
+		if err := s.runCosign(ctx, cfg, args); err != nil {
+			return fmt.Errorf("cosign sign failed for %s: %w", image, err)
+		}
+
+		if predicatePath == "" {
+			continue
+		}
+
+		args = append([]string{"attest", "--predicate", predicatePath, "--type", cosignPredicateType}, s.cosignArgs(cfg)...)
+		args = append(args, cfg.RegistryOptions...)
+		args = append(args, image)
+		if err := s.runCosign(ctx, cfg, args); err != nil {
+			return fmt.Errorf("cosign attest failed for %s: %w", image, err)
+		}
+	}
+
+	return nil
+}
+
+// Preflight verifies that cosign can sign before the release spends time
+// building: the binary is installed, a key or OIDC identity is available and
+// the registries of configured images have credentials.
+func (s *CosignSigner) Preflight(cfg config.Cosign) error {
+	cmd := cfg.Cmd
+	if cmd == "" {
+		cmd = "cosign"
+	}
+	if _, err := exec.LookPath(cmd); err != nil {
+		return fmt.Errorf("%s not found in PATH: %w", cmd, err)
+	}
+
+	if isKeyless(cfg) {
+		token, err := s.tmplCtx.Apply("cosigns.identity_token", cfg.IdentityToken)
+		if err != nil {
+			return fmt.Errorf("failed to expand identity token: %w", err)
+		}
+		if token == "" && !hasAmbientOIDC() {
+			return fmt.Errorf("keyless signing needs an OIDC identity: set identity_token or SIGSTORE_ID_TOKEN, or run in a CI with id-token permissions")
+		}
+	} else if cfg.KeyRef != "" && !strings.Contains(cfg.KeyRef, "://") {
+		keyRef, err := s.tmplCtx.Apply("cosigns.key_ref", cfg.KeyRef)
+		if err != nil {
+			return fmt.Errorf("failed to expand key_ref: %w", err)
+		}
+		if _, err := os.Stat(keyRef); err != nil {
+			return fmt.Errorf("cosign key not found: %w", err)
+		}
+	}
+
+	for i, image := range cfg.Images {
+		ref, err := s.tmplCtx.Apply(fmt.Sprintf("cosigns.images[%d]", i), image)
+		if err != nil {
+			return fmt.Errorf("failed to expand image template: %w", err)
+		}
+		registry := imageRegistry(ref)
+		if !registryLoggedIn(registry) {
+			return fmt.Errorf("no credentials found for registry %s; run docker login or cosign login first", registry)
+		}
+	}
+
+	return nil
+}
+
+// cosignArgs returns the signing flags shared by all cosign commands
+func (s *CosignSigner) cosignArgs(cfg config.Cosign) []string {
+	args := []string{"--yes"}
+
+	if isKeyless(cfg) {
+		if cfg.OIDCIssuer != "" {
+			args = append(args, "--oidc-issuer", cfg.OIDCIssuer)
+		}
+		if cfg.OIDCClientID != "" {
+			args = append(args, "--oidc-client-id", cfg.OIDCClientID)
+		}
+		if cfg.FulcioURL != "" {
+			args = append(args, "--fulcio-url", cfg.FulcioURL)
+		}
+	} else if cfg.KeyRef != "" {
+		keyRef, err := s.tmplCtx.Apply("cosigns.key_ref", cfg.KeyRef)
+		if err != nil {
+			keyRef = cfg.KeyRef
+		}
+		args = append(args, "--key", keyRef)
+	}
+
+	if cfg.RekorURL != "" {
+		args = append(args, "--rekor-url", cfg.RekorURL)
+	}
+
+	return args
+}
+
+// runCosign runs cosign with the configured environment
+func (s *CosignSigner) runCosign(ctx context.Context, cfg config.Cosign, args []string) error {
+	cmd := cfg.Cmd
+	if cmd == "" {
+		cmd = "cosign"
+	}
+
+	env := os.Environ()
+	if isKeyless(cfg) {
+		env = append(env, "COSIGN_EXPERIMENTAL=1")
+		// The token is passed in the environment, where other processes
+		// cannot read it as they can the command line
+		token, err := s.idToken(ctx, cfg)
+		if err != nil {
+			return err
+		}
+		if token != "" {
+			redact.Add(token)
+			env = append(env, "SIGSTORE_ID_TOKEN="+token)
+		}
+	}
+	if cfg.Password != "" {
+		password, err := s.tmplCtx.Apply("cosigns.password", cfg.Password)
+		if err != nil {
+			return fmt.Errorf("failed to expand password: %w", err)
+		}
+		env = append(env, "COSIGN_PASSWORD="+password)
+	}
+	for i, e := range cfg.Env {
+		expanded, err := s.tmplCtx.Apply(fmt.Sprintf("cosigns.env[%d]", i), e)
+		if err != nil {
+			return fmt.Errorf("failed to expand env %s: %w", e, err)
+		}
+		env = append(env, expanded)
+	}
+
+	log.Debug("Running cosign", "args", redact.Args(args))
+	execCmd := exec.CommandContext(ctx, cmd, args...)
+	execCmd.Env = env
+
+	var stderr bytes.Buffer
+	execCmd.Stderr = &stderr
+	if err := execCmd.Run(); err != nil {
+		return fmt.Errorf("%w\nstderr: %s", err, stderr.String())
+	}
+	return nil
+}
+
+// images returns the image references to sign
+func (s *CosignSigner) images(cfg config.Cosign, artifacts []artifact.Artifact) ([]string, error) {
+	var images []string
+	for i, image := range cfg.Images {
+		expanded, err := s.tmplCtx.Apply(fmt.Sprintf("cosigns.images[%d]", i), image)
+		if err != nil {
+			return nil, fmt.Errorf("failed to expand image template: %w", err)
+		}
+		images = append(images, expanded)
+	}
+	if len(images) > 0 {
+		return images, nil
+	}
+
+	if cfg.Artifacts != "images" && cfg.Artifacts != "all" {
+		return nil, nil
+	}
+	for _, a := range artifacts {
+		if a.Type != artifact.TypeDockerImage {
+			continue
+		}
+		if image, ok := a.Extra["image"].(string); ok {
+			images = append(images, image)
+		}
+	}
+	return images, nil
+}
+
+// writePredicate writes the SLSA provenance predicate used for attestations
+func (s *CosignSigner) writePredicate(startedOn time.Time) (string, error) {
+	data, err := json.MarshalIndent(provenance.NewPredicate(s.tmplCtx, startedOn, s.provenance), "", "  ")
+	if err != nil {
+		return "", err
+	}
+	path := filepath.Join(s.distDir, "provenance.predicate.json")
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return "", fmt.Errorf("failed to write provenance predicate: %w", err)
+	}
+	return path, nil
+}
+
+// cosignArtifacts describes the signature and certificate cosign wrote for an artifact
+func cosignArtifacts(a artifact.Artifact, typ artifact.Type, sigPath, certPath string, keyless bool) []*artifact.Artifact {
+	extra := map[string]interface{}{"signed_artifact": a.Name}
+	result := []*artifact.Artifact{{
+		Name:    filepath.Base(sigPath),
+		Path:    sigPath,
+		Type:    typ,
+		Goos:    a.Goos,
+		Goarch:  a.Goarch,
+		BuildID: a.BuildID,
+		Extra:   extra,
+	}}
+	if keyless {
+		result = append(result, &artifact.Artifact{
+			Name:    filepath.Base(certPath),
+			Path:    certPath,
+			Type:    artifact.TypeCertificate,
+			Goos:    a.Goos,
+			Goarch:  a.Goarch,
+			BuildID: a.BuildID,
+			Extra:   extra,
+		})
+	}
+	return result
+}
+
+// isKeyless reports whether to sign keyless: when configured, or when no
+// key is set and the CI provides an OIDC identity
+func isKeyless(cfg config.Cosign) bool {
+	return cfg.Keyless || (cfg.KeyRef == "" && hasAmbientOIDC())
+}
+
+// idToken returns the OIDC token for cosign: identity_token when set,
+// otherwise the token of a GitHub Actions job unless SIGSTORE_ID_TOKEN
+// already provides one
+func (s *CosignSigner) idToken(ctx context.Context, cfg config.Cosign) (string, error) {
+	token, err := s.tmplCtx.Apply("cosigns.identity_token", cfg.IdentityToken)
+	if err != nil {
+		return "", fmt.Errorf("failed to expand identity token: %w", err)
+	}
+	if token != "" {
+		return token, nil
+	}
+	if os.Getenv("SIGSTORE_ID_TOKEN") != "" {
+		return "", nil
+	}
+	token, ok, err := github.ActionsIDToken(ctx, "sigstore")
+	if err != nil {
+		return "", fmt.Errorf("keyless signing: %w", err)
+	}
+	if ok {
+		log.Debug("Using the GitHub Actions OIDC token for keyless signing")
+	}
+	return token, nil
+}
+
+// hasAmbientOIDC reports whether cosign can obtain an identity token from
+// the environment without a browser
+func hasAmbientOIDC() bool {
+	for _, key := range []string{"SIGSTORE_ID_TOKEN", "ACTIONS_ID_TOKEN_REQUEST_URL"} {
+		if os.Getenv(key) != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// imageRegistry returns the registry host of an image reference
+func imageRegistry(ref string) string {
+	host, _, found := strings.Cut(ref, "/")
+	if !found || (!strings.ContainsAny(host, ".:") && host != "localhost") {
+		return "docker.io"
+	}
+	return host
+}
+
+// registryLoggedIn reports whether the docker config has credentials for the registry
+func registryLoggedIn(registry string) bool {
+	dir := os.Getenv("DOCKER_CONFIG")
+	if dir == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return false
+		}
+		dir = filepath.Join(home, ".docker")
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, "config.json"))
+	if err != nil {
+		return false
+	}
+
+	var dockerConfig struct {
+		Auths       map[string]json.RawMessage `json:"auths"`
+		CredHelpers map[string]string          `json:"credHelpers"`
+		CredsStore  string                     `json:"credsStore"`
+	}
+	if err := json.Unmarshal(data, &dockerConfig); err != nil {
+		return false
+	}
+
+	// A credential store may hold credentials for any registry
+	if dockerConfig.CredsStore != "" {
+		return true
+	}
+	if _, ok := dockerConfig.CredHelpers[registry]; ok {
+		return true
+	}
+	for key := range dockerConfig.Auths {
+		host := strings.TrimPrefix(strings.TrimPrefix(key, "https://"), "http://")
+		host, _, _ = strings.Cut(host, "/")
+		if host == registry || (registry == "docker.io" && host == "index.docker.io") {
+			return true
+		}
+	}
+	return false
+}
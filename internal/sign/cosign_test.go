@@ -0,0 +1,55 @@
+package sign
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/oarkflow/releaser/internal/artifact"
+	"github.com/oarkflow/releaser/internal/config"
+	"github.com/oarkflow/releaser/internal/git"
+	"github.com/oarkflow/releaser/internal/tmpl"
+)
+
+func TestCosignIdentityTokenInEnvironment(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the fake cosign is a shell script")
+	}
+	t.Setenv("SIGSTORE_ID_TOKEN", "")
+	t.Setenv("ACTIONS_ID_TOKEN_REQUEST_URL", "")
+
+	dir := t.TempDir()
+	record := filepath.Join(dir, "record")
+	cosign := filepath.Join(dir, "cosign")
+	script := "#!/bin/sh\necho \"args: $*\" >> " + record + "\necho \"token: $SIGSTORE_ID_TOKEN\" >> " + record + "\n"
+	if err := os.WriteFile(cosign, []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	archive := filepath.Join(dir, "demo.tar.gz")
+	if err := os.WriteFile(archive, []byte("demo"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := config.Cosign{Cmd: cosign, Keyless: true, IdentityToken: "secret-jwt"}
+	tmplCtx := tmpl.New(&config.Config{ProjectName: "demo"}, &git.Info{CurrentTag: "v1.2.3"}, false, false)
+	artifacts := []artifact.Artifact{{Name: "demo.tar.gz", Path: archive, Type: artifact.TypeArchive}}
+	if _, err := NewCosignSigner(dir, tmplCtx).SignBlobs(context.Background(), cfg, artifacts, time.Now()); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(record)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := string(data)
+	if !strings.Contains(got, "token: secret-jwt\n") {
+		t.Errorf("cosign ran without SIGSTORE_ID_TOKEN:\n%s", got)
+	}
+	if strings.Contains(got, "--identity-token") || strings.Count(got, "secret-jwt") != 1 {
+		t.Errorf("the identity token is on the command line:\n%s", got)
+	}
+}
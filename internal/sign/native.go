@@ -0,0 +1,365 @@
+package sign
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/blake2b"
+	"golang.org/x/crypto/scrypt"
+	"golang.org/x/crypto/ssh"
+
+	"github.com/oarkflow/releaser/internal/config"
+	"github.com/oarkflow/releaser/internal/tmpl"
+)
+
+// Built-in signing methods
+const (
+	MethodMinisign  = "minisign"
+	MethodSSH       = "ssh"
+	MethodGPGNative = "gpg-native"
+)
+
+// KeyError reports a private key that could not be read, parsed or
+// decrypted, as opposed to a failure signing with it
+type KeyError struct {
+	Method string
+	Err    error
+}
+
+func (e *KeyError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *KeyError) Unwrap() error {
+	return e.Err
+}
+
+// signatureExtension returns the default signature file extension for a method
+func signatureExtension(method string) string {
+	switch method {
+	case MethodMinisign:
+		return ".minisig"
+	case MethodGPGNative:
+		return ".asc"
+	}
+	return ".sig"
+}
+
+// signNative signs the file at path with a built-in signer and writes the
+// signature to sigPath
+func signNative(tmplCtx *tmpl.Context, cfg config.Sign, path, sigPath string) error {
+	key, err := loadSigningKey(tmplCtx, cfg)
+	if err != nil {
+		return &KeyError{Method: cfg.Method, Err: err}
+	}
+
+	var password []byte
+	if cfg.PasswordEnv != "" {
+		password = []byte(os.Getenv(cfg.PasswordEnv))
+	}
+
+	var sig []byte
+	switch cfg.Method {
+	case MethodMinisign:
+		sk, err := parseMinisignKey(key, password)
+		if err != nil {
+			return &KeyError{Method: cfg.Method, Err: err}
+		}
+		sig, err = sk.sign(path)
+		if err != nil {
+			return err
+		}
+	case MethodSSH:
+		namespace := cfg.Namespace
+		if namespace == "" {
+			namespace = "file"
+		}
+		signer, err := parseSSHKey(key, password)
+		if err != nil {
+			return &KeyError{Method: cfg.Method, Err: err}
+		}
+		sig, err = sshSign(signer, namespace, path)
+		if err != nil {
+			return err
+		}
+	case MethodGPGNative:
+		entity, err := parseOpenPGPKey(key, password)
+		if err != nil {
+			return &KeyError{Method: cfg.Method, Err: err}
+		}
+		sig, err = openPGPSign(entity, path)
+		if err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("unknown signing method: %s", cfg.Method)
+	}
+
+	return os.WriteFile(sigPath, sig, 0644)
+}
+
+// loadSigningKey reads the private key from key_env or the key file
+func loadSigningKey(tmplCtx *tmpl.Context, cfg config.Sign) ([]byte, error) {
+	if cfg.KeyEnv != "" {
+		if value := os.Getenv(cfg.KeyEnv); value != "" {
+			return []byte(value), nil
+		}
+		if cfg.Key == "" {
+			return nil, fmt.Errorf("environment variable %s is empty", cfg.KeyEnv)
+		}
+	}
+	if cfg.Key == "" {
+		return nil, fmt.Errorf("signs.key or signs.key_env is required for %s signing", cfg.Method)
+	}
+
+	keyPath, err := tmplCtx.Apply("signs.key", cfg.Key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to expand key path: %w", err)
+	}
+	if strings.HasPrefix(keyPath, "~/") {
+		if home, err := os.UserHomeDir(); err == nil {
+			keyPath = filepath.Join(home, keyPath[2:])
+		}
+	}
+
+	data, err := os.ReadFile(keyPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read signing key: %w", err)
+	}
+	return data, nil
+}
+
+// minisignKey is a decoded minisign secret key
+type minisignKey struct {
+	keyID      [8]byte
+	privateKey ed25519.PrivateKey
+}
+
+// parseMinisignKey decodes a minisign secret key file, decrypting it with
+// the password when the key is protected
+func parseMinisignKey(data, password []byte) (*minisignKey, error) {
+	// The key is the first non-comment line; accept a bare base64 value too
+	var encoded string
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "untrusted comment:") {
+			continue
+		}
+		encoded = line
+		break
+	}
+
+	raw, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, fmt.Errorf("invalid minisign key encoding: %w", err)
+	}
+
+	// sig_alg(2) kdf_alg(2) cksum_alg(2) salt(32) opslimit(8) memlimit(8) keynum_sk(104)
+	if len(raw) != 158 {
+		return nil, fmt.Errorf("invalid minisign key length: %d", len(raw))
+	}
+	if string(raw[0:2]) != "Ed" {
+		return nil, fmt.Errorf("unsupported minisign signature algorithm: %q", raw[0:2])
+	}
+	if string(raw[4:6]) != "B2" {
+		return nil, fmt.Errorf("unsupported minisign checksum algorithm: %q", raw[4:6])
+	}
+
+	keynum := make([]byte, 104)
+	copy(keynum, raw[54:158])
+
+	switch string(raw[2:4]) {
+	case "Sc":
+		if len(password) == 0 {
+			return nil, errors.New("minisign key is encrypted; set signs.password_env")
+		}
+		salt := raw[6:38]
+		opslimit := binary.LittleEndian.Uint64(raw[38:46])
+		memlimit := binary.LittleEndian.Uint64(raw[46:54])
+		n, r, p := scryptParams(opslimit, memlimit)
+		stream, err := scrypt.Key(password, salt, n, r, p, len(keynum))
+		if err != nil {
+			return nil, fmt.Errorf("failed to derive minisign key: %w", err)
+		}
+		for i := range keynum {
+			keynum[i] ^= stream[i]
+		}
+	case "\x00\x00":
+		// Unencrypted key
+	default:
+		return nil, fmt.Errorf("unsupported minisign key derivation: %q", raw[2:4])
+	}
+
+	key := &minisignKey{privateKey: ed25519.PrivateKey(keynum[8:72])}
+	copy(key.keyID[:], keynum[0:8])
+
+	// The checksum covers the algorithm, key id and secret key
+	h, _ := blake2b.New256(nil)
+	h.Write(raw[0:2])
+	h.Write(keynum[0:72])
+	if !bytes.Equal(h.Sum(nil), keynum[72:104]) {
+		return nil, errors.New("minisign key checksum mismatch; wrong password?")
+	}
+
+	return key, nil
+}
+
+// scryptParams converts libsodium opslimit/memlimit values into scrypt
+// parameters the same way crypto_pwhash_scryptsalsa208sha256 does
+func scryptParams(opslimit, memlimit uint64) (n, r, p int) {
+	if opslimit < 32768 {
+		opslimit = 32768
+	}
+	r = 8
+
+	var nLog2 uint
+	if opslimit < memlimit/32 {
+		p = 1
+		maxN := opslimit / uint64(r*4)
+		for nLog2 = 1; nLog2 < 63; nLog2++ {
+			if uint64(1)<<nLog2 > maxN/2 {
+				break
+			}
+		}
+	} else {
+		maxN := memlimit / uint64(r*128)
+		for nLog2 = 1; nLog2 < 63; nLog2++ {
+			if uint64(1)<<nLog2 > maxN/2 {
+				break
+			}
+		}
+		maxrp := (opslimit / 4) / (uint64(1) << nLog2)
+		if maxrp > 0x3fffffff {
+			maxrp = 0x3fffffff
+		}
+		p = int(maxrp) / r
+	}
+
+	return 1 << nLog2, r, p
+}
+
+// sign produces a prehashed minisign signature for the file
+func (k *minisignKey) sign(path string) ([]byte, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	h, _ := blake2b.New512(nil)
+	if _, err := io.Copy(h, f); err != nil {
+		return nil, err
+	}
+
+	signature := ed25519.Sign(k.privateKey, h.Sum(nil))
+
+	sigBlob := make([]byte, 0, 74)
+	sigBlob = append(sigBlob, 'E', 'D')
+	sigBlob = append(sigBlob, k.keyID[:]...)
+	sigBlob = append(sigBlob, signature...)
+
+	trusted := fmt.Sprintf("timestamp:%d\tfile:%s\thashed", time.Now().Unix(), filepath.Base(path))
+	global := ed25519.Sign(k.privateKey, append(append([]byte{}, signature...), trusted...))
+
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "untrusted comment: signature from minisign secret key\n")
+	fmt.Fprintf(&b, "%s\n", base64.StdEncoding.EncodeToString(sigBlob))
+	fmt.Fprintf(&b, "trusted comment: %s\n", trusted)
+	fmt.Fprintf(&b, "%s\n", base64.StdEncoding.EncodeToString(global))
+	return b.Bytes(), nil
+}
+
+// parseSSHKey decodes an OpenSSH or PEM private key, decrypting it with
+// the password when one is set
+func parseSSHKey(key, password []byte) (ssh.Signer, error) {
+	var signer ssh.Signer
+	var err error
+	if len(password) > 0 {
+		signer, err = ssh.ParsePrivateKeyWithPassphrase(key, password)
+	} else {
+		signer, err = ssh.ParsePrivateKey(key)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse SSH private key: %w", err)
+	}
+	return signer, nil
+}
+
+// sshSign produces an armored SSHSIG signature verifiable with
+// ssh-keygen -Y verify
+func sshSign(signer ssh.Signer, namespace, path string) ([]byte, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	h := sha512.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return nil, err
+	}
+
+	const hashAlgorithm = "sha512"
+	signedData := ssh.Marshal(struct {
+		Magic     [6]byte
+		Namespace string
+		Reserved  string
+		Hash      string
+		Digest    string
+	}{
+		Magic:     [6]byte{'S', 'S', 'H', 'S', 'I', 'G'},
+		Namespace: namespace,
+		Hash:      hashAlgorithm,
+		Digest:    string(h.Sum(nil)),
+	})
+
+	var sig *ssh.Signature
+	if algSigner, ok := signer.(ssh.AlgorithmSigner); ok && signer.PublicKey().Type() == ssh.KeyAlgoRSA {
+		// SSHSIG requires SHA-2 for RSA keys
+		sig, err = algSigner.SignWithAlgorithm(rand.Reader, signedData, ssh.KeyAlgoRSASHA512)
+	} else {
+		sig, err = signer.Sign(rand.Reader, signedData)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign: %w", err)
+	}
+
+	blob := ssh.Marshal(struct {
+		Magic     [6]byte
+		Version   uint32
+		PublicKey string
+		Namespace string
+		Reserved  string
+		Hash      string
+		Signature string
+	}{
+		Magic:     [6]byte{'S', 'S', 'H', 'S', 'I', 'G'},
+		Version:   1,
+		PublicKey: string(signer.PublicKey().Marshal()),
+		Namespace: namespace,
+		Hash:      hashAlgorithm,
+		Signature: string(ssh.Marshal(sig)),
+	})
+
+	encoded := base64.StdEncoding.EncodeToString(blob)
+	var b strings.Builder
+	b.WriteString(sshSignatureArmor + "\n")
+	for len(encoded) > 70 {
+		b.WriteString(encoded[:70] + "\n")
+		encoded = encoded[70:]
+	}
+	b.WriteString(encoded + "\n")
+	b.WriteString("-----END SSH SIGNATURE-----\n")
+	return []byte(b.String()), nil
+}
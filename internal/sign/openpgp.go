@@ -0,0 +1,92 @@
+package sign
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+)
+
+// parseOpenPGPKey decodes an armored or binary OpenPGP private key, such
+// as the output of gpg --export-secret-keys, decrypting it with the
+// password when the key is protected
+func parseOpenPGPKey(data, password []byte) (*openpgp.Entity, error) {
+	entities, err := openpgp.ReadArmoredKeyRing(bytes.NewReader(data))
+	if err != nil {
+		entities, err = openpgp.ReadKeyRing(bytes.NewReader(data))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse OpenPGP private key: %w", err)
+	}
+
+	for _, entity := range entities {
+		if entity.PrivateKey == nil {
+			continue
+		}
+		if entity.PrivateKey.Encrypted {
+			if len(password) == 0 {
+				return nil, errors.New("OpenPGP key is encrypted; set signs.password_env")
+			}
+			if err := entity.DecryptPrivateKeys(password); err != nil {
+				return nil, fmt.Errorf("failed to decrypt OpenPGP key; wrong password? %w", err)
+			}
+		}
+		return entity, nil
+	}
+	return nil, errors.New("no OpenPGP private key found; export it with gpg --armor --export-secret-keys")
+}
+
+// openPGPSign produces an armored detached signature of the file, as
+// gpg --detach-sign --armor does
+func openPGPSign(entity *openpgp.Entity, path string) ([]byte, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var b bytes.Buffer
+	if err := openpgp.ArmoredDetachSign(&b, entity, f, nil); err != nil {
+		return nil, fmt.Errorf("failed to sign: %w", err)
+	}
+	b.WriteString("\n")
+	return b.Bytes(), nil
+}
+
+// verifyOpenPGP checks a detached signature, armored or binary, against the
+// public keys at keyPath
+func verifyOpenPGP(keyPath, path, sigPath string) error {
+	data, err := os.ReadFile(keyPath)
+	if err != nil {
+		return fmt.Errorf("failed to read public key: %w", err)
+	}
+	keyring, err := openpgp.ReadArmoredKeyRing(bytes.NewReader(data))
+	if err != nil {
+		keyring, err = openpgp.ReadKeyRing(bytes.NewReader(data))
+	}
+	if err != nil {
+		return fmt.Errorf("failed to parse OpenPGP public key: %w", err)
+	}
+
+	sig, err := os.ReadFile(sigPath)
+	if err != nil {
+		return err
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	if bytes.HasPrefix(bytes.TrimSpace(sig), []byte("-----BEGIN PGP")) {
+		_, err = openpgp.CheckArmoredDetachedSignature(keyring, f, bytes.NewReader(sig), nil)
+	} else {
+		_, err = openpgp.CheckDetachedSignature(keyring, f, bytes.NewReader(sig), nil)
+	}
+	if err != nil {
+		return fmt.Errorf("invalid OpenPGP signature: %w", err)
+	}
+	return nil
+}
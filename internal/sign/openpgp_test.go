@@ -0,0 +1,158 @@
+package sign
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/ProtonMail/go-crypto/openpgp/armor"
+
+	"github.com/oarkflow/releaser/internal/artifact"
+	"github.com/oarkflow/releaser/internal/config"
+	"github.com/oarkflow/releaser/internal/git"
+	"github.com/oarkflow/releaser/internal/tmpl"
+)
+
+// openPGPKey returns a new key and its armored private key, encrypted with
+// password unless it is empty
+func openPGPKey(t *testing.T, password string) (*openpgp.Entity, string) {
+	t.Helper()
+	entity, err := openpgp.NewEntity("Release Bot", "", "release@example.com", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if password != "" {
+		if err := entity.EncryptPrivateKeys([]byte(password), nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var b bytes.Buffer
+	w, err := armor.Encode(&b, openpgp.PrivateKeyType, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := entity.SerializePrivateWithoutSigning(w, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return entity, b.String()
+}
+
+// checksumsArtifact writes a checksums file to dist
+func checksumsArtifact(t *testing.T, dist string) artifact.Artifact {
+	t.Helper()
+	path := filepath.Join(dist, "checksums.txt")
+	if err := os.WriteFile(path, []byte("0123abcd  demo_linux_amd64.tar.gz\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return artifact.Artifact{Name: "checksums.txt", Path: path, Type: artifact.TypeChecksum}
+}
+
+func TestGPGNativeSignature(t *testing.T) {
+	entity, key := openPGPKey(t, "correct horse")
+	t.Setenv("GPG_KEY", key)
+	t.Setenv("GPG_PASSWORD", "correct horse")
+
+	dist := t.TempDir()
+	checksums := checksumsArtifact(t, dist)
+	archive := artifact.Artifact{Name: "demo.tar.gz", Path: filepath.Join(dist, "demo.tar.gz"), Type: artifact.TypeArchive}
+
+	tmplCtx := tmpl.New(&config.Config{ProjectName: "demo"}, &git.Info{CurrentTag: "v1.2.3"}, false, false)
+	cfg := config.Sign{ID: "gpg", Method: MethodGPGNative, KeyEnv: "GPG_KEY", PasswordEnv: "GPG_PASSWORD"}
+	signed, err := NewSigner(dist, tmplCtx).Sign(context.Background(), cfg, []artifact.Artifact{checksums, archive})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Only the checksums file is signed by default
+	if len(signed) != 1 {
+		t.Fatalf("signed %d artifacts, want 1", len(signed))
+	}
+	sig := signed[0]
+	if sig.Type != artifact.TypeSignature || sig.Path != checksums.Path+".asc" || sig.Name != "checksums.txt.asc" ||
+		sig.Extra["signed_artifact"] != "checksums.txt" || sig.Extra["id"] != "gpg" {
+		t.Errorf("signature artifact = %+v", sig)
+	}
+
+	data, err := os.ReadFile(sig.Path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(string(data), "-----BEGIN PGP SIGNATURE-----") {
+		t.Errorf("signature is not armored:\n%s", data)
+	}
+
+	verify := func() error {
+		f, err := os.Open(checksums.Path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer f.Close()
+		_, err = openpgp.CheckArmoredDetachedSignature(openpgp.EntityList{entity}, f, bytes.NewReader(data), nil)
+		return err
+	}
+	if err := verify(); err != nil {
+		t.Errorf("signature does not verify: %v", err)
+	}
+	if err := os.WriteFile(checksums.Path, []byte("tampered\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := verify(); err == nil {
+		t.Error("signature verifies a tampered file")
+	}
+}
+
+func TestGPGNativeKeyErrors(t *testing.T) {
+	_, encrypted := openPGPKey(t, "correct horse")
+	_, plain := openPGPKey(t, "")
+
+	tests := []struct {
+		name     string
+		key      string
+		password string
+		remove   bool
+		keyErr   bool
+		want     string
+	}{
+		{name: "not a key", key: "garbage", keyErr: true, want: "failed to parse OpenPGP private key"},
+		{name: "missing password", key: encrypted, keyErr: true, want: "set signs.password_env"},
+		{name: "wrong password", key: encrypted, password: "battery staple", keyErr: true, want: "wrong password"},
+		{name: "unreadable artifact", key: plain, remove: true, want: "failed to sign checksums.txt"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("GPG_KEY", tt.key)
+			t.Setenv("GPG_PASSWORD", tt.password)
+
+			dist := t.TempDir()
+			checksums := checksumsArtifact(t, dist)
+			if tt.remove {
+				os.Remove(checksums.Path)
+			}
+
+			tmplCtx := tmpl.New(&config.Config{ProjectName: "demo"}, &git.Info{CurrentTag: "v1.2.3"}, false, false)
+			cfg := config.Sign{Method: MethodGPGNative, KeyEnv: "GPG_KEY", PasswordEnv: "GPG_PASSWORD"}
+			_, err := NewSigner(dist, tmplCtx).Sign(context.Background(), cfg, []artifact.Artifact{checksums})
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("Sign() = %v, want %q", err, tt.want)
+			}
+
+			var keyErr *KeyError
+			if errors.As(err, &keyErr) != tt.keyErr {
+				t.Errorf("Sign() = %v, key error %v, want %v", err, !tt.keyErr, tt.keyErr)
+			}
+			if tt.keyErr && !strings.HasPrefix(err.Error(), "failed to load gpg-native signing key") {
+				t.Errorf("key error blamed on an artifact: %v", err)
+			}
+		})
+	}
+}
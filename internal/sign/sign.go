@@ -6,6 +6,7 @@ package sign
 import (
 	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"os"
 	"os/exec"
@@ -16,6 +17,8 @@ import (
 
 	"github.com/oarkflow/releaser/internal/artifact"
 	"github.com/oarkflow/releaser/internal/config"
+	"github.com/oarkflow/releaser/internal/provenance"
+	"github.com/oarkflow/releaser/internal/redact"
 	"github.com/oarkflow/releaser/internal/tmpl"
 )
 
@@ -38,7 +41,7 @@ func (s *Signer) Sign(ctx context.Context, cfg config.Sign, artifacts []artifact
 	var signed []*artifact.Artifact
 
 	// Filter artifacts based on configuration
-	toSign := s.filterArtifacts(cfg, artifacts)
+	toSign := filterArtifacts(cfg, artifacts)
 	if len(toSign) == 0 {
 		log.Debug("No artifacts to sign")
 		return nil, nil
@@ -47,10 +50,29 @@ func (s *Signer) Sign(ctx context.Context, cfg config.Sign, artifacts []artifact
 	for _, a := range toSign {
 		sig, err := s.signArtifact(ctx, cfg, a)
 		if err != nil {
+			// A bad key fails every artifact, so it isn't blamed on one
+			var keyErr *KeyError
+			if errors.As(err, &keyErr) {
+				return nil, fmt.Errorf("failed to load %s signing key: %w", keyErr.Method, err)
+			}
 			return nil, fmt.Errorf("failed to sign %s: %w", a.Name, err)
 		}
-		if sig != nil {
-			signed = append(signed, sig)
+		if sig == nil {
+			continue
+		}
+		signed = append(signed, sig)
+
+		// Register the certificate written alongside the signature
+		if certPath, ok := sig.Extra["certificate"].(string); ok {
+			signed = append(signed, &artifact.Artifact{
+				Name:    filepath.Base(certPath),
+				Path:    certPath,
+				Type:    artifact.TypeCertificate,
+				Goos:    sig.Goos,
+				Goarch:  sig.Goarch,
+				BuildID: sig.BuildID,
+				Extra:   map[string]interface{}{"signed_artifact": a.Name},
+			})
 		}
 	}
 
@@ -61,19 +83,33 @@ func (s *Signer) Sign(ctx context.Context, cfg config.Sign, artifacts []artifact
 func (s *Signer) signArtifact(ctx context.Context, cfg config.Sign, a artifact.Artifact) (*artifact.Artifact, error) {
 	log.Info("Signing artifact", "name", a.Name)
 
+	tmplCtx := s.tmplCtx.ForArtifact(a)
+
 	// Determine signature file path
-	sigPath := cfg.Signature
-	if sigPath == "" {
-		sigPath = a.Path + ".sig"
-	} else {
-		sigPath = filepath.Join(s.distDir, sigPath)
+	sigPath := a.Path + signatureExtension(cfg.Method)
+	if cfg.Signature != "" {
+		expanded, err := s.expandPath(tmplCtx, "signs.signature", cfg.Signature, a, "", "")
+		if err != nil {
+			return nil, fmt.Errorf("failed to expand signature template: %w", err)
+		}
+		sigPath = expanded
 	}
 
-	// Apply template to signature path
-	tmplCtx := s.tmplCtx.WithArtifact(a.Name, a.Goos, a.Goarch, a.Goarm, a.Goamd64)
-	sigPath, err := tmplCtx.Apply(sigPath)
-	if err != nil {
-		return nil, err
+	// Determine certificate file path
+	var certPath string
+	if cfg.Certificate != "" {
+		expanded, err := s.expandPath(tmplCtx, "signs.certificate", cfg.Certificate, a, sigPath, "")
+		if err != nil {
+			return nil, fmt.Errorf("failed to expand certificate template: %w", err)
+		}
+		certPath = expanded
+	}
+
+	if cfg.Method != "" && cfg.Method != "cmd" {
+		if err := signNative(tmplCtx, cfg, a.Path, sigPath); err != nil {
+			return nil, err
+		}
+		return signatureArtifact(cfg, a, sigPath, certPath), nil
 	}
 
 	// Determine signing command
@@ -91,39 +127,48 @@ func (s *Signer) signArtifact(ctx context.Context, cfg config.Sign, a artifact.A
 	// Expand argument templates
 	expandedArgs := make([]string, len(args))
 	for i, arg := range args {
-		expanded := arg
-		expanded = strings.ReplaceAll(expanded, "${artifact}", a.Path)
-		expanded = strings.ReplaceAll(expanded, "${signature}", sigPath)
-		expanded = strings.ReplaceAll(expanded, "${certificate}", cfg.Certificate)
-
-		// Apply template
-		expanded, err = tmplCtx.Apply(expanded)
+		expanded, err := expand(tmplCtx, fmt.Sprintf("signs.args[%d]", i), arg, a.Path, sigPath, certPath)
 		if err != nil {
 			return nil, fmt.Errorf("failed to expand arg %s: %w", arg, err)
 		}
 		expandedArgs[i] = expanded
 	}
 
-	// Prepare environment
+	// Prepare environment; entries without a value pass the variable
+	// through from the releaser environment
 	env := os.Environ()
-	for _, e := range cfg.Env {
-		expanded, err := tmplCtx.Apply(e)
+	for i, e := range cfg.Env {
+		if !strings.Contains(e, "=") {
+			if value, ok := os.LookupEnv(e); ok {
+				env = append(env, e+"="+value)
+			}
+			continue
+		}
+		expanded, err := tmplCtx.Apply(fmt.Sprintf("signs.env[%d]", i), e)
 		if err != nil {
-			return nil, fmt.Errorf("failed to expand env %s: %w", e, err)
+			return nil, fmt.Errorf("failed to expand env: %w", err)
 		}
 		env = append(env, expanded)
 	}
 
 	// Run signing command
-	log.Debug("Running sign command", "cmd", cmd, "args", expandedArgs)
+	log.Debug("Running sign command", "cmd", cmd, "args", redact.Args(expandedArgs))
 	execCmd := exec.CommandContext(ctx, cmd, expandedArgs...)
 	execCmd.Env = env
 
-	// Handle stdin
+	// Handle stdin, typically a passphrase
 	if cfg.Stdin != "" {
-		execCmd.Stdin = strings.NewReader(cfg.Stdin)
+		stdin, err := tmplCtx.Apply("signs.stdin", cfg.Stdin)
+		if err != nil {
+			return nil, fmt.Errorf("failed to expand stdin: %w", err)
+		}
+		execCmd.Stdin = strings.NewReader(stdin)
 	} else if cfg.StdinFile != "" {
-		stdinData, err := os.ReadFile(cfg.StdinFile)
+		stdinFile, err := tmplCtx.Apply("signs.stdin_file", cfg.StdinFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to expand stdin file: %w", err)
+		}
+		stdinData, err := os.ReadFile(stdinFile)
 		if err != nil {
 			return nil, fmt.Errorf("failed to read stdin file: %w", err)
 		}
@@ -143,56 +188,66 @@ func (s *Signer) signArtifact(ctx context.Context, cfg config.Sign, a artifact.A
 		return nil, fmt.Errorf("sign command failed: %w\n%s", err, stderr.String())
 	}
 
+	return signatureArtifact(cfg, a, sigPath, certPath), nil
+}
+
+// signatureArtifact describes the signature written for an artifact
+func signatureArtifact(cfg config.Sign, a artifact.Artifact, sigPath, certPath string) *artifact.Artifact {
+	extra := map[string]interface{}{
+		"signed_artifact": a.Name,
+	}
+	if cfg.ID != "" {
+		extra["id"] = cfg.ID
+	}
+	if certPath != "" {
+		if _, err := os.Stat(certPath); err == nil {
+			extra["certificate"] = certPath
+		}
+	}
+
 	return &artifact.Artifact{
-		Name:   filepath.Base(sigPath),
-		Path:   sigPath,
-		Type:   artifact.TypeSignature,
-		Goos:   a.Goos,
-		Goarch: a.Goarch,
-		Extra: map[string]interface{}{
-			"signed_artifact": a.Name,
-		},
-	}, nil
+		Name:    filepath.Base(sigPath),
+		Path:    sigPath,
+		Type:    artifact.TypeSignature,
+		Goos:    a.Goos,
+		Goarch:  a.Goarch,
+		BuildID: a.BuildID,
+		Extra:   extra,
+	}
 }
 
-// filterArtifacts filters artifacts based on signing configuration
-func (s *Signer) filterArtifacts(cfg config.Sign, artifacts []artifact.Artifact) []artifact.Artifact {
+// expandPath expands a signature or certificate name template into a path.
+// Relative results are placed in the dist directory.
+func (s *Signer) expandPath(tmplCtx *tmpl.Context, source, template string, a artifact.Artifact, sigPath, certPath string) (string, error) {
+	// ${artifact} in a name template refers to the artifact name, not its path
+	template = strings.ReplaceAll(template, "${artifact}", a.Name)
+	expanded, err := expand(tmplCtx, source, template, a.Path, sigPath, certPath)
+	if err != nil {
+		return "", err
+	}
+	if !filepath.IsAbs(expanded) {
+		expanded = filepath.Join(s.distDir, expanded)
+	}
+	return expanded, nil
+}
+
+// expand substitutes ${artifact}, ${signature} and ${certificate} and then
+// applies the template context
+func expand(tmplCtx *tmpl.Context, source, value, artifactPath, sigPath, certPath string) (string, error) {
+	value = strings.ReplaceAll(value, "${artifact}", artifactPath)
+	value = strings.ReplaceAll(value, "${signature}", sigPath)
+	value = strings.ReplaceAll(value, "${certificate}", certPath)
+	return tmplCtx.Apply(source, value)
+}
+
+// filterArtifacts filters artifacts based on signing configuration. Only the
+// checksum file is signed unless configured otherwise.
+func filterArtifacts(cfg config.Sign, artifacts []artifact.Artifact) []artifact.Artifact {
 	var result []artifact.Artifact
 
 	for _, a := range artifacts {
-		// Check artifact type filter
-		switch cfg.Artifacts {
-		case "all":
-			result = append(result, a)
-		case "checksum", "checksums":
-			if a.Type == artifact.TypeChecksum {
-				result = append(result, a)
-			}
-		case "source":
-			if a.Type == artifact.TypeSourceArchive {
-				result = append(result, a)
-			}
-		case "archive", "archives":
-			if a.Type == artifact.TypeArchive {
-				result = append(result, a)
-			}
-		case "binary", "binaries":
-			if a.Type == artifact.TypeBinary {
-				result = append(result, a)
-			}
-		case "package", "packages":
-			if a.Type == artifact.TypePackage || a.Type == artifact.TypeLinuxPackage {
-				result = append(result, a)
-			}
-		default:
-			// Default to archives and binaries
-			if a.Type == artifact.TypeArchive || a.Type == artifact.TypeBinary {
-				result = append(result, a)
-			}
-		}
-
-		// Check ID filter
-		if len(cfg.IDs) > 0 {
+		// Check ID filter; checksum files are not tied to a build
+		if len(cfg.IDs) > 0 && a.Type != artifact.TypeChecksum {
 			found := false
 			for _, id := range cfg.IDs {
 				if a.BuildID == id {
@@ -204,11 +259,57 @@ func (s *Signer) filterArtifacts(cfg config.Sign, artifacts []artifact.Artifact)
 				continue
 			}
 		}
+
+		matched, known := matchesArtifactFilter(cfg.Artifacts, a)
+		if !known {
+			// Warned once for the config entry rather than per artifact
+			log.Warn("Unknown signs artifacts filter, nothing will be signed", "artifacts", cfg.Artifacts)
+			return nil
+		}
+		if matched {
+			result = append(result, a)
+		}
 	}
 
 	return result
 }
 
+// matchesArtifactFilter reports whether the artifact matches the
+// signs[].artifacts value, and whether the value is known
+func matchesArtifactFilter(filter string, a artifact.Artifact) (matched, known bool) {
+	switch filter {
+	case "none":
+		return false, true
+	case "all":
+		switch a.Type {
+		case artifact.TypeSignature, artifact.TypeCertificate, artifact.TypeAttestation, artifact.TypeDockerImage, artifact.TypeDockerManifest, artifact.TypeDirectory, artifact.TypeObfuscationMap:
+			return false, true
+		}
+		return a.Path != "", true
+	case "", "checksum", "checksums":
+		return a.Type == artifact.TypeChecksum, true
+	case "source":
+		return a.Type == artifact.TypeSourceArchive, true
+	case "archive", "archives":
+		return a.Type == artifact.TypeArchive, true
+	case "binary", "binaries":
+		return a.Type == artifact.TypeBinary || a.Type == artifact.TypeUniversalBinary, true
+	case "package", "packages":
+		switch a.Type {
+		case artifact.TypePackage, artifact.TypeLinuxPackage, artifact.TypeDMG, artifact.TypePKG,
+			artifact.TypeMSI, artifact.TypeNSIS, artifact.TypeAppImage, artifact.TypeSnap, artifact.TypeFlatpak:
+			return true, true
+		}
+		return false, true
+	case "sbom":
+		return a.Type == artifact.TypeSBOM, true
+	case "provenance":
+		return a.Type == artifact.TypeProvenance, true
+	default:
+		return false, false
+	}
+}
+
 // MacOSSigner provides macOS code signing
 type MacOSSigner struct {
 	distDir string
@@ -338,8 +439,9 @@ func (s *WindowsSigner) Sign(ctx context.Context, cert, password, timestampServe
 
 // CosignSigner provides cosign-based signing (including keyless with OIDC)
 type CosignSigner struct {
-	distDir string
-	tmplCtx *tmpl.Context
+	distDir    string
+	tmplCtx    *tmpl.Context
+	provenance provenance.Options
 }
 
 // NewCosignSigner creates a new cosign signer
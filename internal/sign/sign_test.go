@@ -0,0 +1,54 @@
+package sign
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/charmbracelet/log"
+
+	"github.com/oarkflow/releaser/internal/artifact"
+	"github.com/oarkflow/releaser/internal/config"
+)
+
+func TestFilterArtifacts(t *testing.T) {
+	artifacts := []artifact.Artifact{
+		{Name: "demo_linux_amd64.tar.gz", Path: "dist/demo_linux_amd64.tar.gz", Type: artifact.TypeArchive, BuildID: "cli"},
+		{Name: "demo_darwin_arm64.tar.gz", Path: "dist/demo_darwin_arm64.tar.gz", Type: artifact.TypeArchive, BuildID: "cli"},
+		{Name: "server_linux_amd64.tar.gz", Path: "dist/server_linux_amd64.tar.gz", Type: artifact.TypeArchive, BuildID: "server"},
+		{Name: "checksums.txt", Path: "dist/checksums.txt", Type: artifact.TypeChecksum},
+	}
+
+	tests := []struct {
+		name string
+		cfg  config.Sign
+		want []string
+		warn int
+	}{
+		{name: "checksums by default", want: []string{"checksums.txt"}},
+		{name: "archives of a build", cfg: config.Sign{Artifacts: "archive", IDs: []string{"cli"}}, want: []string{"demo_linux_amd64.tar.gz", "demo_darwin_arm64.tar.gz"}},
+		{name: "none", cfg: config.Sign{Artifacts: "none"}},
+		{name: "unknown filter", cfg: config.Sign{Artifacts: "archivez"}, warn: 1},
+	}
+
+	logger := log.Default()
+	t.Cleanup(func() { log.SetDefault(logger) })
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			log.SetDefault(log.New(&out))
+
+			var got []string
+			for _, a := range filterArtifacts(tt.cfg, artifacts) {
+				got = append(got, a.Name)
+			}
+			if strings.Join(got, " ") != strings.Join(tt.want, " ") {
+				t.Errorf("signed %v, want %v", got, tt.want)
+			}
+			if warnings := strings.Count(out.String(), "Unknown signs artifacts filter"); warnings != tt.warn {
+				t.Errorf("warned %d times, want %d:\n%s", warnings, tt.warn, out.String())
+			}
+		})
+	}
+}
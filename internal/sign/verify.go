@@ -0,0 +1,287 @@
+package sign
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+
+	"golang.org/x/crypto/blake2b"
+	"golang.org/x/crypto/ssh"
+)
+
+// Signature kinds verified by VerifySignature, besides MethodMinisign
+const (
+	MethodGPG    = "gpg"
+	MethodCosign = "cosign"
+)
+
+// SignatureExtensions are the signature file extensions looked up next to
+// a file to verify
+var SignatureExtensions = []string{".minisig", ".asc", ".sig"}
+
+// SignatureMethod tells which tool made a signature file: minisign for
+// .minisig, gpg for .asc and OpenPGP content, ssh for SSHSIG armor, cosign
+// for other .sig files
+func SignatureMethod(sigPath string) (string, error) {
+	if strings.HasSuffix(sigPath, ".minisig") {
+		return MethodMinisign, nil
+	}
+	if strings.HasSuffix(sigPath, ".asc") {
+		return MethodGPG, nil
+	}
+	data, err := os.ReadFile(sigPath)
+	if err != nil {
+		return "", err
+	}
+	// Armored or binary OpenPGP packets; cosign writes base64 text
+	if bytes.HasPrefix(data, []byte("-----BEGIN PGP")) || (len(data) > 0 && data[0]&0x80 != 0) {
+		return MethodGPG, nil
+	}
+	if bytes.HasPrefix(bytes.TrimSpace(data), []byte(sshSignatureArmor)) {
+		return MethodSSH, nil
+	}
+	return MethodCosign, nil
+}
+
+// VerifySignature checks the signature of the file at path with the public
+// key at keyPath. minisign and ssh signatures are verified natively, gpg
+// ones with gpg when it is installed and natively otherwise, and cosign ones
+// with cosign.
+func VerifySignature(ctx context.Context, method, keyPath, path, sigPath string) error {
+	switch method {
+	case MethodMinisign:
+		key, err := os.ReadFile(keyPath)
+		if err != nil {
+			return fmt.Errorf("failed to read public key: %w", err)
+		}
+		return verifyMinisign(key, path, sigPath)
+	case MethodSSH:
+		key, err := os.ReadFile(keyPath)
+		if err != nil {
+			return fmt.Errorf("failed to read public key: %w", err)
+		}
+		return verifySSH(key, path, sigPath)
+	case MethodGPG:
+		if _, err := exec.LookPath("gpg"); err != nil {
+			return verifyOpenPGP(keyPath, path, sigPath)
+		}
+		return verifyGPG(ctx, keyPath, path, sigPath)
+	case MethodCosign:
+		return runVerifier(ctx, "cosign", nil, "verify-blob", "--key", keyPath, "--signature", sigPath, path)
+	default:
+		return fmt.Errorf("unknown signature method: %s", method)
+	}
+}
+
+// verifyGPG imports the public key into a throwaway keyring, so the user's
+// trust settings play no part, and verifies the detached signature
+func verifyGPG(ctx context.Context, keyPath, path, sigPath string) error {
+	home, err := os.MkdirTemp("", "releaser-gpg-*")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(home)
+
+	env := append(os.Environ(), "GNUPGHOME="+home)
+	if err := runVerifier(ctx, "gpg", env, "--batch", "--quiet", "--import", keyPath); err != nil {
+		return err
+	}
+	return runVerifier(ctx, "gpg", env, "--batch", "--quiet", "--verify", sigPath, path)
+}
+
+// runVerifier runs a verification tool, returning its output on failure
+func runVerifier(ctx context.Context, name string, env []string, args ...string) error {
+	if _, err := exec.LookPath(name); err != nil {
+		return fmt.Errorf("%s is required to verify %s signatures", name, name)
+	}
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.Env = env
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("%s %s failed: %w\n%s", name, args[0], err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+// parseMinisignPublicKey decodes a minisign public key file into its key
+// id and ed25519 key
+func parseMinisignPublicKey(data []byte) ([8]byte, ed25519.PublicKey, error) {
+	var keyID [8]byte
+	raw, err := base64.StdEncoding.DecodeString(minisignLine(data, 0))
+	if err != nil {
+		return keyID, nil, fmt.Errorf("invalid minisign public key encoding: %w", err)
+	}
+	// sig_alg(2) keynum(8) public_key(32)
+	if len(raw) != 42 || string(raw[0:2]) != "Ed" {
+		return keyID, nil, fmt.Errorf("invalid minisign public key")
+	}
+	copy(keyID[:], raw[2:10])
+	return keyID, ed25519.PublicKey(raw[10:42]), nil
+}
+
+// verifyMinisign checks a minisign signature and its trusted comment
+func verifyMinisign(key []byte, path, sigPath string) error {
+	keyID, publicKey, err := parseMinisignPublicKey(key)
+	if err != nil {
+		return err
+	}
+	data, err := os.ReadFile(sigPath)
+	if err != nil {
+		return err
+	}
+
+	sigBlob, err := base64.StdEncoding.DecodeString(minisignLine(data, 0))
+	if err != nil || len(sigBlob) != 74 {
+		return fmt.Errorf("invalid minisign signature")
+	}
+	if !bytes.Equal(sigBlob[2:10], keyID[:]) {
+		return fmt.Errorf("signature was made with key %X, not %X", sigBlob[2:10], keyID[:])
+	}
+	signature := sigBlob[10:]
+
+	// ED signatures are over the blake2b hash, legacy Ed ones over the file
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	var message []byte
+	switch string(sigBlob[0:2]) {
+	case "ED":
+		h, _ := blake2b.New512(nil)
+		if _, err := io.Copy(h, f); err != nil {
+			return err
+		}
+		message = h.Sum(nil)
+	case "Ed":
+		if message, err = io.ReadAll(f); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("unsupported minisign signature algorithm: %q", sigBlob[0:2])
+	}
+	if !ed25519.Verify(publicKey, message, signature) {
+		return fmt.Errorf("signature does not match")
+	}
+
+	trusted := strings.TrimPrefix(minisignLine(data, 1), "trusted comment: ")
+	global, err := base64.StdEncoding.DecodeString(minisignLine(data, 2))
+	if err != nil {
+		return fmt.Errorf("invalid minisign global signature")
+	}
+	if !ed25519.Verify(publicKey, append(append([]byte{}, signature...), trusted...), global) {
+		return fmt.Errorf("trusted comment signature does not match")
+	}
+	return nil
+}
+
+// sshSignatureArmor starts the armored SSHSIG signatures sshSign writes
+const sshSignatureArmor = "-----BEGIN SSH SIGNATURE-----"
+
+// verifySSH checks an armored SSHSIG signature, as ssh-keygen -Y verify
+// does, against a public key in authorized_keys format. The signature's own
+// namespace is used, since the namespace releases sign with is configurable.
+func verifySSH(key []byte, path, sigPath string) error {
+	publicKey, _, _, _, err := ssh.ParseAuthorizedKey(key)
+	if err != nil {
+		return fmt.Errorf("invalid SSH public key: %w", err)
+	}
+	data, err := os.ReadFile(sigPath)
+	if err != nil {
+		return err
+	}
+
+	armored := strings.TrimSpace(string(data))
+	if !strings.HasPrefix(armored, sshSignatureArmor) || !strings.HasSuffix(armored, "-----END SSH SIGNATURE-----") {
+		return fmt.Errorf("invalid SSH signature armor")
+	}
+	armored = strings.TrimSuffix(strings.TrimPrefix(armored, sshSignatureArmor), "-----END SSH SIGNATURE-----")
+	raw, err := base64.StdEncoding.DecodeString(strings.Join(strings.Fields(armored), ""))
+	if err != nil {
+		return fmt.Errorf("invalid SSH signature encoding: %w", err)
+	}
+
+	var blob struct {
+		Magic     [6]byte
+		Version   uint32
+		PublicKey string
+		Namespace string
+		Reserved  string
+		Hash      string
+		Signature string
+	}
+	if err := ssh.Unmarshal(raw, &blob); err != nil || string(blob.Magic[:]) != "SSHSIG" {
+		return fmt.Errorf("invalid SSH signature")
+	}
+	if blob.Version != 1 {
+		return fmt.Errorf("unsupported SSH signature version %d", blob.Version)
+	}
+	if !bytes.Equal([]byte(blob.PublicKey), publicKey.Marshal()) {
+		return fmt.Errorf("signature was made with another key")
+	}
+	var sig ssh.Signature
+	if err := ssh.Unmarshal([]byte(blob.Signature), &sig); err != nil {
+		return fmt.Errorf("invalid SSH signature: %w", err)
+	}
+
+	var h hash.Hash
+	switch blob.Hash {
+	case "sha256":
+		h = sha256.New()
+	case "sha512":
+		h = sha512.New()
+	default:
+		return fmt.Errorf("unsupported SSH signature hash %q", blob.Hash)
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if _, err := io.Copy(h, f); err != nil {
+		return err
+	}
+
+	signedData := ssh.Marshal(struct {
+		Magic     [6]byte
+		Namespace string
+		Reserved  string
+		Hash      string
+		Digest    string
+	}{
+		Magic:     blob.Magic,
+		Namespace: blob.Namespace,
+		Reserved:  blob.Reserved,
+		Hash:      blob.Hash,
+		Digest:    string(h.Sum(nil)),
+	})
+	if err := publicKey.Verify(signedData, &sig); err != nil {
+		return fmt.Errorf("signature does not match")
+	}
+	return nil
+}
+
+// minisignLine returns the n-th line of a minisign file after the
+// untrusted comment
+func minisignLine(data []byte, n int) string {
+	var lines []string
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "untrusted comment:") {
+			continue
+		}
+		lines = append(lines, line)
+	}
+	if n < len(lines) {
+		return lines[n]
+	}
+	return ""
+}
@@ -0,0 +1,129 @@
+package sign
+
+import (
+	"context"
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// writeSSHSignature signs path with key as the ssh method does and returns
+// the signature path
+func writeSSHSignature(t *testing.T, key crypto.Signer, path string) string {
+	t.Helper()
+	signer, err := ssh.NewSignerFromSigner(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sig, err := sshSign(signer, "file", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sigPath := path + ".sig"
+	if err := os.WriteFile(sigPath, sig, 0644); err != nil {
+		t.Fatal(err)
+	}
+	return sigPath
+}
+
+// writeAuthorizedKey writes the public key of key in authorized_keys format
+func writeAuthorizedKey(t *testing.T, key crypto.Signer) string {
+	t.Helper()
+	publicKey, err := ssh.NewPublicKey(key.Public())
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyPath := filepath.Join(t.TempDir(), "key.pub")
+	if err := os.WriteFile(keyPath, ssh.MarshalAuthorizedKey(publicKey), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return keyPath
+}
+
+func TestVerifySSHSignature(t *testing.T) {
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, otherKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for name, key := range map[string]crypto.Signer{"ed25519": edKey, "rsa": rsaKey} {
+		t.Run(name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "demo.tar.gz")
+			if err := os.WriteFile(path, []byte("archive"), 0644); err != nil {
+				t.Fatal(err)
+			}
+			sigPath := writeSSHSignature(t, key, path)
+			keyPath := writeAuthorizedKey(t, key)
+
+			method, err := SignatureMethod(sigPath)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if method != MethodSSH {
+				t.Fatalf("SignatureMethod() = %s, want %s", method, MethodSSH)
+			}
+			if err := VerifySignature(context.Background(), method, keyPath, path, sigPath); err != nil {
+				t.Errorf("verifying a valid signature: %v", err)
+			}
+
+			// Another key does not verify it
+			if err := VerifySignature(context.Background(), method, writeAuthorizedKey(t, otherKey), path, sigPath); err == nil || !strings.Contains(err.Error(), "another key") {
+				t.Errorf("verifying with another key: %v", err)
+			}
+
+			// Nor does it verify a modified file
+			if err := os.WriteFile(path, []byte("modified"), 0644); err != nil {
+				t.Fatal(err)
+			}
+			if err := VerifySignature(context.Background(), method, keyPath, path, sigPath); err == nil || !strings.Contains(err.Error(), "does not match") {
+				t.Errorf("verifying a modified file: %v", err)
+			}
+		})
+	}
+}
+
+func TestSignatureMethod(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    string
+	}{
+		{name: "demo.tar.gz.minisig", content: "untrusted comment: minisign", want: MethodMinisign},
+		{name: "demo.tar.gz.asc", content: "-----BEGIN PGP SIGNATURE-----", want: MethodGPG},
+		{name: "demo.tar.gz.sig", content: "-----BEGIN PGP SIGNATURE-----\n", want: MethodGPG},
+		{name: "demo.tar.gz.sig", content: "\x89\x01\x33", want: MethodGPG},
+		{name: "demo.tar.gz.sig", content: "-----BEGIN SSH SIGNATURE-----\nU1NIU0lH\n-----END SSH SIGNATURE-----\n", want: MethodSSH},
+		{name: "demo.tar.gz.sig", content: "MEUCIQD0cosignsignature==", want: MethodCosign},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.want+"/"+tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), tt.name)
+			if err := os.WriteFile(path, []byte(tt.content), 0644); err != nil {
+				t.Fatal(err)
+			}
+			got, err := SignatureMethod(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("SignatureMethod() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,140 @@
+/*
+Package target provides the build target shared by the pipeline, the
+builders and the archive and package stages.
+*/
+package target
+
+import (
+	"fmt"
+	"runtime"
+	"strings"
+)
+
+// Target is an OS and architecture, with the goarm, goamd64 or gomips
+// variant of the architecture when one is selected
+type Target struct {
+	OS    string
+	Arch  string
+	Arm   string
+	Amd64 string
+	Mips  string
+}
+
+// goTargets are the GOOS/GOARCH pairs of go tool dist list
+var goTargets = map[string]bool{
+	"aix/ppc64": true, "android/386": true, "android/amd64": true, "android/arm": true,
+	"android/arm64": true, "darwin/amd64": true, "darwin/arm64": true, "dragonfly/amd64": true,
+	"freebsd/386": true, "freebsd/amd64": true, "freebsd/arm": true, "freebsd/arm64": true,
+	"freebsd/riscv64": true, "illumos/amd64": true, "ios/amd64": true, "ios/arm64": true,
+	"js/wasm": true, "linux/386": true, "linux/amd64": true, "linux/arm": true,
+	"linux/arm64": true, "linux/loong64": true, "linux/mips": true, "linux/mips64": true,
+	"linux/mips64le": true, "linux/mipsle": true, "linux/ppc64": true, "linux/ppc64le": true,
+	"linux/riscv64": true, "linux/s390x": true, "netbsd/386": true, "netbsd/amd64": true,
+	"netbsd/arm": true, "netbsd/arm64": true, "openbsd/386": true, "openbsd/amd64": true,
+	"openbsd/arm": true, "openbsd/arm64": true, "openbsd/ppc64": true, "openbsd/riscv64": true,
+	"plan9/386": true, "plan9/amd64": true, "plan9/arm": true, "solaris/amd64": true,
+	"wasip1/wasm": true, "windows/386": true, "windows/amd64": true, "windows/arm64": true,
+}
+
+// Valid reports whether Go can build for goos and goarch, so a goos and
+// goarch matrix can leave out pairs such as darwin/386
+func Valid(goos, goarch string) bool {
+	return goTargets[goos+"/"+goarch]
+}
+
+// Current returns the target of the running OS and architecture
+func Current() Target {
+	return Target{OS: runtime.GOOS, Arch: runtime.GOARCH}
+}
+
+// String returns the canonical name of the target, e.g. linux_amd64,
+// linux_arm_v7, linux_amd64_v3 or linux_mips_softfloat. It names dist
+// directories and cache entries.
+func (t Target) String() string {
+	return t.OS + "_" + t.Arch + t.Variant()
+}
+
+// Variant returns the variant part of the name, e.g. _v7, or an empty
+// string when no variant is selected
+func (t Target) Variant() string {
+	var s string
+	if t.Arm != "" {
+		s += "_v" + t.Arm
+	}
+	if t.Amd64 != "" {
+		s += "_" + t.Amd64
+	}
+	if t.Mips != "" {
+		s += "_" + t.Mips
+	}
+	return s
+}
+
+// Matches reports whether t is the target pattern names. A pattern without
+// a variant, e.g. linux_arm, matches every variant of its architecture.
+func (t Target) Matches(pattern Target) bool {
+	if t.OS != pattern.OS || t.Arch != pattern.Arch {
+		return false
+	}
+	return (pattern.Arm == "" || pattern.Arm == t.Arm) &&
+		(pattern.Amd64 == "" || pattern.Amd64 == t.Amd64) &&
+		(pattern.Mips == "" || pattern.Mips == t.Mips)
+}
+
+// IsWindows reports whether the target is Windows
+func (t Target) IsWindows() bool {
+	return t.OS == "windows"
+}
+
+// BinaryExt returns the extension of executables on the target
+func (t Target) BinaryExt() string {
+	if t.IsWindows() {
+		return ".exe"
+	}
+	return ""
+}
+
+// Parse parses a target name like linux_amd64, linux/arm/7, linux_arm_v7,
+// linux_armv7, linux_amd64_v3 or linux_mipsle_softfloat. The arm variant
+// may be written with or without its v.
+func Parse(s string) (Target, error) {
+	fields := strings.FieldsFunc(strings.ToLower(strings.TrimSpace(s)), func(r rune) bool {
+		return r == '_' || r == '/' || r == '-'
+	})
+	if len(fields) < 2 || len(fields) > 3 {
+		return Target{}, fmt.Errorf("invalid target %q, expected os_arch or os_arch_variant", s)
+	}
+
+	t := Target{OS: fields[0], Arch: fields[1]}
+	variant := ""
+	if len(fields) == 3 {
+		variant = fields[2]
+	}
+	// armv7 and armv6 name the arm variant in the architecture
+	if strings.HasPrefix(t.Arch, "armv") && variant == "" {
+		t.Arch, variant = "arm", strings.TrimPrefix(t.Arch, "arm")
+	}
+
+	if variant != "" {
+		switch t.Arch {
+		case "arm":
+			t.Arm = strings.TrimPrefix(variant, "v")
+			if t.Arm != "5" && t.Arm != "6" && t.Arm != "7" {
+				return Target{}, fmt.Errorf("invalid target %q: goarm must be 5, 6 or 7", s)
+			}
+		case "amd64":
+			t.Amd64 = "v" + strings.TrimPrefix(variant, "v")
+			if t.Amd64 < "v1" || t.Amd64 > "v4" || len(t.Amd64) != 2 {
+				return Target{}, fmt.Errorf("invalid target %q: goamd64 must be v1, v2, v3 or v4", s)
+			}
+		case "mips", "mipsle", "mips64", "mips64le":
+			if variant != "softfloat" && variant != "hardfloat" {
+				return Target{}, fmt.Errorf("invalid target %q: gomips must be softfloat or hardfloat", s)
+			}
+			t.Mips = variant
+		default:
+			return Target{}, fmt.Errorf("invalid target %q: %s has no variants", s, t.Arch)
+		}
+	}
+	return t, nil
+}
@@ -5,16 +5,26 @@ package tmpl
 
 import (
 	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
 	"os"
+	"reflect"
+	"regexp"
 	"runtime"
+	"sort"
+	"strconv"
 	"strings"
 	"text/template"
+	"text/template/parse"
 	"time"
 
 	"github.com/charmbracelet/log"
 
+	"github.com/oarkflow/releaser/internal/artifact"
 	"github.com/oarkflow/releaser/internal/config"
 	"github.com/oarkflow/releaser/internal/git"
+	"github.com/oarkflow/releaser/internal/target"
 )
 
 // Context provides template context and rendering
@@ -67,59 +77,52 @@ func (c *Context) init() {
 		c.data["Major"] = c.gitInfo.Major
 		c.data["Minor"] = c.gitInfo.Minor
 		c.data["Patch"] = c.gitInfo.Patch
+		c.data["LatestTag"] = c.gitInfo.LatestTag
+		c.data["CommitCount"] = c.gitInfo.CommitCount
 		c.data["Prerelease"] = c.gitInfo.PrereleaseSuffix
 		c.data["IsPrerelease"] = c.gitInfo.Prerelease
 		c.data["Metadata"] = c.gitInfo.Metadata
+		if c.gitInfo.CurrentTag != "" {
+			c.setSemver(c.gitInfo.CurrentTag)
+		}
 		c.data["Branch"] = c.gitInfo.Branch
 		c.data["Commit"] = c.gitInfo.Commit
 		c.data["ShortCommit"] = c.gitInfo.ShortCommit
 		c.data["FullCommit"] = c.gitInfo.Commit
-		c.data["CommitDate"] = c.gitInfo.CommitDate
+		c.data["CommitDate"] = ""
+		if !c.gitInfo.CommitDate.IsZero() {
+			c.data["CommitDate"] = c.gitInfo.CommitDate.UTC().Format(time.RFC3339)
+		}
 		c.data["CommitTimestamp"] = c.gitInfo.CommitTimestamp
 		c.data["GitURL"] = c.gitInfo.URL
 		c.data["Summary"] = c.gitInfo.Summary
 		c.data["TagSubject"] = c.gitInfo.TagSubject
 		c.data["TagBody"] = c.gitInfo.TagBody
 		c.data["TagContents"] = c.gitInfo.TagContents
+		c.data["GitTreeState"] = c.gitInfo.TreeState
+		c.data["IsGitDirty"] = c.gitInfo.TreeState == "dirty"
 	} else {
 		c.data["Version"] = "0.0.0-SNAPSHOT"
 		c.data["Tag"] = "v0.0.0-SNAPSHOT"
 		c.data["RawVersion"] = "v0.0.0-SNAPSHOT"
-	}
-
-	c.data["OriginalVersion"] = c.Get("Version")
-	c.data["OriginalRawVersion"] = c.Get("RawVersion")
-
-	if c.snapshot {
-		c.data["IsSnapshot"] = true
-
-		if version, ok := c.data["Version"].(string); ok && version != "" && version != "0.0.0-SNAPSHOT" {
-			if !strings.HasSuffix(version, "-SNAPSHOT") {
-				c.data["Version"] = version + "-SNAPSHOT"
-			}
-		} else {
-			c.data["Version"] = "0.0.0-SNAPSHOT"
+		for _, key := range []string{"Major", "Minor", "Patch", "CommitCount"} {
+			c.data[key] = 0
 		}
-
-		if rawVersion, ok := c.data["RawVersion"].(string); ok && rawVersion != "" && rawVersion != "v0.0.0-SNAPSHOT" {
-			if !strings.HasSuffix(rawVersion, "-SNAPSHOT") {
-				c.data["RawVersion"] = rawVersion + "-SNAPSHOT"
-			}
-		} else {
-			c.data["RawVersion"] = "v0.0.0-SNAPSHOT"
+		for _, key := range []string{"PreviousTag", "LatestTag", "Prerelease", "Metadata", "Branch", "Commit", "ShortCommit", "FullCommit", "CommitDate", "GitURL", "Summary", "TagSubject", "TagBody", "TagContents", "GitTreeState", "CommitTimestamp"} {
+			c.data[key] = ""
 		}
+		c.data["IsPrerelease"] = false
+		c.data["IsGitDirty"] = false
 	}
 
-	if c.nightly {
-		nightlyVersion := now.Format("20060102")
-		c.data["Version"] = nightlyVersion
-		c.data["RawVersion"] = nightlyVersion
-		c.data["IsNightly"] = true
-	}
+	c.data["OriginalVersion"] = c.Get("Version")
+	c.data["OriginalRawVersion"] = c.Get("RawVersion")
+	c.data["IsSnapshot"] = c.snapshot
+	c.data["IsNightly"] = c.nightly
 
-	c.applyVersionTemplates()
-	if _, ok := c.data["DisplayVersion"]; !ok {
-		c.data["DisplayVersion"] = c.Get("Version")
+	// Set by the pipeline once the release repository is known
+	for _, key := range []string{"ReleaseURL", "RepoOwner", "RepoName", "RepoURL", "ReleaseDownloadURL"} {
+		c.data[key] = ""
 	}
 
 	// Date/time
@@ -142,10 +145,14 @@ func (c *Context) init() {
 	}
 	c.data["Env"] = env
 
-	// Custom variables from config
+	// Custom variables from config, as .Var.name and, for compatibility,
+	// as top-level fields
+	vars := make(map[string]interface{}, len(c.config.Variables))
 	for k, v := range c.config.Variables {
+		vars[k] = v
 		c.data[k] = v
 	}
+	c.data["Var"] = vars
 
 	// Defaults from config
 	c.data["Homepage"] = c.config.Defaults.Homepage
@@ -153,14 +160,105 @@ func (c *Context) init() {
 	c.data["License"] = c.config.Defaults.License
 	c.data["Maintainer"] = c.config.Defaults.Maintainer
 	c.data["Vendor"] = c.config.Defaults.Vendor
+
+	// Versioning templates may use any of the above, so resolve it last
+	c.resolveVersion(now)
+}
+
+// resolveVersion applies the versioning templates to the tag-derived
+// version, then layers snapshot and nightly naming on top of the result.
+func (c *Context) resolveVersion(now time.Time) {
+	templated := c.applyVersionTemplates()
+
+	if c.snapshot {
+		if version := c.Get("Version"); version != "" && version != "0.0.0-SNAPSHOT" {
+			if !strings.Contains(version, "-SNAPSHOT") {
+				c.data["Version"] = withPrerelease(version, "SNAPSHOT")
+			}
+		} else {
+			c.data["Version"] = "0.0.0-SNAPSHOT"
+		}
+
+		if rawVersion := c.Get("RawVersion"); rawVersion != "" && rawVersion != "v0.0.0-SNAPSHOT" {
+			if !strings.Contains(rawVersion, "-SNAPSHOT") {
+				c.data["RawVersion"] = withPrerelease(rawVersion, "SNAPSHOT")
+			}
+		} else {
+			c.data["RawVersion"] = "v0.0.0-SNAPSHOT"
+		}
+	}
+
+	if c.nightly {
+		nightlyVersion := now.Format("20060102")
+		if templated {
+			// Keep the templated version and mark it as a nightly build
+			c.data["Version"] = withPrerelease(c.Get("Version"), "nightly."+nightlyVersion)
+			c.data["RawVersion"] = withPrerelease(c.Get("RawVersion"), "nightly."+nightlyVersion)
+		} else {
+			c.data["Version"] = nightlyVersion
+			c.data["RawVersion"] = nightlyVersion
+		}
+	}
+
+	c.data["DisplayVersion"] = c.Get("Version")
 }
 
-func (c *Context) applyVersionTemplates() {
+// semverRe matches a semantic version with an optional "v" prefix
+var semverRe = regexp.MustCompile(`^v?(\d+)\.(\d+)\.(\d+)(?:-([0-9A-Za-z.-]+))?(?:\+([0-9A-Za-z.-]+))?$`)
+
+// SetTag sets the tag being released and the version fields derived from
+// it: Version, RawVersion, Major, Minor, Patch, Prerelease, IsPrerelease
+// and Metadata.
+func (c *Context) SetTag(tag string) {
+	c.data["Tag"] = tag
+	c.data["RawVersion"] = tag
+	c.data["Version"] = strings.TrimPrefix(tag, "v")
+	c.setSemver(tag)
+}
+
+// setSemver sets the semver fields of a tag. Prerelease is the part after
+// "-", e.g. rc.1 for v1.3.0-rc.1, and IsPrerelease whether there is one;
+// tags that are not semver leave the fields unchanged.
+func (c *Context) setSemver(tag string) {
+	m := semverRe.FindStringSubmatch(tag)
+	if m == nil {
+		return
+	}
+	major, _ := strconv.Atoi(m[1])
+	minor, _ := strconv.Atoi(m[2])
+	patch, _ := strconv.Atoi(m[3])
+	c.data["Major"] = major
+	c.data["Minor"] = minor
+	c.data["Patch"] = patch
+	c.data["Prerelease"] = m[4]
+	c.data["IsPrerelease"] = m[4] != ""
+	c.data["Metadata"] = m[5]
+}
+
+// IsPrerelease reports whether the release is a semver prerelease
+func (c *Context) IsPrerelease() bool {
+	prerelease, _ := c.data["IsPrerelease"].(bool)
+	return prerelease
+}
+
+// withPrerelease appends a prerelease suffix to a version, keeping any
+// "+build" metadata last as semver requires
+func withPrerelease(version, suffix string) string {
+	core, metadata, found := strings.Cut(version, "+")
+	if !found {
+		return version + "-" + suffix
+	}
+	return core + "-" + suffix + "+" + metadata
+}
+
+// applyVersionTemplates renders versioning.template and raw_template over
+// Version and RawVersion, reporting whether either was set
+func (c *Context) applyVersionTemplates() bool {
 	versionTemplate := strings.TrimSpace(c.config.Versioning.Template)
 	rawTemplate := strings.TrimSpace(c.config.Versioning.RawTemplate)
 
 	if versionTemplate != "" {
-		if rendered, err := c.Apply(versionTemplate); err != nil {
+		if rendered, err := c.Apply("versioning.template", versionTemplate); err != nil {
 			log.Warn("failed to apply version template", "template", versionTemplate, "error", err)
 		} else {
 			c.data["Version"] = rendered
@@ -172,38 +270,208 @@ func (c *Context) applyVersionTemplates() {
 	}
 
 	if rawTemplate != "" {
-		if rendered, err := c.Apply(rawTemplate); err != nil {
+		if rendered, err := c.Apply("versioning.raw_template", rawTemplate); err != nil {
 			log.Warn("failed to apply raw version template", "template", rawTemplate, "error", err)
 		} else {
 			c.data["RawVersion"] = rendered
 		}
 	}
 
-	if versionTemplate != "" || rawTemplate != "" {
-		c.data["DisplayVersion"] = c.Get("Version")
-	}
+	return versionTemplate != "" || rawTemplate != ""
 }
 
-// Apply applies the template to a string
-func (c *Context) Apply(tmpl string) (string, error) {
-	t, err := template.New("").Funcs(c.funcs()).Parse(tmpl)
+// Apply applies the template to a string. source names where the template
+// comes from in errors, e.g. "archives[0].name_template".
+func (c *Context) Apply(source, tmpl string) (string, error) {
+	t, err := c.parse(source, tmpl)
 	if err != nil {
 		return "", err
 	}
 
 	var buf bytes.Buffer
 	if err := t.Execute(&buf, c.data); err != nil {
-		return "", err
+		return "", c.templateError(source, tmpl, err)
 	}
 
 	return buf.String(), nil
 }
 
+// Check parses the template and checks its fields without executing it.
+// Unset .Env variables are left to Apply, hooks may still set them.
+func (c *Context) Check(source, tmpl string) error {
+	lenient := *c
+	cfg := *c.config
+	cfg.StrictEnv = false
+	lenient.config = &cfg
+	_, err := lenient.parse(source, tmpl)
+	return err
+}
+
+// parse parses the template and checks its fields against the data
+func (c *Context) parse(source, tmpl string) (*template.Template, error) {
+	t, err := template.New(source).Funcs(c.funcs()).Parse(tmpl)
+	if err != nil {
+		return nil, c.templateError(source, tmpl, err)
+	}
+	if t.Tree != nil {
+		if err := c.checkFields(source, t.Tree.Root, true); err != nil {
+			return nil, c.templateError(source, tmpl, err)
+		}
+	}
+	return t, nil
+}
+
+// checkFields rejects references to fields the context does not define,
+// unknown .Var entries and, with strict_env, unset .Env variables. Fields
+// are only checked where dot is the root data, outside range and with.
+func (c *Context) checkFields(source string, node parse.Node, root bool) error {
+	switch n := node.(type) {
+	case *parse.ListNode:
+		if n == nil {
+			return nil
+		}
+		for _, child := range n.Nodes {
+			if err := c.checkFields(source, child, root); err != nil {
+				return err
+			}
+		}
+	case *parse.ActionNode:
+		return c.checkFields(source, n.Pipe, root)
+	case *parse.PipeNode:
+		if n == nil {
+			return nil
+		}
+		for _, cmd := range n.Cmds {
+			if err := c.checkFields(source, cmd, root); err != nil {
+				return err
+			}
+		}
+	case *parse.CommandNode:
+		for _, arg := range n.Args {
+			if err := c.checkFields(source, arg, root); err != nil {
+				return err
+			}
+		}
+	case *parse.ChainNode:
+		return c.checkFields(source, n.Node, root)
+	case *parse.IfNode:
+		return c.checkBranch(source, &n.BranchNode, root, root)
+	case *parse.RangeNode:
+		return c.checkBranch(source, &n.BranchNode, root, false)
+	case *parse.WithNode:
+		return c.checkBranch(source, &n.BranchNode, root, false)
+	case *parse.TemplateNode:
+		return c.checkFields(source, n.Pipe, root)
+	case *parse.FieldNode:
+		if root {
+			return c.checkField(source, n.Ident)
+		}
+	case *parse.VariableNode:
+		if len(n.Ident) > 1 && n.Ident[0] == "$" {
+			return c.checkField(source, n.Ident[1:])
+		}
+	}
+	return nil
+}
+
+// checkBranch checks an if, range or with node. inner reports whether dot
+// is still the root data inside the branch.
+func (c *Context) checkBranch(source string, n *parse.BranchNode, root, inner bool) error {
+	if err := c.checkFields(source, n.Pipe, root); err != nil {
+		return err
+	}
+	if err := c.checkFields(source, n.List, root && inner); err != nil {
+		return err
+	}
+	return c.checkFields(source, n.ElseList, root)
+}
+
+// checkField checks a field chain such as [Env HOME] against the data
+func (c *Context) checkField(source string, ident []string) error {
+	value, ok := c.data[ident[0]]
+	if !ok {
+		return &fieldError{field: ident[0], available: c.fields()}
+	}
+	if len(ident) < 2 {
+		return nil
+	}
+	switch ident[0] {
+	case "Var":
+		if vars, ok := value.(map[string]interface{}); ok {
+			if _, ok := vars[ident[1]]; !ok {
+				return fmt.Errorf("unknown variable .Var.%s", ident[1])
+			}
+		}
+	case "Env":
+		if env, ok := value.(map[string]string); ok && c.config.StrictEnv {
+			if _, ok := env[ident[1]]; !ok {
+				return fmt.Errorf("environment variable %s is not set", ident[1])
+			}
+		}
+	}
+	return nil
+}
+
+// fields returns the sorted top-level fields of the data
+func (c *Context) fields() []string {
+	fields := make([]string, 0, len(c.data))
+	for k := range c.data {
+		fields = append(fields, k)
+	}
+	sort.Strings(fields)
+	return fields
+}
+
+// fieldError is a reference to a field the context does not define
+type fieldError struct {
+	field     string
+	available []string
+}
+
+func (e *fieldError) Error() string {
+	return "unknown field ." + e.field
+}
+
+// templateError names the source, quotes the template text unless it spans
+// several lines, where the line number in err points into it, and lists the
+// available fields on unknown field errors. text/template errors already
+// carry the "template: source" prefix.
+func (c *Context) templateError(source, tmpl string, err error) error {
+	if !strings.HasPrefix(err.Error(), "template: ") {
+		if source == "" {
+			err = fmt.Errorf("template: %w", err)
+		} else {
+			err = fmt.Errorf("template: %s: %w", source, err)
+		}
+	}
+	if !strings.Contains(tmpl, "\n") {
+		if len(tmpl) > 120 {
+			tmpl = tmpl[:117] + "..."
+		}
+		err = fmt.Errorf("%w in %q", err, tmpl)
+	}
+	var fieldErr *fieldError
+	if errors.As(err, &fieldErr) {
+		err = fmt.Errorf("%w (available fields: .%s)", err, strings.Join(fieldErr.available, ", ."))
+	}
+	return err
+}
+
 // Set sets a value in the context
 func (c *Context) Set(key string, value interface{}) {
 	c.data[key] = value
 }
 
+// SetEnv sets an environment variable visible to templates as .Env.NAME
+func (c *Context) SetEnv(key, value string) {
+	env, ok := c.data["Env"].(map[string]string)
+	if !ok {
+		env = make(map[string]string)
+		c.data["Env"] = env
+	}
+	env[key] = value
+}
+
 // Get gets a value from the context
 func (c *Context) Get(key string) string {
 	if val, ok := c.data[key]; ok {
@@ -240,6 +508,8 @@ func (c *Context) WithArtifactInfo(name, goos, goarch, goarm, goamd64 string) *C
 	newCtx.data["Arch"] = goarch
 	newCtx.data["Arm"] = goarm
 	newCtx.data["Amd64"] = goamd64
+	newCtx.data["Mips"] = ""
+	newCtx.data["Target"] = target.Target{OS: goos, Arch: goarch, Arm: goarm, Amd64: goamd64}.String()
 
 	// Add platform mappings
 	newCtx.data["GOOS"] = goos
@@ -247,6 +517,26 @@ func (c *Context) WithArtifactInfo(name, goos, goarch, goarm, goamd64 string) *C
 	newCtx.data["GOARM"] = goarm
 	newCtx.data["GOAMD64"] = goamd64
 
+	// Set by ForArtifact
+	newCtx.data["ArtifactPath"] = ""
+	newCtx.data["ArtifactID"] = ""
+	newCtx.data["ArtifactSha256"] = ""
+	newCtx.data["ArtifactChecksum"] = ""
+
+	return newCtx
+}
+
+// ForArtifact creates a context for an artifact, adding its .ArtifactPath,
+// .ArtifactID (build id) and the .ArtifactSha256 and .ArtifactChecksum
+// digests recorded by the checksum stage
+func (c *Context) ForArtifact(a artifact.Artifact) *Context {
+	newCtx := c.WithArtifactInfo(a.Name, a.Goos, a.Goarch, a.Goarm, a.Goamd64)
+	newCtx.data["Mips"] = a.Gomips
+	newCtx.data["Target"] = a.Platform()
+	newCtx.data["ArtifactPath"] = a.Path
+	newCtx.data["ArtifactID"] = a.BuildID
+	newCtx.data["ArtifactSha256"] = a.Checksum("sha256")
+	newCtx.data["ArtifactChecksum"], _ = a.Extra[artifact.ExtraChecksum].(string)
 	return newCtx
 }
 
@@ -297,9 +587,22 @@ func (c *Context) funcs() template.FuncMap {
 			return b
 		},
 
-		// Date formatting
-		"time": func(t time.Time, format string) string {
-			return t.Format(format)
+		// Date formatting: time "2006-01-02" formats the current UTC time,
+		// time .Now "2006-01-02" a given time
+		"time": func(args ...interface{}) (string, error) {
+			switch len(args) {
+			case 1:
+				if format, ok := args[0].(string); ok {
+					return time.Now().UTC().Format(format), nil
+				}
+			case 2:
+				t, ok := args[0].(time.Time)
+				format, isString := args[1].(string)
+				if ok && isString {
+					return t.Format(format), nil
+				}
+			}
+			return "", fmt.Errorf("time expects a format or a time and a format")
 		},
 		"now": time.Now,
 
@@ -348,6 +651,15 @@ func (c *Context) funcs() template.FuncMap {
 			}
 			return 1
 		},
+		"incmajor": func(version string) (string, error) {
+			return git.BumpVersion(version, "major")
+		},
+		"incminor": func(version string) (string, error) {
+			return git.BumpVersion(version, "minor")
+		},
+		"incpatch": func(version string) (string, error) {
+			return git.BumpVersion(version, "patch")
+		},
 
 		// Markdown helpers
 		"mdlink": func(text, url string) string {
@@ -360,17 +672,40 @@ func (c *Context) funcs() template.FuncMap {
 			return "```" + lang + "\n" + code + "\n```"
 		},
 
-		// Filter helpers
-		"filter": func(items []interface{}, key string, value interface{}) []interface{} {
-			var result []interface{}
-			for _, item := range items {
-				if m, ok := item.(map[string]interface{}); ok {
-					if m[key] == value {
-						result = append(result, item)
+		// Filter helpers: filter "text" "regexp" keeps the matching lines,
+		// filter .List "key" value keeps the maps whose key equals value
+		"filter": func(items interface{}, args ...interface{}) (interface{}, error) {
+			switch items := items.(type) {
+			case string:
+				if len(args) != 1 {
+					return nil, fmt.Errorf("filter on text expects a regexp")
+				}
+				return filterLines(items, fmt.Sprint(args[0]))
+			case []interface{}:
+				if len(args) != 2 {
+					return nil, fmt.Errorf("filter on a list expects a key and a value")
+				}
+				var result []interface{}
+				for _, item := range items {
+					if m, ok := item.(map[string]interface{}); ok {
+						if m[fmt.Sprint(args[0])] == args[1] {
+							result = append(result, item)
+						}
 					}
 				}
+				return result, nil
 			}
-			return result
+			return nil, fmt.Errorf("filter expects text or a list, got %T", items)
+		},
+		"indexOrDefault": func(m interface{}, key string, def interface{}) interface{} {
+			v := reflect.ValueOf(m)
+			if v.Kind() != reflect.Map || v.Type().Key().Kind() != reflect.String {
+				return def
+			}
+			if val := v.MapIndex(reflect.ValueOf(key).Convert(v.Type().Key())); val.IsValid() {
+				return val.Interface()
+			}
+			return def
 		},
 
 		// List helpers
@@ -386,6 +721,10 @@ func (c *Context) funcs() template.FuncMap {
 			}
 			return nil
 		},
+		"tojson": func(v interface{}) (string, error) {
+			b, err := json.Marshal(v)
+			return string(b), err
+		},
 		"reverse": func(items []interface{}) []interface{} {
 			result := make([]interface{}, len(items))
 			for i, item := range items {
@@ -396,6 +735,21 @@ func (c *Context) funcs() template.FuncMap {
 	}
 }
 
+// filterLines keeps the lines of text matching a regexp
+func filterLines(text, pattern string) (string, error) {
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return "", fmt.Errorf("invalid filter regexp: %w", err)
+	}
+	var lines []string
+	for _, line := range strings.Split(text, "\n") {
+		if re.MatchString(line) {
+			lines = append(lines, line)
+		}
+	}
+	return strings.Join(lines, "\n"), nil
+}
+
 // Data returns the template data
 func (c *Context) Data() map[string]interface{} {
 	result := make(map[string]interface{})
@@ -404,3 +758,14 @@ func (c *Context) Data() map[string]interface{} {
 	}
 	return result
 }
+
+// Restore sets template data saved by a previous run, e.g. from the state
+// of a prepared release. Whole numbers decoded from JSON become ints again.
+func (c *Context) Restore(data map[string]interface{}) {
+	for k, v := range data {
+		if f, ok := v.(float64); ok && f == float64(int64(f)) {
+			v = int(f)
+		}
+		c.data[k] = v
+	}
+}